@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,6 +17,8 @@ import (
 	"time"
 
 	"github.com/buildpacks/imgutil"
+	"github.com/docker/docker/api/types"
+	dockercli "github.com/docker/docker/client"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/sclevine/spec"
@@ -38,8 +41,13 @@ var (
 	exportDaemonFixtures *daemonImageFixtures
 	exportRegFixtures    *regImageFixtures
 	exportTest           *PhaseTest
+	exportPodman         dockercli.CommonAPIClient // set if a Podman service is available, see podmanSocket
 )
 
+// podmanSocket is the socket of a rootful Podman service (e.g., started with `systemctl start podman.socket`).
+// Exporting to Podman is only tested if the socket exists.
+const podmanSocket = "/run/podman/podman.sock"
+
 func TestExporter(t *testing.T) {
 	h.SkipIf(t, runtime.GOOS == "windows", "Exporter acceptance tests are not yet supported on Windows")
 
@@ -57,6 +65,12 @@ func TestExporter(t *testing.T) {
 	exportDaemonFixtures = exportTest.targetDaemon.fixtures
 	exportRegFixtures = exportTest.targetRegistry.fixtures
 
+	if _, err := os.Stat(podmanSocket); err == nil {
+		exportPodman = newPodmanClient(t)
+		copyImageToPodman(t, exportPodman, exportRegFixtures.ReadOnlyRunImage)
+		defer removePodmanImage(t, exportPodman, exportRegFixtures.ReadOnlyRunImage)
+	}
+
 	for _, platformAPI := range api.Platform.Supported {
 		spec.Run(t, "acceptance-exporter/"+platformAPI.String(), testExporterFunc(platformAPI.String()), spec.Parallel(), spec.Report(report.Terminal{}))
 	}
@@ -230,6 +244,37 @@ func testExporterFunc(platformAPI string) func(t *testing.T, when spec.G, it spe
 			})
 		})
 
+		when("podman daemon case", func() {
+			it.Before(func() {
+				h.SkipIf(t, exportPodman == nil, "Podman socket not found at "+podmanSocket)
+				h.SkipIf(t, api.MustParse(platformAPI).LessThan("0.7"), "Platform API < 0.7 requires a -run-image flag")
+			})
+
+			it("finds the podman socket and exports the app", func() {
+				podmanImageName := "some-exported-image-" + h.RandString(10)
+				exportArgs := []string{ctrPath(exporterPath), "-daemon", "-log-level", "debug", podmanImageName}
+
+				// only the podman socket is mounted, so the exporter finds it at the default location
+				output := h.DockerRun(t,
+					exportImage,
+					h.WithFlags(
+						"--mount", "type=bind,source="+podmanSocket+",target="+podmanSocket,
+						"--user", "0",
+						"--env", "CNB_PLATFORM_API="+platformAPI,
+					),
+					h.WithArgs(exportArgs...),
+				)
+				defer removePodmanImage(t, exportPodman, podmanImageName)
+				h.AssertStringContains(t, output, "Saving "+podmanImageName)
+
+				inspect, _, err := exportPodman.ImageInspectWithRaw(context.TODO(), podmanImageName)
+				h.AssertNil(t, err)
+				h.AssertEq(t, inspect.Os, exportTest.targetDaemon.os)
+				h.AssertEq(t, inspect.Architecture, exportTest.targetDaemon.arch)
+				h.AssertEq(t, inspect.Config.Labels["io.buildpacks.lifecycle.metadata"] != "", true)
+			})
+		})
+
 		when("registry case", func() {
 			when("first build", func() {
 				when("app", func() {
@@ -533,6 +578,29 @@ func assertDaemonImageDoesNotHaveHistory(t *testing.T, repoName string) {
 	}
 }
 
+func newPodmanClient(t *testing.T) dockercli.CommonAPIClient {
+	podman, err := dockercli.NewClientWithOpts(dockercli.WithHost("unix://"+podmanSocket), dockercli.WithAPIVersionNegotiation())
+	h.AssertNil(t, err)
+	return podman
+}
+
+// copyImageToPodman copies the image from the docker daemon to Podman, as Podman does not share the images of the docker daemon.
+func copyImageToPodman(t *testing.T, podman dockercli.CommonAPIClient, imageName string) {
+	rc, err := h.DockerCli(t).ImageSave(context.TODO(), []string{imageName})
+	h.AssertNil(t, err)
+	defer rc.Close()
+	resp, err := podman.ImageLoad(context.TODO(), rc, true)
+	h.AssertNil(t, err)
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	h.AssertNil(t, err)
+}
+
+func removePodmanImage(t *testing.T, podman dockercli.CommonAPIClient, imageName string) {
+	_, err := podman.ImageRemove(context.TODO(), imageName, types.ImageRemoveOptions{Force: true})
+	h.AssertNil(t, err)
+}
+
 func assertDaemonImageHasHistory(t *testing.T, repoName string, expectedHistory []string) {
 	history, err := h.DockerCli(t).ImageHistory(context.TODO(), repoName)
 	h.AssertNil(t, err)
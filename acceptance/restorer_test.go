@@ -249,6 +249,25 @@ func testRestorerFunc(platformAPI string) func(t *testing.T, when spec.G, it spe
 			})
 		})
 
+		when("extending without a kaniko directory", func() {
+			it("errors", func() {
+				h.SkipIf(t, api.MustParse(platformAPI).LessThan("0.12"), "Platform API < 0.12 does not accept -kaniko-dir")
+				command := exec.Command(
+					"docker", "run", "--rm",
+					"--env", "CNB_PLATFORM_API="+platformAPI,
+					"--env", "DOCKER_CONFIG=/docker-config",
+					"--network", restoreRegNetwork,
+					restoreImage,
+					"-analyzed", "/layers/some-extend-true-analyzed.toml",
+					"-kaniko-dir", "/some-missing-kaniko-dir",
+				)
+				output, err := command.CombinedOutput()
+				h.AssertNotNil(t, err)
+				expected := "kaniko directory /some-missing-kaniko-dir does not exist; provide it with -kaniko-dir or CNB_KANIKO_DIR when extending"
+				h.AssertStringContains(t, string(output), expected)
+			})
+		})
+
 		when("target data", func() {
 			it("updates run image reference in analyzed.toml to include digest and target data on newer platforms", func() {
 				h.SkipIf(t, api.MustParse(platformAPI).LessThan("0.7"), "Platform API < 0.7 does not support -analyzed flag")
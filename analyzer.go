@@ -1,12 +1,16 @@
 package lifecycle
 
 import (
+	"context"
+	"os"
+
 	"github.com/buildpacks/imgutil"
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/cache"
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/image"
 	"github.com/buildpacks/lifecycle/internal/fsutil"
 	"github.com/buildpacks/lifecycle/internal/layer"
@@ -81,6 +85,9 @@ func (f *AnalyzerFactory) NewAnalyzer(
 		if err := f.ensureRegistryAccess(additionalTags, cacheImageRef, outputImageRef, runImageRef, previousImageRef); err != nil {
 			return nil, err
 		}
+		if err := f.ensureLaunchCacheAccess(launchCacheDir); err != nil {
+			return nil, err
+		}
 	} else {
 		if err := f.setBuildpacks(analyzer, legacyGroup, legacyGroupPath, logger); err != nil {
 			return nil, err
@@ -123,15 +130,47 @@ func (f *AnalyzerFactory) ensureRegistryAccess(
 		writeImages = append(writeImages, additionalTags...)
 	}
 
+	inputs := map[string]string{previousImageRef: "previous image", runImageRef: "run image", cacheImageRef: "cache image"}
+	for _, tag := range additionalTags {
+		inputs[tag] = "additional tag"
+	}
+	inputs[outputImageRef] = "output image"
 	if err := f.registryHandler.EnsureReadAccess(readImages...); err != nil {
-		return errors.Wrap(err, "validating registry read access")
+		return errors.Wrap(nameRegistryInput(err, inputs), "validating registry read access")
 	}
 	if err := f.registryHandler.EnsureWriteAccess(writeImages...); err != nil {
-		return errors.Wrap(err, "validating registry write access")
+		return errors.Wrap(nameRegistryInput(err, inputs), "validating registry write access")
 	}
 	return nil
 }
 
+// nameRegistryInput records which input the image of a registry auth error was provided as,
+// as the same registry may be used for several of them (e.g., the output image and the cache image).
+func nameRegistryInput(err error, inputs map[string]string) error {
+	var authErr *lerrors.RegistryAuthError
+	if errors.As(err, &authErr) && authErr.Input == "" {
+		authErr.Input = inputs[authErr.Image]
+	}
+	return err
+}
+
+// ensureLaunchCacheAccess ensures that layers can be written to the launch cache, if it is used,
+// so that a misconfigured volume fails the build before the previous image is read instead of when the layers are exported.
+func (f *AnalyzerFactory) ensureLaunchCacheAccess(launchCacheDir string) error {
+	if launchCacheDir == "" || f.imageHandler.Kind() != image.LocalKind {
+		return nil
+	}
+	if err := os.MkdirAll(launchCacheDir, 0777); err != nil {
+		return errors.Wrapf(err, "validating launch cache write access to '%s'", launchCacheDir)
+	}
+	tmp, err := os.CreateTemp(launchCacheDir, ".access-check-*")
+	if err != nil {
+		return errors.Wrapf(err, "validating launch cache write access to '%s'", launchCacheDir)
+	}
+	_ = tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
 func (f *AnalyzerFactory) setBuildpacks(analyzer *Analyzer, group buildpack.Group, path string, logger log.Logger) error {
 	if len(group.Group) > 0 {
 		analyzer.Buildpacks = group.Group
@@ -188,8 +227,16 @@ func (f *AnalyzerFactory) setRun(analyzer *Analyzer, imageRef string) error {
 	return nil
 }
 
-// Analyze fetches the layers metadata from the previous image and writes analyzed.toml.
+// Analyze is equivalent to AnalyzeContext with context.Background().
+//
+// Deprecated: use AnalyzeContext.
 func (a *Analyzer) Analyze() (files.Analyzed, error) {
+	return a.AnalyzeContext(context.Background())
+}
+
+// AnalyzeContext fetches the layers metadata from the previous image and writes analyzed.toml.
+// The context is checked between the requests to the registry (or daemon), as the images do not accept a context.
+func (a *Analyzer) AnalyzeContext(ctx context.Context) (files.Analyzed, error) {
 	defer log.NewMeasurement("Analyzer", a.Logger)()
 	var (
 		err              error
@@ -198,6 +245,9 @@ func (a *Analyzer) Analyze() (files.Analyzed, error) {
 		previousImageRef string
 		runImageRef      string
 	)
+	if err = ctx.Err(); err != nil {
+		return files.Analyzed{}, err
+	}
 	appMeta, previousImageRef, err = a.retrieveAppMetadata()
 	if err != nil {
 		return files.Analyzed{}, err
@@ -210,10 +260,14 @@ func (a *Analyzer) Analyze() (files.Analyzed, error) {
 	}
 
 	var (
-		atm          *files.TargetMetadata
-		runImageName string
+		atm             *files.TargetMetadata
+		runImageName    string
+		runImageStackID string
 	)
 	if a.RunImage != nil {
+		if err = ctx.Err(); err != nil {
+			return files.Analyzed{}, err
+		}
 		runImageRef, err = a.getImageIdentifier(a.RunImage)
 		if err != nil {
 			return files.Analyzed{}, errors.Wrap(err, "identifying run image")
@@ -227,10 +281,27 @@ func (a *Analyzer) Analyze() (files.Analyzed, error) {
 			if atm.OS == "" {
 				platform.GetTargetOSFromFileSystem(&fsutil.Detect{}, atm, a.Logger)
 			}
+			platform.GetTargetDistroFromImage(a.RunImage, atm, a.Logger)
+			// record the stack alongside the target during the deprecation of stacks
+			if runImageStackID, err = a.RunImage.Label(platform.StackIDLabel); err != nil {
+				return files.Analyzed{}, errors.Wrap(err, "reading stack label of run image")
+			}
+			if runImageStackID == "" {
+				runImageStackID = files.StackIDForTarget(*atm)
+			}
 		}
 	}
 
+	var buildTarget *files.TargetMetadata
+	if a.PlatformAPI.AtLeast("0.12") {
+		tm := platform.GetBuildTarget(a.Logger)
+		buildTarget = &tm
+	}
+
 	if a.RestoresLayerMetadata {
+		if err = ctx.Err(); err != nil {
+			return files.Analyzed{}, err
+		}
 		cacheMeta, err = retrieveCacheMetadata(a.Cache, a.Logger)
 		if err != nil {
 			return files.Analyzed{}, err
@@ -249,9 +320,11 @@ func (a *Analyzer) Analyze() (files.Analyzed, error) {
 		RunImage: &files.RunImage{
 			Reference:      runImageRef, // the image identifier, e.g. "s0m3d1g3st" (the image identifier) when exporting to a daemon, or "some.registry/some-repo@sha256:s0m3d1g3st" when exporting to a registry
 			TargetMetadata: atm,
+			StackID:        runImageStackID,
 			Image:          runImageName, // the provided tag, e.g., "some.registry/some-repo:some-tag" if supported by the platform
 		},
 		LayersMetadata: appMeta,
+		BuildTarget:    buildTarget,
 	}, nil
 }
 
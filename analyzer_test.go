@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/apex/log"
@@ -12,9 +13,12 @@ import (
 	"github.com/buildpacks/imgutil/fakes"
 	"github.com/buildpacks/imgutil/local"
 	"github.com/golang/mock/gomock"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/image"
 	"github.com/buildpacks/lifecycle/platform/files"
 
@@ -225,6 +229,55 @@ func testAnalyzerFactory(t *testing.T, when spec.G, it spec.S) {
 					h.AssertPathExists(t, filepath.Join(launchCacheDir, "committed"))
 					h.AssertPathExists(t, filepath.Join(launchCacheDir, "staging"))
 				})
+
+				it("errors if the launch cache cannot be written", func() {
+					fakeImageHandler.EXPECT().Kind().Return(image.LocalKind).AnyTimes()
+					fakeRegistryHandler.EXPECT().EnsureReadAccess()
+					fakeRegistryHandler.EXPECT().EnsureWriteAccess([]string{"some-cache-image-ref"})
+
+					launchCacheDir := filepath.Join(tempDir, "some-launch-cache-dir")
+					h.Mkfile(t, "", launchCacheDir)
+					_, err := analyzerFactory.NewAnalyzer(
+						nil,
+						"some-cache-image-ref",
+						launchCacheDir,
+						"some-layers-dir",
+						"",
+						buildpack.Group{},
+						"",
+						"some-output-image-ref",
+						"some-previous-image-ref",
+						"some-run-image-ref",
+						false,
+						logger,
+					)
+					h.AssertError(t, err, "validating launch cache write access to '"+launchCacheDir+"'")
+				})
+			})
+
+			when("an image is not accessible", func() {
+				it("names the input it was provided as", func() {
+					fakeImageHandler.EXPECT().Kind().Return(image.RemoteKind).AnyTimes()
+					fakeRegistryHandler.EXPECT().EnsureReadAccess([]string{"some-previous-image-ref", "some-run-image-ref"})
+					fakeRegistryHandler.EXPECT().EnsureWriteAccess([]string{"some-cache-image-ref", "some-output-image-ref"}).
+						Return(&lerrors.RegistryAuthError{Image: "some-cache-image-ref", Access: lerrors.AccessReadWrite})
+
+					_, err := analyzerFactory.NewAnalyzer(
+						nil,
+						"some-cache-image-ref",
+						"",
+						"some-layers-dir",
+						"",
+						buildpack.Group{},
+						"",
+						"some-output-image-ref",
+						"some-previous-image-ref",
+						"some-run-image-ref",
+						false,
+						logger,
+					)
+					h.AssertError(t, err, "validating registry write access: ensure registry read/write access to some-cache-image-ref (cache image)")
+				})
 			})
 
 			when("skip layers", func() {
@@ -690,6 +743,51 @@ func testAnalyzer(platformAPI string) func(t *testing.T, when spec.G, it spec.S)
 						h.AssertEq(t, md.RunImage.TargetMetadata.Distribution.Version, "Helpful Holstein")
 					}
 				})
+
+				when("the run image does not have distribution labels", func() {
+					it("reads the distribution from the os-release file of the run image", func() {
+						h.AssertNil(t, image.SetOS("linux"))
+						underlying, err := mutate.AppendLayers(empty.Image, tarLayer(t, map[string]string{
+							"etc/os-release": "ID=ubuntu\nVERSION_ID=\"22.04\"\n",
+						}))
+						h.AssertNil(t, err)
+						analyzer.RunImage = &inspectableImage{Image: image, underlying: underlying}
+
+						md, err := analyzer.Analyze()
+						h.AssertNil(t, err)
+						if api.MustParse(platformAPI).LessThan("0.12") {
+							h.AssertNil(t, md.RunImage.TargetMetadata)
+						} else {
+							h.AssertNotNil(t, md.RunImage.TargetMetadata.Distribution)
+							h.AssertEq(t, md.RunImage.TargetMetadata.Distribution.Name, "ubuntu")
+							h.AssertEq(t, md.RunImage.TargetMetadata.Distribution.Version, "22.04")
+							h.AssertEq(t, md.RunImage.StackID, "io.buildpacks.stacks.jammy")
+						}
+					})
+				})
+
+				it("records the stack of the run image alongside its target", func() {
+					h.AssertNil(t, image.SetLabel("io.buildpacks.stack.id", "some-stack"))
+
+					md, err := analyzer.Analyze()
+					h.AssertNil(t, err)
+					if api.MustParse(platformAPI).LessThan("0.12") {
+						h.AssertEq(t, md.RunImage.StackID, "")
+					} else {
+						h.AssertEq(t, md.RunImage.StackID, "some-stack")
+					}
+				})
+
+				it("records the target of the build image", func() {
+					md, err := analyzer.Analyze()
+					h.AssertNil(t, err)
+					if api.MustParse(platformAPI).LessThan("0.12") {
+						h.AssertNil(t, md.BuildTarget)
+					} else {
+						h.AssertEq(t, md.BuildTarget.OS, runtime.GOOS)
+						h.AssertEq(t, md.BuildTarget.Arch, runtime.GOARCH)
+					}
+				})
 			})
 		})
 	}
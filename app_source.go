@@ -0,0 +1,68 @@
+package lifecycle
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// AppIgnoreFile is the name of the file in the root of the application source that lists gitignore-style patterns
+// for the paths (e.g., node_modules or .git) that are not copied to the app directory.
+const AppIgnoreFile = ".cnbignore"
+
+// CopyAppSource copies the application source in srcDir to the app directory before buildpacks run,
+// skipping the paths that match the patterns in the AppIgnoreFile (if present).
+// Files are copied concurrently, as the application source may contain many small files.
+func CopyAppSource(srcDir, appDir string, logger log.Logger) error {
+	patterns, err := readAppIgnoreFile(srcDir)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Copying application source from '%s'", srcDir)
+	skipped, err := fsutil.CopyDirConcurrently(srcDir, appDir, patterns, 4*runtime.NumCPU())
+	if err != nil {
+		return errors.Wrap(err, "copying application source")
+	}
+	for _, path := range skipped {
+		logger.Debugf("Ignoring '%s'", path)
+	}
+	if len(skipped) > 0 {
+		logger.Infof("Ignored %d paths matching %s", len(skipped), AppIgnoreFile)
+	}
+	return nil
+}
+
+// ApplyAppSymlinkPolicy applies the platform-provided policy (see fsutil.SymlinkPolicy) to the symlinks in the app directory
+// that point outside of it. It is applied by the detector, the builder and the exporter, so that every phase sees the same app directory
+// and the app layers (including slices) never contain symlinks that the policy rejects.
+func ApplyAppSymlinkPolicy(appDir, policy string, logger log.Logger) error {
+	links, err := fsutil.ApplySymlinkPolicy(appDir, fsutil.SymlinkPolicy(policy))
+	if err != nil {
+		return errors.Wrap(err, "applying app symlink policy")
+	}
+	for _, link := range links {
+		logger.Debugf("Following symlink '%s' pointing outside of the app directory", link)
+	}
+	return nil
+}
+
+func readAppIgnoreFile(srcDir string) (*fsutil.Patterns, error) {
+	contents, err := os.ReadFile(filepath.Join(srcDir, AppIgnoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", AppIgnoreFile)
+	}
+	patterns, err := fsutil.NewPatterns(strings.Split(string(contents), "\n"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", AppIgnoreFile)
+	}
+	return patterns, nil
+}
@@ -0,0 +1,107 @@
+package lifecycle_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestCopyAppSource(t *testing.T) {
+	spec.Run(t, "CopyAppSource", testCopyAppSource, spec.Report(report.Terminal{}))
+}
+
+func TestApplyAppSymlinkPolicy(t *testing.T) {
+	spec.Run(t, "ApplyAppSymlinkPolicy", testApplyAppSymlinkPolicy, spec.Report(report.Terminal{}))
+}
+
+func testApplyAppSymlinkPolicy(t *testing.T, when spec.G, it spec.S) {
+	var (
+		appDir     string
+		logHandler *memory.Handler
+		logger     *log.Logger
+	)
+
+	it.Before(func() {
+		h.SkipIf(t, runtime.GOOS == "windows", "symlinks require privileges on windows")
+		tmpDir := t.TempDir()
+		appDir = filepath.Join(tmpDir, "workspace")
+		h.AssertNil(t, os.MkdirAll(appDir, 0755))
+		h.Mkfile(t, "some-shared-contents", filepath.Join(tmpDir, "shared.txt"))
+		h.AssertNil(t, os.Symlink(filepath.Join(tmpDir, "shared.txt"), filepath.Join(appDir, "shared.txt")))
+		logHandler = memory.New()
+		logger = &log.Logger{Handler: logHandler, Level: log.DebugLevel}
+	})
+
+	it("follows the symlinks pointing outside of the app directory", func() {
+		h.AssertNil(t, lifecycle.ApplyAppSymlinkPolicy(appDir, "follow", logger))
+
+		h.AssertEq(t, string(h.MustReadFile(t, filepath.Join(appDir, "shared.txt"))), "some-shared-contents")
+		h.AssertLogEntry(t, logHandler, "Following symlink 'shared.txt' pointing outside of the app directory")
+	})
+
+	it("fails when the policy is error", func() {
+		err := lifecycle.ApplyAppSymlinkPolicy(appDir, "error", logger)
+		h.AssertError(t, err, "applying app symlink policy: symlink 'shared.txt' points outside of")
+	})
+
+	it("preserves the symlinks by default", func() {
+		h.AssertNil(t, lifecycle.ApplyAppSymlinkPolicy(appDir, "", logger))
+
+		target, err := os.Readlink(filepath.Join(appDir, "shared.txt"))
+		h.AssertNil(t, err)
+		h.AssertEq(t, target, filepath.Join(filepath.Dir(appDir), "shared.txt"))
+	})
+}
+
+func testCopyAppSource(t *testing.T, when spec.G, it spec.S) {
+	var (
+		srcDir     string
+		appDir     string
+		logHandler *memory.Handler
+		logger     *log.Logger
+	)
+
+	it.Before(func() {
+		srcDir = t.TempDir()
+		appDir = filepath.Join(t.TempDir(), "workspace")
+		for _, path := range []string{"index.js", "node_modules/dep/index.js", "src/.git/HEAD"} {
+			h.AssertNil(t, os.MkdirAll(filepath.Dir(filepath.Join(srcDir, path)), 0755))
+			h.Mkfile(t, "some-contents", filepath.Join(srcDir, path))
+		}
+		logHandler = memory.New()
+		logger = &log.Logger{Handler: logHandler, Level: log.DebugLevel}
+	})
+
+	it("copies the application source to the app directory", func() {
+		h.AssertNil(t, lifecycle.CopyAppSource(srcDir, appDir, logger))
+
+		h.AssertPathExists(t, filepath.Join(appDir, "index.js"))
+		h.AssertPathExists(t, filepath.Join(appDir, "node_modules", "dep", "index.js"))
+		h.AssertPathExists(t, filepath.Join(appDir, "src", ".git", "HEAD"))
+	})
+
+	when("there is an ignore file", func() {
+		it.Before(func() {
+			h.Mkfile(t, "# dependencies are installed by the buildpack\nnode_modules/\n.git\n", filepath.Join(srcDir, lifecycle.AppIgnoreFile))
+		})
+
+		it("skips the ignored paths", func() {
+			h.AssertNil(t, lifecycle.CopyAppSource(srcDir, appDir, logger))
+
+			h.AssertPathExists(t, filepath.Join(appDir, "index.js"))
+			h.AssertPathDoesNotExist(t, filepath.Join(appDir, "node_modules"))
+			h.AssertPathDoesNotExist(t, filepath.Join(appDir, "src", ".git"))
+			h.AssertLogEntry(t, logHandler, "Ignoring 'node_modules'")
+			h.AssertLogEntry(t, logHandler, "Ignored 2 paths matching .cnbignore")
+		})
+	})
+}
@@ -3,9 +3,10 @@ package archive
 import (
 	"archive/tar"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
 )
 
 // PathInfo associates a path with an os.FileInfo
@@ -44,16 +45,21 @@ func AddFileToArchive(tw TarWriter, path string, fi os.FileInfo) error {
 		header.Linkname = target
 	}
 	addSysAttributes(header, fi)
+	if ntw, ok := tw.(*NormalizingTarWriter); ok && len(ntw.xattrs) > 0 && (fi.Mode().IsRegular() || fi.IsDir()) {
+		if err := addXattrs(header, path, ntw.xattrs); err != nil {
+			return err
+		}
+	}
 	if err := tw.WriteHeader(header); err != nil {
 		return err
 	}
 	if fi.Mode().IsRegular() {
-		f, err := os.Open(path)
+		f, err := fsutil.OpenSequential(path)
 		if err != nil {
 			return err
 		}
 		defer f.Close()
-		if _, err := io.Copy(tw, f); err != nil {
+		if _, err := fsutil.Stream(tw, f); err != nil {
 			return err
 		}
 	}
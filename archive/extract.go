@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -40,27 +41,118 @@ func unsetUmaskIfNeeded() {
 	}
 }
 
+// ExtractOptions configure the extraction of an archive.
+type ExtractOptions struct {
+	// Root, if provided, is the directory that entries must be extracted within (see ExtractWithOptions).
+	Root string
+	// Lenient skips the entries that are rejected rather than failing the extraction.
+	Lenient bool
+	// Xattrs are the prefixes of the names of the extended attributes (e.g., "security.capability" for file capabilities)
+	// that are restored from the archive (see NormalizingTarWriter.WithXattrs). By default, extended attributes are not restored.
+	Xattrs []string
+}
+
+// ExtractReport describes the entries of an archive that could not be extracted as they are.
+type ExtractReport struct {
+	// Rejections are the entries that were not extracted.
+	Rejections []Rejection
+	// SkippedXattrs are the extended attributes of extracted entries that could not be restored,
+	// e.g., file capabilities without the privileges to set them.
+	SkippedXattrs []Rejection
+	// IgnoredXattrs is the number of extended attributes of extracted entries that were not restored
+	// as they don't match ExtractOptions.Xattrs.
+	IgnoredXattrs int
+}
+
+// Complete returns true if all entries were extracted with all of their extended attributes.
+func (r ExtractReport) Complete() bool {
+	return len(r.Rejections) == 0 && len(r.SkippedXattrs) == 0 && r.IgnoredXattrs == 0
+}
+
+// Rejection is an entry of an archive that was not extracted.
+type Rejection struct {
+	Path   string
+	Reason string
+}
+
+func (r Rejection) String() string {
+	return fmt.Sprintf("'%s': %s", r.Path, r.Reason)
+}
+
+// RejectionError is returned when entries of an archive were rejected, unless the extraction is lenient.
+type RejectionError struct {
+	Root       string
+	Rejections []Rejection
+}
+
+const maxReportedRejections = 10
+
+func (e *RejectionError) Error() string {
+	var reported []string
+	for i, rejection := range e.Rejections {
+		if i == maxReportedRejections {
+			reported = append(reported, fmt.Sprintf("and %d more", len(e.Rejections)-maxReportedRejections))
+			break
+		}
+		reported = append(reported, rejection.String())
+	}
+	return fmt.Sprintf("rejected %d entries that are unsafe to extract within '%s': %s", len(e.Rejections), e.Root, strings.Join(reported, ", "))
+}
+
 // Extract reads all entries from TarReader and extracts them to the filesystem.
 func Extract(tr TarReader) error {
+	_, err := ExtractWithOptions(tr, ExtractOptions{})
+	return err
+}
+
+// ExtractWithOptions reads all entries from TarReader and extracts them to the filesystem.
+// If a root is provided, entries that would be written outside of the root are rejected, including entries
+// with paths that escape the root, entries that would be written through a symlink that resolves outside of the root
+// (e.g., a symlink extracted from the same archive), and hard links. Directories that are parents of the root are allowed.
+// Symlinks may point anywhere, as they are never followed when writing entries outside of the root.
+// Rejected entries are skipped and reported; unless the extraction is lenient, a *RejectionError is returned
+// once all other entries are extracted.
+func ExtractWithOptions(tr TarReader, opts ExtractOptions) (ExtractReport, error) {
 	setUmaskIfNeeded()
 	defer unsetUmaskIfNeeded()
 
 	buf := make([]byte, 32*32*1024)
 	dirsFound := make(map[string]bool)
 
+	var (
+		guard  *rootGuard
+		report ExtractReport
+	)
+	if opts.Root != "" {
+		var err error
+		if guard, err = newRootGuard(opts.Root); err != nil {
+			return report, err
+		}
+	}
+
 	var pathModes []PathMode
 	for {
 		hdr, err := tr.Next()
 		if err == io.EOF {
 			for _, pathMode := range pathModes { // directories that are newly created and for which there is a header in the tar should have the right permissions
 				if err := os.Chmod(pathMode.Path, pathMode.Mode); err != nil {
-					return err
+					return report, err
 				}
 			}
-			return nil
+			if len(report.Rejections) > 0 && !opts.Lenient {
+				return report, &RejectionError{Root: guard.root, Rejections: report.Rejections}
+			}
+			return report, nil
 		}
 		if err != nil {
-			return errors.Wrap(err, "error extracting from archive")
+			return report, errors.Wrap(err, "error extracting from archive")
+		}
+
+		if guard != nil {
+			if reason := guard.check(hdr); reason != "" {
+				report.Rejections = append(report.Rejections, Rejection{Path: hdr.Name, Reason: reason})
+				continue
+			}
 		}
 
 		switch hdr.Typeflag {
@@ -70,33 +162,36 @@ func Extract(tr TarReader) error {
 				pathModes = append(pathModes, pathMode)
 			}
 			if err := os.MkdirAll(hdr.Name, os.ModePerm); err != nil {
-				return errors.Wrapf(err, "failed to create directory %q", hdr.Name)
+				return report, errors.Wrapf(err, "failed to create directory %q", hdr.Name)
 			}
 			dirsFound[hdr.Name] = true
+			report.restoreXattrs(hdr, opts.Xattrs)
 
 		case tar.TypeReg:
 			dirPath := filepath.Dir(hdr.Name)
 			if !dirsFound[dirPath] {
 				if _, err := os.Stat(dirPath); os.IsNotExist(err) {
 					if err := os.MkdirAll(dirPath, applyUmask(os.ModePerm, originalUmask)); err != nil { // if there is no header for the parent directory in the tar, apply the provided umask
-						return errors.Wrapf(err, "failed to create parent dir %q for file %q", dirPath, hdr.Name)
+						return report, errors.Wrapf(err, "failed to create parent dir %q for file %q", dirPath, hdr.Name)
 					}
 					dirsFound[dirPath] = true
 				}
 			}
 
 			if err := writeFile(tr, hdr.Name, hdr.FileInfo().Mode(), buf); err != nil {
-				return errors.Wrapf(err, "failed to write file %q", hdr.Name)
+				return report, errors.Wrapf(err, "failed to write file %q", hdr.Name)
 			}
+			// extended attributes are restored once the file is written, as writing a file removes its capabilities
+			report.restoreXattrs(hdr, opts.Xattrs)
 		case tar.TypeSymlink:
 			if err := createSymlink(hdr); err != nil {
-				return errors.Wrapf(err, "failed to create symlink %q with target %q", hdr.Name, hdr.Linkname)
+				return report, errors.Wrapf(err, "failed to create symlink %q with target %q", hdr.Name, hdr.Linkname)
 			}
 		case tar.TypeXGlobalHeader:
 			// ignore PAX Global Extended Headers
 			continue
 		default:
-			return fmt.Errorf("unknown file type in tar %d", hdr.Typeflag)
+			return report, fmt.Errorf("unknown file type in tar %d", hdr.Typeflag)
 		}
 	}
 }
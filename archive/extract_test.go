@@ -2,6 +2,7 @@ package archive_test
 
 import (
 	"archive/tar"
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -118,6 +119,121 @@ func testExtract(t *testing.T, when spec.G, it spec.S) {
 			}
 		})
 	})
+
+	when("#ExtractWithOptions", func() {
+		var (
+			baseDir, rootDir, outsideDir string
+			ftr                          *fakeTarReader
+			subject                      *archive.NormalizingTarReader
+		)
+
+		it.Before(func() {
+			baseDir = t.TempDir()
+			rootDir = filepath.Join(baseDir, "root")
+			outsideDir = filepath.Join(baseDir, "outside")
+			h.AssertNil(t, os.Mkdir(rootDir, 0755))
+			h.AssertNil(t, os.Mkdir(outsideDir, 0755))
+			ftr = &fakeTarReader{}
+			subject = archive.NewNormalizingTarReader(ftr)
+			subject.PrependDir(baseDir)
+		})
+
+		// push pushes the headers so that they are read in the provided order
+		push := func(hdrs ...*tar.Header) {
+			for i := len(hdrs) - 1; i >= 0; i-- {
+				ftr.pushHeader(hdrs[i])
+			}
+		}
+
+		it("extracts the entries within the root and its parents", func() {
+			push(
+				&tar.Header{Name: ".", Typeflag: tar.TypeDir, Mode: 0755},
+				&tar.Header{Name: "root", Typeflag: tar.TypeDir, Mode: 0755},
+				&tar.Header{Name: "root/dir/file", Typeflag: tar.TypeReg, Mode: 0644},
+			)
+
+			report, err := archive.ExtractWithOptions(subject, archive.ExtractOptions{Root: rootDir})
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(report.Rejections), 0)
+			h.AssertPathExists(t, filepath.Join(rootDir, "dir", "file"))
+		})
+
+		when("entries are unsafe", func() {
+			it.Before(func() {
+				push(
+					&tar.Header{Name: "root/../outside/traversal", Typeflag: tar.TypeReg, Mode: 0644},
+					&tar.Header{Name: "root/hardlink", Typeflag: tar.TypeLink, Linkname: "outside/file"},
+					&tar.Header{Name: "root/file", Typeflag: tar.TypeReg, Mode: 0644},
+				)
+			})
+
+			it("fails with a report of the rejected entries after extracting the others", func() {
+				report, err := archive.ExtractWithOptions(subject, archive.ExtractOptions{Root: rootDir})
+				h.AssertError(t, err, "rejected 2 entries that are unsafe to extract within")
+				var rejectionErr *archive.RejectionError
+				h.AssertEq(t, errors.As(err, &rejectionErr), true)
+				h.AssertEq(t, rejectionErr.Rejections, report.Rejections)
+				h.AssertEq(t, report.Rejections, []archive.Rejection{
+					{Path: filepath.Join(outsideDir, "traversal"), Reason: "path is outside of the extraction root"},
+					{Path: filepath.Join(rootDir, "hardlink"), Reason: "hard links are not allowed"},
+				})
+				h.AssertPathDoesNotExist(t, filepath.Join(outsideDir, "traversal"))
+				h.AssertPathDoesNotExist(t, filepath.Join(rootDir, "hardlink"))
+				h.AssertPathExists(t, filepath.Join(rootDir, "file"))
+			})
+
+			when("lenient", func() {
+				it("skips the rejected entries", func() {
+					report, err := archive.ExtractWithOptions(subject, archive.ExtractOptions{Root: rootDir, Lenient: true})
+					h.AssertNil(t, err)
+					h.AssertEq(t, len(report.Rejections), 2)
+					h.AssertPathDoesNotExist(t, filepath.Join(outsideDir, "traversal"))
+					h.AssertPathExists(t, filepath.Join(rootDir, "file"))
+				})
+			})
+		})
+
+		when("symlinks point outside of the root", func() {
+			it.Before(func() {
+				h.SkipIf(t, runtime.GOOS == "windows", "creating symlinks requires privileges on windows")
+				h.Mkfile(t, "original", filepath.Join(outsideDir, "file"))
+			})
+
+			it("rejects entries that would be written through the symlinks", func() {
+				push(
+					&tar.Header{Name: "root/dir-link", Typeflag: tar.TypeSymlink, Linkname: outsideDir},
+					&tar.Header{Name: "root/dir-link/file", Typeflag: tar.TypeReg, Mode: 0644},
+					&tar.Header{Name: "root/file-link", Typeflag: tar.TypeSymlink, Linkname: "../outside/file"},
+					&tar.Header{Name: "root/file-link", Typeflag: tar.TypeReg, Mode: 0644},
+					&tar.Header{Name: "root/dangling-link", Typeflag: tar.TypeSymlink, Linkname: "../outside/new-file"},
+					&tar.Header{Name: "root/dangling-link", Typeflag: tar.TypeReg, Mode: 0644},
+				)
+
+				report, err := archive.ExtractWithOptions(subject, archive.ExtractOptions{Root: rootDir})
+				h.AssertNotNil(t, err)
+				h.AssertEq(t, len(report.Rejections), 3)
+				h.AssertStringContains(t, report.Rejections[0].Reason, "outside of the extraction root")
+				h.AssertStringContains(t, report.Rejections[1].Reason, "outside of the extraction root")
+				h.AssertStringContains(t, report.Rejections[2].Reason, "cannot be resolved")
+
+				contents, err := os.ReadFile(filepath.Join(outsideDir, "file"))
+				h.AssertNil(t, err)
+				h.AssertEq(t, string(contents), "original")
+				h.AssertPathDoesNotExist(t, filepath.Join(outsideDir, "new-file"))
+			})
+
+			it("extracts the symlinks", func() {
+				push(&tar.Header{Name: "root/dir-link", Typeflag: tar.TypeSymlink, Linkname: outsideDir})
+
+				report, err := archive.ExtractWithOptions(subject, archive.ExtractOptions{Root: rootDir})
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(report.Rejections), 0)
+				target, err := os.Readlink(filepath.Join(rootDir, "dir-link"))
+				h.AssertNil(t, err)
+				h.AssertEq(t, target, outsideDir)
+			})
+		})
+	})
 }
 
 func newFakeTarReader(t *testing.T) (*archive.NormalizingTarReader, string) {
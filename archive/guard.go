@@ -0,0 +1,103 @@
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// rootGuard rejects the entries of an archive that would be written outside of a root directory.
+type rootGuard struct {
+	root         string
+	resolvedRoot string
+	// safeDirs are existing directories (not symlinks) that resolve within the root;
+	// as extracting never replaces a directory with a symlink, they don't need to be checked again.
+	safeDirs map[string]bool
+}
+
+func newRootGuard(root string) (*rootGuard, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving extraction root %q", root)
+	}
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "resolving extraction root %q", root)
+		}
+		resolvedRoot = root
+	}
+	return &rootGuard{root: root, resolvedRoot: resolvedRoot, safeDirs: make(map[string]bool)}, nil
+}
+
+// check returns the reason the entry is rejected, or an empty string if it can be extracted.
+func (g *rootGuard) check(hdr *tar.Header) string {
+	if hdr.Typeflag == tar.TypeXGlobalHeader {
+		return ""
+	}
+	if hdr.Typeflag == tar.TypeLink {
+		return "hard links are not allowed"
+	}
+	path, err := filepath.Abs(hdr.Name)
+	if err != nil {
+		return fmt.Sprintf("path cannot be resolved: %s", err)
+	}
+	if !isWithin(g.root, path) {
+		if hdr.Typeflag == tar.TypeDir && isWithin(path, g.root) {
+			return "" // the parents of the root are included in layers
+		}
+		return "path is outside of the extraction root"
+	}
+	if hdr.Typeflag == tar.TypeSymlink {
+		// the symlink itself is created in its parent directory, without following anything it would replace
+		path = filepath.Dir(path)
+	}
+	return g.checkResolved(path)
+}
+
+// checkResolved returns the reason writing to path is rejected if path, or its closest existing parent,
+// resolves outside of the root.
+func (g *rootGuard) checkResolved(path string) string {
+	existing := path
+	var fi os.FileInfo
+	for {
+		if g.safeDirs[existing] {
+			return ""
+		}
+		var err error
+		if fi, err = os.Lstat(existing); err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Sprintf("path cannot be resolved: %s", err)
+		}
+		if !isWithin(g.root, existing) {
+			return "" // the root doesn't exist yet, so its contents will be new directories
+		}
+		existing = filepath.Dir(existing)
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return fmt.Sprintf("would be written through '%s', which cannot be resolved", existing)
+	}
+	if !isWithin(g.resolvedRoot, resolved) {
+		return fmt.Sprintf("would be written through '%s', which resolves to '%s' outside of the extraction root", existing, resolved)
+	}
+	if fi.IsDir() {
+		g.safeDirs[existing] = true
+	}
+	return ""
+}
+
+// isWithin returns true if path is dir or one of its descendants; both must be clean, absolute paths.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
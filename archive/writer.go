@@ -19,11 +19,12 @@ type TarWriter interface {
 }
 
 // NormalizingTarWriter normalizes any written *tar.Header before passing it through to the wrapped TarWriter
-// NormalizingTarWriter always normalizes ModTime, Uname, and Gname
+// NormalizingTarWriter always normalizes ModTime, Uname, and Gname, and removes extended attributes
 // Other modifications can be enabled by invoking options on the NormalizingTarWriter
 type NormalizingTarWriter struct {
 	TarWriter
 	headerOpts []HeaderOpt
+	xattrs     []string
 }
 
 type HeaderOpt func(header *tar.Header) *tar.Header
@@ -52,18 +53,25 @@ func (tw *NormalizingTarWriter) WithModTime(modTime time.Time) {
 	})
 }
 
+// WithXattrs preserves the extended attributes of any subsequently written *tar.Header (and of the files added with AddFileToArchive)
+// whose names start with one of the prefixes, e.g., "security.capability" for file capabilities
+func (tw *NormalizingTarWriter) WithXattrs(prefixes []string) {
+	tw.xattrs = prefixes
+}
+
 // NewNormalizingTarWriter creates a NormalizingTarWriter that wraps the provided TarWriter
 func NewNormalizingTarWriter(tw TarWriter) *NormalizingTarWriter {
-	return &NormalizingTarWriter{tw, []HeaderOpt{}}
+	return &NormalizingTarWriter{TarWriter: tw, headerOpts: []HeaderOpt{}}
 }
 
 // WriteHeader writes the header to the wrapped TarWriter after applying standard and configured modifications
 // Modification options will be apply in the order the options were invoked.
-// Standard modification (ModTime, Uname, and Gname) are applied last.
+// Standard modification (ModTime, Uname, Gname, and extended attributes) are applied last.
 func (tw *NormalizingTarWriter) WriteHeader(hdr *tar.Header) error {
 	for _, opt := range tw.headerOpts {
 		hdr = opt(hdr)
 	}
+	filterXattrs(hdr, tw.xattrs)
 	hdr.Name = filepath.ToSlash(strings.TrimPrefix(hdr.Name, filepath.VolumeName(hdr.Name)))
 	hdr.Uname = ""
 	hdr.Gname = ""
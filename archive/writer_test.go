@@ -84,6 +84,32 @@ func testNormalizingTarWriter(t *testing.T, when spec.G, it spec.S) {
 				}
 			})
 		})
+
+		when("#WithXattrs", func() {
+			var hdr *tar.Header
+
+			it.Before(func() {
+				hdr = &tar.Header{PAXRecords: map[string]string{
+					"SCHILY.xattr.security.capability": "some-capabilities",
+					"SCHILY.xattr.user.some-attr":      "some-value",
+					"some-other-record":                "some-other-value",
+				}}
+			})
+
+			it("removes extended attributes by default", func() {
+				h.AssertNil(t, ntw.WriteHeader(hdr))
+				h.AssertEq(t, ftw.getLastHeader().PAXRecords, map[string]string{"some-other-record": "some-other-value"})
+			})
+
+			it("preserves the extended attributes with the prefixes", func() {
+				ntw.WithXattrs([]string{"security.capability"})
+				h.AssertNil(t, ntw.WriteHeader(hdr))
+				h.AssertEq(t, ftw.getLastHeader().PAXRecords, map[string]string{
+					"SCHILY.xattr.security.capability": "some-capabilities",
+					"some-other-record":                "some-other-value",
+				})
+			})
+		})
 	})
 }
 
@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// xattrPAXPrefix is the prefix of the PAX records with the extended attributes of an entry, as written by GNU tar and go's archive/tar.
+const xattrPAXPrefix = "SCHILY.xattr."
+
+// matchesXattr returns true if the extended attribute name starts with one of the prefixes.
+func matchesXattr(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// addXattrs adds the extended attributes of the file at path that match the prefixes to the PAX records of the header.
+func addXattrs(hdr *tar.Header, path string, prefixes []string) error {
+	xattrs, err := readXattrs(path, prefixes)
+	if err != nil {
+		return fmt.Errorf("reading extended attributes of %s: %w", path, err)
+	}
+	if len(xattrs) == 0 {
+		return nil
+	}
+	if hdr.PAXRecords == nil {
+		hdr.PAXRecords = map[string]string{}
+	}
+	for name, value := range xattrs {
+		hdr.PAXRecords[xattrPAXPrefix+name] = value
+	}
+	return nil
+}
+
+// filterXattrs removes the extended attributes that don't match the prefixes from the PAX records of the header.
+func filterXattrs(hdr *tar.Header, prefixes []string) {
+	for key := range hdr.PAXRecords {
+		if strings.HasPrefix(key, xattrPAXPrefix) && !matchesXattr(strings.TrimPrefix(key, xattrPAXPrefix), prefixes) {
+			delete(hdr.PAXRecords, key)
+		}
+	}
+	hdr.Xattrs = nil //nolint:staticcheck // deprecated in favor of PAXRecords, which is what is written
+}
+
+// restoreXattrs sets the extended attributes of the extracted entry that match the prefixes,
+// reporting those that could not be set or that don't match.
+func (r *ExtractReport) restoreXattrs(hdr *tar.Header, prefixes []string) {
+	var names []string
+	for key := range hdr.PAXRecords {
+		name := strings.TrimPrefix(key, xattrPAXPrefix)
+		if name == key {
+			continue
+		}
+		if !matchesXattr(name, prefixes) {
+			r.IgnoredXattrs++
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := setXattr(hdr.Name, name, hdr.PAXRecords[xattrPAXPrefix+name]); err != nil {
+			r.SkippedXattrs = append(r.SkippedXattrs, Rejection{
+				Path:   hdr.Name,
+				Reason: fmt.Sprintf("extended attribute '%s' could not be restored: %s", name, err),
+			})
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/sys/unix"
+
+	"github.com/buildpacks/lifecycle/archive"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestXattrs(t *testing.T) {
+	spec.Run(t, "xattrs", testXattrs, spec.Report(report.Terminal{}))
+}
+
+func testXattrs(t *testing.T, when spec.G, it spec.S) {
+	var srcDir, destDir string
+
+	it.Before(func() {
+		srcDir = t.TempDir()
+		destDir = t.TempDir()
+		h.Mkfile(t, "some-contents", filepath.Join(srcDir, "file"))
+		err := unix.Setxattr(filepath.Join(srcDir, "file"), "user.some-attr", []byte("some-value"), 0)
+		h.SkipIf(t, err != nil, "extended attributes are not supported by the temp dir")
+		h.AssertNil(t, unix.Setxattr(filepath.Join(srcDir, "file"), "user.other-attr", []byte("other-value"), 0))
+	})
+
+	// roundTrip archives the file with the extended attributes to preserve, and extracts it with those to restore
+	roundTrip := func(preserve, restore []string) archive.ExtractReport {
+		t.Helper()
+		var buf bytes.Buffer
+		tw := archive.NewNormalizingTarWriter(tar.NewWriter(&buf))
+		tw.WithXattrs(preserve)
+		fi, err := os.Stat(filepath.Join(srcDir, "file"))
+		h.AssertNil(t, err)
+		h.AssertNil(t, archive.AddFileToArchive(tw, filepath.Join(srcDir, "file"), fi))
+		h.AssertNil(t, tw.Close())
+
+		tr := archive.NewNormalizingTarReader(tar.NewReader(&buf))
+		tr.Strip(filepath.ToSlash(srcDir))
+		tr.PrependDir(destDir)
+		extractReport, err := archive.ExtractWithOptions(tr, archive.ExtractOptions{Xattrs: restore})
+		h.AssertNil(t, err)
+		return extractReport
+	}
+
+	getXattr := func(name string) (string, bool) {
+		t.Helper()
+		buf := make([]byte, 64)
+		size, err := unix.Getxattr(filepath.Join(destDir, "file"), name, buf)
+		if err != nil {
+			return "", false
+		}
+		return string(buf[:size]), true
+	}
+
+	it("preserves and restores the extended attributes with the prefixes", func() {
+		extractReport := roundTrip([]string{"user.some"}, []string{"user."})
+		h.AssertEq(t, extractReport.Complete(), true)
+		value, ok := getXattr("user.some-attr")
+		h.AssertEq(t, ok, true)
+		h.AssertEq(t, value, "some-value")
+		_, ok = getXattr("user.other-attr")
+		h.AssertEq(t, ok, false)
+	})
+
+	it("does not preserve extended attributes by default", func() {
+		extractReport := roundTrip(nil, []string{"user."})
+		h.AssertEq(t, extractReport.Complete(), true)
+		_, ok := getXattr("user.some-attr")
+		h.AssertEq(t, ok, false)
+	})
+
+	it("reports the extended attributes that are not restored", func() {
+		extractReport := roundTrip([]string{"user."}, []string{"user.some"})
+		h.AssertEq(t, extractReport.Complete(), false)
+		h.AssertEq(t, extractReport.IgnoredXattrs, 1)
+		_, ok := getXattr("user.other-attr")
+		h.AssertEq(t, ok, false)
+	})
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package archive
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func readXattrs(_ string, _ []string) (map[string]string, error) {
+	return nil, nil
+}
+
+func setXattr(_, _, _ string) error {
+	return fmt.Errorf("extended attributes are not supported on %s", runtime.GOOS)
+}
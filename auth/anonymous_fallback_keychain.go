@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// NewAnonymousFallbackKeychain returns a keychain that resolves credentials from the given keychain,
+// except for the given registries and registries that have fallen back to anonymous access (see FallBack).
+func NewAnonymousFallbackKeychain(keychain authn.Keychain, registries ...string) *AnonymousFallbackKeychain {
+	k := &AnonymousFallbackKeychain{
+		Keychain:   keychain,
+		registries: map[string]bool{},
+	}
+	for _, registry := range registries {
+		k.registries[registry] = true
+	}
+	return k
+}
+
+// AnonymousFallbackKeychain is an implementation of authn.Keychain that returns anonymous credentials for registries
+// that rejected the credentials from the wrapped keychain, e.g., when pulling public images with invalid credentials.
+type AnonymousFallbackKeychain struct {
+	Keychain authn.Keychain
+
+	mu         sync.Mutex
+	registries map[string]bool
+}
+
+func (k *AnonymousFallbackKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	k.mu.Lock()
+	fellBack := k.registries[resource.RegistryStr()]
+	k.mu.Unlock()
+	if fellBack {
+		return authn.Anonymous, nil
+	}
+	return k.Keychain.Resolve(resource)
+}
+
+// FallBack configures the keychain to return anonymous credentials for the given registry.
+func (k *AnonymousFallbackKeychain) FallBack(registry string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.registries == nil {
+		k.registries = map[string]bool{}
+	}
+	k.registries[registry] = true
+}
+
+// Registries returns the registries that fell back to anonymous access, in sorted order.
+func (k *AnonymousFallbackKeychain) Registries() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var registries []string
+	for registry := range k.registries {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+	return registries
+}
@@ -0,0 +1,73 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/auth"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestAnonymousFallbackKeychain(t *testing.T) {
+	spec.Run(t, "AnonymousFallbackKeychain", testAnonymousFallbackKeychain, spec.Report(report.Terminal{}))
+}
+
+func testAnonymousFallbackKeychain(t *testing.T, when spec.G, it spec.S) {
+	var keychain *auth.AnonymousFallbackKeychain
+
+	it.Before(func() {
+		keychain = auth.NewAnonymousFallbackKeychain(&FakeKeychain{authMap: map[string]*authn.AuthConfig{
+			"some-registry.io":  {Username: "some-user", Password: "some-password"},
+			"other-registry.io": {Username: "other-user", Password: "other-password"},
+		}})
+	})
+
+	resolve := func(registry string) authn.Authenticator {
+		t.Helper()
+		reg, err := name.NewRegistry(registry)
+		h.AssertNil(t, err)
+		authenticator, err := keychain.Resolve(reg)
+		h.AssertNil(t, err)
+		return authenticator
+	}
+
+	when("#Resolve", func() {
+		it("returns anonymous credentials only for the registries that fell back", func() {
+			keychain.FallBack("some-registry.io")
+
+			h.AssertEq(t, resolve("some-registry.io"), authn.Anonymous)
+			config, err := resolve("other-registry.io").Authorization()
+			h.AssertNil(t, err)
+			h.AssertEq(t, config.Username, "other-user")
+		})
+	})
+
+	when("registries are provided", func() {
+		it.Before(func() {
+			keychain = auth.NewAnonymousFallbackKeychain(&FakeKeychain{authMap: map[string]*authn.AuthConfig{
+				"some-registry.io": {Username: "some-user", Password: "some-password"},
+			}}, "some-registry.io")
+		})
+
+		it("returns anonymous credentials for them", func() {
+			h.AssertEq(t, resolve("some-registry.io"), authn.Anonymous)
+			h.AssertEq(t, keychain.Registries(), []string{"some-registry.io"})
+		})
+	})
+
+	when("#Registries", func() {
+		it("returns the registries that fell back in sorted order", func() {
+			h.AssertEq(t, keychain.Registries(), []string(nil))
+
+			keychain.FallBack("some-registry.io")
+			keychain.FallBack("other-registry.io")
+			keychain.FallBack("some-registry.io")
+
+			h.AssertEq(t, keychain.Registries(), []string{"other-registry.io", "some-registry.io"})
+		})
+	})
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/pkg/errors"
+)
+
+// EnvRegistryCredentialHelpers is a JSON object that maps OCI registry hostnames to docker credential helpers,
+// e.g., {"gcr.io": "gcloud"} to run docker-credential-gcloud for gcr.io, in addition to any helpers configured in the docker config.json file.
+const EnvRegistryCredentialHelpers = "CNB_REGISTRY_CREDENTIAL_HELPERS"
+
+// NewHelperKeychain returns an authn.Keychain that uses the credential helpers declared in the provided environment variable.
+func NewHelperKeychain(envVar string) (authn.Keychain, error) {
+	helpers := map[string]string{}
+	if env := os.Getenv(envVar); env != "" {
+		if err := json.Unmarshal([]byte(env), &helpers); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s value", envVar)
+		}
+	}
+	return &HelperKeychain{Helpers: helpers}, nil
+}
+
+// HelperKeychain is an implementation of authn.Keychain that runs a docker credential helper (docker-credential-<name>)
+// for each registry with a configured helper.
+type HelperKeychain struct {
+	// Helpers maps registry hostnames to credential helper names.
+	Helpers map[string]string
+}
+
+func (k *HelperKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	helper, ok := k.Helpers[resource.RegistryStr()]
+	if !ok {
+		return authn.Anonymous, nil
+	}
+	return authn.NewKeychainFromHelper(&execHelper{name: helper}).Resolve(resource)
+}
+
+// execHelper implements the "get" command of the docker credential helper protocol.
+type execHelper struct {
+	name string
+}
+
+func (h *execHelper) Get(serverURL string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker-credential-"+h.name, "get") // #nosec G204
+	cmd.Stdin = strings.NewReader(serverURL)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", "", errors.Wrapf(err, "running credential helper '%s': %s", h.name, strings.TrimSpace(stderr.String()))
+	}
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return "", "", errors.Wrapf(err, "parsing output of credential helper '%s'", h.name)
+	}
+	return creds.Username, creds.Secret, nil
+}
@@ -0,0 +1,101 @@
+package auth_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/auth"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestHelperKeychain(t *testing.T) {
+	spec.Run(t, "HelperKeychain", testHelperKeychain, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testHelperKeychain(t *testing.T, when spec.G, it spec.S) {
+	when("#NewHelperKeychain", func() {
+		it.After(func() {
+			h.AssertNil(t, os.Unsetenv("CNB_REGISTRY_CREDENTIAL_HELPERS"))
+		})
+
+		it("reads the helpers from the environment variable", func() {
+			h.AssertNil(t, os.Setenv("CNB_REGISTRY_CREDENTIAL_HELPERS", `{"gcr.io": "gcloud"}`))
+
+			keychain, err := auth.NewHelperKeychain("CNB_REGISTRY_CREDENTIAL_HELPERS")
+			h.AssertNil(t, err)
+			h.AssertEq(t, keychain, &auth.HelperKeychain{Helpers: map[string]string{"gcr.io": "gcloud"}})
+		})
+
+		it("errors for invalid values", func() {
+			h.AssertNil(t, os.Setenv("CNB_REGISTRY_CREDENTIAL_HELPERS", `not-json`))
+
+			_, err := auth.NewHelperKeychain("CNB_REGISTRY_CREDENTIAL_HELPERS")
+			h.AssertError(t, err, "failed to parse CNB_REGISTRY_CREDENTIAL_HELPERS value")
+		})
+	})
+
+	when("HelperKeychain", func() {
+		when("#Resolve", func() {
+			var (
+				keychain *auth.HelperKeychain
+				origPath = os.Getenv("PATH")
+			)
+
+			it.Before(func() {
+				if runtime.GOOS == "windows" {
+					t.Skip("credential helper scripts are not supported on windows")
+				}
+				binDir := t.TempDir()
+				script := "#!/usr/bin/env bash\n" +
+					`[[ "$1" == "get" && "$(cat)" == "some-registry.io" ]] || exit 1` + "\n" +
+					`echo '{"ServerURL": "some-registry.io", "Username": "some-user", "Secret": "some-secret"}'` + "\n"
+				h.AssertNil(t, os.WriteFile(filepath.Join(binDir, "docker-credential-some-helper"), []byte(script), 0755)) // #nosec G306
+				h.AssertNil(t, os.Setenv("PATH", binDir+string(os.PathListSeparator)+origPath))
+				keychain = &auth.HelperKeychain{Helpers: map[string]string{
+					"some-registry.io":  "some-helper",
+					"other-registry.io": "missing-helper",
+				}}
+			})
+
+			it.After(func() {
+				h.AssertNil(t, os.Setenv("PATH", origPath))
+			})
+
+			it("returns the credentials from the helper configured for the registry", func() {
+				registry, err := name.NewRegistry("some-registry.io")
+				h.AssertNil(t, err)
+
+				authenticator, err := keychain.Resolve(registry)
+				h.AssertNil(t, err)
+				config, err := authenticator.Authorization()
+				h.AssertNil(t, err)
+				h.AssertEq(t, config, &authn.AuthConfig{Username: "some-user", Password: "some-secret"})
+			})
+
+			it("returns anonymous if the helper fails", func() {
+				registry, err := name.NewRegistry("other-registry.io")
+				h.AssertNil(t, err)
+
+				authenticator, err := keychain.Resolve(registry)
+				h.AssertNil(t, err)
+				h.AssertEq(t, authenticator, authn.Anonymous)
+			})
+
+			it("returns anonymous if no helper is configured for the registry", func() {
+				registry, err := name.NewRegistry("unknown-registry.io")
+				h.AssertNil(t, err)
+
+				authenticator, err := keychain.Resolve(registry)
+				h.AssertNil(t, err)
+				h.AssertEq(t, authenticator, authn.Anonymous)
+			})
+		})
+	})
+}
@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 
 	ecr "github.com/awslabs/amazon-ecr-credential-helper/ecr-login"
 	"github.com/chrismellard/docker-credential-acr-env/pkg/credhelper"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/google"
 	"github.com/pkg/errors"
 )
 
@@ -25,20 +27,36 @@ var (
 // DefaultKeychain returns a keychain containing authentication configuration for the given images
 // from the following sources, if they exist, in order of precedence:
 // the provided environment variable
+// keychains registered by the platform with RegisterKeychain
+// credential helpers declared in the CNB_REGISTRY_CREDENTIAL_HELPERS environment variable
+// docker config files in the CNB_REGISTRY_SECRET_DIRS directories (e.g., mounted Kubernetes secrets), which are re-read when they change,
+// and skipped with a warning (see SetKeychainLogger) if they cannot be read
 // the docker config.json file
-// credential helpers for Amazon and Azure
+// credential helpers for Amazon, Azure and Google
+// Credentials from registered keychains and credential helpers are resolved again when they may have expired.
 func DefaultKeychain(images ...string) (authn.Keychain, error) {
 	envKeychain, err := NewEnvKeychain(EnvRegistryAuth)
 	if err != nil {
 		return nil, err
 	}
+	helperKeychain, err := NewHelperKeychain(EnvRegistryCredentialHelpers)
+	if err != nil {
+		return nil, err
+	}
 
-	return authn.NewMultiKeychain(
-		envKeychain,
+	keychains := []authn.Keychain{envKeychain}
+	for _, keychain := range registeredKeychains() {
+		keychains = append(keychains, NewRefreshingKeychain(keychain, images...))
+	}
+	keychains = append(keychains,
+		NewRefreshingKeychain(helperKeychain, images...),
+		&SecretDirKeychain{Dirs: filepath.SplitList(os.Getenv(EnvRegistrySecretDirs)), Logger: keychainLogger()},
 		NewResolvedKeychain(authn.DefaultKeychain, images...),
-		NewResolvedKeychain(amazonKeychain, images...),
-		NewResolvedKeychain(azureKeychain, images...),
-	), nil
+		NewRefreshingKeychain(amazonKeychain, images...),
+		NewRefreshingKeychain(azureKeychain, images...),
+		NewRefreshingKeychain(google.Keychain, images...),
+	)
+	return authn.NewMultiKeychain(keychains...), nil
 }
 
 // NewEnvKeychain returns an authn.Keychain that uses the provided environment variable as a source of credentials.
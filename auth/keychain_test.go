@@ -438,9 +438,11 @@ func testEnvKeychain(t *testing.T, when spec.G, it spec.S) {
 type FakeKeychain struct {
 	authMap           map[string]*authn.AuthConfig
 	returnsForResolve error // if set, return the error
+	resolveCount      int
 }
 
 func (f *FakeKeychain) Resolve(r authn.Resource) (authn.Authenticator, error) {
+	f.resolveCount++
 	if f.returnsForResolve != nil {
 		return nil, f.returnsForResolve
 	}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// DefaultRefreshInterval is how long credentials from a RefreshingKeychain are used before they are resolved again.
+// It is shorter than the lifetime of ECR (12 hours), ACR (3 hours) and GCR (1 hour) tokens.
+const DefaultRefreshInterval = 30 * time.Minute
+
+// NewRefreshingKeychain resolves credentials for the given images from the given keychain, like NewResolvedKeychain,
+// but resolves them again once they are older than DefaultRefreshInterval, so that builds that outlive short-lived registry tokens
+// can still push images. If the credentials cannot be resolved again (e.g., the credential store is no longer accessible),
+// the previously resolved credentials are used.
+// Registries without credentials are remembered and not resolved again.
+func NewRefreshingKeychain(keychain authn.Keychain, images ...string) *RefreshingKeychain {
+	k := &RefreshingKeychain{
+		Keychain: keychain,
+		Interval: DefaultRefreshInterval,
+		entries:  map[string]refreshingEntry{},
+	}
+	now := time.Now()
+	configs := buildAuthConfigs(keychain, images...)
+	for _, image := range images {
+		ref, err := name.ParseReference(image, name.WeakValidation)
+		if err != nil {
+			continue
+		}
+		registry := ref.Context().Registry.Name()
+		if config, ok := configs[registry]; ok {
+			k.entries[registry] = refreshingEntry{config: config, resolvedAt: now}
+		} else {
+			k.entries[registry] = refreshingEntry{} // no credentials
+		}
+	}
+	return k
+}
+
+// RefreshingKeychain is an implementation of authn.Keychain that caches credentials for Interval.
+type RefreshingKeychain struct {
+	Keychain authn.Keychain
+	Interval time.Duration
+
+	mu      sync.Mutex
+	entries map[string]refreshingEntry
+}
+
+// refreshingEntry holds the credentials for a registry, or nil if the registry has no credentials.
+// Only entries with credentials expire.
+type refreshingEntry struct {
+	config     *authn.AuthConfig
+	resolvedAt time.Time
+}
+
+func (e refreshingEntry) expired(interval time.Duration) bool {
+	return e.config != nil && time.Since(e.resolvedAt) >= interval
+}
+
+// Resolve returns an authenticator for the registry of the resource, or authn.Anonymous if there are no credentials for it.
+// The authenticator returns the current credentials each time it is used, so that a long-running push that needs to
+// exchange credentials for a new registry token (e.g., after the previous token expired) uses refreshed credentials.
+func (k *RefreshingKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	if k.authConfig(resource) == nil {
+		return authn.Anonymous, nil
+	}
+	return &refreshingAuth{keychain: k, resource: resource}, nil
+}
+
+func (k *RefreshingKeychain) authConfig(resource authn.Resource) *authn.AuthConfig {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.entries == nil {
+		k.entries = map[string]refreshingEntry{}
+	}
+	registry := resource.RegistryStr()
+	entry, ok := k.entries[registry]
+	if ok && !entry.expired(k.Interval) {
+		return entry.config
+	}
+	if config := k.resolve(resource); config != nil {
+		k.entries[registry] = refreshingEntry{config: config, resolvedAt: time.Now()}
+		return config
+	}
+	if ok {
+		return entry.config
+	}
+	k.entries[registry] = refreshingEntry{} // no credentials
+	return nil
+}
+
+func (k *RefreshingKeychain) resolve(resource authn.Resource) *authn.AuthConfig {
+	authenticator, err := k.Keychain.Resolve(resource)
+	if err != nil || authenticator == authn.Anonymous {
+		return nil
+	}
+	config, err := authenticator.Authorization()
+	if err != nil || *config == (authn.AuthConfig{}) {
+		return nil
+	}
+	return config
+}
+
+type refreshingAuth struct {
+	keychain *RefreshingKeychain
+	resource authn.Resource
+}
+
+func (a *refreshingAuth) Authorization() (*authn.AuthConfig, error) {
+	if config := a.keychain.authConfig(a.resource); config != nil {
+		return config, nil
+	}
+	return &authn.AuthConfig{}, nil
+}
@@ -0,0 +1,111 @@
+package auth_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/auth"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestRefreshingKeychain(t *testing.T) {
+	spec.Run(t, "RefreshingKeychain", testRefreshingKeychain, spec.Report(report.Terminal{}))
+}
+
+func testRefreshingKeychain(t *testing.T, when spec.G, it spec.S) {
+	when("#Resolve", func() {
+		var (
+			fakeKeychain *FakeKeychain
+			keychain     *auth.RefreshingKeychain
+			registry     name.Registry
+		)
+
+		it.Before(func() {
+			fakeKeychain = &FakeKeychain{authMap: map[string]*authn.AuthConfig{
+				"some-registry.io": {Username: "some-user", Password: "some-token"},
+			}}
+			keychain = auth.NewRefreshingKeychain(fakeKeychain, "some-registry.io/some-image")
+			var err error
+			registry, err = name.NewRegistry("some-registry.io")
+			h.AssertNil(t, err)
+		})
+
+		resolve := func() *authn.AuthConfig {
+			t.Helper()
+			authenticator, err := keychain.Resolve(registry)
+			h.AssertNil(t, err)
+			config, err := authenticator.Authorization()
+			h.AssertNil(t, err)
+			return config
+		}
+
+		it("returns the resolved credentials until they are due to be refreshed", func() {
+			fakeKeychain.authMap["some-registry.io"] = &authn.AuthConfig{Username: "some-user", Password: "new-token"}
+			h.AssertEq(t, resolve().Password, "some-token")
+
+			keychain.Interval = 0
+			h.AssertEq(t, resolve().Password, "new-token")
+		})
+
+		it("returns authenticators that use the refreshed credentials", func() {
+			authenticator, err := keychain.Resolve(registry)
+			h.AssertNil(t, err)
+
+			keychain.Interval = 0
+			fakeKeychain.authMap["some-registry.io"] = &authn.AuthConfig{Username: "some-user", Password: "new-token"}
+			config, err := authenticator.Authorization()
+			h.AssertNil(t, err)
+			h.AssertEq(t, config.Password, "new-token")
+		})
+
+		it("returns anonymous if there are no credentials for the registry", func() {
+			other, err := name.NewRegistry("other-registry.io")
+			h.AssertNil(t, err)
+
+			authenticator, err := keychain.Resolve(other)
+			h.AssertNil(t, err)
+			h.AssertEq(t, authenticator, authn.Anonymous)
+		})
+
+		it("does not resolve registries without credentials again", func() {
+			keychain = auth.NewRefreshingKeychain(fakeKeychain, "some-registry.io/some-image", "other-registry.io/other-image")
+			keychain.Interval = 0
+			fakeKeychain.resolveCount = 0
+			other, err := name.NewRegistry("other-registry.io")
+			h.AssertNil(t, err)
+
+			authenticator, err := keychain.Resolve(other)
+			h.AssertNil(t, err)
+			h.AssertEq(t, authenticator, authn.Anonymous)
+			h.AssertEq(t, fakeKeychain.resolveCount, 0)
+
+			unlisted, err := name.NewRegistry("unlisted-registry.io")
+			h.AssertNil(t, err)
+			for i := 0; i < 2; i++ {
+				authenticator, err = keychain.Resolve(unlisted)
+				h.AssertNil(t, err)
+				h.AssertEq(t, authenticator, authn.Anonymous)
+			}
+			h.AssertEq(t, fakeKeychain.resolveCount, 1)
+		})
+
+		it("does not resolve credentials again until they are due to be refreshed", func() {
+			fakeKeychain.resolveCount = 0
+			resolve()
+			resolve()
+			h.AssertEq(t, fakeKeychain.resolveCount, 0)
+		})
+
+		it("returns the previously resolved credentials if they cannot be refreshed", func() {
+			keychain.Interval = 0
+			fakeKeychain.returnsForResolve = errors.New("some-error")
+
+			h.AssertEq(t, resolve().Password, "some-token")
+		})
+	})
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// Keychain is a source of registry credentials.
+// Platforms embedding the lifecycle can register their own credential sources (e.g., vault lookups or workload identity)
+// with RegisterKeychain, to be used for all registry interactions.
+type Keychain = authn.Keychain
+
+// KeychainFunc adapts a function to the Keychain interface.
+type KeychainFunc func(resource authn.Resource) (authn.Authenticator, error)
+
+// Resolve calls f(resource).
+func (f KeychainFunc) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	return f(resource)
+}
+
+var keychains struct {
+	sync.Mutex
+	registered []Keychain
+	logger     log.Logger
+}
+
+// RegisterKeychain adds a keychain that will be consulted by all subsequent calls to DefaultKeychain,
+// after the CNB_REGISTRY_AUTH environment variable and before all other sources of credentials.
+// Keychains are consulted in the order they are registered; a keychain that has no credentials for a registry
+// should return authn.Anonymous.
+func RegisterKeychain(keychain Keychain) {
+	keychains.Lock()
+	defer keychains.Unlock()
+	keychains.registered = append(keychains.registered, keychain)
+}
+
+// ResetKeychains removes all registered keychains.
+func ResetKeychains() {
+	keychains.Lock()
+	defer keychains.Unlock()
+	keychains.registered = nil
+}
+
+func registeredKeychains() []Keychain {
+	keychains.Lock()
+	defer keychains.Unlock()
+	return append([]Keychain{}, keychains.registered...)
+}
+
+// SetKeychainLogger sets the logger to which the keychains returned by subsequent calls to DefaultKeychain report
+// sources of credentials that could not be read (and were skipped).
+func SetKeychainLogger(logger log.Logger) {
+	keychains.Lock()
+	defer keychains.Unlock()
+	keychains.logger = logger
+}
+
+func keychainLogger() log.Logger {
+	keychains.Lock()
+	defer keychains.Unlock()
+	return keychains.logger
+}
@@ -0,0 +1,74 @@
+package auth_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/auth"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestRegisterKeychain(t *testing.T) {
+	spec.Run(t, "RegisterKeychain", testRegisterKeychain, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testRegisterKeychain(t *testing.T, when spec.G, it spec.S) {
+	resolve := func(keychain authn.Keychain, registry string) *authn.AuthConfig {
+		t.Helper()
+		reg, err := name.NewRegistry(registry)
+		h.AssertNil(t, err)
+		authenticator, err := keychain.Resolve(reg)
+		h.AssertNil(t, err)
+		config, err := authenticator.Authorization()
+		h.AssertNil(t, err)
+		return config
+	}
+
+	it.Before(func() {
+		auth.RegisterKeychain(auth.KeychainFunc(func(resource authn.Resource) (authn.Authenticator, error) {
+			if resource.RegistryStr() != "some-registry.io" {
+				return authn.Anonymous, nil
+			}
+			return authn.FromConfig(authn.AuthConfig{Username: "vault-user", Password: "vault-password"}), nil
+		}))
+	})
+
+	it.After(func() {
+		auth.ResetKeychains()
+		h.AssertNil(t, os.Unsetenv("CNB_REGISTRY_AUTH"))
+	})
+
+	when("#DefaultKeychain", func() {
+		it("uses the registered keychains", func() {
+			keychain, err := auth.DefaultKeychain("some-registry.io/some-image")
+			h.AssertNil(t, err)
+
+			config := resolve(keychain, "some-registry.io")
+			h.AssertEq(t, config.Username, "vault-user")
+			h.AssertEq(t, config.Password, "vault-password")
+		})
+
+		it("prefers the environment variable", func() {
+			h.AssertNil(t, os.Setenv("CNB_REGISTRY_AUTH", `{"some-registry.io": "Basic some-basic-auth="}`))
+
+			keychain, err := auth.DefaultKeychain("some-registry.io/some-image")
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, resolve(keychain, "some-registry.io").Auth, "some-basic-auth=")
+		})
+
+		it("does not use keychains that have been reset", func() {
+			auth.ResetKeychains()
+
+			keychain, err := auth.DefaultKeychain("some-registry.io/some-image")
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, resolve(keychain, "some-registry.io").Username, "")
+		})
+	})
+}
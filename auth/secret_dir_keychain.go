@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// EnvRegistrySecretDirs is a list of directories (separated by the OS path list separator) containing docker config files,
+// such as Kubernetes secrets of type kubernetes.io/dockerconfigjson mounted as volumes.
+const EnvRegistrySecretDirs = "CNB_REGISTRY_SECRET_DIRS"
+
+// dockerConfigFiles are the names of the files that are read from each secret directory, in order of precedence.
+var dockerConfigFiles = []string{".dockerconfigjson", "config.json"}
+
+// NewSecretDirKeychain returns an authn.Keychain that reads credentials from docker config files in the provided directories.
+func NewSecretDirKeychain(dirs ...string) authn.Keychain {
+	return &SecretDirKeychain{Dirs: dirs}
+}
+
+// SecretDirKeychain is an implementation of authn.Keychain that reads credentials from docker config files in directories.
+// The files are re-read when they change, so that credentials rotated in mounted Kubernetes secrets are used without restarting the build.
+// Files that cannot be read are skipped (and reported to Logger, if provided), so that other sources of credentials are still consulted.
+type SecretDirKeychain struct {
+	Dirs   []string
+	Logger log.Logger
+
+	mu    sync.Mutex
+	files map[string]*dockerConfigFile
+}
+
+type dockerConfigFile struct {
+	modTime time.Time
+	auths   map[string]authn.AuthConfig
+}
+
+func (k *SecretDirKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	for _, dir := range k.Dirs {
+		for _, file := range dockerConfigFiles {
+			path := filepath.Join(dir, file)
+			auths, err := k.read(path)
+			if err != nil {
+				if k.Logger != nil {
+					k.Logger.Warnf("Failed to read registry credentials from '%s': %s", path, err)
+				}
+				continue
+			}
+			if config, ok := auths[resource.RegistryStr()]; ok {
+				return authn.FromConfig(config), nil
+			}
+		}
+	}
+	return authn.Anonymous, nil
+}
+
+// read returns the credentials in the docker config file at path, keyed by registry, re-reading the file if it was modified.
+func (k *SecretDirKeychain) read(path string) (map[string]authn.AuthConfig, error) {
+	fi, err := os.Stat(path) // follows the symlinks that Kubernetes swaps when a secret is updated
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.files == nil {
+		k.files = map[string]*dockerConfigFile{}
+	}
+	if cached, ok := k.files[path]; ok && cached.modTime.Equal(fi.ModTime()) {
+		return cached.auths, nil
+	}
+	contents, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Auths map[string]authn.AuthConfig `json:"auths"`
+	}
+	if err = json.Unmarshal(contents, &config); err != nil {
+		return nil, err
+	}
+	auths := map[string]authn.AuthConfig{}
+	for key, auth := range config.Auths {
+		if auth.Auth != "" && auth.Username == "" {
+			// Kubernetes secrets often only provide the encoded "auth" field
+			if decoded, err := base64.StdEncoding.DecodeString(auth.Auth); err == nil {
+				if username, password, ok := strings.Cut(string(decoded), ":"); ok {
+					auth.Username, auth.Password, auth.Auth = username, password, ""
+				}
+			}
+		}
+		auths[registryForConfigKey(key)] = auth
+	}
+	k.files[path] = &dockerConfigFile{modTime: fi.ModTime(), auths: auths}
+	return auths, nil
+}
+
+// registryForConfigKey returns the registry for a key in a docker config file, which may be a URL (e.g., https://index.docker.io/v1/).
+func registryForConfigKey(key string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	registry, err := name.NewRegistry(host, name.WeakValidation)
+	if err != nil {
+		return host
+	}
+	return registry.RegistryStr()
+}
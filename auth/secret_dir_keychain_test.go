@@ -0,0 +1,83 @@
+package auth_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/auth"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestSecretDirKeychain(t *testing.T) {
+	spec.Run(t, "SecretDirKeychain", testSecretDirKeychain, spec.Report(report.Terminal{}))
+}
+
+func testSecretDirKeychain(t *testing.T, when spec.G, it spec.S) {
+	when("#Resolve", func() {
+		var (
+			secretDir string
+			keychain  authn.Keychain
+		)
+
+		it.Before(func() {
+			secretDir = t.TempDir()
+			keychain = auth.NewSecretDirKeychain(filepath.Join(t.TempDir(), "missing-dir"), secretDir)
+		})
+
+		resolve := func(registry string) *authn.AuthConfig {
+			t.Helper()
+			reg, err := name.NewRegistry(registry)
+			h.AssertNil(t, err)
+			authenticator, err := keychain.Resolve(reg)
+			h.AssertNil(t, err)
+			config, err := authenticator.Authorization()
+			h.AssertNil(t, err)
+			return &authn.AuthConfig{Username: config.Username, Password: config.Password}
+		}
+
+		it("returns the credentials from a mounted dockerconfigjson secret", func() {
+			// base64("some-user:some-password")
+			h.Mkfile(t, `{"auths": {
+				"https://index.docker.io/v1/": {"auth": "c29tZS11c2VyOnNvbWUtcGFzc3dvcmQ="},
+				"some-registry.io": {"username": "other-user", "password": "other-password"}
+			}}`, filepath.Join(secretDir, ".dockerconfigjson"))
+
+			h.AssertEq(t, resolve("index.docker.io"), &authn.AuthConfig{Username: "some-user", Password: "some-password"})
+			h.AssertEq(t, resolve("some-registry.io"), &authn.AuthConfig{Username: "other-user", Password: "other-password"})
+			h.AssertEq(t, resolve("unknown-registry.io"), &authn.AuthConfig{})
+		})
+
+		it("skips secrets that cannot be read", func() {
+			h.Mkfile(t, `not json`, filepath.Join(secretDir, ".dockerconfigjson"))
+			h.Mkfile(t, `{"auths": {"some-registry.io": {"username": "some-user", "password": "some-password"}}}`, filepath.Join(secretDir, "config.json"))
+			logHandler := memory.New()
+			keychain = &auth.SecretDirKeychain{Dirs: []string{secretDir}, Logger: &log.Logger{Handler: logHandler}}
+
+			h.AssertEq(t, resolve("some-registry.io"), &authn.AuthConfig{Username: "some-user", Password: "some-password"})
+			h.AssertEq(t, resolve("other-registry.io"), &authn.AuthConfig{})
+			h.AssertEq(t, len(logHandler.Entries) > 0, true)
+			h.AssertStringContains(t, logHandler.Entries[0].Message, "Failed to read registry credentials from")
+		})
+
+		it("re-reads the secret when it changes", func() {
+			path := filepath.Join(secretDir, ".dockerconfigjson")
+			h.Mkfile(t, `{"auths": {"some-registry.io": {"username": "some-user", "password": "some-password"}}}`, path)
+			h.AssertEq(t, resolve("some-registry.io").Password, "some-password")
+
+			h.Mkfile(t, `{"auths": {"some-registry.io": {"username": "some-user", "password": "rotated-password"}}}`, path)
+			later := time.Now().Add(time.Minute)
+			h.AssertNil(t, os.Chtimes(path, later, later))
+
+			h.AssertEq(t, resolve("some-registry.io").Password, "rotated-password")
+		})
+	})
+}
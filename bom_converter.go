@@ -0,0 +1,114 @@
+package lifecycle
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/image"
+	"github.com/buildpacks/lifecycle/launch"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
+)
+
+// BOMConverter converts the (deprecated) BOM in the build metadata label of an application image
+// into SBOM files, to help platforms migrate images built by older lifecycles.
+type BOMConverter struct {
+	Logger log.Logger
+}
+
+// Convert writes an SBOM file for each requested media type and each buildpack that contributed to the BOM of the provided image,
+// using the same layout as the launch SBOM layer, i.e., <outputDir>/launch/<escaped-buildpack-id>/sbom.<ext>.
+// It returns the paths of the files that were written.
+func (c *BOMConverter) Convert(appImage imgutil.Image, outputDir string, mediaTypes []string) ([]string, error) {
+	defer log.NewMeasurement("BOM converter", c.Logger)()
+	var buildMD files.BuildMetadata
+	if err := image.DecodeLabel(appImage, platform.BuildMetadataLabel, &buildMD); err != nil {
+		return nil, errors.Wrap(err, "reading build metadata")
+	}
+	if buildMD.External != nil {
+		c.Logger.Debugf("Reading build metadata from '%s' in layer '%s'", buildMD.External.Path, buildMD.External.Layer)
+		if err := readExternalBuildMetadata(appImage, *buildMD.External, &buildMD); err != nil {
+			return nil, errors.Wrap(err, "reading external build metadata")
+		}
+	}
+	if len(buildMD.BOM) == 0 {
+		c.Logger.Warnf("Image '%s' has no BOM in label '%s'; nothing to convert", appImage.Name(), platform.BuildMetadataLabel)
+		return nil, nil
+	}
+
+	created, err := appImage.CreatedAt()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading image creation time")
+	}
+	var (
+		ids       []string
+		bomByBpID = map[string][]buildpack.BOMEntry{}
+		written   []string
+	)
+	for _, entry := range buildMD.BOM {
+		if _, ok := bomByBpID[entry.Buildpack.ID]; !ok {
+			ids = append(ids, entry.Buildpack.ID)
+		}
+		bomByBpID[entry.Buildpack.ID] = append(bomByBpID[entry.Buildpack.ID], entry)
+	}
+	for _, id := range ids {
+		dir := filepath.Join(outputDir, "launch", launch.EscapeID(id))
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return written, err
+		}
+		for _, mediaType := range mediaTypes {
+			contents, err := buildpack.ConvertLegacyBOM(bomByBpID[id], mediaType, appImage.Name(), created)
+			if err != nil {
+				return written, err
+			}
+			sbomPath := filepath.Join(dir, sbomFileName(mediaType))
+			c.Logger.Infof("Writing %d BOM entries for buildpack '%s' to '%s'", len(bomByBpID[id]), id, sbomPath)
+			if err = os.WriteFile(sbomPath, contents, 0644); err != nil { // #nosec G306
+				return written, errors.Wrapf(err, "writing '%s'", sbomPath)
+			}
+			written = append(written, sbomPath)
+		}
+	}
+	return written, nil
+}
+
+func sbomFileName(mediaType string) string {
+	if mediaType == buildpack.MediaTypeSPDX {
+		return buildpack.ExtensionSPDX
+	}
+	return buildpack.ExtensionCycloneDX
+}
+
+// readExternalBuildMetadata reads build metadata that was too large for the build metadata label from the image layer that contains it.
+func readExternalBuildMetadata(appImage imgutil.Image, external files.ExternalBuildMetadata, buildMD *files.BuildMetadata) error {
+	rc, err := appImage.GetLayer(external.Layer)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	want := strings.TrimPrefix(filepath.ToSlash(external.Path), "/")
+	tr := tar.NewReader(rc)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return errors.Errorf("'%s' not found in layer '%s'", external.Path, external.Layer)
+		}
+		if err != nil {
+			return err
+		}
+		if strings.TrimPrefix(path.Clean(header.Name), "/") != want {
+			continue
+		}
+		return json.NewDecoder(tr).Decode(buildMD)
+	}
+}
@@ -0,0 +1,126 @@
+package lifecycle_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/buildpacks/imgutil/fakes"
+	"github.com/buildpacks/imgutil/local"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestBOMConverter(t *testing.T) {
+	spec.Run(t, "BOMConverter", testBOMConverter, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testBOMConverter(t *testing.T, when spec.G, it spec.S) {
+	var (
+		converter    *lifecycle.BOMConverter
+		fakeAppImage *fakes.Image
+		logHandler   *memory.Handler
+		outputDir    string
+		buildMD      files.BuildMetadata
+	)
+
+	it.Before(func() {
+		var err error
+		outputDir, err = os.MkdirTemp("", "lifecycle.convert-bom")
+		h.AssertNil(t, err)
+
+		fakeAppImage = fakes.NewImage("some-repo/app-image", "some-top-layer-sha", local.IDIdentifier{ImageID: "some-image-id"})
+		logHandler = memory.New()
+		converter = &lifecycle.BOMConverter{Logger: &log.Logger{Handler: logHandler}}
+
+		buildMD = files.BuildMetadata{
+			BOM: []buildpack.BOMEntry{
+				{
+					Require:   buildpack.Require{Name: "some-dep", Metadata: map[string]interface{}{"version": "1.2.3"}},
+					Buildpack: buildpack.GroupElement{ID: "some/buildpack", Version: "v1"},
+				},
+				{
+					Require:   buildpack.Require{Name: "other-dep", Metadata: map[string]interface{}{"version": "4.5.6"}},
+					Buildpack: buildpack.GroupElement{ID: "other-buildpack", Version: "v2"},
+				},
+			},
+		}
+	})
+
+	it.After(func() {
+		h.AssertNil(t, fakeAppImage.Cleanup())
+		h.AssertNil(t, os.RemoveAll(outputDir))
+	})
+
+	setLabel := func(md files.BuildMetadata) {
+		contents, err := json.Marshal(md)
+		h.AssertNil(t, err)
+		h.AssertNil(t, fakeAppImage.SetLabel(platform.BuildMetadataLabel, string(contents)))
+	}
+
+	when("#Convert", func() {
+		it("writes SBOM files for each buildpack", func() {
+			setLabel(buildMD)
+
+			written, err := converter.Convert(fakeAppImage, outputDir, []string{buildpack.MediaTypeCycloneDX, buildpack.MediaTypeSPDX})
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, written, []string{
+				filepath.Join(outputDir, "launch", "some_buildpack", "sbom.cdx.json"),
+				filepath.Join(outputDir, "launch", "some_buildpack", "sbom.spdx.json"),
+				filepath.Join(outputDir, "launch", "other-buildpack", "sbom.cdx.json"),
+				filepath.Join(outputDir, "launch", "other-buildpack", "sbom.spdx.json"),
+			})
+			contents, err := os.ReadFile(written[0])
+			h.AssertNil(t, err)
+			h.AssertStringContains(t, string(contents), `"name": "some-dep"`)
+			h.AssertStringDoesNotContain(t, string(contents), "other-dep")
+		})
+
+		it("reads the BOM from the config layer when the build metadata was externalized", func() {
+			layersDir := filepath.Join(outputDir, "layers-for-test")
+			externalPath := filepath.Join("/layers", "config", files.ExternalBuildMetadataFile)
+			contents, err := json.Marshal(buildMD)
+			h.AssertNil(t, err)
+			r, err := h.CreateSingleFileTar(externalPath, string(contents))
+			h.AssertNil(t, err)
+			h.AssertNil(t, os.MkdirAll(layersDir, 0755))
+			layerPath := filepath.Join(layersDir, "config.tar")
+			layerFile, err := os.Create(layerPath)
+			h.AssertNil(t, err)
+			_, err = io.Copy(layerFile, r)
+			h.AssertNil(t, err)
+			h.AssertNil(t, layerFile.Close())
+			h.AssertNil(t, fakeAppImage.AddLayerWithDiffID(layerPath, "sha256:some-config-layer"))
+
+			setLabel(files.BuildMetadata{
+				BOM:      []buildpack.BOMEntry{},
+				External: &files.ExternalBuildMetadata{Path: externalPath, Layer: "sha256:some-config-layer"},
+			})
+
+			written, err := converter.Convert(fakeAppImage, filepath.Join(outputDir, "sbom"), []string{buildpack.MediaTypeCycloneDX})
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(written), 2)
+			h.AssertPathExists(t, filepath.Join(outputDir, "sbom", "launch", "other-buildpack", "sbom.cdx.json"))
+		})
+
+		it("warns when the image has no BOM", func() {
+			setLabel(files.BuildMetadata{})
+
+			written, err := converter.Convert(fakeAppImage, outputDir, []string{buildpack.MediaTypeCycloneDX})
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(written), 0)
+			h.AssertLogEntry(t, logHandler, "has no BOM")
+		})
+	})
+}
@@ -1,6 +1,7 @@
 package lifecycle
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -14,11 +15,16 @@ import (
 	"github.com/buildpacks/lifecycle/env"
 	"github.com/buildpacks/lifecycle/internal/encoding"
 	"github.com/buildpacks/lifecycle/internal/fsutil"
+	"github.com/buildpacks/lifecycle/internal/network"
+	"github.com/buildpacks/lifecycle/internal/redact"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/platform/files"
+	"github.com/buildpacks/lifecycle/telemetry/metrics"
+	"github.com/buildpacks/lifecycle/telemetry/progress"
+	"github.com/buildpacks/lifecycle/telemetry/tracing"
 )
 
 type Platform interface {
@@ -35,22 +41,88 @@ type BuildEnv interface {
 
 type Builder struct {
 	AppDir         string
+	AppSymlinks    string // how symlinks pointing outside of the app directory are handled (see ApplyAppSymlinkPolicy)
 	BuildConfigDir string
+	GeneratedDir   string // e.g., <layers>/generated; SBOM files written by extensions are copied from here
 	LayersDir      string
 	PlatformDir    string
 	BuildExecutor  buildpack.BuildExecutor
 	DirStore       DirStore
+	Constraints    files.Constraints // versions of dependencies enforced by the platform in the plan
+	ExplainEnv     bool              // log the environment of each buildpack, with the env files that contributed to each variable
 	Group          buildpack.Group
 	Logger         log.Logger
 	Out, Err       io.Writer
 	Plan           files.Plan
 	PlatformAPI    *api.Version
 	AnalyzeMD      files.Analyzed
+	NetworkBPs     []string // IDs of the buildpacks that may access the network during build; if nil, all buildpacks may (see mayAccessNetwork)
+	ProjectEnv     []string // "NAME=value" env vars declared in the project descriptor
+	SBOMValidation string   // how invalid SBOM files are handled: platform.SBOMValidationWarn, platform.SBOMValidationFail, or off if empty
 }
 
+// NewBuilderFromInputs returns a builder configured from the platform inputs, reading the constraints
+// and the project descriptor (for Platform API 0.12 and above) provided by the platform, if any.
+// The builder command and the phases package both use it, so that a build is configured the same way
+// whether the lifecycle runs as a binary or in-process.
+func NewBuilderFromInputs(inputs *platform.LifecycleInputs, group buildpack.Group, plan files.Plan, analyzedMD files.Analyzed, logger log.Logger, out, errOut io.Writer) (*Builder, error) {
+	var (
+		constraints  files.Constraints
+		descriptor   files.ProjectDescriptor
+		generatedDir string
+		err          error
+	)
+	if inputs.ConstraintsPath != "" {
+		if constraints, err = files.ReadConstraints(inputs.ConstraintsPath); err != nil {
+			return nil, fmt.Errorf("reading constraints: %w", err)
+		}
+	}
+	if inputs.PlatformAPI.AtLeast("0.12") {
+		if descriptor, err = files.ReadProjectDescriptor(inputs.ProjectDescriptorPath, logger); err != nil {
+			return nil, fmt.Errorf("reading project descriptor: %w", err)
+		}
+		generatedDir = inputs.GeneratedDir
+	}
+	return &Builder{
+		AppDir:         inputs.AppDir,
+		AppSymlinks:    inputs.AppSymlinks,
+		BuildConfigDir: inputs.BuildConfigDir,
+		GeneratedDir:   generatedDir,
+		LayersDir:      inputs.LayersDir,
+		PlatformDir:    inputs.PlatformDir,
+		BuildExecutor:  &buildpack.DefaultBuildExecutor{},
+		DirStore:       platform.NewDirStore(inputs.BuildpacksDir, ""),
+		Constraints:    constraints,
+		ExplainEnv:     inputs.ExplainEnv,
+		Group:          group,
+		Logger:         logger,
+		Out:            out,
+		Err:            errOut,
+		Plan:           plan,
+		PlatformAPI:    inputs.PlatformAPI,
+		AnalyzeMD:      analyzedMD,
+		NetworkBPs:     inputs.NetworkBuildpackIDs(),
+		ProjectEnv:     descriptor.EnvList(),
+		SBOMValidation: inputs.SBOMValidation,
+	}, nil
+}
+
+// Build is equivalent to BuildContext with context.Background().
+//
+// Deprecated: use BuildContext.
 func (b *Builder) Build() (*files.BuildMetadata, error) {
+	return b.BuildContext(context.Background())
+}
+
+// BuildContext runs the build for each buildpack in the group, and returns the build metadata.
+// If the context is done, the running buildpack is stopped (when supported by the build executor) and the context error is returned.
+func (b *Builder) BuildContext(ctx context.Context) (*files.BuildMetadata, error) {
 	defer log.NewMeasurement("Builder", b.Logger)()
 
+	if err := ApplyAppSymlinkPolicy(b.AppDir, b.AppSymlinks, b.Logger); err != nil {
+		return nil, err
+	}
+
 	// ensure layers SBOM directory is removed
 	if err := os.RemoveAll(filepath.Join(b.LayersDir, "sbom")); err != nil {
 		return nil, errors.Wrap(err, "cleaning layers SBOM directory")
@@ -64,16 +136,34 @@ func (b *Builder) Build() (*files.BuildMetadata, error) {
 		slices    []layers.Slice
 	)
 	processMap := newProcessMap()
+	taskMap := make(map[string]launch.Task)
+	envConstraints := make(map[string]*buildpack.EnvOrder)
 	inputs := b.getBuildInputs()
+	var buildEnv *env.Env
 	if b.AnalyzeMD.RunImage != nil && b.AnalyzeMD.RunImage.TargetMetadata != nil && b.PlatformAPI.AtLeast("0.12") {
-		inputs.Env = env.NewBuildEnv(append(os.Environ(), platform.EnvVarsFor(*b.AnalyzeMD.RunImage.TargetMetadata)...))
+		buildEnv = withProjectEnv(env.NewBuildEnv(append(os.Environ(), platform.EnvVarsFor(*b.AnalyzeMD.RunImage.TargetMetadata)...)), b.ProjectEnv)
+		inputs.Target = platform.BuildpackTarget(*b.AnalyzeMD.RunImage.TargetMetadata)
 	} else {
-		inputs.Env = env.NewBuildEnv(os.Environ())
+		buildEnv = withProjectEnv(env.NewBuildEnv(os.Environ()), b.ProjectEnv)
+	}
+	inputs.Env = buildEnv
+
+	var (
+		egress            *network.EgressProxy
+		networkViolations []files.NetworkViolation
+	)
+	if b.NetworkBPs != nil {
+		var err error
+		if egress, err = network.StartEgressProxy(); err != nil {
+			return nil, errors.Wrap(err, "starting network policy proxy")
+		}
+		defer egress.Close()
 	}
 
-	filteredPlan := b.Plan
+	// the plan is constrained by the detector, but constraints are applied again in case they were only provided to the builder
+	filteredPlan := b.Plan.WithConstraints(b.Constraints)
 
-	for _, bp := range b.Group.Group {
+	for i, bp := range b.Group.Group {
 		b.Logger.Debugf("Running build for buildpack %s", bp)
 
 		b.Logger.Debug("Looking up buildpack")
@@ -81,15 +171,43 @@ func (b *Builder) Build() (*files.BuildMetadata, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err = b.recordDigest(&b.Group.Group[i], bpTOML); err != nil {
+			return nil, err
+		}
 
 		b.Logger.Debug("Finding plan")
 		inputs.Plan = filteredPlan.Find(buildpack.KindBuildpack, bp.ID)
 
-		br, err := b.BuildExecutor.Build(*bpTOML, inputs, b.Logger)
+		if b.ExplainEnv {
+			explainEnv(b.Logger, "build", bp, bpTOML.Buildpack.ClearEnv, buildEnv, b.PlatformDir, b.BuildConfigDir)
+		}
+
+		bpLogger, out, errOut, flush := buildpackOutput(b.Logger, bp.ID, b.Out, b.Err)
+		inputs.Out, inputs.Err = out, errOut
+		inputs.NetworkEnv = nil
+		if egress != nil && !b.mayAccessNetwork(bp.ID) {
+			b.Logger.Debugf("Denying network access to buildpack %s", bp)
+			inputs.NetworkEnv = egress.Env()
+		}
+		span := tracing.Start("build "+bp.ID, tracing.String("cnb.buildpack.id", bp.ID), tracing.String("cnb.buildpack.version", bp.Version))
+		stopTimer := metrics.Timer(metrics.BuildpackDuration, metrics.L("buildpack", bp.ID), metrics.L("version", bp.Version), metrics.L("step", "build"))
+		finishProgress := progress.BuildpackStarted(bp.ID, bp.Version, progress.StepBuild)
+		br, err := buildWithContext(ctx, b.BuildExecutor, *bpTOML, inputs, bpLogger)
+		flush()
+		stopTimer()
+		finishProgress(err)
+		span.End(err)
+		if egress != nil {
+			networkViolations = append(networkViolations, b.networkViolations(bp.ID, egress.Attempts())...)
+		}
 		if err != nil {
 			return nil, err
 		}
 
+		if err = b.validateSBOMFiles(bp, br.BOMFiles); err != nil {
+			return nil, err
+		}
+
 		b.Logger.Debug("Updating buildpack processes")
 		updateDefaultProcesses(br.Processes, api.MustParse(bp.API), b.PlatformAPI)
 
@@ -105,6 +223,12 @@ func (b *Builder) Build() (*files.BuildMetadata, error) {
 		if warning != "" {
 			b.Logger.Warn(warning)
 		}
+		for _, task := range br.Tasks {
+			taskMap[task.Type] = task
+		}
+		if br.EnvOrder != nil {
+			envConstraints[bp.ID] = br.EnvOrder
+		}
 
 		b.Logger.Debugf("Finished running build for buildpack %s", bp)
 	}
@@ -121,6 +245,12 @@ func (b *Builder) Build() (*files.BuildMetadata, error) {
 		if err := b.copySBOMFiles(inputs.LayersDir, bomFiles); err != nil {
 			return nil, err
 		}
+		if b.GeneratedDir != "" && len(b.Group.GroupExtensions) > 0 {
+			b.Logger.Debug("Copying extension SBOM files")
+			if err := b.copyExtensionSBOMFiles(); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if b.PlatformAPI.AtLeast("0.9") {
@@ -134,8 +264,23 @@ func (b *Builder) Build() (*files.BuildMetadata, error) {
 		launchBOM = []buildpack.BOMEntry{}
 	}
 
+	var order []string
+	if len(envConstraints) > 0 {
+		b.Logger.Debug("Ordering env contributions")
+		var err error
+		if order, err = envOrder(b.Group.Group, envConstraints, b.Logger); err != nil {
+			return nil, buildpack.NewError(err, buildpack.ErrTypeBuildpack)
+		}
+	}
+
+	// buildpacks may have added symlinks to the app directory
+	if err := ApplyAppSymlinkPolicy(b.AppDir, b.AppSymlinks, b.Logger); err != nil {
+		return nil, err
+	}
+
 	b.Logger.Debug("Listing processes")
 	procList := processMap.list(b.PlatformAPI)
+	taskList := listTasks(taskMap, b.PlatformAPI)
 
 	// Don't redundantly print `extension = true` and `optional = true` in metadata.toml and metadata label
 	for i, ext := range b.Group.GroupExtensions {
@@ -145,14 +290,78 @@ func (b *Builder) Build() (*files.BuildMetadata, error) {
 	return &files.BuildMetadata{
 		BOM:                         launchBOM,
 		Buildpacks:                  b.Group.Group,
+		EnvOrder:                    order,
 		Extensions:                  b.Group.GroupExtensions,
 		Labels:                      labels,
+		NetworkViolations:           networkViolations,
 		Processes:                   procList,
 		Slices:                      slices,
+		Tasks:                       taskList,
 		BuildpackDefaultProcessType: processMap.defaultType,
 	}, nil
 }
 
+// recordDigest records the digest of the directory of the buildpack that is about to run, in the build metadata,
+// failing if it differs from the digest recorded by the detector.
+func (b *Builder) recordDigest(bp *buildpack.GroupElement, descriptor *buildpack.BpDescriptor) error {
+	if b.PlatformAPI.LessThan("0.12") || descriptor.WithRootDir == "" {
+		return nil
+	}
+	digest, err := buildpack.DirDigest(descriptor.WithRootDir)
+	if err != nil {
+		return err
+	}
+	if bp.Digest != "" && bp.Digest != digest {
+		return fmt.Errorf("buildpack %s changed since detection: its digest was %s and is now %s", bp, bp.Digest, digest)
+	}
+	bp.Digest = digest
+	return nil
+}
+
+// mayAccessNetwork returns true if the buildpack with the provided ID may access the network during build.
+// The requests of the other buildpacks are denied by a proxy, and recorded as violations in the build metadata.
+func (b *Builder) mayAccessNetwork(id string) bool {
+	for _, allowed := range b.NetworkBPs {
+		if allowed == id {
+			return true
+		}
+	}
+	return false
+}
+
+// networkViolations logs and returns the violations of the network policy by the buildpack with the provided ID,
+// from the hosts of its denied requests.
+func (b *Builder) networkViolations(id string, hosts []string) []files.NetworkViolation {
+	var violations []files.NetworkViolation
+	for _, host := range hosts {
+		b.Logger.Warnf("Buildpack '%s' is not allowed to access the network; denied request to '%s'", id, host)
+		violations = append(violations, files.NetworkViolation{Buildpack: id, Host: host})
+	}
+	return violations
+}
+
+// buildpackOutput returns the logger and output writers for the buildpack or extension with the provided ID.
+// Secret values and credentials are redacted from the output, and counted for the buildpack.
+// When logging structured records, buildpack output is logged line by line and tagged with the buildpack ID;
+// the returned function writes any output remaining after the buildpack exits.
+func buildpackOutput(logger log.Logger, id string, out, errOut io.Writer) (log.Logger, io.Writer, io.Writer, func()) {
+	var closers []io.Closer
+	if log.IsStructured(logger) {
+		logger = log.WithBuildpack(logger, id)
+		logOut, logErrOut := log.NewWriter(logger), log.NewWriter(logger)
+		out, errOut = logOut, logErrOut
+		closers = append(closers, logOut, logErrOut)
+	}
+	// secrets are redacted before the output is logged, so that redactions are counted for the buildpack
+	redactedOut, redactedErrOut := redact.NewWriter(out, id), redact.NewWriter(errOut, id)
+	closers = append([]io.Closer{redactedOut, redactedErrOut}, closers...)
+	return logger, redactedOut, redactedErrOut, func() {
+		for _, c := range closers {
+			_ = c.Close()
+		}
+	}
+}
+
 func (b *Builder) getBuildInputs() buildpack.BuildInputs {
 	return buildpack.BuildInputs{
 		AppDir:         b.AppDir,
@@ -228,6 +437,46 @@ func (b *Builder) copySBOMFiles(layersDir string, bomFiles []buildpack.BOMFile)
 	return nil
 }
 
+// validateSBOMFiles checks the structure of the SBOM files written by the provided buildpack (see buildpack.BOMFile.CheckStructure),
+// logging or failing the build (depending on b.SBOMValidation) for any invalid files.
+func (b *Builder) validateSBOMFiles(bp buildpack.GroupElement, bomFiles []buildpack.BOMFile) error {
+	if b.SBOMValidation != platform.SBOMValidationWarn && b.SBOMValidation != platform.SBOMValidationFail {
+		return nil
+	}
+	validated := map[string]bool{}
+	for _, bomFile := range bomFiles {
+		if validated[bomFile.Path] { // files for cached launch layers are listed twice
+			continue
+		}
+		validated[bomFile.Path] = true
+		err := bomFile.CheckStructure()
+		if err == nil {
+			continue
+		}
+		if b.SBOMValidation == platform.SBOMValidationFail {
+			return buildpack.NewError(errors.Wrapf(err, "validating SBOM file '%s' for buildpack '%s'", bomFile.Path, bp), buildpack.ErrTypeBuildpack)
+		}
+		b.Logger.Warnf("Invalid SBOM file '%s' for buildpack '%s': %s", bomFile.Path, bp, err)
+	}
+	return nil
+}
+
+// copyExtensionSBOMFiles copies any SBOM files written by extensions during the generate phase
+// from <generated>/sbom to <layers>/sbom, so that they are exported alongside SBOM files written by buildpacks.
+func (b *Builder) copyExtensionSBOMFiles() error {
+	srcDir := filepath.Join(b.GeneratedDir, "sbom")
+	if _, err := os.Stat(srcDir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := fsutil.Copy(srcDir, filepath.Join(b.LayersDir, "sbom")); err != nil {
+		return errors.Wrap(err, "copying extension SBOM files")
+	}
+	return nil
+}
+
 // we set default = true for web processes when platformAPI >= 0.6 and buildpackAPI < 0.6
 func updateDefaultProcesses(processes []launch.Process, buildpackAPI *api.Version, platformAPI *api.Version) {
 	if platformAPI.LessThan("0.6") || buildpackAPI.AtLeast("0.6") {
@@ -241,6 +490,23 @@ func updateDefaultProcesses(processes []launch.Process, buildpackAPI *api.Versio
 	}
 }
 
+// listTasks returns the tasks sorted by type, for reproducibility.
+// A task provided by a later buildpack overrides a task of the same type provided by an earlier buildpack.
+func listTasks(taskMap map[string]launch.Task, platformAPI *api.Version) []launch.Task {
+	var types []string
+	for tType := range taskMap {
+		types = append(types, tType)
+	}
+	sort.Strings(types)
+	var result []launch.Task
+	for _, tType := range types {
+		task := taskMap[tType]
+		task.Process = task.Process.WithPlatformAPI(platformAPI)
+		result = append(result, task)
+	}
+	return result
+}
+
 type processMap struct {
 	typeToProcess map[string]launch.Process
 	defaultType   string
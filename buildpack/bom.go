@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/internal/deprecation"
 	"github.com/buildpacks/lifecycle/log"
 )
 
@@ -29,13 +30,13 @@ type defaultBOMValidator struct {
 }
 
 func (v *defaultBOMValidator) ValidateBOM(bp GroupElement, bom []BOMEntry) ([]BOMEntry, error) {
-	if err := v.validateBOM(bom); err != nil {
+	if err := v.validateBOM(bp, bom); err != nil {
 		return []BOMEntry{}, err
 	}
 	return v.processBOM(bp, bom), nil
 }
 
-func (v *defaultBOMValidator) validateBOM(bom []BOMEntry) error {
+func (v *defaultBOMValidator) validateBOM(bp GroupElement, bom []BOMEntry) error {
 	sbomMatches, err := sbomGlob(v.layersDir)
 	if err != nil {
 		return err
@@ -47,6 +48,12 @@ func (v *defaultBOMValidator) validateBOM(bom []BOMEntry) error {
 		// This code path represents buildpack authors providing a
 		// migration path from old BOM to new SBOM.
 	case len(bom) > 0:
+		deprecation.Record(deprecation.Notice{
+			Kind:    deprecation.KindLegacyFormat,
+			Subject: bp.ID,
+			API:     bp.API,
+			Message: "BOM table is deprecated in this buildpack api version; BOM information should be written to <layer>.sbom.<ext>, launch.sbom.<ext>, or build.sbom.<ext>",
+		})
 		v.logger.Warn("BOM table is deprecated in this buildpack api version, though it remains supported for backwards compatibility. Buildpack authors should write BOM information to <layer>.sbom.<ext>, launch.sbom.<ext>, or build.sbom.<ext>.")
 	}
 
@@ -0,0 +1,234 @@
+package buildpack
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	cycloneDXSpecVersion = "1.4"
+	spdxVersion          = "SPDX-2.3"
+	spdxNoAssertion      = "NOASSERTION"
+
+	bomPropertyBuildpackID      = "io.buildpacks.buildpack.id"
+	bomPropertyBuildpackVersion = "io.buildpacks.buildpack.version"
+	bomPropertyMetadata         = "io.buildpacks.bom.metadata"
+)
+
+// ConvertLegacyBOM encodes the (deprecated) BOM entries written by buildpacks as an SBOM document with the provided media type.
+// Only CycloneDX and SPDX JSON are supported.
+// The name identifies the SBOM subject (usually the application image), and created is recorded as the document creation time
+// so that converting the same image twice produces the same document.
+// Information that has no equivalent field in the SBOM format (such as the contributing buildpack and arbitrary metadata)
+// is preserved as component properties (CycloneDX) or package comments (SPDX).
+func ConvertLegacyBOM(bom []BOMEntry, mediaType, name string, created time.Time) ([]byte, error) {
+	switch mediaType {
+	case MediaTypeCycloneDX:
+		return json.MarshalIndent(toCycloneDX(bom, name, created), "", "  ")
+	case MediaTypeSPDX:
+		return json.MarshalIndent(toSPDX(bom, name, created), "", "  ")
+	default:
+		return nil, errors.Errorf("unsupported SBOM format for legacy BOM conversion: '%s'", mediaType)
+	}
+}
+
+// CycloneDX
+
+type cdxDocument struct {
+	BOMFormat   string         `json:"bomFormat"`
+	SpecVersion string         `json:"specVersion"`
+	Version     int            `json:"version"`
+	Metadata    cdxMetadata    `json:"metadata"`
+	Components  []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp,omitempty"`
+	Tools     []cdxTool    `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTool struct {
+	Vendor string `json:"vendor"`
+	Name   string `json:"name"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func toCycloneDX(bom []BOMEntry, name string, created time.Time) cdxDocument {
+	doc := cdxDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: cycloneDXSpecVersion,
+		Version:     1,
+		Metadata: cdxMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Tools:     []cdxTool{{Vendor: "buildpacks.io", Name: "lifecycle"}},
+			Component: cdxComponent{Type: "container", Name: name},
+		},
+		Components: []cdxComponent{},
+	}
+	for _, entry := range bom {
+		component := cdxComponent{
+			Type:    "library",
+			Name:    entry.Name,
+			Version: legacyBOMVersion(entry),
+			PURL:    legacyBOMPURL(entry),
+		}
+		if entry.Buildpack.ID != "" {
+			component.Properties = append(component.Properties,
+				cdxProperty{Name: bomPropertyBuildpackID, Value: entry.Buildpack.ID},
+				cdxProperty{Name: bomPropertyBuildpackVersion, Value: entry.Buildpack.Version},
+			)
+		}
+		if metadata := legacyBOMMetadata(entry); metadata != "" {
+			component.Properties = append(component.Properties, cdxProperty{Name: bomPropertyMetadata, Value: metadata})
+		}
+		doc.Components = append(doc.Components, component)
+	}
+	return doc
+}
+
+// SPDX
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func toSPDX(bom []BOMEntry, name string, created time.Time) spdxDocument {
+	timestamp := created.UTC().Format(time.RFC3339)
+	doc := spdxDocument{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("https://buildpacks.io/spdx/legacy-bom/%x", sha256.Sum256([]byte(name+"@"+timestamp))),
+		CreationInfo: spdxCreationInfo{
+			Created:  timestamp,
+			Creators: []string{"Organization: buildpacks.io", "Tool: lifecycle"},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+	for i, entry := range bom {
+		pkg := spdxPackage{
+			Name:             entry.Name,
+			SPDXID:           fmt.Sprintf("SPDXRef-Package-%d", i),
+			VersionInfo:      legacyBOMVersion(entry),
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			Comment:          legacyBOMComment(entry),
+		}
+		if purl := legacyBOMPURL(entry); purl != "" {
+			pkg.ExternalRefs = []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl,
+			}}
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      doc.SPDXID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+	return doc
+}
+
+func legacyBOMComment(entry BOMEntry) string {
+	var comment string
+	if entry.Buildpack.ID != "" {
+		comment = fmt.Sprintf("Contributed by buildpack %s@%s.", entry.Buildpack.ID, entry.Buildpack.Version)
+	}
+	if metadata := legacyBOMMetadata(entry); metadata != "" {
+		if comment != "" {
+			comment += " "
+		}
+		comment += "Metadata: " + metadata
+	}
+	return comment
+}
+
+// helpers
+
+// legacyBOMVersion returns the top-level version of the entry (written by Buildpack API < 0.5)
+// or the version in the entry metadata.
+func legacyBOMVersion(entry BOMEntry) string {
+	if entry.Version != "" {
+		return entry.Version
+	}
+	if version, ok := entry.Metadata["version"]; ok {
+		return fmt.Sprintf("%v", version)
+	}
+	return ""
+}
+
+func legacyBOMPURL(entry BOMEntry) string {
+	if purl, ok := entry.Metadata["purl"].(string); ok {
+		return purl
+	}
+	return ""
+}
+
+func legacyBOMMetadata(entry BOMEntry) string {
+	if len(entry.Metadata) == 0 {
+		return ""
+	}
+	contents, err := json.Marshal(entry.Metadata)
+	if err != nil {
+		return ""
+	}
+	return string(contents)
+}
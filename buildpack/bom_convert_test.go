@@ -0,0 +1,132 @@
+package buildpack_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestBOMConvert(t *testing.T) {
+	spec.Run(t, "unit-bom-convert", testBOMConvert, spec.Report(report.Terminal{}))
+}
+
+func testBOMConvert(t *testing.T, when spec.G, it spec.S) {
+	when("#ConvertLegacyBOM", func() {
+		var (
+			bom     []buildpack.BOMEntry
+			created = time.Date(1980, time.January, 1, 0, 0, 1, 0, time.UTC)
+		)
+
+		it.Before(func() {
+			bom = []buildpack.BOMEntry{
+				{
+					Require: buildpack.Require{
+						Name:     "some-dep",
+						Metadata: map[string]interface{}{"version": "1.2.3", "purl": "pkg:generic/some-dep@1.2.3"},
+					},
+					Buildpack: buildpack.GroupElement{ID: "some/buildpack", Version: "v1"},
+				},
+				{
+					Require: buildpack.Require{
+						Name:    "other-dep",
+						Version: "4.5.6",
+					},
+					Buildpack: buildpack.GroupElement{ID: "some/buildpack", Version: "v1"},
+				},
+			}
+		})
+
+		when("CycloneDX", func() {
+			it("converts the entries to components", func() {
+				contents, err := buildpack.ConvertLegacyBOM(bom, buildpack.MediaTypeCycloneDX, "some-image", created)
+				h.AssertNil(t, err)
+
+				var doc struct {
+					BOMFormat   string `json:"bomFormat"`
+					SpecVersion string `json:"specVersion"`
+					Metadata    struct {
+						Timestamp string `json:"timestamp"`
+					} `json:"metadata"`
+					Components []struct {
+						Name       string `json:"name"`
+						Version    string `json:"version"`
+						PURL       string `json:"purl"`
+						Properties []struct {
+							Name  string `json:"name"`
+							Value string `json:"value"`
+						} `json:"properties"`
+					} `json:"components"`
+				}
+				h.AssertNil(t, json.Unmarshal(contents, &doc))
+				h.AssertEq(t, doc.BOMFormat, "CycloneDX")
+				h.AssertEq(t, doc.SpecVersion, "1.4")
+				h.AssertEq(t, doc.Metadata.Timestamp, "1980-01-01T00:00:01Z")
+				h.AssertEq(t, len(doc.Components), 2)
+				h.AssertEq(t, doc.Components[0].Name, "some-dep")
+				h.AssertEq(t, doc.Components[0].Version, "1.2.3")
+				h.AssertEq(t, doc.Components[0].PURL, "pkg:generic/some-dep@1.2.3")
+				h.AssertEq(t, doc.Components[0].Properties[0].Name, "io.buildpacks.buildpack.id")
+				h.AssertEq(t, doc.Components[0].Properties[0].Value, "some/buildpack")
+				h.AssertEq(t, doc.Components[1].Version, "4.5.6")
+				h.AssertEq(t, doc.Components[1].PURL, "")
+			})
+		})
+
+		when("SPDX", func() {
+			it("converts the entries to packages", func() {
+				contents, err := buildpack.ConvertLegacyBOM(bom, buildpack.MediaTypeSPDX, "some-image", created)
+				h.AssertNil(t, err)
+
+				var doc struct {
+					SPDXVersion  string `json:"spdxVersion"`
+					Name         string `json:"name"`
+					CreationInfo struct {
+						Created string `json:"created"`
+					} `json:"creationInfo"`
+					Packages []struct {
+						Name         string `json:"name"`
+						SPDXID       string `json:"SPDXID"`
+						VersionInfo  string `json:"versionInfo"`
+						Comment      string `json:"comment"`
+						ExternalRefs []struct {
+							ReferenceLocator string `json:"referenceLocator"`
+						} `json:"externalRefs"`
+					} `json:"packages"`
+					Relationships []struct {
+						RelatedSPDXElement string `json:"relatedSpdxElement"`
+					} `json:"relationships"`
+				}
+				h.AssertNil(t, json.Unmarshal(contents, &doc))
+				h.AssertEq(t, doc.SPDXVersion, "SPDX-2.3")
+				h.AssertEq(t, doc.Name, "some-image")
+				h.AssertEq(t, doc.CreationInfo.Created, "1980-01-01T00:00:01Z")
+				h.AssertEq(t, len(doc.Packages), 2)
+				h.AssertEq(t, doc.Packages[0].VersionInfo, "1.2.3")
+				h.AssertEq(t, doc.Packages[0].ExternalRefs[0].ReferenceLocator, "pkg:generic/some-dep@1.2.3")
+				h.AssertStringContains(t, doc.Packages[0].Comment, "Contributed by buildpack some/buildpack@v1.")
+				h.AssertEq(t, doc.Packages[1].VersionInfo, "4.5.6")
+				h.AssertEq(t, len(doc.Relationships), 2)
+				h.AssertEq(t, doc.Relationships[1].RelatedSPDXElement, doc.Packages[1].SPDXID)
+			})
+
+			it("is reproducible", func() {
+				first, err := buildpack.ConvertLegacyBOM(bom, buildpack.MediaTypeSPDX, "some-image", created)
+				h.AssertNil(t, err)
+				second, err := buildpack.ConvertLegacyBOM(bom, buildpack.MediaTypeSPDX, "some-image", created)
+				h.AssertNil(t, err)
+				h.AssertEq(t, string(first), string(second))
+			})
+		})
+
+		it("errors for unsupported formats", func() {
+			_, err := buildpack.ConvertLegacyBOM(bom, buildpack.MediaTypeSyft, "some-image", created)
+			h.AssertError(t, err, "unsupported SBOM format for legacy BOM conversion")
+		})
+	})
+}
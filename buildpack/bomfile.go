@@ -93,7 +93,11 @@ func validateMediaTypes(bp GroupElement, bomfiles []BOMFile, declaredTypes []str
 			return errors.Errorf("unsupported SBOM file format: '%s'", bomFile.Path)
 		default:
 			if err := ensureDeclared(declaredTypes, fileType); err != nil {
-				return errors.Wrap(err, fmt.Sprintf("validating SBOM file '%s' for buildpack: '%s'", bomFile.Path, bp.String()))
+				kind := "buildpack"
+				if bp.Extension {
+					kind = "extension"
+				}
+				return errors.Wrap(err, fmt.Sprintf("validating SBOM file '%s' for %s: '%s'", bomFile.Path, kind, bp.String()))
 			}
 		}
 	}
@@ -107,6 +111,37 @@ func sbomGlob(layersDir string) (matches []string, err error) {
 	return
 }
 
+// processSBOMFiles returns the SBOM files written by an extension to its output directory,
+// describing packages installed by its Dockerfiles: run.sbom.<ext> for the run image and build.sbom.<ext> for the build image.
+func (d *ExtDescriptor) processSBOMFiles(outputDir string) ([]BOMFile, error) {
+	var files []BOMFile
+
+	matches, err := sbomGlob(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range matches {
+		switch strings.SplitN(filepath.Base(m), ".", 2)[0] {
+		case DockerfileKindRun:
+			files = append(files, BOMFile{
+				BuildpackID: d.Extension.ID,
+				LayerType:   LayerTypeLaunch,
+				Path:        m,
+			})
+		case DockerfileKindBuild:
+			files = append(files, BOMFile{
+				BuildpackID: d.Extension.ID,
+				LayerType:   LayerTypeBuild,
+				Path:        m,
+			})
+		}
+	}
+
+	ext := GroupElement{ID: d.Extension.ID, Version: d.Extension.Version, Extension: true}
+	return files, validateMediaTypes(ext, files, d.Extension.SBOM)
+}
+
 func (d *BpDescriptor) processSBOMFiles(layersDir string, bp GroupElement, bpLayers map[string]LayerMetadataFile, logger log.Logger) ([]BOMFile, error) {
 	var (
 		files []BOMFile
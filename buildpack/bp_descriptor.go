@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 
 	"github.com/BurntSushi/toml"
+
+	lerrors "github.com/buildpacks/lifecycle/errors"
 )
 
 type BpDescriptor struct {
@@ -66,7 +68,7 @@ func ReadBpDescriptor(path string) (*BpDescriptor, error) {
 		err        error
 	)
 	if _, err = toml.DecodeFile(path, &descriptor); err != nil {
-		return &BpDescriptor{}, err
+		return &BpDescriptor{}, lerrors.NewDecodeError(path, err)
 	}
 	if descriptor.WithRootDir, err = filepath.Abs(filepath.Dir(path)); err != nil {
 		return &BpDescriptor{}, err
@@ -108,6 +110,25 @@ func (d *BpDescriptor) API() string {
 	return d.WithAPI
 }
 
+// BinDir returns the directory of the executables (i.e., bin/detect and bin/build) of the buildpack for the provided target.
+// Buildpacks distributed for several targets may provide executables for each target in <root>/<os>/<arch>[/<arch-variant>]/bin;
+// the most specific directory that exists for the target is used, falling back to <root>/bin.
+func (d *BpDescriptor) BinDir(target TargetMetadata) string {
+	if target.OS != "" && target.Arch != "" {
+		var candidates []string
+		if target.ArchVariant != "" {
+			candidates = append(candidates, filepath.Join(d.WithRootDir, target.OS, target.Arch, target.ArchVariant, "bin"))
+		}
+		candidates = append(candidates, filepath.Join(d.WithRootDir, target.OS, target.Arch, "bin"))
+		for _, dir := range candidates {
+			if stat, err := os.Stat(dir); err == nil && stat.IsDir() {
+				return dir
+			}
+		}
+	}
+	return filepath.Join(d.WithRootDir, "bin")
+}
+
 func (d *BpDescriptor) ClearEnv() bool {
 	return d.Buildpack.ClearEnv
 }
@@ -157,6 +178,8 @@ type GroupElement struct {
 	Homepage string `toml:"homepage,omitempty" json:"homepage,omitempty"`
 	// Extension specifies whether the group element is a buildpack or an extension.
 	Extension bool `toml:"extension,omitempty" json:"-"`
+	// Digest is the digest of the directory of the buildpack or extension that was used (see DirDigest).
+	Digest string `toml:"digest,omitempty" json:"digest,omitempty"`
 
 	// Fields that are in order.toml only
 
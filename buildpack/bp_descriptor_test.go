@@ -176,4 +176,28 @@ func testBpDescriptor(t *testing.T, when spec.G, it spec.S) {
 			h.AssertEq(t, descriptor.Targets[1].OS, "linux")
 		})
 	})
+
+	when("#BinDir", func() {
+		var descriptor *buildpack.BpDescriptor
+
+		it.Before(func() {
+			descriptor = &buildpack.BpDescriptor{WithRootDir: t.TempDir()}
+			h.Mkdir(t,
+				filepath.Join(descriptor.WithRootDir, "bin"),
+				filepath.Join(descriptor.WithRootDir, "linux", "amd64", "bin"),
+				filepath.Join(descriptor.WithRootDir, "linux", "arm64", "v8", "bin"),
+			)
+		})
+
+		it("selects the executables for the target", func() {
+			h.AssertEq(t, descriptor.BinDir(buildpack.TargetMetadata{OS: "linux", Arch: "amd64"}), filepath.Join(descriptor.WithRootDir, "linux", "amd64", "bin"))
+			h.AssertEq(t, descriptor.BinDir(buildpack.TargetMetadata{OS: "linux", Arch: "amd64", ArchVariant: "v3"}), filepath.Join(descriptor.WithRootDir, "linux", "amd64", "bin"))
+			h.AssertEq(t, descriptor.BinDir(buildpack.TargetMetadata{OS: "linux", Arch: "arm64", ArchVariant: "v8"}), filepath.Join(descriptor.WithRootDir, "linux", "arm64", "v8", "bin"))
+		})
+
+		it("falls back to the bin directory of the buildpack", func() {
+			h.AssertEq(t, descriptor.BinDir(buildpack.TargetMetadata{OS: "linux", Arch: "arm64"}), filepath.Join(descriptor.WithRootDir, "bin"))
+			h.AssertEq(t, descriptor.BinDir(buildpack.TargetMetadata{}), filepath.Join(descriptor.WithRootDir, "bin"))
+		})
+	})
 }
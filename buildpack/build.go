@@ -1,6 +1,7 @@
 package buildpack
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -13,8 +14,10 @@ import (
 
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/env"
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/internal/encoding"
 	"github.com/buildpacks/lifecycle/internal/fsutil"
+	"github.com/buildpacks/lifecycle/internal/usage"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/log"
@@ -35,6 +38,8 @@ type BuildInputs struct {
 	LayersDir      string
 	PlatformDir    string
 	Env            BuildEnv
+	NetworkEnv     []string       // overrides the environment of the buildpack to restrict its network access, e.g., with a denying proxy
+	Target         TargetMetadata // the target of the build, selecting the executables of buildpacks providing them for several targets
 	Out, Err       io.Writer
 	Plan           Plan
 }
@@ -54,6 +59,9 @@ type BuildOutputs struct {
 	MetRequires []string
 	Processes   []launch.Process
 	Slices      []layers.Slice
+	Tasks       []launch.Task
+	// EnvOrder is the union of the env order constraints of the build and launch layers of the buildpack, if any.
+	EnvOrder *EnvOrder
 }
 
 //go:generate mockgen -package testmock -destination ../testmock/build_executor.go github.com/buildpacks/lifecycle/buildpack BuildExecutor
@@ -61,9 +69,22 @@ type BuildExecutor interface {
 	Build(d BpDescriptor, inputs BuildInputs, logger log.Logger) (BuildOutputs, error)
 }
 
+// ContextBuildExecutor is implemented by build executors that stop building when the provided context is done.
+type ContextBuildExecutor interface {
+	BuildContext(ctx context.Context, d BpDescriptor, inputs BuildInputs, logger log.Logger) (BuildOutputs, error)
+}
+
 type DefaultBuildExecutor struct{}
 
+// Build is equivalent to BuildContext with context.Background().
+//
+// Deprecated: use BuildContext.
 func (e *DefaultBuildExecutor) Build(d BpDescriptor, inputs BuildInputs, logger log.Logger) (BuildOutputs, error) {
+	return e.BuildContext(context.Background(), d, inputs, logger)
+}
+
+// BuildContext runs the ./bin/build executable of the buildpack, killing it if the context is done before it exits.
+func (e *DefaultBuildExecutor) BuildContext(ctx context.Context, d BpDescriptor, inputs BuildInputs, logger log.Logger) (BuildOutputs, error) {
 	if api.MustParse(d.WithAPI).Equal(api.MustParse("0.2")) {
 		logger.Debug("Updating plan entries")
 		for i := range inputs.Plan.Entries {
@@ -85,7 +106,7 @@ func (e *DefaultBuildExecutor) Build(d BpDescriptor, inputs BuildInputs, logger
 	}
 
 	logger.Debug("Running build command")
-	if err := runBuildCmd(d, bpLayersDir, planPath, inputs, inputs.Env); err != nil {
+	if err := runBuildCmd(ctx, d, bpLayersDir, planPath, inputs, inputs.Env); err != nil {
 		return BuildOutputs{}, err
 	}
 
@@ -101,7 +122,22 @@ func (e *DefaultBuildExecutor) Build(d BpDescriptor, inputs BuildInputs, logger
 	}
 
 	logger.Debug("Reading output files")
-	return d.readOutputFilesBp(bpLayersDir, planPath, inputs.Plan, createdLayers, logger)
+	br, err := d.readOutputFilesBp(bpLayersDir, planPath, inputs.Plan, createdLayers, logger)
+	if err != nil {
+		return BuildOutputs{}, err
+	}
+	br.EnvOrder = envOrderOf(createdLayers)
+	return br, nil
+}
+
+func envOrderOf(createdLayers map[string]LayerMetadataFile) *EnvOrder {
+	var envOrder *EnvOrder
+	for _, layerMetadataFile := range createdLayers {
+		if layerMetadataFile.Build || layerMetadataFile.Launch {
+			envOrder = envOrder.Merge(layerMetadataFile.EnvOrder)
+		}
+	}
+	return envOrder
 }
 
 func prepareInputPaths(bpID string, plan Plan, layersDir, parentPlanDir string) (string, string, error) {
@@ -126,9 +162,10 @@ func prepareInputPaths(bpID string, plan Plan, layersDir, parentPlanDir string)
 	return bpLayersDir, planPath, nil
 }
 
-func runBuildCmd(d BpDescriptor, bpLayersDir, planPath string, inputs BuildInputs, buildEnv BuildEnv) error {
-	cmd := exec.Command(
-		filepath.Join(d.WithRootDir, "bin", "build"),
+func runBuildCmd(ctx context.Context, d BpDescriptor, bpLayersDir, planPath string, inputs BuildInputs, buildEnv BuildEnv) error {
+	cmd := exec.CommandContext(
+		ctx,
+		filepath.Join(d.BinDir(inputs.Target), "build"),
 		bpLayersDir,
 		inputs.PlatformDir,
 		planPath,
@@ -154,9 +191,12 @@ func runBuildCmd(d BpDescriptor, bpLayersDir, planPath string, inputs BuildInput
 			EnvLayersDir+"="+bpLayersDir,
 		)
 	}
+	cmd.Env = append(cmd.Env, inputs.NetworkEnv...)
 
-	if err = cmd.Run(); err != nil {
-		return NewError(err, ErrTypeBuildpack)
+	err = cmd.Run()
+	usage.RecordProcess(d.Buildpack.ID, d.Buildpack.Version, "build", cmd.ProcessState, bpLayersDir)
+	if err != nil {
+		return NewError(lerrors.NewBuildpackFailedError(d.Buildpack.ID, err), ErrTypeBuildpack)
 	}
 	return nil
 }
@@ -249,7 +289,7 @@ func (d BpDescriptor) readOutputFilesBp(bpLayersDir, bpPlanPath string, bpPlanIn
 		// read buildpack plan
 		var bpPlanOut Plan
 		if _, err := toml.DecodeFile(bpPlanPath, &bpPlanOut); err != nil {
-			return BuildOutputs{}, err
+			return BuildOutputs{}, lerrors.NewDecodeError(bpPlanPath, err)
 		}
 
 		// set BOM and MetRequires
@@ -276,7 +316,7 @@ func (d BpDescriptor) readOutputFilesBp(bpLayersDir, bpPlanPath string, bpPlanIn
 		var buildTOML BuildTOML
 		buildPath := filepath.Join(bpLayersDir, "build.toml")
 		if _, err := toml.DecodeFile(buildPath, &buildTOML); err != nil && !os.IsNotExist(err) {
-			return BuildOutputs{}, err
+			return BuildOutputs{}, lerrors.NewDecodeError(buildPath, err)
 		}
 		if _, err := bomValidator.ValidateBOM(bpFromBpInfo, buildTOML.BOM); err != nil {
 			return BuildOutputs{}, err
@@ -333,6 +373,20 @@ func (d BpDescriptor) readOutputFilesBp(bpLayersDir, bpPlanPath string, bpPlanIn
 	br.Processes = append([]launch.Process{}, launchTOML.ToLaunchProcessesForBuildpack(d.Buildpack.ID)...)
 	br.Slices = append([]layers.Slice{}, launchTOML.Slices...)
 
+	if len(launchTOML.Tasks) > 0 {
+		bpAPI := api.MustParse(d.WithAPI)
+		if bpAPI.LessThan("0.11") {
+			logger.Warn("Warning: tasks aren't supported in this buildpack api version. Ignoring tasks")
+		} else {
+			if err := validateTasks(launchTOML, bpAPI); err != nil {
+				return BuildOutputs{}, err
+			}
+			for _, task := range launchTOML.Tasks {
+				br.Tasks = append(br.Tasks, task.ToLaunchTask(d.Buildpack.ID))
+			}
+		}
+	}
+
 	return br, nil
 }
 
@@ -380,6 +434,30 @@ func overrideDefaultForOldBuildpacks(processes []ProcessEntry, bpAPI string, log
 	return nil
 }
 
+func validateTasks(launchTOML LaunchTOML, bpAPI *api.Version) error {
+	types := make(map[string]bool)
+	for _, process := range launchTOML.Processes {
+		types[process.Type] = true
+	}
+	for _, task := range launchTOML.Tasks {
+		switch {
+		case task.Type == "":
+			return fmt.Errorf("task type is required")
+		case types[task.Type]:
+			return fmt.Errorf("task type '%s' is already used by a process or another task", task.Type)
+		case task.Run != launch.TaskRunExport && task.Run != launch.TaskRunFirstBoot:
+			return fmt.Errorf("task '%s' has invalid run value '%s', must be one of: %s, %s", task.Type, task.Run, launch.TaskRunExport, launch.TaskRunFirstBoot)
+		case len(task.Command) == 0:
+			return fmt.Errorf("task '%s' has no command", task.Type)
+		case task.Direct != nil && bpAPI.AtLeast("0.11"):
+			// like process.direct, direct is not allowed as a key
+			return fmt.Errorf("task.direct is not supported on this buildpack version")
+		}
+		types[task.Type] = true
+	}
+	return nil
+}
+
 func validateNoMultipleDefaults(processes []ProcessEntry) error {
 	defaultType := ""
 	for _, process := range processes {
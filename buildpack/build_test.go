@@ -2,10 +2,12 @@ package buildpack_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -21,6 +23,7 @@ import (
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/env"
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/layers"
 	llog "github.com/buildpacks/lifecycle/log"
@@ -113,6 +116,28 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 	})
 
 	when("#Build", func() {
+		when("the buildpack provides executables for several targets", func() {
+			it.Before(func() {
+				if runtime.GOOS == "windows" {
+					t.Skip("executables are shell scripts")
+				}
+				descriptor.WithRootDir = filepath.Join(tmpDir, "multi-target-buildpack")
+				for _, arch := range []string{"amd64", "arm64"} {
+					binDir := filepath.Join(descriptor.WithRootDir, "linux", arch, "bin")
+					h.Mkdir(t, binDir)
+					h.AssertNil(t, os.WriteFile(filepath.Join(binDir, "build"), []byte("#!/bin/sh\necho -n "+arch+" > build-target\n"), 0755))
+				}
+				mockEnv.EXPECT().WithOverrides(platformDir, buildConfigDir).Return(os.Environ(), nil)
+			})
+
+			it("runs the executable for the target", func() {
+				inputs.Target = buildpack.TargetMetadata{OS: "linux", Arch: "arm64"}
+				_, err := executor.Build(descriptor, inputs, logger)
+				h.AssertNil(t, err)
+				h.AssertEq(t, h.Rdfile(t, filepath.Join(appDir, "build-target")), "arm64")
+			})
+		})
+
 		when("env", func() {
 			when("clear", func() {
 				it.Before(func() {
@@ -179,6 +204,14 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 					}
 				})
 
+				it("overrides the env with the network env", func() {
+					inputs.NetworkEnv = []string{"TEST_ENV=no-network"}
+					if _, err := executor.Build(descriptor, inputs, logger); err != nil {
+						t.Fatalf("Unexpected error:\n%s\n", err)
+					}
+					h.AssertEq(t, h.Rdfile(t, filepath.Join(appDir, "build-info-A-v1")), "TEST_ENV: no-network\n")
+				})
+
 				it("sets CNB_ vars", func() {
 					if _, err := executor.Build(descriptor, inputs, logger); err != nil {
 						t.Fatalf("Unexpected error:\n%s\n", err)
@@ -352,9 +385,41 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 					if err, ok := err.(*buildpack.Error); !ok || err.Type != buildpack.ErrTypeBuildpack {
 						t.Fatalf("Incorrect error: %s\n", err)
 					}
+					var bpErr *lerrors.BuildpackFailedError
+					h.AssertEq(t, errors.As(err, &bpErr), true)
+					h.AssertEq(t, bpErr.ID, "A")
+					h.AssertEq(t, bpErr.ExitCode > 0, true)
+				})
+
+				it("does not run the command when the context is done", func() {
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel()
+
+					_, err := executor.BuildContext(ctx, descriptor, inputs, logger)
+					h.AssertEq(t, errors.Is(err, context.Canceled), true)
+					h.AssertPathDoesNotExist(t, filepath.Join(appDir, "build-info-A-v1"))
 				})
 
 				when("<layer>.toml", func() {
+					it("returns the env order constraints of the build and launch layers", func() {
+						h.Mkdir(t,
+							filepath.Join(layersDir, "A", "launch-layer"),
+							filepath.Join(layersDir, "A", "cache-layer"),
+						)
+						h.Mkfile(t,
+							"[types]\n  launch=true\n[env-order]\n  before=[\"B\"]\n  after=[\"C\"]",
+							filepath.Join(layersDir, "A", "launch-layer.toml"),
+						)
+						h.Mkfile(t,
+							"[types]\n  cache=true\n[env-order]\n  before=[\"D\"]",
+							filepath.Join(layersDir, "A", "cache-layer.toml"),
+						)
+
+						br, err := executor.Build(descriptor, inputs, logger)
+						h.AssertNil(t, err)
+						h.AssertEq(t, br.EnvOrder, &buildpack.EnvOrder{Before: []string{"B"}, After: []string{"C"}})
+					})
+
 					when("the launch, cache and build flags are false", func() {
 						when("the flags are specified in <layer>.toml", func() {
 							it("renames <layers>/<layer> to <layers>/<layer>.ignore", func() {
@@ -846,6 +911,119 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 							h.AssertEq(t, len(br.Processes), 1)
 							h.AssertEq(t, br.Processes[0].WorkingDirectory, "/working-directory")
 						})
+
+						it("sets the description and metadata", func() {
+							h.Mkfile(t,
+								"[[processes]]\n"+
+									`type = "web"`+"\n"+
+									`command = ["some-cmd"]`+"\n"+
+									`description = "serves HTTP on $PORT"`+"\n"+
+									"[processes.metadata]\n"+
+									`icon = "https://example.com/web.svg"`,
+								filepath.Join(appDir, "launch-A-v1.toml"),
+							)
+							br, err := executor.Build(descriptor, inputs, logger)
+							h.AssertNil(t, err)
+							h.AssertEq(t, len(br.Processes), 1)
+							h.AssertEq(t, br.Processes[0].Description, "serves HTTP on $PORT")
+							h.AssertEq(t, br.Processes[0].Metadata, map[string]string{"icon": "https://example.com/web.svg"})
+						})
+					})
+
+					when("tasks", func() {
+						it("includes tasks", func() {
+							h.Mkfile(t,
+								`[[processes]]`+"\n"+
+									`type = "web"`+"\n"+
+									`command = ["some-cmd"]`+"\n"+
+									`[[tasks]]`+"\n"+
+									`type = "migrate"`+"\n"+
+									`command = ["some-migrate-cmd", "cmd-arg"]`+"\n"+
+									`args = ["first-arg"]`+"\n"+
+									`run = "export"`+"\n"+
+									`[[tasks]]`+"\n"+
+									`type = "warm-cache"`+"\n"+
+									`command = ["some-warm-cmd"]`+"\n"+
+									`working-dir = "/working-directory"`+"\n"+
+									`run = "first-boot"`+"\n",
+								filepath.Join(appDir, "launch-A-v1.toml"),
+							)
+							br, err := executor.Build(descriptor, inputs, logger)
+							h.AssertNil(t, err)
+
+							h.AssertEq(t, len(br.Processes), 1)
+							h.AssertEq(t, br.Tasks, []launch.Task{
+								{
+									Process: launch.Process{Type: "migrate", Command: launch.NewRawCommand([]string{"some-migrate-cmd", "cmd-arg"}), Args: []string{"first-arg"}, BuildpackID: "A", Direct: true},
+									Run:     "export",
+								},
+								{
+									Process: launch.Process{Type: "warm-cache", Command: launch.NewRawCommand([]string{"some-warm-cmd"}), BuildpackID: "A", Direct: true, WorkingDirectory: "/working-directory"},
+									Run:     "first-boot",
+								},
+							}, processCmpOpts...)
+						})
+
+						it("errors when the run value is invalid", func() {
+							h.Mkfile(t,
+								`[[tasks]]`+"\n"+
+									`type = "migrate"`+"\n"+
+									`command = ["some-cmd"]`+"\n"+
+									`run = "always"`+"\n",
+								filepath.Join(appDir, "launch-A-v1.toml"),
+							)
+							_, err := executor.Build(descriptor, inputs, logger)
+							h.AssertError(t, err, "task 'migrate' has invalid run value 'always', must be one of: export, first-boot")
+						})
+
+						it("errors when the type is used by a process", func() {
+							h.Mkfile(t,
+								`[[processes]]`+"\n"+
+									`type = "migrate"`+"\n"+
+									`command = ["some-cmd"]`+"\n"+
+									`[[tasks]]`+"\n"+
+									`type = "migrate"`+"\n"+
+									`command = ["some-cmd"]`+"\n"+
+									`run = "export"`+"\n",
+								filepath.Join(appDir, "launch-A-v1.toml"),
+							)
+							_, err := executor.Build(descriptor, inputs, logger)
+							h.AssertError(t, err, "task type 'migrate' is already used by a process or another task")
+						})
+
+						it("errors when direct is set", func() {
+							h.Mkfile(t,
+								`[[tasks]]`+"\n"+
+									`type = "migrate"`+"\n"+
+									`command = ["some-cmd"]`+"\n"+
+									`direct = true`+"\n"+
+									`run = "export"`+"\n",
+								filepath.Join(appDir, "launch-A-v1.toml"),
+							)
+							_, err := executor.Build(descriptor, inputs, logger)
+							h.AssertError(t, err, "task.direct is not supported on this buildpack version")
+						})
+
+						when("the buildpack api does not support tasks", func() {
+							it.Before(func() {
+								descriptor.WithAPI = "0.10"
+							})
+
+							it("ignores tasks", func() {
+								h.Mkfile(t,
+									`[[tasks]]`+"\n"+
+										`type = "migrate"`+"\n"+
+										`command = ["some-cmd"]`+"\n"+
+										`run = "export"`+"\n",
+									filepath.Join(appDir, "launch-A-v1.toml"),
+								)
+								br, err := executor.Build(descriptor, inputs, logger)
+								h.AssertNil(t, err)
+
+								h.AssertEq(t, len(br.Tasks), 0)
+								assertLogEntry(t, logHandler, "tasks aren't supported in this buildpack api version")
+							})
+						})
 					})
 
 					when("slices", func() {
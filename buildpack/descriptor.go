@@ -13,7 +13,8 @@ type Descriptor interface {
 }
 
 // BaseInfo is information shared by both buildpacks and extensions.
-// For buildpacks it winds up under the toml `buildpack` key along with SBOM info, but extensions have no SBOMs.
+// For buildpacks it winds up under the toml `buildpack` key and for extensions under the toml `extension` key,
+// in both cases along with SBOM info.
 type BaseInfo struct {
 	ClearEnv bool   `toml:"clear-env,omitempty"`
 	Homepage string `toml:"homepage,omitempty"`
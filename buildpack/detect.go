@@ -2,6 +2,7 @@ package buildpack
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -11,6 +12,9 @@ import (
 	"github.com/BurntSushi/toml"
 
 	"github.com/buildpacks/lifecycle/api"
+	lerrors "github.com/buildpacks/lifecycle/errors"
+	"github.com/buildpacks/lifecycle/internal/deprecation"
+	"github.com/buildpacks/lifecycle/internal/usage"
 	"github.com/buildpacks/lifecycle/log"
 )
 
@@ -30,6 +34,7 @@ type DetectInputs struct {
 	BuildConfigDir string
 	PlatformDir    string
 	Env            BuildEnv
+	Target         TargetMetadata // the target of the build, selecting the executables of buildpacks providing them for several targets
 }
 
 type DetectOutputs struct {
@@ -44,33 +49,46 @@ type DetectExecutor interface {
 	Detect(d Descriptor, inputs DetectInputs, logger log.Logger) DetectOutputs
 }
 
+// ContextDetectExecutor is implemented by detect executors that stop detection when the provided context is done.
+type ContextDetectExecutor interface {
+	DetectContext(ctx context.Context, d Descriptor, inputs DetectInputs, logger log.Logger) DetectOutputs
+}
+
 type DefaultDetectExecutor struct{}
 
+// Detect is equivalent to DetectContext with context.Background().
+//
+// Deprecated: use DetectContext.
 func (e *DefaultDetectExecutor) Detect(d Descriptor, inputs DetectInputs, logger log.Logger) DetectOutputs {
+	return e.DetectContext(context.Background(), d, inputs, logger)
+}
+
+// DetectContext runs the ./bin/detect executable of the buildpack or extension, killing it if the context is done before it exits.
+func (e *DefaultDetectExecutor) DetectContext(ctx context.Context, d Descriptor, inputs DetectInputs, logger log.Logger) DetectOutputs {
 	switch descriptor := d.(type) {
 	case *BpDescriptor:
-		return detectBp(*descriptor, inputs, logger)
+		return detectBp(ctx, *descriptor, inputs, logger)
 	case *ExtDescriptor:
-		return detectExt(*descriptor, inputs, logger)
+		return detectExt(ctx, *descriptor, inputs, logger)
 	default:
 		return DetectOutputs{Code: -1, Err: fmt.Errorf("unknown descriptor type: %t", descriptor)}
 	}
 }
 
-func detectBp(d BpDescriptor, inputs DetectInputs, logger log.Logger) DetectOutputs {
+func detectBp(ctx context.Context, d BpDescriptor, inputs DetectInputs, logger log.Logger) DetectOutputs {
 	planDir, planPath, err := processBuildpackPaths()
 	defer os.RemoveAll(planDir)
 	if err != nil {
 		return DetectOutputs{Code: -1, Err: err}
 	}
 
-	result := runDetect(&d, inputs, planPath, EnvBuildpackDir)
+	result := runDetect(ctx, &d, d.BinDir(inputs.Target), d.Buildpack.BaseInfo, inputs, planPath, EnvBuildpackDir)
 	if result.Code != 0 {
 		return result
 	}
 	backupOut := result.Output
 	if _, err := toml.DecodeFile(planPath, &result); err != nil {
-		return DetectOutputs{Code: -1, Err: err, Output: backupOut}
+		return DetectOutputs{Code: -1, Err: lerrors.NewDecodeError(planPath, err), Output: backupOut}
 	}
 
 	if api.MustParse(d.WithAPI).Equal(api.MustParse("0.2")) {
@@ -87,14 +105,14 @@ func detectBp(d BpDescriptor, inputs DetectInputs, logger log.Logger) DetectOutp
 	}
 	if api.MustParse(d.WithAPI).AtLeast("0.3") {
 		if result.hasTopLevelVersions() || result.Or.hasTopLevelVersions() {
-			logger.Warnf(`buildpack %s has a "version" key. This key is deprecated in build plan requirements in buildpack API 0.3. "metadata.version" should be used instead`, d.Buildpack.ID)
+			recordBuildPlanVersionKey("buildpack", d.Buildpack.ID, d.WithAPI, logger)
 		}
 	}
 
 	return result
 }
 
-func detectExt(d ExtDescriptor, inputs DetectInputs, logger log.Logger) DetectOutputs {
+func detectExt(ctx context.Context, d ExtDescriptor, inputs DetectInputs, logger log.Logger) DetectOutputs {
 	planDir, planPath, err := processBuildpackPaths()
 	defer os.RemoveAll(planDir)
 	if err != nil {
@@ -107,16 +125,16 @@ func detectExt(d ExtDescriptor, inputs DetectInputs, logger log.Logger) DetectOu
 		// treat extension root directory as pre-populated output directory
 		planPath = filepath.Join(d.WithRootDir, "detect", "plan.toml")
 		if _, err := toml.DecodeFile(planPath, &result); err != nil && !os.IsNotExist(err) {
-			return DetectOutputs{Code: -1, Err: err}
+			return DetectOutputs{Code: -1, Err: lerrors.NewDecodeError(planPath, err)}
 		}
 	} else {
-		result = runDetect(&d, inputs, planPath, EnvExtensionDir)
+		result = runDetect(ctx, &d, filepath.Join(d.WithRootDir, "bin"), d.Extension.BaseInfo, inputs, planPath, EnvExtensionDir)
 		if result.Code != 0 {
 			return result
 		}
 		backupOut := result.Output
 		if _, err := toml.DecodeFile(planPath, &result); err != nil {
-			return DetectOutputs{Code: -1, Err: err, Output: backupOut}
+			return DetectOutputs{Code: -1, Err: lerrors.NewDecodeError(planPath, err), Output: backupOut}
 		}
 	}
 
@@ -125,7 +143,7 @@ func detectExt(d ExtDescriptor, inputs DetectInputs, logger log.Logger) DetectOu
 		result.Code = -1
 	}
 	if result.hasTopLevelVersions() || result.Or.hasTopLevelVersions() {
-		logger.Warnf(`extension %s has a "version" key. This key is deprecated in build plan requirements in buildpack API 0.3. "metadata.version" should be used instead`, d.Extension.ID)
+		recordBuildPlanVersionKey("extension", d.Extension.ID, d.WithAPI, logger)
 	}
 	if result.hasRequires() || result.Or.hasRequires() {
 		result.Err = fmt.Errorf(`extension %s outputs "requires" which is not allowed`, d.Extension.ID)
@@ -153,10 +171,11 @@ type detectable interface {
 	RootDir() string
 }
 
-func runDetect(d detectable, inputs DetectInputs, planPath string, envRootDirKey string) DetectOutputs {
+func runDetect(ctx context.Context, d detectable, binDir string, info BaseInfo, inputs DetectInputs, planPath string, envRootDirKey string) DetectOutputs {
 	out := &bytes.Buffer{}
-	cmd := exec.Command(
-		filepath.Join(d.RootDir(), "bin", "detect"),
+	cmd := exec.CommandContext(
+		ctx,
+		filepath.Join(binDir, "detect"),
 		inputs.PlatformDir,
 		planPath,
 	) // #nosec G204
@@ -182,7 +201,9 @@ func runDetect(d detectable, inputs DetectInputs, planPath string, envRootDirKey
 		)
 	}
 
-	if err := cmd.Run(); err != nil {
+	err = cmd.Run()
+	usage.RecordProcess(info.ID, info.Version, "detect", cmd.ProcessState, "")
+	if err != nil {
 		if err, ok := err.(*exec.ExitError); ok {
 			if status, ok := err.Sys().(syscall.WaitStatus); ok {
 				return DetectOutputs{Code: status.ExitStatus(), Output: out.Bytes()}
@@ -192,3 +213,9 @@ func runDetect(d detectable, inputs DetectInputs, planPath string, envRootDirKey
 	}
 	return DetectOutputs{Code: 0, Err: nil, Output: out.Bytes()}
 }
+
+func recordBuildPlanVersionKey(kind, id, withAPI string, logger log.Logger) {
+	message := fmt.Sprintf(`%s %s has a "version" key. This key is deprecated in build plan requirements in buildpack API 0.3. "metadata.version" should be used instead`, kind, id)
+	deprecation.Record(deprecation.Notice{Kind: deprecation.KindLegacyFormat, Subject: id, API: withAPI, Message: message})
+	logger.Warn(message)
+}
@@ -0,0 +1,72 @@
+package buildpack
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// DirDigest returns the digest of the contents of a buildpack or extension directory, e.g., "sha256:<hex>".
+// The digest covers the path, type, permissions and contents (or link target) of every file in the directory,
+// so that it changes if any file is added, removed or modified, but not if the directory is copied elsewhere.
+func DirDigest(dir string) (string, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		writeField(hasher, filepath.ToSlash(rel))
+		writeField(hasher, strconv.FormatUint(uint64(info.Mode()&(fs.ModeType|fs.ModePerm)), 8))
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			writeField(hasher, filepath.ToSlash(target))
+		case info.Mode().IsRegular():
+			writeField(hasher, strconv.FormatInt(info.Size(), 10))
+			return copyFile(hasher, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("computing digest of %s: %w", dir, err)
+	}
+	return fmt.Sprintf("sha256:%x", hasher.Sum(nil)), nil
+}
+
+// writeField writes a NUL-terminated field, so that adjacent fields cannot be confused.
+func writeField(h hash.Hash, field string) {
+	_, _ = h.Write(append([]byte(field), 0))
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
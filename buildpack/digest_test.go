@@ -0,0 +1,89 @@
+package buildpack_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestDigest(t *testing.T) {
+	spec.Run(t, "unit-digest", testDigest, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testDigest(t *testing.T, when spec.G, it spec.S) {
+	var dir string
+
+	makeBuildpack := func() string {
+		dir := t.TempDir()
+		h.Mkdir(t, filepath.Join(dir, "bin"))
+		h.Mkfile(t, "some-build-script", filepath.Join(dir, "bin", "build"))
+		h.Mkfile(t, "some-detect-script", filepath.Join(dir, "bin", "detect"))
+		h.AssertNil(t, os.Symlink("build", filepath.Join(dir, "bin", "generate")))
+		return dir
+	}
+
+	it.Before(func() {
+		dir = makeBuildpack()
+	})
+
+	digest := func(dir string) string {
+		t.Helper()
+		digest, err := buildpack.DirDigest(dir)
+		h.AssertNil(t, err)
+		return digest
+	}
+
+	assertChanged := func(before string) {
+		t.Helper()
+		if digest(dir) == before {
+			t.Fatalf("expected digest to change from %s", before)
+		}
+	}
+
+	when("#DirDigest", func() {
+		it("returns a sha256 digest", func() {
+			h.AssertEq(t, strings.HasPrefix(digest(dir), "sha256:"), true)
+		})
+
+		it("does not depend on the location of the directory", func() {
+			h.AssertEq(t, digest(makeBuildpack()), digest(dir))
+		})
+
+		it("changes when the contents of a file change", func() {
+			before := digest(dir)
+			h.Mkfile(t, "some-other-build-script", filepath.Join(dir, "bin", "build"))
+			assertChanged(before)
+		})
+
+		it("changes when a file is renamed", func() {
+			before := digest(dir)
+			h.AssertNil(t, os.Rename(filepath.Join(dir, "bin", "detect"), filepath.Join(dir, "bin", "detect.sh")))
+			assertChanged(before)
+		})
+
+		it("changes when the permissions of a file change", func() {
+			before := digest(dir)
+			h.AssertNil(t, os.Chmod(filepath.Join(dir, "bin", "build"), 0755))
+			assertChanged(before)
+		})
+
+		it("changes when the target of a symlink changes", func() {
+			before := digest(dir)
+			h.AssertNil(t, os.Remove(filepath.Join(dir, "bin", "generate")))
+			h.AssertNil(t, os.Symlink("detect", filepath.Join(dir, "bin", "generate")))
+			assertChanged(before)
+		})
+
+		it("errors if the directory does not exist", func() {
+			_, err := buildpack.DirDigest(filepath.Join(dir, "missing"))
+			h.AssertNotNil(t, err)
+		})
+	})
+}
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/moby/buildkit/frontend/dockerfile/shell"
 
 	"github.com/buildpacks/lifecycle/log"
 )
@@ -29,6 +32,10 @@ const (
 	errMissingRequiredStage             = "%s should have at least one stage"
 	errMultiStageNotPermitted           = "%s is not permitted to use multistage build"
 	errRunOtherInstructionsNotPermitted = "run.Dockerfile is not permitted to have instructions other than FROM"
+	errRunFromArgNotPermitted           = "run.Dockerfile FROM on line %d is not permitted to reference arguments other than base_image"
+	errCopyFromNotPermitted             = "%s command %s on line %d is not permitted to copy from another stage or image"
+	errMountFromNotPermitted            = "%s command %s on line %d is not permitted to mount from another stage or image"
+	errCopyOutsideContextNotPermitted   = "%s command %s on line %d is not permitted to copy from outside the build context: '%s'"
 	warnCommandNotRecommended           = "%s command %s on line %d is not recommended"
 )
 
@@ -95,6 +102,9 @@ func ValidateBuildDockerfile(dockerfile string, logger log.Logger) error {
 	// validate only permitted Commands
 	for _, stage := range stages {
 		for _, command := range stage.Commands {
+			if err = validateCommand(command, buildDockerfileName); err != nil {
+				return err
+			}
 			found := false
 			for _, rc := range recommendedCommands {
 				if rc == strings.ToUpper(command.Name()) {
@@ -128,7 +138,7 @@ func ValidateBuildDockerfile(dockerfile string, logger log.Logger) error {
 }
 
 func ValidateRunDockerfile(dInfo *DockerfileInfo, logger log.Logger) error {
-	stages, _, err := parseDockerfile(dInfo.Path)
+	stages, margs, err := parseDockerfile(dInfo.Path)
 	if err != nil {
 		return err
 	}
@@ -147,11 +157,18 @@ func ValidateRunDockerfile(dInfo *DockerfileInfo, logger log.Logger) error {
 	)
 	// validate only permitted Commands
 	for _, stage := range stages {
-		if stage.BaseName != baseImageArgRef {
+		referencesBase, err := validateRunFrom(stage, margs)
+		if err != nil {
+			return err
+		}
+		if !referencesBase {
 			newBase = stage.BaseName
 		}
 		for _, command := range stage.Commands {
 			extend = true
+			if err = validateCommand(command, runDockerfileName); err != nil {
+				return err
+			}
 			found := false
 			for _, rc := range recommendedCommands {
 				if rc == strings.ToUpper(command.Name()) {
@@ -169,3 +186,65 @@ func ValidateRunDockerfile(dInfo *DockerfileInfo, logger log.Logger) error {
 	dInfo.Extend = extend
 	return nil
 }
+
+// validateRunFrom ensures that the FROM instruction of the provided run.Dockerfile stage
+// does not reference arguments other than base_image, and returns whether it references base_image.
+func validateRunFrom(stage instructions.Stage, margs []instructions.ArgCommand) (bool, error) {
+	// FROM may only reference arguments declared before it
+	env := map[string]string{baseImageArgName: ""}
+	for _, marg := range margs {
+		for _, arg := range marg.Args {
+			env[arg.Key] = ""
+		}
+	}
+	_, matches, err := shell.NewLex(parser.DefaultEscapeToken).ProcessWordWithMatches(stage.BaseName, env)
+	if err != nil {
+		return false, err
+	}
+	for argName := range matches {
+		if argName != baseImageArgName {
+			return false, fmt.Errorf(errRunFromArgNotPermitted, stage.Location[0].Start.Line)
+		}
+	}
+	_, referencesBase := matches[baseImageArgName]
+	return referencesBase, nil
+}
+
+// validateCommand ensures that the provided command does not read from outside the build context.
+func validateCommand(command instructions.Command, dockerfileName string) error {
+	name := strings.ToUpper(command.Name())
+	line := command.Location()[0].Start.Line
+	var sources []string
+	switch c := command.(type) {
+	case *instructions.CopyCommand:
+		if c.From != "" {
+			return fmt.Errorf(errCopyFromNotPermitted, dockerfileName, name, line)
+		}
+		sources = c.SourcePaths
+	case *instructions.AddCommand:
+		sources = c.SourcePaths
+	case *instructions.RunCommand:
+		for _, mount := range instructions.GetMounts(c) {
+			if mount.From != "" {
+				return fmt.Errorf(errMountFromNotPermitted, dockerfileName, name, line)
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+	for _, source := range sources {
+		if isOutsideContext(source) {
+			return fmt.Errorf(errCopyOutsideContextNotPermitted, dockerfileName, name, line, source)
+		}
+	}
+	return nil
+}
+
+func isOutsideContext(source string) bool {
+	if strings.Contains(source, "://") || strings.HasPrefix(source, "git@") {
+		return true
+	}
+	cleaned := path.Clean(filepath.ToSlash(source))
+	return cleaned == ".." || strings.HasPrefix(cleaned, "../")
+}
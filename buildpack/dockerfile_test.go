@@ -181,6 +181,39 @@ COPY --from=0 /some-source.txt ./some-dest.txt
 `,
 							expectedError: "build.Dockerfile is not permitted to use multistage build",
 						},
+						{
+							dockerfileContent: `
+ARG base_image=0
+FROM ${base_image}
+COPY --from=some-image /some-source.txt ./some-dest.txt
+`,
+							expectedError: "build.Dockerfile command COPY on line 4 is not permitted to copy from another stage or image",
+						},
+						{
+							dockerfileContent: `
+ARG base_image=0
+FROM ${base_image}
+RUN --mount=type=bind,from=some-image,target=/some-target ls /some-target
+`,
+							expectedError: "build.Dockerfile command RUN on line 4 is not permitted to mount from another stage or image",
+						},
+						{
+							dockerfileContent: `
+ARG base_image=0
+FROM ${base_image}
+RUN echo "hello" > /world.txt
+COPY ../some-source.txt ./some-dest.txt
+`,
+							expectedError: "build.Dockerfile command COPY on line 5 is not permitted to copy from outside the build context: '../some-source.txt'",
+						},
+						{
+							dockerfileContent: `
+ARG base_image=0
+FROM ${base_image}
+ADD https://example.com/some-source.txt ./some-dest.txt
+`,
+							expectedError: "build.Dockerfile command ADD on line 4 is not permitted to copy from outside the build context: 'https://example.com/some-source.txt'",
+						},
 					}
 					for i, tc := range testCases {
 						dockerfilePath := filepath.Join(tmpDir, fmt.Sprintf("Dockerfile%d", i))
@@ -231,6 +264,22 @@ FROM ${base_image}
 					})
 				})
 
+				when("referencing base_image without braces", func() {
+					it("does not switch the runtime base image", func() {
+						dockerfilePath := filepath.Join(tmpDir, "run.Dockerfile")
+						h.AssertNil(t, os.WriteFile(dockerfilePath, []byte(`
+ARG base_image=0
+FROM $base_image
+RUN echo "hello" > /world.txt
+`), 0600))
+						dInfo := &buildpack.DockerfileInfo{Path: dockerfilePath}
+						err := buildpack.ValidateRunDockerfile(dInfo, logger)
+						h.AssertNil(t, err)
+						h.AssertEq(t, dInfo.Extend, true)
+						h.AssertEq(t, dInfo.WithBase, "")
+					})
+				})
+
 				when("switching the runtime base image", func() {
 					it("sets the new base image in the result", func() {
 						dockerfilePath := filepath.Join(tmpDir, "run.Dockerfile")
@@ -266,6 +315,30 @@ COPY --from=0 /some-source.txt ./some-dest.txt
 `,
 							expectedError: "run.Dockerfile is not permitted to use multistage build",
 						},
+						{
+							dockerfileContent: `
+ARG base_image=0
+ARG some_arg=some-base-image
+FROM ${some_arg}
+`,
+							expectedError: "run.Dockerfile FROM on line 4 is not permitted to reference arguments other than base_image",
+						},
+						{
+							dockerfileContent: `
+ARG base_image=0
+FROM ${base_image}
+RUN --mount=type=cache,from=some-image,target=/some-target ls /some-target
+`,
+							expectedError: "run.Dockerfile command RUN on line 4 is not permitted to mount from another stage or image",
+						},
+						{
+							dockerfileContent: `
+ARG base_image=0
+FROM ${base_image}
+COPY some-dir/../../some-source.txt ./some-dest.txt
+`,
+							expectedError: "run.Dockerfile command COPY on line 4 is not permitted to copy from outside the build context: 'some-dir/../../some-source.txt'",
+						},
 					}
 					for i, tc := range testCases {
 						dockerfilePath := filepath.Join(tmpDir, fmt.Sprintf("Dockerfile%d", i))
@@ -21,6 +21,10 @@ func (le *Error) Cause() error {
 	return le.RootError
 }
 
+func (le *Error) Unwrap() error {
+	return le.RootError
+}
+
 func NewError(cause error, errType ErrorType) *Error {
 	return &Error{RootError: cause, Type: errType}
 }
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 
 	"github.com/BurntSushi/toml"
+
+	lerrors "github.com/buildpacks/lifecycle/errors"
 )
 
 type ExtDescriptor struct {
@@ -18,6 +20,7 @@ type ExtDescriptor struct {
 
 type ExtInfo struct {
 	BaseInfo
+	SBOM []string `toml:"sbom-formats,omitempty"`
 }
 
 func ReadExtDescriptor(path string) (*ExtDescriptor, error) {
@@ -26,7 +29,7 @@ func ReadExtDescriptor(path string) (*ExtDescriptor, error) {
 		err        error
 	)
 	if _, err = toml.DecodeFile(path, &descriptor); err != nil {
-		return &ExtDescriptor{}, err
+		return &ExtDescriptor{}, lerrors.NewDecodeError(path, err)
 	}
 	if descriptor.WithRootDir, err = filepath.Abs(filepath.Dir(path)); err != nil {
 		return &ExtDescriptor{}, err
@@ -8,6 +8,7 @@ import (
 	"github.com/BurntSushi/toml"
 
 	"github.com/buildpacks/lifecycle/api"
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/layers"
 )
@@ -19,16 +20,26 @@ type LaunchTOML struct {
 	Labels    []Label
 	Processes []ProcessEntry `toml:"processes"`
 	Slices    []layers.Slice `toml:"slices"`
+	// Tasks are processes to run once rather than launch (Buildpack API >= 0.10).
+	Tasks []TaskEntry `toml:"tasks"`
 }
 
 type ProcessEntry struct {
-	Type             string         `toml:"type" json:"type"`
-	Command          []string       `toml:"-"` // ignored
-	RawCommandValue  toml.Primitive `toml:"command" json:"command"`
-	Args             []string       `toml:"args" json:"args"`
-	Direct           *bool          `toml:"direct" json:"direct"`
-	Default          bool           `toml:"default,omitempty" json:"default,omitempty"`
-	WorkingDirectory string         `toml:"working-dir,omitempty" json:"working-dir,omitempty"`
+	Type             string            `toml:"type" json:"type"`
+	Command          []string          `toml:"-"` // ignored
+	RawCommandValue  toml.Primitive    `toml:"command" json:"command"`
+	Args             []string          `toml:"args" json:"args"`
+	Direct           *bool             `toml:"direct" json:"direct"`
+	Default          bool              `toml:"default,omitempty" json:"default,omitempty"`
+	WorkingDirectory string            `toml:"working-dir,omitempty" json:"working-dir,omitempty"`
+	Description      string            `toml:"description,omitempty" json:"description,omitempty"`
+	Metadata         map[string]string `toml:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+// TaskEntry is a process that is run once, either by the platform after export or by the launcher on first boot.
+type TaskEntry struct {
+	ProcessEntry
+	Run string `toml:"run"`
 }
 
 // DecodeLaunchTOML reads a launch.toml file
@@ -36,7 +47,7 @@ func DecodeLaunchTOML(launchPath string, bpAPI string, launchTOML *LaunchTOML) e
 	// decode the common bits
 	md, err := toml.DecodeFile(launchPath, &launchTOML)
 	if err != nil {
-		return err
+		return lerrors.NewDecodeError(launchPath, err)
 	}
 
 	// decode the process.commands, which differ based on buildpack API
@@ -69,6 +80,15 @@ func DecodeLaunchTOML(launchPath string, bpAPI string, launchTOML *LaunchTOML) e
 		}
 	}
 
+	// tasks are only supported by newer buildpack APIs, so their commands are always arrays
+	for i, task := range launchTOML.Tasks {
+		var command []string
+		if err = md.PrimitiveDecode(task.RawCommandValue, &command); err != nil {
+			return err
+		}
+		launchTOML.Tasks[i].Command = command
+	}
+
 	return nil
 }
 
@@ -91,9 +111,18 @@ func (p *ProcessEntry) ToLaunchProcess(bpID string) launch.Process {
 		Default:          p.Default,
 		BuildpackID:      bpID,
 		WorkingDirectory: p.WorkingDirectory,
+		Description:      p.Description,
+		Metadata:         p.Metadata,
 	}
 }
 
+// ToLaunchTask converts a buildpack.TaskEntry to a launch.Task
+func (t *TaskEntry) ToLaunchTask(bpID string) launch.Task {
+	process := t.ToLaunchProcess(bpID)
+	process.Default = false
+	return launch.Task{Process: process, Run: t.Run}
+}
+
 // converts launch.toml processes to launch.Processes
 func (lt LaunchTOML) ToLaunchProcessesForBuildpack(bpID string) []launch.Process {
 	var processes []launch.Process
@@ -288,6 +317,20 @@ func (p Plan) filter(unmet []Unmet) Plan {
 	return Plan{Entries: out}
 }
 
+// MetadataValues returns the values for the provided metadata key of each entry with the provided name.
+func (p Plan) MetadataValues(name, key string) []interface{} {
+	var values []interface{}
+	for _, entry := range p.Entries {
+		if entry.Name != name {
+			continue
+		}
+		if value, ok := entry.Metadata[key]; ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func (p Plan) toBOM() []BOMEntry {
 	var bom []BOMEntry
 	for _, entry := range p.Entries {
@@ -316,5 +359,8 @@ type LayersMetadata struct {
 
 type LayerMetadata struct {
 	SHA string `json:"sha" toml:"sha"`
+	// Squashed is true if the layer was squashed with other layers into a single layer (whose SHA is SHA) at export,
+	// in which case it cannot be reused from the image on its own.
+	Squashed bool `json:"squashed,omitempty" toml:"squashed,omitempty"`
 	LayerMetadataFile
 }
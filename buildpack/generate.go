@@ -1,13 +1,16 @@
 package buildpack
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/internal/extend"
+	"github.com/buildpacks/lifecycle/internal/usage"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/log"
 )
@@ -30,6 +33,7 @@ type GenerateInputs struct {
 }
 
 type GenerateOutputs struct {
+	BOMFiles    []BOMFile
 	Dockerfiles []DockerfileInfo
 	MetRequires []string
 }
@@ -39,9 +43,22 @@ type GenerateExecutor interface {
 	Generate(d ExtDescriptor, inputs GenerateInputs, logger log.Logger) (GenerateOutputs, error)
 }
 
+// ContextGenerateExecutor is implemented by generate executors that stop generation when the provided context is done.
+type ContextGenerateExecutor interface {
+	GenerateContext(ctx context.Context, d ExtDescriptor, inputs GenerateInputs, logger log.Logger) (GenerateOutputs, error)
+}
+
 type DefaultGenerateExecutor struct{}
 
+// Generate is equivalent to GenerateContext with context.Background().
+//
+// Deprecated: use GenerateContext.
 func (e *DefaultGenerateExecutor) Generate(d ExtDescriptor, inputs GenerateInputs, logger log.Logger) (GenerateOutputs, error) {
+	return e.GenerateContext(context.Background(), d, inputs, logger)
+}
+
+// GenerateContext runs the ./bin/generate executable of the extension, killing it if the context is done before it exits.
+func (e *DefaultGenerateExecutor) GenerateContext(ctx context.Context, d ExtDescriptor, inputs GenerateInputs, logger log.Logger) (GenerateOutputs, error) {
 	logger.Debug("Creating plan directory")
 	planDir, err := os.MkdirTemp("", launch.EscapeID(d.Extension.ID)+"-")
 	if err != nil {
@@ -63,7 +80,7 @@ func (e *DefaultGenerateExecutor) Generate(d ExtDescriptor, inputs GenerateInput
 		}
 		return GenerateOutputs{}, err
 	}
-	if err = runGenerateCmd(d, extOutputDir, planPath, inputs); err != nil {
+	if err = runGenerateCmd(ctx, d, extOutputDir, planPath, inputs); err != nil {
 		return GenerateOutputs{}, err
 	}
 
@@ -71,8 +88,9 @@ func (e *DefaultGenerateExecutor) Generate(d ExtDescriptor, inputs GenerateInput
 	return readOutputFilesExt(d, extOutputDir, inputs.Plan, logger)
 }
 
-func runGenerateCmd(d ExtDescriptor, extOutputDir, planPath string, inputs GenerateInputs) error {
-	cmd := exec.Command(
+func runGenerateCmd(ctx context.Context, d ExtDescriptor, extOutputDir, planPath string, inputs GenerateInputs) error {
+	cmd := exec.CommandContext(
+		ctx,
 		filepath.Join(d.WithRootDir, "bin", "generate"),
 		extOutputDir,
 		inputs.PlatformDir,
@@ -98,8 +116,10 @@ func runGenerateCmd(d ExtDescriptor, extOutputDir, planPath string, inputs Gener
 		EnvPlatformDir+"="+inputs.PlatformDir,
 	)
 
-	if err := cmd.Run(); err != nil {
-		return NewError(err, ErrTypeBuildpack)
+	err = cmd.Run()
+	usage.RecordProcess(d.Extension.ID, d.Extension.Version, "generate", cmd.ProcessState, "")
+	if err != nil {
+		return NewError(lerrors.NewBuildpackFailedError(d.Extension.ID, err), ErrTypeBuildpack)
 	}
 	return nil
 }
@@ -133,6 +153,11 @@ func readOutputFilesExt(d ExtDescriptor, extOutputDir string, extPlanIn Plan, lo
 
 	logger.Debugf("Found '%d' Dockerfiles for processing", len(gr.Dockerfiles))
 
+	// set BOMFiles
+	if gr.BOMFiles, err = d.processSBOMFiles(extOutputDir); err != nil {
+		return GenerateOutputs{}, err
+	}
+
 	return gr, nil
 }
 
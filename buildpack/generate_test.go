@@ -343,6 +343,40 @@ func testGenerate(t *testing.T, when spec.G, it spec.S) {
 						})
 					})
 
+					when("sbom files", func() {
+						it.Before(func() {
+							descriptor.Extension.SBOM = []string{"application/vnd.cyclonedx+json"}
+						})
+
+						it("are included", func() {
+							h.Mkfile(t, `{"key": "some-run-bom-content"}`, filepath.Join(appDir, "run.sbom.cdx.json-A-v1"))
+							h.Mkfile(t, `{"key": "some-build-bom-content"}`, filepath.Join(appDir, "build.sbom.cdx.json-A-v1"))
+
+							br, err := executor.Generate(descriptor, inputs, logger)
+							h.AssertNil(t, err)
+
+							h.AssertEq(t, br.BOMFiles, []buildpack.BOMFile{
+								{
+									BuildpackID: "A",
+									LayerType:   buildpack.LayerTypeBuild,
+									Path:        filepath.Join(outputDir, "A", "build.sbom.cdx.json"),
+								},
+								{
+									BuildpackID: "A",
+									LayerType:   buildpack.LayerTypeLaunch,
+									Path:        filepath.Join(outputDir, "A", "run.sbom.cdx.json"),
+								},
+							})
+						})
+
+						it("errors for undeclared media types", func() {
+							h.Mkfile(t, `{"key": "some-run-bom-content"}`, filepath.Join(appDir, "run.sbom.spdx.json-A-v1"))
+
+							_, err := executor.Generate(descriptor, inputs, logger)
+							h.AssertError(t, err, "validating SBOM file '"+filepath.Join(outputDir, "A", "run.sbom.spdx.json")+"' for extension: 'A@v1': undeclared SBOM media type: 'application/spdx+json'")
+						})
+					})
+
 					when("met requires", func() {
 						it("are derived from input plan.toml", func() {
 							inputs.Plan = buildpack.Plan{
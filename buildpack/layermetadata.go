@@ -7,9 +7,11 @@ import (
 
 	"github.com/BurntSushi/toml"
 
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/log"
 
 	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/internal/deprecation"
 )
 
 type LayerMetadataFile struct {
@@ -17,6 +19,31 @@ type LayerMetadataFile struct {
 	Build  bool        `json:"build" toml:"build"`
 	Launch bool        `json:"launch" toml:"launch"`
 	Cache  bool        `json:"cache" toml:"cache"`
+	// EnvOrder constrains the order in which the env contributions of the buildpack are applied at launch,
+	// relative to the env contributions of other buildpacks.
+	// During the build, the env contributions of each buildpack are applied when it completes.
+	EnvOrder *EnvOrder `json:"env-order,omitempty" toml:"env-order,omitempty"`
+}
+
+// EnvOrder is the `[env-order]` table of <layer>.toml, listing the IDs of the buildpacks whose env contributions
+// must be applied after (Before) or before (After) the env contributions of the buildpack.
+type EnvOrder struct {
+	Before []string `json:"before,omitempty" toml:"before,omitempty"`
+	After  []string `json:"after,omitempty" toml:"after,omitempty"`
+}
+
+// Merge returns the constraints of both env orders.
+func (o *EnvOrder) Merge(other *EnvOrder) *EnvOrder {
+	if other == nil {
+		return o
+	}
+	if o == nil {
+		o = &EnvOrder{}
+	}
+	return &EnvOrder{
+		Before: append(append([]string{}, o.Before...), other.Before...),
+		After:  append(append([]string{}, o.After...), other.After...),
+	}
 }
 
 func EncodeLayerMetadataFile(lmf LayerMetadataFile, path, buildpackAPI string) error {
@@ -52,6 +79,7 @@ func DecodeLayerMetadataFile(path string, buildpackAPI string, logger log.Logger
 			lmf, str, err := decoder.Decode(path)
 			if str != "" {
 				if api.MustParse(buildpackAPI).LessThan("0.6") {
+					deprecation.Record(deprecation.Notice{Kind: deprecation.KindLegacyFormat, Subject: path, API: buildpackAPI, Message: str})
 					logger.Warn(str)
 				} else {
 					return LayerMetadataFile{}, errors.New(str)
@@ -98,20 +126,21 @@ func (d *defaultEncoderDecoder) Decode(path string) (LayerMetadataFile, string,
 		Cache  bool `toml:"cache"`
 	}
 	type layerMetadataTomlFile struct {
-		Data  interface{} `toml:"metadata"`
-		Types typesTable  `toml:"types"`
+		Data     interface{} `toml:"metadata"`
+		Types    typesTable  `toml:"types"`
+		EnvOrder *EnvOrder   `toml:"env-order"`
 	}
 
 	var lmtf layerMetadataTomlFile
 	md, err := toml.DecodeFile(path, &lmtf)
 	if err != nil {
-		return LayerMetadataFile{}, "", err
+		return LayerMetadataFile{}, "", lerrors.NewDecodeError(path, err)
 	}
 	msg := ""
 	if isWrongFormat := typesInTopLevel(md); isWrongFormat {
 		msg = fmt.Sprintf("the launch, cache and build flags should be in the types table of %s", path)
 	}
-	return LayerMetadataFile{Data: lmtf.Data, Build: lmtf.Types.Build, Launch: lmtf.Types.Launch, Cache: lmtf.Types.Cache}, msg, nil
+	return LayerMetadataFile{Data: lmtf.Data, Build: lmtf.Types.Build, Launch: lmtf.Types.Launch, Cache: lmtf.Types.Cache, EnvOrder: lmtf.EnvOrder}, msg, nil
 }
 
 func typesInTopLevel(md toml.MetaData) bool {
@@ -132,7 +161,7 @@ func (d *legacyEncoderDecoder) Decode(path string) (LayerMetadataFile, string, e
 	var lmf LayerMetadataFile
 	md, err := toml.DecodeFile(path, &lmf)
 	if err != nil {
-		return LayerMetadataFile{}, "", err
+		return LayerMetadataFile{}, "", lerrors.NewDecodeError(path, err)
 	}
 	msg := ""
 	if isWrongFormat := typesInTypesTable(md); isWrongFormat {
@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/lifecycle/api"
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/log"
 )
@@ -58,7 +59,7 @@ func ReadLayersDir(layersDir string, bp GroupElement, logger log.Logger) (Layers
 			var bpStore StoreTOML
 			_, err := toml.DecodeFile(tf, &bpStore)
 			if err != nil {
-				return LayersDir{}, errors.Wrapf(err, "failed decoding store.toml for buildpack %q", bp.ID)
+				return LayersDir{}, errors.Wrapf(lerrors.NewDecodeError(tf, err), "failed decoding store.toml for buildpack %q", bp.ID)
 			}
 			bpDir.Store = &bpStore
 			continue
@@ -0,0 +1,317 @@
+package buildpack
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ConvertSBOM converts the provided SBOM document (CycloneDX, SPDX or syft JSON) to CycloneDX or SPDX JSON,
+// so that platforms can request a single SBOM format regardless of the format written by each buildpack.
+// The name identifies the SBOM subject (usually the application image).
+// The returned warnings describe any information that could not be represented in the requested format and was dropped.
+func ConvertSBOM(contents []byte, fromMediaType, toMediaType, name string) ([]byte, []string, error) {
+	components, dropped, err := sbomComponents(contents, fromMediaType)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		out    []byte
+		format string
+	)
+	switch toMediaType {
+	case MediaTypeCycloneDX:
+		out, err = json.MarshalIndent(cycloneDXDocument(components, name), "", "  ")
+		format = "CycloneDX"
+	case MediaTypeSPDX:
+		out, err = json.MarshalIndent(spdxFromComponents(components, name, dropped), "", "  ")
+		format = "SPDX"
+	default:
+		return nil, nil, errors.Errorf("unsupported SBOM format for conversion: '%s'", toMediaType)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, dropped.warnings(format), nil
+}
+
+type cdxComponentsDocument struct {
+	BOMFormat   string                   `json:"bomFormat"`
+	SpecVersion string                   `json:"specVersion"`
+	Version     int                      `json:"version"`
+	Metadata    cdxMetadata              `json:"metadata"`
+	Components  []map[string]interface{} `json:"components"`
+}
+
+func cycloneDXDocument(components []map[string]interface{}, name string) cdxComponentsDocument {
+	if components == nil {
+		components = []map[string]interface{}{}
+	}
+	return cdxComponentsDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: cycloneDXSpecVersion,
+		Version:     1,
+		Metadata: cdxMetadata{
+			Tools:     []cdxTool{{Vendor: "buildpacks.io", Name: "lifecycle"}},
+			Component: cdxComponent{Type: "container", Name: name},
+		},
+		Components: components,
+	}
+}
+
+// reading
+
+// sbomComponents returns the components described by the SBOM document, as CycloneDX components,
+// and the fields that have no CycloneDX component equivalent.
+func sbomComponents(contents []byte, mediaType string) ([]map[string]interface{}, *droppedFields, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(contents, &doc); err != nil {
+		return nil, nil, err
+	}
+	dropped := &droppedFields{}
+	var components []map[string]interface{}
+	switch mediaType {
+	case MediaTypeCycloneDX:
+		for _, key := range []string{"dependencies", "services", "compositions", "vulnerabilities"} {
+			if _, ok := doc[key]; ok {
+				dropped.add("CycloneDX", key)
+			}
+		}
+		for _, c := range objects(doc["components"]) {
+			components = append(components, c)
+		}
+	case MediaTypeSPDX:
+		for _, key := range []string{"files", "snippets", "hasExtractedLicensingInfos"} {
+			if _, ok := doc[key]; ok {
+				dropped.add("SPDX", key)
+			}
+		}
+		for _, rel := range objects(doc["relationships"]) {
+			if rel["relationshipType"] != "DESCRIBES" {
+				dropped.add("SPDX", "relationships")
+				break
+			}
+		}
+		for _, pkg := range objects(doc["packages"]) {
+			components = append(components, componentFromSPDX(pkg, dropped))
+		}
+	case MediaTypeSyft:
+		for _, artifact := range objects(doc["artifacts"]) {
+			components = append(components, componentFromSyft(artifact, dropped))
+		}
+	default:
+		return nil, nil, errors.Errorf("unsupported SBOM format: '%s'", mediaType)
+	}
+	return components, dropped, nil
+}
+
+func componentFromSPDX(pkg map[string]interface{}, dropped *droppedFields) map[string]interface{} {
+	component := newComponent(str(pkg["name"]), str(pkg["versionInfo"]))
+	for key, val := range pkg {
+		switch key {
+		case "name", "versionInfo", "SPDXID", "filesAnalyzed", "licenseConcluded", "licenseDeclared":
+		case "comment":
+			component["description"] = val
+		case "downloadLocation":
+			if v := str(val); v != spdxNoAssertion && v != "NONE" {
+				dropped.add("SPDX", "packages."+key)
+			}
+		case "externalRefs":
+			for _, ref := range objects(val) {
+				if ref["referenceType"] == spdxPURLType {
+					component["purl"] = ref["referenceLocator"]
+				} else {
+					dropped.add("SPDX", fmt.Sprintf("packages.externalRefs[%s]", str(ref["referenceType"])))
+				}
+			}
+		default:
+			dropped.add("SPDX", "packages."+key)
+		}
+	}
+	license := str(pkg["licenseDeclared"])
+	if !spdxLicenseSet(license) {
+		license = str(pkg["licenseConcluded"])
+	}
+	if spdxLicenseSet(license) {
+		component["licenses"] = []interface{}{map[string]interface{}{"expression": license}}
+	}
+	return component
+}
+
+func componentFromSyft(artifact map[string]interface{}, dropped *droppedFields) map[string]interface{} {
+	component := newComponent(str(artifact["name"]), str(artifact["version"]))
+	for key, val := range artifact {
+		switch key {
+		case "name", "version", "id", "type":
+		case "purl":
+			if purl := str(val); purl != "" {
+				component["purl"] = purl
+			}
+		case "licenses":
+			list, _ := val.([]interface{})
+			var licenses []interface{}
+			for _, l := range list {
+				// licenses are strings in older syft schemas, and objects with a value in newer ones
+				switch license := l.(type) {
+				case string:
+					licenses = append(licenses, map[string]interface{}{"license": map[string]interface{}{"name": license}})
+				case map[string]interface{}:
+					if value := str(license["value"]); value != "" {
+						licenses = append(licenses, map[string]interface{}{"license": map[string]interface{}{"name": value}})
+					}
+				}
+			}
+			if len(licenses) > 0 {
+				component["licenses"] = licenses
+			}
+		default:
+			dropped.add("syft", "artifacts."+key)
+		}
+	}
+	return component
+}
+
+func newComponent(name, version string) map[string]interface{} {
+	component := map[string]interface{}{"type": "library", "name": name}
+	if version != "" {
+		component["version"] = version
+	}
+	return component
+}
+
+// writing
+
+var licenseRefInvalidChars = regexp.MustCompile(`[^A-Za-z0-9.\-]+`)
+
+// spdxFromComponents returns an SPDX document describing the provided CycloneDX components,
+// recording the fields that have no SPDX equivalent.
+func spdxFromComponents(components []map[string]interface{}, name string, dropped *droppedFields) spdxDocument {
+	contents, _ := json.Marshal(components)
+	doc := spdxDocument{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("https://buildpacks.io/spdx/%x", sha256.Sum256(append([]byte(name+"@"), contents...))),
+		CreationInfo: spdxCreationInfo{
+			Created:  "1980-01-01T00:00:01Z", // SPDX requires a creation time; use the same normalized time as the image
+			Creators: []string{"Organization: buildpacks.io", "Tool: lifecycle"},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+	for i, component := range components {
+		pkg := spdxPackage{
+			Name:             str(component["name"]),
+			SPDXID:           fmt.Sprintf("SPDXRef-Package-%d", i),
+			VersionInfo:      str(component["version"]),
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+		}
+		var comments []string
+		for key, val := range component {
+			switch key {
+			case "type", "name", "version", "bom-ref":
+			case "purl":
+				pkg.ExternalRefs = []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: spdxPURLType, ReferenceLocator: str(val)}}
+			case "licenses":
+				if license := spdxLicenseExpression(val); license != "" {
+					pkg.LicenseDeclared = license
+				}
+			case "description":
+				comments = append(comments, str(val))
+			case "properties":
+				for _, p := range objects(val) {
+					comments = append(comments, fmt.Sprintf("%s=%s", str(p["name"]), str(p["value"])))
+				}
+			default:
+				dropped.add("CycloneDX", "components."+key)
+			}
+		}
+		sort.Strings(comments)
+		pkg.Comment = strings.Join(comments, "; ")
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      doc.SPDXID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+	return doc
+}
+
+func spdxLicenseExpression(licenses interface{}) string {
+	var ids []string
+	for _, l := range objects(licenses) {
+		if expression := str(l["expression"]); expression != "" {
+			ids = append(ids, expression)
+			continue
+		}
+		license, ok := l["license"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id := str(license["id"]); id != "" {
+			ids = append(ids, id)
+		} else if name := str(license["name"]); name != "" {
+			ids = append(ids, "LicenseRef-"+licenseRefInvalidChars.ReplaceAllString(name, "-"))
+		}
+	}
+	return strings.Join(ids, " AND ")
+}
+
+func spdxLicenseSet(license string) bool {
+	return license != "" && license != spdxNoAssertion && license != "NONE"
+}
+
+// droppedFields counts the fields that could not be converted.
+type droppedFields struct {
+	counts map[string]int
+	format map[string]string
+}
+
+func (d *droppedFields) add(format, field string) {
+	if d.counts == nil {
+		d.counts = map[string]int{}
+		d.format = map[string]string{}
+	}
+	d.counts[field]++
+	d.format[field] = format
+}
+
+func (d *droppedFields) warnings(to string) []string {
+	var fields []string
+	for field := range d.counts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var warnings []string
+	for _, field := range fields {
+		warnings = append(warnings, fmt.Sprintf("%s field '%s' (%d occurrence(s)) is not preserved in %s", d.format[field], field, d.counts[field], to))
+	}
+	return warnings
+}
+
+// helpers
+
+func objects(val interface{}) []map[string]interface{} {
+	list, _ := val.([]interface{})
+	var out []map[string]interface{}
+	for _, el := range list {
+		if m, ok := el.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func str(val interface{}) string {
+	s, _ := val.(string)
+	return s
+}
@@ -0,0 +1,138 @@
+package buildpack_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestSBOMConvert(t *testing.T) {
+	spec.Run(t, "unit-sbom-convert", testSBOMConvert, spec.Report(report.Terminal{}))
+}
+
+func testSBOMConvert(t *testing.T, when spec.G, it spec.S) {
+	when("#ConvertSBOM", func() {
+		when("converting to SPDX", func() {
+			it("maps CycloneDX components to packages", func() {
+				cdx := `{"components": [{
+					"type": "library", "name": "dep-a", "version": "1.0", "purl": "pkg:generic/dep-a@1.0",
+					"licenses": [{"license": {"id": "MIT"}}, {"license": {"name": "Some License"}}],
+					"properties": [{"name": "io.buildpacks.buildpack.id", "value": "some/buildpack"}]
+				}]}`
+				contents, warnings, err := buildpack.ConvertSBOM([]byte(cdx), buildpack.MediaTypeCycloneDX, buildpack.MediaTypeSPDX, "some-image")
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(warnings), 0)
+
+				var doc struct {
+					SPDXVersion string `json:"spdxVersion"`
+					Name        string `json:"name"`
+					Packages    []struct {
+						Name            string `json:"name"`
+						VersionInfo     string `json:"versionInfo"`
+						LicenseDeclared string `json:"licenseDeclared"`
+						Comment         string `json:"comment"`
+						ExternalRefs    []struct {
+							ReferenceType    string `json:"referenceType"`
+							ReferenceLocator string `json:"referenceLocator"`
+						} `json:"externalRefs"`
+					} `json:"packages"`
+				}
+				h.AssertNil(t, json.Unmarshal(contents, &doc))
+				h.AssertEq(t, doc.SPDXVersion, "SPDX-2.3")
+				h.AssertEq(t, doc.Name, "some-image")
+				h.AssertEq(t, len(doc.Packages), 1)
+				h.AssertEq(t, doc.Packages[0].Name, "dep-a")
+				h.AssertEq(t, doc.Packages[0].VersionInfo, "1.0")
+				h.AssertEq(t, doc.Packages[0].LicenseDeclared, "MIT AND LicenseRef-Some-License")
+				h.AssertEq(t, doc.Packages[0].Comment, "io.buildpacks.buildpack.id=some/buildpack")
+				h.AssertEq(t, doc.Packages[0].ExternalRefs[0].ReferenceType, "purl")
+				h.AssertEq(t, doc.Packages[0].ExternalRefs[0].ReferenceLocator, "pkg:generic/dep-a@1.0")
+			})
+
+			it("warns about CycloneDX fields that are dropped", func() {
+				cdx := `{
+					"components": [
+						{"type": "library", "name": "dep-a", "hashes": [{"alg": "SHA-256", "content": "abc"}]},
+						{"type": "library", "name": "dep-b", "hashes": [{"alg": "SHA-256", "content": "def"}], "cpe": "cpe:2.3:a:dep-b"}
+					],
+					"dependencies": [{"ref": "dep-a"}]
+				}`
+				_, warnings, err := buildpack.ConvertSBOM([]byte(cdx), buildpack.MediaTypeCycloneDX, buildpack.MediaTypeSPDX, "some-image")
+				h.AssertNil(t, err)
+				h.AssertEq(t, warnings, []string{
+					"CycloneDX field 'components.cpe' (1 occurrence(s)) is not preserved in SPDX",
+					"CycloneDX field 'components.hashes' (2 occurrence(s)) is not preserved in SPDX",
+					"CycloneDX field 'dependencies' (1 occurrence(s)) is not preserved in SPDX",
+				})
+			})
+		})
+
+		when("converting to CycloneDX", func() {
+			it("maps SPDX packages to components", func() {
+				spdx := `{
+					"packages": [{
+						"SPDXID": "SPDXRef-Package-0", "name": "dep-a", "versionInfo": "1.0",
+						"licenseDeclared": "NOASSERTION", "licenseConcluded": "Apache-2.0",
+						"checksums": [{"algorithm": "SHA256", "checksumValue": "abc"}],
+						"externalRefs": [
+							{"referenceType": "purl", "referenceLocator": "pkg:generic/dep-a@1.0"},
+							{"referenceType": "cpe23Type", "referenceLocator": "cpe:2.3:a:dep-a"}
+						]
+					}],
+					"relationships": [{"relationshipType": "DESCRIBES"}]
+				}`
+				contents, warnings, err := buildpack.ConvertSBOM([]byte(spdx), buildpack.MediaTypeSPDX, buildpack.MediaTypeCycloneDX, "some-image")
+				h.AssertNil(t, err)
+				h.AssertEq(t, warnings, []string{
+					"SPDX field 'packages.checksums' (1 occurrence(s)) is not preserved in CycloneDX",
+					"SPDX field 'packages.externalRefs[cpe23Type]' (1 occurrence(s)) is not preserved in CycloneDX",
+				})
+
+				var doc struct {
+					BOMFormat  string            `json:"bomFormat"`
+					Components []mergedComponent `json:"components"`
+				}
+				h.AssertNil(t, json.Unmarshal(contents, &doc))
+				h.AssertEq(t, doc.BOMFormat, "CycloneDX")
+				h.AssertEq(t, len(doc.Components), 1)
+				h.AssertEq(t, doc.Components[0].Name, "dep-a")
+				h.AssertEq(t, doc.Components[0].Version, "1.0")
+				h.AssertEq(t, doc.Components[0].PURL, "pkg:generic/dep-a@1.0")
+				h.AssertEq(t, doc.Components[0].Licenses[0]["expression"], "Apache-2.0")
+			})
+
+			it("maps syft artifacts to components", func() {
+				syft := `{"artifacts": [
+					{"id": "1", "name": "dep-a", "version": "1.0", "purl": "pkg:generic/dep-a@1.0", "licenses": ["MIT"], "locations": [{"path": "/some/path"}]},
+					{"id": "2", "name": "dep-b", "version": "2.0", "licenses": [{"value": "BSD-3-Clause"}], "locations": [{"path": "/other/path"}]}
+				]}`
+				contents, warnings, err := buildpack.ConvertSBOM([]byte(syft), buildpack.MediaTypeSyft, buildpack.MediaTypeCycloneDX, "some-image")
+				h.AssertNil(t, err)
+				h.AssertEq(t, warnings, []string{
+					"syft field 'artifacts.locations' (2 occurrence(s)) is not preserved in CycloneDX",
+				})
+
+				var doc struct {
+					Components []mergedComponent `json:"components"`
+				}
+				h.AssertNil(t, json.Unmarshal(contents, &doc))
+				h.AssertEq(t, len(doc.Components), 2)
+				h.AssertEq(t, doc.Components[0].PURL, "pkg:generic/dep-a@1.0")
+				h.AssertEq(t, doc.Components[1].Licenses[0]["license"], map[string]interface{}{"name": "BSD-3-Clause"})
+			})
+		})
+
+		it("errors for unsupported formats", func() {
+			_, _, err := buildpack.ConvertSBOM([]byte(`{}`), buildpack.MediaTypeCycloneDX, "application/unknown", "some-image")
+			h.AssertError(t, err, "unsupported SBOM format for conversion: 'application/unknown'")
+
+			_, _, err = buildpack.ConvertSBOM([]byte(`{}`), "application/unknown", buildpack.MediaTypeSPDX, "some-image")
+			h.AssertError(t, err, "unsupported SBOM format: 'application/unknown'")
+		})
+	})
+}
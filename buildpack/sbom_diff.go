@@ -0,0 +1,115 @@
+package buildpack
+
+import (
+	"sort"
+	"strings"
+)
+
+// SBOMDiff summarizes the changes in the components of an application image between two builds.
+type SBOMDiff struct {
+	// Added lists components that are only present in the current image.
+	Added []SBOMDiffComponent `toml:"added,omitempty" json:"added,omitempty"`
+	// Removed lists components that are only present in the previous image.
+	Removed []SBOMDiffComponent `toml:"removed,omitempty" json:"removed,omitempty"`
+	// Upgraded lists components that are present in both images with a different version (including downgrades).
+	Upgraded []SBOMDiffUpgrade `toml:"upgraded,omitempty" json:"upgraded,omitempty"`
+}
+
+type SBOMDiffComponent struct {
+	Name    string `toml:"name" json:"name"`
+	Version string `toml:"version,omitempty" json:"version,omitempty"`
+	PURL    string `toml:"purl,omitempty" json:"purl,omitempty"`
+}
+
+type SBOMDiffUpgrade struct {
+	Name            string `toml:"name" json:"name"`
+	PURL            string `toml:"purl,omitempty" json:"purl,omitempty"`
+	PreviousVersion string `toml:"previous-version" json:"previousVersion"`
+	Version         string `toml:"version" json:"version"`
+}
+
+// DiffSBOMs compares the components in the previous and current CycloneDX documents.
+// Components are matched by package URL without the version (or by name if there is no package URL),
+// so that a change in version is reported as an upgrade rather than an addition and a removal.
+func DiffSBOMs(previous, current []byte) (SBOMDiff, error) {
+	previousComponents, err := diffComponents(previous)
+	if err != nil {
+		return SBOMDiff{}, err
+	}
+	currentComponents, err := diffComponents(current)
+	if err != nil {
+		return SBOMDiff{}, err
+	}
+
+	var diff SBOMDiff
+	for key, c := range currentComponents {
+		p, ok := previousComponents[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, c)
+		case p.Version != c.Version:
+			diff.Upgraded = append(diff.Upgraded, SBOMDiffUpgrade{
+				Name:            c.Name,
+				PURL:            c.PURL,
+				PreviousVersion: p.Version,
+				Version:         c.Version,
+			})
+		}
+	}
+	for key, p := range previousComponents {
+		if _, ok := currentComponents[key]; !ok {
+			diff.Removed = append(diff.Removed, p)
+		}
+	}
+
+	sortDiffComponents(diff.Added)
+	sortDiffComponents(diff.Removed)
+	sort.Slice(diff.Upgraded, func(i, j int) bool {
+		if diff.Upgraded[i].Name != diff.Upgraded[j].Name {
+			return diff.Upgraded[i].Name < diff.Upgraded[j].Name
+		}
+		return diff.Upgraded[i].PURL < diff.Upgraded[j].PURL
+	})
+	return diff, nil
+}
+
+func diffComponents(contents []byte) (map[string]SBOMDiffComponent, error) {
+	components, _, err := sbomComponents(contents, MediaTypeCycloneDX)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]SBOMDiffComponent{}
+	for _, component := range components {
+		c := SBOMDiffComponent{
+			Name:    str(component["name"]),
+			Version: str(component["version"]),
+			PURL:    str(component["purl"]),
+		}
+		out[unversionedKey(c)] = c
+	}
+	return out, nil
+}
+
+// unversionedKey returns the package URL without the version, qualifiers and subpath, or else the component name.
+func unversionedKey(c SBOMDiffComponent) string {
+	if c.PURL == "" {
+		return c.Name
+	}
+	key := c.PURL
+	if i := strings.IndexAny(key, "?#"); i >= 0 {
+		key = key[:i]
+	}
+	if i := strings.LastIndex(key, "@"); i > strings.LastIndex(key, "/") {
+		key = key[:i]
+	}
+	return key
+}
+
+func sortDiffComponents(components []SBOMDiffComponent) {
+	sort.Slice(components, func(i, j int) bool {
+		if components[i].Name != components[j].Name {
+			return components[i].Name < components[j].Name
+		}
+		return components[i].Version < components[j].Version
+	})
+}
@@ -0,0 +1,58 @@
+package buildpack_test
+
+import (
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestSBOMDiff(t *testing.T) {
+	spec.Run(t, "unit-sbom-diff", testSBOMDiff, spec.Report(report.Terminal{}))
+}
+
+func testSBOMDiff(t *testing.T, when spec.G, it spec.S) {
+	when("#DiffSBOMs", func() {
+		it("reports added, removed and upgraded components", func() {
+			previous := `{"components": [
+				{"type": "library", "name": "dep-a", "version": "1.0", "purl": "pkg:generic/dep-a@1.0?arch=amd64"},
+				{"type": "library", "name": "dep-b", "version": "2.0"},
+				{"type": "library", "name": "dep-c", "version": "3.0", "purl": "pkg:generic/dep-c@3.0"}
+			]}`
+			current := `{"components": [
+				{"type": "library", "name": "dep-a", "version": "1.1", "purl": "pkg:generic/dep-a@1.1?arch=amd64"},
+				{"type": "library", "name": "dep-c", "version": "3.0", "purl": "pkg:generic/dep-c@3.0"},
+				{"type": "library", "name": "dep-d", "version": "4.0"}
+			]}`
+
+			diff, err := buildpack.DiffSBOMs([]byte(previous), []byte(current))
+			h.AssertNil(t, err)
+			h.AssertEq(t, diff, buildpack.SBOMDiff{
+				Added:   []buildpack.SBOMDiffComponent{{Name: "dep-d", Version: "4.0"}},
+				Removed: []buildpack.SBOMDiffComponent{{Name: "dep-b", Version: "2.0"}},
+				Upgraded: []buildpack.SBOMDiffUpgrade{{
+					Name:            "dep-a",
+					PURL:            "pkg:generic/dep-a@1.1?arch=amd64",
+					PreviousVersion: "1.0",
+					Version:         "1.1",
+				}},
+			})
+		})
+
+		it("reports no changes for identical SBOMs", func() {
+			sbom := `{"components": [{"type": "library", "name": "dep-a", "version": "1.0"}]}`
+
+			diff, err := buildpack.DiffSBOMs([]byte(sbom), []byte(sbom))
+			h.AssertNil(t, err)
+			h.AssertEq(t, diff, buildpack.SBOMDiff{})
+		})
+
+		it("errors for invalid SBOMs", func() {
+			_, err := buildpack.DiffSBOMs([]byte(`not-json`), []byte(`{}`))
+			h.AssertNotNil(t, err)
+		})
+	})
+}
@@ -0,0 +1,198 @@
+package buildpack
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	bomPropertyLayerName = "io.buildpacks.layer.name"
+	spdxPURLType         = "purl"
+)
+
+// SBOMSource is an SBOM file in the launch SBOM layer, contributed by a buildpack (or by the lifecycle)
+// for one of its layers or for the application image.
+type SBOMSource struct {
+	// BuildpackID is the ID of the buildpack that contributed the SBOM.
+	BuildpackID string
+	// LayerName is the name of the layer described by the SBOM, or empty for the buildpack's launch.sbom.<ext>.
+	LayerName string
+	// Path is the location of the SBOM file.
+	Path string
+}
+
+// SBOMSourcesIn returns the SBOM files in the provided SBOM directory (e.g., <layers>/sbom/launch),
+// which are laid out as <escaped buildpack ID>/[<layer name>/]sbom.<ext>.
+// Escaped buildpack IDs are translated to buildpack IDs using buildpackIDs, if present.
+func SBOMSourcesIn(dir string, buildpackIDs map[string]string) ([]SBOMSource, error) {
+	var sources []SBOMSource
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil
+		}
+		source := SBOMSource{BuildpackID: parts[0], Path: path}
+		if id, ok := buildpackIDs[parts[0]]; ok {
+			source.BuildpackID = id
+		}
+		if len(parts) == 3 {
+			source.LayerName = parts[1]
+		}
+		sources = append(sources, source)
+		return nil
+	})
+	return sources, err
+}
+
+// sbomFormatPreference is the order in which SBOM formats are used when a buildpack provides the same SBOM in several formats,
+// as CycloneDX components can be merged without loss.
+var sbomFormatPreference = []string{ExtensionCycloneDX, ExtensionSyft, ExtensionSPDX}
+
+// MergeSBOMs merges the provided CycloneDX, SPDX and syft SBOM files into a single CycloneDX document describing the application image,
+// so that scanners don't need to understand the layout of the SBOM layer.
+// When a buildpack provides an SBOM in several formats, only the CycloneDX (or else syft) file is used.
+// Components are de-duplicated by package URL (or by name and version), and the buildpacks and layers that contributed each component
+// are recorded as component properties.
+func MergeSBOMs(sources []SBOMSource, name string) ([]byte, error) {
+	merged := &mergedSBOM{byKey: map[string]map[string]interface{}{}}
+	for _, source := range preferredSBOMSources(sources) {
+		components, err := readSBOMComponents(source.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading SBOM '%s'", source.Path)
+		}
+		for _, component := range components {
+			merged.add(component, source)
+		}
+	}
+	return json.MarshalIndent(cycloneDXDocument(merged.components, name), "", "  ")
+}
+
+// preferredSBOMSources returns a single source for each buildpack and layer, in a stable order.
+func preferredSBOMSources(sources []SBOMSource) []SBOMSource {
+	byLocation := map[[2]string][]SBOMSource{}
+	var locations [][2]string
+	for _, source := range sources {
+		location := [2]string{source.BuildpackID, source.LayerName}
+		if _, ok := byLocation[location]; !ok {
+			locations = append(locations, location)
+		}
+		byLocation[location] = append(byLocation[location], source)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i][0] != locations[j][0] {
+			return locations[i][0] < locations[j][0]
+		}
+		return locations[i][1] < locations[j][1]
+	})
+	var preferred []SBOMSource
+	for _, location := range locations {
+	formats:
+		for _, ext := range sbomFormatPreference {
+			for _, source := range byLocation[location] {
+				if strings.HasSuffix(strings.TrimSuffix(filepath.Base(source.Path), ExtensionGzip), ext) {
+					preferred = append(preferred, source)
+					break formats
+				}
+			}
+		}
+	}
+	return preferred
+}
+
+type mergedSBOM struct {
+	components []map[string]interface{}
+	byKey      map[string]map[string]interface{}
+}
+
+func (m *mergedSBOM) add(component map[string]interface{}, source SBOMSource) {
+	delete(component, "bom-ref") // dependencies are not merged, so references may collide
+	key := componentKey(component)
+	if existing, ok := m.byKey[key]; ok {
+		addProvenance(existing, source)
+		return
+	}
+	addProvenance(component, source)
+	m.byKey[key] = component
+	m.components = append(m.components, component)
+}
+
+func componentKey(component map[string]interface{}) string {
+	if purl, ok := component["purl"].(string); ok && purl != "" {
+		return purl
+	}
+	name, _ := component["name"].(string)
+	version, _ := component["version"].(string)
+	return name + "@" + version
+}
+
+func addProvenance(component map[string]interface{}, source SBOMSource) {
+	properties, _ := component["properties"].([]interface{})
+	for _, p := range provenanceProperties(source) {
+		var found bool
+		for _, existing := range properties {
+			if e, ok := existing.(map[string]interface{}); ok && e["name"] == p.Name && e["value"] == p.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			properties = append(properties, map[string]interface{}{"name": p.Name, "value": p.Value})
+		}
+	}
+	component["properties"] = properties
+}
+
+func provenanceProperties(source SBOMSource) []cdxProperty {
+	properties := []cdxProperty{{Name: bomPropertyBuildpackID, Value: source.BuildpackID}}
+	if source.LayerName != "" {
+		properties = append(properties, cdxProperty{Name: bomPropertyLayerName, Value: source.BuildpackID + ":" + source.LayerName})
+	}
+	return properties
+}
+
+// readSBOMComponents returns the components described by the SBOM file at path, as CycloneDX components.
+func readSBOMComponents(path string) ([]map[string]interface{}, error) {
+	mediaType := sbomMediaType(path)
+	if mediaType == mediaTypeUnsupported {
+		return nil, errors.Errorf("unsupported SBOM format: '%s'", path)
+	}
+	contents, err := readSBOMFile(path)
+	if err != nil {
+		return nil, err
+	}
+	components, _, err := sbomComponents(contents, mediaType)
+	return components, err
+}
+
+// sbomMediaType returns the media type of the SBOM file at path, based on its extension (ignoring any compression extension).
+func sbomMediaType(path string) string {
+	switch base := strings.TrimSuffix(filepath.Base(path), ExtensionGzip); {
+	case strings.HasSuffix(base, ExtensionCycloneDX):
+		return MediaTypeCycloneDX
+	case strings.HasSuffix(base, ExtensionSPDX):
+		return MediaTypeSPDX
+	case strings.HasSuffix(base, ExtensionSyft):
+		return MediaTypeSyft
+	default:
+		return mediaTypeUnsupported
+	}
+}
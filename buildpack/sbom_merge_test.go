@@ -0,0 +1,123 @@
+package buildpack_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestSBOMMerge(t *testing.T) {
+	spec.Run(t, "unit-sbom-merge", testSBOMMerge, spec.Report(report.Terminal{}))
+}
+
+type mergedComponent struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	PURL       string `json:"purl"`
+	Properties []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"properties"`
+	Licenses []map[string]interface{} `json:"licenses"`
+}
+
+func testSBOMMerge(t *testing.T, when spec.G, it spec.S) {
+	when("#MergeSBOMs", func() {
+		var tmpDir string
+
+		it.Before(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "lifecycle.sbom-merge")
+			h.AssertNil(t, err)
+		})
+
+		it.After(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		writeSBOM := func(name, contents string) string {
+			path := filepath.Join(tmpDir, name)
+			h.AssertNil(t, os.MkdirAll(filepath.Dir(path), 0755))
+			h.Mkfile(t, contents, path)
+			return path
+		}
+
+		merge := func(sources []buildpack.SBOMSource) []mergedComponent {
+			contents, err := buildpack.MergeSBOMs(sources, "some-image")
+			h.AssertNil(t, err)
+			var doc struct {
+				BOMFormat  string            `json:"bomFormat"`
+				Components []mergedComponent `json:"components"`
+			}
+			h.AssertNil(t, json.Unmarshal(contents, &doc))
+			h.AssertEq(t, doc.BOMFormat, "CycloneDX")
+			return doc.Components
+		}
+
+		it("merges CycloneDX, SPDX and syft files, recording provenance", func() {
+			components := merge([]buildpack.SBOMSource{
+				{
+					BuildpackID: "some/buildpack",
+					LayerName:   "some-layer",
+					Path:        writeSBOM("a/some-layer/sbom.cdx.json", `{"components": [{"type": "library", "name": "dep-a", "version": "1.0", "purl": "pkg:generic/dep-a@1.0", "bom-ref": "ref-1"}]}`),
+				},
+				{
+					BuildpackID: "other/buildpack",
+					Path: writeSBOM("b/sbom.spdx.json", `{"packages": [
+						{"name": "dep-b", "versionInfo": "2.0", "licenseDeclared": "MIT", "externalRefs": [{"referenceType": "purl", "referenceLocator": "pkg:generic/dep-b@2.0"}]}
+					]}`),
+				},
+				{
+					BuildpackID: "third/buildpack",
+					Path:        writeSBOM("c/sbom.syft.json", `{"artifacts": [{"name": "dep-c", "version": "3.0", "purl": "pkg:generic/dep-c@3.0", "licenses": ["Apache-2.0"]}]}`),
+				},
+			})
+
+			h.AssertEq(t, len(components), 3)
+			h.AssertEq(t, components[0].PURL, "pkg:generic/dep-b@2.0")
+			h.AssertEq(t, components[0].Properties[0].Value, "other/buildpack")
+			h.AssertEq(t, components[0].Licenses[0]["expression"], "MIT")
+			h.AssertEq(t, components[1].Name, "dep-a")
+			h.AssertEq(t, components[1].Properties[0].Value, "some/buildpack")
+			h.AssertEq(t, components[1].Properties[1].Value, "some/buildpack:some-layer")
+			h.AssertEq(t, components[2].Version, "3.0")
+		})
+
+		it("de-duplicates components, preserving all contributors", func() {
+			cdx := `{"components": [{"type": "library", "name": "dep-a", "version": "1.0", "purl": "pkg:generic/dep-a@1.0"}]}`
+			components := merge([]buildpack.SBOMSource{
+				{BuildpackID: "some/buildpack", Path: writeSBOM("a/sbom.cdx.json", cdx)},
+				{BuildpackID: "other/buildpack", Path: writeSBOM("b/sbom.cdx.json", cdx)},
+			})
+
+			h.AssertEq(t, len(components), 1)
+			h.AssertEq(t, len(components[0].Properties), 2)
+			h.AssertEq(t, components[0].Properties[0].Value, "other/buildpack")
+			h.AssertEq(t, components[0].Properties[1].Value, "some/buildpack")
+		})
+
+		it("prefers CycloneDX when a buildpack provides several formats", func() {
+			components := merge([]buildpack.SBOMSource{
+				{BuildpackID: "some/buildpack", Path: writeSBOM("a/sbom.spdx.json", `{"packages": [{"name": "from-spdx"}]}`)},
+				{BuildpackID: "some/buildpack", Path: writeSBOM("a/sbom.cdx.json", `{"components": [{"type": "library", "name": "from-cdx"}]}`)},
+			})
+
+			h.AssertEq(t, len(components), 1)
+			h.AssertEq(t, components[0].Name, "from-cdx")
+		})
+
+		it("errors when an SBOM file is invalid", func() {
+			_, err := buildpack.MergeSBOMs([]buildpack.SBOMSource{
+				{BuildpackID: "some/buildpack", Path: writeSBOM("a/sbom.cdx.json", `not-json`)},
+			}, "some-image")
+			h.AssertError(t, err, "reading SBOM")
+		})
+	})
+}
@@ -0,0 +1,178 @@
+package buildpack
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SBOMPolicy restricts the licenses and packages that may be included in the application image.
+type SBOMPolicy struct {
+	// Enforce fails the build if the image SBOM violates the policy. Otherwise, violations are only reported.
+	Enforce bool `toml:"enforce"`
+	// DenyLicenses are SPDX license IDs (or license names) that components may not be licensed under.
+	// Patterns such as "GPL-*" are supported.
+	DenyLicenses []string `toml:"deny-licenses"`
+	// Packages restrict specific packages.
+	Packages []SBOMPackagePolicy `toml:"packages"`
+}
+
+// SBOMPackagePolicy restricts the components matching a package name or a package URL (without the version).
+type SBOMPackagePolicy struct {
+	Name string `toml:"name,omitempty"`
+	PURL string `toml:"purl,omitempty"`
+	// Deny forbids the package.
+	Deny bool `toml:"deny,omitempty"`
+	// MinVersion is the minimum allowed version of the package.
+	MinVersion string `toml:"min-version,omitempty"`
+}
+
+const (
+	SBOMPolicyRuleDeniedLicense = "denied-license"
+	SBOMPolicyRuleDeniedPackage = "denied-package"
+	SBOMPolicyRuleMinVersion    = "min-version"
+)
+
+// SBOMPolicyViolation describes a component that violates an SBOM policy.
+type SBOMPolicyViolation struct {
+	Component string `toml:"component"`
+	Version   string `toml:"version,omitempty"`
+	PURL      string `toml:"purl,omitempty"`
+	Rule      string `toml:"rule"`
+	Message   string `toml:"message"`
+}
+
+func (v SBOMPolicyViolation) String() string {
+	component := v.Component
+	if v.Version != "" {
+		component += "@" + v.Version
+	}
+	return fmt.Sprintf("%s: %s", component, v.Message)
+}
+
+// EvaluateSBOMPolicy returns the components of the provided CycloneDX document that violate the policy.
+func EvaluateSBOMPolicy(policy SBOMPolicy, sbom []byte) ([]SBOMPolicyViolation, error) {
+	components, _, err := sbomComponents(sbom, MediaTypeCycloneDX)
+	if err != nil {
+		return nil, err
+	}
+	var violations []SBOMPolicyViolation
+	for _, component := range components {
+		c := SBOMDiffComponent{
+			Name:    str(component["name"]),
+			Version: str(component["version"]),
+			PURL:    str(component["purl"]),
+		}
+		violation := func(rule, format string, args ...interface{}) {
+			violations = append(violations, SBOMPolicyViolation{
+				Component: c.Name,
+				Version:   c.Version,
+				PURL:      c.PURL,
+				Rule:      rule,
+				Message:   fmt.Sprintf(format, args...),
+			})
+		}
+		for _, license := range componentLicenses(component) {
+			if pattern, ok := matchesAny(policy.DenyLicenses, license); ok {
+				violation(SBOMPolicyRuleDeniedLicense, "license '%s' is denied by '%s'", license, pattern)
+			}
+		}
+		for _, pkg := range policy.Packages {
+			if !pkg.matches(c) {
+				continue
+			}
+			if pkg.Deny {
+				violation(SBOMPolicyRuleDeniedPackage, "package is denied")
+			}
+			if pkg.MinVersion != "" && compareVersions(c.Version, pkg.MinVersion) < 0 {
+				violation(SBOMPolicyRuleMinVersion, "version '%s' is less than the minimum version '%s'", c.Version, pkg.MinVersion)
+			}
+		}
+	}
+	return violations, nil
+}
+
+func (p SBOMPackagePolicy) matches(c SBOMDiffComponent) bool {
+	if p.PURL != "" {
+		return c.PURL != "" && unversionedKey(c) == unversionedKey(SBOMDiffComponent{PURL: p.PURL})
+	}
+	return p.Name != "" && p.Name == c.Name
+}
+
+// componentLicenses returns the license IDs, names and the IDs in license expressions of the CycloneDX component.
+func componentLicenses(component map[string]interface{}) []string {
+	var licenses []string
+	for _, l := range objects(component["licenses"]) {
+		if expression := str(l["expression"]); expression != "" {
+			for _, token := range strings.FieldsFunc(expression, func(r rune) bool { return unicode.IsSpace(r) || r == '(' || r == ')' }) {
+				switch strings.ToUpper(token) {
+				case "AND", "OR", "WITH":
+				default:
+					licenses = append(licenses, token)
+				}
+			}
+			continue
+		}
+		license, ok := l["license"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id := str(license["id"]); id != "" {
+			licenses = append(licenses, id)
+		} else if name := str(license["name"]); name != "" {
+			licenses = append(licenses, name)
+		}
+	}
+	return licenses
+}
+
+func matchesAny(patterns []string, s string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(s)); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// compareVersions compares versions segment by segment, where segments are separated by any non-alphanumeric characters
+// and are compared numerically if both are numbers. Missing numeric segments are treated as zero, so that 1.0 == 1.0.0.
+// It returns a negative number if a < b, zero if a == b, and a positive number if a > b.
+func compareVersions(a, b string) int {
+	split := func(v string) []string {
+		return strings.FieldsFunc(strings.TrimPrefix(v, "v"), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	}
+	as, bs := split(a), split(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		if x == "" && yErr == nil {
+			xErr = nil
+		}
+		if y == "" && xErr == nil {
+			yErr = nil
+		}
+		switch {
+		case xErr == nil && yErr == nil:
+			if xn != yn {
+				return xn - yn
+			}
+		case x == "":
+			return -1
+		case y == "":
+			return 1
+		case x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
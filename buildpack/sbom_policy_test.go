@@ -0,0 +1,74 @@
+package buildpack_test
+
+import (
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestSBOMPolicy(t *testing.T) {
+	spec.Run(t, "unit-sbom-policy", testSBOMPolicy, spec.Report(report.Terminal{}))
+}
+
+func testSBOMPolicy(t *testing.T, when spec.G, it spec.S) {
+	when("#EvaluateSBOMPolicy", func() {
+		sbom := []byte(`{"components": [
+			{"type": "library", "name": "dep-a", "version": "1.0", "licenses": [{"license": {"id": "MIT"}}]},
+			{"type": "library", "name": "dep-b", "version": "2.0", "licenses": [{"expression": "(Apache-2.0 OR GPL-3.0-only)"}]},
+			{"type": "library", "name": "log4j-core", "version": "2.14.1", "purl": "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"},
+			{"type": "library", "name": "dep-c", "version": "v1.10.0"}
+		]}`)
+
+		it("reports denied licenses", func() {
+			violations, err := buildpack.EvaluateSBOMPolicy(buildpack.SBOMPolicy{DenyLicenses: []string{"gpl-*"}}, sbom)
+			h.AssertNil(t, err)
+			h.AssertEq(t, violations, []buildpack.SBOMPolicyViolation{{
+				Component: "dep-b",
+				Version:   "2.0",
+				Rule:      buildpack.SBOMPolicyRuleDeniedLicense,
+				Message:   "license 'GPL-3.0-only' is denied by 'gpl-*'",
+			}})
+		})
+
+		it("reports denied packages", func() {
+			policy := buildpack.SBOMPolicy{Packages: []buildpack.SBOMPackagePolicy{
+				{Name: "dep-a", Deny: true},
+				{PURL: "pkg:maven/org.apache.logging.log4j/log4j-core", Deny: true},
+				{Name: "some-other-dep", Deny: true},
+			}}
+			violations, err := buildpack.EvaluateSBOMPolicy(policy, sbom)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(violations), 2)
+			h.AssertEq(t, violations[0].Component, "dep-a")
+			h.AssertEq(t, violations[0].Rule, buildpack.SBOMPolicyRuleDeniedPackage)
+			h.AssertEq(t, violations[1].PURL, "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1")
+			h.AssertEq(t, violations[1].String(), "log4j-core@2.14.1: package is denied")
+		})
+
+		it("reports packages below the minimum version", func() {
+			policy := buildpack.SBOMPolicy{Packages: []buildpack.SBOMPackagePolicy{
+				{PURL: "pkg:maven/org.apache.logging.log4j/log4j-core@2.17.1", MinVersion: "2.17.1"},
+				{Name: "dep-a", MinVersion: "1.0.0"},
+				{Name: "dep-c", MinVersion: "1.9"},
+			}}
+			violations, err := buildpack.EvaluateSBOMPolicy(policy, sbom)
+			h.AssertNil(t, err)
+			h.AssertEq(t, violations, []buildpack.SBOMPolicyViolation{{
+				Component: "log4j-core",
+				Version:   "2.14.1",
+				PURL:      "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1",
+				Rule:      buildpack.SBOMPolicyRuleMinVersion,
+				Message:   "version '2.14.1' is less than the minimum version '2.17.1'",
+			}})
+		})
+
+		it("errors for invalid SBOMs", func() {
+			_, err := buildpack.EvaluateSBOMPolicy(buildpack.SBOMPolicy{}, []byte(`not-json`))
+			h.AssertNotNil(t, err)
+		})
+	})
+}
@@ -0,0 +1,216 @@
+package buildpack
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// spdxBuildOnlyRelationships are the SPDX relationship types whose source element is only needed to build (or test) the target,
+// and is therefore not present in the application image.
+var spdxBuildOnlyRelationships = map[string]bool{
+	"BUILD_DEPENDENCY_OF": true,
+	"BUILD_TOOL_OF":       true,
+	"DEV_DEPENDENCY_OF":   true,
+	"DEV_TOOL_OF":         true,
+	"TEST_DEPENDENCY_OF":  true,
+	"TEST_TOOL_OF":        true,
+}
+
+// PruneSBOM removes build-only entries and build-time metadata from the provided SBOM document (CycloneDX, SPDX or syft JSON),
+// so that the SBOM in the application image only describes what is present at runtime:
+//   - CycloneDX: components with the "excluded" scope (and the dependencies on them), and metadata.tools
+//   - SPDX: packages that are build, dev or test dependencies or tools (and their relationships), and creationInfo.creators tools
+//   - syft: descriptor.configuration
+//
+// It returns the pruned document and the number of entries removed, or a nil document if there is nothing to prune.
+func PruneSBOM(contents []byte, mediaType string) ([]byte, int, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(contents, &doc); err != nil {
+		return nil, 0, err
+	}
+	var (
+		removed int
+		changed bool
+	)
+	switch mediaType {
+	case MediaTypeCycloneDX:
+		removed, changed = pruneCycloneDX(doc)
+	case MediaTypeSPDX:
+		removed, changed = pruneSPDX(doc)
+	case MediaTypeSyft:
+		if descriptor, ok := doc["descriptor"].(map[string]interface{}); ok {
+			changed = deleteKey(descriptor, "configuration")
+		}
+	default:
+		return nil, 0, errors.Errorf("unsupported SBOM format: '%s'", mediaType)
+	}
+	if !changed {
+		return nil, 0, nil
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	return out, removed, err
+}
+
+// PruneSBOMFile prunes the SBOM file at path in place (see PruneSBOM), based on its extension.
+// It returns the number of entries removed.
+func PruneSBOMFile(path string) (int, error) {
+	mediaType := sbomMediaType(path)
+	if mediaType == mediaTypeUnsupported {
+		return 0, errors.Errorf("unsupported SBOM format: '%s'", path)
+	}
+	contents, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return 0, err
+	}
+	pruned, removed, err := PruneSBOM(contents, mediaType)
+	if err != nil {
+		return 0, errors.Wrapf(err, "pruning SBOM '%s'", path)
+	}
+	if pruned == nil {
+		return 0, nil
+	}
+	return removed, os.WriteFile(path, pruned, 0644) // #nosec G306
+}
+
+func pruneCycloneDX(doc map[string]interface{}) (int, bool) {
+	var changed bool
+	if metadata, ok := doc["metadata"].(map[string]interface{}); ok {
+		changed = deleteKey(metadata, "tools")
+	}
+	var removed int
+	removedRefs := map[string]bool{}
+	var prune func(components []interface{}) []interface{}
+	prune = func(components []interface{}) []interface{} {
+		kept := []interface{}{}
+		for _, c := range components {
+			component, ok := c.(map[string]interface{})
+			if !ok {
+				kept = append(kept, c)
+				continue
+			}
+			if component["scope"] == "excluded" {
+				if ref := str(component["bom-ref"]); ref != "" {
+					removedRefs[ref] = true
+				}
+				removed++
+				continue
+			}
+			if nested, ok := component["components"].([]interface{}); ok {
+				component["components"] = prune(nested)
+			}
+			kept = append(kept, component)
+		}
+		return kept
+	}
+	components, ok := doc["components"].([]interface{})
+	if !ok {
+		return 0, changed
+	}
+	doc["components"] = prune(components)
+	if removed == 0 {
+		return 0, changed
+	}
+	if dependencies, ok := doc["dependencies"].([]interface{}); ok {
+		kept := []interface{}{}
+		for _, d := range dependencies {
+			dependency, ok := d.(map[string]interface{})
+			if ok && removedRefs[str(dependency["ref"])] {
+				continue
+			}
+			if ok {
+				if dependsOn, ok := dependency["dependsOn"].([]interface{}); ok {
+					dependency["dependsOn"] = withoutRefs(dependsOn, removedRefs)
+				}
+			}
+			kept = append(kept, d)
+		}
+		doc["dependencies"] = kept
+	}
+	return removed, true
+}
+
+func pruneSPDX(doc map[string]interface{}) (int, bool) {
+	var changed bool
+	if creationInfo, ok := doc["creationInfo"].(map[string]interface{}); ok {
+		if creators, ok := creationInfo["creators"].([]interface{}); ok {
+			kept := []interface{}{}
+			for _, creator := range creators {
+				if s, ok := creator.(string); ok && strings.HasPrefix(s, "Tool:") {
+					changed = true
+					continue
+				}
+				kept = append(kept, creator)
+			}
+			creationInfo["creators"] = kept
+		}
+	}
+	buildOnly := map[string]bool{}
+	for _, relationship := range objects(doc["relationships"]) {
+		if spdxBuildOnlyRelationships[str(relationship["relationshipType"])] {
+			buildOnly[str(relationship["spdxElementId"])] = true
+		}
+	}
+	// packages that are also runtime dependencies are kept
+	for _, relationship := range objects(doc["relationships"]) {
+		switch str(relationship["relationshipType"]) {
+		case "DEPENDENCY_OF", "RUNTIME_DEPENDENCY_OF":
+			delete(buildOnly, str(relationship["spdxElementId"]))
+		case "DEPENDS_ON":
+			delete(buildOnly, str(relationship["relatedSpdxElement"]))
+		}
+	}
+	packages, ok := doc["packages"].([]interface{})
+	if !ok || len(buildOnly) == 0 {
+		return 0, changed
+	}
+	removed := map[string]bool{}
+	kept := []interface{}{}
+	for _, p := range packages {
+		if pkg, ok := p.(map[string]interface{}); ok && buildOnly[str(pkg["SPDXID"])] {
+			removed[str(pkg["SPDXID"])] = true
+			continue
+		}
+		kept = append(kept, p)
+	}
+	if len(removed) == 0 {
+		return 0, changed
+	}
+	doc["packages"] = kept
+	if relationships, ok := doc["relationships"].([]interface{}); ok {
+		kept := []interface{}{}
+		for _, r := range relationships {
+			if relationship, ok := r.(map[string]interface{}); ok &&
+				(removed[str(relationship["spdxElementId"])] || removed[str(relationship["relatedSpdxElement"])]) {
+				continue
+			}
+			kept = append(kept, r)
+		}
+		doc["relationships"] = kept
+	}
+	if described, ok := doc["documentDescribes"].([]interface{}); ok {
+		doc["documentDescribes"] = withoutRefs(described, removed)
+	}
+	return len(removed), true
+}
+
+func withoutRefs(refs []interface{}, removed map[string]bool) []interface{} {
+	kept := []interface{}{}
+	for _, ref := range refs {
+		if s, ok := ref.(string); ok && removed[s] {
+			continue
+		}
+		kept = append(kept, ref)
+	}
+	return kept
+}
+
+func deleteKey(m map[string]interface{}, key string) bool {
+	if _, ok := m[key]; !ok {
+		return false
+	}
+	delete(m, key)
+	return true
+}
@@ -0,0 +1,126 @@
+package buildpack_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestSBOMPrune(t *testing.T) {
+	spec.Run(t, "unit-sbom-prune", testSBOMPrune, spec.Report(report.Terminal{}))
+}
+
+func testSBOMPrune(t *testing.T, when spec.G, it spec.S) {
+	prune := func(sbom, mediaType string) (map[string]interface{}, int) {
+		t.Helper()
+		pruned, removed, err := buildpack.PruneSBOM([]byte(sbom), mediaType)
+		h.AssertNil(t, err)
+		var doc map[string]interface{}
+		h.AssertNil(t, json.Unmarshal(pruned, &doc))
+		return doc, removed
+	}
+
+	when("#PruneSBOM", func() {
+		it("removes excluded CycloneDX components, the dependencies on them and the tools", func() {
+			doc, removed := prune(`{
+				"bomFormat": "CycloneDX",
+				"metadata": {"tools": [{"name": "some-tool"}], "component": {"name": "some-app"}},
+				"components": [
+					{"bom-ref": "a", "name": "dep-a", "components": [{"bom-ref": "a1", "name": "dep-a1", "scope": "excluded"}]},
+					{"bom-ref": "b", "name": "dep-b", "scope": "excluded"},
+					{"bom-ref": "c", "name": "dep-c", "scope": "required"}
+				],
+				"dependencies": [{"ref": "a", "dependsOn": ["a1", "c"]}, {"ref": "b", "dependsOn": ["c"]}]
+			}`, buildpack.MediaTypeCycloneDX)
+
+			h.AssertEq(t, removed, 2)
+			h.AssertEq(t, doc["metadata"], map[string]interface{}{"component": map[string]interface{}{"name": "some-app"}})
+			h.AssertEq(t, doc["components"], []interface{}{
+				map[string]interface{}{"bom-ref": "a", "name": "dep-a", "components": []interface{}{}},
+				map[string]interface{}{"bom-ref": "c", "name": "dep-c", "scope": "required"},
+			})
+			h.AssertEq(t, doc["dependencies"], []interface{}{
+				map[string]interface{}{"ref": "a", "dependsOn": []interface{}{"c"}},
+			})
+		})
+
+		it("removes SPDX packages that are only build or test dependencies", func() {
+			doc, removed := prune(`{
+				"spdxVersion": "SPDX-2.3",
+				"creationInfo": {"creators": ["Organization: some-org", "Tool: some-tool"]},
+				"packages": [
+					{"SPDXID": "SPDXRef-app", "name": "some-app"},
+					{"SPDXID": "SPDXRef-compiler", "name": "some-compiler"},
+					{"SPDXID": "SPDXRef-lib", "name": "some-lib"},
+					{"SPDXID": "SPDXRef-shared", "name": "some-shared-lib"}
+				],
+				"relationships": [
+					{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-app"},
+					{"spdxElementId": "SPDXRef-compiler", "relationshipType": "BUILD_TOOL_OF", "relatedSpdxElement": "SPDXRef-app"},
+					{"spdxElementId": "SPDXRef-lib", "relationshipType": "RUNTIME_DEPENDENCY_OF", "relatedSpdxElement": "SPDXRef-app"},
+					{"spdxElementId": "SPDXRef-shared", "relationshipType": "TEST_DEPENDENCY_OF", "relatedSpdxElement": "SPDXRef-app"},
+					{"spdxElementId": "SPDXRef-app", "relationshipType": "DEPENDS_ON", "relatedSpdxElement": "SPDXRef-shared"}
+				]
+			}`, buildpack.MediaTypeSPDX)
+
+			h.AssertEq(t, removed, 1)
+			h.AssertEq(t, doc["creationInfo"], map[string]interface{}{"creators": []interface{}{"Organization: some-org"}})
+			h.AssertEq(t, len(doc["packages"].([]interface{})), 3)
+			packages, err := json.Marshal(doc["packages"])
+			h.AssertNil(t, err)
+			h.AssertStringDoesNotContain(t, string(packages), "SPDXRef-compiler")
+			h.AssertEq(t, len(doc["relationships"].([]interface{})), 4)
+		})
+
+		it("removes the syft configuration", func() {
+			doc, removed := prune(`{
+				"artifacts": [{"name": "some-dep"}],
+				"descriptor": {"name": "syft", "configuration": {"some": "config"}}
+			}`, buildpack.MediaTypeSyft)
+
+			h.AssertEq(t, removed, 0)
+			h.AssertEq(t, doc["descriptor"], map[string]interface{}{"name": "syft"})
+		})
+
+		it("returns nil if there is nothing to prune", func() {
+			pruned, removed, err := buildpack.PruneSBOM([]byte(`{"components": [{"name": "dep-a"}]}`), buildpack.MediaTypeCycloneDX)
+			h.AssertNil(t, err)
+			h.AssertEq(t, removed, 0)
+			h.AssertEq(t, pruned, []byte(nil))
+		})
+
+		it("errors for unsupported formats", func() {
+			_, _, err := buildpack.PruneSBOM([]byte(`{}`), "application/some-format")
+			h.AssertError(t, err, "unsupported SBOM format: 'application/some-format'")
+		})
+	})
+
+	when("#PruneSBOMFile", func() {
+		it("rewrites the file only if something was pruned", func() {
+			tmpDir := t.TempDir()
+			prunable := filepath.Join(tmpDir, "launch.sbom.cdx.json")
+			h.Mkfile(t, `{"components": [{"name": "dep-a", "scope": "excluded"}]}`, prunable)
+			unchanged := filepath.Join(tmpDir, "other.sbom.cdx.json")
+			h.Mkfile(t, `{"components": [{"name": "dep-a"}]}`, unchanged)
+
+			removed, err := buildpack.PruneSBOMFile(prunable)
+			h.AssertNil(t, err)
+			h.AssertEq(t, removed, 1)
+			contents, err := os.ReadFile(prunable)
+			h.AssertNil(t, err)
+			h.AssertStringDoesNotContain(t, string(contents), "dep-a")
+
+			removed, err = buildpack.PruneSBOMFile(unchanged)
+			h.AssertNil(t, err)
+			h.AssertEq(t, removed, 0)
+			h.AssertEq(t, h.MustReadFile(t, unchanged), []byte(`{"components": [{"name": "dep-a"}]}`))
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package buildpack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ExtensionGzip is appended to the name of SBOM files that are stored gzip-compressed (e.g., sbom.syft.json.gz).
+// The media type of a compressed file is the media type of the SBOM format, with gzip content encoding.
+const ExtensionGzip = ".gz"
+
+// CompressSBOMs gzip-compresses the SBOM files in the provided directory, replacing each sbom.<ext> file with sbom.<ext>.gz.
+// Compression is deterministic, so that identical files are compressed to identical blobs.
+func CompressSBOMs(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || sbomMediaType(path) == mediaTypeUnsupported || strings.HasSuffix(path, ExtensionGzip) {
+			return nil
+		}
+		contents, err := os.ReadFile(path) // #nosec G304
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err = zw.Write(contents); err != nil {
+			return err
+		}
+		if err = zw.Close(); err != nil {
+			return err
+		}
+		if err = os.WriteFile(path+ExtensionGzip, buf.Bytes(), 0644); err != nil { // #nosec G306
+			return err
+		}
+		return os.Remove(path)
+	})
+}
+
+// DedupeSBOMs replaces SBOM files in the provided directory that are identical to an earlier file (in lexical order)
+// with relative symlinks to that file, as buildpacks often provide identical SBOM files for several layers.
+// It returns the number of files that were replaced and the number of bytes saved.
+func DedupeSBOMs(dir string) (int, int64, error) {
+	var (
+		deduped int
+		saved   int64
+	)
+	byDigest := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || sbomMediaType(path) == mediaTypeUnsupported {
+			return nil
+		}
+		digest, size, err := fileDigest(path)
+		if err != nil {
+			return err
+		}
+		original, ok := byDigest[digest]
+		if !ok {
+			byDigest[digest] = path
+			return nil
+		}
+		target, err := filepath.Rel(filepath.Dir(path), original)
+		if err != nil {
+			return err
+		}
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+		if err = os.Symlink(target, path); err != nil {
+			return errors.Wrapf(err, "linking '%s' to '%s'", path, original)
+		}
+		deduped++
+		saved += size
+		return nil
+	})
+	return deduped, saved, err
+}
+
+func fileDigest(path string) (string, int64, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// readSBOMFile returns the contents of the SBOM file at path, decompressing it if it is gzip-compressed.
+func readSBOMFile(path string) ([]byte, error) {
+	if !strings.HasSuffix(path, ExtensionGzip) {
+		return os.ReadFile(path) // #nosec G304
+	}
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
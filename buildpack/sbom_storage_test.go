@@ -0,0 +1,71 @@
+package buildpack_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestSBOMStorage(t *testing.T) {
+	spec.Run(t, "unit-sbom-storage", testSBOMStorage, spec.Report(report.Terminal{}))
+}
+
+func testSBOMStorage(t *testing.T, when spec.G, it spec.S) {
+	var dir string
+
+	it.Before(func() {
+		dir = t.TempDir()
+		for _, layer := range []string{"layer-a", "layer-b"} {
+			h.AssertNil(t, os.MkdirAll(filepath.Join(dir, "some-buildpack", layer), 0755))
+			h.Mkfile(t, `{"components": [{"type": "library", "name": "dep-a", "version": "1.0"}]}`, filepath.Join(dir, "some-buildpack", layer, "sbom.cdx.json"))
+		}
+		h.Mkfile(t, `{"components": [{"type": "library", "name": "dep-b", "version": "2.0"}]}`, filepath.Join(dir, "some-buildpack", "sbom.cdx.json"))
+		h.Mkfile(t, `some-other-file`, filepath.Join(dir, "some-buildpack", "other.txt"))
+	})
+
+	when("#CompressSBOMs", func() {
+		it("replaces the SBOM files with gzip-compressed files that can still be merged", func() {
+			h.AssertNil(t, buildpack.CompressSBOMs(dir))
+
+			h.AssertPathDoesNotExist(t, filepath.Join(dir, "some-buildpack", "layer-a", "sbom.cdx.json"))
+			h.AssertPathExists(t, filepath.Join(dir, "some-buildpack", "layer-a", "sbom.cdx.json.gz"))
+			h.AssertPathExists(t, filepath.Join(dir, "some-buildpack", "other.txt"))
+			h.AssertEq(t, h.MustReadFile(t, filepath.Join(dir, "some-buildpack", "layer-a", "sbom.cdx.json.gz")),
+				h.MustReadFile(t, filepath.Join(dir, "some-buildpack", "layer-b", "sbom.cdx.json.gz")))
+
+			sources, err := buildpack.SBOMSourcesIn(dir, nil)
+			h.AssertNil(t, err)
+			merged, err := buildpack.MergeSBOMs(sources, "some-image")
+			h.AssertNil(t, err)
+			h.AssertStringContains(t, string(merged), "dep-a")
+			h.AssertStringContains(t, string(merged), "dep-b")
+		})
+	})
+
+	when("#DedupeSBOMs", func() {
+		it.Before(func() {
+			if runtime.GOOS == "windows" {
+				t.Skip("symlinks require elevated privileges on windows")
+			}
+		})
+
+		it("replaces identical SBOM files with symlinks", func() {
+			deduped, saved, err := buildpack.DedupeSBOMs(dir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, deduped, 1)
+			h.AssertEq(t, saved, int64(len(`{"components": [{"type": "library", "name": "dep-a", "version": "1.0"}]}`)))
+
+			target, err := os.Readlink(filepath.Join(dir, "some-buildpack", "layer-b", "sbom.cdx.json"))
+			h.AssertNil(t, err)
+			h.AssertEq(t, target, filepath.Join("..", "layer-a", "sbom.cdx.json"))
+			h.AssertEq(t, h.Rdfile(t, filepath.Join(dir, "some-buildpack", "layer-b", "sbom.cdx.json")), `{"components": [{"type": "library", "name": "dep-a", "version": "1.0"}]}`)
+		})
+	})
+}
@@ -0,0 +1,246 @@
+package buildpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CheckStructure returns an error if the SBOM file does not have the structure expected for its format.
+// It is not a complete validation against the JSON schemas of the formats: only the document structure,
+// the required fields, and the types of the fields used to identify packages are checked,
+// as these are what common SBOM consumers rely upon.
+func (b *BOMFile) CheckStructure() error {
+	contents, err := os.ReadFile(b.Path)
+	if err != nil {
+		return err
+	}
+	problems, err := validateSBOM(contents, b.mediaType())
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("invalid SBOM: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+var (
+	// component types from the CycloneDX 1.6 schema (which includes those from earlier versions)
+	cdxComponentTypes = []string{
+		"application", "framework", "library", "container", "platform", "operating-system",
+		"device", "device-driver", "firmware", "file", "machine-learning-model", "data", "cryptographic-asset",
+	}
+	spdxVersionPattern = regexp.MustCompile(`^SPDX-2\.[0-9]+$`)
+	spdxIDPattern      = regexp.MustCompile(`^SPDXRef-[A-Za-z0-9.\-]+$`)
+)
+
+func validateSBOM(contents []byte, mediaType string) ([]string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(contents, &doc); err != nil {
+		return []string{fmt.Sprintf("parsing JSON: %s", err)}, nil
+	}
+	v := &sbomValidator{}
+	obj := v.object(doc, "")
+	if obj == nil {
+		return v.problems, nil
+	}
+	switch mediaType {
+	case MediaTypeCycloneDX:
+		v.validateCycloneDX(obj)
+	case MediaTypeSPDX:
+		v.validateSPDX(obj)
+	case MediaTypeSyft:
+		v.validateSyft(obj)
+	default:
+		return nil, errors.Errorf("unsupported SBOM format: '%s'", mediaType)
+	}
+	return v.problems, nil
+}
+
+type sbomValidator struct {
+	problems []string
+}
+
+func (v *sbomValidator) fail(path, format string, args ...interface{}) {
+	if path == "" {
+		path = "(root)"
+	}
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *sbomValidator) validateCycloneDX(doc map[string]interface{}) {
+	if bomFormat, ok := v.requiredString(doc, "", "bomFormat"); ok && bomFormat != "CycloneDX" {
+		v.fail("bomFormat", "must be 'CycloneDX'")
+	}
+	v.requiredString(doc, "", "specVersion")
+	if version, ok := doc["version"]; ok {
+		if n, ok := version.(float64); !ok || n < 1 || n != float64(int(n)) {
+			v.fail("version", "must be an integer greater than or equal to 1")
+		}
+	}
+	if metadata, ok := doc["metadata"]; ok {
+		if m := v.object(metadata, "metadata"); m != nil {
+			if component, ok := m["component"]; ok {
+				v.validateCycloneDXComponent(component, "metadata.component")
+			}
+		}
+	}
+	v.validateCycloneDXComponents(doc, "")
+}
+
+func (v *sbomValidator) validateCycloneDXComponents(parent map[string]interface{}, path string) {
+	components, ok := parent["components"]
+	if !ok {
+		return
+	}
+	for i, component := range v.array(components, fieldPath(path, "components")) {
+		v.validateCycloneDXComponent(component, fmt.Sprintf("%s[%d]", fieldPath(path, "components"), i))
+	}
+}
+
+func (v *sbomValidator) validateCycloneDXComponent(component interface{}, path string) {
+	c := v.object(component, path)
+	if c == nil {
+		return
+	}
+	if typ, ok := v.requiredString(c, path, "type"); ok && !contains(cdxComponentTypes, typ) {
+		v.fail(fieldPath(path, "type"), "must be one of %s", strings.Join(cdxComponentTypes, ", "))
+	}
+	v.requiredString(c, path, "name")
+	v.optionalString(c, path, "version")
+	v.optionalString(c, path, "purl")
+	v.optionalString(c, path, "bom-ref")
+	v.validateCycloneDXComponents(c, path)
+}
+
+func (v *sbomValidator) validateSPDX(doc map[string]interface{}) {
+	if version, ok := v.requiredString(doc, "", "spdxVersion"); ok && !spdxVersionPattern.MatchString(version) {
+		v.fail("spdxVersion", "must match '%s'", spdxVersionPattern)
+	}
+	if dataLicense, ok := v.requiredString(doc, "", "dataLicense"); ok && dataLicense != "CC0-1.0" {
+		v.fail("dataLicense", "must be 'CC0-1.0'")
+	}
+	if id, ok := v.requiredString(doc, "", "SPDXID"); ok && id != "SPDXRef-DOCUMENT" {
+		v.fail("SPDXID", "must be 'SPDXRef-DOCUMENT'")
+	}
+	v.requiredString(doc, "", "name")
+	v.requiredString(doc, "", "documentNamespace")
+	if creationInfo, ok := v.required(doc, "", "creationInfo"); ok {
+		if info := v.object(creationInfo, "creationInfo"); info != nil {
+			v.requiredString(info, "creationInfo", "created")
+			if creators, ok := v.required(info, "creationInfo", "creators"); ok {
+				list := v.array(creators, "creationInfo.creators")
+				if list != nil && len(list) == 0 {
+					v.fail("creationInfo.creators", "must contain at least 1 item")
+				}
+				for i, creator := range list {
+					if _, ok := creator.(string); !ok {
+						v.fail(fmt.Sprintf("creationInfo.creators[%d]", i), "must be a string")
+					}
+				}
+			}
+		}
+	}
+	packages, ok := doc["packages"]
+	if !ok {
+		return
+	}
+	for i, pkg := range v.array(packages, "packages") {
+		path := fmt.Sprintf("packages[%d]", i)
+		p := v.object(pkg, path)
+		if p == nil {
+			continue
+		}
+		if id, ok := v.requiredString(p, path, "SPDXID"); ok && !spdxIDPattern.MatchString(id) {
+			v.fail(fieldPath(path, "SPDXID"), "must match '%s'", spdxIDPattern)
+		}
+		v.requiredString(p, path, "name")
+		v.requiredString(p, path, "downloadLocation")
+		v.optionalString(p, path, "versionInfo")
+	}
+}
+
+func (v *sbomValidator) validateSyft(doc map[string]interface{}) {
+	artifacts, ok := v.required(doc, "", "artifacts")
+	if !ok {
+		return
+	}
+	for i, artifact := range v.array(artifacts, "artifacts") {
+		path := fmt.Sprintf("artifacts[%d]", i)
+		a := v.object(artifact, path)
+		if a == nil {
+			continue
+		}
+		v.requiredString(a, path, "name")
+		v.optionalString(a, path, "version")
+		v.optionalString(a, path, "type")
+		v.optionalString(a, path, "purl")
+	}
+}
+
+// helpers
+
+func (v *sbomValidator) required(obj map[string]interface{}, path, key string) (interface{}, bool) {
+	val, ok := obj[key]
+	if !ok {
+		v.fail(fieldPath(path, key), "is required")
+	}
+	return val, ok
+}
+
+func (v *sbomValidator) requiredString(obj map[string]interface{}, path, key string) (string, bool) {
+	val, ok := v.required(obj, path, key)
+	if !ok {
+		return "", false
+	}
+	s, ok := val.(string)
+	if !ok {
+		v.fail(fieldPath(path, key), "must be a string")
+	}
+	return s, ok
+}
+
+func (v *sbomValidator) optionalString(obj map[string]interface{}, path, key string) {
+	if val, ok := obj[key]; ok {
+		if _, ok := val.(string); !ok {
+			v.fail(fieldPath(path, key), "must be a string")
+		}
+	}
+}
+
+func (v *sbomValidator) object(val interface{}, path string) map[string]interface{} {
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		v.fail(path, "must be an object")
+	}
+	return obj
+}
+
+func (v *sbomValidator) array(val interface{}, path string) []interface{} {
+	list, ok := val.([]interface{})
+	if !ok {
+		v.fail(path, "must be an array")
+	}
+	return list
+}
+
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func contains(list []string, s string) bool {
+	for _, el := range list {
+		if el == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package buildpack_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestSBOMValidate(t *testing.T) {
+	spec.Run(t, "unit-sbom-validate", testSBOMValidate, spec.Report(report.Terminal{}))
+}
+
+func testSBOMValidate(t *testing.T, when spec.G, it spec.S) {
+	when("BOMFile#CheckStructure", func() {
+		var tmpDir string
+
+		it.Before(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "lifecycle.sbom-validate")
+			h.AssertNil(t, err)
+		})
+
+		it.After(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		validate := func(name, contents string) error {
+			path := filepath.Join(tmpDir, name)
+			h.Mkfile(t, contents, path)
+			bomFile := buildpack.BOMFile{BuildpackID: "some/buildpack", LayerType: buildpack.LayerTypeLaunch, Path: path}
+			return bomFile.CheckStructure()
+		}
+
+		when("CycloneDX", func() {
+			it("accepts valid documents", func() {
+				h.AssertNil(t, validate("launch.sbom.cdx.json", `{
+					"bomFormat": "CycloneDX", "specVersion": "1.4", "version": 1,
+					"components": [{"type": "library", "name": "dep", "version": "1.0", "components": [{"type": "file", "name": "nested"}]}]
+				}`))
+			})
+
+			it("accepts component types added in CycloneDX 1.6", func() {
+				h.AssertNil(t, validate("launch.sbom.cdx.json", `{
+					"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+					"components": [{"type": "cryptographic-asset", "name": "some-key"}]
+				}`))
+			})
+
+			it("reports missing and invalid fields", func() {
+				err := validate("launch.sbom.cdx.json", `{
+					"bomFormat": "cyclonedx", "version": 0,
+					"components": [{"type": "some-type", "name": 1, "components": [{"name": "nested"}]}]
+				}`)
+				h.AssertError(t, err, "invalid SBOM: bomFormat: must be 'CycloneDX'; "+
+					"specVersion: is required; "+
+					"version: must be an integer greater than or equal to 1; "+
+					"components[0].type: must be one of ")
+				h.AssertError(t, err, "components[0].name: must be a string; components[0].components[0].type: is required")
+			})
+		})
+
+		when("SPDX", func() {
+			it("accepts valid documents", func() {
+				h.AssertNil(t, validate("launch.sbom.spdx.json", `{
+					"spdxVersion": "SPDX-2.3", "dataLicense": "CC0-1.0", "SPDXID": "SPDXRef-DOCUMENT",
+					"name": "some-doc", "documentNamespace": "https://example.com/some-doc",
+					"creationInfo": {"created": "2023-01-01T00:00:00Z", "creators": ["Tool: some-tool"]},
+					"packages": [{"SPDXID": "SPDXRef-Package-1", "name": "dep", "downloadLocation": "NOASSERTION"}]
+				}`))
+			})
+
+			it("reports missing and invalid fields", func() {
+				err := validate("launch.sbom.spdx.json", `{
+					"spdxVersion": "SPDX-3.0", "dataLicense": "MIT", "SPDXID": "SPDXRef-DOCUMENT", "name": "some-doc",
+					"creationInfo": {"created": "2023-01-01T00:00:00Z", "creators": []},
+					"packages": [{"SPDXID": "some-id", "name": "dep"}]
+				}`)
+				h.AssertError(t, err, "invalid SBOM: spdxVersion: must match '^SPDX-2\\.[0-9]+$'; "+
+					"dataLicense: must be 'CC0-1.0'; "+
+					"documentNamespace: is required; "+
+					"creationInfo.creators: must contain at least 1 item; "+
+					"packages[0].SPDXID: must match '^SPDXRef-[A-Za-z0-9.\\-]+$'; "+
+					"packages[0].downloadLocation: is required")
+			})
+		})
+
+		when("syft", func() {
+			it("accepts valid documents", func() {
+				h.AssertNil(t, validate("launch.sbom.syft.json", `{"artifacts": [{"name": "dep", "version": "1.0", "type": "go-module"}]}`))
+			})
+
+			it("reports missing and invalid fields", func() {
+				err := validate("launch.sbom.syft.json", `{"artifacts": [{"version": 1}]}`)
+				h.AssertError(t, err, "invalid SBOM: artifacts[0].name: is required; artifacts[0].version: must be a string")
+			})
+		})
+
+		it("reports invalid JSON", func() {
+			err := validate("launch.sbom.cdx.json", `not-json`)
+			h.AssertError(t, err, "invalid SBOM: parsing JSON: ")
+		})
+
+		it("reports documents that are not objects", func() {
+			err := validate("launch.sbom.cdx.json", `[]`)
+			h.AssertError(t, err, "invalid SBOM: (root): must be an object")
+		})
+	})
+}
@@ -1,7 +1,9 @@
 package lifecycle
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/telemetry/metrics"
 )
 
 type LayerDir interface {
@@ -18,7 +21,16 @@ type LayerDir interface {
 	Path() string
 }
 
+// Cache is equivalent to CacheContext with context.Background().
+//
+// Deprecated: use CacheContext.
 func (e *Exporter) Cache(layersDir string, cacheStore Cache) error {
+	return e.CacheContext(context.Background(), layersDir, cacheStore)
+}
+
+// CacheContext adds the cache=true layers of the build to the cache, reusing the layers that are unchanged, and commits the cache.
+// The context is checked before each layer is added; the cache is not committed if the context is done.
+func (e *Exporter) CacheContext(ctx context.Context, layersDir string, cacheStore Cache) error {
 	defer log.NewMeasurement("Cache", e.Logger)()
 	var err error
 	if !cacheStore.Exists() {
@@ -43,6 +55,9 @@ func (e *Exporter) Cache(layersDir string, cacheStore Cache) error {
 		}
 		for _, layer := range bpDir.FindLayers(buildpack.MadeCached) {
 			layer := layer
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			if !layer.HasLocalContents() {
 				e.Logger.Warnf("Failed to cache layer '%s' because it has no contents", layer.Identifier())
 				continue
@@ -54,7 +69,11 @@ func (e *Exporter) Cache(layersDir string, cacheStore Cache) error {
 			}
 			origLayerMetadata := origMeta.MetadataForBuildpack(bp.ID).Layers[layer.Name()]
 			createdBy := fmt.Sprintf(layers.BuildpackLayerName, layer.Name(), fmt.Sprintf("%s@%s", bp.ID, bp.Version))
-			if lmd.SHA, err = e.addOrReuseCacheLayer(cacheStore, &layer, origLayerMetadata.SHA, createdBy); err != nil {
+			factory := e.cacheLayerFactory()
+			if lmd.Launch {
+				factory = e.LayerFactory
+			}
+			if lmd.SHA, err = e.addOrReuseCacheLayer(factory, cacheStore, &layer, origLayerMetadata.SHA, createdBy); err != nil {
 				e.Logger.Warnf("Failed to cache layer '%s': %s", layer.Identifier(), err)
 				continue
 			}
@@ -69,6 +88,9 @@ func (e *Exporter) Cache(layersDir string, cacheStore Cache) error {
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if err := cacheStore.SetMetadata(meta); err != nil {
 		return errors.Wrap(err, "setting cache metadata")
 	}
@@ -92,21 +114,60 @@ func (l *layerDir) Path() string {
 	return l.path
 }
 
-func (e *Exporter) addOrReuseCacheLayer(cache Cache, layerDir LayerDir, previousSHA, createdBy string) (string, error) {
-	layer, err := e.LayerFactory.DirLayer(layerDir.Identifier(), layerDir.Path(), createdBy)
+func (e *Exporter) cacheLayerFactory() LayerFactory {
+	if e.CacheLayerFactory == nil {
+		return e.LayerFactory
+	}
+	return e.CacheLayerFactory
+}
+
+func (e *Exporter) addOrReuseCacheLayer(factory LayerFactory, cache Cache, layerDir LayerDir, previousSHA, createdBy string) (string, error) {
+	layer, err := factory.DirLayer(layerDir.Identifier(), layerDir.Path(), createdBy)
 	if err != nil {
 		return "", errors.Wrapf(err, "creating layer '%s'", layerDir.Identifier())
 	}
 	if layer.Digest == previousSHA {
+		metrics.Add(metrics.LayerCacheLookups, 1, metrics.L("cache", metrics.CacheBuild), metrics.L("result", metrics.ResultHit))
 		e.Logger.Infof("Reusing cache layer '%s'\n", layer.ID)
 		e.Logger.Debugf("Layer '%s' SHA: %s\n", layer.ID, layer.Digest)
 		return layer.Digest, cache.ReuseLayer(previousSHA)
 	}
+	metrics.Add(metrics.LayerCacheLookups, 1, metrics.L("cache", metrics.CacheBuild), metrics.L("result", metrics.ResultMiss))
 	e.Logger.Infof("Adding cache layer '%s'\n", layer.ID)
 	e.Logger.Debugf("Layer '%s' SHA: %s\n", layer.ID, layer.Digest)
+	if mounter, ok := cache.(layerMounter); ok {
+		if path, imageName, ok := e.UploadedBlobs.Lookup(layer.Digest); ok {
+			// the layer was uploaded with the app image, so its compressed tarball is reused and its blob is mounted
+			e.Logger.Debugf("Mounting layer '%s' from '%s'\n", layer.ID, imageName)
+			return layer.Digest, mounter.AddLayerFileFrom(path, layer.Digest, imageName)
+		}
+	}
+	if streamer, ok := cache.(layerStreamer); ok && !layer.Written() {
+		// stream the layer into the cache, rather than writing its tarball only to copy it
+		rc, err := layer.Open()
+		if err != nil {
+			return "", errors.Wrapf(err, "opening layer '%s'", layer.ID)
+		}
+		defer rc.Close()
+		return layer.Digest, streamer.AddLayer(rc, layer.Digest)
+	}
+	if err := layer.WriteTar(); err != nil {
+		return "", errors.Wrapf(err, "writing layer '%s'", layer.ID)
+	}
 	return layer.Digest, cache.AddLayerFile(layer.TarPath, layer.Digest)
 }
 
+// layerMounter is implemented by caches in a registry that can mount a layer from an image
+// in another repository, rather than upload it again.
+type layerMounter interface {
+	AddLayerFileFrom(tarPath, diffID, imageName string) error
+}
+
+// layerStreamer is implemented by caches that can add a layer from its uncompressed tarball as it is generated.
+type layerStreamer interface {
+	AddLayer(rc io.ReadCloser, diffID string) error
+}
+
 func (e *Exporter) addSBOMCacheLayer(layersDir string, cacheStore Cache, origMetadata platform.CacheMetadata, meta *platform.CacheMetadata) error {
 	sbomCacheDir, err := readLayersSBOM(layersDir, "cache", e.Logger)
 	if err != nil {
@@ -114,14 +175,14 @@ func (e *Exporter) addSBOMCacheLayer(layersDir string, cacheStore Cache, origMet
 	}
 
 	if sbomCacheDir != nil {
-		l, err := e.LayerFactory.DirLayer(sbomCacheDir.Identifier(), sbomCacheDir.Path(), layers.SBOMLayerName)
+		l, err := e.cacheLayerFactory().DirLayer(sbomCacheDir.Identifier(), sbomCacheDir.Path(), layers.SBOMLayerName)
 		if err != nil {
 			return errors.Wrapf(err, "creating layer '%s', path: '%s'", sbomCacheDir.Identifier(), sbomCacheDir.Path())
 		}
 
 		lyr := &layerDir{path: l.TarPath, identifier: l.ID}
 
-		meta.BOM.SHA, err = e.addOrReuseCacheLayer(cacheStore, lyr, origMetadata.BOM.SHA, layers.SBOMLayerName)
+		meta.BOM.SHA, err = e.addOrReuseCacheLayer(e.cacheLayerFactory(), cacheStore, lyr, origMetadata.BOM.SHA, layers.SBOMLayerName)
 		if err != nil {
 			return err
 		}
@@ -10,14 +10,33 @@ import (
 	"github.com/buildpacks/imgutil"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
 )
 
+// LaunchCache stores the layers of images exported to a daemon, so that they can be reused without retrieving them from the daemon.
+// It is implemented by VolumeCache (a launch cache volume) and RegistryLaunchCache.
+type LaunchCache interface {
+	AddLayerFile(tarPath string, diffID string) error
+	AddLayer(rc io.ReadCloser, diffID string) error
+	HasLayer(diffID string) (bool, error)
+	ReuseLayer(diffID string) error
+	RetrieveLayer(diffID string) (io.ReadCloser, error)
+	RetrieveLayerFile(diffID string) (string, error)
+	Commit() error
+}
+
+// readOnlyCache is implemented by launch caches that are not populated with the layers reused from the daemon.
+type readOnlyCache interface {
+	ReadOnly() bool
+}
+
 type CachingImage struct {
 	imgutil.Image
-	cache *VolumeCache
+	cache LaunchCache
 }
 
-func NewCachingImage(image imgutil.Image, cache *VolumeCache) imgutil.Image {
+func NewCachingImage(image imgutil.Image, cache LaunchCache) imgutil.Image {
 	return &CachingImage{
 		Image: image,
 		cache: cache,
@@ -32,7 +51,7 @@ func (c *CachingImage) AddLayer(path string) error {
 	defer f.Close()
 
 	hasher := sha256.New()
-	if _, err := io.Copy(hasher, f); err != nil {
+	if _, err := fsutil.Stream(hasher, f); err != nil {
 		return errors.Wrap(err, "hashing layer")
 	}
 	diffID := "sha256:" + hex.EncodeToString(hasher.Sum(make([]byte, 0, hasher.Size())))
@@ -75,6 +94,9 @@ func (c *CachingImage) ReuseLayer(diffID string) error {
 	if err := c.Image.ReuseLayer(diffID); err != nil {
 		return err
 	}
+	if c.readOnly() {
+		return nil
+	}
 	rc, err := c.Image.GetLayer(diffID)
 	if err != nil {
 		return err
@@ -102,6 +124,9 @@ func (c *CachingImage) ReuseLayerWithHistory(diffID string, history v1.History)
 	if err := c.Image.ReuseLayerWithHistory(diffID, history); err != nil {
 		return err
 	}
+	if c.readOnly() {
+		return nil
+	}
 	rc, err := c.Image.GetLayer(diffID)
 	if err != nil {
 		return err
@@ -109,6 +134,11 @@ func (c *CachingImage) ReuseLayerWithHistory(diffID string, history v1.History)
 	return c.cache.AddLayer(rc, diffID)
 }
 
+func (c *CachingImage) readOnly() bool {
+	cache, ok := c.cache.(readOnlyCache)
+	return ok && cache.ReadOnly()
+}
+
 func (c *CachingImage) GetLayer(diffID string) (io.ReadCloser, error) {
 	if found, err := c.cache.HasLayer(diffID); err != nil {
 		return nil, fmt.Errorf("layer with SHA '%s' not found", diffID)
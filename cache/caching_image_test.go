@@ -127,6 +127,23 @@ func testCachingImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("the cache is read-only", func() {
+		it.Before(func() {
+			subject = cache.NewCachingImage(fakeImage, &readOnlyCache{VolumeCache: volumeCache})
+			fakeImage.AddPreviousLayer(layerSHA, layerPath)
+		})
+
+		it("reuses the layer from the image without adding it to the cache", func() {
+			h.AssertNil(t, subject.ReuseLayer(layerSHA))
+
+			h.AssertNil(t, subject.Save())
+			h.AssertEq(t, fakeImage.IsSaved(), true)
+			found, err := volumeCache.HasLayer(layerSHA)
+			h.AssertNil(t, err)
+			h.AssertEq(t, found, false)
+		})
+	})
+
 	when("#GetLayer", func() {
 		when("the layer exists in the cache", func() {
 			it.Before(func() {
@@ -162,3 +179,12 @@ func testCachingImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 }
+
+// readOnlyCache is a launch cache that is not populated with the layers reused from the image.
+type readOnlyCache struct {
+	*cache.VolumeCache
+}
+
+func (c *readOnlyCache) ReadOnly() bool {
+	return true
+}
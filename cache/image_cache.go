@@ -9,8 +9,12 @@ import (
 	"github.com/buildpacks/imgutil"
 	"github.com/buildpacks/imgutil/remote"
 	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/pkg/errors"
 
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/image"
 	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform"
@@ -24,6 +28,14 @@ type ImageCache struct {
 	newImage     imgutil.Image
 	logger       log.Logger
 	imageDeleter ImageDeleter
+	keychain     authn.Keychain
+	mounts       []layerMount
+}
+
+// layerMount is a layer of the new image to mount from an image in another repository when the new image is saved.
+type layerMount struct {
+	tarPath   string
+	imageName string
 }
 
 // NewImageCache creates a new ImageCache instance
@@ -45,7 +57,7 @@ func NewImageCacheFromName(name string, keychain authn.Keychain, logger log.Logg
 		remote.WithDefaultPlatform(imgutil.Platform{OS: runtime.GOOS}),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("accessing cache image %q: %v", name, err)
+		return nil, fmt.Errorf("accessing cache image %q: %w", name, lerrors.WrapRegistryError(name, lerrors.AccessRead, err))
 	}
 	emptyImage, err := remote.NewImage(
 		name,
@@ -55,10 +67,12 @@ func NewImageCacheFromName(name string, keychain authn.Keychain, logger log.Logg
 		remote.AddEmptyLayerOnSave(),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("creating new cache image %q: %v", name, err)
+		return nil, fmt.Errorf("creating new cache image %q: %w", name, lerrors.WrapRegistryError(name, lerrors.AccessReadWrite, err))
 	}
 
-	return NewImageCache(origImage, emptyImage, logger, imageDeleter), nil
+	imageCache := NewImageCache(origImage, emptyImage, logger, imageDeleter)
+	imageCache.keychain = keychain
+	return imageCache, nil
 }
 
 func (c *ImageCache) Exists() bool {
@@ -99,6 +113,18 @@ func (c *ImageCache) AddLayerFile(tarPath string, diffID string) error {
 	return c.newImage.AddLayerWithDiffID(tarPath, diffID)
 }
 
+// AddLayerFileFrom adds the layer like AddLayerFile, given its compressed tarball which is already uploaded with the named image.
+// When the cache is committed, the layer is mounted from the repository of that image (if the registry supports it) rather than uploaded again.
+func (c *ImageCache) AddLayerFileFrom(tarPath string, diffID string, imageName string) error {
+	if err := c.AddLayerFile(tarPath, diffID); err != nil {
+		return err
+	}
+	if c.keychain != nil {
+		c.mounts = append(c.mounts, layerMount{tarPath: tarPath, imageName: imageName})
+	}
+	return nil
+}
+
 func (c *ImageCache) ReuseLayer(diffID string) error {
 	if c.committed {
 		return errCacheCommitted
@@ -115,6 +141,7 @@ func (c *ImageCache) Commit() error {
 		return errCacheCommitted
 	}
 
+	c.mountLayers()
 	if err := c.newImage.Save(); err != nil {
 		return errors.Wrapf(err, "saving image '%s'", c.newImage.Name())
 	}
@@ -129,3 +156,31 @@ func (c *ImageCache) Commit() error {
 
 	return nil
 }
+
+// mountLayers mounts the layers added with AddLayerFileFrom into the repository of the new image,
+// so that saving the image finds their blobs rather than uploading them.
+// A layer that cannot be mounted is uploaded when the image is saved.
+func (c *ImageCache) mountLayers() {
+	if len(c.mounts) == 0 {
+		return
+	}
+	repo, err := name.ParseReference(c.newImage.Name(), name.WeakValidation)
+	if err != nil {
+		return
+	}
+	for _, mount := range c.mounts {
+		source, err := name.ParseReference(mount.imageName, name.WeakValidation)
+		if err != nil {
+			continue
+		}
+		layer, err := tarball.LayerFromFile(mount.tarPath)
+		if err != nil {
+			continue
+		}
+		mountable := &ggcrremote.MountableLayer{Layer: layer, Reference: source}
+		if err = ggcrremote.WriteLayer(repo.Context(), mountable, ggcrremote.WithAuthFromKeychain(c.keychain)); err != nil {
+			c.logger.Debugf("Failed to mount layer from '%s': %s", mount.imageName, err)
+		}
+	}
+	c.mounts = nil
+}
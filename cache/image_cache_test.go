@@ -3,8 +3,13 @@ package cache_test
 import (
 	"fmt"
 	"io"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	cacheMock "github.com/buildpacks/lifecycle/testmock/cache"
@@ -13,6 +18,13 @@ import (
 
 	"github.com/buildpacks/imgutil/fakes"
 	"github.com/buildpacks/imgutil/local"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
@@ -242,6 +254,77 @@ func testImageCache(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 
+		when("with #AddLayerFileFrom", func() {
+			var (
+				server   *httptest.Server
+				layer    v1.Layer
+				mounted  bool
+				uploaded bool
+				mu       sync.Mutex
+			)
+
+			it.Before(func() {
+				var err error
+				layer, err = random.Layer(1024, types.DockerLayer)
+				h.AssertNil(t, err)
+				digest, err := layer.Digest()
+				h.AssertNil(t, err)
+
+				// the test registry stores blobs for every repository, so a registry that stores them by repository
+				// and supports mounting is simulated for the blob of the layer in the cache repository
+				handler := registry.New(registry.Logger(stdlog.New(io.Discard, "", 0)))
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					defer mu.Unlock()
+					switch {
+					case r.Method == http.MethodHead && r.URL.Path == "/v2/some-repo/cache/blobs/"+digest.String() && !mounted:
+						w.WriteHeader(http.StatusNotFound)
+						return
+					case r.Method == http.MethodPost && r.URL.Path == "/v2/some-repo/cache/blobs/uploads/" && r.URL.Query().Get("mount") == digest.String():
+						mounted = r.URL.Query().Get("from") == "some-repo/app"
+						w.WriteHeader(http.StatusCreated)
+						return
+					case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v2/some-repo/cache/blobs/uploads/") && r.URL.Query().Get("digest") == digest.String():
+						uploaded = true
+					}
+					handler.ServeHTTP(w, r)
+				}))
+			})
+
+			it.After(func() {
+				server.Close()
+			})
+
+			it("mounts the layer from the repository of the image it was uploaded with", func() {
+				host := strings.TrimPrefix(server.URL, "http://")
+				appRepo, err := name.NewRepository(host + "/some-repo/app")
+				h.AssertNil(t, err)
+				h.AssertNil(t, ggcrremote.WriteLayer(appRepo, layer))
+				rc, err := layer.Compressed()
+				h.AssertNil(t, err)
+				contents, err := io.ReadAll(rc)
+				h.AssertNil(t, err)
+				h.AssertNil(t, rc.Close())
+				compressedPath := filepath.Join(tmpDir, "some-layer.tar.gz")
+				h.AssertNil(t, os.WriteFile(compressedPath, contents, 0600))
+				diffID, err := layer.DiffID()
+				h.AssertNil(t, err)
+
+				imageCache, err := cache.NewImageCacheFromName(host+"/some-repo/cache", authn.DefaultKeychain, testLogger, cache.NewImageDeleter(cache.NewImageComparer(), testLogger, false))
+				h.AssertNil(t, err)
+				h.AssertNil(t, imageCache.AddLayerFileFrom(compressedPath, diffID.String(), appRepo.Tag("latest").String()))
+				h.AssertNil(t, imageCache.Commit())
+
+				mu.Lock()
+				h.AssertEq(t, mounted, true)
+				h.AssertEq(t, uploaded, false)
+				mu.Unlock()
+				rc, err = imageCache.RetrieveLayer(diffID.String())
+				h.AssertNil(t, err)
+				h.AssertNil(t, rc.Close())
+			})
+		})
+
 		when("with #ReuseLayer", func() {
 			it.Before(func() {
 				fakeNewImage.AddPreviousLayer(testLayerSHA, testLayerTarPath)
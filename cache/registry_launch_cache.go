@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// RegistryLaunchCache is a launch cache backed by an image in a registry (e.g., the previous image, as published by the platform),
+// for exports to a daemon without a launch cache volume (e.g., on ephemeral CI runners).
+// The cache is populated lazily: a layer is only fetched (by its diff ID) when the exporter reuses it,
+// rather than retrieved from the previous image in the daemon. The cache is read-only; layers added to the image are not stored.
+type RegistryLaunchCache struct {
+	imageRef string
+	keychain authn.Keychain
+	dir      string
+	logger   log.Logger
+
+	once    sync.Once
+	image   v1.Image
+	diffIDs map[string]bool
+	mu      sync.Mutex
+}
+
+// NewRegistryLaunchCache returns a launch cache backed by the image in a registry, which keeps the layers it fetches in dir.
+func NewRegistryLaunchCache(imageRef string, keychain authn.Keychain, dir string, logger log.Logger) *RegistryLaunchCache {
+	return &RegistryLaunchCache{
+		imageRef: imageRef,
+		keychain: keychain,
+		dir:      dir,
+		logger:   logger,
+	}
+}
+
+// load reads the manifest and config of the image the first time it is needed.
+// If the image cannot be read, the cache is empty, so that layers are reused from the image in the daemon instead.
+func (c *RegistryLaunchCache) load() {
+	c.once.Do(func() {
+		c.diffIDs = map[string]bool{}
+		ref, err := name.ParseReference(c.imageRef, name.WeakValidation)
+		if err != nil {
+			c.logger.Warnf("Ignoring launch cache image '%s': %s", c.imageRef, err)
+			return
+		}
+		image, err := ggcrremote.Image(ref,
+			ggcrremote.WithAuthFromKeychain(c.keychain),
+			ggcrremote.WithPlatform(v1.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH}),
+		)
+		if err != nil {
+			c.logger.Warnf("Ignoring launch cache image '%s': %s", c.imageRef, err)
+			return
+		}
+		configFile, err := image.ConfigFile()
+		if err != nil {
+			c.logger.Warnf("Ignoring launch cache image '%s': %s", c.imageRef, err)
+			return
+		}
+		for _, diffID := range configFile.RootFS.DiffIDs {
+			c.diffIDs[diffID.String()] = true
+		}
+		c.image = image
+	})
+}
+
+func (c *RegistryLaunchCache) HasLayer(diffID string) (bool, error) {
+	c.load()
+	return c.diffIDs[diffID], nil
+}
+
+// RetrieveLayerFile fetches the uncompressed tarball of the layer from the registry, the first time it is retrieved.
+func (c *RegistryLaunchCache) RetrieveLayerFile(diffID string) (string, error) {
+	c.load()
+	if !c.diffIDs[diffID] {
+		return "", errors.Errorf("layer with SHA '%s' not found", diffID)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := filepath.Join(c.dir, strings.TrimPrefix(diffID, "sha256:")+".tar")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	hash, err := v1.NewHash(diffID)
+	if err != nil {
+		return "", err
+	}
+	layer, err := c.image.LayerByDiffID(hash)
+	if err != nil {
+		return "", errors.Wrapf(err, "getting layer with SHA '%s'", diffID)
+	}
+	c.logger.Debugf("Fetching layer with SHA '%s' from launch cache image '%s'", diffID, c.imageRef)
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return "", errors.Wrapf(err, "fetching layer with SHA '%s'", diffID)
+	}
+	defer rc.Close()
+	if err = os.MkdirAll(c.dir, 0755); err != nil {
+		return "", err
+	}
+	// the layer is written to a temporary file, so that a failed fetch does not leave a partial layer
+	tmp, err := os.CreateTemp(c.dir, "layer-*.tar")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, rc); err != nil {
+		tmp.Close()
+		return "", errors.Wrapf(err, "fetching layer with SHA '%s'", diffID)
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp.Name(), path)
+}
+
+func (c *RegistryLaunchCache) RetrieveLayer(diffID string) (io.ReadCloser, error) {
+	path, err := c.RetrieveLayerFile(diffID)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// AddLayerFile does nothing, as the cache is read-only.
+func (c *RegistryLaunchCache) AddLayerFile(_ string, _ string) error {
+	return nil
+}
+
+// AddLayer closes the layer, as the cache is read-only.
+func (c *RegistryLaunchCache) AddLayer(rc io.ReadCloser, _ string) error {
+	return rc.Close()
+}
+
+// ReuseLayer does nothing, as the cache is read-only.
+func (c *RegistryLaunchCache) ReuseLayer(_ string) error {
+	return nil
+}
+
+// Commit does nothing, as the cache is read-only.
+func (c *RegistryLaunchCache) Commit() error {
+	return nil
+}
+
+// ReadOnly returns true, so that layers reused from the image in the daemon are not retrieved to populate the cache.
+func (c *RegistryLaunchCache) ReadOnly() bool {
+	return true
+}
@@ -0,0 +1,112 @@
+package cache_test
+
+import (
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/cache"
+	"github.com/buildpacks/lifecycle/cmd"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestRegistryLaunchCache(t *testing.T) {
+	spec.Run(t, "RegistryLaunchCache", testRegistryLaunchCache, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testRegistryLaunchCache(t *testing.T, when spec.G, it spec.S) {
+	var (
+		server   *httptest.Server
+		imageRef string
+		image    v1.Image
+		tmpDir   string
+		subject  *cache.RegistryLaunchCache
+	)
+
+	it.Before(func() {
+		var err error
+		server = httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+		imageRef = strings.TrimPrefix(server.URL, "http://") + "/some-repo/previous-image:latest"
+		image, err = random.Image(1024, 2)
+		h.AssertNil(t, err)
+		ref, err := name.ParseReference(imageRef)
+		h.AssertNil(t, err)
+		h.AssertNil(t, ggcrremote.Write(ref, image))
+
+		tmpDir = t.TempDir()
+		subject = cache.NewRegistryLaunchCache(imageRef, authn.DefaultKeychain, filepath.Join(tmpDir, "launch-cache"), cmd.DefaultLogger)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	layerDiffID := func(i int) string {
+		layers, err := image.Layers()
+		h.AssertNil(t, err)
+		diffID, err := layers[i].DiffID()
+		h.AssertNil(t, err)
+		return diffID.String()
+	}
+
+	when("#HasLayer", func() {
+		it("returns true for the layers of the image", func() {
+			found, err := subject.HasLayer(layerDiffID(1))
+			h.AssertNil(t, err)
+			h.AssertEq(t, found, true)
+
+			found, err = subject.HasLayer("sha256:" + strings.Repeat("0", 64))
+			h.AssertNil(t, err)
+			h.AssertEq(t, found, false)
+		})
+
+		it("returns false if the image cannot be read", func() {
+			subject = cache.NewRegistryLaunchCache(strings.TrimPrefix(server.URL, "http://")+"/some-repo/missing-image", authn.DefaultKeychain, tmpDir, cmd.DefaultLogger)
+
+			found, err := subject.HasLayer(layerDiffID(1))
+			h.AssertNil(t, err)
+			h.AssertEq(t, found, false)
+		})
+	})
+
+	when("#RetrieveLayerFile", func() {
+		it("fetches the uncompressed layer once", func() {
+			layers, err := image.Layers()
+			h.AssertNil(t, err)
+			rc, err := layers[0].Uncompressed()
+			h.AssertNil(t, err)
+			expected, err := io.ReadAll(rc)
+			h.AssertNil(t, err)
+			h.AssertNil(t, rc.Close())
+
+			path, err := subject.RetrieveLayerFile(layerDiffID(0))
+			h.AssertNil(t, err)
+			contents, err := os.ReadFile(path)
+			h.AssertNil(t, err)
+			h.AssertEq(t, contents, expected)
+
+			server.Close()
+			again, err := subject.RetrieveLayerFile(layerDiffID(0))
+			h.AssertNil(t, err)
+			h.AssertEq(t, again, path)
+		})
+
+		it("errors if the image does not have the layer", func() {
+			_, err := subject.RetrieveLayerFile("sha256:" + strings.Repeat("0", 64))
+			h.AssertError(t, err, "not found")
+		})
+	})
+}
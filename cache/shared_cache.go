@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"io"
+
+	"github.com/buildpacks/lifecycle/platform"
+)
+
+// Store is a build cache that can be shared by several builds (see SharedCache).
+// It is implemented by VolumeCache and ImageCache.
+type Store interface {
+	Exists() bool
+	Name() string
+	SetMetadata(metadata platform.CacheMetadata) error
+	RetrieveMetadata() (platform.CacheMetadata, error)
+	AddLayerFile(tarPath string, diffID string) error
+	ReuseLayer(diffID string) error
+	RetrieveLayer(diffID string) (io.ReadCloser, error)
+	Commit() error
+}
+
+// layerMounter and layerStreamer are the optional methods of a store that the exporter uses when adding layers (see ImageCache.AddLayerFileFrom and VolumeCache.AddLayer).
+type layerMounter interface {
+	AddLayerFileFrom(tarPath, diffID, imageName string) error
+}
+
+type layerStreamer interface {
+	AddLayer(rc io.ReadCloser, diffID string) error
+}
+
+// SharedCache is a store that is shared by the apps that are built together (e.g., from apps.toml by the creator).
+// Each app restores from and exports to its own view of the store (see ForApp), which keeps the metadata of the app under its name;
+// a layer that is cached by several apps is added to the store once.
+// The store is committed once, with the metadata of every app, when the shared cache is committed.
+type SharedCache struct {
+	store Store
+
+	prev  *platform.CacheMetadata // the committed metadata of the store, once retrieved
+	next  platform.CacheMetadata
+	added map[string]bool // the diffIDs of the layers added to (or reused in) the store
+}
+
+// NewSharedCache returns a cache that shares the store among apps.
+func NewSharedCache(store Store) *SharedCache {
+	return &SharedCache{
+		store: store,
+		next:  platform.CacheMetadata{Apps: map[string]platform.CacheMetadata{}},
+		added: map[string]bool{},
+	}
+}
+
+// ForApp returns the view of the cache for the named app. Committing the view does nothing;
+// the metadata that is set on the view is committed with the shared cache.
+// The view supports the optional methods of the store (e.g., mounting layers into a cache image).
+func (c *SharedCache) ForApp(name string) Store {
+	view := &appCache{shared: c, name: name}
+	if mounter, ok := c.store.(layerMounter); ok {
+		return &mountingAppCache{appCache: view, mounter: mounter}
+	}
+	if streamer, ok := c.store.(layerStreamer); ok {
+		return &streamingAppCache{appCache: view, streamer: streamer}
+	}
+	return view
+}
+
+// Commit commits the store with the metadata of the apps that exported their cache.
+// If no app exported its cache, the store is left as it is.
+func (c *SharedCache) Commit() error {
+	if len(c.next.Apps) == 0 {
+		return nil
+	}
+	if err := c.store.SetMetadata(c.next); err != nil {
+		return err
+	}
+	return c.store.Commit()
+}
+
+func (c *SharedCache) retrieveMetadata(name string) (platform.CacheMetadata, error) {
+	if c.prev == nil {
+		prev, err := c.store.RetrieveMetadata()
+		if err != nil {
+			return platform.CacheMetadata{}, err
+		}
+		c.prev = &prev
+	}
+	return c.prev.Apps[name], nil
+}
+
+// addOnce adds the layer to the store with add, unless it was already added for another app.
+func (c *SharedCache) addOnce(diffID string, add func() error) error {
+	if c.added[diffID] {
+		return nil
+	}
+	if err := add(); err != nil {
+		return err
+	}
+	c.added[diffID] = true
+	return nil
+}
+
+type appCache struct {
+	shared *SharedCache
+	name   string
+}
+
+func (c *appCache) Exists() bool {
+	return c.shared.store.Exists()
+}
+
+func (c *appCache) Name() string {
+	return c.shared.store.Name()
+}
+
+func (c *appCache) SetMetadata(metadata platform.CacheMetadata) error {
+	c.shared.next.Apps[c.name] = metadata
+	return nil
+}
+
+func (c *appCache) RetrieveMetadata() (platform.CacheMetadata, error) {
+	return c.shared.retrieveMetadata(c.name)
+}
+
+func (c *appCache) AddLayerFile(tarPath string, diffID string) error {
+	return c.shared.addOnce(diffID, func() error {
+		return c.shared.store.AddLayerFile(tarPath, diffID)
+	})
+}
+
+func (c *appCache) ReuseLayer(diffID string) error {
+	return c.shared.addOnce(diffID, func() error {
+		return c.shared.store.ReuseLayer(diffID)
+	})
+}
+
+func (c *appCache) RetrieveLayer(diffID string) (io.ReadCloser, error) {
+	return c.shared.store.RetrieveLayer(diffID)
+}
+
+func (c *appCache) Commit() error {
+	return nil
+}
+
+type mountingAppCache struct {
+	*appCache
+	mounter layerMounter
+}
+
+func (c *mountingAppCache) AddLayerFileFrom(tarPath, diffID, imageName string) error {
+	return c.shared.addOnce(diffID, func() error {
+		return c.mounter.AddLayerFileFrom(tarPath, diffID, imageName)
+	})
+}
+
+type streamingAppCache struct {
+	*appCache
+	streamer layerStreamer
+}
+
+func (c *streamingAppCache) AddLayer(rc io.ReadCloser, diffID string) error {
+	return c.shared.addOnce(diffID, func() error {
+		return c.streamer.AddLayer(rc, diffID)
+	})
+}
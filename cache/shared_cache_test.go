@@ -0,0 +1,165 @@
+package cache_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/cache"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestSharedCache(t *testing.T) {
+	spec.Run(t, "SharedCache", testSharedCache, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testSharedCache(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir    string
+		volumeDir string
+		store     *cache.VolumeCache
+		subject   *cache.SharedCache
+		layerTar  string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle.cache.shared_cache")
+		h.AssertNil(t, err)
+		volumeDir = filepath.Join(tmpDir, "volume")
+		h.AssertNil(t, os.MkdirAll(volumeDir, os.ModePerm))
+		store, err = cache.NewVolumeCache(volumeDir)
+		h.AssertNil(t, err)
+		subject = cache.NewSharedCache(store)
+
+		layerTar = filepath.Join(tmpDir, "some-layer.tar")
+		h.AssertNil(t, os.WriteFile(layerTar, []byte("dummy data"), 0600))
+	})
+
+	it.After(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	reopen := func() *cache.SharedCache {
+		reopened, err := cache.NewVolumeCache(volumeDir)
+		h.AssertNil(t, err)
+		return cache.NewSharedCache(reopened)
+	}
+
+	when("#Commit", func() {
+		it("commits the metadata of each app under its name", func() {
+			h.AssertNil(t, subject.ForApp("app-a").SetMetadata(platform.CacheMetadata{BOM: files.LayerMetadata{SHA: "some-sha-a"}}))
+			h.AssertNil(t, subject.ForApp("app-b").SetMetadata(platform.CacheMetadata{BOM: files.LayerMetadata{SHA: "some-sha-b"}}))
+			h.AssertNil(t, subject.Commit())
+
+			reopened := reopen()
+			meta, err := reopened.ForApp("app-a").RetrieveMetadata()
+			h.AssertNil(t, err)
+			h.AssertEq(t, meta.BOM.SHA, "some-sha-a")
+			meta, err = reopened.ForApp("app-b").RetrieveMetadata()
+			h.AssertNil(t, err)
+			h.AssertEq(t, meta.BOM.SHA, "some-sha-b")
+			meta, err = reopened.ForApp("other-app").RetrieveMetadata()
+			h.AssertNil(t, err)
+			h.AssertEq(t, meta.BOM.SHA, "")
+		})
+
+		it("commits the layers of all apps", func() {
+			appA, appB := subject.ForApp("app-a"), subject.ForApp("app-b")
+			h.AssertNil(t, appA.AddLayerFile(layerTar, "some_sha"))
+			h.AssertNil(t, appA.SetMetadata(platform.CacheMetadata{}))
+			streamer, ok := appB.(interface {
+				AddLayer(rc io.ReadCloser, diffID string) error
+			})
+			h.AssertEq(t, ok, true)
+			h.AssertNil(t, streamer.AddLayer(io.NopCloser(strings.NewReader("other data")), "other_sha"))
+			h.AssertNil(t, appB.SetMetadata(platform.CacheMetadata{}))
+			h.AssertNil(t, subject.Commit())
+
+			reopened := reopen()
+			for _, diffID := range []string{"some_sha", "other_sha"} {
+				rc, err := reopened.ForApp("app-a").RetrieveLayer(diffID)
+				h.AssertNil(t, err)
+				h.AssertNil(t, rc.Close())
+			}
+		})
+
+		it("leaves the store as it is when no app exported its cache", func() {
+			h.AssertNil(t, subject.ForApp("app-a").AddLayerFile(layerTar, "some_sha"))
+			h.AssertNil(t, subject.ForApp("app-a").SetMetadata(platform.CacheMetadata{BOM: files.LayerMetadata{SHA: "some_sha"}}))
+			h.AssertNil(t, subject.Commit())
+
+			reopened := reopen()
+			_, err := reopened.ForApp("app-a").RetrieveMetadata()
+			h.AssertNil(t, err)
+			h.AssertNil(t, reopened.Commit())
+
+			reopened = reopen()
+			meta, err := reopened.ForApp("app-a").RetrieveMetadata()
+			h.AssertNil(t, err)
+			h.AssertEq(t, meta.BOM.SHA, "some_sha")
+			rc, err := reopened.ForApp("app-a").RetrieveLayer("some_sha")
+			h.AssertNil(t, err)
+			h.AssertNil(t, rc.Close())
+		})
+	})
+
+	when("#ForApp", func() {
+		it("does not commit the store when the view is committed", func() {
+			view := subject.ForApp("app-a")
+			h.AssertNil(t, view.AddLayerFile(layerTar, "some_sha"))
+			h.AssertNil(t, view.SetMetadata(platform.CacheMetadata{}))
+			h.AssertNil(t, view.Commit())
+
+			_, err := os.Stat(filepath.Join(volumeDir, "committed", "some_sha.tar"))
+			h.AssertNotNil(t, err)
+		})
+
+		it("adds a layer that is cached by several apps once", func() {
+			counting := &countingStore{Store: store}
+			shared := cache.NewSharedCache(counting)
+			h.AssertNil(t, shared.ForApp("app-a").AddLayerFile(layerTar, "some_sha"))
+			h.AssertNil(t, shared.ForApp("app-b").AddLayerFile(layerTar, "some_sha"))
+			h.AssertNil(t, shared.ForApp("app-b").ReuseLayer("some_sha"))
+			h.AssertEq(t, counting.adds, 1)
+		})
+
+		it("adds a layer again if adding it failed", func() {
+			counting := &countingStore{Store: store}
+			shared := cache.NewSharedCache(counting)
+			h.AssertNotNil(t, shared.ForApp("app-a").AddLayerFile(filepath.Join(tmpDir, "does-not-exist.tar"), "some_sha"))
+			h.AssertNil(t, shared.ForApp("app-b").AddLayerFile(layerTar, "some_sha"))
+			h.AssertEq(t, counting.adds, 2)
+		})
+
+		it("streams layers if the store does", func() {
+			_, ok := subject.ForApp("app-a").(interface {
+				AddLayer(rc io.ReadCloser, diffID string) error
+			})
+			h.AssertEq(t, ok, true)
+
+			_, ok = cache.NewSharedCache(&countingStore{Store: store}).ForApp("app-a").(interface {
+				AddLayer(rc io.ReadCloser, diffID string) error
+			})
+			h.AssertEq(t, ok, false)
+		})
+	})
+}
+
+// countingStore counts the layers added to the store, and hides its optional methods.
+type countingStore struct {
+	cache.Store
+	adds int
+}
+
+func (s *countingStore) AddLayerFile(tarPath string, diffID string) error {
+	s.adds++
+	return s.Store.AddLayerFile(tarPath, diffID)
+}
@@ -7,22 +7,44 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/lifecycle/internal/fsutil"
 	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/telemetry/metrics"
 )
 
+// DefaultLockTimeout is how long to wait for another build to release the lock of a volume cache.
+const DefaultLockTimeout = 10 * time.Minute
+
+// VolumeCache is a cache in a directory, e.g., a volume that may be shared by concurrent builds.
+// Access to the cache is serialized with an advisory lock (see fsutil.Lock): the lock is held while
+// reading from the cache, and from the first write to the cache until it is committed, so that concurrent builds
+// never write to the staging directory at the same time, and never read from the cache while it is committed.
 type VolumeCache struct {
 	committed    bool
 	dir          string
 	backupDir    string
 	stagingDir   string
 	committedDir string
+	lockPath     string
+	lockTimeout  time.Duration
+	writeLock    *fsutil.Lock // held from the first write until the cache is committed
+}
+
+// VolumeCacheOption configures a VolumeCache.
+type VolumeCacheOption func(c *VolumeCache)
+
+// WithLockTimeout configures how long to wait for another build to release the lock of the cache.
+func WithLockTimeout(timeout time.Duration) VolumeCacheOption {
+	return func(c *VolumeCache) {
+		c.lockTimeout = timeout
+	}
 }
 
-func NewVolumeCache(dir string) (*VolumeCache, error) {
+func NewVolumeCache(dir string, opts ...VolumeCacheOption) (*VolumeCache, error) {
 	if _, err := os.Stat(dir); err != nil {
 		return nil, err
 	}
@@ -32,20 +54,30 @@ func NewVolumeCache(dir string) (*VolumeCache, error) {
 		backupDir:    filepath.Join(dir, "committed-backup"),
 		stagingDir:   filepath.Join(dir, "staging"),
 		committedDir: filepath.Join(dir, "committed"),
+		lockPath:     filepath.Join(dir, ".lock"),
+		lockTimeout:  DefaultLockTimeout,
 	}
-
-	if err := c.setupStagingDir(); err != nil {
-		return nil, errors.Wrapf(err, "initializing staging directory '%s'", c.stagingDir)
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	if err := os.RemoveAll(c.backupDir); err != nil {
-		return nil, errors.Wrapf(err, "removing backup directory '%s'", c.backupDir)
-	}
+	err := c.withLock(func() error {
+		if err := c.setupStagingDir(); err != nil {
+			return errors.Wrapf(err, "initializing staging directory '%s'", c.stagingDir)
+		}
 
-	if err := os.MkdirAll(c.committedDir, 0777); err != nil {
-		return nil, errors.Wrapf(err, "creating committed directory '%s'", c.committedDir)
-	}
+		if err := os.RemoveAll(c.backupDir); err != nil {
+			return errors.Wrapf(err, "removing backup directory '%s'", c.backupDir)
+		}
 
+		if err := os.MkdirAll(c.committedDir, 0777); err != nil {
+			return errors.Wrapf(err, "creating committed directory '%s'", c.committedDir)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
@@ -64,6 +96,9 @@ func (c *VolumeCache) SetMetadata(metadata platform.CacheMetadata) error {
 	if c.committed {
 		return errCacheCommitted
 	}
+	if err := c.beginWrite(); err != nil {
+		return err
+	}
 	metadataPath := filepath.Join(c.stagingDir, MetadataLabel)
 	file, err := os.Create(metadataPath)
 	if err != nil {
@@ -78,7 +113,15 @@ func (c *VolumeCache) SetMetadata(metadata platform.CacheMetadata) error {
 	return nil
 }
 
-func (c *VolumeCache) RetrieveMetadata() (platform.CacheMetadata, error) {
+func (c *VolumeCache) RetrieveMetadata() (metadata platform.CacheMetadata, err error) {
+	err = c.withLock(func() error {
+		metadata, err = c.retrieveMetadata()
+		return err
+	})
+	return metadata, err
+}
+
+func (c *VolumeCache) retrieveMetadata() (platform.CacheMetadata, error) {
 	metadataPath := filepath.Join(c.committedDir, MetadataLabel)
 	file, err := os.Open(metadataPath)
 	if err != nil {
@@ -100,13 +143,17 @@ func (c *VolumeCache) AddLayerFile(tarPath string, diffID string) error {
 	if c.committed {
 		return errCacheCommitted
 	}
+	if err := c.beginWrite(); err != nil {
+		return err
+	}
 	layerTar := diffIDPath(c.stagingDir, diffID)
 	if _, err := os.Stat(layerTar); err == nil {
 		// don't waste time rewriting an identical layer
 		return nil
 	}
 
-	if err := fsutil.Copy(tarPath, layerTar); err != nil {
+	// layer tarballs are never modified, so they can be linked rather than copied
+	if err := fsutil.LinkOrCopy(tarPath, layerTar); err != nil {
 		return errors.Wrapf(err, "caching layer (%s)", diffID)
 	}
 	return nil
@@ -116,42 +163,67 @@ func (c *VolumeCache) AddLayer(rc io.ReadCloser, diffID string) error {
 	if c.committed {
 		return errCacheCommitted
 	}
+	if err := c.beginWrite(); err != nil {
+		return err
+	}
 
-	fh, err := os.Create(diffIDPath(c.stagingDir, diffID))
+	// write to a temporary file, so that a layer that fails to copy is never found in the cache
+	fh, err := os.CreateTemp(c.stagingDir, ".layer-*.tar")
 	if err != nil {
 		return errors.Wrapf(err, "create layer file in cache")
 	}
-	defer fh.Close()
+	defer os.Remove(fh.Name())
 
 	if _, err := io.Copy(fh, rc); err != nil {
+		_ = fh.Close()
 		return errors.Wrap(err, "copying layer to tar file")
 	}
-	return nil
+	if err := fh.Close(); err != nil {
+		return errors.Wrap(err, "copying layer to tar file")
+	}
+	if err := os.Chmod(fh.Name(), 0644); err != nil { // #nosec G302
+		return errors.Wrap(err, "copying layer to tar file")
+	}
+	return os.Rename(fh.Name(), diffIDPath(c.stagingDir, diffID))
 }
 
 func (c *VolumeCache) ReuseLayer(diffID string) error {
 	if c.committed {
 		return errCacheCommitted
 	}
+	if err := c.beginWrite(); err != nil {
+		return err
+	}
 	if err := os.Link(diffIDPath(c.committedDir, diffID), diffIDPath(c.stagingDir, diffID)); err != nil && !os.IsExist(err) {
 		return errors.Wrapf(err, "reusing layer (%s)", diffID)
 	}
 	return nil
 }
 
-func (c *VolumeCache) RetrieveLayer(diffID string) (io.ReadCloser, error) {
-	path, err := c.RetrieveLayerFile(diffID)
-	if err != nil {
-		return nil, err
-	}
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, errors.Wrapf(err, "opening layer with SHA '%s'", diffID)
-	}
-	return file, nil
+func (c *VolumeCache) RetrieveLayer(diffID string) (rc io.ReadCloser, err error) {
+	err = c.withLock(func() error {
+		path, err := c.retrieveLayerFile(diffID)
+		if err != nil {
+			return err
+		}
+		// once opened, the layer can be read even if the cache is committed by another build
+		if rc, err = os.Open(path); err != nil {
+			return errors.Wrapf(err, "opening layer with SHA '%s'", diffID)
+		}
+		return nil
+	})
+	return rc, err
 }
 
-func (c *VolumeCache) HasLayer(diffID string) (bool, error) {
+func (c *VolumeCache) HasLayer(diffID string) (found bool, err error) {
+	err = c.withLock(func() error {
+		found, err = c.hasLayer(diffID)
+		return err
+	})
+	return found, err
+}
+
+func (c *VolumeCache) hasLayer(diffID string) (bool, error) {
 	if _, err := os.Stat(diffIDPath(c.committedDir, diffID)); err != nil {
 		if os.IsNotExist(err) {
 			return false, nil
@@ -161,7 +233,15 @@ func (c *VolumeCache) HasLayer(diffID string) (bool, error) {
 	return true, nil
 }
 
-func (c *VolumeCache) RetrieveLayerFile(diffID string) (string, error) {
+func (c *VolumeCache) RetrieveLayerFile(diffID string) (path string, err error) {
+	err = c.withLock(func() error {
+		path, err = c.retrieveLayerFile(diffID)
+		return err
+	})
+	return path, err
+}
+
+func (c *VolumeCache) retrieveLayerFile(diffID string) (string, error) {
 	path := diffIDPath(c.committedDir, diffID)
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
@@ -176,6 +256,10 @@ func (c *VolumeCache) Commit() error {
 	if c.committed {
 		return errCacheCommitted
 	}
+	if err := c.beginWrite(); err != nil {
+		return err
+	}
+	defer c.endWrite()
 	c.committed = true
 	if err := fsutil.RenameWithWindowsFallback(c.committedDir, c.backupDir); err != nil {
 		return errors.Wrap(err, "backing up cache")
@@ -192,10 +276,12 @@ func (c *VolumeCache) Commit() error {
 	return nil
 }
 
+// diffIDPath returns the path of the tarball of the layer with the provided diffID, which may be a digest of any algorithm
+// (see layers.DigestAlgorithm); the tarballs of layers with sha256 digests are named after the hex digest on Windows.
 func diffIDPath(basePath, diffID string) string {
 	if runtime.GOOS == "windows" {
 		// Avoid colons in Windows file paths
-		diffID = strings.TrimPrefix(diffID, "sha256:")
+		diffID = strings.Replace(strings.TrimPrefix(diffID, "sha256:"), ":", "-", 1)
 	}
 	return filepath.Join(basePath, diffID+".tar")
 }
@@ -206,3 +292,61 @@ func (c *VolumeCache) setupStagingDir() error {
 	}
 	return os.MkdirAll(c.stagingDir, 0777)
 }
+
+// withLock runs fn while holding the lock of the cache.
+func (c *VolumeCache) withLock(fn func() error) error {
+	if c.writeLock != nil {
+		return fn()
+	}
+	lock, err := c.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	return fn()
+}
+
+// beginWrite acquires the lock of the cache, unless it is already held, until the cache is committed.
+func (c *VolumeCache) beginWrite() error {
+	if c.writeLock != nil {
+		return nil
+	}
+	lock, err := c.lock()
+	if err != nil {
+		return err
+	}
+	c.writeLock = lock
+	// another build may have committed (or left behind) the staging directory since the cache was initialized
+	if err = c.setupStagingDir(); err != nil {
+		c.endWrite()
+		return errors.Wrapf(err, "initializing staging directory '%s'", c.stagingDir)
+	}
+	return nil
+}
+
+func (c *VolumeCache) endWrite() {
+	if c.writeLock == nil {
+		return
+	}
+	_ = c.writeLock.Release()
+	c.writeLock = nil
+}
+
+func (c *VolumeCache) lock() (*fsutil.Lock, error) {
+	lock, stats, err := fsutil.AcquireLock(c.lockPath, fsutil.LockOptions{Timeout: c.lockTimeout})
+	if stats.Contended {
+		outcome := metrics.LockAcquired
+		switch {
+		case err != nil:
+			outcome = metrics.LockTimedOut
+		case stats.Recovered:
+			outcome = metrics.LockRecovered
+		}
+		metrics.Add(metrics.CacheLockContentions, 1, metrics.L("outcome", outcome))
+		metrics.Add(metrics.CacheLockWait, stats.Waited.Seconds())
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "locking cache '%s'", c.dir)
+	}
+	return lock, nil
+}
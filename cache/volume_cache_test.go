@@ -1,17 +1,20 @@
 package cache_test
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/cache"
+	"github.com/buildpacks/lifecycle/internal/fsutil"
 	"github.com/buildpacks/lifecycle/platform"
 	h "github.com/buildpacks/lifecycle/testhelpers"
 )
@@ -519,5 +522,52 @@ func testVolumeCache(t *testing.T, when spec.G, it spec.S) {
 				})
 			})
 		})
+
+		when("the cache is shared", func() {
+			var other *cache.VolumeCache
+
+			it.Before(func() {
+				var err error
+				other, err = cache.NewVolumeCache(volumeDir, cache.WithLockTimeout(200*time.Millisecond))
+				h.AssertNil(t, err)
+			})
+
+			it("holds the lock from the first write until the cache is committed", func() {
+				h.AssertNil(t, subject.SetMetadata(platform.CacheMetadata{}))
+				h.AssertPathExists(t, filepath.Join(volumeDir, ".lock"))
+
+				_, err := other.RetrieveMetadata()
+				h.AssertNotNil(t, err)
+				var timeoutErr *fsutil.LockTimeoutError
+				h.AssertEq(t, errors.As(err, &timeoutErr), true)
+				h.AssertStringContains(t, timeoutErr.Holder, fmt.Sprintf("pid %d", os.Getpid()))
+
+				h.AssertNil(t, subject.Commit())
+				h.AssertPathDoesNotExist(t, filepath.Join(volumeDir, ".lock"))
+
+				_, err = other.RetrieveMetadata()
+				h.AssertNil(t, err)
+			})
+
+			it("releases the lock after reading", func() {
+				_, err := subject.HasLayer("some_sha")
+				h.AssertNil(t, err)
+				h.AssertPathDoesNotExist(t, filepath.Join(volumeDir, ".lock"))
+
+				h.AssertNil(t, other.SetMetadata(platform.CacheMetadata{}))
+				h.AssertNil(t, other.Commit())
+			})
+
+			it("recovers a stale lock", func() {
+				lockPath := filepath.Join(volumeDir, ".lock")
+				h.Mkfile(t, "pid 1 on some-host", lockPath)
+				past := time.Now().Add(-time.Hour)
+				h.AssertNil(t, os.Chtimes(lockPath, past, past))
+
+				_, err := other.RetrieveMetadata()
+				h.AssertNil(t, err)
+				h.AssertPathDoesNotExist(t, lockPath)
+			})
+		})
 	})
 }
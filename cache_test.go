@@ -1,8 +1,11 @@
 package lifecycle_test
 
 import (
+	"crypto/sha256"
+	"crypto/sha512"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
@@ -124,6 +127,22 @@ func testCache(t *testing.T, when spec.G, it spec.S) {
 				})
 			})
 
+			when("a layer was uploaded with the app image", func() {
+				it("mounts the layer from the app image", func() {
+					volumeCache := testCache.(*cache.VolumeCache)
+					mountingCache := &mountingCache{VolumeCache: volumeCache, mounts: map[string]string{}}
+					exporter.UploadedBlobs = lifecycle.NewUploadedBlobs()
+					exporter.UploadedBlobs.Record(testLayerDigest("buildpack.id:cache-true-layer"), filepath.Join(tmpDir, "artifacts", "cache-true-layer.tar.gz"), "some-registry.io/some-app")
+
+					err := exporter.Cache(layersDir, mountingCache)
+					h.AssertNil(t, err)
+
+					h.AssertEq(t, mountingCache.mounts, map[string]string{
+						testLayerDigest("buildpack.id:cache-true-layer"): "some-registry.io/some-app",
+					})
+				})
+			})
+
 			when("structured SBOM", func() {
 				when("there is a 'cache=true' layer with a bom.<ext> file", func() {
 					it("adds the bom.<ext> file to the cache", func() {
@@ -256,6 +275,70 @@ func testCache(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 
+		when("the layer factory is streaming", func() {
+			it.Before(func() {
+				exporter.LayerFactory = &layers.Factory{
+					ArtifactsDir: filepath.Join(tmpDir, "artifacts"),
+					Logger:       exporter.Logger,
+					Streaming:    true,
+				}
+				layersDir = filepath.Join("testdata", "cacher", "layers")
+			})
+
+			it("streams layers into the cache without writing them to the artifacts directory", func() {
+				h.AssertNil(t, exporter.Cache(layersDir, testCache))
+
+				metadata, err := testCache.RetrieveMetadata()
+				h.AssertNil(t, err)
+				sha := metadata.Buildpacks[0].Layers["cache-true-layer"].SHA
+				h.AssertEq(t, strings.HasPrefix(sha, "sha256:"), true)
+				rc, err := testCache.RetrieveLayer(sha)
+				h.AssertNil(t, err)
+				contents, err := io.ReadAll(rc)
+				h.AssertNil(t, err)
+				h.AssertNil(t, rc.Close())
+				h.AssertEq(t, fmt.Sprintf("sha256:%x", sha256.Sum256(contents)), sha)
+
+				matches, err := filepath.Glob(filepath.Join(tmpDir, "artifacts", "*.tar"))
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(matches), 0)
+			})
+		})
+
+		when("the layers that are not exported are identified with another digest algorithm", func() {
+			it.Before(func() {
+				artifactsDir := filepath.Join(tmpDir, "artifacts")
+				exporter.LayerFactory = &layers.Factory{ArtifactsDir: artifactsDir, Logger: exporter.Logger, Streaming: true}
+				exporter.CacheLayerFactory = &layers.Factory{ArtifactsDir: artifactsDir, Logger: exporter.Logger, Streaming: true, DigestAlgorithm: layers.SHA512}
+				layersDir = filepath.Join("testdata", "cacher", "layers")
+			})
+
+			it("round trips the layers through the cache directory", func() {
+				h.AssertNil(t, exporter.Cache(layersDir, testCache))
+
+				metadata, err := testCache.RetrieveMetadata()
+				h.AssertNil(t, err)
+
+				t.Log("keeps the digests of exported layers")
+				launchSHA := metadata.Buildpacks[0].Layers["cache-true-layer"].SHA
+				h.AssertEq(t, strings.HasPrefix(launchSHA, "sha256:"), true)
+				assertCacheLayerDigest(t, testCache, launchSHA, sha256.New())
+
+				t.Log("identifies the layers that are only cached with the algorithm")
+				cacheSHA := metadata.Buildpacks[0].Layers["cache-true-no-sha-layer"].SHA
+				h.AssertEq(t, strings.HasPrefix(cacheSHA, "sha512:"), true)
+				assertCacheLayerDigest(t, testCache, cacheSHA, sha512.New())
+				h.AssertEq(t, strings.HasPrefix(metadata.BOM.SHA, "sha512:"), true)
+				assertCacheLayerDigest(t, testCache, metadata.BOM.SHA, sha512.New())
+
+				t.Log("reuses the layers in the next build")
+				testCache, err = cache.NewVolumeCache(cacheDir)
+				h.AssertNil(t, err)
+				h.AssertNil(t, exporter.Cache(layersDir, testCache))
+				assertLogEntry(t, logHandler, "Reusing cache layer 'buildpack.id:cache-true-no-sha-layer'")
+			})
+		})
+
 		when("there are invalid layers", func() {
 			it.Before(func() {
 				layerFactory.EXPECT().
@@ -356,6 +439,17 @@ func assertCacheHasLayer(t *testing.T, cache lifecycle.Cache, id string) {
 	h.AssertEq(t, string(contents), testLayerContents(id))
 }
 
+func assertCacheLayerDigest(t *testing.T, cache lifecycle.Cache, digest string, hasher hash.Hash) {
+	t.Helper()
+
+	rc, err := cache.RetrieveLayer(digest)
+	h.AssertNil(t, err)
+	defer rc.Close()
+	_, err = io.Copy(hasher, rc)
+	h.AssertNil(t, err)
+	h.AssertEq(t, digest[strings.Index(digest, ":")+1:], fmt.Sprintf("%x", hasher.Sum(nil)))
+}
+
 func initializeCache(t *testing.T, exporter *lifecycle.Exporter, testCache *lifecycle.Cache, cacheDir, layersDir, metadataTemplate string) {
 	previousCache, err := cache.NewVolumeCache(cacheDir)
 	h.AssertNil(t, err)
@@ -372,3 +466,14 @@ func initializeCache(t *testing.T, exporter *lifecycle.Exporter, testCache *life
 		0600,
 	))
 }
+
+// mountingCache records the layers that are mounted rather than added.
+type mountingCache struct {
+	*cache.VolumeCache
+	mounts map[string]string
+}
+
+func (c *mountingCache) AddLayerFileFrom(_, diffID, imageName string) error {
+	c.mounts[diffID] = imageName
+	return nil
+}
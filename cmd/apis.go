@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/internal/deprecation"
 	"github.com/buildpacks/lifecycle/log"
 )
 
@@ -39,6 +40,12 @@ func VerifyBuildpackAPI(kind, name, requested string, logger log.Logger) error {
 	}
 	if api.Buildpack.IsSupported(requestedAPI) {
 		if api.Buildpack.IsDeprecated(requestedAPI) {
+			deprecation.Record(deprecation.Notice{
+				Kind:    deprecation.KindBuildpackAPI,
+				Subject: name,
+				API:     requested,
+				Message: fmt.Sprintf("%s '%s' requests deprecated API '%s'", kind, name, requested),
+			})
 			switch DeprecationMode {
 			case ModeQuiet:
 				break
@@ -83,6 +90,11 @@ func VerifyPlatformAPI(requested string, logger log.Logger) error {
 	}
 	if api.Platform.IsSupported(requestedAPI) {
 		if api.Platform.IsDeprecated(requestedAPI) {
+			deprecation.Record(deprecation.Notice{
+				Kind:    deprecation.KindPlatformAPI,
+				API:     requested,
+				Message: fmt.Sprintf("Platform requested deprecated API '%s'", requested),
+			})
 			switch DeprecationMode {
 			case ModeQuiet:
 				break
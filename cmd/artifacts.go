@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+)
+
+// ArtifactsTracker provides the directory where the files generated by a phase are copied (or an empty string if they are not copied),
+// and the files that may be generated, by the names used for their copies.
+type ArtifactsTracker interface {
+	ArtifactFiles() (string, map[string]string)
+}
+
+var artifacts struct {
+	tracker ArtifactsTracker
+	phase   string
+	start   time.Time
+}
+
+// TrackArtifacts records the start of the provided phase, so that Exit can copy the files generated by the phase.
+func TrackArtifacts(tracker ArtifactsTracker, phase string) {
+	artifacts.tracker = tracker
+	artifacts.phase = phase
+	artifacts.start = time.Now()
+}
+
+func saveArtifacts() {
+	if artifacts.tracker == nil {
+		return
+	}
+	dir, files := artifacts.tracker.ArtifactFiles()
+	if dir == "" {
+		return
+	}
+	if err := CopyArtifacts(dir, artifacts.phase, files, artifacts.start); err != nil {
+		DefaultLogger.Warnf("Failed to copy artifacts: %s", err)
+	}
+}
+
+// CopyArtifacts copies the provided files that were modified since the provided time to dir,
+// prefixing their names with the name of the phase, e.g., "detector-group.toml".
+// Files that do not exist are ignored, as a phase only generates some of the files (and may fail before generating them).
+func CopyArtifacts(dir, phase string, files map[string]string, since time.Time) error {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// file systems may only record modification times in seconds
+	since = since.Truncate(time.Second)
+	for _, name := range names {
+		fi, err := os.Stat(files[name])
+		if err != nil || !fi.Mode().IsRegular() || fi.ModTime().Before(since) {
+			continue
+		}
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err = fsutil.Copy(files[name], filepath.Join(dir, phase+"-"+name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/cmd"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestArtifacts(t *testing.T) {
+	spec.Run(t, "Artifacts", testArtifacts, spec.Report(report.Terminal{}))
+}
+
+func testArtifacts(t *testing.T, when spec.G, it spec.S) {
+	var tmpDir string
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle.test")
+		h.AssertNil(t, err)
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.RemoveAll(tmpDir))
+	})
+
+	when("#CopyArtifacts", func() {
+		it("copies the files modified since the phase started, prefixed with the phase", func() {
+			start := time.Now()
+			h.Mkfile(t, "some-group", filepath.Join(tmpDir, "group.toml"))
+			h.Mkfile(t, "some-analyzed", filepath.Join(tmpDir, "analyzed.toml"))
+			old := start.Add(-time.Hour)
+			h.AssertNil(t, os.Chtimes(filepath.Join(tmpDir, "analyzed.toml"), old, old))
+			artifactsDir := filepath.Join(tmpDir, "artifacts")
+
+			err := cmd.CopyArtifacts(artifactsDir, "detector", map[string]string{
+				"group.toml":    filepath.Join(tmpDir, "group.toml"),
+				"analyzed.toml": filepath.Join(tmpDir, "analyzed.toml"),
+				"plan.toml":     filepath.Join(tmpDir, "missing.toml"),
+			}, start)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, h.MustReadFile(t, filepath.Join(artifactsDir, "detector-group.toml")), []byte("some-group"))
+			h.AssertPathDoesNotExist(t, filepath.Join(artifactsDir, "detector-analyzed.toml"))
+			h.AssertPathDoesNotExist(t, filepath.Join(artifactsDir, "detector-plan.toml"))
+		})
+	})
+}
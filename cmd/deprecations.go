@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/buildpacks/lifecycle/internal/deprecation"
+)
+
+// DeprecationTracker provides the location of the file used to share deprecation notices between phases.
+type DeprecationTracker interface {
+	DeprecationsFile() string
+}
+
+var deprecationsFile string
+
+// TrackDeprecations loads the deprecation notices recorded by previous phases,
+// so that Exit can save them together with any notices recorded by the current phase.
+func TrackDeprecations(tracker DeprecationTracker) {
+	deprecationsFile = tracker.DeprecationsFile()
+	if deprecationsFile == "" {
+		return
+	}
+	if err := deprecation.Load(deprecationsFile); err != nil {
+		DefaultLogger.Debugf("Failed to read deprecations file: %s", err)
+	}
+}
+
+func saveDeprecations() {
+	if deprecationsFile == "" {
+		return
+	}
+	if err := deprecation.Save(deprecationsFile); err != nil {
+		DefaultLogger.Debugf("Failed to write deprecations file: %s", err)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"strings"
 )
 
+// Exit codes that are common to all phases; phase-specific exit codes are defined by the platform package.
+// When a phase fails, the exit code and error class (see ErrorClassFor) are also written to the failure file, if configured.
 const (
 	CodeForFailed = 1
 	// 2: reserved
@@ -32,6 +34,10 @@ func (e *ErrorFail) Error() string {
 	return fmt.Sprintf("%s: %s", message, e.Err)
 }
 
+func (e *ErrorFail) Unwrap() error {
+	return e.Err
+}
+
 func FailCode(code int, action ...string) *ErrorFail {
 	return FailErrCode(nil, code, action...)
 }
@@ -49,14 +55,30 @@ func FailErrCode(err error, code int, action ...string) *ErrorFail {
 }
 
 func Exit(err error) {
+	stopProfiling()
+	saveDeprecations()
+	saveUsage()
+	saveRedactions()
+	saveArtifacts()
 	if err == nil {
+		reportTelemetry(nil, 0)
+		exportTrace(nil)
+		writeMetrics(nil)
+		finishProgress(nil, 0)
 		os.Exit(0)
 	}
 	DefaultLogger.Errorf("%s\n", err)
+	code := CodeForFailed
 	if err, ok := err.(*ErrorFail); ok {
-		os.Exit(err.Code)
+		code = err.Code
 	}
-	os.Exit(CodeForFailed)
+	writeFailureFile(err, code)
+	saveSupportBundle(err, code)
+	reportTelemetry(err, code)
+	exportTrace(err)
+	writeMetrics(err)
+	finishProgress(err, code)
+	os.Exit(code)
 }
 
 func ExitWithVersion() {
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+
+	"github.com/buildpacks/lifecycle/internal/encoding"
+)
+
+// Error classes categorize failures so that platforms can handle them programmatically,
+// e.g., by surfacing user errors to the application developer and retrying infrastructure failures.
+const (
+	// ErrorClassUser indicates a problem with the application source, e.g., no buildpacks detected.
+	ErrorClassUser = "user"
+	// ErrorClassBuildpack indicates that a buildpack or extension failed or is incompatible with the lifecycle.
+	ErrorClassBuildpack = "buildpack"
+	// ErrorClassPlatform indicates that the platform provided invalid or incompatible inputs.
+	ErrorClassPlatform = "platform"
+	// ErrorClassInfra indicates a failure, e.g., a registry or network error, that may succeed on retry.
+	ErrorClassInfra = "infra"
+	// ErrorClassLifecycle indicates any other failure.
+	ErrorClassLifecycle = "lifecycle"
+)
+
+var remediations = map[string]string{
+	ErrorClassUser:      "check that the application source is supported by the builder",
+	ErrorClassBuildpack: "check the buildpack output above, or contact the buildpack author",
+	ErrorClassPlatform:  "check the flags and environment variables provided to the lifecycle",
+	ErrorClassInfra:     "retry the build",
+	ErrorClassLifecycle: "re-run the build with CNB_LOG_LEVEL=debug and report the failure to the lifecycle maintainers",
+}
+
+// Failure is written to the failure file when a phase exits with an error.
+type Failure struct {
+	Code        int    `toml:"code"`
+	Class       string `toml:"class"`
+	Component   string `toml:"component"`
+	Message     string `toml:"message"`
+	Remediation string `toml:"remediation,omitempty"`
+}
+
+// FailureReporter provides the location of the failure file (if any),
+// and the error class for exit codes that are specific to a Platform API.
+type FailureReporter interface {
+	FailureFile() string
+	ErrorClassFor(code int) string
+}
+
+var failureReporter struct {
+	FailureReporter
+	component string
+}
+
+// SetFailureReporter configures Exit to write a failure file for the provided component (e.g., a phase name).
+func SetFailureReporter(reporter FailureReporter, component string) {
+	failureReporter.FailureReporter = reporter
+	failureReporter.component = component
+}
+
+// NewFailure returns the failure for the provided error, exit code, and component.
+func NewFailure(err error, code int, component string, classFor func(code int) string) Failure {
+	class := classify(err, code, classFor)
+	return Failure{
+		Code:        code,
+		Class:       class,
+		Component:   component,
+		Message:     err.Error(),
+		Remediation: remediations[class],
+	}
+}
+
+// ErrorClassFor returns the error class for the provided error and exit code,
+// for exit codes that are common to all phases.
+func ErrorClassFor(err error, code int) string {
+	var (
+		netErr       net.Error
+		transportErr *transport.Error
+	)
+	switch {
+	case errors.As(err, &transportErr) && transportErr.Temporary():
+		return ErrorClassInfra
+	case errors.As(err, &netErr):
+		return ErrorClassInfra
+	}
+	switch code {
+	case CodeForInvalidArgs, CodeForIncompatiblePlatformAPI:
+		return ErrorClassPlatform
+	case CodeForIncompatibleBuildpackAPI:
+		return ErrorClassBuildpack
+	}
+	return ErrorClassLifecycle
+}
+
+// classify returns the error class for the provided error and exit code,
+// using classFor (if provided) for exit codes that are specific to a Platform API.
+func classify(err error, code int, classFor func(code int) string) string {
+	class := ErrorClassFor(err, code)
+	if class == ErrorClassLifecycle && classFor != nil {
+		if c := classFor(code); c != "" {
+			class = c
+		}
+	}
+	return class
+}
+
+func writeFailureFile(err error, code int) {
+	if failureReporter.FailureReporter == nil {
+		return
+	}
+	path := failureReporter.FailureFile()
+	if path == "" {
+		return
+	}
+	failure := NewFailure(err, code, failureReporter.component, failureReporter.ErrorClassFor)
+	if err := encoding.WriteTOML(path, failure); err != nil {
+		DefaultLogger.Warnf("Failed to write failure file: %s", err)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/platform"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestFailure(t *testing.T) {
+	spec.Run(t, "Failure", testFailure, spec.Report(report.Terminal{}))
+}
+
+func testFailure(t *testing.T, when spec.G, it spec.S) {
+	when("#NewFailure", func() {
+		it("describes the failure", func() {
+			err := cmd.FailErrCode(errors.New("some-error"), cmd.CodeForInvalidArgs, "parse arguments")
+
+			failure := cmd.NewFailure(err, cmd.CodeForInvalidArgs, "detector", nil)
+
+			h.AssertEq(t, failure.Code, cmd.CodeForInvalidArgs)
+			h.AssertEq(t, failure.Class, cmd.ErrorClassPlatform)
+			h.AssertEq(t, failure.Component, "detector")
+			h.AssertEq(t, failure.Message, "failed to parse arguments: some-error")
+			h.AssertEq(t, failure.Remediation, "check the flags and environment variables provided to the lifecycle")
+		})
+
+		it("uses the phase-specific error class", func() {
+			exiter := &platform.DefaultExiter{}
+			code := exiter.CodeFor(platform.FailedBuildWithErrors)
+
+			failure := cmd.NewFailure(errors.New("some-error"), code, "builder", exiter.ErrorClassFor)
+
+			h.AssertEq(t, failure.Class, cmd.ErrorClassBuildpack)
+		})
+
+		it("defaults to the lifecycle error class", func() {
+			exiter := &platform.DefaultExiter{}
+			code := exiter.CodeFor(platform.ExportError)
+
+			failure := cmd.NewFailure(errors.New("some-error"), code, "exporter", exiter.ErrorClassFor)
+
+			h.AssertEq(t, failure.Class, cmd.ErrorClassLifecycle)
+		})
+	})
+
+	when("#ErrorClassFor", func() {
+		it("returns the infra error class for temporary registry errors", func() {
+			registryErr := &transport.Error{StatusCode: http.StatusServiceUnavailable}
+			err := cmd.FailErrCode(registryErr, 62, "export")
+
+			h.AssertEq(t, cmd.ErrorClassFor(err, 62), cmd.ErrorClassInfra)
+		})
+
+		it("returns the buildpack error class for incompatible buildpacks", func() {
+			h.AssertEq(t, cmd.ErrorClassFor(errors.New("some-error"), cmd.CodeForIncompatibleBuildpackAPI), cmd.ErrorClassBuildpack)
+		})
+	})
+}
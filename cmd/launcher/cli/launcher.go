@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -26,6 +29,7 @@ func RunLaunch() error {
 		cmd.Exit(err)
 	}
 	p := platform.NewPlatform(platformAPI)
+	launchEnv := env.NewLaunchEnv(os.Environ(), launch.ProcessDir, launch.LifecycleDir)
 
 	var md launch.Metadata
 	if _, err := toml.DecodeFile(launch.GetMetadataFilePath(cmd.EnvOrDefault(platform.EnvLayersDir, platform.DefaultLayersDir)), &md); err != nil {
@@ -43,20 +47,86 @@ func RunLaunch() error {
 		AppDir:             cmd.EnvOrDefault(platform.EnvAppDir, platform.DefaultAppDir),
 		PlatformAPI:        p.API(),
 		Processes:          md.Processes,
-		Buildpacks:         md.Buildpacks,
-		Env:                env.NewLaunchEnv(os.Environ(), launch.ProcessDir, launch.LifecycleDir),
+		Buildpacks:         md.BuildpacksInEnvOrder(),
+		Env:                launchEnv,
 		Exec:               launch.OSExecFunc,
 		ExecD:              launch.NewExecDRunner(),
 		Shell:              launch.DefaultShell,
 		Setenv:             os.Setenv,
 	}
 
+	if invokedAsLauncher() && len(os.Args) > 1 && os.Args[1] == launch.TaskFlag {
+		return launchTask(launcher, md, os.Args[2:], p)
+	}
+	if invokedAsLauncher() && len(os.Args) > 1 && os.Args[1] == launch.ExplainEnvFlag {
+		return explainEnv(launcher, launchEnv, md, os.Args[2:])
+	}
+	if err := launch.RunFirstBootTasks(md.Tasks, cmd.EnvOrDefault(platform.EnvTaskStateDir, platform.DefaultTaskStateDir), runTask); err != nil {
+		return cmd.FailErrCode(err, p.CodeFor(platform.LaunchError), "launch")
+	}
 	if err := launcher.Launch(os.Args[0], os.Args[1:]); err != nil {
 		return cmd.FailErrCode(err, p.CodeFor(platform.LaunchError), "launch")
 	}
 	return nil
 }
 
+// launchTask launches the task with the type provided as the first argument, appending any other arguments to its args.
+func launchTask(launcher *launch.Launcher, md launch.Metadata, args []string, p *platform.Platform) error {
+	if len(args) == 0 {
+		return cmd.FailErrCode(errors.New("a task type is required"), cmd.CodeForInvalidArgs, "parse arguments")
+	}
+	task, ok := md.FindTask(args[0])
+	if !ok {
+		return cmd.FailErrCode(fmt.Errorf("task type %s was not found", args[0]), p.CodeFor(platform.LaunchError), "launch")
+	}
+	task.Args = append(task.Args, args[1:]...)
+	if err := launcher.LaunchProcess(os.Args[0], task.Process); err != nil {
+		return cmd.FailErrCode(err, p.CodeFor(platform.LaunchError), "launch task")
+	}
+	return nil
+}
+
+// explainEnv prints the environment of the process type provided as the first argument (or of the default process type),
+// with the env files that contributed to each variable.
+func explainEnv(launcher *launch.Launcher, launchEnv *env.Env, md launch.Metadata, args []string) error {
+	procType := launcher.DefaultProcessType
+	if len(args) > 0 {
+		procType = args[0]
+	}
+	if procType != "" {
+		_, isProcess := md.FindProcessType(procType)
+		_, isTask := md.FindTask(procType)
+		if !isProcess && !isTask {
+			return cmd.FailErrCode(fmt.Errorf("process type %s was not found", procType), cmd.CodeForInvalidArgs, "parse arguments")
+		}
+	}
+	if err := launcher.ComposeEnv(procType); err != nil {
+		return cmd.FailErr(err, "compose environment")
+	}
+	fmt.Print(env.FormatAnnotated(launchEnv.Annotated()))
+	return nil
+}
+
+// runTask runs the task in a new launcher process and waits for it to exit, as launching it would replace the current process.
+func runTask(task launch.Task) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd.DefaultLogger.Debugf("Running task '%s'", task.Type)
+	c := exec.Command(self, launch.TaskFlag, task.Type) // #nosec G204
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
+
+// invokedAsLauncher returns true if the launcher was invoked directly, rather than through the symlink of a process type.
+func invokedAsLauncher() bool {
+	_, name := filepath.Split(os.Args[0])
+	return strings.TrimSuffix(name, platform.DefaultExecExt) == "launcher"
+}
+
 func boolEnv(k string) bool {
 	v := os.Getenv(k)
 	b, err := strconv.ParseBool(v)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/buildpacks/lifecycle/image"
@@ -37,9 +38,11 @@ func (a *analyzeCmd) DefineFlags() {
 	}
 	switch {
 	case a.PlatformAPI.AtLeast("0.12"):
+		cli.FlagAnonymousFallback(&a.AnonymousFallback)
 		cli.FlagLayoutDir(&a.LayoutDir)
 		cli.FlagUseLayout(&a.UseLayout)
 		cli.FlagRunPath(&a.RunPath)
+		cli.FlagTrustPolicyPath(&a.TrustPolicyPath)
 		fallthrough
 	case a.PlatformAPI.AtLeast("0.9"):
 		cli.FlagLaunchCacheDir(&a.LaunchCacheDir)
@@ -67,7 +70,7 @@ func (a *analyzeCmd) Args(nargs int, args []string) error {
 		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "parse arguments")
 	}
 	a.LifecycleInputs.OutputImageRef = args[0]
-	if err := platform.ResolveInputs(platform.Analyze, a.LifecycleInputs, cmd.DefaultLogger); err != nil {
+	if err := platform.VerifyInputs(platform.Analyze, a.LifecycleInputs, cmd.DefaultLogger); err != nil {
 		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
 	}
 	if a.UseLayout {
@@ -85,8 +88,11 @@ func (a *analyzeCmd) Privileges() error {
 	if err != nil {
 		return cmd.FailErr(err, "resolve keychain")
 	}
+	if a.AnonymousFallback {
+		a.keychain = auth.NewAnonymousFallbackKeychain(a.keychain)
+	}
 	if a.UseDaemon {
-		a.docker, err = priv.DockerClient()
+		a.docker, err = priv.DaemonClient()
 		if err != nil {
 			return cmd.FailErr(err, "initialize docker client")
 		}
@@ -102,10 +108,13 @@ func (a *analyzeCmd) Privileges() error {
 
 // Exec executes the command.
 func (a *analyzeCmd) Exec() error {
+	if _, err := verifyBaseImage(a.TrustPolicyPath, a.RunImageRef, a.keychain); err != nil {
+		return err
+	}
 	factory := lifecycle.NewAnalyzerFactory(
 		a.PlatformAPI,
 		&cmd.BuildpackAPIVerifier{},
-		NewCacheHandler(a.keychain),
+		NewCacheHandler(a.keychain, a.CacheLockTimeout),
 		lifecycle.NewConfigHandler(),
 		image.NewHandler(a.docker, a.keychain, a.LayoutDir, a.UseLayout),
 		NewRegistryHandler(a.keychain),
@@ -127,10 +136,16 @@ func (a *analyzeCmd) Exec() error {
 	if err != nil {
 		return unwrapErrorFailWithMessage(err, "initialize analyzer")
 	}
-	analyzedMD, err := analyzer.Analyze()
+	analyzedMD, err := analyzer.AnalyzeContext(context.Background())
 	if err != nil {
 		return cmd.FailErrCode(err, a.CodeFor(platform.AnalyzeError), "analyze")
 	}
+	if analyzedMD.RunImage != nil {
+		analyzedMD.RunImage.Selection = a.RunImageSelection
+	}
+	if fallbackKeychain, ok := a.keychain.(*auth.AnonymousFallbackKeychain); ok {
+		analyzedMD.AnonymousRegistries = fallbackKeychain.Registries()
+	}
 	cmd.DefaultLogger.Debugf("Run image info in analyzed metadata is: ")
 	cmd.DefaultLogger.Debugf(encoding.ToJSONMaybe(analyzedMD.RunImage))
 	if err = encoding.WriteTOML(a.AnalyzedPath, analyzedMD); err != nil {
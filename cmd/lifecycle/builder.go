@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"io"
+	"os"
 
 	"github.com/BurntSushi/toml"
 
@@ -25,6 +28,15 @@ func (b *buildCmd) DefineFlags() {
 	switch {
 	case b.PlatformAPI.AtLeast("0.12"):
 		cli.FlagAnalyzedPath(&b.AnalyzedPath)
+		cli.FlagAppSymlinks(&b.AppSymlinks)
+		cli.FlagBuildLogPath(&b.BuildLogPath)
+		cli.FlagConstraintsPath(&b.ConstraintsPath)
+		cli.FlagExplainEnv(&b.ExplainEnv)
+		cli.FlagGeneratedDir(&b.GeneratedDir)
+		cli.FlagNetworkBuildpacks(&b.NetworkBuildpacks)
+		cli.FlagProjectDescriptorPath(&b.ProjectDescriptorPath)
+		cli.FlagSBOMValidation(&b.SBOMValidation)
+		cli.FlagUmask(&b.Umask)
 		fallthrough
 	case b.PlatformAPI.AtLeast("0.11"):
 		cli.FlagBuildConfigDir(&b.BuildConfigDir)
@@ -44,7 +56,7 @@ func (b *buildCmd) Args(nargs int, _ []string) error {
 	if nargs != 0 {
 		return cmd.FailErrCode(errors.New("received unexpected arguments"), cmd.CodeForInvalidArgs, "parse arguments")
 	}
-	if err := platform.ResolveInputs(platform.Build, b.LifecycleInputs, cmd.DefaultLogger); err != nil {
+	if err := platform.VerifyInputs(platform.Build, b.LifecycleInputs, cmd.DefaultLogger); err != nil {
 		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
 	}
 	return nil
@@ -59,6 +71,7 @@ func (b *buildCmd) Privileges() error {
 }
 
 func (b *buildCmd) Exec() error {
+	applyUmask(b.LifecycleInputs)
 	group, plan, err := b.readData()
 	if err != nil {
 		return err
@@ -74,22 +87,21 @@ func (b *buildCmd) Exec() error {
 }
 
 func (b *buildCmd) build(group buildpack.Group, plan files.Plan, analyzedMD files.Analyzed) error {
-	builder := &lifecycle.Builder{
-		AppDir:         b.AppDir,
-		BuildConfigDir: b.BuildConfigDir,
-		LayersDir:      b.LayersDir,
-		PlatformDir:    b.PlatformDir,
-		BuildExecutor:  &buildpack.DefaultBuildExecutor{},
-		DirStore:       platform.NewDirStore(b.BuildpacksDir, ""),
-		Group:          group,
-		Logger:         cmd.DefaultLogger,
-		Out:            cmd.Stdout,
-		Err:            cmd.Stderr,
-		Plan:           plan,
-		PlatformAPI:    b.PlatformAPI,
-		AnalyzeMD:      analyzedMD,
+	var out, errOut io.Writer = cmd.Stdout, cmd.Stderr
+	if b.BuildLogPath != "" {
+		// the output of the buildpacks is also written to the build log, e.g., to add it to the debug image
+		buildLog, err := os.Create(b.BuildLogPath)
+		if err != nil {
+			return cmd.FailErr(err, "create build log")
+		}
+		defer buildLog.Close()
+		out, errOut = io.MultiWriter(out, buildLog), io.MultiWriter(errOut, buildLog)
+	}
+	builder, err := lifecycle.NewBuilderFromInputs(b.LifecycleInputs, group, plan, analyzedMD, cmd.DefaultLogger, out, errOut)
+	if err != nil {
+		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "initialize builder")
 	}
-	md, err := builder.Build()
+	md, err := builder.BuildContext(context.Background())
 	if err != nil {
 		return b.unwrapBuildFail(err)
 	}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/cmd/lifecycle/cli"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/priv"
+)
+
+// cleanLayersCmd removes content left in a reused layers directory by previous builds that is not used by the current group.
+// It is not a phase of the build; platforms that reuse workspace volumes run it after the detector and before the restorer.
+type cleanLayersCmd struct {
+	*platform.Platform
+}
+
+// DefineFlags defines the flags that are considered valid and reads their values (if provided).
+func (c *cleanLayersCmd) DefineFlags() {
+	cli.FlagBuildpacksDir(&c.BuildpacksDir)
+	cli.FlagCleanDryRun(&c.CleanDryRun)
+	cli.FlagExtensionsDir(&c.ExtensionsDir)
+	cli.FlagGID(&c.GID)
+	cli.FlagGroupPath(&c.GroupPath)
+	cli.FlagLayersDir(&c.LayersDir)
+	cli.FlagUID(&c.UID)
+}
+
+// Args validates arguments and flags, and fills in default values.
+func (c *cleanLayersCmd) Args(nargs int, _ []string) error {
+	if nargs > 0 {
+		return cmd.FailErrCode(errors.New("received unexpected Args"), cmd.CodeForInvalidArgs, "parse arguments")
+	}
+	if c.PlatformAPI.LessThan("0.12") {
+		return cmd.FailErrCode(fmt.Errorf("clean-layers requires Platform API 0.12 or above, but %s was requested", c.PlatformAPI), cmd.CodeForIncompatiblePlatformAPI, "parse arguments")
+	}
+	if err := platform.VerifyInputs(platform.Clean, c.LifecycleInputs, cmd.DefaultLogger); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
+	}
+	return nil
+}
+
+func (c *cleanLayersCmd) Privileges() error {
+	if err := priv.RunAs(c.UID, c.GID); err != nil {
+		return cmd.FailErr(err, fmt.Sprintf("exec as user %d:%d", c.UID, c.GID))
+	}
+	return nil
+}
+
+func (c *cleanLayersCmd) Exec() error {
+	group, err := lifecycle.ReadGroup(c.GroupPath)
+	if err != nil {
+		return cmd.FailErr(err, "read buildpack group")
+	}
+	cleaner := &lifecycle.LayersCleaner{
+		LayersDir:     c.LayersDir,
+		BuildpacksDir: c.BuildpacksDir,
+		ExtensionsDir: c.ExtensionsDir,
+		TmpDir:        c.TmpDir, // only the temporary directory of the lifecycle is cleaned, never the system temporary directory
+		Group:         group,
+		DryRun:        c.CleanDryRun,
+		Logger:        cmd.DefaultLogger,
+	}
+	report, err := cleaner.Clean()
+	if err != nil {
+		return cmd.FailErr(err, "clean layers directory")
+	}
+	if c.CleanDryRun {
+		cmd.DefaultLogger.Infof("Would remove %d stale entries (%d bytes)", len(report.Removed), report.Bytes())
+		return nil
+	}
+	cmd.DefaultLogger.Infof("Removed %d stale entries (%d bytes)", len(report.Removed), report.Bytes())
+	return nil
+}
@@ -1,12 +1,22 @@
 package cli
 
 import (
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
 
+	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/auth"
 	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/internal/chaos"
+	"github.com/buildpacks/lifecycle/internal/deprecation"
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	"github.com/buildpacks/lifecycle/internal/network"
+	"github.com/buildpacks/lifecycle/internal/str"
 	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/telemetry"
 )
 
 // Command defines the interface for running the lifecycle phases
@@ -24,17 +34,51 @@ type Command interface {
 	Exec() error
 }
 
+// InputsCommand is implemented by commands with lifecycle inputs (see platform.Platform),
+// which are set by the flags common to all commands (e.g., -tmp-dir).
+type InputsCommand interface {
+	Inputs() *platform.LifecycleInputs
+}
+
+// KanikoCommand is implemented by commands that may run kaniko,
+// which requires http.DefaultTransport to be an *http.Transport.
+type KanikoCommand interface {
+	UsesKaniko() bool
+}
+
 func Run(c Command, withPhaseName string, asSubcommand bool) {
 	var (
-		printVersion bool
-		logLevel     string
-		noColor      bool
+		printVersion    bool
+		logFormat       = new(string)
+		logLevel        string
+		noColor         bool
+		profiles        str.Slice
+		registryNetwork = map[string]*string{}
+		tmpDir          = new(string)
 	)
 
 	log.SetOutput(io.Discard)
+	if reporter, ok := c.(cmd.FailureReporter); ok {
+		cmd.SetFailureReporter(reporter, withPhaseName)
+	}
 	FlagVersion(&printVersion)
 	FlagLogLevel(&logLevel)
 	FlagNoColor(&noColor)
+	FlagProfile(&profiles)
+	FlagImageLock(registryNetwork)
+	FlagRegistryAuditLog(registryNetwork)
+	FlagRegistryMirrors(registryNetwork)
+	FlagRegistryProxies(registryNetwork)
+	FlagRegistryTLS(registryNetwork)
+	if p, ok := c.(InputsCommand); ok && p.Inputs() != nil {
+		logFormat = &p.Inputs().LogFormat
+		tmpDir = &p.Inputs().TmpDir
+	} else {
+		*logFormat = stringEnvOrDefault(platform.EnvLogFormat, platform.DefaultLogFormat)
+		*tmpDir = platform.Getenv(platform.EnvTmpDir)
+	}
+	FlagLogFormat(logFormat)
+	FlagTmpDir(tmpDir)
 	c.DefineFlags()
 	if asSubcommand {
 		if err := flagSet.Parse(os.Args[2:]); err != nil {
@@ -47,16 +91,44 @@ func Run(c Command, withPhaseName string, asSubcommand bool) {
 			cmd.Exit(err)
 		}
 	}
+	if err := setFlagsFromLifecycleConfig(); err != nil {
+		cmd.Exit(cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "read lifecycle config file"))
+	}
 	cmd.DisableColor(noColor)
 
 	if printVersion {
 		cmd.ExitWithVersion()
 	}
+	if err := cmd.DefaultLogger.SetFormat(*logFormat, withPhaseName); err != nil {
+		cmd.Exit(err)
+	}
 	if err := cmd.DefaultLogger.SetLevel(logLevel); err != nil {
 		cmd.Exit(err)
 	}
+	auth.SetKeychainLogger(cmd.DefaultLogger)
+	if err := cmd.StartRedaction(platform.Getenv); err != nil {
+		cmd.Exit(cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "configure redaction"))
+	}
 	cmd.DefaultLogger.Debugf("Starting %s...", withPhaseName)
 
+	failures, err := chaos.Configure(platform.Getenv)
+	if err != nil {
+		cmd.Exit(cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "configure failure injection"))
+	}
+	for _, failure := range failures {
+		cmd.DefaultLogger.Warnf("Failure injection is enabled: %s", failure)
+	}
+	if err := configureNetwork(c, registryNetwork, withPhaseName); err != nil {
+		cmd.Exit(cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "configure registry network settings"))
+	}
+	if err := fsutil.SetTempDir(*tmpDir); err != nil {
+		cmd.Exit(cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "configure temporary directory"))
+	}
+	if err := cmd.StartProfiling(profiles); err != nil {
+		cmd.Exit(cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "start profiling"))
+	}
+	configureTelemetry(c, withPhaseName)
+
 	for _, arg := range flagSet.Args() {
 		if arg[0:1] == "-" {
 			cmd.DefaultLogger.Warnf("Warning: unconsumed flag-like positional arg: \n\t%s\n\t This will not be interpreted as a flag.\n\t Did you mean to put this before the first positional argument?", arg)
@@ -64,7 +136,12 @@ func Run(c Command, withPhaseName string, asSubcommand bool) {
 	}
 
 	// Warn when CNB_PLATFORM_API is unset
-	if os.Getenv(platform.EnvPlatformAPI) == "" {
+	if platform.Getenv(platform.EnvPlatformAPI) == "" {
+		deprecation.Record(deprecation.Notice{
+			Kind:    deprecation.KindPlatformAPI,
+			API:     platform.DefaultPlatformAPI,
+			Message: fmt.Sprintf("%s is unset; it should be set to avoid breaking changes when upgrading the lifecycle", platform.EnvPlatformAPI),
+		})
 		cmd.DefaultLogger.Warnf("%s is unset; using Platform API version '%s'", platform.EnvPlatformAPI, platform.DefaultPlatformAPI)
 		cmd.DefaultLogger.Infof("%s should be set to avoid breaking changes when upgrading the lifecycle", platform.EnvPlatformAPI)
 	}
@@ -73,6 +150,21 @@ func Run(c Command, withPhaseName string, asSubcommand bool) {
 	if err := c.Args(flagSet.NArg(), flagSet.Args()); err != nil {
 		cmd.Exit(err)
 	}
+	if tracker, ok := c.(cmd.DeprecationTracker); ok {
+		cmd.TrackDeprecations(tracker)
+	}
+	if tracker, ok := c.(cmd.UsageTracker); ok {
+		cmd.TrackUsage(tracker, withPhaseName)
+	}
+	if tracker, ok := c.(cmd.RedactionTracker); ok {
+		cmd.TrackRedactions(tracker)
+	}
+	if tracker, ok := c.(cmd.ArtifactsTracker); ok {
+		cmd.TrackArtifacts(tracker, withPhaseName)
+	}
+	if bundler, ok := c.(cmd.SupportBundler); ok {
+		cmd.TrackSupportBundle(bundler, withPhaseName)
+	}
 	cmd.DefaultLogger.Debugf("Ensuring privileges...")
 	if err := c.Privileges(); err != nil {
 		cmd.Exit(err)
@@ -80,3 +172,72 @@ func Run(c Command, withPhaseName string, asSubcommand bool) {
 	cmd.DefaultLogger.Debugf("Executing command...")
 	cmd.Exit(c.Exec())
 }
+
+// configureTelemetry starts timing the phase if the platform provided a telemetry reporter,
+// starts tracing the phase if the platform provided an OTLP endpoint,
+// starts recording metrics if the platform provided a metrics destination,
+// and starts the progress event stream if the platform provided a file descriptor or socket for it.
+func configureTelemetry(c Command, phase string) {
+	platformAPI := platform.DefaultPlatformAPI
+	if p, ok := c.(interface{ API() *api.Version }); ok && p.API() != nil {
+		platformAPI = p.API().String()
+	}
+	if err := cmd.StartTracing(phase, platformAPI, platform.Getenv); err != nil {
+		cmd.DefaultLogger.Warnf("Tracing is disabled: %s", err)
+	}
+	if err := cmd.StartMetrics(phase, platform.Getenv); err != nil {
+		cmd.DefaultLogger.Warnf("Metrics are disabled: %s", err)
+	}
+	if err := cmd.StartProgress(phase, platform.Getenv); err != nil {
+		cmd.DefaultLogger.Warnf("Progress events are disabled: %s", err)
+	}
+	reporter := platform.Getenv(platform.EnvTelemetryReporter)
+	if reporter == "" {
+		return
+	}
+	telemetry.Register(telemetry.NewExecHook(reporter))
+	cmd.StartTelemetry(phase, platformAPI)
+}
+
+// setFlagsFromLifecycleConfig sets the flags that were not provided on the command line or in the environment
+// to their values in the [flags] table of the lifecycle config file (see platform.EnvLifecycleConfig).
+func setFlagsFromLifecycleConfig() error {
+	values, err := platform.LifecycleConfigFlags()
+	if err != nil {
+		return err
+	}
+	provided := map[string]bool{}
+	flagSet.Visit(func(f *flag.Flag) {
+		provided[f.Name] = true
+	})
+	for name, vals := range values {
+		if provided[name] || envProvides(name) || flagSet.Lookup(name) == nil {
+			continue
+		}
+		for _, val := range vals {
+			if err := flagSet.Set(name, val); err != nil {
+				return fmt.Errorf("invalid value for flag '%s': %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configureNetwork configures the proxy, TLS and mirror settings for all registry requests
+// from the provided flags, the environment, and the lifecycle config file.
+func configureNetwork(c Command, flags map[string]*string, phase string) error {
+	config, err := network.ConfigFromEnv(func(key string) string {
+		if val, ok := flags[key]; ok {
+			return *val
+		}
+		return platform.Getenv(key)
+	})
+	if err != nil {
+		return err
+	}
+	config.Audit.Phase = phase
+	if k, ok := c.(KanikoCommand); ok && k.UsesKaniko() {
+		config.PlainDefaultTransport = true
+	}
+	return network.Configure(config)
+}
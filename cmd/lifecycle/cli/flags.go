@@ -6,12 +6,123 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/buildpacks/lifecycle/internal/network"
 	"github.com/buildpacks/lifecycle/internal/str"
 	"github.com/buildpacks/lifecycle/platform"
 )
 
 var flagSet = flag.NewFlagSet("lifecycle", flag.ExitOnError)
 
+// flagEnvVars maps the names of flags to the environment variables that provide their defaults,
+// so that values for these flags in the lifecycle config file do not take precedence over the environment.
+var flagEnvVars = map[string][]string{
+	"analyzed":              {platform.EnvAnalyzedPath},
+	"anonymous-fallback":    {platform.EnvAnonymousFallback},
+	"app":                   {platform.EnvAppDir},
+	"app-source":            {platform.EnvAppSourceDir},
+	"app-symlinks":          {platform.EnvAppSymlinks},
+	"apps":                  {platform.EnvAppsPath},
+	"attach-attestations":   {platform.EnvAttachAttestations},
+	"build-config":          {platform.EnvBuildConfigDir},
+	"build-image":           {platform.EnvBuildImage},
+	"build-log":             {platform.EnvBuildLogPath},
+	"buildpacks":            {platform.EnvBuildpacksDir},
+	"cache-dir":             {platform.EnvCacheDir},
+	"cache-image":           {platform.EnvCacheImage},
+	"cache-lock-timeout":    {platform.EnvCacheLockTimeout},
+	"constraints":           {platform.EnvConstraintsPath},
+	"create-working-dirs":   {platform.EnvCreateWorkingDirs},
+	"daemon":                {platform.EnvUseDaemon},
+	"debug-image":           {platform.EnvDebugImage},
+	"dedupe-sbom":           {platform.EnvDedupeSBOM},
+	"digest-algorithm":      {platform.EnvDigestAlgorithm},
+	"dry-run":               {platform.EnvCleanDryRun, platform.EnvRebaseDryRun},
+	"explain-env":           {platform.EnvExplainEnv},
+	"extend-backend":        {platform.EnvExtendBackend},
+	"extend-cache-image":    {platform.EnvExtendCacheImage},
+	"extend-parallel":       {platform.EnvExtendParallel},
+	"extend-rootless":       {platform.EnvExtendRootless},
+	"extend-secrets-dir":    {platform.EnvExtendSecretsDir},
+	"extended":              {platform.EnvExtendedDir},
+	"extensions":            {platform.EnvExtensionsDir},
+	"force":                 {platform.EnvForceRebase},
+	"generate-dry-run":      {platform.EnvGenerateDryRunDir},
+	"generated":             {platform.EnvGeneratedDir},
+	"gid":                   {platform.EnvGID},
+	"group":                 {platform.EnvGroupPath},
+	"image-lock":            {network.EnvImageLockPath},
+	"images":                {platform.EnvRebaseImagesPath},
+	"kaniko-cache-ttl":      {platform.EnvKanikoCacheTTL},
+	"kaniko-dir":            {platform.EnvKanikoDir},
+	"kind":                  {platform.EnvExtendKind},
+	"launch-cache":          {platform.EnvLaunchCacheDir},
+	"launch-cache-image":    {platform.EnvLaunchCacheImage},
+	"layer-compression":     {platform.EnvLayerCompression},
+	"layers":                {platform.EnvLayersDir},
+	"layout":                {platform.EnvUseLayout},
+	"layout-dir":            {platform.EnvLayoutDir},
+	"lenient-extraction":    {platform.EnvLenientExtraction},
+	"log-format":            {platform.EnvLogFormat},
+	"log-level":             {platform.EnvLogLevel},
+	"merged-sbom":           {platform.EnvMergedSBOMPath},
+	"network-buildpacks":    {platform.EnvNetworkBuildpacks},
+	"no-color":              {platform.EnvNoColor},
+	"no-digest-cache":       {platform.EnvNoDigestCache},
+	"normalize-ownership":   {platform.EnvNormalizeOwnership},
+	"order":                 {platform.EnvOrderPath},
+	"parallelism":           {platform.EnvRebaseParallelism},
+	"plan":                  {platform.EnvPlanPath},
+	"platform":              {platform.EnvPlatformDir},
+	"preserve-xattrs":       {platform.EnvPreserveXattrs},
+	"previous-image":        {platform.EnvPreviousImage},
+	"process-type":          {platform.EnvProcessType},
+	"process-type-fallback": {platform.EnvProcessTypeFallback},
+	"project-descriptor":    {platform.EnvProjectDescriptorPath},
+	"project-metadata":      {platform.EnvProjectMetadataPath},
+	"proxy-ca-cert":         {network.EnvProxyCACert},
+	"prune-launch-sbom":     {platform.EnvPruneLaunchSBOM},
+	"registry-audit-log":    {network.EnvRegistryAuditLog},
+	"registry-ca-certs":     {network.EnvRegistryCACerts},
+	"registry-client-cert":  {network.EnvRegistryClientCert},
+	"registry-client-key":   {network.EnvRegistryClientKey},
+	"registry-mirrors":      {network.EnvRegistryMirrors},
+	"registry-proxies":      {network.EnvRegistryProxies},
+	"registry-tls-config":   {network.EnvRegistryTLSConfig},
+	"report":                {platform.EnvReportPath},
+	"run":                   {platform.EnvRunPath},
+	"run-image":             {platform.EnvRunImage},
+	"sbom-compression":      {platform.EnvSBOMCompression},
+	"sbom-policy":           {platform.EnvSBOMPolicyPath},
+	"sbom-validation":       {platform.EnvSBOMValidation},
+	"scanner":               {platform.EnvScanner},
+	"scanner-enforce":       {platform.EnvScannerEnforce},
+	"skip-analyze":          {platform.EnvSkipAnalyze},
+	"skip-build":            {platform.EnvSkipBuild},
+	"skip-detect":           {platform.EnvSkipDetect},
+	"skip-layers":           {platform.EnvSkipLayers, platform.EnvSkipRestore},
+	"skip-restore":          {platform.EnvSkipLayers, platform.EnvSkipRestore},
+	"snapshot":              {platform.EnvRebaseSnapshot},
+	"source-sbom":           {platform.EnvSourceSBOMPath},
+	"squash-buildpacks":     {platform.EnvSquashBuildpacks},
+	"squash-layers-below":   {platform.EnvSquashLayersBelow},
+	"stack":                 {platform.EnvStackPath},
+	"strip-setuid":          {platform.EnvStripSetuid},
+	"tmp-dir":               {platform.EnvTmpDir},
+	"trust-policy":          {platform.EnvTrustPolicyPath},
+	"uid":                   {platform.EnvUID},
+	"umask":                 {platform.EnvUmask},
+}
+
+// envProvides returns true if any environment variable that provides the default of the flag is set.
+func envProvides(flagName string) bool {
+	for _, key := range flagEnvVars[flagName] {
+		if os.Getenv(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func FlagLayoutDir(layoutDir *string) {
 	flagSet.StringVar(layoutDir, "layout-dir", *layoutDir, "path to output directory for images in OCI layout format")
 }
@@ -24,10 +135,30 @@ func FlagAnalyzedPath(analyzedPath *string) {
 	flagSet.StringVar(analyzedPath, "analyzed", *analyzedPath, "path to analyzed.toml")
 }
 
+func FlagAnonymousFallback(anonymousFallback *bool) {
+	flagSet.BoolVar(anonymousFallback, "anonymous-fallback", *anonymousFallback, "pull images anonymously from registries that reject the provided credentials")
+}
+
 func FlagAppDir(appDir *string) {
 	flagSet.StringVar(appDir, "app", *appDir, "path to app directory")
 }
 
+func FlagAppsPath(appsPath *string) {
+	flagSet.StringVar(appsPath, "apps", *appsPath, "path to apps.toml, to build each app it declares")
+}
+
+func FlagAppSymlinks(appSymlinks *string) {
+	flagSet.StringVar(appSymlinks, "app-symlinks", *appSymlinks, "how to handle symlinks pointing outside of the app directory (preserve, follow or error)")
+}
+
+func FlagAppSourceDir(appSourceDir *string) {
+	flagSet.StringVar(appSourceDir, "app-source", *appSourceDir, "path to application source to copy to the app directory, skipping paths in .cnbignore")
+}
+
+func FlagAttachAttestations(attachAttestations *bool) {
+	flagSet.BoolVar(attachAttestations, "attach-attestations", *attachAttestations, "attach the SBOM and build metadata to the application image as OCI referrers")
+}
+
 func FlagBuildConfigDir(buildConfigDir *string) {
 	flagSet.StringVar(buildConfigDir, "build-config", *buildConfigDir, "path to build config directory")
 }
@@ -36,6 +167,10 @@ func FlagBuildImage(buildImage *string) {
 	flagSet.StringVar(buildImage, "build-image", *buildImage, "build image tag name")
 }
 
+func FlagBuildLogPath(buildLogPath *string) {
+	flagSet.StringVar(buildLogPath, "build-log", *buildLogPath, "path to write the output of the buildpacks to, e.g., to add it to the debug image")
+}
+
 func FlagBuildpacksDir(buildpacksDir *string) {
 	flagSet.StringVar(buildpacksDir, "buildpacks", *buildpacksDir, "path to buildpacks directory")
 }
@@ -44,14 +179,62 @@ func FlagCacheDir(cacheDir *string) {
 	flagSet.StringVar(cacheDir, "cache-dir", *cacheDir, "path to cache directory")
 }
 
+func FlagCacheLockTimeout(cacheLockTimeout *time.Duration) {
+	flagSet.DurationVar(cacheLockTimeout, "cache-lock-timeout", *cacheLockTimeout, "time to wait for concurrent builds sharing the cache directory to release it")
+}
+
 func FlagCacheImage(cacheImage *string) {
 	flagSet.StringVar(cacheImage, "cache-image", *cacheImage, "cache image tag name")
 }
 
+func FlagCleanDryRun(dryRun *bool) {
+	flagSet.BoolVar(dryRun, "dry-run", *dryRun, "report the stale content of the layers directory without removing it")
+}
+
+func FlagConstraintsPath(constraintsPath *string) {
+	flagSet.StringVar(constraintsPath, "constraints", *constraintsPath, "path to a constraints file overriding the versions of the dependencies requested by buildpacks")
+}
+
+func FlagCreateWorkingDirs(createWorkingDirs *bool) {
+	flagSet.BoolVar(createWorkingDirs, "create-working-dirs", *createWorkingDirs, "create the working directories of processes that do not exist in the app directory or in a launch layer")
+}
+
+func FlagDebugImage(debugImage *string) {
+	flagSet.StringVar(debugImage, "debug-image", *debugImage, "debug image tag name, for an image containing the build-only layers and build artifacts")
+}
+
+func FlagDigestAlgorithm(digestAlgorithm *string) {
+	flagSet.StringVar(digestAlgorithm, "digest-algorithm", *digestAlgorithm, "algorithm of the digests of the layers only added to the cache directory (sha256 or sha512); exported layers always use sha256")
+}
+
+func FlagExplainEnv(explainEnv *bool) {
+	flagSet.BoolVar(explainEnv, "explain-env", *explainEnv, "log the environment of each buildpack with the env files that contributed to each variable")
+}
+
+func FlagExtendBackend(extendBackend *string) {
+	flagSet.StringVar(extendBackend, "extend-backend", *extendBackend, "tool used to apply extension Dockerfiles (kaniko or buildkit)")
+}
+
+func FlagExtendCacheImage(extendCacheImage *string) {
+	flagSet.StringVar(extendCacheImage, "extend-cache-image", *extendCacheImage, "registry image used to share layers cached during extension between builders")
+}
+
 func FlagExtendKind(extendKind *string) {
 	flagSet.StringVar(extendKind, "kind", *extendKind, "kind of image to extend")
 }
 
+func FlagExtendParallel(extendParallel *bool) {
+	flagSet.BoolVar(extendParallel, "extend-parallel", *extendParallel, "extend the run image concurrently with the build image")
+}
+
+func FlagExtendRootless(extendRootless *bool) {
+	flagSet.BoolVar(extendRootless, "extend-rootless", *extendRootless, "extend without root privileges on the host (requires the buildkit backend)")
+}
+
+func FlagExtendSecretsDir(extendSecretsDir *string) {
+	flagSet.StringVar(extendSecretsDir, "extend-secrets-dir", *extendSecretsDir, "path to directory containing secrets for extension Dockerfiles")
+}
+
 func FlagExtendedDir(extendedDir *string) {
 	flagSet.StringVar(extendedDir, "extended", *extendedDir, "path to output directory for image layers created from applying generated Dockerfiles")
 }
@@ -64,6 +247,10 @@ func FlagGID(gid *int) {
 	flagSet.IntVar(gid, "gid", *gid, "GID of user's group in the stack's build and run images")
 }
 
+func FlagGenerateDryRunDir(generateDryRunDir *string) {
+	flagSet.StringVar(generateDryRunDir, "generate-dry-run", *generateDryRunDir, "path to output directory for Dockerfiles rendered by image extensions; if provided, the build does not continue")
+}
+
 func FlagGeneratedDir(generatedDir *string) {
 	flagSet.StringVar(generatedDir, "generated", *generatedDir, "path to output directory for files generated by image extensions")
 }
@@ -72,14 +259,27 @@ func FlagGroupPath(groupPath *string) {
 	flagSet.StringVar(groupPath, "group", *groupPath, "path to group.toml")
 }
 
+// FlagImageLock defines the flag for the image lock file, keyed by the corresponding environment variable.
+func FlagImageLock(registryNetwork map[string]*string) {
+	registryNetwork[network.EnvImageLockPath] = flagSet.String("image-lock", platform.Getenv(network.EnvImageLockPath), "path to a file pinning base image tags to digests")
+}
+
 func FlagKanikoCacheTTL(kanikoCacheTTL *time.Duration) {
 	flagSet.DurationVar(kanikoCacheTTL, "kaniko-cache-ttl", *kanikoCacheTTL, "kaniko cache time-to-live")
 }
 
+func FlagKanikoDir(kanikoDir *string) {
+	flagSet.StringVar(kanikoDir, "kaniko-dir", *kanikoDir, "path to writable directory for base images to extend, cached layers and working files of the extender")
+}
+
 func FlagLaunchCacheDir(launchCacheDir *string) {
 	flagSet.StringVar(launchCacheDir, "launch-cache", *launchCacheDir, "path to launch cache directory")
 }
 
+func FlagLaunchCacheImage(launchCacheImage *string) {
+	flagSet.StringVar(launchCacheImage, "launch-cache-image", *launchCacheImage, "registry image to lazily fetch reused layers from when exporting to a daemon without a launch cache directory")
+}
+
 func FlagLauncherPath(launcherPath *string) {
 	flagSet.StringVar(launcherPath, "launcher", *launcherPath, "path to launcher binary")
 }
@@ -88,22 +288,54 @@ func FlagLauncherSBOMDir(launcherSBOMDir *string) {
 	flagSet.StringVar(launcherSBOMDir, "launcher-sbom", *launcherSBOMDir, "path to launcher SBOM directory")
 }
 
+func FlagLayerCompression(layerCompression *string) {
+	flagSet.StringVar(layerCompression, "layer-compression", *layerCompression, "gzip compression level of the layers exported to a registry or layout (0-9), or auto to choose it from the contents of each layer")
+}
+
 func FlagLayersDir(layersDir *string) {
 	flagSet.StringVar(layersDir, "layers", *layersDir, "path to layers directory")
 }
 
+func FlagLenientExtraction(lenientExtraction *bool) {
+	flagSet.BoolVar(lenientExtraction, "lenient-extraction", *lenientExtraction, "skip entries of cached layers that would be written outside of the layers directory, rather than failing the restore")
+}
+
+func FlagLogFormat(logFormat *string) {
+	flagSet.StringVar(logFormat, "log-format", *logFormat, "logging format (human or json)")
+}
+
 func FlagLogLevel(logLevel *string) {
 	flagSet.StringVar(logLevel, "log-level", platform.DefaultLogLevel, "logging level")
 }
 
+func FlagMergedSBOMPath(mergedSBOMPath *string) {
+	flagSet.StringVar(mergedSBOMPath, "merged-sbom", *mergedSBOMPath, "path to write an SBOM merging the SBOM files for all layers (SPDX if the path ends in .spdx.json, else CycloneDX)")
+}
+
+func FlagNoDigestCache(noDigestCache *bool) {
+	flagSet.BoolVar(noDigestCache, "no-digest-cache", *noDigestCache, "always hash layers when exporting, rather than trusting file metadata to detect unchanged layers")
+}
+
 func FlagNoColor(noColor *bool) {
 	flagSet.BoolVar(noColor, "no-color", boolEnv(platform.EnvNoColor), "disable color output")
 }
 
+func FlagNormalizeOwnership(normalizeOwnership *bool) {
+	flagSet.BoolVar(normalizeOwnership, "normalize-ownership", *normalizeOwnership, "chown the app and layers directories to the CNB user and group")
+}
+
+func FlagNetworkBuildpacks(networkBuildpacks *string) {
+	flagSet.StringVar(networkBuildpacks, "network-buildpacks", *networkBuildpacks, "comma-separated IDs of the buildpacks that may access the network during build")
+}
+
 func FlagOrderPath(orderPath *string) {
 	flagSet.StringVar(orderPath, "order", *orderPath, "path to order.toml")
 }
 
+func FlagOutputDir(outputDir *string) {
+	flagSet.StringVar(outputDir, "output-dir", *outputDir, "path to directory for converted SBOM files")
+}
+
 func FlagPlanPath(planPath *string) {
 	flagSet.StringVar(planPath, "plan", *planPath, "path to plan.toml")
 }
@@ -112,6 +344,10 @@ func FlagPlatformDir(platformDir *string) {
 	flagSet.StringVar(platformDir, "platform", *platformDir, "path to platform directory")
 }
 
+func FlagPreserveXattrs(preserveXattrs *string) {
+	flagSet.StringVar(preserveXattrs, "preserve-xattrs", *preserveXattrs, "comma-separated prefixes of the extended attributes to preserve in layers (e.g., security.capability), or none")
+}
+
 func FlagPreviousImage(previousImage *string) {
 	flagSet.StringVar(previousImage, "previous-image", *previousImage, "reference to previous image")
 }
@@ -120,10 +356,55 @@ func FlagProcessType(processType *string) {
 	flagSet.StringVar(processType, "process-type", *processType, "default process type")
 }
 
+func FlagProcessTypeFallback(processTypeFallback *string) {
+	flagSet.StringVar(processTypeFallback, "process-type-fallback", *processTypeFallback, "comma-separated process types to use as the default process type when none is specified, in order of preference")
+}
+
+func FlagProfile(profiles *str.Slice) {
+	flagSet.Var(profiles, "profile", "write a pprof profile, as cpu:<path>, mem:<path> or allocs:<path>; may be repeated")
+}
+
+func FlagProjectDescriptorPath(projectDescriptorPath *string) {
+	flagSet.StringVar(projectDescriptorPath, "project-descriptor", *projectDescriptorPath, "path to project.toml")
+}
+
 func FlagProjectMetadataPath(projectMetadataPath *string) {
 	flagSet.StringVar(projectMetadataPath, "project-metadata", *projectMetadataPath, "path to project-metadata.toml")
 }
 
+func FlagDedupeSBOM(dedupeSBOM *bool) {
+	flagSet.BoolVar(dedupeSBOM, "dedupe-sbom", *dedupeSBOM, "replace duplicate SBOM files in the application image with symlinks")
+}
+
+func FlagPruneLaunchSBOM(pruneLaunchSBOM *bool) {
+	flagSet.BoolVar(pruneLaunchSBOM, "prune-launch-sbom", *pruneLaunchSBOM, "remove build-only SBOM entries and build-time metadata from the application image")
+}
+
+// FlagRegistryAuditLog defines the flag for the registry audit log, keyed by the corresponding environment variable.
+func FlagRegistryAuditLog(registryNetwork map[string]*string) {
+	registryNetwork[network.EnvRegistryAuditLog] = flagSet.String("registry-audit-log", platform.Getenv(network.EnvRegistryAuditLog), "path to a file to which every registry request is appended")
+}
+
+// FlagRegistryMirrors defines the flag for registry mirrors, keyed by the corresponding environment variable.
+func FlagRegistryMirrors(registryMirrors map[string]*string) {
+	registryMirrors[network.EnvRegistryMirrors] = flagSet.String("registry-mirrors", platform.Getenv(network.EnvRegistryMirrors), "path to a file with pull-through mirrors for specific registries")
+}
+
+// FlagRegistryProxies defines the flags for registry proxy settings, keyed by the corresponding environment variable.
+// The standard proxy environment variables (HTTP_PROXY, HTTPS_PROXY and NO_PROXY) apply to every registry.
+func FlagRegistryProxies(registryProxies map[string]*string) {
+	registryProxies[network.EnvRegistryProxies] = flagSet.String("registry-proxies", platform.Getenv(network.EnvRegistryProxies), "comma-separated <registry>=<proxy URL> overrides of the proxy for specific registries")
+	registryProxies[network.EnvProxyCACert] = flagSet.String("proxy-ca-cert", platform.Getenv(network.EnvProxyCACert), "path to additional CA certificates to trust, e.g., for proxies that intercept TLS connections")
+}
+
+// FlagRegistryTLS defines the flags for registry TLS settings, keyed by the corresponding environment variable.
+func FlagRegistryTLS(registryTLS map[string]*string) {
+	registryTLS[network.EnvRegistryCACerts] = flagSet.String("registry-ca-certs", platform.Getenv(network.EnvRegistryCACerts), "comma-separated paths to additional CA certificates to trust for all registries")
+	registryTLS[network.EnvRegistryClientCert] = flagSet.String("registry-client-cert", platform.Getenv(network.EnvRegistryClientCert), "path to a client certificate for registries that require mutual TLS")
+	registryTLS[network.EnvRegistryClientKey] = flagSet.String("registry-client-key", platform.Getenv(network.EnvRegistryClientKey), "path to the key for the registry client certificate")
+	registryTLS[network.EnvRegistryTLSConfig] = flagSet.String("registry-tls-config", platform.Getenv(network.EnvRegistryTLSConfig), "path to a file with TLS settings for specific registries")
+}
+
 func FlagReportPath(reportPath *string) {
 	flagSet.StringVar(reportPath, "report", *reportPath, "path to report.toml")
 }
@@ -136,26 +417,90 @@ func FlagRunPath(runPath *string) {
 	flagSet.StringVar(runPath, "run", *runPath, "path to run.toml")
 }
 
+func FlagSBOMFormats(sbomFormats *str.Slice) {
+	flagSet.Var(sbomFormats, "sbom-format", "SBOM format to convert to (cyclonedx or spdx); may be repeated")
+}
+
+func FlagSBOMCompression(sbomCompression *string) {
+	flagSet.StringVar(sbomCompression, "sbom-compression", *sbomCompression, "compression of the SBOM files in the application image (gzip or none)")
+}
+
+func FlagSBOMPolicyPath(sbomPolicyPath *string) {
+	flagSet.StringVar(sbomPolicyPath, "sbom-policy", *sbomPolicyPath, "path to a policy file restricting the licenses and packages in the image SBOM")
+}
+
+func FlagSBOMValidation(sbomValidation *string) {
+	flagSet.StringVar(sbomValidation, "sbom-validation", *sbomValidation, "how to handle invalid buildpack SBOM files (warn, fail or off)")
+}
+
+func FlagScanner(scanner *string) {
+	flagSet.StringVar(scanner, "scanner", *scanner, "path to a vulnerability scanner executable, or an http(s) endpoint, to scan the application image with")
+}
+
+func FlagScannerEnforce(scannerEnforce *bool) {
+	flagSet.BoolVar(scannerEnforce, "scanner-enforce", *scannerEnforce, "fail the export if the application image fails the scan")
+}
+
 func FlagSkipLayers(skipLayers *bool) {
 	flagSet.BoolVar(skipLayers, "skip-layers", *skipLayers, "do not provide layer metadata to buildpacks")
 }
 
+func FlagSkipAnalyze(skipAnalyze *bool) {
+	flagSet.BoolVar(skipAnalyze, "skip-analyze", *skipAnalyze, "do not analyze, read analyzed.toml from a previous analysis instead")
+}
+
+func FlagSkipBuild(skipBuild *bool) {
+	flagSet.BoolVar(skipBuild, "skip-build", *skipBuild, "do not build, export the layers directory from a previous build instead")
+}
+
+func FlagSkipDetect(skipDetect *bool) {
+	flagSet.BoolVar(skipDetect, "skip-detect", *skipDetect, "do not detect, read group.toml and plan.toml from a previous detection instead")
+}
+
 func FlagSkipRestore(skipRestore *bool) {
 	flagSet.BoolVar(skipRestore, "skip-restore", *skipRestore, "do not restore layers or layer metadata")
 }
 
+func FlagSourceSBOMPath(sourceSBOMPath *string) {
+	flagSet.StringVar(sourceSBOMPath, "source-sbom", *sourceSBOMPath, "path to an SBOM describing the application source")
+}
+
+func FlagSquashBuildpacks(squashBuildpacks *string) {
+	flagSet.StringVar(squashBuildpacks, "squash-buildpacks", *squashBuildpacks, "comma-separated IDs of the buildpacks whose launch layers are squashed into a single layer")
+}
+
+func FlagSquashLayersBelow(squashLayersBelow *int) {
+	flagSet.IntVar(squashLayersBelow, "squash-layers-below", *squashLayersBelow, "squash the launch layers smaller than the provided size (in bytes) into a single layer")
+}
+
 func FlagStackPath(stackPath *string) {
 	flagSet.StringVar(stackPath, "stack", *stackPath, "path to stack.toml")
 }
 
+func FlagStripSetuid(stripSetuid *bool) {
+	flagSet.BoolVar(stripSetuid, "strip-setuid", *stripSetuid, "remove the setuid and setgid bits from files in the app and layers directories")
+}
+
 func FlagTags(tags *str.Slice) {
 	flagSet.Var(tags, "tag", "additional tags")
 }
 
+func FlagTrustPolicyPath(trustPolicyPath *string) {
+	flagSet.StringVar(trustPolicyPath, "trust-policy", *trustPolicyPath, "path to a trust policy listing the keys that base images must be signed by")
+}
+
 func FlagUID(uid *int) {
 	flagSet.IntVar(uid, "uid", *uid, "UID of user in the stack's build and run images")
 }
 
+func FlagUmask(umask *string) {
+	flagSet.StringVar(umask, "umask", *umask, "umask (in octal, e.g., 022) to apply while buildpacks run")
+}
+
+func FlagTmpDir(tmpDir *string) {
+	flagSet.StringVar(tmpDir, "tmp-dir", *tmpDir, "path to directory for temporary files")
+}
+
 func FlagUseDaemon(useDaemon *bool) {
 	flagSet.BoolVar(useDaemon, "daemon", *useDaemon, "export to docker daemon")
 }
@@ -168,6 +513,22 @@ func FlagForceRebase(force *bool) {
 	flagSet.BoolVar(force, "force", *force, "execute rebase even if operation is unsafe")
 }
 
+func FlagRebaseDryRun(dryRun *bool) {
+	flagSet.BoolVar(dryRun, "dry-run", *dryRun, "rebase without saving, reporting the digest of the rebased image")
+}
+
+func FlagRebaseSnapshot(snapshot *bool) {
+	flagSet.BoolVar(snapshot, "snapshot", *snapshot, "tag the app image before rebasing as pre-rebase-<timestamp>, to allow rolling back")
+}
+
+func FlagRebaseImagesPath(rebaseImagesPath *string) {
+	flagSet.StringVar(rebaseImagesPath, "images", *rebaseImagesPath, "path to a file listing app images to rebase onto the run image, one per line (- for stdin)")
+}
+
+func FlagRebaseParallelism(rebaseParallelism *int) {
+	flagSet.IntVar(rebaseParallelism, "parallelism", *rebaseParallelism, "maximum number of images to rebase at a time with -images")
+}
+
 // deprecated
 
 func DeprecatedFlagRunImage(deprecatedRunImage *string) {
@@ -176,8 +537,15 @@ func DeprecatedFlagRunImage(deprecatedRunImage *string) {
 
 // helpers
 
+func stringEnvOrDefault(k string, defaultVal string) string {
+	if v := platform.Getenv(k); v != "" {
+		return v
+	}
+	return defaultVal
+}
+
 func boolEnv(k string) bool {
-	v := os.Getenv(k)
+	v := platform.Getenv(k)
 	b, err := strconv.ParseBool(v)
 	if err != nil {
 		return false
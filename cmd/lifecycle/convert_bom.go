@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/auth"
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/cmd/lifecycle/cli"
+	"github.com/buildpacks/lifecycle/image"
+	"github.com/buildpacks/lifecycle/internal/str"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/priv"
+)
+
+const defaultConvertBOMOutputDir = "sbom"
+
+// convertBOMCmd converts the (deprecated) BOM in the build metadata label of an existing image into SBOM files.
+// It is not a phase of the build; it helps platforms migrate images built by lifecycles that pre-date SBOM layers.
+type convertBOMCmd struct {
+	*platform.Platform
+
+	imageRef    string
+	outputDir   string
+	sbomFormats str.Slice
+	mediaTypes  []string
+
+	docker   client.CommonAPIClient // construct if necessary before dropping privileges
+	keychain authn.Keychain         // construct if necessary before dropping privileges
+}
+
+// DefineFlags defines the flags that are considered valid and reads their values (if provided).
+func (c *convertBOMCmd) DefineFlags() {
+	c.outputDir = defaultConvertBOMOutputDir
+	cli.FlagGID(&c.GID)
+	cli.FlagOutputDir(&c.outputDir)
+	cli.FlagSBOMFormats(&c.sbomFormats)
+	cli.FlagUID(&c.UID)
+	cli.FlagUseDaemon(&c.UseDaemon)
+}
+
+// Args validates arguments and flags, and fills in default values.
+func (c *convertBOMCmd) Args(nargs int, args []string) error {
+	if nargs != 1 {
+		return cmd.FailErrCode(fmt.Errorf("received %d arguments, but expected 1", nargs), cmd.CodeForInvalidArgs, "parse arguments")
+	}
+	c.imageRef = args[0]
+	if c.outputDir == "" {
+		return cmd.FailErrCode(errors.New("-output-dir is required"), cmd.CodeForInvalidArgs, "parse arguments")
+	}
+	formats := c.sbomFormats
+	if len(formats) == 0 {
+		formats = []string{"cyclonedx", "spdx"}
+	}
+	for _, format := range formats {
+		mediaType, err := mediaTypeForSBOMFormat(format)
+		if err != nil {
+			return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "parse arguments")
+		}
+		c.mediaTypes = append(c.mediaTypes, mediaType)
+	}
+	return nil
+}
+
+func (c *convertBOMCmd) Privileges() error {
+	var err error
+	c.keychain, err = auth.DefaultKeychain(c.imageRef)
+	if err != nil {
+		return cmd.FailErr(err, "resolve keychain")
+	}
+	if c.UseDaemon {
+		c.docker, err = priv.DaemonClient()
+		if err != nil {
+			return cmd.FailErr(err, "initialize docker client")
+		}
+	}
+	if err = priv.RunAs(c.UID, c.GID); err != nil {
+		return cmd.FailErr(err, fmt.Sprintf("exec as user %d:%d", c.UID, c.GID))
+	}
+	return nil
+}
+
+func (c *convertBOMCmd) Exec() error {
+	appImage, err := image.NewHandler(c.docker, c.keychain, "", false).InitImage(c.imageRef)
+	if err != nil || !appImage.Found() {
+		return cmd.FailErr(err, "access image to convert")
+	}
+	cmd.DefaultLogger.Warn("The BOM in the image label is deprecated; converted SBOM files should be used instead")
+	converter := &lifecycle.BOMConverter{Logger: cmd.DefaultLogger}
+	if _, err = converter.Convert(appImage, c.outputDir, c.mediaTypes); err != nil {
+		return cmd.FailErr(err, "convert BOM")
+	}
+	return nil
+}
+
+func mediaTypeForSBOMFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "cyclonedx", "cdx", buildpack.MediaTypeCycloneDX:
+		return buildpack.MediaTypeCycloneDX, nil
+	case "spdx", buildpack.MediaTypeSPDX:
+		return buildpack.MediaTypeSPDX, nil
+	default:
+		return "", fmt.Errorf("unsupported SBOM format '%s', must be one of: cyclonedx, spdx", format)
+	}
+}
@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/buildpacks/lifecycle/image"
 	"github.com/buildpacks/lifecycle/platform/files"
 
+	"github.com/buildpacks/imgutil"
 	"github.com/docker/docker/client"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/pkg/errors"
@@ -15,6 +18,7 @@ import (
 	"github.com/buildpacks/lifecycle"
 	"github.com/buildpacks/lifecycle/auth"
 	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/cache"
 	"github.com/buildpacks/lifecycle/cmd"
 	"github.com/buildpacks/lifecycle/cmd/lifecycle/cli"
 	"github.com/buildpacks/lifecycle/platform"
@@ -26,14 +30,72 @@ type createCmd struct {
 
 	docker   client.CommonAPIClient // construct if necessary before dropping privileges
 	keychain authn.Keychain         // construct if necessary before dropping privileges
+
+	apps   []*createCmd // the apps in apps.toml, if provided
+	name   string       // the name of the app in apps.toml, if built from apps.toml
+	shared *appsShared  // the work that is shared by the apps in apps.toml, if built from apps.toml
+}
+
+// appsShared is the work that is shared by the apps in apps.toml, so that it is done once rather than for each app.
+type appsShared struct {
+	cache        *cache.SharedCache   // the cache of all apps, committed once all apps are exported
+	launchCache  cache.LaunchCache    // the launch cache image, if provided
+	artifactsDir string               // the parent of the directory of the layers exported by each app
+	runImages    map[string]*runImage // by the run image reference of the apps
+}
+
+// runImage is a run image that was verified against the trust policy (if any) and initialized once for the apps in apps.toml.
+type runImage struct {
+	image      imgutil.Image // the image that is analyzed
+	identifier string        // the reference of the image by digest (or its ID in a daemon), from which each app image is created
 }
 
 // DefineFlags defines the flags that are considered valid and reads their values (if provided).
 func (c *createCmd) DefineFlags() {
 	if c.PlatformAPI.AtLeast("0.12") {
+		cli.FlagAnalyzedPath(&c.AnalyzedPath)
+		cli.FlagAnonymousFallback(&c.AnonymousFallback)
+		cli.FlagAppSourceDir(&c.AppSourceDir)
+		cli.FlagAppsPath(&c.AppsPath)
+		cli.FlagAppSymlinks(&c.AppSymlinks)
+		cli.FlagTrustPolicyPath(&c.TrustPolicyPath)
+		cli.FlagAttachAttestations(&c.AttachAttestations)
+		cli.FlagBuildLogPath(&c.BuildLogPath)
+		cli.FlagCacheLockTimeout(&c.CacheLockTimeout)
+		cli.FlagConstraintsPath(&c.ConstraintsPath)
+		cli.FlagCreateWorkingDirs(&c.CreateWorkingDirs)
+		cli.FlagDebugImage(&c.DebugImageRef)
+		cli.FlagDedupeSBOM(&c.DedupeSBOM)
+		cli.FlagDigestAlgorithm(&c.DigestAlgorithm)
+		cli.FlagExplainEnv(&c.ExplainEnv)
+		cli.FlagGroupPath(&c.GroupPath)
+		cli.FlagLayerCompression(&c.LayerCompression)
 		cli.FlagLayoutDir(&c.LayoutDir)
+		cli.FlagLenientExtraction(&c.LenientExtraction)
+		cli.FlagMergedSBOMPath(&c.MergedSBOMPath)
+		cli.FlagNetworkBuildpacks(&c.NetworkBuildpacks)
+		cli.FlagNoDigestCache(&c.NoDigestCache)
+		cli.FlagNormalizeOwnership(&c.NormalizeOwnership)
+		cli.FlagPlanPath(&c.PlanPath)
+		cli.FlagPreserveXattrs(&c.PreserveXattrs)
 		cli.FlagUseLayout(&c.UseLayout)
+		cli.FlagProcessTypeFallback(&c.DefaultProcessTypeFallback)
+		cli.FlagProjectDescriptorPath(&c.ProjectDescriptorPath)
+		cli.FlagPruneLaunchSBOM(&c.PruneLaunchSBOM)
 		cli.FlagRunPath(&c.RunPath)
+		cli.FlagSBOMCompression(&c.SBOMCompression)
+		cli.FlagSBOMPolicyPath(&c.SBOMPolicyPath)
+		cli.FlagSBOMValidation(&c.SBOMValidation)
+		cli.FlagScanner(&c.Scanner)
+		cli.FlagScannerEnforce(&c.ScannerEnforce)
+		cli.FlagSkipAnalyze(&c.SkipAnalyze)
+		cli.FlagSkipBuild(&c.SkipBuild)
+		cli.FlagSkipDetect(&c.SkipDetect)
+		cli.FlagSourceSBOMPath(&c.SourceSBOMPath)
+		cli.FlagSquashBuildpacks(&c.SquashBuildpacks)
+		cli.FlagSquashLayersBelow(&c.SquashLayersBelow)
+		cli.FlagStripSetuid(&c.StripSetuid)
+		cli.FlagUmask(&c.Umask)
 	}
 	if c.PlatformAPI.AtLeast("0.11") {
 		cli.FlagBuildConfigDir(&c.BuildConfigDir)
@@ -45,6 +107,7 @@ func (c *createCmd) DefineFlags() {
 	cli.FlagCacheImage(&c.CacheImageRef)
 	cli.FlagGID(&c.GID)
 	cli.FlagLaunchCacheDir(&c.LaunchCacheDir)
+	cli.FlagLaunchCacheImage(&c.LaunchCacheImageRef)
 	cli.FlagLauncherPath(&c.LauncherPath)
 	cli.FlagLayersDir(&c.LayersDir)
 	cli.FlagOrderPath(&c.OrderPath)
@@ -63,11 +126,14 @@ func (c *createCmd) DefineFlags() {
 
 // Args validates arguments and flags, and fills in default values.
 func (c *createCmd) Args(nargs int, args []string) error {
+	if c.AppsPath != "" {
+		return c.appsArgs(nargs)
+	}
 	if nargs != 1 {
 		return cmd.FailErrCode(fmt.Errorf("received %d arguments, but expected 1", nargs), cmd.CodeForInvalidArgs, "parse arguments")
 	}
 	c.OutputImageRef = args[0]
-	if err := platform.ResolveInputs(platform.Create, c.LifecycleInputs, cmd.DefaultLogger); err != nil {
+	if err := platform.VerifyInputs(platform.Create, c.LifecycleInputs, cmd.DefaultLogger); err != nil {
 		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
 	}
 	if c.UseLayout {
@@ -78,14 +144,59 @@ func (c *createCmd) Args(nargs int, args []string) error {
 	return nil
 }
 
+// appsArgs validates flags and resolves the inputs of each app in apps.toml.
+// The inputs of an app are the inputs of the creator, with the layers directory, the app directory and the images of the app.
+func (c *createCmd) appsArgs(nargs int) error {
+	if nargs != 0 {
+		return cmd.FailErrCode(fmt.Errorf("received %d arguments, but expected none when building the apps in %s", nargs, c.AppsPath), cmd.CodeForInvalidArgs, "parse arguments")
+	}
+	unresolved := *c.LifecycleInputs // placeholders are resolved again for each app, with the layers directory of the app
+	if err := platform.VerifyInputs(platform.Create, c.LifecycleInputs, cmd.DefaultLogger); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
+	}
+	apps, err := files.ReadApps(c.AppsPath)
+	if err != nil {
+		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "read apps")
+	}
+	for _, app := range apps.Apps {
+		inputs := unresolved
+		inputs.AppsPath = ""
+		inputs.AppSourceDir = "" // the source of all apps is copied once
+		inputs.AppDir = filepath.Join(c.AppDir, filepath.FromSlash(app.Path))
+		inputs.LayersDir = filepath.Join(c.LayersDir, "apps", app.Name)
+		inputs.OutputImageRef = app.Image
+		inputs.AdditionalTags = app.Tags
+		inputs.PreviousImageRef = app.PreviousImage
+		if err = platform.ResolveInputs(platform.Create, &inputs, cmd.DefaultLogger); err != nil {
+			return cmd.FailErrCode(fmt.Errorf("app '%s': %w", app.Name, err), cmd.CodeForInvalidArgs, "resolve inputs")
+		}
+		c.apps = append(c.apps, &createCmd{Platform: &platform.Platform{LifecycleInputs: &inputs, Exiter: c.Exiter}, name: app.Name})
+	}
+	if c.UseLayout {
+		return platform.GuardExperimental(platform.LayoutFormat, cmd.DefaultLogger)
+	}
+	return nil
+}
+
+func (c *createCmd) registryImages() []string {
+	images := c.RegistryImages()
+	for _, app := range c.apps {
+		images = append(images, app.RegistryImages()...)
+	}
+	return images
+}
+
 func (c *createCmd) Privileges() error {
 	var err error
-	c.keychain, err = auth.DefaultKeychain(c.RegistryImages()...)
+	c.keychain, err = auth.DefaultKeychain(c.registryImages()...)
 	if err != nil {
 		return cmd.FailErr(err, "resolve keychain")
 	}
+	if c.AnonymousFallback {
+		c.keychain = auth.NewAnonymousFallbackKeychain(c.keychain)
+	}
 	if c.UseDaemon {
-		c.docker, err = priv.DockerClient()
+		c.docker, err = priv.DaemonClient()
 		if err != nil {
 			return cmd.FailErr(err, "initialize docker client")
 		}
@@ -93,6 +204,9 @@ func (c *createCmd) Privileges() error {
 	if err = priv.EnsureOwner(c.UID, c.GID, c.CacheDir, c.LaunchCacheDir, c.LayersDir); err != nil {
 		return cmd.FailErr(err, "chown volumes")
 	}
+	if err = normalizeDirs(c.LifecycleInputs); err != nil {
+		return err
+	}
 	if err = priv.RunAs(c.UID, c.GID); err != nil {
 		return cmd.FailErr(err, fmt.Sprintf("exec as user %d:%d", c.UID, c.GID))
 	}
@@ -103,13 +217,27 @@ func (c *createCmd) Privileges() error {
 }
 
 func (c *createCmd) Exec() error {
-	cacheStore, err := initCache(c.CacheImageRef, c.CacheDir, c.keychain, c.PlatformAPI.LessThan("0.13"))
+	applyUmask(c.LifecycleInputs)
+	if len(c.apps) > 0 {
+		return c.execApps()
+	}
+	cacheStore, err := initCache(c.CacheImageRef, c.CacheDir, c.keychain, c.PlatformAPI.LessThan("0.13"), c.CacheLockTimeout)
 	if err != nil {
 		return err
 	}
+	return c.create(cacheStore)
+}
+
+// create builds and exports the app, with the provided cache (if any).
+func (c *createCmd) create(cacheStore lifecycle.Cache) error {
+	var err error
 	dirStore := platform.NewDirStore(c.BuildpacksDir, "")
-	if err != nil {
-		return err
+
+	exportCmd := &exportCmd{
+		Platform: c.Platform,
+		docker:   c.docker,
+		keychain: c.keychain,
+		shared:   c.shared,
 	}
 
 	// Analyze, Detect
@@ -119,51 +247,46 @@ func (c *createCmd) Exec() error {
 		plan       files.Plan
 	)
 	if c.PlatformAPI.AtLeast("0.7") {
-		cmd.DefaultLogger.Phase("ANALYZING")
-		analyzerFactory := lifecycle.NewAnalyzerFactory(
-			c.PlatformAPI,
-			&cmd.BuildpackAPIVerifier{},
-			NewCacheHandler(c.keychain),
-			lifecycle.NewConfigHandler(),
-			image.NewHandler(c.docker, c.keychain, c.LayoutDir, c.UseLayout),
-			NewRegistryHandler(c.keychain),
-		)
-		analyzer, err := analyzerFactory.NewAnalyzer(
-			c.AdditionalTags,
-			c.CacheImageRef,
-			c.LaunchCacheDir,
-			c.LayersDir,
-			"",
-			buildpack.Group{},
-			"",
-			c.OutputImageRef,
-			c.PreviousImageRef,
-			c.RunImageRef,
-			c.SkipLayers,
-			cmd.DefaultLogger,
-		)
-		if err != nil {
-			return unwrapErrorFailWithMessage(err, "initialize analyzer")
-		}
-		analyzedMD, err = analyzer.Analyze()
-		if err != nil {
+		if analyzedMD, err = c.analyze(); err != nil {
 			return err
 		}
+		// fetch the run image and the previous image while detecting and building
+		exportCmd.prefetchAppImage(analyzedMD)
 
-		cmd.DefaultLogger.Phase("DETECTING")
-		detectorFactory := lifecycle.NewDetectorFactory(
-			c.PlatformAPI,
-			&cmd.BuildpackAPIVerifier{},
-			lifecycle.NewConfigHandler(),
-			dirStore,
-		)
-		detector, err := detectorFactory.NewDetector(analyzedMD, c.AppDir, c.BuildConfigDir, c.OrderPath, c.PlatformDir, cmd.DefaultLogger)
-		if err != nil {
-			return unwrapErrorFailWithMessage(err, "initialize detector")
+		if !c.SkipDetect {
+			cmd.DefaultLogger.Phase("DETECTING")
 		}
-		group, plan, err = doDetect(detector, c.Platform)
-		if err != nil {
-			return err // pass through error
+		if c.AppSourceDir != "" {
+			if err = lifecycle.CopyAppSource(c.AppSourceDir, c.AppDir, cmd.DefaultLogger); err != nil {
+				return cmd.FailErr(err, "copy app source")
+			}
+		}
+		if c.SkipDetect {
+			cmd.DefaultLogger.Infof("Skipping detection, reading %s and %s", c.GroupPath, c.PlanPath)
+			if group, plan, err = (&buildCmd{Platform: c.Platform}).readData(); err != nil {
+				return err
+			}
+			if err = verifyBuildpackApis(group); err != nil {
+				return err
+			}
+		} else {
+			detectorFactory := lifecycle.NewDetectorFactory(
+				c.PlatformAPI,
+				&cmd.BuildpackAPIVerifier{},
+				lifecycle.NewConfigHandler(),
+				dirStore,
+			)
+			detector, err := detectorFactory.NewDetector(analyzedMD, c.AppDir, c.BuildConfigDir, c.OrderPath, c.PlatformDir, cmd.DefaultLogger)
+			if err != nil {
+				return unwrapErrorFailWithMessage(err, "initialize detector")
+			}
+			if err = applyProjectDescriptor(detectorFactory, detector, c.Platform); err != nil {
+				return err
+			}
+			group, plan, err = doDetect(detector, c.Platform)
+			if err != nil {
+				return err // pass through error
+			}
 		}
 	} else {
 		cmd.DefaultLogger.Phase("DETECTING")
@@ -177,6 +300,9 @@ func (c *createCmd) Exec() error {
 		if err != nil {
 			return unwrapErrorFailWithMessage(err, "initialize detector")
 		}
+		if err = applyProjectDescriptor(detectorFactory, detector, c.Platform); err != nil {
+			return err
+		}
 		group, plan, err = doDetect(detector, c.Platform)
 		if err != nil {
 			return err // pass through error
@@ -186,9 +312,9 @@ func (c *createCmd) Exec() error {
 		analyzerFactory := lifecycle.NewAnalyzerFactory(
 			c.PlatformAPI,
 			&cmd.BuildpackAPIVerifier{},
-			NewCacheHandler(c.keychain),
+			NewCacheHandler(c.keychain, c.CacheLockTimeout),
 			lifecycle.NewConfigHandler(),
-			image.NewHandler(c.docker, c.keychain, c.LayoutDir, c.UseLayout),
+			c.imageHandler(),
 			NewRegistryHandler(c.keychain),
 		)
 		analyzer, err := analyzerFactory.NewAnalyzer(
@@ -208,14 +334,16 @@ func (c *createCmd) Exec() error {
 		if err != nil {
 			return unwrapErrorFailWithMessage(err, "initialize analyzer")
 		}
-		analyzedMD, err = analyzer.Analyze()
+		analyzedMD, err = analyzer.AnalyzeContext(context.Background())
 		if err != nil {
 			return err
 		}
+		exportCmd.prefetchAppImage(analyzedMD)
 	}
 
 	// Restore
-	if !c.SkipLayers || c.PlatformAPI.AtLeast("0.10") {
+	// the layers of a skipped build are exported as they are, so nothing is restored over them
+	if (!c.SkipLayers || c.PlatformAPI.AtLeast("0.10")) && !c.SkipBuild {
 		cmd.DefaultLogger.Phase("RESTORING")
 		restoreCmd := &restoreCmd{
 			Platform: c.Platform,
@@ -228,25 +356,175 @@ func (c *createCmd) Exec() error {
 	}
 
 	// Build
-	stopPinging := startPinging(c.docker) // send pings to docker daemon while building to prevent connection closure
-	cmd.DefaultLogger.Phase("BUILDING")
-	buildCmd := &buildCmd{Platform: c.Platform}
-	err = buildCmd.build(group, plan, analyzedMD)
-	stopPinging()
-	if err != nil {
-		return err
+	if c.SkipBuild {
+		cmd.DefaultLogger.Infof("Skipping build, exporting the layers in %s", c.LayersDir)
+	} else {
+		stopPinging := startPinging(c.docker) // send pings to docker daemon while building to prevent connection closure
+		cmd.DefaultLogger.Phase("BUILDING")
+		buildCmd := &buildCmd{Platform: c.Platform}
+		err = buildCmd.build(group, plan, analyzedMD)
+		stopPinging()
+		if err != nil {
+			return err
+		}
 	}
 
 	// Export
 	cmd.DefaultLogger.Phase("EXPORTING")
-	exportCmd := &exportCmd{
-		Platform: c.Platform,
-		docker:   c.docker,
-		keychain: c.keychain,
-	}
 	return exportCmd.export(group, cacheStore, analyzedMD)
 }
 
+// execApps builds and exports each app in apps.toml, sharing the credentials, the daemon client, the caches and the run image.
+// The cache is one store, in which the metadata of each app is kept under its name (see cache.SharedCache);
+// it is committed once all apps are exported, so a layer that several apps cache is stored once.
+// Each run image is verified and initialized once: each app analyzes it without fetching it again,
+// and each app image is created from its digest, so that all apps are exported on the same run image.
+// The previous image of each app is still analyzed on its own.
+// It stops at the first app that fails, without committing the cache; the report of each app is written to its layers directory.
+func (c *createCmd) execApps() error {
+	if c.AppSourceDir != "" {
+		if err := lifecycle.CopyAppSource(c.AppSourceDir, c.AppDir, cmd.DefaultLogger); err != nil {
+			return cmd.FailErr(err, "copy app source")
+		}
+	}
+	cacheStore, err := initCache(c.CacheImageRef, c.CacheDir, c.keychain, c.PlatformAPI.LessThan("0.13"), c.CacheLockTimeout)
+	if err != nil {
+		return err
+	}
+	// a cache image reads the layers that are added to it when it is saved, so the layers exported by each app are kept until then
+	artifactsDir, err := os.MkdirTemp("", "lifecycle.exporter.layer")
+	if err != nil {
+		return cmd.FailErr(err, "create temp directory")
+	}
+	defer os.RemoveAll(artifactsDir)
+	c.shared = &appsShared{artifactsDir: artifactsDir, runImages: map[string]*runImage{}}
+	if cacheStore != nil {
+		c.shared.cache = cache.NewSharedCache(cacheStore)
+	}
+	if c.UseDaemon && c.LaunchCacheDir == "" && c.LaunchCacheImageRef != "" {
+		c.shared.launchCache = cache.NewRegistryLaunchCache(c.LaunchCacheImageRef, c.keychain, filepath.Join(artifactsDir, "launch-cache"), cmd.DefaultLogger)
+	}
+
+	for i, app := range c.apps {
+		cmd.DefaultLogger.Infof("Building app %d of %d: %s from %s", i+1, len(c.apps), app.OutputImageRef, app.AppDir)
+		if err = os.MkdirAll(app.LayersDir, os.ModePerm); err != nil {
+			return cmd.FailErr(err, "create layers directory")
+		}
+		if err = c.initRunImage(app.RunImageRef); err != nil {
+			return err
+		}
+		app.docker, app.keychain, app.shared = c.docker, c.keychain, c.shared
+		var appCache lifecycle.Cache
+		if c.shared.cache != nil {
+			appCache = c.shared.cache.ForApp(app.name)
+		}
+		if err = app.create(appCache); err != nil {
+			cmd.DefaultLogger.Errorf("Failed to build app %s", app.OutputImageRef)
+			return err
+		}
+	}
+
+	if c.shared.cache != nil {
+		if err = c.shared.cache.Commit(); err != nil {
+			cmd.DefaultLogger.Warnf("Failed to export cache: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// initRunImage verifies and initializes the run image of an app, unless it was already initialized for another app.
+// A run image that cannot be resolved (e.g., that is not found) is not shared, so that the analyzer of each app reports it.
+func (c *createCmd) initRunImage(imageRef string) error {
+	if _, ok := c.shared.runImages[imageRef]; ok || imageRef == "" {
+		return nil
+	}
+	verifiedRef, err := verifyBaseImage(c.TrustPolicyPath, imageRef, c.keychain)
+	if err != nil {
+		return err
+	}
+	img, err := image.NewHandler(c.docker, c.keychain, c.LayoutDir, c.UseLayout).InitImage(verifiedRef)
+	if err != nil || img == nil || !img.Found() {
+		return nil
+	}
+	identifier, err := img.Identifier()
+	if err != nil {
+		return nil
+	}
+	c.shared.runImages[imageRef] = &runImage{image: img, identifier: identifier.String()}
+	return nil
+}
+
+// imageHandler returns the handler of the images to analyze, which returns the run image that is shared by the apps in apps.toml (if any)
+// rather than initializing it again.
+func (c *createCmd) imageHandler() image.Handler {
+	handler := image.NewHandler(c.docker, c.keychain, c.LayoutDir, c.UseLayout)
+	if c.shared == nil {
+		return handler
+	}
+	return &sharedImageHandler{Handler: handler, runImages: c.shared.runImages}
+}
+
+type sharedImageHandler struct {
+	image.Handler
+	runImages map[string]*runImage
+}
+
+func (h *sharedImageHandler) InitImage(imageRef string) (imgutil.Image, error) {
+	if shared, ok := h.runImages[imageRef]; ok {
+		return shared.image, nil
+	}
+	return h.Handler.InitImage(imageRef)
+}
+
+// analyze runs the analyzer, or reads analyzed.toml if analysis is skipped.
+func (c *createCmd) analyze() (files.Analyzed, error) {
+	if c.SkipAnalyze {
+		cmd.DefaultLogger.Infof("Skipping analysis, reading %s", c.AnalyzedPath)
+		// unlike in the phases that follow the analyzer, analyzed.toml is required
+		if _, err := os.Stat(c.AnalyzedPath); err != nil {
+			return files.Analyzed{}, cmd.FailErr(err, "read analyzed metadata")
+		}
+		analyzedMD, err := files.ReadAnalyzed(c.AnalyzedPath, cmd.DefaultLogger)
+		if err != nil {
+			return files.Analyzed{}, unwrapErrorFailWithMessage(err, "reading analyzed.toml")
+		}
+		return analyzedMD, nil
+	}
+
+	cmd.DefaultLogger.Phase("ANALYZING")
+	if c.shared == nil || c.shared.runImages[c.RunImageRef] == nil {
+		if _, err := verifyBaseImage(c.TrustPolicyPath, c.RunImageRef, c.keychain); err != nil {
+			return files.Analyzed{}, err
+		}
+	}
+	analyzerFactory := lifecycle.NewAnalyzerFactory(
+		c.PlatformAPI,
+		&cmd.BuildpackAPIVerifier{},
+		NewCacheHandler(c.keychain, c.CacheLockTimeout),
+		lifecycle.NewConfigHandler(),
+		c.imageHandler(),
+		NewRegistryHandler(c.keychain),
+	)
+	analyzer, err := analyzerFactory.NewAnalyzer(
+		c.AdditionalTags,
+		c.CacheImageRef,
+		c.LaunchCacheDir,
+		c.LayersDir,
+		"",
+		buildpack.Group{},
+		"",
+		c.OutputImageRef,
+		c.PreviousImageRef,
+		c.RunImageRef,
+		c.SkipLayers,
+		cmd.DefaultLogger,
+	)
+	if err != nil {
+		return files.Analyzed{}, unwrapErrorFailWithMessage(err, "initialize analyzer")
+	}
+	return analyzer.AnalyzeContext(context.Background())
+}
+
 func startPinging(docker client.CommonAPIClient) (stopPinging func()) {
 	pingCtx, cancelPing := context.WithCancel(context.Background())
 	pingDoneChan := make(chan struct{})
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 
 	"github.com/buildpacks/lifecycle"
@@ -20,6 +21,11 @@ type detectCmd struct {
 // DefineFlags defines the flags that are considered valid and reads their values (if provided).
 func (d *detectCmd) DefineFlags() {
 	if d.PlatformAPI.AtLeast("0.12") {
+		cli.FlagAppSourceDir(&d.AppSourceDir)
+		cli.FlagAppSymlinks(&d.AppSymlinks)
+		cli.FlagConstraintsPath(&d.ConstraintsPath)
+		cli.FlagExplainEnv(&d.ExplainEnv)
+		cli.FlagProjectDescriptorPath(&d.ProjectDescriptorPath)
 		cli.FlagRunPath(&d.RunPath)
 	}
 	if d.PlatformAPI.AtLeast("0.11") {
@@ -28,6 +34,7 @@ func (d *detectCmd) DefineFlags() {
 	if d.PlatformAPI.AtLeast("0.10") {
 		cli.FlagAnalyzedPath(&d.AnalyzedPath)
 		cli.FlagExtensionsDir(&d.ExtensionsDir)
+		cli.FlagGenerateDryRunDir(&d.GenerateDryRunDir)
 		cli.FlagGeneratedDir(&d.GeneratedDir)
 	}
 	cli.FlagAppDir(&d.AppDir)
@@ -44,7 +51,7 @@ func (d *detectCmd) Args(nargs int, _ []string) error {
 	if nargs != 0 {
 		return cmd.FailErrCode(errors.New("received unexpected arguments"), cmd.CodeForInvalidArgs, "parse arguments")
 	}
-	if err := platform.ResolveInputs(platform.Detect, d.LifecycleInputs, cmd.DefaultLogger); err != nil {
+	if err := platform.VerifyInputs(platform.Detect, d.LifecycleInputs, cmd.DefaultLogger); err != nil {
 		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
 	}
 	return nil
@@ -59,6 +66,11 @@ func (d *detectCmd) Privileges() error {
 }
 
 func (d *detectCmd) Exec() error {
+	if d.AppSourceDir != "" {
+		if err := lifecycle.CopyAppSource(d.AppSourceDir, d.AppDir, cmd.DefaultLogger); err != nil {
+			return cmd.FailErr(err, "copy app source")
+		}
+	}
 	dirStore := platform.NewDirStore(d.BuildpacksDir, d.ExtensionsDir)
 	detectorFactory := lifecycle.NewDetectorFactory(
 		d.PlatformAPI,
@@ -81,6 +93,9 @@ func (d *detectCmd) Exec() error {
 	if err != nil {
 		return unwrapErrorFailWithMessage(err, "initialize detector")
 	}
+	if err = applyProjectDescriptor(detectorFactory, detector, d.Platform); err != nil {
+		return err
+	}
 	if detector.HasExtensions {
 		if err = platform.GuardExperimental(platform.FeatureDockerfiles, cmd.DefaultLogger); err != nil {
 			return err
@@ -90,6 +105,9 @@ func (d *detectCmd) Exec() error {
 	if err != nil {
 		return err // pass through error
 	}
+	if d.GenerateDryRunDir != "" {
+		return d.generateDryRun(group, plan, dirStore)
+	}
 	if group.HasExtensions() {
 		generatorFactory := lifecycle.NewGeneratorFactory(
 			&cmd.BuildpackAPIVerifier{},
@@ -114,7 +132,7 @@ func (d *detectCmd) Exec() error {
 			return unwrapErrorFailWithMessage(err, "initialize generator")
 		}
 		var result lifecycle.GenerateResult
-		result, err = generator.Generate()
+		result, err = generator.GenerateContext(context.Background())
 		if err != nil {
 			return d.unwrapGenerateFail(err)
 		}
@@ -130,6 +148,65 @@ func (d *detectCmd) Exec() error {
 	return d.writeDetectData(group, plan)
 }
 
+// generateDryRun runs only the generate phase for the provided group,
+// writing rendered Dockerfiles and their args to the dry-run directory.
+func (d *detectCmd) generateDryRun(group buildpack.Group, plan files.Plan, dirStore *platform.DirStore) error {
+	if !group.HasExtensions() {
+		cmd.DefaultLogger.Info("No extensions passed detection, nothing to generate")
+		return nil
+	}
+	generator, err := lifecycle.NewGeneratorFactory(
+		&cmd.BuildpackAPIVerifier{},
+		lifecycle.Config,
+		dirStore,
+	).NewGenerator(
+		d.AnalyzedPath,
+		d.AppDir,
+		d.BuildConfigDir,
+		group.GroupExtensions,
+		d.GenerateDryRunDir,
+		plan,
+		d.PlatformAPI,
+		d.PlatformDir,
+		d.RunPath,
+		cmd.Stdout, cmd.Stderr,
+		cmd.DefaultLogger,
+	)
+	if err != nil {
+		return unwrapErrorFailWithMessage(err, "initialize generator")
+	}
+	generator.DryRun = true
+	if _, err = generator.GenerateContext(context.Background()); err != nil {
+		return d.unwrapGenerateFail(err)
+	}
+	cmd.DefaultLogger.Infof("Wrote rendered Dockerfiles to %s", d.GenerateDryRunDir)
+	return nil
+}
+
+// applyProjectDescriptor applies the project descriptor (if provided) to the detector.
+func applyProjectDescriptor(detectorFactory *lifecycle.DetectorFactory, detector *lifecycle.Detector, p *platform.Platform) error {
+	descriptor, err := readProjectDescriptor(p)
+	if err != nil {
+		return err
+	}
+	if err = detectorFactory.ApplyProjectDescriptor(detector, descriptor, cmd.DefaultLogger); err != nil {
+		return unwrapErrorFailWithMessage(err, "apply project descriptor")
+	}
+	return nil
+}
+
+// readProjectDescriptor reads the project descriptor, which is only supported for Platform API 0.12 and above.
+func readProjectDescriptor(p *platform.Platform) (files.ProjectDescriptor, error) {
+	if p.PlatformAPI.LessThan("0.12") {
+		return files.ProjectDescriptor{}, nil
+	}
+	descriptor, err := files.ReadProjectDescriptor(p.ProjectDescriptorPath, cmd.DefaultLogger)
+	if err != nil {
+		return files.ProjectDescriptor{}, cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "read project descriptor")
+	}
+	return descriptor, nil
+}
+
 func unwrapErrorFailWithMessage(err error, msg string) error {
 	errorFail, ok := err.(*cmd.ErrorFail)
 	if ok {
@@ -148,7 +225,16 @@ func (d *detectCmd) unwrapGenerateFail(err error) error {
 }
 
 func doDetect(detector *lifecycle.Detector, p *platform.Platform) (buildpack.Group, files.Plan, error) {
-	group, plan, err := detector.Detect()
+	detector.AppSymlinks = p.AppSymlinks
+	detector.ExplainEnv = p.ExplainEnv
+	if p.ConstraintsPath != "" {
+		constraints, err := files.ReadConstraints(p.ConstraintsPath)
+		if err != nil {
+			return buildpack.Group{}, files.Plan{}, cmd.FailErr(err, "read constraints")
+		}
+		detector.Constraints = constraints
+	}
+	group, plan, err := detector.DetectContext(context.Background())
 	if err != nil {
 		switch err := err.(type) {
 		case *buildpack.Error:
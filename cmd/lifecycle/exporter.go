@@ -1,13 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"time"
 
-	"github.com/BurntSushi/toml"
 	"github.com/buildpacks/imgutil"
 	"github.com/buildpacks/imgutil/layout"
 	"github.com/buildpacks/imgutil/local"
@@ -28,7 +28,7 @@ import (
 	"github.com/buildpacks/lifecycle/cmd/lifecycle/cli"
 	"github.com/buildpacks/lifecycle/image"
 	"github.com/buildpacks/lifecycle/internal/encoding"
-	"github.com/buildpacks/lifecycle/layers"
+	"github.com/buildpacks/lifecycle/internal/network"
 	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/platform/files"
 	"github.com/buildpacks/lifecycle/priv"
@@ -41,18 +41,48 @@ type exportCmd struct {
 	keychain authn.Keychain         // construct if necessary before dropping privileges
 
 	persistedData exportData
+	prefetched    chan appImageResult // the app image initialized by prefetchAppImage, if any
+	shared        *appsShared         // the work that is shared by the apps in apps.toml, if exported by the creator
 }
 
 type exportData struct {
 	analyzedMD files.Analyzed
 }
 
+type appImageResult struct {
+	appImage   imgutil.Image
+	runImageID string
+	err        error
+}
+
 // DefineFlags defines the flags that are considered valid and reads their values (if provided).
 func (e *exportCmd) DefineFlags() {
 	if e.PlatformAPI.AtLeast("0.12") {
+		cli.FlagAppSymlinks(&e.AppSymlinks)
+		cli.FlagAttachAttestations(&e.AttachAttestations)
+		cli.FlagCacheLockTimeout(&e.CacheLockTimeout)
+		cli.FlagCreateWorkingDirs(&e.CreateWorkingDirs)
+		cli.FlagDebugImage(&e.DebugImageRef)
+		cli.FlagDedupeSBOM(&e.DedupeSBOM)
+		cli.FlagDigestAlgorithm(&e.DigestAlgorithm)
 		cli.FlagExtendedDir(&e.ExtendedDir)
+		cli.FlagLayerCompression(&e.LayerCompression)
 		cli.FlagLayoutDir(&e.LayoutDir)
+		cli.FlagMergedSBOMPath(&e.MergedSBOMPath)
+		cli.FlagNoDigestCache(&e.NoDigestCache)
+		cli.FlagPreserveXattrs(&e.PreserveXattrs)
+		cli.FlagProcessTypeFallback(&e.DefaultProcessTypeFallback)
+		cli.FlagProjectDescriptorPath(&e.ProjectDescriptorPath)
+		cli.FlagPruneLaunchSBOM(&e.PruneLaunchSBOM)
 		cli.FlagRunPath(&e.RunPath)
+		cli.FlagSBOMCompression(&e.SBOMCompression)
+		cli.FlagSBOMPolicyPath(&e.SBOMPolicyPath)
+		cli.FlagScanner(&e.Scanner)
+		cli.FlagScannerEnforce(&e.ScannerEnforce)
+		cli.FlagSourceSBOMPath(&e.SourceSBOMPath)
+		cli.FlagTrustPolicyPath(&e.TrustPolicyPath)
+		cli.FlagSquashBuildpacks(&e.SquashBuildpacks)
+		cli.FlagSquashLayersBelow(&e.SquashLayersBelow)
 		cli.FlagUseLayout(&e.UseLayout)
 	} else {
 		cli.FlagStackPath(&e.StackPath)
@@ -67,6 +97,7 @@ func (e *exportCmd) DefineFlags() {
 	cli.FlagGID(&e.GID)
 	cli.FlagGroupPath(&e.GroupPath)
 	cli.FlagLaunchCacheDir(&e.LaunchCacheDir)
+	cli.FlagLaunchCacheImage(&e.LaunchCacheImageRef)
 	cli.FlagLauncherPath(&e.LauncherPath)
 	cli.FlagLayersDir(&e.LayersDir)
 	cli.FlagProcessType(&e.DefaultProcessType)
@@ -86,7 +117,7 @@ func (e *exportCmd) Args(nargs int, args []string) error {
 	}
 	e.OutputImageRef = args[0]
 	e.AdditionalTags = args[1:]
-	if err := platform.ResolveInputs(platform.Export, e.LifecycleInputs, cmd.DefaultLogger); err != nil {
+	if err := platform.VerifyInputs(platform.Export, e.LifecycleInputs, cmd.DefaultLogger); err != nil {
 		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
 	}
 	// read analyzed metadata for use in later stages
@@ -109,9 +140,12 @@ func (e *exportCmd) Privileges() error {
 	if err != nil {
 		return cmd.FailErr(err, "resolve keychain")
 	}
+	if registries := e.persistedData.analyzedMD.AnonymousRegistries; len(registries) > 0 {
+		e.keychain = auth.NewAnonymousFallbackKeychain(e.keychain, registries...)
+	}
 	if e.UseDaemon {
 		var err error
-		e.docker, err = priv.DockerClient()
+		e.docker, err = priv.DaemonClient()
 		if err != nil {
 			return cmd.FailErr(err, "initialize docker client")
 		}
@@ -133,7 +167,7 @@ func (e *exportCmd) Exec() error {
 	if err = verifyBuildpackApis(group); err != nil {
 		return err
 	}
-	cacheStore, err := initCache(e.CacheImageRef, e.CacheDir, e.keychain, e.PlatformAPI.LessThan("0.13"))
+	cacheStore, err := initCache(e.CacheImageRef, e.CacheDir, e.keychain, e.PlatformAPI.LessThan("0.13"), e.CacheLockTimeout)
 	if err != nil {
 		return err
 	}
@@ -156,32 +190,22 @@ func (e *exportCmd) registryImages() []string {
 }
 
 func (e *exportCmd) export(group buildpack.Group, cacheStore lifecycle.Cache, analyzedMD files.Analyzed) error {
-	artifactsDir, err := os.MkdirTemp("", "lifecycle.exporter.layer")
+	var parentDir string
+	if e.shared != nil {
+		parentDir = e.shared.artifactsDir // removed once the shared cache is committed
+	}
+	artifactsDir, err := os.MkdirTemp(parentDir, "lifecycle.exporter.layer")
 
 	if err != nil {
 		return cmd.FailErr(err, "create temp directory")
 	}
-	defer os.RemoveAll(artifactsDir)
-
-	var projectMD files.ProjectMetadata
-	_, err = toml.DecodeFile(e.ProjectMetadataPath, &projectMD)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return err
-		}
-		cmd.DefaultLogger.Debugf("no project metadata found at path '%s', project metadata will not be exported\n", e.ProjectMetadataPath)
+	if e.shared == nil {
+		defer os.RemoveAll(artifactsDir)
 	}
 
-	exporter := &lifecycle.Exporter{
-		Buildpacks: group.Group,
-		LayerFactory: &layers.Factory{
-			ArtifactsDir: artifactsDir,
-			UID:          e.UID,
-			GID:          e.GID,
-			Logger:       cmd.DefaultLogger,
-		},
-		Logger:      cmd.DefaultLogger,
-		PlatformAPI: e.PlatformAPI,
+	exporter, err := lifecycle.NewExporterFromInputs(e.LifecycleInputs, group, artifactsDir, cmd.DefaultLogger)
+	if err != nil {
+		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "initialize exporter")
 	}
 
 	var (
@@ -192,32 +216,37 @@ func (e *exportCmd) export(group buildpack.Group, cacheStore lifecycle.Cache, an
 	case e.UseLayout:
 		appImage, runImageID, err = e.initLayoutAppImage(analyzedMD)
 	case e.UseDaemon:
-		appImage, runImageID, err = e.initDaemonAppImage(analyzedMD)
+		appImage, runImageID, err = e.initDaemonAppImage(analyzedMD, filepath.Join(artifactsDir, "launch-cache"))
+	case e.prefetched != nil:
+		result := <-e.prefetched
+		appImage, runImageID, err = result.appImage, result.runImageID, result.err
 	default:
 		appImage, runImageID, err = e.initRemoteAppImage(analyzedMD)
 	}
 	if err != nil {
 		return err
 	}
+	if !e.UseLayout && !e.UseDaemon && analyzedMD.PreviousImageRef() != "" {
+		cmd.DefaultLogger.Infof("Reusing layers from image '%s'", analyzedMD.PreviousImageRef())
+	}
+
+	var debugImage imgutil.Image
+	if e.DebugImageRef != "" {
+		if debugImage, err = e.initDebugImage(appImage); err != nil {
+			return err
+		}
+	}
 
-	runImageForExport, err := platform.GetRunImageForExport(*e.LifecycleInputs)
+	opts, err := lifecycle.NewExportOptions(e.LifecycleInputs, e.keychain, cmd.DefaultLogger)
 	if err != nil {
 		return err
 	}
-
-	report, err := exporter.Export(lifecycle.ExportOptions{
-		AdditionalNames:    e.AdditionalTags,
-		AppDir:             e.AppDir,
-		DefaultProcessType: e.DefaultProcessType,
-		ExtendedDir:        e.ExtendedDir,
-		LauncherConfig:     launcherConfig(e.LauncherPath, e.LauncherSBOMDir),
-		LayersDir:          e.LayersDir,
-		OrigMetadata:       analyzedMD.LayersMetadata,
-		Project:            projectMD,
-		RunImageRef:        runImageID,
-		RunImageForExport:  runImageForExport,
-		WorkingImage:       appImage,
-	})
+	opts.DebugImage = debugImage
+	opts.LauncherConfig = launcherConfig(e.LauncherPath, e.LauncherSBOMDir)
+	opts.OrigMetadata = analyzedMD.LayersMetadata
+	opts.RunImageRef = runImageID
+	opts.WorkingImage = appImage
+	report, err := exporter.ExportContext(context.Background(), opts)
 	if err != nil {
 		return cmd.FailErrCode(err, e.CodeFor(platform.ExportError), "export")
 	}
@@ -226,14 +255,30 @@ func (e *exportCmd) export(group buildpack.Group, cacheStore lifecycle.Cache, an
 	}
 
 	if cacheStore != nil {
-		if cacheErr := exporter.Cache(e.LayersDir, cacheStore); cacheErr != nil {
+		if cacheErr := exporter.CacheContext(context.Background(), e.LayersDir, cacheStore); cacheErr != nil {
 			cmd.DefaultLogger.Warnf("Failed to export cache: %v\n", cacheErr)
 		}
 	}
 	return nil
 }
 
-func (e *exportCmd) initDaemonAppImage(analyzedMD files.Analyzed) (imgutil.Image, string, error) {
+// prefetchAppImage starts initializing the app image in a registry in the background, which fetches the run image and the previous image,
+// so that the creator does not wait for the network once the build completes. The app image does not depend on the build,
+// except when the run image is extended.
+func (e *exportCmd) prefetchAppImage(analyzedMD files.Analyzed) {
+	if e.UseLayout || e.UseDaemon || (analyzedMD.RunImage != nil && analyzedMD.RunImage.Extend) {
+		return
+	}
+	e.prefetched = make(chan appImageResult, 1)
+	go func() {
+		appImage, runImageID, err := e.initRemoteAppImage(analyzedMD)
+		e.prefetched <- appImageResult{appImage: appImage, runImageID: runImageID, err: err}
+	}()
+}
+
+// initDaemonAppImage initializes the app image in the daemon. If a launch cache image is provided without a launch cache directory,
+// the layers it fetches are kept in launchCacheDir.
+func (e *exportCmd) initDaemonAppImage(analyzedMD files.Analyzed, launchCacheDir string) (imgutil.Image, string, error) {
 	var opts = []local.ImageOption{
 		local.FromBaseImage(e.RunImageRef),
 	}
@@ -276,11 +321,15 @@ func (e *exportCmd) initDaemonAppImage(analyzedMD files.Analyzed) (imgutil.Image
 	}
 
 	if e.LaunchCacheDir != "" {
-		volumeCache, err := cache.NewVolumeCache(e.LaunchCacheDir)
+		volumeCache, err := cache.NewVolumeCache(e.LaunchCacheDir, cache.WithLockTimeout(e.CacheLockTimeout))
 		if err != nil {
 			return nil, "", cmd.FailErr(err, "create launch cache")
 		}
 		appImage = cache.NewCachingImage(appImage, volumeCache)
+	} else if e.shared != nil && e.shared.launchCache != nil {
+		appImage = cache.NewCachingImage(appImage, e.shared.launchCache)
+	} else if e.LaunchCacheImageRef != "" {
+		appImage = cache.NewCachingImage(appImage, cache.NewRegistryLaunchCache(e.LaunchCacheImageRef, e.keychain, launchCacheDir, cmd.DefaultLogger))
 	}
 	return appImage, runImageID.String(), nil
 }
@@ -337,8 +386,19 @@ func toNATPortSet(v1Ps map[string]struct{}) nat.PortSet {
 }
 
 func (e *exportCmd) initRemoteAppImage(analyzedMD files.Analyzed) (imgutil.Image, string, error) {
+	var (
+		runImageRef string
+		runImageID  string
+		err         error
+	)
+	if shared := e.sharedRunImage(); shared != nil {
+		// the run image was verified for all apps in apps.toml; the app image is created from its digest
+		runImageRef, runImageID = shared.identifier, shared.identifier
+	} else if runImageRef, err = verifyBaseImage(e.TrustPolicyPath, e.RunImageRef, e.keychain); err != nil {
+		return nil, "", err
+	}
 	var opts = []remote.ImageOption{
-		remote.FromBaseImage(e.RunImageRef),
+		remote.FromBaseImage(network.PullRef(runImageRef, e.keychain)),
 	}
 	if e.supportsRunImageExtension() {
 		extendedConfig, err := e.getExtendedConfig(analyzedMD.RunImage)
@@ -356,7 +416,6 @@ func (e *exportCmd) initRemoteAppImage(analyzedMD files.Analyzed) (imgutil.Image
 	}
 
 	if analyzedMD.PreviousImageRef() != "" {
-		cmd.DefaultLogger.Infof("Reusing layers from image '%s'", analyzedMD.PreviousImageRef())
 		opts = append(opts, remote.WithPreviousImage(analyzedMD.PreviousImageRef()))
 	}
 
@@ -373,15 +432,26 @@ func (e *exportCmd) initRemoteAppImage(analyzedMD files.Analyzed) (imgutil.Image
 		return nil, "", cmd.FailErr(err, "create new app image")
 	}
 
-	runImage, err := remote.NewImage(e.RunImageRef, e.keychain, remote.FromBaseImage(e.RunImageRef))
+	if runImageID != "" {
+		return appImage, runImageID, nil
+	}
+	runImage, err := remote.NewImage(e.RunImageRef, e.keychain, remote.FromBaseImage(network.PullRef(runImageRef, e.keychain)))
 	if err != nil {
 		return nil, "", cmd.FailErr(err, "access run image")
 	}
-	runImageID, err := runImage.Identifier()
+	identifier, err := runImage.Identifier()
 	if err != nil {
 		return nil, "", cmd.FailErr(err, "get run image reference")
 	}
-	return appImage, runImageID.String(), nil
+	return appImage, identifier.String(), nil
+}
+
+// sharedRunImage returns the run image that was initialized in a registry for all apps in apps.toml, if any.
+func (e *exportCmd) sharedRunImage() *runImage {
+	if e.shared == nil || e.UseDaemon || e.UseLayout {
+		return nil
+	}
+	return e.shared.runImages[e.RunImageRef]
 }
 
 func (e *exportCmd) initLayoutAppImage(analyzedMD files.Analyzed) (imgutil.Image, string, error) {
@@ -443,6 +513,59 @@ func (e *exportCmd) initLayoutAppImage(analyzedMD files.Analyzed) (imgutil.Image
 	return appImage, runImageID.String(), nil
 }
 
+// initDebugImage returns the debug image, which is saved the same way as the app image, without a base image.
+func (e *exportCmd) initDebugImage(appImage imgutil.Image) (imgutil.Image, error) {
+	imageOS, err := appImage.OS()
+	if err != nil {
+		return nil, cmd.FailErr(err, "get app image OS")
+	}
+	imageArch, err := appImage.Architecture()
+	if err != nil {
+		return nil, cmd.FailErr(err, "get app image architecture")
+	}
+	imagePlatform := imgutil.Platform{OS: imageOS, Architecture: imageArch}
+	createdAt := e.customSourceDateEpoch()
+
+	var debugImage imgutil.Image
+	switch {
+	case e.UseLayout:
+		debugImageRefPath, err := layout.ParseRefToPath(e.DebugImageRef)
+		if err != nil {
+			return nil, cmd.FailErr(err, "parsing debug image reference")
+		}
+		opts := []layout.ImageOption{layout.WithDefaultPlatform(imagePlatform)}
+		if e.supportsHistory() {
+			opts = append(opts, layout.WithHistory())
+		}
+		if !createdAt.IsZero() {
+			opts = append(opts, layout.WithCreatedAt(createdAt))
+		}
+		debugImage, err = layout.NewImage(filepath.Join(e.LayoutDir, debugImageRefPath), opts...)
+	case e.UseDaemon:
+		opts := []local.ImageOption{local.WithDefaultPlatform(imagePlatform)}
+		if e.supportsHistory() {
+			opts = append(opts, local.WithHistory())
+		}
+		if !createdAt.IsZero() {
+			opts = append(opts, local.WithCreatedAt(createdAt))
+		}
+		debugImage, err = local.NewImage(e.DebugImageRef, e.docker, opts...)
+	default:
+		opts := []remote.ImageOption{remote.WithDefaultPlatform(imagePlatform)}
+		if e.supportsHistory() {
+			opts = append(opts, remote.WithHistory())
+		}
+		if !createdAt.IsZero() {
+			opts = append(opts, remote.WithCreatedAt(createdAt))
+		}
+		debugImage, err = remote.NewImage(e.DebugImageRef, e.keychain, opts...)
+	}
+	if err != nil {
+		return nil, cmd.FailErr(err, "create new debug image")
+	}
+	return debugImage, nil
+}
+
 func launcherConfig(launcherPath, launcherSBOMDir string) lifecycle.LauncherConfig {
 	return lifecycle.LauncherConfig{
 		Path:    launcherPath,
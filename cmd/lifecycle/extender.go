@@ -3,33 +3,51 @@ package main
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/auth"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/cmd"
 	"github.com/buildpacks/lifecycle/cmd/lifecycle/cli"
+	"github.com/buildpacks/lifecycle/internal/extend"
+	"github.com/buildpacks/lifecycle/internal/extend/buildkit"
 	"github.com/buildpacks/lifecycle/internal/extend/kaniko"
 	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
 	"github.com/buildpacks/lifecycle/priv"
 )
 
 type extendCmd struct {
 	*platform.Platform
+
+	cacheImageDiffID string // the diffID of the layer pulled from the cache image, if any
+	keychain         authn.Keychain
 }
 
 // DefineFlags defines the flags that are considered valid and reads their values (if provided).
 func (e *extendCmd) DefineFlags() {
 	if e.PlatformAPI.AtLeast("0.12") {
 		cli.FlagExtendKind(&e.ExtendKind)
+		cli.FlagExtendParallel(&e.ExtendParallel)
 		cli.FlagExtendedDir(&e.ExtendedDir)
 	}
 	cli.FlagAnalyzedPath(&e.AnalyzedPath)
 	cli.FlagAppDir(&e.AppDir)
 	cli.FlagBuildpacksDir(&e.BuildpacksDir)
+	cli.FlagCacheDir(&e.CacheDir)
+	cli.FlagExtendBackend(&e.ExtendBackend)
+	cli.FlagExtendCacheImage(&e.ExtendCacheImage)
+	cli.FlagExtendRootless(&e.ExtendRootless)
+	cli.FlagExtendSecretsDir(&e.ExtendSecretsDir)
 	cli.FlagGID(&e.GID)
 	cli.FlagGeneratedDir(&e.GeneratedDir)
 	cli.FlagGroupPath(&e.GroupPath)
 	cli.FlagKanikoCacheTTL(&e.KanikoCacheTTL)
+	cli.FlagKanikoDir(&e.KanikoDir)
 	cli.FlagLayersDir(&e.LayersDir)
 	cli.FlagPlanPath(&e.PlanPath)
 	cli.FlagPlatformDir(&e.PlatformDir)
@@ -41,43 +59,56 @@ func (e *extendCmd) Args(nargs int, _ []string) error {
 	if nargs != 0 {
 		return cmd.FailErrCode(errors.New("received unexpected arguments"), cmd.CodeForInvalidArgs, "parse arguments")
 	}
-	if err := platform.ResolveInputs(platform.Extend, e.LifecycleInputs, cmd.DefaultLogger); err != nil {
+	if err := platform.VerifyInputs(platform.Extend, e.LifecycleInputs, cmd.DefaultLogger); err != nil {
 		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
 	}
 	return nil
 }
 
 func (e *extendCmd) Privileges() error {
+	if e.ExtendCacheImage != "" {
+		var err error
+		e.keychain, err = auth.DefaultKeychain(e.ExtendCacheImage)
+		if err != nil {
+			return cmd.FailErr(err, "resolve keychain")
+		}
+		e.keychain = withAnonymousFallback(e.keychain, e.AnalyzedPath)
+	}
+	if !e.ExtendRootless {
+		if !priv.IsPrivileged() {
+			return cmd.FailErrCode(
+				priv.RequiresRoot("extend", "run the extender as root, or provide -extend-rootless with the buildkit extend backend"),
+				cmd.CodeForInvalidArgs, "check privileges",
+			)
+		}
+		return nil
+	}
+	if !priv.IsPrivileged() {
+		// unpacking new layers to the filesystem requires uid 0, which may be obtained in a user namespace
+		if e.ExtendKind == buildpack.DockerfileKindBuild {
+			return cmd.FailErrCode(
+				errors.New("extending the build image in rootless mode requires uid 0, e.g., in a user namespace via `unshare --user --map-root-user`"),
+				cmd.CodeForInvalidArgs, "check privileges",
+			)
+		}
+	}
+	cmd.DefaultLogger.Debugf("Extending in rootless mode (in user namespace: %t)", priv.InUserNamespace())
 	return nil
 }
 
 func (e *extendCmd) Exec() error {
 	extenderFactory := lifecycle.NewExtenderFactory(&cmd.BuildpackAPIVerifier{}, lifecycle.NewConfigHandler())
-	applier, err := kaniko.NewDockerfileApplier()
+	extender, err := e.newExtender(extenderFactory, e.ExtendKind)
 	if err != nil {
 		return err
 	}
-	extender, err := extenderFactory.NewExtender(
-		e.AnalyzedPath,
-		e.AppDir,
-		e.ExtendedDir,
-		e.GeneratedDir,
-		e.GroupPath,
-		e.LayersDir,
-		e.PlatformDir,
-		e.KanikoCacheTTL,
-		applier,
-		e.ExtendKind,
-		cmd.DefaultLogger,
-	)
-	if err != nil {
-		return unwrapErrorFailWithMessage(err, "initialize extender")
-	}
+	e.restoreLayerCache()
 	switch e.ExtendKind {
 	case buildpack.DockerfileKindBuild:
-		if err = extender.Extend(e.ExtendKind, cmd.DefaultLogger); err != nil {
-			return cmd.FailErrCode(err, e.CodeFor(platform.ExtendError), "extend build image")
+		if err = e.extendBuild(extenderFactory, extender); err != nil {
+			return err
 		}
+		e.saveLayerCache()
 		if err = priv.EnsureOwner(e.UID, e.GID, e.LayersDir); err != nil {
 			return cmd.FailErr(err, "chown volumes")
 		}
@@ -96,8 +127,140 @@ func (e *extendCmd) Exec() error {
 		if err = extender.Extend(e.ExtendKind, cmd.DefaultLogger); err != nil {
 			return cmd.FailErrCode(err, e.CodeFor(platform.ExtendError), "extend run image")
 		}
+		e.saveLayerCache()
 	default:
 		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs)
 	}
 	return nil
 }
+
+func (e *extendCmd) newExtender(extenderFactory *lifecycle.ExtenderFactory, kind string) (*lifecycle.Extender, error) {
+	applier, err := e.dockerfileApplier()
+	if err != nil {
+		return nil, cmd.FailErr(err, "initialize Dockerfile applier")
+	}
+	extender, err := extenderFactory.NewExtender(
+		e.AnalyzedPath,
+		e.AppDir,
+		e.ExtendedDir,
+		e.GeneratedDir,
+		e.GroupPath,
+		e.LayersDir,
+		e.PlatformDir,
+		e.KanikoCacheTTL,
+		applier,
+		kind,
+		cmd.DefaultLogger,
+	)
+	if err != nil {
+		return nil, unwrapErrorFailWithMessage(err, "initialize extender")
+	}
+	return extender, nil
+}
+
+// extendBuild extends the build image and, if requested and needed, the run image concurrently.
+// Each extender has its own Dockerfile applier and working directory, and BuildKit exports the cache of each kind of image
+// to its own directory within the layer cache directory.
+func (e *extendCmd) extendBuild(extenderFactory *lifecycle.ExtenderFactory, buildExtender *lifecycle.Extender) error {
+	var (
+		runExtender *lifecycle.Extender
+		err         error
+	)
+	if e.ExtendParallel && e.runImageNeedsExtension() {
+		if runExtender, err = e.newExtender(extenderFactory, buildpack.DockerfileKindRun); err != nil {
+			return err
+		}
+	}
+	var group errgroup.Group
+	group.Go(func() error {
+		if err := buildExtender.Extend(buildpack.DockerfileKindBuild, cmd.DefaultLogger); err != nil {
+			return cmd.FailErrCode(err, e.CodeFor(platform.ExtendError), "extend build image")
+		}
+		return nil
+	})
+	if runExtender != nil {
+		group.Go(func() error {
+			if err := runExtender.Extend(buildpack.DockerfileKindRun, cmd.DefaultLogger); err != nil {
+				return cmd.FailErrCode(err, e.CodeFor(platform.ExtendError), "extend run image")
+			}
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+func (e *extendCmd) runImageNeedsExtension() bool {
+	analyzedMD, err := files.ReadAnalyzed(e.AnalyzedPath, cmd.DefaultLogger)
+	if err != nil {
+		return false
+	}
+	return analyzedMD.RunImage != nil && analyzedMD.RunImage.Extend
+}
+
+// layerCacheDir returns the directory where the Dockerfile applier caches the layers it creates.
+// The BuildKit applier keeps the cache of each kind of image in a subdirectory (see buildkit.DockerfileApplier).
+func (e *extendCmd) layerCacheDir() string {
+	if e.ExtendBackend == platform.ExtendBackendBuildKit {
+		return filepath.Join(e.KanikoDir, "cache", "buildkit")
+	}
+	return filepath.Join(e.KanikoDir, "cache", "layers")
+}
+
+func (e *extendCmd) restoreLayerCache() {
+	if e.CacheDir != "" {
+		if err := extend.RestoreLayerCache(e.CacheDir, e.layerCacheDir()); err != nil {
+			cmd.DefaultLogger.Warnf("Failed to restore extension layer cache: %s", err)
+		}
+	}
+	if e.ExtendCacheImage != "" {
+		var err error
+		if e.cacheImageDiffID, err = extend.PullLayerCache(e.ExtendCacheImage, e.layerCacheDir(), e.keychain); err != nil {
+			cmd.DefaultLogger.Warnf("Failed to pull extension layer cache: %s", err)
+		}
+	}
+}
+
+func (e *extendCmd) saveLayerCache() {
+	if e.CacheDir != "" {
+		if err := extend.SaveLayerCache(e.layerCacheDir(), e.CacheDir); err != nil {
+			cmd.DefaultLogger.Warnf("Failed to save extension layer cache: %s", err)
+		}
+	}
+	if e.ExtendCacheImage != "" {
+		if err := extend.PushLayerCache(e.layerCacheDir(), e.ExtendCacheImage, e.keychain, e.cacheImageDiffID, cmd.DefaultLogger); err != nil {
+			cmd.DefaultLogger.Warnf("Failed to push extension layer cache: %s", err)
+		}
+	}
+}
+
+// UsesKaniko returns true if the extender will use kaniko to apply the Dockerfiles.
+func (e *extendCmd) UsesKaniko() bool {
+	return e.ExtendBackend != platform.ExtendBackendBuildKit
+}
+
+func (e *extendCmd) dockerfileApplier() (lifecycle.DockerfileApplier, error) {
+	if e.ExtendBackend == platform.ExtendBackendBuildKit {
+		keychain, err := auth.DefaultKeychain(e.baseImageRefs()...)
+		if err != nil {
+			return nil, fmt.Errorf("resolving keychain: %w", err)
+		}
+		return buildkit.NewDockerfileApplier(e.KanikoDir, e.ExtendSecretsDir, withAnonymousFallback(keychain, e.AnalyzedPath), e.ExtendRootless)
+	}
+	return kaniko.NewDockerfileApplier(e.KanikoDir)
+}
+
+// baseImageRefs returns the references of the images that may be extended, so that BuildKit can be given credentials to pull them.
+func (e *extendCmd) baseImageRefs() []string {
+	analyzedMD, err := files.ReadAnalyzed(e.AnalyzedPath, cmd.DefaultLogger)
+	if err != nil {
+		return nil
+	}
+	var refs []string
+	if analyzedMD.BuildImage != nil && analyzedMD.BuildImage.Reference != "" {
+		refs = append(refs, analyzedMD.BuildImage.Reference)
+	}
+	if analyzedMD.RunImage != nil && analyzedMD.RunImage.Reference != "" {
+		refs = append(refs, analyzedMD.RunImage.Reference)
+	}
+	return refs
+}
@@ -1,20 +1,31 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/buildpacks/imgutil/remote"
 	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/auth"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/cache"
 	"github.com/buildpacks/lifecycle/cmd"
 	"github.com/buildpacks/lifecycle/cmd/lifecycle/cli"
+	lerrors "github.com/buildpacks/lifecycle/errors"
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
+	"github.com/buildpacks/lifecycle/priv"
 )
 
 func main() {
@@ -41,14 +52,30 @@ func main() {
 			cmd.Exit(cmd.FailCode(cmd.CodeForInvalidArgs, "parse arguments"))
 		}
 		if os.Args[1] == "-version" {
+			if len(os.Args) > 2 && strings.TrimLeft(os.Args[2], "-") == "capabilities" {
+				exitWithCapabilities()
+			}
 			cmd.ExitWithVersion()
 		}
 		subcommand(platformAPIWithExitOnError())
 	}
 }
 
+// exitWithCapabilities prints the capabilities of the lifecycle as JSON, for platforms to negotiate behavior.
+func exitWithCapabilities() {
+	contents, err := json.MarshalIndent(platform.GetCapabilities(cmd.Version), "", "  ")
+	if err != nil {
+		cmd.Exit(cmd.FailErr(err, "encode capabilities"))
+	}
+	fmt.Fprintln(cmd.Stdout, string(contents))
+	cmd.Exit(nil)
+}
+
 func platformAPIWithExitOnError() string {
-	platformAPI := cmd.EnvOrDefault(platform.EnvPlatformAPI, platform.DefaultPlatformAPI)
+	platformAPI := platform.Getenv(platform.EnvPlatformAPI)
+	if platformAPI == "" {
+		platformAPI = platform.DefaultPlatformAPI
+	}
 	if err := cmd.VerifyPlatformAPI(platformAPI, cmd.DefaultLogger); err != nil {
 		cmd.Exit(err)
 	}
@@ -74,20 +101,28 @@ func subcommand(platformAPI string) {
 		cli.Run(&createCmd{Platform: platform.NewPlatformFor(platformAPI)}, phase, true)
 	case "extend":
 		cli.Run(&extendCmd{Platform: platform.NewPlatformFor(platformAPI)}, phase, true)
+	case "convert-bom":
+		cli.Run(&convertBOMCmd{Platform: platform.NewPlatformFor(platformAPI)}, phase, true)
+	case "clean-layers":
+		cli.Run(&cleanLayersCmd{Platform: platform.NewPlatformFor(platformAPI)}, phase, true)
+	case "verify-reproducible":
+		cli.Run(&verifyReproducibleCmd{Platform: platform.NewPlatformFor(platformAPI)}, phase, true)
 	default:
-		cmd.Exit(cmd.FailCode(cmd.CodeForInvalidArgs, "unknown phase:", phase, "\nValid phases: detect, analyze, restore, build, export, rebase, create, extend"))
+		cmd.Exit(cmd.FailCode(cmd.CodeForInvalidArgs, "unknown phase:", phase, "\nValid phases: detect, analyze, restore, build, export, rebase, create, extend, convert-bom, clean-layers, verify-reproducible"))
 	}
 }
 
 // handlers
 
 type DefaultCacheHandler struct {
-	keychain authn.Keychain
+	keychain    authn.Keychain
+	lockTimeout time.Duration
 }
 
-func NewCacheHandler(keychain authn.Keychain) *DefaultCacheHandler {
+func NewCacheHandler(keychain authn.Keychain, lockTimeout time.Duration) *DefaultCacheHandler {
 	return &DefaultCacheHandler{
-		keychain: keychain,
+		keychain:    keychain,
+		lockTimeout: lockTimeout,
 	}
 }
 
@@ -104,7 +139,7 @@ func (ch *DefaultCacheHandler) InitCache(cacheImageRef string, cacheDir string,
 			return nil, errors.Wrap(err, "creating image cache")
 		}
 	} else if cacheDir != "" {
-		cacheStore, err = cache.NewVolumeCache(cacheDir)
+		cacheStore, err = cache.NewVolumeCache(cacheDir, cache.WithLockTimeout(ch.lockTimeout))
 		if err != nil {
 			return nil, errors.Wrap(err, "creating volume cache")
 		}
@@ -125,6 +160,9 @@ func NewRegistryHandler(keychain authn.Keychain) *DefaultRegistryHandler {
 func (rv *DefaultRegistryHandler) EnsureReadAccess(imageRefs ...string) error {
 	for _, imageRef := range imageRefs {
 		if err := verifyReadAccess(imageRef, rv.keychain); err != nil {
+			if fallbackKeychain, ok := rv.keychain.(*auth.AnonymousFallbackKeychain); ok && fallBackToAnonymous(imageRef, fallbackKeychain) {
+				continue
+			}
 			return err
 		}
 	}
@@ -147,12 +185,37 @@ func verifyReadAccess(imageRef string, keychain authn.Keychain) error {
 	img, _ := remote.NewImage(imageRef, keychain)
 	canRead, err := img.CheckReadAccess()
 	if !canRead {
-		cmd.DefaultLogger.Debugf("Error checking read access: %s", err)
-		return errors.Errorf("ensure registry read access to %s", imageRef)
+		return &lerrors.RegistryAuthError{Image: imageRef, Access: lerrors.AccessRead, Err: err}
 	}
 	return nil
 }
 
+// fallBackToAnonymous checks if the image can be read anonymously, and if so, configures the keychain to use anonymous access
+// for the registry of the image.
+func fallBackToAnonymous(imageRef string, keychain *auth.AnonymousFallbackKeychain) bool {
+	ref, err := name.ParseReference(imageRef, name.WeakValidation)
+	if err != nil {
+		return false
+	}
+	if err = verifyReadAccess(imageRef, authn.NewMultiKeychain()); err != nil {
+		return false
+	}
+	registry := ref.Context().RegistryStr()
+	keychain.FallBack(registry)
+	cmd.DefaultLogger.Warnf("Credentials for registry '%s' were rejected; falling back to anonymous access to read '%s'", registry, imageRef)
+	return true
+}
+
+// withAnonymousFallback returns a keychain that uses anonymous access for the registries that fell back to anonymous access
+// in the analyzer, as recorded in analyzed.toml.
+func withAnonymousFallback(keychain authn.Keychain, analyzedPath string) authn.Keychain {
+	analyzedMD, err := files.ReadAnalyzed(analyzedPath, log.NewDefaultLogger(io.Discard))
+	if err != nil || len(analyzedMD.AnonymousRegistries) == 0 {
+		return keychain
+	}
+	return auth.NewAnonymousFallbackKeychain(keychain, analyzedMD.AnonymousRegistries...)
+}
+
 func verifyReadWriteAccess(imageRef string, keychain authn.Keychain) error {
 	if imageRef == "" {
 		return nil
@@ -160,15 +223,31 @@ func verifyReadWriteAccess(imageRef string, keychain authn.Keychain) error {
 	img, _ := remote.NewImage(imageRef, keychain)
 	canReadWrite, err := img.CheckReadWriteAccess()
 	if !canReadWrite {
-		cmd.DefaultLogger.Debugf("Error checking read/write access: %s", err)
-		return errors.Errorf("ensure registry read/write access to %s", imageRef)
+		return &lerrors.RegistryAuthError{Image: imageRef, Access: lerrors.AccessReadWrite, Err: err}
 	}
 	return nil
 }
 
+// verifyBaseImage verifies that the base image at imageRef is signed by a key trusted by the policy at policyPath (if provided),
+// and returns a reference to the verified digest of the image, so that a tag that is moved after verification is not used.
+func verifyBaseImage(policyPath, imageRef string, keychain authn.Keychain) (string, error) {
+	if policyPath == "" || imageRef == "" {
+		return imageRef, nil
+	}
+	policy, err := files.ReadTrustPolicy(policyPath)
+	if err != nil {
+		return "", cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "read trust policy")
+	}
+	verifiedRef, err := platform.VerifyBaseImage(policy, imageRef, keychain, cmd.DefaultLogger)
+	if err != nil {
+		return "", cmd.FailErr(err, "verify base image")
+	}
+	return verifiedRef, nil
+}
+
 // helpers
 
-func initCache(cacheImageTag, cacheDir string, keychain authn.Keychain, deletionEnabled bool) (lifecycle.Cache, error) {
+func initCache(cacheImageTag, cacheDir string, keychain authn.Keychain, deletionEnabled bool, lockTimeout time.Duration) (lifecycle.Cache, error) {
 	var (
 		cacheStore lifecycle.Cache
 		err        error
@@ -180,7 +259,7 @@ func initCache(cacheImageTag, cacheDir string, keychain authn.Keychain, deletion
 			return nil, cmd.FailErr(err, "create image cache")
 		}
 	} else if cacheDir != "" {
-		cacheStore, err = cache.NewVolumeCache(cacheDir)
+		cacheStore, err = cache.NewVolumeCache(cacheDir, cache.WithLockTimeout(lockTimeout))
 		if err != nil {
 			return nil, cmd.FailErr(err, "create volume cache")
 		}
@@ -201,3 +280,31 @@ func verifyBuildpackApis(group buildpack.Group) error {
 	}
 	return nil
 }
+
+// normalizeDirs chowns the app and layers directories to the CNB user and group,
+// and removes the setuid and setgid bits from their files, if requested by the platform.
+// It must be called before dropping privileges.
+func normalizeDirs(inputs *platform.LifecycleInputs) error {
+	if inputs.NormalizeOwnership {
+		if err := priv.ChownAll(inputs.UID, inputs.GID, inputs.AppDir, inputs.LayersDir); err != nil {
+			return cmd.FailErr(err, "normalize ownership")
+		}
+	}
+	if inputs.StripSetuid {
+		stripped, err := fsutil.StripSetuid(inputs.AppDir, inputs.LayersDir)
+		if err != nil {
+			return cmd.FailErr(err, "strip setuid bits")
+		}
+		if stripped > 0 {
+			cmd.DefaultLogger.Debugf("Removed setuid and setgid bits from %d files", stripped)
+		}
+	}
+	return nil
+}
+
+// applyUmask sets the umask provided by the platform (if any), which applies to the files created by buildpacks.
+func applyUmask(inputs *platform.LifecycleInputs) {
+	if mask, ok := inputs.UmaskValue(); ok {
+		priv.SetUmask(mask)
+	}
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/buildpacks/imgutil"
 	"github.com/buildpacks/imgutil/local"
@@ -17,6 +22,7 @@ import (
 	"github.com/buildpacks/lifecycle/cmd/lifecycle/cli"
 	"github.com/buildpacks/lifecycle/image"
 	"github.com/buildpacks/lifecycle/internal/encoding"
+	"github.com/buildpacks/lifecycle/internal/network"
 	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/platform/files"
 	"github.com/buildpacks/lifecycle/priv"
@@ -28,7 +34,8 @@ type rebaseCmd struct {
 	docker   client.CommonAPIClient // construct if necessary before dropping privileges
 	keychain authn.Keychain         // construct if necessary before dropping privileges
 
-	appImage imgutil.Image
+	appImage   imgutil.Image
+	bulkImages []string // bulkImages are the app images to rebase, when rebasing a list of images
 }
 
 // DefineFlags defines the flags that are considered valid and reads their values (if provided).
@@ -46,17 +53,25 @@ func (r *rebaseCmd) DefineFlags() {
 
 	if r.PlatformAPI.AtLeast("0.12") {
 		cli.FlagForceRebase(&r.ForceRebase)
+		cli.FlagRebaseDryRun(&r.RebaseDryRun)
+		cli.FlagRebaseImagesPath(&r.RebaseImagesPath)
+		cli.FlagRebaseParallelism(&r.RebaseParallelism)
+		cli.FlagRebaseSnapshot(&r.RebaseSnapshot)
+		cli.FlagTrustPolicyPath(&r.TrustPolicyPath)
 	}
 }
 
 // Args validates arguments and flags, and fills in default values.
 func (r *rebaseCmd) Args(nargs int, args []string) error {
+	if r.RebaseImagesPath != "" {
+		return r.bulkArgs(nargs, args)
+	}
 	if nargs == 0 {
 		return cmd.FailErrCode(errors.New("at least one image argument is required"), cmd.CodeForInvalidArgs, "parse arguments")
 	}
 	r.OutputImageRef = args[0]
 	r.AdditionalTags = args[1:]
-	if err := platform.ResolveInputs(platform.Rebase, r.LifecycleInputs, cmd.DefaultLogger); err != nil {
+	if err := platform.VerifyInputs(platform.Rebase, r.LifecycleInputs, cmd.DefaultLogger); err != nil {
 		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
 	}
 	var err error
@@ -74,15 +89,69 @@ func (r *rebaseCmd) Args(nargs int, args []string) error {
 	return nil
 }
 
+// bulkArgs validates arguments and flags when rebasing the list of images at RebaseImagesPath, and reads the list.
+func (r *rebaseCmd) bulkArgs(nargs int, args []string) error {
+	if nargs > 0 {
+		// unsupported, but set so that the error is reported when resolving inputs
+		r.OutputImageRef = args[0]
+		r.AdditionalTags = args[1:]
+	}
+	if err := platform.VerifyInputs(platform.Rebase, r.LifecycleInputs, cmd.DefaultLogger); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
+	}
+	var err error
+	if r.bulkImages, err = readImageList(r.RebaseImagesPath); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "read image list")
+	}
+	return nil
+}
+
+// readImageList reads the image references listed in the file at path (or standard input, for "-"), one per line,
+// ignoring blank lines, comments (starting with #) and duplicates.
+func readImageList(path string) ([]string, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	var (
+		refs []string
+		seen = map[string]bool{}
+	)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		ref := strings.TrimSpace(scanner.Text())
+		if ref == "" || strings.HasPrefix(ref, "#") || seen[ref] {
+			continue
+		}
+		if _, err := name.ParseReference(ref, name.WeakValidation); err != nil {
+			return nil, err
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("no images to rebase in '%s'", path)
+	}
+	return refs, nil
+}
+
 func (r *rebaseCmd) Privileges() error {
 	var err error
-	r.keychain, err = auth.DefaultKeychain(r.RegistryImages()...)
+	r.keychain, err = auth.DefaultKeychain(append(r.RegistryImages(), r.bulkImages...)...)
 	if err != nil {
 		return cmd.FailErr(err, "resolve keychain")
 	}
 	if r.UseDaemon {
 		var err error
-		r.docker, err = priv.DockerClient()
+		r.docker, err = priv.DaemonClient()
 		if err != nil {
 			return cmd.FailErr(err, "initialize docker client")
 		}
@@ -94,6 +163,9 @@ func (r *rebaseCmd) Privileges() error {
 }
 
 func (r *rebaseCmd) Exec() error {
+	if r.RebaseImagesPath != "" {
+		return r.execBulk()
+	}
 	var err error
 	if r.UseDaemon {
 		// We may need to read the application image in order to know the run image,
@@ -103,7 +175,77 @@ func (r *rebaseCmd) Exec() error {
 			return cmd.FailErrCode(errors.New(err.Error()), r.CodeFor(platform.RebaseError), "set app image")
 		}
 	}
-	var newBaseImage imgutil.Image
+	newBaseImage, err := r.newBaseImage()
+	if err != nil {
+		return err
+	}
+
+	rebaser := r.newRebaser()
+	report, err := rebaser.Rebase(r.appImage, newBaseImage, r.OutputImageRef, r.AdditionalTags)
+	if err != nil {
+		if report.Compatibility != nil {
+			// the report explains why the run images are incompatible
+			if err := encoding.WriteTOML(r.ReportPath, &report); err != nil {
+				cmd.DefaultLogger.Warnf("Failed to write rebase report: %s", err)
+			}
+		}
+		return cmd.FailErrCode(err, r.CodeFor(platform.RebaseError), "rebase")
+	}
+
+	if err := encoding.WriteTOML(r.ReportPath, &report); err != nil {
+		return cmd.FailErrCode(err, r.CodeFor(platform.RebaseError), "write rebase report")
+	}
+	return nil
+}
+
+// execBulk rebases the images in the list at RebaseImagesPath, writing a report with the outcome of each image.
+func (r *rebaseCmd) execBulk() error {
+	newBaseImage, err := r.newBaseImage()
+	if err != nil {
+		return err
+	}
+	rebaser := r.newRebaser()
+	report := rebaser.RebaseAll(r.bulkImages, r.openAppImage, newBaseImage, r.RebaseParallelism)
+	if err := encoding.WriteTOML(r.ReportPath, &report); err != nil {
+		return cmd.FailErrCode(err, r.CodeFor(platform.RebaseError), "write rebase report")
+	}
+	if failed := report.Failed(); failed > 0 {
+		return cmd.FailErrCode(fmt.Errorf("failed to rebase %d of %d images", failed, len(report.Images)), r.CodeFor(platform.RebaseError), "rebase")
+	}
+	cmd.DefaultLogger.Infof("Rebased %d images", len(report.Images))
+	return nil
+}
+
+func (r *rebaseCmd) newRebaser() *lifecycle.Rebaser {
+	rebaser := &lifecycle.Rebaser{
+		Logger:      cmd.DefaultLogger,
+		PlatformAPI: r.PlatformAPI,
+		Force:       r.ForceRebase,
+		DryRun:      r.RebaseDryRun,
+	}
+	if r.RebaseSnapshot {
+		rebaser.SnapshotTag = "pre-rebase-" + time.Now().UTC().Format("20060102-150405")
+		rebaser.SnapshotKeychain = r.keychain
+	}
+	return rebaser
+}
+
+func (r *rebaseCmd) openAppImage(ref string) (imgutil.Image, error) {
+	appImage, err := remote.NewImage(ref, r.keychain, remote.FromBaseImage(ref))
+	if err != nil {
+		return nil, err
+	}
+	if !appImage.Found() {
+		return nil, fmt.Errorf("image '%s' not found", ref)
+	}
+	return appImage, nil
+}
+
+func (r *rebaseCmd) newBaseImage() (imgutil.Image, error) {
+	var (
+		newBaseImage imgutil.Image
+		err          error
+	)
 	if r.UseDaemon {
 		newBaseImage, err = local.NewImage(
 			r.RunImageRef,
@@ -111,30 +253,23 @@ func (r *rebaseCmd) Exec() error {
 			local.FromBaseImage(r.RunImageRef),
 		)
 	} else {
+		if _, err = network.ResolveImage(r.RunImageRef); err != nil {
+			return nil, cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve run image")
+		}
+		var runImageRef string
+		if runImageRef, err = verifyBaseImage(r.TrustPolicyPath, r.RunImageRef, r.keychain); err != nil {
+			return nil, err
+		}
 		newBaseImage, err = remote.NewImage(
 			r.RunImageRef,
 			r.keychain,
-			remote.FromBaseImage(r.RunImageRef),
+			remote.FromBaseImage(network.PullRef(runImageRef, r.keychain)),
 		)
 	}
 	if err != nil || !newBaseImage.Found() {
-		return cmd.FailErr(err, "access run image")
-	}
-
-	rebaser := &lifecycle.Rebaser{
-		Logger:      cmd.DefaultLogger,
-		PlatformAPI: r.PlatformAPI,
-		Force:       r.ForceRebase,
+		return nil, cmd.FailErr(err, "access run image")
 	}
-	report, err := rebaser.Rebase(r.appImage, newBaseImage, r.OutputImageRef, r.AdditionalTags)
-	if err != nil {
-		return cmd.FailErrCode(err, r.CodeFor(platform.RebaseError), "rebase")
-	}
-
-	if err := encoding.WriteTOML(r.ReportPath, &report); err != nil {
-		return cmd.FailErrCode(err, r.CodeFor(platform.RebaseError), "write rebase report")
-	}
-	return nil
+	return newBaseImage, nil
 }
 
 func (r *rebaseCmd) setAppImage() error {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -19,13 +20,13 @@ import (
 	"github.com/buildpacks/lifecycle/cmd/lifecycle/cli"
 	"github.com/buildpacks/lifecycle/internal/encoding"
 	"github.com/buildpacks/lifecycle/internal/layer"
+	"github.com/buildpacks/lifecycle/internal/network"
+	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/platform/files"
 	"github.com/buildpacks/lifecycle/priv"
 )
 
-const kanikoDir = "/kaniko"
-
 type restoreCmd struct {
 	*platform.Platform
 
@@ -35,7 +36,16 @@ type restoreCmd struct {
 // DefineFlags defines the flags that are considered valid and reads their values (if provided).
 func (r *restoreCmd) DefineFlags() {
 	if r.PlatformAPI.AtLeast("0.12") {
+		cli.FlagAppDir(&r.AppDir)
+		cli.FlagCacheLockTimeout(&r.CacheLockTimeout)
 		cli.FlagGeneratedDir(&r.GeneratedDir)
+		cli.FlagKanikoDir(&r.KanikoDir)
+		cli.FlagLenientExtraction(&r.LenientExtraction)
+		cli.FlagNoDigestCache(&r.NoDigestCache)
+		cli.FlagNormalizeOwnership(&r.NormalizeOwnership)
+		cli.FlagPreserveXattrs(&r.PreserveXattrs)
+		cli.FlagStripSetuid(&r.StripSetuid)
+		cli.FlagTrustPolicyPath(&r.TrustPolicyPath)
 	}
 	if r.PlatformAPI.AtLeast("0.10") {
 		cli.FlagBuildImage(&r.BuildImageRef)
@@ -57,7 +67,7 @@ func (r *restoreCmd) Args(nargs int, _ []string) error {
 	if nargs > 0 {
 		return cmd.FailErrCode(errors.New("received unexpected Args"), cmd.CodeForInvalidArgs, "parse arguments")
 	}
-	if err := platform.ResolveInputs(platform.Restore, r.LifecycleInputs, cmd.DefaultLogger); err != nil {
+	if err := platform.VerifyInputs(platform.Restore, r.LifecycleInputs, cmd.DefaultLogger); err != nil {
 		return cmd.FailErrCode(err, cmd.CodeForInvalidArgs, "resolve inputs")
 	}
 	return nil
@@ -69,9 +79,13 @@ func (r *restoreCmd) Privileges() error {
 	if err != nil {
 		return cmd.FailErr(err, "resolve keychain")
 	}
+	r.keychain = withAnonymousFallback(r.keychain, r.AnalyzedPath)
 	if err = priv.EnsureOwner(r.UID, r.GID, r.LayersDir, r.CacheDir, r.KanikoDir); err != nil {
 		return cmd.FailErr(err, "chown volumes")
 	}
+	if err = normalizeDirs(r.LifecycleInputs); err != nil {
+		return err
+	}
 	if err = priv.RunAs(r.UID, r.GID); err != nil {
 		return cmd.FailErr(err, fmt.Sprintf("exec as user %d:%d", r.UID, r.GID))
 	}
@@ -86,7 +100,11 @@ func (r *restoreCmd) Exec() error {
 	if analyzedMD, err = files.ReadAnalyzed(r.AnalyzedPath, cmd.DefaultLogger); err == nil {
 		if r.supportsBuildImageExtension() && r.BuildImageRef != "" {
 			cmd.DefaultLogger.Debugf("Pulling builder image metadata for %s...", r.BuildImageRef)
-			remoteBuildImage, err := r.pullSparse(r.BuildImageRef)
+			buildImageRef, err := verifyBaseImage(r.TrustPolicyPath, r.BuildImageRef, r.keychain)
+			if err != nil {
+				return err
+			}
+			remoteBuildImage, err := r.pullSparse(buildImageRef)
 			if err != nil {
 				return cmd.FailErr(err, "pull builder image")
 			}
@@ -139,7 +157,7 @@ func (r *restoreCmd) Exec() error {
 		return err
 	}
 
-	cacheStore, err := initCache(r.CacheImageRef, r.CacheDir, r.keychain, r.PlatformAPI.LessThan("0.13"))
+	cacheStore, err := initCache(r.CacheImageRef, r.CacheDir, r.keychain, r.PlatformAPI.LessThan("0.13"), r.CacheLockTimeout)
 	if err != nil {
 		return err
 	}
@@ -161,6 +179,7 @@ func updateAnalyzedMD(analyzedMD *files.Analyzed, remoteRunImage imgutil.Image)
 	if err != nil {
 		return cmd.FailErr(err, "read target data from run image")
 	}
+	platform.GetTargetDistroFromImage(remoteRunImage, targetData, cmd.DefaultLogger)
 	cmd.DefaultLogger.Debugf("Run image info in analyzed metadata was: ")
 	cmd.DefaultLogger.Debugf(encoding.ToJSONMaybe(analyzedMD.RunImage))
 	analyzedMD.RunImage.Reference = digestRef.String()
@@ -206,24 +225,25 @@ func (r *restoreCmd) supportsTargetData() bool {
 }
 
 func (r *restoreCmd) pullSparse(imageRef string) (imgutil.Image, error) {
-	baseCacheDir := filepath.Join(kanikoDir, "cache", "base")
-	if err := os.MkdirAll(baseCacheDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	// check for usable kaniko dir; it is provided by the platform (e.g., as a volume) when extending,
+	// and is not created otherwise as the root filesystem may be read-only
+	if _, err := os.Stat(r.KanikoDir); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read kaniko directory: %w", err)
+		}
+		return nil, fmt.Errorf("kaniko directory %s does not exist; provide it with -kaniko-dir or %s when extending", r.KanikoDir, platform.EnvKanikoDir)
 	}
 	// get remote image
-	remoteImage, err := remote.NewImage(imageRef, r.keychain, remote.FromBaseImage(imageRef))
+	remoteImage, err := remote.NewImage(imageRef, r.keychain, remote.FromBaseImage(network.PullRef(imageRef, r.keychain)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize remote image: %w", err)
 	}
 	if !remoteImage.Found() {
 		return nil, fmt.Errorf("failed to get remote image")
 	}
-	// check for usable kaniko dir
-	if _, err := os.Stat(kanikoDir); err != nil {
-		if !os.IsNotExist(err) {
-			return nil, fmt.Errorf("failed to read kaniko directory: %w", err)
-		}
-		return nil, nil
+	baseCacheDir := filepath.Join(r.KanikoDir, "cache", "base")
+	if err := os.MkdirAll(baseCacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
 	}
 	// save to disk
 	h, err := remoteImage.UnderlyingImage().Digest()
@@ -259,12 +279,16 @@ func (r *restoreCmd) restore(layerMetadata files.LayersMetadata, group buildpack
 		LayerMetadataRestorer: layer.NewDefaultMetadataRestorer(r.LayersDir, r.SkipLayers, cmd.DefaultLogger),
 		LayersMetadata:        layerMetadata,
 		SBOMRestorer: layer.NewSBOMRestorer(layer.SBOMRestorerOpts{
-			LayersDir: r.LayersDir,
-			Logger:    cmd.DefaultLogger,
-			Nop:       r.SkipLayers,
+			LayersDir:         r.LayersDir,
+			Logger:            cmd.DefaultLogger,
+			Nop:               r.SkipLayers,
+			LenientExtraction: r.LenientExtraction,
 		}, r.PlatformAPI),
+		DigestCache:       layers.DigestCacheFor(r.LayersDir, r.NoDigestCache),
+		LenientExtraction: r.LenientExtraction,
+		Xattrs:            r.XattrPrefixes(),
 	}
-	if err := restorer.Restore(cacheStore); err != nil {
+	if err := restorer.RestoreContext(context.Background(), cacheStore); err != nil {
 		return cmd.FailErrCode(err, r.CodeFor(platform.RestoreError), "restore")
 	}
 	return nil
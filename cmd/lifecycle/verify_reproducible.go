@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/authn"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/auth"
+	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/cmd/lifecycle/cli"
+	"github.com/buildpacks/lifecycle/image"
+	"github.com/buildpacks/lifecycle/internal/encoding"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/priv"
+)
+
+const defaultReproducibilityReportPath = "reproducibility.toml"
+
+// verifyReproducibleCmd compares the layers of an application image with the layers of a rebuild of the image
+// (e.g., exported by the creator to another tag, from the same inputs), writing a report of the layers that differ.
+// It is not a phase of the build; it helps platforms attest that builds are reproducible.
+type verifyReproducibleCmd struct {
+	*platform.Platform
+
+	imageRef   string
+	rebuiltRef string
+	reportPath string
+
+	docker   client.CommonAPIClient // construct if necessary before dropping privileges
+	keychain authn.Keychain         // construct if necessary before dropping privileges
+}
+
+// DefineFlags defines the flags that are considered valid and reads their values (if provided).
+func (v *verifyReproducibleCmd) DefineFlags() {
+	v.reportPath = defaultReproducibilityReportPath
+	cli.FlagGID(&v.GID)
+	cli.FlagReportPath(&v.reportPath)
+	cli.FlagUID(&v.UID)
+	cli.FlagUseDaemon(&v.UseDaemon)
+}
+
+// Args validates arguments and flags, and fills in default values.
+func (v *verifyReproducibleCmd) Args(nargs int, args []string) error {
+	if nargs != 2 {
+		return cmd.FailErrCode(fmt.Errorf("received %d arguments, but expected 2 (the image and the rebuilt image)", nargs), cmd.CodeForInvalidArgs, "parse arguments")
+	}
+	v.imageRef, v.rebuiltRef = args[0], args[1]
+	if v.PlatformAPI.LessThan("0.12") {
+		return cmd.FailErrCode(fmt.Errorf("verify-reproducible requires Platform API 0.12 or above, but %s was requested", v.PlatformAPI), cmd.CodeForIncompatiblePlatformAPI, "parse arguments")
+	}
+	return nil
+}
+
+func (v *verifyReproducibleCmd) Privileges() error {
+	var err error
+	v.keychain, err = auth.DefaultKeychain(v.imageRef, v.rebuiltRef)
+	if err != nil {
+		return cmd.FailErr(err, "resolve keychain")
+	}
+	if v.UseDaemon {
+		v.docker, err = priv.DaemonClient()
+		if err != nil {
+			return cmd.FailErr(err, "initialize docker client")
+		}
+	}
+	if err = priv.RunAs(v.UID, v.GID); err != nil {
+		return cmd.FailErr(err, fmt.Sprintf("exec as user %d:%d", v.UID, v.GID))
+	}
+	return nil
+}
+
+func (v *verifyReproducibleCmd) Exec() error {
+	handler := image.NewHandler(v.docker, v.keychain, "", false)
+	appImage, err := handler.InitImage(v.imageRef)
+	if err != nil || !appImage.Found() {
+		return cmd.FailErr(err, "access image to verify")
+	}
+	rebuiltImage, err := handler.InitImage(v.rebuiltRef)
+	if err != nil || !rebuiltImage.Found() {
+		return cmd.FailErr(err, "access rebuilt image")
+	}
+	verifier := &lifecycle.ReproducibilityVerifier{Logger: cmd.DefaultLogger}
+	report, err := verifier.Verify(appImage, rebuiltImage)
+	if err != nil {
+		return cmd.FailErr(err, "verify reproducibility")
+	}
+	if err = encoding.WriteTOML(v.reportPath, &report); err != nil {
+		return cmd.FailErr(err, "write reproducibility report")
+	}
+	if !report.Reproducible {
+		return cmd.FailErr(fmt.Errorf("%d layers of the rebuilt image differ, see '%s'", len(report.Differences), v.reportPath), "verify reproducibility")
+	}
+	cmd.DefaultLogger.Infof("Image '%s' is reproducible", v.imageRef)
+	return nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/buildpacks/lifecycle/internal/profile"
+)
+
+var profiler *profile.Profiler
+
+// StartProfiling starts writing the requested pprof profiles (in the form <kind>:<path>), so that Exit can finish writing them.
+func StartProfiling(requested []string) error {
+	if len(requested) == 0 {
+		return nil
+	}
+	var specs []profile.Spec
+	for _, r := range requested {
+		spec, err := profile.ParseSpec(r)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	}
+	var err error
+	profiler, err = profile.Start(specs)
+	return err
+}
+
+func stopProfiling() {
+	if profiler == nil {
+		return
+	}
+	if err := profiler.Stop(); err != nil {
+		DefaultLogger.Warnf("Failed to write profile: %s", err)
+	}
+	profiler = nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"io"
+
+	"github.com/buildpacks/lifecycle/internal/redact"
+)
+
+// RedactionTracker provides the location of the file used to share the counts of redacted values between phases.
+type RedactionTracker interface {
+	RedactionsFile() string
+}
+
+var redactionsFile string
+
+// StartRedaction redacts the secret values provided by the platform (and common credential patterns)
+// from the logs of the lifecycle.
+func StartRedaction(getenv func(string) string) error {
+	if err := redact.Configure(getenv); err != nil {
+		return err
+	}
+	DefaultLogger.SetWriter(redact.NewWriter(io.MultiWriter(Stdout, logTail), redact.Lifecycle))
+	return nil
+}
+
+// TrackRedactions loads the counts of values redacted by previous phases,
+// so that Exit can save them together with the values redacted by the current phase.
+func TrackRedactions(tracker RedactionTracker) {
+	redactionsFile = tracker.RedactionsFile()
+	if redactionsFile == "" {
+		return
+	}
+	if err := redact.Load(redactionsFile); err != nil {
+		DefaultLogger.Debugf("Failed to read redactions file: %s", err)
+	}
+}
+
+func saveRedactions() {
+	if redactionsFile == "" {
+		return
+	}
+	if err := redact.Save(redactionsFile); err != nil {
+		DefaultLogger.Debugf("Failed to write redactions file: %s", err)
+	}
+}
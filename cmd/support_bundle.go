@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/buildpacks/lifecycle/internal/redact"
+)
+
+// SupportBundler provides the location of the support bundle written when a phase fails (or an empty string if it is not written),
+// the files to include in it by the names used in the bundle, and the references of the images used by the phase.
+type SupportBundler interface {
+	SupportBundle() (string, map[string]string, []string)
+}
+
+// supportBundleLogSize is the size of the end of the logs included in a support bundle.
+const supportBundleLogSize = 64 * 1024
+
+// secretEnvName matches the names of environment variables whose values are always redacted from support bundles.
+var secretEnvName = regexp.MustCompile(`(?i)password|passwd|secret|token|key|auth|credential`)
+
+var supportBundle struct {
+	bundler SupportBundler
+	phase   string
+}
+
+// logTail records the end of the (redacted) logs of the lifecycle, for inclusion in a support bundle.
+var logTail = &tailWriter{size: supportBundleLogSize}
+
+type tailWriter struct {
+	mu   sync.Mutex
+	size int
+	buf  []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	if len(w.buf) > w.size {
+		w.buf = w.buf[len(w.buf)-w.size:]
+	}
+	return len(p), nil
+}
+
+func (w *tailWriter) Bytes() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]byte{}, w.buf...)
+}
+
+// TrackSupportBundle records the provided phase, so that Exit can write a support bundle if the phase fails.
+func TrackSupportBundle(bundler SupportBundler, phase string) {
+	supportBundle.bundler = bundler
+	supportBundle.phase = phase
+}
+
+func saveSupportBundle(err error, code int) {
+	if supportBundle.bundler == nil {
+		return
+	}
+	path, files, imageRefs := supportBundle.bundler.SupportBundle()
+	if path == "" {
+		return
+	}
+	bundle := SupportBundle{
+		Phase:     supportBundle.phase,
+		Code:      code,
+		Message:   err.Error(),
+		Files:     files,
+		ImageRefs: imageRefs,
+		Env:       os.Environ(),
+		Logs:      logTail.Bytes(),
+	}
+	if err := WriteSupportBundle(path, bundle); err != nil {
+		DefaultLogger.Warnf("Failed to write support bundle: %s", err)
+		return
+	}
+	DefaultLogger.Infof("Wrote support bundle to '%s'", path)
+}
+
+// SupportBundle holds the information about a failed phase that is written to a support bundle.
+type SupportBundle struct {
+	Phase   string
+	Code    int
+	Message string
+	// Files are the files provided to and generated by the phase, by the names used in the bundle; files that do not exist are ignored.
+	Files map[string]string
+	// ImageRefs are the references of the images used by the phase; only their registries are included in the bundle.
+	ImageRefs []string
+	// Env is the environment of the phase, as "KEY=value" strings.
+	Env  []string
+	Logs []byte
+}
+
+type supportBundleSummary struct {
+	Phase            string   `toml:"phase"`
+	Code             int      `toml:"code"`
+	Message          string   `toml:"message"`
+	LifecycleVersion string   `toml:"lifecycle-version"`
+	PlatformAPI      string   `toml:"platform-api"`
+	GoVersion        string   `toml:"go-version"`
+	OS               string   `toml:"os"`
+	Arch             string   `toml:"arch"`
+	Registries       []string `toml:"registries"`
+}
+
+// WriteSupportBundle writes the provided information to a gzipped tarball at path, containing:
+//   - summary.toml, with the failure, the versions of the lifecycle and the Platform API, and the registries used by the phase
+//   - env.txt, with the environment of the phase
+//   - logs.txt, with the end of the logs of the phase
+//   - files/<name> for each file provided to or generated by the phase
+//
+// Secret values are redacted from every entry, and the values of environment variables with secret-looking names are omitted.
+func WriteSupportBundle(path string, bundle SupportBundle) error {
+	var summary bytes.Buffer
+	if err := toml.NewEncoder(&summary).Encode(supportBundleSummary{
+		Phase:            bundle.Phase,
+		Code:             bundle.Code,
+		Message:          bundle.Message,
+		LifecycleVersion: buildVersion(),
+		PlatformAPI:      envPlatformAPI(bundle.Env),
+		GoVersion:        runtime.Version(),
+		OS:               runtime.GOOS,
+		Arch:             runtime.GOARCH,
+		Registries:       registriesFor(bundle.ImageRefs),
+	}); err != nil {
+		return err
+	}
+	entries := map[string]string{
+		"summary.toml": summary.String(),
+		"env.txt":      sanitizeEnv(bundle.Env),
+		"logs.txt":     string(bundle.Logs),
+	}
+	for fileName, filePath := range bundle.Files {
+		contents, err := os.ReadFile(filePath) // #nosec G304
+		if err != nil {
+			continue
+		}
+		entries["files/"+fileName] = string(contents)
+	}
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	var names []string
+	for entryName := range entries {
+		names = append(names, entryName)
+	}
+	sort.Strings(names)
+	modTime := time.Now()
+	for _, entryName := range names {
+		contents := redact.String(entries[entryName], redact.Lifecycle)
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     entryName,
+			Mode:     0600,
+			Size:     int64(len(contents)),
+			ModTime:  modTime,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gzw.Close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0600)
+}
+
+func sanitizeEnv(env []string) string {
+	var sb strings.Builder
+	sorted := append([]string{}, env...)
+	sort.Strings(sorted)
+	for _, kv := range sorted {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && secretEnvName.MatchString(parts[0]) {
+			kv = parts[0] + "=" + redact.Mask
+		}
+		sb.WriteString(kv + "\n")
+	}
+	return sb.String()
+}
+
+func envPlatformAPI(env []string) string {
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, EnvPlatformAPI+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func registriesFor(imageRefs []string) []string {
+	seen := map[string]bool{}
+	var registries []string
+	for _, imageRef := range imageRefs {
+		if imageRef == "" {
+			continue
+		}
+		ref, err := name.ParseReference(imageRef, name.WeakValidation)
+		if err != nil || seen[ref.Context().RegistryStr()] {
+			continue
+		}
+		seen[ref.Context().RegistryStr()] = true
+		registries = append(registries, ref.Context().RegistryStr())
+	}
+	sort.Strings(registries)
+	return registries
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"github.com/buildpacks/lifecycle/telemetry"
+	"github.com/buildpacks/lifecycle/telemetry/metrics"
+	"github.com/buildpacks/lifecycle/telemetry/progress"
+	"github.com/buildpacks/lifecycle/telemetry/tracing"
+)
+
+var telemetryTimer *telemetry.Timer
+
+// StartTelemetry starts timing the provided phase, so that Exit can report its outcome to any registered telemetry hooks.
+func StartTelemetry(phase, platformAPI string) {
+	if !telemetry.Enabled() {
+		return
+	}
+	telemetryTimer = telemetry.Start(phase, Version, platformAPI)
+}
+
+func reportTelemetry(err error, code int) {
+	if telemetryTimer == nil {
+		return
+	}
+	var class string
+	if err != nil {
+		var classFor func(code int) string
+		if failureReporter.FailureReporter != nil {
+			classFor = failureReporter.ErrorClassFor
+		}
+		class = classify(err, code, classFor)
+	}
+	if err := telemetryTimer.Complete(code, class); err != nil {
+		DefaultLogger.Debugf("Failed to report telemetry: %s", err)
+	}
+}
+
+// StartTracing starts tracing the provided phase if the platform provided an OTLP endpoint,
+// so that Exit can export the trace.
+func StartTracing(phase, platformAPI string, getenv func(string) string) error {
+	return tracing.Configure(phase, getenv,
+		tracing.String("cnb.lifecycle.version", Version),
+		tracing.String("cnb.platform.api", platformAPI),
+	)
+}
+
+func exportTrace(err error) {
+	if err := tracing.Shutdown(err); err != nil {
+		DefaultLogger.Debugf("Failed to export trace: %s", err)
+	}
+}
+
+// StartMetrics starts recording the metrics of the provided phase if the platform provided a destination,
+// so that Exit can write them.
+func StartMetrics(phase string, getenv func(string) string) error {
+	return metrics.Configure(phase, getenv)
+}
+
+func writeMetrics(err error) {
+	if err := metrics.Complete(err); err != nil {
+		DefaultLogger.Debugf("Failed to write metrics: %s", err)
+	}
+}
+
+// StartProgress starts the progress event stream of the provided phase if the platform provided a file descriptor or socket,
+// so that Exit can finish it.
+func StartProgress(phase string, getenv func(string) string) error {
+	return progress.Configure(phase, getenv)
+}
+
+func finishProgress(err error, code int) {
+	if err := progress.Finish(err, code); err != nil {
+		DefaultLogger.Debugf("Failed to finish progress event stream: %s", err)
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/buildpacks/lifecycle/internal/usage"
+)
+
+// UsageTracker provides the location of the file used to share resource usage between phases.
+// The file is in the layers directory, whose disk usage is recorded for each phase.
+type UsageTracker interface {
+	UsageFile() string
+}
+
+var usageFile string
+
+// TrackUsage loads the resource usage recorded by previous phases, and starts recording the usage of the provided phase,
+// so that Exit can save them together.
+func TrackUsage(tracker UsageTracker, phase string) {
+	usageFile = tracker.UsageFile()
+	if usageFile == "" {
+		return
+	}
+	if err := usage.Load(usageFile); err != nil {
+		DefaultLogger.Debugf("Failed to read usage file: %s", err)
+	}
+	usage.Start(phase, filepath.Dir(usageFile))
+}
+
+func saveUsage() {
+	if usageFile == "" {
+		return
+	}
+	if err := usage.Save(usageFile); err != nil {
+		DefaultLogger.Debugf("Failed to write usage file: %s", err)
+	}
+}
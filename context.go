@@ -0,0 +1,59 @@
+package lifecycle
+
+import (
+	"context"
+	"io"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/internal/chaos"
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// contextReader stops reading when the context is done, e.g., to stop extracting a layer.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// The executors provided by platforms may not support cancellation;
+// in that case the context is only checked before each buildpack (or extension) is run.
+
+func detectWithContext(ctx context.Context, executor buildpack.DetectExecutor, d buildpack.Descriptor, inputs buildpack.DetectInputs, logger log.Logger) buildpack.DetectOutputs {
+	if err := ctx.Err(); err != nil {
+		return buildpack.DetectOutputs{Code: -1, Err: err}
+	}
+	if e, ok := executor.(buildpack.ContextDetectExecutor); ok {
+		return e.DetectContext(ctx, d, inputs, logger)
+	}
+	return executor.Detect(d, inputs, logger)
+}
+
+func buildWithContext(ctx context.Context, executor buildpack.BuildExecutor, d buildpack.BpDescriptor, inputs buildpack.BuildInputs, logger log.Logger) (buildpack.BuildOutputs, error) {
+	if err := chaos.DelayBuildpack(ctx, d.Buildpack.ID); err != nil {
+		return buildpack.BuildOutputs{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return buildpack.BuildOutputs{}, err
+	}
+	if e, ok := executor.(buildpack.ContextBuildExecutor); ok {
+		return e.BuildContext(ctx, d, inputs, logger)
+	}
+	return executor.Build(d, inputs, logger)
+}
+
+func generateWithContext(ctx context.Context, executor buildpack.GenerateExecutor, d buildpack.ExtDescriptor, inputs buildpack.GenerateInputs, logger log.Logger) (buildpack.GenerateOutputs, error) {
+	if err := ctx.Err(); err != nil {
+		return buildpack.GenerateOutputs{}, err
+	}
+	if e, ok := executor.(buildpack.ContextGenerateExecutor); ok {
+		return e.GenerateContext(ctx, d, inputs, logger)
+	}
+	return executor.Generate(d, inputs, logger)
+}
@@ -0,0 +1,82 @@
+package lifecycle
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/layers"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
+)
+
+// DebugArtifactsLayerID is the ID of the layer of the debug image containing the debug artifacts.
+const DebugArtifactsLayerID = "buildpacksio/lifecycle:debug-artifacts"
+
+// exportDebugImage adds the build-only layers and the debug artifacts to the debug image, and saves it.
+// The debug image is labeled with the reference of the application image, so that it can be traced back to it.
+func (e *Exporter) exportDebugImage(opts ExportOptions, appImage files.ImageReport) (*files.ImageReport, error) {
+	for _, bp := range e.Buildpacks {
+		bpDir, err := buildpack.ReadLayersDir(opts.LayersDir, bp, e.Logger)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading layers for buildpack '%s'", bp.ID)
+		}
+		for _, fsLayer := range bpDir.FindLayers(madeBuildOnly) {
+			fsLayer := fsLayer
+			if !fsLayer.HasLocalContents() {
+				continue
+			}
+			createdBy := fmt.Sprintf(layers.BuildpackLayerName, fsLayer.Name(), fmt.Sprintf("%s@%s", bp.ID, bp.Version))
+			layer, err := e.LayerFactory.DirLayer(fsLayer.Identifier(), fsLayer.Path(), createdBy)
+			if err != nil {
+				return nil, errors.Wrapf(err, "creating layer '%s'", fsLayer.Identifier())
+			}
+			e.Logger.Debugf("Adding build layer '%s' to debug image\n", layer.ID)
+			if err = e.addLayer(opts.DebugImage, layer); err != nil {
+				return nil, errors.Wrapf(err, "adding layer '%s'", layer.ID)
+			}
+		}
+	}
+
+	var artifacts []string
+	for _, path := range opts.DebugArtifacts {
+		if _, err := os.Lstat(path); err != nil {
+			if os.IsNotExist(err) {
+				e.Logger.Debugf("Skipping missing debug artifact '%s'", path)
+				continue
+			}
+			return nil, err
+		}
+		artifacts = append(artifacts, path)
+	}
+	if len(artifacts) > 0 {
+		layer, err := e.LayerFactory.FilesLayer(DebugArtifactsLayerID, artifacts, layers.DebugArtifactsLayerName)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating debug artifacts layer")
+		}
+		e.Logger.Debugf("Adding debug artifacts layer to debug image\n")
+		if err = e.addLayer(opts.DebugImage, layer); err != nil {
+			return nil, errors.Wrap(err, "adding debug artifacts layer")
+		}
+	}
+
+	appImageRef := opts.WorkingImage.Name()
+	if appImage.Digest != "" {
+		appImageRef = fmt.Sprintf("%s@%s", appImageRef, appImage.Digest)
+	}
+	if err := opts.DebugImage.SetLabel(platform.DebugAppImageLabel, appImageRef); err != nil {
+		return nil, errors.Wrap(err, "set debug image label")
+	}
+	report, err := saveImage(opts.DebugImage, nil, e.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func madeBuildOnly(l buildpack.Layer) bool {
+	md, err := l.Read()
+	return err == nil && md.Build && !md.Launch
+}
@@ -1,6 +1,7 @@
 package lifecycle
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sync"
@@ -16,6 +17,9 @@ import (
 	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/platform/files"
+	"github.com/buildpacks/lifecycle/telemetry/metrics"
+	"github.com/buildpacks/lifecycle/telemetry/progress"
+	"github.com/buildpacks/lifecycle/telemetry/tracing"
 )
 
 const (
@@ -56,13 +60,17 @@ func NewDetectorFactory(
 
 type Detector struct {
 	AppDir         string
+	AppSymlinks    string // how symlinks pointing outside of the app directory are handled (see ApplyAppSymlinkPolicy)
 	BuildConfigDir string
 	DirStore       DirStore
 	Executor       buildpack.DetectExecutor
+	Constraints    files.Constraints // versions of dependencies enforced by the platform in the plan
+	ExplainEnv     bool              // log the environment of each buildpack, with the env files that contributed to each variable
 	HasExtensions  bool
 	Logger         log.LoggerHandlerWithLevel
 	Order          buildpack.Order
 	PlatformDir    string
+	ProjectEnv     []string // "NAME=value" env vars declared in the project descriptor
 	Resolver       DetectResolver
 	Runs           *sync.Map
 	AnalyzeMD      files.Analyzed
@@ -125,9 +133,24 @@ func (f *DetectorFactory) verifyAPIs(orderBp buildpack.Order, orderExt buildpack
 	return nil
 }
 
+// Detect is equivalent to DetectContext with context.Background().
+//
+// Deprecated: use DetectContext.
 func (d *Detector) Detect() (buildpack.Group, files.Plan, error) {
+	return d.DetectContext(context.Background())
+}
+
+// DetectContext runs detection for the order of the detector, and returns the first group that passes with its build plan.
+// If the context is done, the running buildpacks are stopped (when supported by the detect executor) and the context error is returned.
+func (d *Detector) DetectContext(ctx context.Context) (buildpack.Group, files.Plan, error) {
 	defer log.NewMeasurement("Detector", d.Logger)()
-	group, plan, detectErr := d.DetectOrder(d.Order)
+	if err := ApplyAppSymlinkPolicy(d.AppDir, d.AppSymlinks, d.Logger); err != nil {
+		return buildpack.Group{}, files.Plan{}, err
+	}
+	group, plan, detectErr := d.DetectOrderContext(ctx, d.Order)
+	if detectErr == nil && d.PlatformAPI.AtLeast("0.12") {
+		detectErr = d.addDigests(&group)
+	}
 	for _, e := range d.memHandler.Entries {
 		if detectErr != nil || e.Level >= d.Logger.LogLevel() {
 			if err := d.Logger.HandleLog(e); err != nil {
@@ -138,8 +161,20 @@ func (d *Detector) Detect() (buildpack.Group, files.Plan, error) {
 	return group, plan, detectErr
 }
 
+// DetectOrder is equivalent to DetectOrderContext with context.Background().
+//
+// Deprecated: use DetectOrderContext.
 func (d *Detector) DetectOrder(order buildpack.Order) (buildpack.Group, files.Plan, error) {
-	detected, planEntries, err := d.detectOrder(order, nil, nil, false, &sync.WaitGroup{})
+	return d.DetectOrderContext(context.Background(), order)
+}
+
+// DetectOrderContext runs detection for the provided order, and returns the first group that passes with its build plan.
+func (d *Detector) DetectOrderContext(ctx context.Context, order buildpack.Order) (buildpack.Group, files.Plan, error) {
+	detected, planEntries, err := d.detectOrder(ctx, order, nil, nil, false, &sync.WaitGroup{})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// buildpacks that were stopped fail detection, which should not be reported as such
+		return buildpack.Group{}, files.Plan{}, ctxErr
+	}
 	if err == ErrBuildpack {
 		err = buildpack.NewError(err, buildpack.ErrTypeBuildpack)
 	} else if err == ErrFailedDetection {
@@ -151,10 +186,41 @@ func (d *Detector) DetectOrder(order buildpack.Order) (buildpack.Group, files.Pl
 		}
 	}
 	return buildpack.Group{Group: filter(detected, buildpack.KindBuildpack), GroupExtensions: filter(detected, buildpack.KindExtension)},
-		files.Plan{Entries: planEntries},
+		files.Plan{Entries: planEntries}.WithConstraints(d.Constraints),
 		err
 }
 
+// addDigests records the digest of the directory of each buildpack and extension in the group,
+// so that platforms can tell exactly which buildpacks produced an image.
+// Descriptors that were not read from a directory have no digest.
+func (d *Detector) addDigests(group *buildpack.Group) error {
+	for i, el := range group.Group {
+		descriptor, err := d.DirStore.LookupBp(el.ID, el.Version)
+		if err != nil {
+			return err
+		}
+		if descriptor.WithRootDir == "" {
+			continue
+		}
+		if group.Group[i].Digest, err = buildpack.DirDigest(descriptor.WithRootDir); err != nil {
+			return err
+		}
+	}
+	for i, el := range group.GroupExtensions {
+		descriptor, err := d.DirStore.LookupExt(el.ID, el.Version)
+		if err != nil {
+			return err
+		}
+		if descriptor.WithRootDir == "" {
+			continue
+		}
+		if group.GroupExtensions[i].Digest, err = buildpack.DirDigest(descriptor.WithRootDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func filter(group []buildpack.GroupElement, kind string) []buildpack.GroupElement {
 	var out []buildpack.GroupElement
 	for _, el := range group {
@@ -165,12 +231,12 @@ func filter(group []buildpack.GroupElement, kind string) []buildpack.GroupElemen
 	return out
 }
 
-func (d *Detector) detectOrder(order buildpack.Order, done, next []buildpack.GroupElement, optional bool, wg *sync.WaitGroup) ([]buildpack.GroupElement, []files.BuildPlanEntry, error) {
+func (d *Detector) detectOrder(ctx context.Context, order buildpack.Order, done, next []buildpack.GroupElement, optional bool, wg *sync.WaitGroup) ([]buildpack.GroupElement, []files.BuildPlanEntry, error) {
 	ngroup := buildpack.Group{Group: next}
 	buildpackErr := false
 	for _, group := range order {
 		// FIXME: double-check slice safety here
-		found, plan, err := d.detectGroup(group.Append(ngroup), done, wg)
+		found, plan, err := d.detectGroup(ctx, group.Append(ngroup), done, wg)
 		if err == ErrBuildpack {
 			buildpackErr = true
 		}
@@ -181,7 +247,7 @@ func (d *Detector) detectOrder(order buildpack.Order, done, next []buildpack.Gro
 		return found, plan, err
 	}
 	if optional {
-		return d.detectGroup(ngroup, done, wg)
+		return d.detectGroup(ctx, ngroup, done, wg)
 	}
 
 	if buildpackErr {
@@ -204,7 +270,7 @@ func hasWildcard(ts []buildpack.TargetMetadata) bool {
 	return false
 }
 
-func (d *Detector) detectGroup(group buildpack.Group, done []buildpack.GroupElement, wg *sync.WaitGroup) ([]buildpack.GroupElement, []files.BuildPlanEntry, error) {
+func (d *Detector) detectGroup(ctx context.Context, group buildpack.Group, done []buildpack.GroupElement, wg *sync.WaitGroup) ([]buildpack.GroupElement, []files.BuildPlanEntry, error) {
 	// used below to mark each item as "done" by appending it to the done list
 	markDone := func(groupEl buildpack.GroupElement, descriptor buildpack.Descriptor) {
 		done = append(done, groupEl.WithAPI(descriptor.API()).WithHomepage(descriptor.Homepage()))
@@ -218,7 +284,7 @@ func (d *Detector) detectGroup(group buildpack.Group, done []buildpack.GroupElem
 
 		// Resolve order if element is the order for extensions.
 		if groupEl.IsExtensionsOrder() {
-			return d.detectOrder(groupEl.OrderExtensions, done, group.Group[i+1:], true, wg)
+			return d.detectOrder(ctx, groupEl.OrderExtensions, done, group.Group[i+1:], true, wg)
 		}
 
 		// Lookup element in store.  <-- "the store" is the directory where all the buildpacks are.
@@ -236,7 +302,7 @@ func (d *Detector) detectGroup(group buildpack.Group, done []buildpack.GroupElem
 			if order := bpDescriptor.Order; len(order) > 0 {
 				// FIXME: double-check slice safety here
 				// FIXME: cyclical references lead to infinite recursion
-				return d.detectOrder(order, done, group.Group[i+1:], groupEl.Optional, wg)
+				return d.detectOrder(ctx, order, done, group.Group[i+1:], groupEl.Optional, wg)
 			}
 			descriptor = bpDescriptor // standardize the type so below we don't have to care whether it was an extension
 		} else {
@@ -279,22 +345,36 @@ func (d *Detector) detectGroup(group buildpack.Group, done []buildpack.GroupElem
 		// Run detect if element is a component buildpack or an extension.
 		wg.Add(1)
 		key := keyFor(groupEl)
-		go func(key string, descriptor buildpack.Descriptor) {
+		go func(key string, groupEl buildpack.GroupElement, descriptor buildpack.Descriptor) {
 			if _, ok := d.Runs.Load(key); !ok {
 				inputs := buildpack.DetectInputs{
 					AppDir:         d.AppDir,
 					BuildConfigDir: d.BuildConfigDir,
 					PlatformDir:    d.PlatformDir,
 				}
+				var detectEnv *env.Env
 				if d.AnalyzeMD.RunImage != nil && d.AnalyzeMD.RunImage.TargetMetadata != nil && d.PlatformAPI.AtLeast("0.12") {
-					inputs.Env = env.NewBuildEnv(append(os.Environ(), platform.EnvVarsFor(*d.AnalyzeMD.RunImage.TargetMetadata)...))
+					detectEnv = withProjectEnv(env.NewBuildEnv(append(os.Environ(), platform.EnvVarsFor(*d.AnalyzeMD.RunImage.TargetMetadata)...)), d.ProjectEnv)
+					inputs.Target = platform.BuildpackTarget(*d.AnalyzeMD.RunImage.TargetMetadata)
 				} else {
-					inputs.Env = env.NewBuildEnv(os.Environ())
+					detectEnv = withProjectEnv(env.NewBuildEnv(os.Environ()), d.ProjectEnv)
+				}
+				inputs.Env = detectEnv
+				if d.ExplainEnv {
+					explainEnv(d.Logger, "detect", groupEl, clearsEnv(descriptor), detectEnv, d.PlatformDir, d.BuildConfigDir)
 				}
-				d.Runs.Store(key, d.Executor.Detect(descriptor, inputs, d.Logger)) // this is where we finally invoke bin/detect
+				span := tracing.Start("detect "+groupEl.ID, tracing.String("cnb.buildpack.id", groupEl.ID), tracing.String("cnb.buildpack.version", groupEl.Version))
+				stopTimer := metrics.Timer(metrics.BuildpackDuration, metrics.L("buildpack", groupEl.ID), metrics.L("version", groupEl.Version), metrics.L("step", "detect"))
+				finishProgress := progress.BuildpackStarted(groupEl.ID, groupEl.Version, progress.StepDetect)
+				result := detectWithContext(ctx, d.Executor, descriptor, inputs, d.Logger) // this is where we finally invoke bin/detect
+				stopTimer()
+				finishProgress(result.Err)
+				span.SetAttributes(tracing.Int("cnb.detect.code", result.Code))
+				span.End(result.Err)
+				d.Runs.Store(key, result)
 			}
 			wg.Done()
-		}(key, descriptor)
+		}(key, groupEl, descriptor)
 	}
 
 	wg.Wait()
@@ -1,8 +1,10 @@
 package lifecycle_test
 
 import (
+	"context"
 	"errors"
 	"io"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
@@ -149,6 +151,55 @@ func testDetector(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#ApplyProjectDescriptor", func() {
+		var detector *lifecycle.Detector
+
+		it.Before(func() {
+			detector = &lifecycle.Detector{
+				AppDir: "some-app-dir",
+				Order: buildpack.Order{
+					{Group: []buildpack.GroupElement{{ID: "A", Version: "v1"}, {ID: "B", Version: "v1"}}},
+					{Group: []buildpack.GroupElement{{ID: "C", Version: "v2"}}},
+				},
+			}
+		})
+
+		it("replaces the order with the buildpack group in the descriptor", func() {
+			bpC2 := &buildpack.BpDescriptor{WithAPI: "0.9"}
+			dirStore.EXPECT().Lookup(buildpack.KindBuildpack, "C", "v2").Return(bpC2, nil)
+			apiVerifier.EXPECT().VerifyBuildpackAPI(buildpack.KindBuildpack, "C@v2", "0.9", logger)
+			bpD1 := &buildpack.BpDescriptor{WithAPI: "0.9"}
+			dirStore.EXPECT().Lookup(buildpack.KindBuildpack, "D", "v1").Return(bpD1, nil)
+			apiVerifier.EXPECT().VerifyBuildpackAPI(buildpack.KindBuildpack, "D@v1", "0.9", logger)
+
+			err := detectorFactory.ApplyProjectDescriptor(detector, files.ProjectDescriptor{
+				Buildpacks: []files.ProjectBuildpack{{ID: "C"}, {ID: "D", Version: "v1"}},
+				Env:        []files.ProjectEnvVar{{Name: "SOME_VAR", Value: "some-value"}},
+			}, logger)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, detector.Order, buildpack.Order{
+				{Group: []buildpack.GroupElement{{ID: "C", Version: "v2"}, {ID: "D", Version: "v1"}}},
+			})
+			h.AssertEq(t, detector.ProjectEnv, []string{"SOME_VAR=some-value"})
+		})
+
+		it("errors when a buildpack version cannot be determined", func() {
+			err := detectorFactory.ApplyProjectDescriptor(detector, files.ProjectDescriptor{
+				Buildpacks: []files.ProjectBuildpack{{ID: "E"}},
+			}, logger)
+			h.AssertError(t, err, "buildpack 'E' in project descriptor must have a version")
+		})
+
+		it("keeps the order when the descriptor has no buildpacks", func() {
+			order := detector.Order
+
+			h.AssertNil(t, detectorFactory.ApplyProjectDescriptor(detector, files.ProjectDescriptor{}, logger))
+
+			h.AssertEq(t, detector.Order, order)
+		})
+	})
+
 	when(".Detect", func() {
 		var (
 			detector *lifecycle.Detector
@@ -199,6 +250,23 @@ func testDetector(t *testing.T, when spec.G, it spec.S) {
 			_, _, _ = detector.Detect()
 		})
 
+		when("the context is done", func() {
+			it("does not run detect and returns the context error", func() {
+				bpA1 := &buildpack.BpDescriptor{
+					WithAPI:   "0.3",
+					Buildpack: buildpack.BpInfo{BaseInfo: buildpack.BaseInfo{ID: "A", Version: "v1"}},
+				}
+				dirStore.EXPECT().LookupBp("A", "v1").Return(bpA1, nil).AnyTimes()
+				resolver.EXPECT().Resolve(gomock.Any(), detector.Runs).Return(nil, nil, lifecycle.ErrFailedDetection)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				detector.Order = buildpack.Order{{Group: []buildpack.GroupElement{{ID: "A", Version: "v1"}}}}
+				_, _, err := detector.DetectContext(ctx)
+				h.AssertEq(t, errors.Is(err, context.Canceled), true)
+			})
+		})
+
 		it("passes through the CNB_TARGET_* env vars", func() {
 			bpA1 := &buildpack.BpDescriptor{
 				WithAPI:   "0.8",
@@ -667,6 +735,43 @@ func testDetector(t *testing.T, when spec.G, it spec.S) {
 			}
 		})
 
+		it("applies the constraints of the platform to the plan", func() {
+			bpA1 := &buildpack.BpDescriptor{
+				WithAPI:   "0.3",
+				Buildpack: buildpack.BpInfo{BaseInfo: buildpack.BaseInfo{ID: "A", Version: "v1"}},
+			}
+			dirStore.EXPECT().LookupBp("A", "v1").Return(bpA1, nil).AnyTimes()
+			executor.EXPECT().Detect(bpA1, gomock.Any(), gomock.Any())
+
+			group := []buildpack.GroupElement{{ID: "A", Version: "v1", API: "0.3"}}
+			resolver.EXPECT().Resolve(group, detector.Runs).Return(group, []files.BuildPlanEntry{
+				{
+					Providers: []buildpack.GroupElement{{ID: "A", Version: "v1"}},
+					Requires: []buildpack.Require{
+						{Name: "node", Version: "16.x"},
+						{Name: "other-dep", Version: "some-version"},
+					},
+				},
+			}, nil)
+
+			detector.Order = buildpack.Order{{Group: group}}
+			detector.Constraints = files.Constraints{Entries: []files.Constraint{{Name: "node", Version: "18.x"}}}
+			_, plan, err := detector.Detect()
+			h.AssertNil(t, err)
+
+			if !hasEntries(plan.Entries, []files.BuildPlanEntry{
+				{
+					Providers: []buildpack.GroupElement{{ID: "A", Version: "v1"}},
+					Requires: []buildpack.Require{
+						{Name: "node", Metadata: map[string]interface{}{"version": "18.x", "version-source": "platform", "requested-version": "16.x"}},
+						{Name: "other-dep", Metadata: map[string]interface{}{"version": "some-version"}},
+					},
+				},
+			}) {
+				t.Fatalf("Unexpected entries:\n%+v\n", plan.Entries)
+			}
+		})
+
 		it("updates detect runs for each buildpack", func() {
 			bpA1 := &buildpack.BpDescriptor{
 				WithAPI:   "0.3",
@@ -751,6 +856,29 @@ func testDetector(t *testing.T, when spec.G, it spec.S) {
 			}
 		})
 
+		it("records the digest of each buildpack read from a directory", func() {
+			bpDir := t.TempDir()
+			h.Mkfile(t, "some-detect-script", filepath.Join(bpDir, "detect"))
+			bpA1 := &buildpack.BpDescriptor{
+				WithAPI:     "0.3",
+				WithRootDir: bpDir,
+				Buildpack:   buildpack.BpInfo{BaseInfo: buildpack.BaseInfo{ID: "A", Version: "v1"}},
+			}
+			dirStore.EXPECT().LookupBp("A", "v1").Return(bpA1, nil).AnyTimes()
+			executor.EXPECT().Detect(bpA1, gomock.Any(), gomock.Any())
+
+			group := []buildpack.GroupElement{{ID: "A", Version: "v1", API: "0.3"}}
+			resolver.EXPECT().Resolve(group, detector.Runs).Return(group, []files.BuildPlanEntry{}, nil)
+
+			detector.Order = buildpack.Order{{Group: group}}
+			found, _, err := detector.Detect()
+			h.AssertNil(t, err)
+
+			digest, err := buildpack.DirDigest(bpDir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, found.Group[0].Digest, digest)
+		})
+
 		it("preserves 'optional'", func() {
 			bpA1 := &buildpack.BpDescriptor{
 				WithAPI:   "0.3",
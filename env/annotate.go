@@ -0,0 +1,75 @@
+package env
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Modification is a change to the value of an environment variable.
+type Modification struct {
+	Action ActionType
+	// Source is the env file (or layer directory) that made the modification, or empty if the variable was set by the lifecycle.
+	Source string
+}
+
+// AnnotatedVar is an environment variable with the modifications that contributed to its value, in the order they were made.
+// Variables without modifications were inherited from the environment of the lifecycle.
+type AnnotatedVar struct {
+	Name          string
+	Value         string
+	Modifications []Modification
+}
+
+// Annotated returns the environment, sorted by variable name, with the modifications that contributed to each variable.
+// As an override replaces the value of a variable, the modifications made before the last override are not included.
+func (p *Env) Annotated() []AnnotatedVar {
+	var result []AnnotatedVar
+	for k, v := range p.Vars.vals {
+		result = append(result, AnnotatedVar{Name: k, Value: v, Modifications: p.modifications[k]})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// FormatAnnotated returns a description of the provided environment, listing each variable followed by its modifications.
+func FormatAnnotated(vars []AnnotatedVar) string {
+	var sb strings.Builder
+	for _, v := range vars {
+		sb.WriteString(fmt.Sprintf("%s=%s\n", v.Name, v.Value))
+		if len(v.Modifications) == 0 {
+			sb.WriteString("    (inherited)\n")
+			continue
+		}
+		for _, mod := range v.Modifications {
+			source := mod.Source
+			if source == "" {
+				source = "(set by the lifecycle)"
+			}
+			sb.WriteString(fmt.Sprintf("    %-12s %s\n", describeAction(mod.Action), source))
+		}
+	}
+	return sb.String()
+}
+
+func describeAction(action ActionType) string {
+	if action == ActionTypePrependPath {
+		return "prepend-path"
+	}
+	return string(action)
+}
+
+func (p *Env) modify(name, value string, mod Modification) {
+	p.Vars.Set(name, value)
+	if p.modifications == nil {
+		p.modifications = make(map[string][]Modification)
+	}
+	key := p.Vars.key(name)
+	if mod.Action == ActionTypeOverride || mod.Action == ActionTypeDefault {
+		p.modifications[key] = []Modification{mod}
+		return
+	}
+	p.modifications[key] = append(p.modifications[key], mod)
+}
@@ -0,0 +1,157 @@
+package env_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/env"
+)
+
+func TestAnnotate(t *testing.T) {
+	spec.Run(t, "Annotate", testAnnotate, spec.Report(report.Terminal{}))
+}
+
+func testAnnotate(t *testing.T, when spec.G, it spec.S) {
+	var (
+		envv   *env.Env
+		tmpDir string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle")
+		if err != nil {
+			t.Fatalf("Error: %s\n", err)
+		}
+		envv = &env.Env{
+			RootDirMap: map[string][]string{
+				"bin": {"PATH"},
+			},
+			Vars: env.NewVars(map[string]string{
+				"PATH":      "some-path",
+				"INHERITED": "some-value",
+			}, false),
+		}
+	})
+
+	it.After(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	when("#Annotated", func() {
+		it("annotates each variable with the modifications that contributed to its value", func() {
+			layerDir := filepath.Join(tmpDir, "some-layer")
+			mkdir(t, filepath.Join(layerDir, "bin"), filepath.Join(layerDir, "env"))
+			mkfile(t, "some-append", filepath.Join(layerDir, "env", "PATH.append"))
+			mkfile(t, "first-override", filepath.Join(layerDir, "env", "OVERRIDDEN.override"))
+			otherEnvDir := filepath.Join(tmpDir, "other-env")
+			mkdir(t, otherEnvDir)
+			mkfile(t, "second-override", filepath.Join(otherEnvDir, "OVERRIDDEN.override"))
+
+			if err := envv.AddRootDir(layerDir); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+			if err := envv.AddEnvDir(filepath.Join(layerDir, "env"), env.ActionTypeOverride); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+			if err := envv.AddEnvDir(otherEnvDir, env.ActionTypeOverride); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+			envv.Set("SET", "some-value")
+
+			expected := []env.AnnotatedVar{
+				{Name: "INHERITED", Value: "some-value"},
+				{
+					Name:  "OVERRIDDEN",
+					Value: "second-override",
+					Modifications: []env.Modification{
+						{Action: env.ActionTypeOverride, Source: filepath.Join(otherEnvDir, "OVERRIDDEN.override")},
+					},
+				},
+				{
+					Name:  "PATH",
+					Value: filepath.Join(layerDir, "bin") + string(os.PathListSeparator) + "some-pathsome-append",
+					Modifications: []env.Modification{
+						{Action: env.ActionTypePrepend, Source: filepath.Join(layerDir, "bin")},
+						{Action: env.ActionTypeAppend, Source: filepath.Join(layerDir, "env", "PATH.append")},
+					},
+				},
+				{
+					Name:          "SET",
+					Value:         "some-value",
+					Modifications: []env.Modification{{Action: env.ActionTypeOverride}},
+				},
+			}
+			if s := cmp.Diff(envv.Annotated(), expected); s != "" {
+				t.Fatalf("Unexpected env:\n%s\n", s)
+			}
+		})
+	})
+
+	when("#AnnotatedWithOverrides", func() {
+		it("includes the platform and build config env files without modifying the env", func() {
+			mkdir(t, filepath.Join(tmpDir, "platform", "env"), filepath.Join(tmpDir, "build-config", "env"))
+			mkfile(t, "platform-value", filepath.Join(tmpDir, "platform", "env", "INHERITED"))
+			mkfile(t, "config-value", filepath.Join(tmpDir, "build-config", "env", "CONFIG.default"))
+
+			annotated, err := envv.AnnotatedWithOverrides(filepath.Join(tmpDir, "platform"), filepath.Join(tmpDir, "build-config"))
+			if err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+
+			expected := []env.AnnotatedVar{
+				{
+					Name:  "CONFIG",
+					Value: "config-value",
+					Modifications: []env.Modification{
+						{Action: env.ActionTypeDefault, Source: filepath.Join(tmpDir, "build-config", "env", "CONFIG.default")},
+					},
+				},
+				{
+					Name:  "INHERITED",
+					Value: "platform-value",
+					Modifications: []env.Modification{
+						{Action: env.ActionTypeOverride, Source: filepath.Join(tmpDir, "platform", "env", "INHERITED")},
+					},
+				},
+				{Name: "PATH", Value: "some-path"},
+			}
+			if s := cmp.Diff(annotated, expected); s != "" {
+				t.Fatalf("Unexpected env:\n%s\n", s)
+			}
+			if s := cmp.Diff(envv.Get("INHERITED"), "some-value"); s != "" {
+				t.Fatalf("Unexpected env:\n%s\n", s)
+			}
+		})
+	})
+
+	when(".FormatAnnotated", func() {
+		it("lists each variable followed by its modifications", func() {
+			out := env.FormatAnnotated([]env.AnnotatedVar{
+				{Name: "INHERITED", Value: "some-value"},
+				{
+					Name:  "PATH",
+					Value: "/layers/some-layer/bin:/usr/bin",
+					Modifications: []env.Modification{
+						{Action: env.ActionTypePrependPath, Source: "/layers/some-layer/env/PATH"},
+						{Action: env.ActionTypeOverride},
+					},
+				},
+			})
+
+			expected := "INHERITED=some-value\n" +
+				"    (inherited)\n" +
+				"PATH=/layers/some-layer/bin:/usr/bin\n" +
+				"    prepend-path /layers/some-layer/env/PATH\n" +
+				"    override     (set by the lifecycle)\n"
+			if s := cmp.Diff(out, expected); s != "" {
+				t.Fatalf("Unexpected output:\n%s\n", s)
+			}
+		})
+	})
+}
@@ -15,6 +15,9 @@ type Env struct {
 	// RootDirMap maps directories in a posix root filesystem to a slice of environment variables that
 	RootDirMap map[string][]string
 	Vars       *Vars
+
+	// modifications are the modifications that contributed to the value of each variable, keyed by variable name.
+	modifications map[string][]Modification
 }
 
 // AddRootDir modifies the environment given a root dir. If the root dir contains a directory that matches a key in
@@ -33,7 +36,7 @@ func (p *Env) AddRootDir(dir string) error {
 			return err
 		}
 		for _, key := range vars {
-			p.Vars.Set(key, childDir+prefix(p.Vars.Get(key), os.PathListSeparator))
+			p.modify(key, childDir+prefix(p.Vars.Get(key), os.PathListSeparator), Modification{Action: ActionTypePrepend, Source: childDir})
 		}
 	}
 	return nil
@@ -73,12 +76,12 @@ func DefaultActionType(bpAPI *api.Version) ActionType {
 // a period delimited suffix, the action matching the given suffix will be performed. If the file has no suffix,
 // the default action will be performed. If the suffix does not match a known type, AddEnvDir will ignore the file.
 func (p *Env) AddEnvDir(envDir string, defaultAction ActionType) error {
-	return addEnvDir(p.Vars, envDir, defaultAction)
+	return p.addEnvDir(envDir, defaultAction)
 }
 
 // Set sets the environment variable with the given name to the given value.
 func (p *Env) Set(name, v string) {
-	p.Vars.Set(name, v)
+	p.modify(name, v, Modification{Action: ActionTypeOverride})
 }
 
 // WithOverrides returns the environment after applying modifications from the given platform dir and build config
@@ -90,15 +93,42 @@ func (p *Env) Set(name, v string) {
 // a period delimited suffix, the action matching the given suffix will be performed. If the file has no suffix,
 // the default action will be performed. If the suffix does not match a known type, AddEnvDir will ignore the file.
 func (p *Env) WithOverrides(platformDir string, baseConfigDir string) (output []string, err error) {
-	vars := NewVars(p.Vars.vals, p.Vars.ignoreCase)
+	overridden, err := p.withOverrides(platformDir, baseConfigDir)
+	if err != nil {
+		return nil, err
+	}
+	return overridden.List(), nil
+}
+
+// AnnotatedWithOverrides returns the annotated environment after applying modifications from the given platform dir and build config
+// dir, as in WithOverrides.
+func (p *Env) AnnotatedWithOverrides(platformDir string, baseConfigDir string) ([]AnnotatedVar, error) {
+	overridden, err := p.withOverrides(platformDir, baseConfigDir)
+	if err != nil {
+		return nil, err
+	}
+	return overridden.Annotated(), nil
+}
+
+func (p *Env) withOverrides(platformDir string, baseConfigDir string) (*Env, error) {
+	overridden := &Env{
+		RootDirMap:    p.RootDirMap,
+		Vars:          NewVars(p.Vars.vals, p.Vars.ignoreCase),
+		modifications: make(map[string][]Modification, len(p.modifications)),
+	}
+	for k, mods := range p.modifications {
+		overridden.modifications[k] = append([]Modification{}, mods...)
+	}
 
 	if platformDir != "" {
-		if err := eachEnvFile(filepath.Join(platformDir, "env"), func(k, v string) error {
+		envDir := filepath.Join(platformDir, "env")
+		if err := eachEnvFile(envDir, func(k, v string) error {
+			source := filepath.Join(envDir, k)
 			if p.isRootEnv(k) {
-				vars.Set(k, v+prefix(vars.Get(k), os.PathListSeparator))
+				overridden.modify(k, v+prefix(overridden.Vars.Get(k), os.PathListSeparator), Modification{Action: ActionTypePrepend, Source: source})
 				return nil
 			}
-			vars.Set(k, v)
+			overridden.modify(k, v, Modification{Action: ActionTypeOverride, Source: source})
 			return nil
 		}); err != nil {
 			return nil, err
@@ -106,15 +136,16 @@ func (p *Env) WithOverrides(platformDir string, baseConfigDir string) (output []
 	}
 
 	if baseConfigDir != "" {
-		if err := addEnvDir(vars, filepath.Join(baseConfigDir, "env"), ActionTypeDefault); err != nil {
+		if err := overridden.addEnvDir(filepath.Join(baseConfigDir, "env"), ActionTypeDefault); err != nil {
 			return nil, err
 		}
 	}
 
-	return vars.List(), nil
+	return overridden, nil
 }
 
-func addEnvDir(vars *Vars, envDir string, defaultAction ActionType) error {
+func (p *Env) addEnvDir(envDir string, defaultAction ActionType) error {
+	vars := p.Vars
 	if err := eachEnvFile(envDir, func(k, v string) error {
 		parts := strings.SplitN(k, ".", 2)
 		name := parts[0]
@@ -124,20 +155,21 @@ func addEnvDir(vars *Vars, envDir string, defaultAction ActionType) error {
 		} else {
 			action = defaultAction
 		}
+		mod := Modification{Action: action, Source: filepath.Join(envDir, k)}
 		switch action {
 		case ActionTypePrepend:
-			vars.Set(name, v+prefix(vars.Get(name), delim(envDir, name)...))
+			p.modify(name, v+prefix(vars.Get(name), delim(envDir, name)...), mod)
 		case ActionTypeAppend:
-			vars.Set(name, suffix(vars.Get(name), delim(envDir, name)...)+v)
+			p.modify(name, suffix(vars.Get(name), delim(envDir, name)...)+v, mod)
 		case ActionTypeOverride:
-			vars.Set(name, v)
+			p.modify(name, v, mod)
 		case ActionTypeDefault:
 			if vars.Get(name) != "" {
 				return nil
 			}
-			vars.Set(name, v)
+			p.modify(name, v, mod)
 		case ActionTypePrependPath:
-			vars.Set(name, v+prefix(vars.Get(name), delim(envDir, name, os.PathListSeparator)...))
+			p.modify(name, v+prefix(vars.Get(name), delim(envDir, name, os.PathListSeparator)...), mod)
 		}
 		return nil
 	}); err != nil {
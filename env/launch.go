@@ -9,6 +9,7 @@ var LaunchEnvExcludelist = []string{
 	"CNB_LAYERS_DIR",
 	"CNB_APP_DIR",
 	"CNB_PROCESS_TYPE",
+	"CNB_TASK_STATE_DIR",
 	"CNB_PLATFORM_API",
 	"CNB_DEPRECATION_MODE",
 }
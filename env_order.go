@@ -0,0 +1,83 @@
+package lifecycle
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// envOrder returns the IDs of the buildpacks in the group, in the order their env contributions are applied:
+// the group order, changed only as much as needed to satisfy the env order constraints of the buildpacks.
+// Constraints on buildpacks that are not in the group are ignored. It fails if the constraints form a cycle.
+func envOrder(group []buildpack.GroupElement, constraints map[string]*buildpack.EnvOrder, logger log.Logger) ([]string, error) {
+	index := make(map[string]int, len(group))
+	for i, bp := range group {
+		index[bp.ID] = i
+	}
+	after := make([]map[int]bool, len(group)) // after[i] holds the buildpacks whose env is applied before the env of buildpack i
+	for i := range after {
+		after[i] = map[int]bool{}
+	}
+	for i, bp := range group {
+		constraint := constraints[bp.ID]
+		if constraint == nil {
+			continue
+		}
+		for _, ids := range []struct {
+			others []string
+			before bool
+		}{{constraint.Before, true}, {constraint.After, false}} {
+			for _, other := range ids.others {
+				j, ok := index[other]
+				switch {
+				case !ok:
+					logger.Debugf("Ignoring env order constraint of buildpack '%s' on '%s', which is not in the group", bp.ID, other)
+				case i == j:
+				case ids.before:
+					after[j][i] = true
+				default:
+					after[i][j] = true
+				}
+			}
+		}
+	}
+
+	// repeatedly apply the first buildpack in group order whose constraints are satisfied
+	var (
+		order   []string
+		applied = make([]bool, len(group))
+	)
+	for len(order) < len(group) {
+		next := -1
+		for i := range group {
+			if applied[i] || !allApplied(after[i], applied) {
+				continue
+			}
+			next = i
+			break
+		}
+		if next < 0 {
+			var cycle []string
+			for i, bp := range group {
+				if !applied[i] {
+					cycle = append(cycle, bp.ID)
+				}
+			}
+			return nil, fmt.Errorf("env order constraints of buildpacks %s form a cycle", strings.Join(cycle, ", "))
+		}
+		applied[next] = true
+		order = append(order, group[next].ID)
+	}
+	return order, nil
+}
+
+func allApplied(bps map[int]bool, applied []bool) bool {
+	for i := range bps {
+		if !applied[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+// Package errors provides the typed errors returned by the lifecycle, so that platforms using the lifecycle as a library
+// can handle failures programmatically with errors.As, e.g.:
+//
+//	var bpErr *lerrors.BuildpackFailedError
+//	if errors.As(err, &bpErr) {
+//		// bpErr.ID, bpErr.ExitCode
+//	}
+//
+// The errors wrap the errors that caused them; except for RegistryAuthError, they do not change their messages.
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os/exec"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+const (
+	AccessRead      = "read"
+	AccessReadWrite = "read/write"
+)
+
+// RegistryAuthError is returned when the lifecycle does not have the required access to an image in a registry,
+// e.g., because the registry rejected the provided credentials.
+type RegistryAuthError struct {
+	Image string
+	// Access is the required access, AccessRead or AccessReadWrite.
+	Access string
+	// Input is the input that the image was provided as (e.g., "cache image"), if known.
+	Input string
+	Err   error
+}
+
+func (e *RegistryAuthError) Error() string {
+	msg := fmt.Sprintf("ensure registry %s access to %s", e.Access, e.Image)
+	if e.Input != "" {
+		msg += fmt.Sprintf(" (%s)", e.Input)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", msg, e.Err)
+	}
+	return msg
+}
+
+func (e *RegistryAuthError) Unwrap() error {
+	return e.Err
+}
+
+// WrapRegistryError returns a RegistryAuthError if the registry rejected the request for the image
+// with an unauthorized or forbidden status, and err otherwise.
+func WrapRegistryError(image, access string, err error) error {
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) {
+		if transportErr.StatusCode == http.StatusUnauthorized || transportErr.StatusCode == http.StatusForbidden {
+			return &RegistryAuthError{Image: image, Access: access, Err: err}
+		}
+	}
+	return err
+}
+
+// BuildpackFailedError is returned when an executable of a buildpack (or image extension) fails, e.g., bin/build exits with a non-zero code.
+type BuildpackFailedError struct {
+	ID string
+	// ExitCode is the exit code of the executable, or -1 if it could not be run.
+	ExitCode int
+	Err      error
+}
+
+func (e *BuildpackFailedError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("buildpack %s exited with code %d", e.ID, e.ExitCode)
+}
+
+func (e *BuildpackFailedError) Unwrap() error {
+	return e.Err
+}
+
+// NewBuildpackFailedError returns a BuildpackFailedError for the buildpack with the provided ID, with the exit code of err if it is an *exec.ExitError.
+func NewBuildpackFailedError(id string, err error) *BuildpackFailedError {
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	return &BuildpackFailedError{ID: id, ExitCode: exitCode, Err: err}
+}
+
+// Position is the position of a decoding error in a file.
+type Position struct {
+	// Line is the line of the error, starting at 1, or 0 if unknown.
+	Line int
+	// Offset is the byte offset of the error, starting at 0.
+	Offset int64
+}
+
+// DecodeError is returned when a TOML or JSON file provided to (or written by) the lifecycle cannot be decoded.
+type DecodeError struct {
+	// File is the path of the file, or the name of the label for image labels.
+	File string
+	Pos  Position
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// NewDecodeError returns a DecodeError for the provided file, with the position of the error if known.
+// It returns err if it is nil or if the file could not be read, so that os.IsNotExist(err) continues to report missing files.
+func NewDecodeError(file string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return err
+	}
+	decodeErr := &DecodeError{File: file, Err: err}
+	var (
+		parseErr  toml.ParseError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &parseErr):
+		decodeErr.Pos = Position{Line: parseErr.Position.Line, Offset: int64(parseErr.Position.Start)}
+	case errors.As(err, &syntaxErr):
+		decodeErr.Pos = Position{Offset: syntaxErr.Offset}
+	case errors.As(err, &typeErr):
+		decodeErr.Pos = Position{Offset: typeErr.Offset}
+	}
+	return decodeErr
+}
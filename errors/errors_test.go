@@ -0,0 +1,129 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	lerrors "github.com/buildpacks/lifecycle/errors"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestErrors(t *testing.T) {
+	spec.Run(t, "Errors", testErrors, spec.Report(report.Terminal{}))
+}
+
+func testErrors(t *testing.T, when spec.G, it spec.S) {
+	when("#NewDecodeError", func() {
+		var tmpDir string
+
+		it.Before(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "lifecycle.test")
+			h.AssertNil(t, err)
+		})
+
+		it.After(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		it("returns the position of TOML errors", func() {
+			path := filepath.Join(tmpDir, "some.toml")
+			h.Mkfile(t, "[some-table]\nkey = = 1\nother = 2\n", path)
+			var v map[string]interface{}
+			_, err := toml.DecodeFile(path, &v)
+
+			wrapped := fmt.Errorf("reading some file: %w", lerrors.NewDecodeError(path, err))
+
+			var decodeErr *lerrors.DecodeError
+			h.AssertEq(t, errors.As(wrapped, &decodeErr), true)
+			h.AssertEq(t, decodeErr.File, path)
+			h.AssertEq(t, decodeErr.Pos.Line, 2)
+			h.AssertEq(t, wrapped.Error(), "reading some file: "+err.Error())
+		})
+
+		it("returns the offset of JSON errors", func() {
+			var v map[string]interface{}
+			err := json.Unmarshal([]byte(`{"key": }`), &v)
+
+			var decodeErr *lerrors.DecodeError
+			h.AssertEq(t, errors.As(lerrors.NewDecodeError("some-label", err), &decodeErr), true)
+			h.AssertEq(t, decodeErr.Pos.Offset, int64(9))
+		})
+
+		it("does not wrap errors reading the file", func() {
+			var v map[string]interface{}
+			_, err := toml.DecodeFile(filepath.Join(tmpDir, "missing.toml"), &v)
+
+			h.AssertEq(t, os.IsNotExist(lerrors.NewDecodeError(filepath.Join(tmpDir, "missing.toml"), err)), true)
+			h.AssertNil(t, lerrors.NewDecodeError("some-file", nil))
+		})
+	})
+
+	when("#NewBuildpackFailedError", func() {
+		it("returns the exit code of the executable", func() {
+			if runtime.GOOS == "windows" {
+				t.Skip("uses sh")
+			}
+			err := exec.Command("sh", "-c", "exit 3").Run()
+
+			bpErr := lerrors.NewBuildpackFailedError("some/bp", err)
+			h.AssertEq(t, bpErr.ID, "some/bp")
+			h.AssertEq(t, bpErr.ExitCode, 3)
+			h.AssertEq(t, bpErr.Error(), "exit status 3")
+		})
+
+		it("returns -1 if the executable could not be run", func() {
+			bpErr := lerrors.NewBuildpackFailedError("some/bp", errors.New("some-error"))
+			h.AssertEq(t, bpErr.ExitCode, -1)
+		})
+	})
+
+	when("#WrapRegistryError", func() {
+		it("returns a RegistryAuthError if the registry rejected the credentials", func() {
+			cause := &transport.Error{StatusCode: http.StatusUnauthorized}
+
+			err := lerrors.WrapRegistryError("some-image", lerrors.AccessRead, cause)
+
+			var authErr *lerrors.RegistryAuthError
+			h.AssertEq(t, errors.As(err, &authErr), true)
+			h.AssertEq(t, authErr.Image, "some-image")
+			h.AssertEq(t, authErr.Access, lerrors.AccessRead)
+			h.AssertEq(t, errors.Is(err, cause), true)
+		})
+
+		it("returns other errors unchanged", func() {
+			cause := &transport.Error{StatusCode: http.StatusInternalServerError}
+
+			err := lerrors.WrapRegistryError("some-image", lerrors.AccessRead, cause)
+
+			var authErr *lerrors.RegistryAuthError
+			h.AssertEq(t, errors.As(err, &authErr), false)
+		})
+	})
+
+	when("RegistryAuthError", func() {
+		it("describes the required access", func() {
+			err := &lerrors.RegistryAuthError{Image: "some-image", Access: lerrors.AccessReadWrite}
+
+			h.AssertEq(t, err.Error(), "ensure registry read/write access to some-image")
+		})
+
+		it("names the input and the cause", func() {
+			err := &lerrors.RegistryAuthError{Image: "some-image", Access: lerrors.AccessRead, Input: "run image", Err: errors.New("some-error")}
+
+			h.AssertEq(t, err.Error(), "ensure registry read access to some-image (run image): some-error")
+		})
+	})
+}
@@ -0,0 +1,29 @@
+package lifecycle
+
+import (
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/env"
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// explainEnv logs the environment that the buildpack (or extension) runs with in the provided phase,
+// with the env files that contributed to each variable.
+func explainEnv(logger log.Logger, phase string, el buildpack.GroupElement, clearEnv bool, buildEnv *env.Env, platformDir, buildConfigDir string) {
+	if clearEnv {
+		platformDir = ""
+	}
+	vars, err := buildEnv.AnnotatedWithOverrides(platformDir, buildConfigDir)
+	if err != nil {
+		logger.Warnf("Unable to explain the %s environment of %s: %s", phase, el, err)
+		return
+	}
+	logger.Infof("Environment of %s for %s:\n%s", el, phase, env.FormatAnnotated(vars))
+}
+
+// clearsEnv returns true if the buildpack (or extension) runs with a clear environment, i.e., without the platform env vars.
+func clearsEnv(descriptor buildpack.Descriptor) bool {
+	if d, ok := descriptor.(interface{ ClearEnv() bool }); ok {
+		return d.ClearEnv()
+	}
+	return false
+}
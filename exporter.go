@@ -2,6 +2,7 @@ package lifecycle
 
 import (
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -10,23 +11,37 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/layout"
 	"github.com/buildpacks/imgutil/local"
+	"github.com/buildpacks/imgutil/remote"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/image"
+	"github.com/buildpacks/lifecycle/internal/attest"
+	"github.com/buildpacks/lifecycle/internal/deprecation"
 	"github.com/buildpacks/lifecycle/internal/fsutil"
+	"github.com/buildpacks/lifecycle/internal/redact"
+	"github.com/buildpacks/lifecycle/internal/scan"
+	"github.com/buildpacks/lifecycle/internal/usage"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/platform/files"
+	"github.com/buildpacks/lifecycle/telemetry/metrics"
+	"github.com/buildpacks/lifecycle/telemetry/progress"
+	"github.com/buildpacks/lifecycle/telemetry/tracing"
 )
 
 type Cache interface {
@@ -45,11 +60,31 @@ type Exporter struct {
 	LayerFactory LayerFactory
 	Logger       log.Logger
 	PlatformAPI  *api.Version
+	// BuildMetadataLabelLimit is the maximum size (in bytes) of the `io.buildpacks.build.metadata` label;
+	// larger build metadata is moved to the config layer. Defaults to DefaultBuildMetadataLabelLimit.
+	BuildMetadataLabelLimit int
+	// LayerCompression is the gzip compression level of the layers added to images exported to a registry or a layout
+	// ("0" to "9"), or "auto" (the default) to choose the level of each layer from its contents.
+	LayerCompression string
+	// UploadedBlobs records the layers added to images exported to a registry, so that the cache can mount them
+	// rather than upload them again (see Cache). It is created by Export if it is nil.
+	UploadedBlobs *UploadedBlobs
+	// CacheLayerFactory creates the layers that are only added to the cache (launch=false layers and the cached SBOM),
+	// e.g., to identify them with another digest algorithm in a cache directory. Defaults to LayerFactory.
+	// Layers that are also exported are always created with LayerFactory, so that their digests in the cache
+	// match their digests in the app image.
+	CacheLayerFactory LayerFactory
 }
 
+// DefaultBuildMetadataLabelLimit is the default maximum size (in bytes) of the `io.buildpacks.build.metadata` label,
+// chosen to keep the image config well below the size limits of common registries and daemons.
+const DefaultBuildMetadataLabelLimit = 256 * 1024
+
 //go:generate mockgen -package testmock -destination testmock/layer_factory.go github.com/buildpacks/lifecycle LayerFactory
 type LayerFactory interface {
 	DirLayer(id string, dir string, createdBy string) (layers.Layer, error)
+	DirsLayer(id string, dirs []string, createdBy string) (layers.Layer, error)
+	FilesLayer(id string, files []string, createdBy string) (layers.Layer, error)
 	LauncherLayer(path string) (layers.Layer, error)
 	ProcessTypesLayer(metadata launch.Metadata) (layers.Layer, error)
 	SliceLayers(dir string, slices []layers.Slice) ([]layers.Layer, error)
@@ -66,29 +101,186 @@ type ExportOptions struct {
 	WorkingImage imgutil.Image
 	// AdditionalNames are additional tags to save to, besides WorkingImage.Name().
 	AdditionalNames []string
+	// AttestationKeychain, if provided, is used to attach the merged SBOM and the build metadata to the saved image
+	// as in-toto attestations (OCI referrer artifacts). It should only be provided when exporting to a registry.
+	AttestationKeychain authn.Keychain
+	// RegistryKeychain, if provided, is used to push the image to the additional names in other repositories than WorkingImage.Name()
+	// (e.g., in other registries), mounting its layers instead of uploading them again where the registries support it.
+	// It should only be provided when exporting to a registry.
+	RegistryKeychain authn.Keychain
 	// ExtendedDir is the location of extension-provided layers.
 	ExtendedDir string
 	// AppDir is the source directory.
 	AppDir string
+	// AppSymlinks is how symlinks pointing outside of the app directory are handled before the app layers (and slices) are added
+	// (see ApplyAppSymlinkPolicy).
+	AppSymlinks string
 	// LayersDir is the location of buildpack-provided layers.
 	LayersDir string
+	// MergedSBOMPath is the location where a document merging the SBOM files for all layers should be written, if provided.
+	// The document is SPDX if the path ends in .spdx.json, or CycloneDX otherwise.
+	MergedSBOMPath string
+	// SBOMCompression is the compression of the SBOM files in the application image ("gzip" or "none").
+	SBOMCompression string
+	// SourceSBOMPath is the location of a platform-provided SBOM describing the application source (e.g., vendored dependencies), if provided.
+	// It is added to the launch SBOM layer for the "application" component, and merged with the buildpack-provided SBOM files.
+	SourceSBOMPath string
+	// SBOMPolicyPath is the location of the platform-provided policy that the merged SBOM is evaluated against, if provided.
+	SBOMPolicyPath string
 	// OrigMetadata was read from the previous image during the `analyze` phase, and is used to determine if a previously-uploaded layer can be re-used.
 	OrigMetadata files.LayersMetadata
 	// LauncherConfig is the launcher config.
 	LauncherConfig LauncherConfig
 	// DefaultProcessType is the user-provided default process type.
 	DefaultProcessType string
+	// DefaultProcessTypeFallback is the platform-provided list of process types, in order of preference,
+	// to use as the default process type when neither the user nor a buildpack specifies one.
+	DefaultProcessTypeFallback []string
 	// RunImageRef is the run image reference for the layer metadata label.
 	RunImageRef string
 	// RunImageForExport is run image metadata for the layer metadata label for Platform API >= 0.12.
 	RunImageForExport files.RunImageForExport
 	// Project is project metadata for the project metadata label.
 	Project files.ProjectMetadata
+	// Scanner is the vulnerability scanner (an executable or an http(s) endpoint) to run with the merged SBOM of the saved image, if provided.
+	Scanner string
+	// ScannerEnforce fails the export if the scanner cannot be run or reports a failing verdict.
+	// The image is scanned after it is saved, so a failed scan does not unpublish the image or its additional names.
+	ScannerEnforce bool
+	// CreateWorkingDirs creates the working directories of processes that do not exist in the app directory or in a launch layer,
+	// rather than failing the export.
+	CreateWorkingDirs bool
+	// DebugImage, if provided, is saved after the working image, with the build-only layers and the DebugArtifacts.
+	// It should be exported the same way as the working image (e.g., to the same registry), without a base image.
+	DebugImage imgutil.Image
+	// DebugArtifacts are the files (e.g., the plan and the build log) to add to the debug image, at their paths.
+	// Files that do not exist are skipped.
+	DebugArtifacts []string
+	// SquashPolicy selects the launch layers that are squashed into a single layer, if any.
+	SquashPolicy LayerSquashPolicy
+	// PruneLaunchSBOM removes build-only entries and build-time metadata from the SBOM files in the application image.
+	// The unpruned SBOM files are kept in <layers>/sbom/launch-unpruned.
+	PruneLaunchSBOM bool
+	// DedupeSBOM replaces SBOM files in the application image that are identical to another SBOM file with symlinks.
+	DedupeSBOM bool
+}
+
+// NewExporterFromInputs returns an exporter configured from the platform inputs, writing layer tarballs to artifactsDir.
+// The include and exclude filters of the project descriptor (for Platform API 0.12 and above) select the files of the app layers.
+// The exporter command and the phases package both use it, so that an image is exported the same way
+// whether the lifecycle runs as a binary or in-process.
+func NewExporterFromInputs(inputs *platform.LifecycleInputs, group buildpack.Group, artifactsDir string, logger log.Logger) (*Exporter, error) {
+	var descriptor files.ProjectDescriptor
+	if inputs.PlatformAPI.AtLeast("0.12") {
+		var err error
+		if descriptor, err = files.ReadProjectDescriptor(inputs.ProjectDescriptorPath, logger); err != nil {
+			return nil, fmt.Errorf("reading project descriptor: %w", err)
+		}
+	}
+	exporter := &Exporter{
+		Buildpacks: group.Group,
+		LayerFactory: &layers.Factory{
+			ArtifactsDir: artifactsDir,
+			UID:          inputs.UID,
+			GID:          inputs.GID,
+			Logger:       logger,
+			Streaming:    true,
+			DigestCache:  layers.DigestCacheFor(inputs.LayersDir, inputs.NoDigestCache),
+			Xattrs:       inputs.XattrPrefixes(),
+			AppInclude:   descriptor.Include,
+			AppExclude:   descriptor.Exclude,
+		},
+		Logger:           logger,
+		PlatformAPI:      inputs.PlatformAPI,
+		LayerCompression: inputs.LayerCompression,
+	}
+	if algorithm := inputs.CacheDigestAlgorithm(); algorithm.Name != layers.SHA256.Name {
+		exporter.CacheLayerFactory = &layers.Factory{
+			ArtifactsDir:    artifactsDir,
+			UID:             inputs.UID,
+			GID:             inputs.GID,
+			Logger:          logger,
+			Streaming:       true,
+			DigestCache:     layers.DigestCacheFor(inputs.LayersDir, inputs.NoDigestCache),
+			Xattrs:          inputs.XattrPrefixes(),
+			DigestAlgorithm: algorithm,
+		}
+	}
+	return exporter, nil
+}
+
+// NewExportOptions returns export options configured from the platform inputs.
+// The platform provides the options that depend on how the images are constructed:
+// WorkingImage, DebugImage (if any), RunImageRef, LauncherConfig and OrigMetadata.
+// When exporting to a registry, keychain is used to push to the additional names and (if requested) to attach attestations.
+func NewExportOptions(inputs *platform.LifecycleInputs, keychain authn.Keychain, logger log.Logger) (ExportOptions, error) {
+	projectMD, err := files.NewHandler(inputs.PlatformAPI, logger).ReadProjectMetadata(inputs.ProjectMetadataPath)
+	if err != nil {
+		return ExportOptions{}, err
+	}
+	runImageForExport, err := platform.GetRunImageForExport(*inputs)
+	if err != nil {
+		return ExportOptions{}, err
+	}
+	var attestationKeychain, registryKeychain authn.Keychain
+	if !inputs.UseDaemon && !inputs.UseLayout {
+		registryKeychain = keychain
+	}
+	if inputs.AttachAttestations {
+		if inputs.UseDaemon || inputs.UseLayout {
+			logger.Warn("Attestations can only be attached to images exported to a registry")
+		} else {
+			attestationKeychain = keychain
+		}
+	}
+	debugArtifacts := []string{inputs.AnalyzedPath, inputs.GroupPath, inputs.PlanPath}
+	if inputs.BuildLogPath != "" {
+		debugArtifacts = append(debugArtifacts, inputs.BuildLogPath)
+	}
+	return ExportOptions{
+		AdditionalNames:            inputs.AdditionalTags,
+		AttestationKeychain:        attestationKeychain,
+		RegistryKeychain:           registryKeychain,
+		AppDir:                     inputs.AppDir,
+		AppSymlinks:                inputs.AppSymlinks,
+		DefaultProcessType:         inputs.DefaultProcessType,
+		DefaultProcessTypeFallback: platform.SplitProcessTypes(inputs.DefaultProcessTypeFallback),
+		ExtendedDir:                inputs.ExtendedDir,
+		LayersDir:                  inputs.LayersDir,
+		MergedSBOMPath:             inputs.MergedSBOMPath,
+		Project:                    projectMD,
+		PruneLaunchSBOM:            inputs.PruneLaunchSBOM,
+		DedupeSBOM:                 inputs.DedupeSBOM,
+		CreateWorkingDirs:          inputs.CreateWorkingDirs,
+		DebugArtifacts:             debugArtifacts,
+		RunImageForExport:          runImageForExport,
+		SBOMCompression:            inputs.SBOMCompression,
+		SBOMPolicyPath:             inputs.SBOMPolicyPath,
+		Scanner:                    inputs.Scanner,
+		ScannerEnforce:             inputs.ScannerEnforce,
+		SourceSBOMPath:             inputs.SourceSBOMPath,
+		SquashPolicy:               LayerSquashPolicy{MaxLayerSize: int64(inputs.SquashLayersBelow), Buildpacks: inputs.SquashBuildpackIDs()},
+	}, nil
 }
 
+// Export is equivalent to ExportContext with context.Background().
+//
+// Deprecated: use ExportContext.
 func (e *Exporter) Export(opts ExportOptions) (files.Report, error) {
+	return e.ExportContext(context.Background(), opts)
+}
+
+// ExportContext adds the layers of the build to the working image, and saves it with the additional names.
+// The context is checked before adding each kind of layer and before saving the image, as the images do not accept a context.
+func (e *Exporter) ExportContext(ctx context.Context, opts ExportOptions) (files.Report, error) {
 	var err error
 	defer log.NewMeasurement("Exporter", e.Logger)()
+	if err = ctx.Err(); err != nil {
+		return files.Report{}, err
+	}
+	if e.UploadedBlobs == nil {
+		e.UploadedBlobs = NewUploadedBlobs()
+	}
 
 	if e.PlatformAPI.AtLeast("0.11") {
 		if err = e.copyBuildpacksioSBOMs(opts); err != nil {
@@ -105,6 +297,9 @@ func (e *Exporter) Export(opts ExportOptions) (files.Report, error) {
 	if err != nil {
 		return files.Report{}, errors.Wrapf(err, "app dir absolute path")
 	}
+	if err = ApplyAppSymlinkPolicy(opts.AppDir, opts.AppSymlinks, e.Logger); err != nil {
+		return files.Report{}, err
+	}
 
 	meta := files.LayersMetadata{}
 	meta.RunImage.TopLayer, err = opts.WorkingImage.TopLayer()
@@ -124,33 +319,85 @@ func (e *Exporter) Export(opts ExportOptions) (files.Report, error) {
 	if err := files.DecodeBuildMetadata(launch.GetMetadataFilePath(opts.LayersDir), e.PlatformAPI, buildMD); err != nil {
 		return files.Report{}, errors.Wrap(err, "read build metadata")
 	}
+	if err := e.checkWorkingDirs(opts, buildMD.ToLaunchMD()); err != nil {
+		return files.Report{}, err
+	}
 
 	// extension-provided layers
-	if err := e.addExtensionLayers(opts); err != nil {
+	if err := e.addExtensionLayers(opts, &meta); err != nil {
 		return files.Report{}, err
 	}
 
 	// buildpack-provided layers
+	if err = ctx.Err(); err != nil {
+		return files.Report{}, err
+	}
 	if err := e.addBuildpackLayers(opts, &meta); err != nil {
 		return files.Report{}, err
 	}
 
+	var (
+		sbomDiff         *files.SBOMDiff
+		policyViolations []files.SBOMPolicyViolation
+	)
 	if e.PlatformAPI.AtLeast("0.8") {
+		if opts.SourceSBOMPath != "" {
+			if err := e.addSourceSBOM(opts); err != nil {
+				return files.Report{}, errors.Wrap(err, "adding source SBOM")
+			}
+		}
+		if opts.PruneLaunchSBOM {
+			if err := e.pruneLaunchSBOMs(opts); err != nil {
+				return files.Report{}, errors.Wrap(err, "pruning launch SBOM")
+			}
+		}
 		if err := e.addSBOMLaunchLayer(opts, &meta); err != nil {
 			return files.Report{}, err
 		}
+		if opts.MergedSBOMPath != "" {
+			if err := e.writeMergedSBOM(opts); err != nil {
+				return files.Report{}, errors.Wrap(err, "writing merged SBOM")
+			}
+		}
+		if sbomDiff, err = e.diffPreviousSBOM(opts); err != nil {
+			return files.Report{}, errors.Wrap(err, "comparing SBOM with previous image")
+		}
+		if opts.SBOMPolicyPath != "" {
+			if policyViolations, err = e.evaluateSBOMPolicy(opts); err != nil {
+				return files.Report{}, err
+			}
+		}
 	}
 
 	// app layers (split into 1 or more slices)
+	if err = ctx.Err(); err != nil {
+		return files.Report{}, err
+	}
 	if err := e.addAppLayers(opts, buildMD.Slices, &meta); err != nil {
 		return files.Report{}, errors.Wrap(err, "exporting app layers")
 	}
 
+	// build metadata that is too large for the label is added to the launcher config layer
+	buildMD.Launcher = opts.LauncherConfig.Metadata
+	if opts.PruneLaunchSBOM {
+		buildMD.Launcher.Source = files.SourceMetadata{}
+	}
+	externalized, err := e.externalizeBuildMetadata(opts, buildMD)
+	if err != nil {
+		return files.Report{}, err
+	}
+
 	// launcher layers (launcher binary, launcher config, process symlinks)
 	if err := e.addLauncherLayers(opts, buildMD, &meta); err != nil {
 		return files.Report{}, err
 	}
 
+	if externalized {
+		buildMD.External = &files.ExternalBuildMetadata{
+			Path:  filepath.Join(opts.LayersDir, "config", files.ExternalBuildMetadataFile),
+			Layer: meta.Config.SHA,
+		}
+	}
 	if err := e.setLabels(opts, meta, buildMD); err != nil {
 		return files.Report{}, err
 	}
@@ -166,7 +413,7 @@ func (e *Exporter) Export(opts ExportOptions) (files.Report, error) {
 		}
 	}
 
-	entrypoint, err := e.entrypoint(buildMD.ToLaunchMD(), opts.DefaultProcessType, buildMD.BuildpackDefaultProcessType)
+	entrypoint, err := e.entrypoint(buildMD.ToLaunchMD(), opts.DefaultProcessType, buildMD.BuildpackDefaultProcessType, opts.DefaultProcessTypeFallback)
 	if err != nil {
 		return files.Report{}, errors.Wrap(err, "determining entrypoint")
 	}
@@ -184,7 +431,10 @@ func (e *Exporter) Export(opts ExportOptions) (files.Report, error) {
 	if err != nil {
 		return files.Report{}, err
 	}
-	report.Image, err = saveImage(opts.WorkingImage, opts.AdditionalNames, e.Logger)
+	if err = ctx.Err(); err != nil {
+		return files.Report{}, err
+	}
+	report.Image, err = saveImageWithKeychain(opts.WorkingImage, opts.WorkingImage.Name(), opts.AdditionalNames, opts.RegistryKeychain, e.Logger)
 	if err != nil {
 		return files.Report{}, err
 	}
@@ -192,10 +442,41 @@ func (e *Exporter) Export(opts ExportOptions) (files.Report, error) {
 		// unset manifest size in report.toml for old platform API versions
 		report.Image.ManifestSize = 0
 	}
+	if opts.AttestationKeychain != nil && report.Image.Digest != "" {
+		// the image was saved, so failing to attach attestations should not fail the export
+		if err = e.attachAttestations(opts, buildMD, report.Image.Digest); err != nil {
+			e.Logger.Warnf("Failed to attach attestations: %s", err)
+		}
+	}
+	if opts.DebugImage != nil {
+		// the image was saved, so failing to save the debug image should not fail the export
+		if report.DebugImage, err = e.exportDebugImage(opts, report.Image); err != nil {
+			e.Logger.Warnf("Failed to export debug image: %s", err)
+		}
+	}
+	if opts.Scanner != "" {
+		if report.Scan, err = e.scanImage(ctx, opts, report.Image); err != nil {
+			return files.Report{}, err
+		}
+	}
+	report.Deprecations = reportDeprecations()
+	report.Usage = usage.Collect()
+	report.Redactions = redact.Counts()
+	report.SBOMDiff = sbomDiff
+	report.PolicyViolations = policyViolations
 
 	return report, nil
 }
 
+// reportDeprecations returns the deprecation notices recorded during the build, for the report.
+func reportDeprecations() []files.Deprecation {
+	var deprecations []files.Deprecation
+	for _, notice := range deprecation.Notices() {
+		deprecations = append(deprecations, files.Deprecation(notice))
+	}
+	return deprecations
+}
+
 func SBOMExtensions() []string {
 	return []string{buildpack.ExtensionCycloneDX, buildpack.ExtensionSPDX, buildpack.ExtensionSyft}
 }
@@ -217,6 +498,32 @@ func (e *Exporter) copyBuildpacksioSBOMs(opts ExportOptions) error {
 	}
 }
 
+// addSourceSBOM copies the platform-provided source SBOM to the launch SBOM layer,
+// as <layers>/sbom/launch/buildpacksio_lifecycle/application/sbom.<ext> (alongside the launcher SBOM),
+// so that it is attributed to the application rather than to a buildpack.
+func (e *Exporter) addSourceSBOM(opts ExportOptions) error {
+	var filename string
+	for _, extension := range SBOMExtensions() {
+		// accept e.g. source.cdx.json as well as source.sbom.cdx.json
+		if strings.HasSuffix(opts.SourceSBOMPath, strings.TrimPrefix(extension, "sbom")) {
+			filename = extension
+			break
+		}
+	}
+	if filename == "" {
+		return errors.Errorf("unsupported SBOM format: '%s'", opts.SourceSBOMPath)
+	}
+	dstDir := filepath.Join(opts.LayersDir, "sbom", "launch", launch.EscapeID("buildpacksio/lifecycle"), "application")
+	if err := os.RemoveAll(dstDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return err
+	}
+	e.Logger.Infof("Adding source SBOM '%s'", opts.SourceSBOMPath)
+	return fsutil.Copy(opts.SourceSBOMPath, filepath.Join(dstDir, filename))
+}
+
 func (e *Exporter) copyLauncherSBOMs(srcDir string, dstDir string) error {
 	sboms, err := fsutil.FilesWithExtensions(srcDir, SBOMExtensions())
 	if err != nil {
@@ -264,7 +571,7 @@ func (e *Exporter) copyDefaultSBOMsForComponent(component, dstDir string) error
 	return nil
 }
 
-func (e *Exporter) addExtensionLayers(opts ExportOptions) error {
+func (e *Exporter) addExtensionLayers(opts ExportOptions, meta *files.LayersMetadata) error {
 	if !e.PlatformAPI.AtLeast("0.12") || opts.ExtendedDir == "" {
 		return nil
 	}
@@ -329,6 +636,8 @@ func (e *Exporter) addExtensionLayers(opts ExportOptions) error {
 		if _, err = e.addOrReuseExtensionLayer(opts.WorkingImage, layer); err != nil {
 			return err
 		}
+		// the rebaser needs to know that these layers depend on the run image
+		meta.RunImage.ExtensionLayers = append(meta.RunImage.ExtensionLayers, files.ExtensionLayer{ExtensionID: extID, DiffID: layer.Digest})
 	}
 	return nil
 }
@@ -395,6 +704,7 @@ func uncompressLayerAt(layerPath string, toArtifactsDir string) (string, error)
 }
 
 func (e *Exporter) addBuildpackLayers(opts ExportOptions, meta *files.LayersMetadata) error {
+	var toSquash []squashedLayer
 	for _, bp := range e.Buildpacks {
 		bpDir, err := buildpack.ReadLayersDir(opts.LayersDir, bp, e.Logger)
 		e.Logger.Debugf("Processing buildpack directory: %s", bpDir.Path)
@@ -417,12 +727,21 @@ func (e *Exporter) addBuildpackLayers(opts ExportOptions, meta *files.LayersMeta
 
 			createdBy := fmt.Sprintf(layers.BuildpackLayerName, fsLayer.Name(), fmt.Sprintf("%s@%s", bp.ID, bp.Version))
 			if fsLayer.HasLocalContents() {
-				layer, err := e.LayerFactory.DirLayer(fsLayer.Identifier(), fsLayer.Path(), createdBy)
+				squash, err := opts.SquashPolicy.selects(bp.ID, fsLayer.Path())
 				if err != nil {
-					return errors.Wrapf(err, "creating layer")
+					return errors.Wrapf(err, "reading size of layer '%s'", fsLayer.Identifier())
 				}
-				origLayerMetadata := opts.OrigMetadata.LayersMetadataFor(bp.ID).Layers[fsLayer.Name()]
-				lmd.SHA, err = e.addOrReuseBuildpackLayer(opts.WorkingImage, layer, origLayerMetadata.SHA, createdBy)
+				if squash {
+					toSquash = append(toSquash, squashedLayer{
+						buildpackID: bp.ID,
+						layer:       fsLayer,
+						createdBy:   createdBy,
+						metadata:    lmd,
+						bpLayers:    bpMD.Layers,
+					})
+					continue
+				}
+				lmd.SHA, err = e.addLocalBuildpackLayer(opts, bp.ID, fsLayer, createdBy)
 				if err != nil {
 					return err
 				}
@@ -434,6 +753,9 @@ func (e *Exporter) addBuildpackLayers(opts ExportOptions, meta *files.LayersMeta
 				if !ok {
 					return fmt.Errorf("cannot reuse '%s', previous image has no metadata for layer '%s'", fsLayer.Identifier(), fsLayer.Identifier())
 				}
+				if origLayerMetadata.Squashed {
+					return fmt.Errorf("cannot reuse '%s', it was squashed with other layers in the previous image", fsLayer.Identifier())
+				}
 
 				e.Logger.Infof("Reusing layer '%s'\n", fsLayer.Identifier())
 				e.Logger.Debugf("Layer '%s' SHA: %s\n", fsLayer.Identifier(), origLayerMetadata.SHA)
@@ -441,6 +763,7 @@ func (e *Exporter) addBuildpackLayers(opts ExportOptions, meta *files.LayersMeta
 					return errors.Wrapf(err, "reusing layer: '%s'", fsLayer.Identifier())
 				}
 				lmd.SHA = origLayerMetadata.SHA
+				progress.LayerExported(fsLayer.Identifier(), lmd.SHA, true)
 			}
 			bpMD.Layers[fsLayer.Name()] = lmd
 		}
@@ -454,11 +777,21 @@ func (e *Exporter) addBuildpackLayers(opts ExportOptions, meta *files.LayersMeta
 			return fmt.Errorf("failed to parse metadata for layers '%s'", ids)
 		}
 	}
-	return nil
+	return e.addSquashedLayer(opts, toSquash)
+}
+
+// addLocalBuildpackLayer adds (or reuses) the layer created from the contents of the provided buildpack layer, and returns its SHA.
+func (e *Exporter) addLocalBuildpackLayer(opts ExportOptions, buildpackID string, fsLayer buildpack.Layer, createdBy string) (string, error) {
+	origLayerMetadata := opts.OrigMetadata.LayersMetadataFor(buildpackID).Layers[fsLayer.Name()]
+	layer, err := e.layerFactoryFor(origLayerMetadata.SHA).DirLayer(fsLayer.Identifier(), fsLayer.Path(), createdBy)
+	if err != nil {
+		return "", errors.Wrapf(err, "creating layer")
+	}
+	return e.addOrReuseBuildpackLayer(opts.WorkingImage, layer, origLayerMetadata.SHA, createdBy)
 }
 
 func (e *Exporter) addLauncherLayers(opts ExportOptions, buildMD *files.BuildMetadata, meta *files.LayersMetadata) error {
-	launcherLayer, err := e.LayerFactory.LauncherLayer(opts.LauncherConfig.Path)
+	launcherLayer, err := e.layerFactoryFor(opts.OrigMetadata.Launcher.SHA).LauncherLayer(opts.LauncherConfig.Path)
 	if err != nil {
 		return errors.Wrap(err, "creating launcher layers")
 	}
@@ -466,7 +799,7 @@ func (e *Exporter) addLauncherLayers(opts ExportOptions, buildMD *files.BuildMet
 	if err != nil {
 		return errors.Wrap(err, "exporting launcher configLayer")
 	}
-	configLayer, err := e.LayerFactory.DirLayer("buildpacksio/lifecycle:config", filepath.Join(opts.LayersDir, "config"), layers.LauncherConfigLayerName)
+	configLayer, err := e.layerFactoryFor(opts.OrigMetadata.Config.SHA).DirLayer("buildpacksio/lifecycle:config", filepath.Join(opts.LayersDir, "config"), layers.LauncherConfigLayerName)
 	if err != nil {
 		return errors.Wrapf(err, "creating layer '%s'", configLayer.ID)
 	}
@@ -481,8 +814,23 @@ func (e *Exporter) addLauncherLayers(opts ExportOptions, buildMD *files.BuildMet
 }
 
 func (e *Exporter) addAppLayers(opts ExportOptions, slices []layers.Slice, meta *files.LayersMetadata) error {
+	if len(slices) == 0 {
+		// without declared slices, dependencies are exported in their own layers, as they change less frequently than the source
+		var err error
+		if slices, err = layers.DependencySlices(opts.AppDir); err != nil {
+			return errors.Wrap(err, "detecting dependency directories")
+		}
+		for _, slice := range slices {
+			e.Logger.Debugf("Adding dependency directories %s to a separate app layer", strings.Join(slice.Paths, ", "))
+		}
+	}
+
 	// creating app layers (slices + app dir)
-	sliceLayers, err := e.LayerFactory.SliceLayers(opts.AppDir, slices)
+	var previousSHA string
+	if len(opts.OrigMetadata.App) > 0 {
+		previousSHA = opts.OrigMetadata.App[0].SHA
+	}
+	sliceLayers, err := e.layerFactoryFor(previousSHA).SliceLayers(opts.AppDir, slices)
 	if err != nil {
 		return errors.Wrap(err, "creating app layers")
 	}
@@ -502,12 +850,13 @@ func (e *Exporter) addAppLayers(opts ExportOptions, slices []layers.Slice, meta
 			err = opts.WorkingImage.ReuseLayerWithHistory(slice.Digest, slice.History)
 			numberOfReusedLayers++
 		} else {
-			err = opts.WorkingImage.AddLayerWithDiffIDAndHistory(slice.TarPath, slice.Digest, slice.History)
+			err = e.addLayer(opts.WorkingImage, slice)
 		}
 		if err != nil {
 			return err
 		}
 		e.Logger.Debugf("Layer '%s' SHA: %s\n", slice.ID, slice.Digest)
+		progress.LayerExported(slice.ID, slice.Digest, found)
 		meta.App = append(meta.App, files.LayerMetadata{SHA: slice.Digest})
 	}
 
@@ -532,8 +881,7 @@ func (e *Exporter) setLabels(opts ExportOptions, meta files.LayersMetadata, buil
 		return errors.Wrap(err, "set app image metadata label")
 	}
 
-	buildMD.Launcher = opts.LauncherConfig.Metadata
-	buildJSON, err := json.Marshal(buildMD)
+	buildJSON, err := json.Marshal(buildMetadataForLabel(buildMD))
 	if err != nil {
 		return errors.Wrap(err, "parse build metadata")
 	}
@@ -553,6 +901,10 @@ func (e *Exporter) setLabels(opts ExportOptions, meta files.LayersMetadata, buil
 		return errors.Wrap(err, "set project metadata label")
 	}
 
+	if err := e.setStackLabels(opts.WorkingImage); err != nil {
+		return err
+	}
+
 	for _, label := range buildMD.Labels {
 		e.Logger.Infof("Adding label '%s'", label.Key)
 		if err := opts.WorkingImage.SetLabel(label.Key, label.Value); err != nil {
@@ -562,6 +914,79 @@ func (e *Exporter) setLabels(opts ExportOptions, meta files.LayersMetadata, buil
 	return nil
 }
 
+// setStackLabels writes the stack label translated from the target of the run image, or the distribution labels translated from its stack,
+// when the run image only has one of them, so that platforms and lifecycles on either side of the deprecation of stacks can rebase the app image.
+func (e *Exporter) setStackLabels(image imgutil.Image) error {
+	labels, err := image.Labels()
+	if err != nil {
+		return errors.Wrap(err, "get run image labels")
+	}
+	target, err := platform.GetTargetMetadata(image)
+	if err != nil {
+		return errors.Wrap(err, "get run image target")
+	}
+	if labels[platform.StackIDLabel] == "" {
+		if stackID := files.StackIDForTarget(*target); stackID != "" {
+			e.Logger.Infof("Adding label '%s'", platform.StackIDLabel)
+			if err = image.SetLabel(platform.StackIDLabel, stackID); err != nil {
+				return errors.Wrap(err, "set stack label")
+			}
+		}
+		return nil
+	}
+	_, hasDistroName := labels[platform.OSDistributionNameLabel]
+	_, hasDistroVersion := labels[platform.OSDistributionVersionLabel]
+	if hasDistroName || hasDistroVersion || target.Distribution == nil {
+		return nil
+	}
+	e.Logger.Infof("Adding labels '%s' and '%s'", platform.OSDistributionNameLabel, platform.OSDistributionVersionLabel)
+	if err = image.SetLabel(platform.OSDistributionNameLabel, target.Distribution.Name); err != nil {
+		return errors.Wrap(err, "set distribution name label")
+	}
+	if err = image.SetLabel(platform.OSDistributionVersionLabel, target.Distribution.Version); err != nil {
+		return errors.Wrap(err, "set distribution version label")
+	}
+	return nil
+}
+
+// externalizeBuildMetadata writes the build metadata to <layers>/config when it is larger than the build metadata label limit,
+// so that it is exported in the launcher config layer instead of the label.
+// It returns true if the build metadata was written.
+func (e *Exporter) externalizeBuildMetadata(opts ExportOptions, buildMD *files.BuildMetadata) (bool, error) {
+	path := filepath.Join(opts.LayersDir, "config", files.ExternalBuildMetadataFile)
+	if err := os.RemoveAll(path); err != nil {
+		return false, errors.Wrap(err, "removing external build metadata")
+	}
+	buildJSON, err := json.Marshal(buildMD)
+	if err != nil {
+		return false, errors.Wrap(err, "parse build metadata")
+	}
+	limit := e.BuildMetadataLabelLimit
+	if limit <= 0 {
+		limit = DefaultBuildMetadataLabelLimit
+	}
+	if len(buildJSON) <= limit {
+		return false, nil
+	}
+	e.Logger.Warnf("Build metadata (%d bytes) exceeds the label size limit (%d bytes); the BOM will be written to '%s'", len(buildJSON), limit, path)
+	if err = os.WriteFile(path, buildJSON, 0644); err != nil { // #nosec G306
+		return false, errors.Wrap(err, "writing external build metadata")
+	}
+	return true, nil
+}
+
+// buildMetadataForLabel returns the build metadata to serialize in the build metadata label,
+// omitting the BOM if the metadata was externalized.
+// The processes are kept, as tools inspecting the image (e.g., to list its process types) only read the label.
+func buildMetadataForLabel(buildMD *files.BuildMetadata) *files.BuildMetadata {
+	if buildMD.External == nil {
+		return buildMD
+	}
+	summary := *buildMD
+	summary.BOM = []buildpack.BOMEntry{}
+	return &summary
+}
+
 func (e *Exporter) setEnv(opts ExportOptions, launchMD launch.Metadata) error {
 	e.Logger.Debugf("Setting %s=%s", platform.EnvLayersDir, opts.LayersDir)
 	if err := opts.WorkingImage.SetEnv(platform.EnvLayersDir, opts.LayersDir); err != nil {
@@ -609,7 +1034,7 @@ func (e *Exporter) setWorkingDir(opts ExportOptions) error {
 	return opts.WorkingImage.SetWorkingDir(opts.AppDir)
 }
 
-func (e *Exporter) entrypoint(launchMD launch.Metadata, userDefaultProcessType, buildpackDefaultProcessType string) (string, error) {
+func (e *Exporter) entrypoint(launchMD launch.Metadata, userDefaultProcessType, buildpackDefaultProcessType string, fallback []string) (string, error) {
 	if !e.supportsMulticallLauncher() {
 		return launch.LauncherPath, nil
 	}
@@ -633,6 +1058,10 @@ func (e *Exporter) entrypoint(launchMD launch.Metadata, userDefaultProcessType,
 		return launch.ProcessPath(defaultProcess.Type), nil
 	}
 	if buildpackDefaultProcessType == "" {
+		if processType, ok := e.fallbackProcessType(launchMD, fallback); ok {
+			e.Logger.Infof("Setting default process type '%s' from the platform-provided fallback %v", processType, fallback)
+			return launch.ProcessPath(processType), nil
+		}
 		e.Logger.Info("no default process type")
 		return launch.LauncherPath, nil
 	}
@@ -640,6 +1069,23 @@ func (e *Exporter) entrypoint(launchMD launch.Metadata, userDefaultProcessType,
 	return launch.ProcessPath(buildpackDefaultProcessType), nil
 }
 
+// fallbackProcessType returns the first process type in the platform-provided fallback list that was defined by a buildpack,
+// where platform.ProcessTypeFallbackAny matches the first defined process type.
+func (e *Exporter) fallbackProcessType(launchMD launch.Metadata, fallback []string) (string, bool) {
+	if e.PlatformAPI.LessThan("0.12") || len(launchMD.Processes) == 0 {
+		return "", false
+	}
+	for _, processType := range fallback {
+		if processType == platform.ProcessTypeFallbackAny {
+			return launchMD.Processes[0].Type, true
+		}
+		if _, ok := launchMD.FindProcessType(processType); ok {
+			return processType, true
+		}
+	}
+	return "", false
+}
+
 func (e *Exporter) launcherConfig(opts ExportOptions, buildMD *files.BuildMetadata, meta *files.LayersMetadata) error {
 	if e.supportsMulticallLauncher() {
 		launchMD := launch.Metadata{
@@ -679,19 +1125,80 @@ func processTypeWarning(launchMD launch.Metadata, defaultProcessType string) str
 	return fmt.Sprintf("default process type '%s' not present in list %+v", defaultProcessType, typeList)
 }
 
-func (e *Exporter) addOrReuseBuildpackLayer(image imgutil.Image, layer layers.Layer, previousSHA, createdBy string) (string, error) {
-	layer, err := e.LayerFactory.DirLayer(layer.ID, layer.TarPath, createdBy)
+func (e *Exporter) addOrReuseBuildpackLayer(image imgutil.Image, layer layers.Layer, previousSHA, createdBy string) (digest string, err error) {
+	span := tracing.Start("layer "+layer.ID, tracing.String("cnb.layer.id", layer.ID))
+	defer func() {
+		span.SetAttributes(tracing.String("cnb.layer.diff_id", digest), tracing.Bool("cnb.layer.reused", digest != "" && digest == previousSHA))
+		span.End(err)
+		if err == nil {
+			progress.LayerExported(layer.ID, digest, digest == previousSHA)
+		}
+	}()
+	layer, err = e.LayerFactory.DirLayer(layer.ID, layer.TarPath, createdBy)
 	if err != nil {
 		return "", errors.Wrapf(err, "creating layer '%s'", layer.ID)
 	}
 	if layer.Digest == previousSHA {
+		metrics.Add(metrics.LayerCacheLookups, 1, metrics.L("cache", metrics.CachePreviousImage), metrics.L("result", metrics.ResultHit))
 		e.Logger.Infof("Reusing layer '%s'\n", layer.ID)
 		e.Logger.Debugf("Layer '%s' SHA: %s\n", layer.ID, layer.Digest)
 		return layer.Digest, image.ReuseLayerWithHistory(previousSHA, layer.History)
 	}
+	metrics.Add(metrics.LayerCacheLookups, 1, metrics.L("cache", metrics.CachePreviousImage), metrics.L("result", metrics.ResultMiss))
 	e.Logger.Infof("Adding layer '%s'\n", layer.ID)
 	e.Logger.Debugf("Layer '%s' SHA: %s\n", layer.ID, layer.Digest)
-	return layer.Digest, image.AddLayerWithDiffIDAndHistory(layer.TarPath, layer.Digest, layer.History)
+	return layer.Digest, e.addLayer(image, layer)
+}
+
+// layerFactoryFor returns the factory to create a layer with, given the digest of the layer in the previous image (if any).
+// A layer without a previous layer to reuse is added to the image regardless of its digest,
+// so a streaming factory writes its tarball as it is hashed rather than generating the tarball again to add it (see layers.Factory.Eager).
+func (e *Exporter) layerFactoryFor(previousSHA string) LayerFactory {
+	if factory, ok := e.LayerFactory.(*layers.Factory); ok && factory.Streaming && previousSHA == "" {
+		return factory.Eager()
+	}
+	return e.LayerFactory
+}
+
+// addLayer adds the tarball of the layer to the image.
+// imgutil adds layers from files, so the tarball (or its compressed copy) is written to disk even when the layer was streamed.
+// imgutil compresses the layers of images exported to a registry or a layout with a fixed level,
+// so they are compressed beforehand, at the level chosen by e.LayerCompression, and added as-is.
+func (e *Exporter) addLayer(image imgutil.Image, layer layers.Layer) error {
+	if !compressesLayers(image) {
+		if err := layer.WriteTar(); err != nil {
+			return errors.Wrapf(err, "writing layer '%s'", layer.ID)
+		}
+		return image.AddLayerWithDiffIDAndHistory(layer.TarPath, layer.Digest, layer.History)
+	}
+	path, level, err := layer.WriteCompressed(e.layerCompressionLevel())
+	if err != nil {
+		return errors.Wrapf(err, "compressing layer '%s'", layer.ID)
+	}
+	e.Logger.Debugf("Layer '%s' compressed with level %d\n", layer.ID, level)
+	if err = image.AddLayerWithDiffIDAndHistory(path, layer.Digest, layer.History); err != nil {
+		return err
+	}
+	if _, ok := image.(*remote.Image); ok {
+		e.UploadedBlobs.Record(layer.Digest, path, image.Name())
+	}
+	return nil
+}
+
+func (e *Exporter) layerCompressionLevel() int {
+	if level, err := strconv.Atoi(e.LayerCompression); err == nil {
+		return level
+	}
+	return layers.AdaptiveCompression
+}
+
+func compressesLayers(image imgutil.Image) bool {
+	switch image.(type) {
+	case *remote.Image, *layout.Image:
+		return true
+	default:
+		return false
+	}
 }
 
 func (e *Exporter) addOrReuseExtensionLayer(image imgutil.Image, layer layers.Layer) (string, error) {
@@ -704,12 +1211,20 @@ func (e *Exporter) addOrReuseExtensionLayer(image imgutil.Image, layer layers.La
 		}
 		e.Logger.Infof("Adding extension layer %s\n", layer.ID)
 		e.Logger.Debugf("Layer '%s' SHA: %s\n", layer.ID, layer.Digest)
-		return layer.Digest, image.AddLayerWithDiffIDAndHistory(layer.TarPath, layer.Digest, layer.History)
+		if err := image.AddLayerWithDiffIDAndHistory(layer.TarPath, layer.Digest, layer.History); err != nil {
+			return layer.Digest, err
+		}
+		progress.LayerExported(layer.ID, layer.Digest, false)
+		return layer.Digest, nil
 	}
 	_ = rc.Close() // close the layer reader
 	e.Logger.Infof("Reusing layer %s\n", layer.ID)
 	e.Logger.Debugf("Layer '%s' SHA: %s\n", layer.ID, layer.Digest)
-	return layer.Digest, image.ReuseLayerWithHistory(layer.Digest, layer.History)
+	if err := image.ReuseLayerWithHistory(layer.Digest, layer.History); err != nil {
+		return layer.Digest, err
+	}
+	progress.LayerExported(layer.ID, layer.Digest, true)
+	return layer.Digest, nil
 }
 
 func (e *Exporter) makeBuildReport(layersDir string) (files.BuildReport, error) {
@@ -731,6 +1246,233 @@ func (e *Exporter) makeBuildReport(layersDir string) (files.BuildReport, error)
 	return files.BuildReport{BOM: out}, nil
 }
 
+// mergeLaunchSBOMs merges the SBOM files in the launch SBOM layer into a single CycloneDX document.
+func (e *Exporter) mergeLaunchSBOMs(opts ExportOptions) ([]byte, []buildpack.SBOMSource, error) {
+	buildpackIDs := map[string]string{launch.EscapeID("buildpacksio/lifecycle"): "buildpacksio/lifecycle"}
+	for _, bp := range e.Buildpacks {
+		buildpackIDs[launch.EscapeID(bp.ID)] = bp.ID
+	}
+	sources, err := buildpack.SBOMSourcesIn(filepath.Join(opts.LayersDir, "sbom", "launch"), buildpackIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	contents, err := buildpack.MergeSBOMs(sources, opts.WorkingImage.Name())
+	if err != nil {
+		return nil, nil, err
+	}
+	return contents, sources, nil
+}
+
+// pruneLaunchSBOMs removes build-only entries and build-time metadata from the SBOM files in the launch SBOM layer,
+// after copying the unpruned files to <layers>/sbom/launch-unpruned so that the platform can keep them as a build artifact.
+func (e *Exporter) pruneLaunchSBOMs(opts ExportOptions) error {
+	launchDir := filepath.Join(opts.LayersDir, "sbom", "launch")
+	if _, err := os.Stat(launchDir); os.IsNotExist(err) {
+		return nil
+	}
+	unprunedDir := filepath.Join(opts.LayersDir, "sbom", "launch-unpruned")
+	if err := os.RemoveAll(unprunedDir); err != nil {
+		return err
+	}
+	if err := fsutil.Copy(launchDir, unprunedDir); err != nil {
+		return errors.Wrap(err, "copying unpruned SBOM files")
+	}
+	sources, err := buildpack.SBOMSourcesIn(launchDir, nil)
+	if err != nil {
+		return err
+	}
+	var removed int
+	for _, source := range sources {
+		n, err := buildpack.PruneSBOMFile(source.Path)
+		if err != nil {
+			return err
+		}
+		removed += n
+	}
+	e.Logger.Infof("Pruned %d build-only SBOM entries; unpruned SBOM files are in '%s'", removed, unprunedDir)
+	return nil
+}
+
+// attachAttestations pushes the merged SBOM and the build metadata as in-toto attestations referring to the saved image.
+func (e *Exporter) attachAttestations(opts ExportOptions, buildMD *files.BuildMetadata, digest string) error {
+	ref, err := name.ParseReference(opts.WorkingImage.Name(), name.WeakValidation)
+	if err != nil {
+		return err
+	}
+	var attestations []attest.Attestation
+	if e.PlatformAPI.AtLeast("0.8") {
+		sbom, _, err := e.mergeLaunchSBOMs(opts)
+		if err != nil {
+			return errors.Wrap(err, "merging SBOM files")
+		}
+		attestations = append(attestations, attest.Attestation{PredicateType: attest.PredicateTypeCycloneDX, Predicate: sbom})
+	}
+	buildJSON, err := json.Marshal(buildMD)
+	if err != nil {
+		return errors.Wrap(err, "parse build metadata")
+	}
+	attestations = append(attestations, attest.Attestation{PredicateType: attest.PredicateTypeBuildMetadata, Predicate: buildJSON})
+	subject := ref.Context().Digest(digest).String()
+	digests, err := attest.Attach(subject, attestations, opts.AttestationKeychain)
+	if err != nil {
+		return err
+	}
+	e.Logger.Infof("Attached %d attestation(s) to '%s'", len(digests), subject)
+	for i, d := range digests {
+		e.Logger.Debugf("  %s: %s", attestations[i].PredicateType, d)
+	}
+	return nil
+}
+
+// scanImage runs the platform-provided scanner with the merged SBOM of the saved image.
+// Unless the scan is enforced, failures to scan are logged and a failing verdict is only reported.
+// The scanner identifies the image by its digest, so the image is scanned after it is saved:
+// an enforced scan that fails the export leaves the image published under all of its names.
+func (e *Exporter) scanImage(ctx context.Context, opts ExportOptions, imageReport files.ImageReport) (*files.ScanResult, error) {
+	image := imageReport.ImageID
+	if imageReport.Digest != "" {
+		ref, err := name.ParseReference(opts.WorkingImage.Name(), name.WeakValidation)
+		if err != nil {
+			return nil, err
+		}
+		image = ref.Context().Digest(imageReport.Digest).String()
+	}
+	result, err := e.runScanner(ctx, opts, image)
+	if err != nil {
+		if opts.ScannerEnforce {
+			return nil, errors.Wrap(err, "scanning image")
+		}
+		e.Logger.Warnf("Failed to scan image: %s", err)
+		return nil, nil
+	}
+	switch result.Verdict {
+	case scan.VerdictFail:
+		if opts.ScannerEnforce {
+			return nil, errors.Errorf("image '%s' failed the scan: %s", image, result)
+		}
+		e.Logger.Warnf("Scan result: %s", result)
+	case scan.VerdictWarn:
+		e.Logger.Warnf("Scan result: %s", result)
+	default:
+		e.Logger.Infof("Scan result: %s", result)
+	}
+	return &files.ScanResult{
+		Scanner: result.Scanner,
+		Image:   result.Image,
+		Verdict: result.Verdict,
+		Summary: result.Summary,
+		Message: result.Message,
+	}, nil
+}
+
+func (e *Exporter) runScanner(ctx context.Context, opts ExportOptions, image string) (scan.Result, error) {
+	sbom, _, err := e.mergeLaunchSBOMs(opts)
+	if err != nil {
+		return scan.Result{}, errors.Wrap(err, "merging SBOM files")
+	}
+	tmpDir, err := os.MkdirTemp("", "lifecycle.scan")
+	if err != nil {
+		return scan.Result{}, err
+	}
+	defer os.RemoveAll(tmpDir)
+	sbomPath := filepath.Join(tmpDir, buildpack.ExtensionCycloneDX)
+	if err = os.WriteFile(sbomPath, sbom, 0600); err != nil {
+		return scan.Result{}, err
+	}
+	e.Logger.Infof("Scanning '%s' with '%s'", image, opts.Scanner)
+	return scan.Run(ctx, opts.Scanner, sbomPath, image)
+}
+
+// writeMergedSBOM merges the SBOM files in the launch SBOM layer into a single document at opts.MergedSBOMPath.
+// The document is SPDX if the path ends in .spdx.json, or CycloneDX otherwise.
+func (e *Exporter) writeMergedSBOM(opts ExportOptions) error {
+	contents, sources, err := e.mergeLaunchSBOMs(opts)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(opts.MergedSBOMPath, buildpack.ExtensionSPDX) {
+		var warnings []string
+		contents, warnings, err = buildpack.ConvertSBOM(contents, buildpack.MediaTypeCycloneDX, buildpack.MediaTypeSPDX, opts.WorkingImage.Name())
+		if err != nil {
+			return err
+		}
+		for _, warning := range warnings {
+			e.Logger.Warnf("Merged SBOM: %s", warning)
+		}
+	}
+	if err = os.MkdirAll(filepath.Dir(opts.MergedSBOMPath), 0755); err != nil {
+		return err
+	}
+	e.Logger.Infof("Writing merged SBOM for %d SBOM file(s) to '%s'", len(sources), opts.MergedSBOMPath)
+	return os.WriteFile(opts.MergedSBOMPath, contents, 0644) // #nosec G306
+}
+
+// diffPreviousSBOM compares the launch SBOM files with the SBOM of the previous image saved during the `analyze` phase, if any
+// (see platform.DefaultPreviousSBOMFile).
+func (e *Exporter) diffPreviousSBOM(opts ExportOptions) (*files.SBOMDiff, error) {
+	previous, err := os.ReadFile(filepath.Join(opts.LayersDir, platform.DefaultPreviousSBOMFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	current, _, err := e.mergeLaunchSBOMs(opts)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := buildpack.DiffSBOMs(previous, current)
+	if err != nil {
+		return nil, err
+	}
+	e.Logger.Debugf("SBOM changes since the previous image: %d added, %d removed, %d upgraded", len(diff.Added), len(diff.Removed), len(diff.Upgraded))
+	return &diff, nil
+}
+
+// evaluateSBOMPolicy evaluates the merged SBOM against the policy at opts.SBOMPolicyPath,
+// returning an error if there are violations and the policy is enforced.
+func (e *Exporter) evaluateSBOMPolicy(opts ExportOptions) ([]files.SBOMPolicyViolation, error) {
+	policy, err := files.ReadSBOMPolicy(opts.SBOMPolicyPath)
+	if err != nil {
+		return nil, err
+	}
+	merged, _, err := e.mergeLaunchSBOMs(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "merging SBOM files")
+	}
+	violations, err := buildpack.EvaluateSBOMPolicy(policy, merged)
+	if err != nil {
+		return nil, errors.Wrap(err, "evaluating SBOM policy")
+	}
+	for _, violation := range violations {
+		e.Logger.Warnf("SBOM policy violation: %s", violation)
+	}
+	if policy.Enforce && len(violations) > 0 {
+		return nil, errors.Errorf("image SBOM violates the policy at '%s' (%d violation(s))", opts.SBOMPolicyPath, len(violations))
+	}
+	return violations, nil
+}
+
+// optimizeSBOMs compresses the SBOM files in the provided directory and replaces duplicate files with symlinks, if requested.
+func (e *Exporter) optimizeSBOMs(opts ExportOptions, dir string) error {
+	if opts.SBOMCompression == platform.SBOMCompressionGzip {
+		if err := buildpack.CompressSBOMs(dir); err != nil {
+			return errors.Wrap(err, "compressing SBOM files")
+		}
+	}
+	if !opts.DedupeSBOM || runtime.GOOS == "windows" {
+		return nil
+	}
+	deduped, saved, err := buildpack.DedupeSBOMs(dir)
+	if err != nil {
+		return errors.Wrap(err, "deduplicating SBOM files")
+	}
+	if deduped > 0 {
+		e.Logger.Debugf("Replaced %d duplicate SBOM file(s) with symlinks, saving %d bytes", deduped, saved)
+	}
+	return nil
+}
+
 func (e *Exporter) addSBOMLaunchLayer(opts ExportOptions, meta *files.LayersMetadata) error {
 	sbomLaunchDir, err := readLayersSBOM(opts.LayersDir, "launch", e.Logger)
 	if err != nil {
@@ -738,6 +1480,9 @@ func (e *Exporter) addSBOMLaunchLayer(opts ExportOptions, meta *files.LayersMeta
 	}
 
 	if sbomLaunchDir != nil {
+		if err = e.optimizeSBOMs(opts, sbomLaunchDir.Path()); err != nil {
+			return err
+		}
 		layer, err := e.LayerFactory.DirLayer(sbomLaunchDir.Identifier(), sbomLaunchDir.Path(), layers.SBOMLayerName)
 		if err != nil {
 			return errors.Wrapf(err, "creating layer")
@@ -16,6 +16,7 @@ import (
 	"github.com/buildpacks/imgutil/local"
 	"github.com/buildpacks/imgutil/remote"
 	"github.com/golang/mock/gomock"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/sclevine/spec"
 	specreport "github.com/sclevine/spec/report"
@@ -23,9 +24,11 @@ import (
 	"github.com/buildpacks/lifecycle"
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/internal/deprecation"
 	"github.com/buildpacks/lifecycle/internal/path"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/layers"
+	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/platform/files"
 	h "github.com/buildpacks/lifecycle/testhelpers"
 	"github.com/buildpacks/lifecycle/testmock"
@@ -289,6 +292,65 @@ func testExporter(t *testing.T, when spec.G, it spec.S) {
 				assertReuseLayerLog(t, logHandler, "other.buildpack.id:local-reusable-layer")
 			})
 
+			when("a squash policy is provided", func() {
+				it.Before(func() {
+					opts.SquashPolicy = lifecycle.LayerSquashPolicy{Buildpacks: []string{"buildpack.id", "other.buildpack.id"}}
+					layerFactory.EXPECT().
+						DirsLayer(lifecycle.SquashedLayerID, gomock.Any(), layers.SquashedLayerName).
+						DoAndReturn(func(id string, _ []string, _ string) (layers.Layer, error) {
+							return createTestLayer(id, tmpDir)
+						}).AnyTimes()
+				})
+
+				it.After(func() {
+					opts.SquashPolicy = lifecycle.LayerSquashPolicy{}
+				})
+
+				it("squashes the selected layers and reuses layers without contents on their own", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					assertHasLayer(t, fakeAppImage, lifecycle.SquashedLayerID)
+					assertAddLayerLog(t, logHandler, lifecycle.SquashedLayerID)
+					assertDoesNotHaveLayer(t, fakeAppImage, "buildpack.id:new-launch-layer")
+					h.AssertContains(t, fakeAppImage.ReusedLayers(), "launch-layer-no-local-dir-digest")
+
+					// expects 3 layers
+					// 1. app layer
+					// 2. config layer
+					// 3. squashed layer
+					h.AssertEq(t, fakeAppImage.NumberOfAddedLayers(), 3)
+				})
+
+				when("the layers were squashed in the previous image", func() {
+					it.Before(func() {
+						fakeAppImage.AddPreviousLayer(testLayerDigest(lifecycle.SquashedLayerID), "")
+						opts.OrigMetadata.LayersMetadataFor("other.buildpack.id").Layers["local-reusable-layer"] = buildpack.LayerMetadata{
+							SHA:      testLayerDigest(lifecycle.SquashedLayerID),
+							Squashed: true,
+						}
+					})
+
+					it("reuses the squashed layer if the sha matches", func() {
+						_, err := exporter.Export(opts)
+						h.AssertNil(t, err)
+
+						h.AssertContains(t, fakeAppImage.ReusedLayers(), testLayerDigest(lifecycle.SquashedLayerID))
+						assertReuseLayerLog(t, logHandler, lifecycle.SquashedLayerID)
+					})
+
+					it("fails to reuse a layer without contents on its own", func() {
+						opts.OrigMetadata.LayersMetadataFor("buildpack.id").Layers["launch-layer-no-local-dir"] = buildpack.LayerMetadata{
+							SHA:      testLayerDigest(lifecycle.SquashedLayerID),
+							Squashed: true,
+						}
+
+						_, err := exporter.Export(opts)
+						h.AssertError(t, err, "cannot reuse 'buildpack.id:launch-layer-no-local-dir', it was squashed with other layers in the previous image")
+					})
+				})
+			})
+
 			when("the launch flag is in the top level table", func() {
 				it.Before(func() {
 					exporter.Buildpacks = []buildpack.GroupElement{{ID: "bad.buildpack.id", API: api.Buildpack.Latest().String()}}
@@ -506,6 +568,32 @@ func testExporter(t *testing.T, when spec.G, it spec.S) {
 					h.AssertJSONEq(t, expectedJSON, metadataJSON)
 				})
 
+				when("the build metadata is larger than the label limit", func() {
+					it.Before(func() {
+						exporter.BuildMetadataLabelLimit = 10
+					})
+
+					it("moves the BOM to the config layer and keeps the processes in the label", func() {
+						_, err := exporter.Export(opts)
+						h.AssertNil(t, err)
+
+						metadataJSON, err := fakeAppImage.Label("io.buildpacks.build.metadata")
+						h.AssertNil(t, err)
+						var buildMD map[string]interface{}
+						h.AssertNil(t, json.Unmarshal([]byte(metadataJSON), &buildMD))
+						h.AssertEq(t, len(buildMD["processes"].([]interface{})), 1)
+						h.AssertEq(t, len(buildMD["buildpacks"].([]interface{})), 2)
+						external := buildMD["external"].(map[string]interface{})
+						externalPath := filepath.Join(opts.LayersDir, "config", "build-metadata.json")
+						h.AssertEq(t, external["path"], externalPath)
+						h.AssertEq(t, external["layer"] != "", true)
+
+						contents, err := os.ReadFile(externalPath)
+						h.AssertNil(t, err)
+						h.AssertStringContains(t, string(contents), "some-process-type")
+					})
+				})
+
 				when("platform api < 0.9", func() {
 					platformAPI = api.MustParse("0.8")
 
@@ -867,6 +955,38 @@ version = "4.5.6"
 				h.AssertNil(t, err)
 				h.AssertEq(t, label, "other-label-value")
 			})
+
+			when("the run image only has a target", func() {
+				it.Before(func() {
+					h.AssertNil(t, fakeAppImage.SetLabel(platform.OSDistributionNameLabel, "ubuntu"))
+					h.AssertNil(t, fakeAppImage.SetLabel(platform.OSDistributionVersionLabel, "22.04"))
+				})
+
+				it("adds the stack label translated from the target", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+					label, err := fakeAppImage.Label(platform.StackIDLabel)
+					h.AssertNil(t, err)
+					h.AssertEq(t, label, "io.buildpacks.stacks.jammy")
+				})
+			})
+
+			when("the run image only has a stack", func() {
+				it.Before(func() {
+					h.AssertNil(t, fakeAppImage.SetLabel(platform.StackIDLabel, "io.buildpacks.stacks.focal"))
+				})
+
+				it("adds the distribution labels translated from the stack", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+					label, err := fakeAppImage.Label(platform.OSDistributionNameLabel)
+					h.AssertNil(t, err)
+					h.AssertEq(t, label, "ubuntu")
+					label, err = fakeAppImage.Label(platform.OSDistributionVersionLabel)
+					h.AssertNil(t, err)
+					h.AssertEq(t, label, "20.04")
+				})
+			})
 		})
 
 		when("previous image doesn't exist", func() {
@@ -911,6 +1031,30 @@ version = "4.5.6"
 				})
 			})
 
+			when("there are no slices and the app has dependency directories", func() {
+				it.Before(func() {
+					opts.AppDir = filepath.Join(tmpDir, "app-with-dependencies")
+					h.Mkdir(t, filepath.Join(opts.AppDir, "node_modules"), filepath.Join(opts.AppDir, "frontend", "node_modules"), filepath.Join(opts.AppDir, "vendor"))
+					layerFactory.EXPECT().SliceLayers(
+						opts.AppDir,
+						[]layers.Slice{
+							{Paths: []string{filepath.Join("frontend", "node_modules"), "node_modules"}},
+							{Paths: []string{"vendor"}},
+						},
+					).Return([]layers.Layer{
+						{ID: "slice-1", Digest: "slice-1-digest"},
+						{ID: "slice-2", Digest: "slice-2-digest"},
+						{ID: "slice-3", Digest: "slice-3-digest"},
+					}, nil)
+				})
+
+				it("exports the dependency directories in separate app layers", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+					assertLogEntry(t, logHandler, "Adding 3/3 app layer(s)")
+				})
+			})
+
 			when("structured SBOM", func() {
 				when("there is a 'launch=true' layer with a bom.<ext> file", func() {
 					it("creates a bom layer on Run image", func() {
@@ -1003,6 +1147,121 @@ version = "4.5.6"
 				h.AssertEq(t, fakeAppImage.NumberOfAddedLayers(), 7)
 			})
 
+			when("a squash policy is provided", func() {
+				var squashedDirs []string
+
+				it.Before(func() {
+					opts.SquashPolicy = lifecycle.LayerSquashPolicy{MaxLayerSize: 1024}
+					squashedDirs = nil
+					layerFactory.EXPECT().
+						DirsLayer(lifecycle.SquashedLayerID, gomock.Any(), layers.SquashedLayerName).
+						DoAndReturn(func(id string, dirs []string, _ string) (layers.Layer, error) {
+							squashedDirs = dirs
+							return createTestLayer(id, tmpDir)
+						}).AnyTimes()
+				})
+
+				it.After(func() {
+					opts.SquashPolicy = lifecycle.LayerSquashPolicy{}
+				})
+
+				it("squashes the layers smaller than the size threshold", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					h.AssertEq(t, squashedDirs, []string{
+						filepath.Join(opts.LayersDir, "buildpack.id", "layer1"),
+						filepath.Join(opts.LayersDir, "buildpack.id", "layer2"),
+					})
+					assertHasLayer(t, fakeAppImage, lifecycle.SquashedLayerID)
+					assertDoesNotHaveLayer(t, fakeAppImage, "buildpack.id:layer1")
+					assertDoesNotHaveLayer(t, fakeAppImage, "buildpack.id:layer2")
+				})
+
+				it("records the squashed layer in the metadata of each layer", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					metadataJSON, err := fakeAppImage.Label("io.buildpacks.lifecycle.metadata")
+					h.AssertNil(t, err)
+					var meta files.LayersMetadata
+					h.AssertNil(t, json.Unmarshal([]byte(metadataJSON), &meta))
+					for _, name := range []string{"layer1", "layer2"} {
+						h.AssertEq(t, meta.Buildpacks[0].Layers[name].SHA, testLayerDigest(lifecycle.SquashedLayerID))
+						h.AssertEq(t, meta.Buildpacks[0].Layers[name].Squashed, true)
+					}
+					h.AssertEq(t, meta.Buildpacks[0].Layers["layer1"].Data, map[string]interface{}{
+						"mykey": "new val",
+					})
+				})
+
+				it("adds a single selected layer on its own", func() {
+					h.Mkfile(t, strings.Repeat("x", 2048), filepath.Join(opts.LayersDir, "buildpack.id", "layer2", "large-file"))
+
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					assertHasLayer(t, fakeAppImage, "buildpack.id:layer1")
+					assertHasLayer(t, fakeAppImage, "buildpack.id:layer2")
+					assertDoesNotHaveLayer(t, fakeAppImage, lifecycle.SquashedLayerID)
+				})
+			})
+
+			when("a debug image is provided", func() {
+				var (
+					debugImage    *fakes.Image
+					artifactsPath string
+				)
+
+				it.Before(func() {
+					debugImage = fakes.NewImage("some-repo/debug-image", "", local.IDIdentifier{ImageID: "some-debug-image-id"})
+					opts.DebugImage = debugImage
+
+					buildLayerDir := filepath.Join(opts.LayersDir, "buildpack.id", "build-layer")
+					h.Mkdir(t, buildLayerDir)
+					h.Mkfile(t, "some-build-output", filepath.Join(buildLayerDir, "some-file"))
+					h.Mkfile(t, "[types]\n  build = true\n", filepath.Join(opts.LayersDir, "buildpack.id", "build-layer.toml"))
+
+					artifactsPath = filepath.Join(tmpDir, "build.log")
+					h.Mkfile(t, "some-build-log", artifactsPath)
+					opts.DebugArtifacts = []string{artifactsPath, filepath.Join(tmpDir, "missing.toml")}
+
+					layerFactory.EXPECT().
+						FilesLayer(lifecycle.DebugArtifactsLayerID, []string{artifactsPath}, layers.DebugArtifactsLayerName).
+						DoAndReturn(func(id string, _ []string, _ string) (layers.Layer, error) {
+							return createTestLayer(id, tmpDir)
+						})
+				})
+
+				it.After(func() {
+					opts.DebugImage = nil
+					opts.DebugArtifacts = nil
+					h.AssertNil(t, debugImage.Cleanup())
+				})
+
+				it("adds the build-only layers and the existing debug artifacts to the debug image", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					assertHasLayer(t, debugImage, "buildpack.id:build-layer")
+					assertHasLayer(t, debugImage, lifecycle.DebugArtifactsLayerID)
+					assertDoesNotHaveLayer(t, debugImage, "buildpack.id:layer1")
+					assertDoesNotHaveLayer(t, fakeAppImage, "buildpack.id:build-layer")
+				})
+
+				it("labels the debug image with the app image and reports it", func() {
+					report, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					h.AssertEq(t, debugImage.IsSaved(), true)
+					label, err := debugImage.Label("io.buildpacks.debug.app-image")
+					h.AssertNil(t, err)
+					h.AssertEq(t, label, "some-repo/app-image")
+					h.AssertNotNil(t, report.DebugImage)
+					h.AssertEq(t, report.DebugImage.Tags, []string{"some-repo/debug-image"})
+				})
+			})
+
 			it("saves metadata with layer info", func() {
 				_, err := exporter.Export(opts)
 				h.AssertNil(t, err)
@@ -1172,6 +1431,34 @@ version = "4.5.6"
 						assertLogEntry(t, logHandler, "no default process type")
 						assertHasEntrypoint(t, fakeAppImage, filepath.Join(path.RootDir, "cnb", "lifecycle", "launcher"+path.ExecExt))
 					})
+
+					when("a process type fallback is provided", func() {
+						it("sets the ENTRYPOINT to the first matching process type", func() {
+							opts.DefaultProcessTypeFallback = []string{"web", "some-process-type"}
+
+							_, err := exporter.Export(opts)
+							h.AssertNil(t, err)
+							assertLogEntry(t, logHandler, "Setting default process type 'some-process-type' from the platform-provided fallback")
+							assertHasEntrypoint(t, fakeAppImage, filepath.Join(path.RootDir, "cnb", "process", "some-process-type"+path.ExecExt))
+						})
+
+						it("sets the ENTRYPOINT to the first process type when the fallback includes '*'", func() {
+							opts.DefaultProcessTypeFallback = []string{"web", platform.ProcessTypeFallbackAny}
+
+							_, err := exporter.Export(opts)
+							h.AssertNil(t, err)
+							assertHasEntrypoint(t, fakeAppImage, filepath.Join(path.RootDir, "cnb", "process", "some-process-type"+path.ExecExt))
+						})
+
+						it("sets the ENTRYPOINT to the launcher when no process type matches", func() {
+							opts.DefaultProcessTypeFallback = []string{"web", "worker"}
+
+							_, err := exporter.Export(opts)
+							h.AssertNil(t, err)
+							assertLogEntry(t, logHandler, "no default process type")
+							assertHasEntrypoint(t, fakeAppImage, filepath.Join(path.RootDir, "cnb", "lifecycle", "launcher"+path.ExecExt))
+						})
+					})
 				})
 
 				when("buildpack-default-process-type is set in metadata.toml", func() {
@@ -1274,6 +1561,82 @@ version = "4.5.6"
 			})
 		})
 
+		when("processes have working directories", func() {
+			writeProcess := func(workingDir string) {
+				h.Mkfile(t,
+					"[[processes]]\n"+
+						"  type = \"some-process-type\"\n"+
+						"  command = \"/some/command\"\n"+
+						"  buildpack-id = \"buildpack.id\"\n"+
+						fmt.Sprintf("  working-dir = %q\n", workingDir),
+					filepath.Join(opts.LayersDir, "config", "metadata.toml"),
+				)
+			}
+
+			it.Before(func() {
+				h.RecursiveCopy(t, filepath.Join("testdata", "exporter", "previous-image-not-exist", "layers"), opts.LayersDir)
+				h.Mkdir(t, opts.AppDir)
+				layerFactory.EXPECT().
+					ProcessTypesLayer(gomock.Any()).
+					DoAndReturn(func(_ launch.Metadata) (layers.Layer, error) {
+						return createTestLayer("process-types", tmpDir)
+					}).
+					AnyTimes()
+			})
+
+			it("accepts working directories in the app directory and in launch layers", func() {
+				h.Mkdir(t, filepath.Join(opts.AppDir, "some-dir"))
+				writeProcess("some-dir")
+				_, err := exporter.Export(opts)
+				h.AssertNil(t, err)
+
+				writeProcess(filepath.Join(opts.LayersDir, "buildpack.id", "layer1"))
+				_, err = exporter.Export(opts)
+				h.AssertNil(t, err)
+			})
+
+			it("fails if the working directory does not exist", func() {
+				writeProcess(filepath.Join(opts.AppDir, "missing-dir"))
+
+				_, err := exporter.Export(opts)
+				h.AssertError(t, err, fmt.Sprintf("working directory '%s' of process type 'some-process-type' (buildpack 'buildpack.id') does not exist in the app directory or in a launch layer", filepath.Join(opts.AppDir, "missing-dir")))
+			})
+
+			it("fails if the working directory is not in a launch layer", func() {
+				h.Mkdir(t, filepath.Join(opts.LayersDir, "buildpack.id", "build-layer", "some-dir"))
+				h.Mkfile(t, "[types]\n  build = true\n", filepath.Join(opts.LayersDir, "buildpack.id", "build-layer.toml"))
+				writeProcess(filepath.Join(opts.LayersDir, "buildpack.id", "build-layer", "some-dir"))
+
+				_, err := exporter.Export(opts)
+				h.AssertError(t, err, "does not exist in the app directory or in a launch layer")
+			})
+
+			it("does not check working directories outside of the app and layers directories", func() {
+				writeProcess(filepath.Join(tmpDir, "some-run-image-dir"))
+
+				_, err := exporter.Export(opts)
+				h.AssertNil(t, err)
+			})
+
+			when("creating working directories", func() {
+				it.Before(func() {
+					opts.CreateWorkingDirs = true
+				})
+
+				it.After(func() {
+					opts.CreateWorkingDirs = false
+				})
+
+				it("creates the missing working directory", func() {
+					writeProcess("missing-dir")
+
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+					h.AssertPathExists(t, filepath.Join(opts.AppDir, "missing-dir"))
+				})
+			})
+		})
+
 		when("report.toml", func() {
 			when("manifest size", func() {
 				var fakeRemoteManifestSize int64
@@ -1441,6 +1804,307 @@ version = "4.5.6"
 					})
 				})
 			})
+
+			when("merged SBOM", func() {
+				it.Before(func() {
+					h.RecursiveCopy(t, filepath.Join("testdata", "exporter", "build-metadata", "layers"), opts.LayersDir)
+					sbomDir := filepath.Join(opts.LayersDir, "sbom", "launch", "buildpack.id", "some-layer")
+					h.AssertNil(t, os.MkdirAll(sbomDir, 0755))
+					h.Mkfile(t, `{"components": [{"type": "library", "name": "some-dep", "version": "1.0"}]}`, filepath.Join(sbomDir, "sbom.cdx.json"))
+					opts.MergedSBOMPath = filepath.Join(tmpDir, "merged", "sbom.cdx.json")
+				})
+
+				it("writes a CycloneDX document merging the SBOM files for all layers", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					contents, err := os.ReadFile(opts.MergedSBOMPath)
+					h.AssertNil(t, err)
+					h.AssertStringContains(t, string(contents), `"name": "some-dep"`)
+					h.AssertStringContains(t, string(contents), `"value": "buildpack.id:some-layer"`)
+				})
+
+				when("the path has an SPDX extension", func() {
+					it.Before(func() {
+						opts.MergedSBOMPath = filepath.Join(tmpDir, "merged", "sbom.spdx.json")
+						h.Mkfile(t,
+							`{"components": [{"type": "library", "name": "some-dep", "version": "1.0", "hashes": [{"alg": "SHA-256", "content": "abc"}]}]}`,
+							filepath.Join(opts.LayersDir, "sbom", "launch", "buildpack.id", "some-layer", "sbom.cdx.json"),
+						)
+					})
+
+					it("writes an SPDX document and warns about dropped fields", func() {
+						_, err := exporter.Export(opts)
+						h.AssertNil(t, err)
+
+						contents, err := os.ReadFile(opts.MergedSBOMPath)
+						h.AssertNil(t, err)
+						h.AssertStringContains(t, string(contents), `"spdxVersion": "SPDX-2.3"`)
+						h.AssertStringContains(t, string(contents), `"name": "some-dep"`)
+						assertLogEntry(t, logHandler, "CycloneDX field 'components.hashes' (1 occurrence(s)) is not preserved in SPDX")
+					})
+				})
+			})
+
+			when("the SBOM of the previous image was saved", func() {
+				it.Before(func() {
+					h.RecursiveCopy(t, filepath.Join("testdata", "exporter", "build-metadata", "layers"), opts.LayersDir)
+					sbomDir := filepath.Join(opts.LayersDir, "sbom", "launch", "buildpack.id", "some-layer")
+					h.AssertNil(t, os.MkdirAll(sbomDir, 0755))
+					h.Mkfile(t, `{"components": [{"type": "library", "name": "some-dep", "version": "1.1"}, {"type": "library", "name": "new-dep"}]}`, filepath.Join(sbomDir, "sbom.cdx.json"))
+					h.Mkfile(t, `{"components": [{"type": "library", "name": "some-dep", "version": "1.0"}, {"type": "library", "name": "old-dep"}]}`, filepath.Join(opts.LayersDir, "previous-sbom.cdx.json"))
+				})
+
+				it("adds the changes in components to the report", func() {
+					report, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					h.AssertEq(t, report.SBOMDiff, &files.SBOMDiff{
+						Added:    []buildpack.SBOMDiffComponent{{Name: "new-dep"}},
+						Removed:  []buildpack.SBOMDiffComponent{{Name: "old-dep"}},
+						Upgraded: []buildpack.SBOMDiffUpgrade{{Name: "some-dep", PreviousVersion: "1.0", Version: "1.1"}},
+					})
+				})
+			})
+
+			when("an SBOM policy is provided", func() {
+				it.Before(func() {
+					h.RecursiveCopy(t, filepath.Join("testdata", "exporter", "build-metadata", "layers"), opts.LayersDir)
+					sbomDir := filepath.Join(opts.LayersDir, "sbom", "launch", "buildpack.id", "some-layer")
+					h.AssertNil(t, os.MkdirAll(sbomDir, 0755))
+					h.Mkfile(t, `{"components": [{"type": "library", "name": "some-dep", "version": "1.0", "licenses": [{"license": {"id": "AGPL-3.0-only"}}]}]}`, filepath.Join(sbomDir, "sbom.cdx.json"))
+					opts.SBOMPolicyPath = filepath.Join(tmpDir, "policy.toml")
+				})
+
+				it("adds violations to the report", func() {
+					h.Mkfile(t, `deny-licenses = ["AGPL-*"]`, opts.SBOMPolicyPath)
+
+					report, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					h.AssertEq(t, len(report.PolicyViolations), 1)
+					h.AssertEq(t, report.PolicyViolations[0].Component, "some-dep")
+					h.AssertEq(t, report.PolicyViolations[0].Rule, buildpack.SBOMPolicyRuleDeniedLicense)
+					assertLogEntry(t, logHandler, "SBOM policy violation: some-dep@1.0: license 'AGPL-3.0-only' is denied by 'AGPL-*'")
+				})
+
+				when("the policy is enforced", func() {
+					it("fails", func() {
+						h.Mkfile(t, "enforce = true\n"+`deny-licenses = ["AGPL-*"]`, opts.SBOMPolicyPath)
+
+						_, err := exporter.Export(opts)
+						h.AssertError(t, err, fmt.Sprintf("image SBOM violates the policy at '%s' (1 violation(s))", opts.SBOMPolicyPath))
+					})
+				})
+
+				when("the policy is invalid", func() {
+					it("fails", func() {
+						h.Mkfile(t, "[[packages]]\nname = \"some-dep\"", opts.SBOMPolicyPath)
+
+						_, err := exporter.Export(opts)
+						h.AssertError(t, err, "invalid SBOM policy: packages[0] must provide deny or min-version")
+					})
+				})
+			})
+
+			when("the launch SBOM is pruned", func() {
+				it.Before(func() {
+					h.RecursiveCopy(t, filepath.Join("testdata", "exporter", "build-metadata", "layers"), opts.LayersDir)
+					sbomDir := filepath.Join(opts.LayersDir, "sbom", "launch", "buildpack.id", "some-layer")
+					h.AssertNil(t, os.MkdirAll(sbomDir, 0755))
+					h.Mkfile(t, `{"components": [{"name": "some-dep"}, {"name": "some-build-tool", "scope": "excluded"}]}`, filepath.Join(sbomDir, "sbom.cdx.json"))
+					opts.PruneLaunchSBOM = true
+				})
+
+				it("removes build-only entries and keeps the unpruned files in the layers directory", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					pruned := string(h.MustReadFile(t, filepath.Join(opts.LayersDir, "sbom", "launch", "buildpack.id", "some-layer", "sbom.cdx.json")))
+					h.AssertStringContains(t, pruned, "some-dep")
+					h.AssertStringDoesNotContain(t, pruned, "some-build-tool")
+					unpruned := string(h.MustReadFile(t, filepath.Join(opts.LayersDir, "sbom", "launch-unpruned", "buildpack.id", "some-layer", "sbom.cdx.json")))
+					h.AssertStringContains(t, unpruned, "some-build-tool")
+					assertLogEntry(t, logHandler, "Pruned 1 build-only SBOM entries")
+				})
+
+				it("omits the launcher source from the build metadata label", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					metadataJSON, err := fakeAppImage.Label("io.buildpacks.build.metadata")
+					h.AssertNil(t, err)
+					h.AssertStringDoesNotContain(t, metadataJSON, "github.com/buildpacks/lifecycle")
+				})
+			})
+
+			when("SBOM compression is enabled", func() {
+				it.Before(func() {
+					h.RecursiveCopy(t, filepath.Join("testdata", "exporter", "build-metadata", "layers"), opts.LayersDir)
+					sbomDir := filepath.Join(opts.LayersDir, "sbom", "launch", "buildpack.id", "some-layer")
+					h.AssertNil(t, os.MkdirAll(sbomDir, 0755))
+					h.Mkfile(t, `{"components": [{"type": "library", "name": "some-dep", "version": "1.0"}]}`, filepath.Join(sbomDir, "sbom.cdx.json"))
+					opts.SBOMCompression = "gzip"
+					opts.MergedSBOMPath = filepath.Join(tmpDir, "merged.cdx.json")
+				})
+
+				it("compresses the SBOM files in the SBOM layer", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					h.AssertPathExists(t, filepath.Join(opts.LayersDir, "sbom", "launch", "buildpack.id", "some-layer", "sbom.cdx.json.gz"))
+					h.AssertPathDoesNotExist(t, filepath.Join(opts.LayersDir, "sbom", "launch", "buildpack.id", "some-layer", "sbom.cdx.json"))
+					h.AssertStringContains(t, h.Rdfile(t, opts.MergedSBOMPath), "some-dep")
+				})
+			})
+
+			when("SBOM deduplication is enabled", func() {
+				var sbomDir string
+
+				it.Before(func() {
+					h.RecursiveCopy(t, filepath.Join("testdata", "exporter", "build-metadata", "layers"), opts.LayersDir)
+					sbomDir = filepath.Join(opts.LayersDir, "sbom", "launch", "buildpack.id")
+					for _, layer := range []string{"some-layer", "other-layer"} {
+						h.AssertNil(t, os.MkdirAll(filepath.Join(sbomDir, layer), 0755))
+						h.Mkfile(t, `{"components": [{"type": "library", "name": "some-dep", "version": "1.0"}]}`, filepath.Join(sbomDir, layer, "sbom.cdx.json"))
+					}
+				})
+
+				it("replaces duplicate SBOM files with symlinks", func() {
+					if runtime.GOOS == "windows" {
+						t.Skip("SBOM files are not deduplicated on Windows")
+					}
+					opts.DedupeSBOM = true
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					fi, err := os.Lstat(filepath.Join(sbomDir, "some-layer", "sbom.cdx.json"))
+					h.AssertNil(t, err)
+					h.AssertEq(t, fi.Mode()&os.ModeSymlink != 0, true)
+				})
+
+				it("keeps duplicate SBOM files by default", func() {
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					for _, layer := range []string{"some-layer", "other-layer"} {
+						fi, err := os.Lstat(filepath.Join(sbomDir, layer, "sbom.cdx.json"))
+						h.AssertNil(t, err)
+						h.AssertEq(t, fi.Mode().IsRegular(), true)
+					}
+				})
+			})
+
+			when("a source SBOM is provided", func() {
+				it.Before(func() {
+					h.RecursiveCopy(t, filepath.Join("testdata", "exporter", "build-metadata", "layers"), opts.LayersDir)
+					opts.SourceSBOMPath = filepath.Join(tmpDir, "source.cdx.json")
+					opts.MergedSBOMPath = filepath.Join(tmpDir, "merged.cdx.json")
+				})
+
+				it("adds it to the launch SBOM layer for the application and merges it", func() {
+					h.Mkfile(t, `{"components": [{"type": "library", "name": "some-vendored-dep", "version": "1.0"}]}`, opts.SourceSBOMPath)
+
+					_, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					h.AssertPathExists(t, filepath.Join(opts.LayersDir, "sbom", "launch", "buildpacksio_lifecycle", "application", "sbom.cdx.json"))
+					merged := h.Rdfile(t, opts.MergedSBOMPath)
+					h.AssertStringContains(t, merged, "some-vendored-dep")
+					h.AssertStringContains(t, merged, "buildpacksio/lifecycle:application")
+				})
+
+				it("fails for unsupported formats", func() {
+					opts.SourceSBOMPath = filepath.Join(tmpDir, "source.json")
+					h.Mkfile(t, `{}`, opts.SourceSBOMPath)
+
+					_, err := exporter.Export(opts)
+					h.AssertError(t, err, fmt.Sprintf("unsupported SBOM format: '%s'", opts.SourceSBOMPath))
+				})
+			})
+
+			when("a scanner is provided", func() {
+				var fakeRemoteDigest = "sha256:c27a27006b74a056bed5d9edcebc394783880abe8691a8c87c78b7cffa6fa5ad"
+
+				it.Before(func() {
+					if runtime.GOOS == "windows" {
+						t.Skip("scanner scripts are not supported on windows")
+					}
+					h.RecursiveCopy(t, filepath.Join("testdata", "exporter", "build-metadata", "layers"), opts.LayersDir)
+					digestRef, err := name.NewDigest("some-repo/app-image@" + fakeRemoteDigest)
+					h.AssertNil(t, err)
+					fakeAppImage.SetIdentifier(remote.DigestIdentifier{Digest: digestRef})
+					opts.Scanner = filepath.Join(tmpDir, "scanner")
+				})
+
+				writeScanner := func(verdict string) {
+					script := "#!/usr/bin/env bash\n" +
+						`echo "$2" > "$(dirname "$0")/scanned-image"` + "\n" +
+						`echo '{"verdict": "` + verdict + `", "summary": {"critical": 1}}'` + "\n"
+					h.AssertNil(t, os.WriteFile(opts.Scanner, []byte(script), 0755)) // #nosec G306
+				}
+
+				it("adds the scan result to the report", func() {
+					writeScanner("fail")
+
+					report, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					h.AssertEq(t, report.Scan.Verdict, "fail")
+					h.AssertEq(t, report.Scan.Summary, map[string]int{"critical": 1})
+					h.AssertStringContains(t, h.Rdfile(t, filepath.Join(tmpDir, "scanned-image")), "@"+fakeRemoteDigest)
+					assertLogEntry(t, logHandler, "Scan result: fail (critical: 1)")
+				})
+
+				when("the scan is enforced", func() {
+					it.Before(func() {
+						opts.ScannerEnforce = true
+					})
+
+					it("fails if the image fails the scan", func() {
+						writeScanner("fail")
+
+						_, err := exporter.Export(opts)
+						h.AssertNotNil(t, err)
+						h.AssertStringContains(t, err.Error(), "failed the scan: fail (critical: 1)")
+					})
+
+					it("succeeds if the image passes the scan", func() {
+						writeScanner("pass")
+
+						report, err := exporter.Export(opts)
+						h.AssertNil(t, err)
+						h.AssertEq(t, report.Scan.Verdict, "pass")
+					})
+				})
+			})
+
+			when("deprecations", func() {
+				it.Before(func() {
+					opts.LayersDir = filepath.Join("testdata", "exporter", "build-metadata", "layers")
+				})
+
+				it("adds the deprecated APIs and file formats encountered during the build to the report", func() {
+					notice := deprecation.Notice{
+						Kind:    deprecation.KindBuildpackAPI,
+						Subject: "some-deprecated-buildpack",
+						API:     "0.2",
+						Message: "some-message",
+					}
+					deprecation.Record(notice)
+
+					report, err := exporter.Export(opts)
+					h.AssertNil(t, err)
+
+					var found bool
+					for _, d := range report.Deprecations {
+						if d == files.Deprecation(notice) {
+							found = true
+						}
+					}
+					h.AssertEq(t, found, true)
+				})
+			})
 		})
 
 		when("buildpack requires an escaped id", func() {
@@ -1604,6 +2268,65 @@ version = "4.5.6"
 			})
 		})
 	})
+
+	when("#NewExporterFromInputs", func() {
+		it("applies the filters of the project descriptor to the app layers", func() {
+			inputs := platform.NewLifecycleInputs(platformAPI)
+			inputs.ProjectDescriptorPath = filepath.Join(tmpDir, "project.toml")
+			h.Mkfile(t, "[build]\nexclude = [\"*.log\"]\n", inputs.ProjectDescriptorPath)
+
+			exporter, err := lifecycle.NewExporterFromInputs(inputs, buildpack.Group{}, tmpDir, &log.Logger{Handler: logHandler})
+			h.AssertNil(t, err)
+
+			factory, ok := exporter.LayerFactory.(*layers.Factory)
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, factory.AppExclude, []string{"*.log"})
+			h.AssertNil(t, exporter.CacheLayerFactory)
+		})
+
+		it("creates the cache-only layers with the cache digest algorithm", func() {
+			inputs := platform.NewLifecycleInputs(platformAPI)
+			inputs.DigestAlgorithm = "sha512"
+
+			exporter, err := lifecycle.NewExporterFromInputs(inputs, buildpack.Group{}, tmpDir, &log.Logger{Handler: logHandler})
+			h.AssertNil(t, err)
+
+			factory, ok := exporter.CacheLayerFactory.(*layers.Factory)
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, factory.DigestAlgorithm.Name, "sha512")
+		})
+	})
+
+	when("#NewExportOptions", func() {
+		var inputs *platform.LifecycleInputs
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(platformAPI)
+			inputs.RunPath = filepath.Join(tmpDir, "run.toml")
+			inputs.ProjectMetadataPath = filepath.Join(tmpDir, "project-metadata.toml")
+			inputs.AttachAttestations = true
+		})
+
+		it("provides the keychain when exporting to a registry", func() {
+			opts, err := lifecycle.NewExportOptions(inputs, authn.DefaultKeychain, &log.Logger{Handler: logHandler})
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, opts.RegistryKeychain == authn.DefaultKeychain, true)
+			h.AssertEq(t, opts.AttestationKeychain == authn.DefaultKeychain, true)
+			h.AssertEq(t, opts.DebugArtifacts, []string{inputs.AnalyzedPath, inputs.GroupPath, inputs.PlanPath})
+		})
+
+		it("does not provide the keychain when exporting to the daemon", func() {
+			inputs.UseDaemon = true
+
+			opts, err := lifecycle.NewExportOptions(inputs, authn.DefaultKeychain, &log.Logger{Handler: logHandler})
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, opts.RegistryKeychain)
+			h.AssertNil(t, opts.AttestationKeychain)
+			assertLogEntry(t, logHandler, "Attestations can only be attached to images exported to a registry")
+		})
+	})
 }
 
 func assertHasEntrypoint(t *testing.T, image *fakes.Image, entrypointPath string) {
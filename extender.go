@@ -307,7 +307,7 @@ func (e *Extender) extend(kind string, baseImage v1.Image, logger log.Logger) (v
 		return nil, err
 	}
 	workingHistory = configFile.History
-	buildOptions := e.extendOptions()
+	buildOptions := e.extendOptions(kind)
 	userID, groupID := userFrom(*configFile)
 	origUserID := userID
 	for _, dockerfile := range dockerfiles {
@@ -439,10 +439,11 @@ func (e *Extender) dockerfileFor(kind, extID string) (*extend.Dockerfile, error)
 	}, nil
 }
 
-func (e *Extender) extendOptions() extend.Options {
+func (e *Extender) extendOptions(kind string) extend.Options {
 	return extend.Options{
 		BuildContext: e.AppDir,
 		CacheTTL:     e.CacheTTL,
 		IgnorePaths:  []string{e.AppDir, e.LayersDir, e.PlatformDir},
+		Kind:         kind,
 	}
 }
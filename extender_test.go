@@ -237,6 +237,7 @@ func testExtender(t *testing.T, when spec.G, it spec.S) {
 						BuildContext: "some-app-dir",
 						IgnorePaths:  []string{"some-app-dir", "some-layers-dir", "some-platform-dir"},
 						CacheTTL:     7 * (24 * time.Hour),
+						Kind:         "build",
 					},
 					logger,
 				).DoAndReturn(
@@ -270,6 +271,7 @@ func testExtender(t *testing.T, when spec.G, it spec.S) {
 						BuildContext: "some-app-dir",
 						IgnorePaths:  []string{"some-app-dir", "some-layers-dir", "some-platform-dir"},
 						CacheTTL:     7 * (24 * time.Hour),
+						Kind:         "build",
 					},
 					logger,
 				).DoAndReturn(
@@ -376,6 +378,7 @@ func testExtender(t *testing.T, when spec.G, it spec.S) {
 								BuildContext: "some-app-dir",
 								IgnorePaths:  []string{"some-app-dir", "some-layers-dir", "some-platform-dir"},
 								CacheTTL:     7 * (24 * time.Hour),
+								Kind:         "run",
 							},
 							logger,
 						).DoAndReturn(
@@ -408,6 +411,7 @@ func testExtender(t *testing.T, when spec.G, it spec.S) {
 								BuildContext: "some-app-dir",
 								IgnorePaths:  []string{"some-app-dir", "some-layers-dir", "some-platform-dir"},
 								CacheTTL:     7 * (24 * time.Hour),
+								Kind:         "run",
 							},
 							logger,
 						).DoAndReturn(
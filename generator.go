@@ -1,25 +1,34 @@
 package lifecycle
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 
+	"github.com/BurntSushi/toml"
+
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/platform"
 
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/env"
+	"github.com/buildpacks/lifecycle/internal/encoding"
+	"github.com/buildpacks/lifecycle/internal/extend"
 	"github.com/buildpacks/lifecycle/internal/fsutil"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform/files"
+	"github.com/buildpacks/lifecycle/telemetry/metrics"
+	"github.com/buildpacks/lifecycle/telemetry/progress"
+	"github.com/buildpacks/lifecycle/telemetry/tracing"
 )
 
 type Generator struct {
 	AppDir         string
 	BuildConfigDir string
+	DryRun         bool   // if true, the args for each Dockerfile are written to args.toml alongside the Dockerfile
 	GeneratedDir   string // e.g., <layers>/generated
 	PlatformAPI    *api.Version
 	PlatformDir    string
@@ -117,7 +126,16 @@ type GenerateResult struct {
 	UsePlan    bool
 }
 
+// Generate is equivalent to GenerateContext with context.Background().
+//
+// Deprecated: use GenerateContext.
 func (g *Generator) Generate() (GenerateResult, error) {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext runs the generate step for each extension in the group.
+// If the context is done, the running extension is stopped (when supported by the generate executor) and the context error is returned.
+func (g *Generator) GenerateContext(ctx context.Context) (GenerateResult, error) {
 	defer log.NewMeasurement("Generator", g.Logger)()
 	inputs := g.getGenerateInputs()
 	extensionOutputParentDir, err := os.MkdirTemp("", "cnb-extensions-generated.")
@@ -127,7 +145,11 @@ func (g *Generator) Generate() (GenerateResult, error) {
 	defer os.RemoveAll(extensionOutputParentDir)
 	inputs.OutputDir = extensionOutputParentDir
 
-	var dockerfiles []buildpack.DockerfileInfo
+	var (
+		bomFiles    []buildpack.BOMFile
+		dockerfiles []buildpack.DockerfileInfo
+	)
+	plans := map[string]buildpack.Plan{} // the plan provided to each extension, used to resolve args in extend config
 	filteredPlan := g.Plan
 	for _, ext := range g.Extensions {
 		g.Logger.Debugf("Running generate for extension %s", ext)
@@ -140,17 +162,28 @@ func (g *Generator) Generate() (GenerateResult, error) {
 
 		g.Logger.Debug("Finding plan")
 		inputs.Plan = filteredPlan.Find(buildpack.KindExtension, ext.ID)
+		plans[ext.ID] = inputs.Plan
 
 		if g.AnalyzedMD.RunImage != nil && g.AnalyzedMD.RunImage.TargetMetadata != nil && g.PlatformAPI.AtLeast("0.12") {
 			inputs.Env = env.NewBuildEnv(append(inputs.Env.List(), platform.EnvVarsFor(*g.AnalyzedMD.RunImage.TargetMetadata)...))
 		}
 		g.Logger.Debug("Invoking command")
-		result, err := g.Executor.Generate(*descriptor, inputs, g.Logger)
+		extLogger, out, errOut, flush := buildpackOutput(g.Logger, ext.ID, g.Out, g.Err)
+		inputs.Out, inputs.Err = out, errOut
+		span := tracing.Start("generate "+ext.ID, tracing.String("cnb.extension.id", ext.ID), tracing.String("cnb.extension.version", ext.Version))
+		stopTimer := metrics.Timer(metrics.BuildpackDuration, metrics.L("buildpack", ext.ID), metrics.L("version", ext.Version), metrics.L("step", "generate"))
+		finishProgress := progress.BuildpackStarted(ext.ID, ext.Version, progress.StepGenerate)
+		result, err := generateWithContext(ctx, g.Executor, *descriptor, inputs, extLogger)
+		flush()
+		stopTimer()
+		finishProgress(err)
+		span.End(err)
 		if err != nil {
 			return GenerateResult{}, err
 		}
 
 		// aggregate build results
+		bomFiles = append(bomFiles, result.BOMFiles...)
 		dockerfiles = append(dockerfiles, result.Dockerfiles...)
 		filteredPlan = filteredPlan.Filter(result.MetRequires)
 
@@ -178,7 +211,12 @@ func (g *Generator) Generate() (GenerateResult, error) {
 	}
 
 	g.Logger.Debug("Copying Dockerfiles")
-	if err = g.copyDockerfiles(dockerfiles); err != nil {
+	if err = g.copyDockerfiles(dockerfiles, plans); err != nil {
+		return GenerateResult{}, err
+	}
+
+	g.Logger.Debug("Copying SBOM files")
+	if err = g.copySBOMFiles(bomFiles, dockerfiles); err != nil {
 		return GenerateResult{}, err
 	}
 
@@ -200,7 +238,7 @@ func (g *Generator) getGenerateInputs() buildpack.GenerateInputs {
 	}
 }
 
-func (g *Generator) copyDockerfiles(dockerfiles []buildpack.DockerfileInfo) error {
+func (g *Generator) copyDockerfiles(dockerfiles []buildpack.DockerfileInfo, plans map[string]buildpack.Plan) error {
 	for _, dockerfile := range dockerfiles {
 		targetDir := filepath.Join(g.GeneratedDir, dockerfile.Kind, launch.EscapeID(dockerfile.ExtensionID))
 		var targetPath = filepath.Join(targetDir, "Dockerfile")
@@ -214,13 +252,94 @@ func (g *Generator) copyDockerfiles(dockerfiles []buildpack.DockerfileInfo) erro
 		if err := fsutil.Copy(dockerfile.Path, targetPath); err != nil {
 			return fmt.Errorf("failed to copy Dockerfile at %s: %w", dockerfile.Path, err)
 		}
-		// check for extend-config.toml and if found, copy
+		// check for extend-config.toml and if found, copy with args from the build plan resolved
 		extendConfigPath := filepath.Join(filepath.Dir(dockerfile.Path), "extend-config.toml")
-		if err := fsutil.Copy(extendConfigPath, filepath.Join(targetDir, "extend-config.toml")); err != nil {
+		if _, err := os.Stat(extendConfigPath); err != nil {
 			if !os.IsNotExist(err) {
+				return fmt.Errorf("failed to read extend config at %s: %w", extendConfigPath, err)
+			}
+		} else {
+			plan := plans[dockerfile.ExtensionID]
+			if err := extend.ResolveConfig(extendConfigPath, filepath.Join(targetDir, "extend-config.toml"), plan.MetadataValues); err != nil {
 				return fmt.Errorf("failed to copy extend config at %s: %w", extendConfigPath, err)
 			}
 		}
+		if g.DryRun {
+			if err := writeArgs(targetDir, dockerfile.Kind); err != nil {
+				return fmt.Errorf("failed to write args for Dockerfile at %s: %w", dockerfile.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeArgs writes the args that will be provided to the Dockerfile in the provided directory,
+// as resolved from its extend-config.toml, to args.toml.
+// Args provided by the extender itself (base_image, build_id, user_id, and group_id) depend on the image being extended
+// and are not included.
+func writeArgs(dir, kind string) error {
+	var config extend.Config
+	if _, err := toml.DecodeFile(filepath.Join(dir, "extend-config.toml"), &config); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	args := config.Build.Args
+	if kind == buildpack.DockerfileKindRun {
+		args = config.Run.Args
+	}
+	return encoding.WriteTOML(filepath.Join(dir, "args.toml"), extend.BuildConfig{Args: args})
+}
+
+// copySBOMFiles copies any SBOM files written by extensions to the <generated>/sbom directory,
+// from which the builder copies them to <layers>/sbom alongside SBOM files written by buildpacks.
+// SBOM files describing the run image are skipped for extensions whose run.Dockerfile is ignored,
+// as the packages they describe will not be present in the final run image.
+//
+// After:
+// /generated
+// └── sbom
+//
+//	├── build
+//	│   └── extension.id
+//	│       └── sbom.cdx.json
+//	└── launch
+//	    └── extension.id
+//	        └── sbom.cdx.json
+func (g *Generator) copySBOMFiles(bomFiles []buildpack.BOMFile, dockerfiles []buildpack.DockerfileInfo) error {
+	sbomDir := filepath.Join(g.GeneratedDir, "sbom")
+	if err := os.RemoveAll(sbomDir); err != nil {
+		return fmt.Errorf("failed to clean generated SBOM directory: %w", err)
+	}
+	ignored := map[string]bool{}
+	for _, dockerfile := range dockerfiles {
+		if dockerfile.Kind == buildpack.DockerfileKindRun && dockerfile.Ignore {
+			ignored[dockerfile.ExtensionID] = true
+		}
+	}
+	for _, bomFile := range bomFiles {
+		var targetDir string
+		switch bomFile.LayerType {
+		case buildpack.LayerTypeBuild:
+			targetDir = filepath.Join(sbomDir, "build", launch.EscapeID(bomFile.BuildpackID))
+		case buildpack.LayerTypeLaunch:
+			if ignored[bomFile.BuildpackID] {
+				g.Logger.Debugf("Skipping %s as the run.Dockerfile for extension %s is ignored", bomFile.Path, bomFile.BuildpackID)
+				continue
+			}
+			targetDir = filepath.Join(sbomDir, "launch", launch.EscapeID(bomFile.BuildpackID))
+		default:
+			continue
+		}
+		name, err := bomFile.Name()
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(targetDir, os.ModePerm); err != nil {
+			return err
+		}
+		g.Logger.Debugf("Copying %s to %s", bomFile.Path, filepath.Join(targetDir, name))
+		if err = fsutil.Copy(bomFile.Path, filepath.Join(targetDir, name)); err != nil {
+			return fmt.Errorf("failed to copy SBOM file at %s: %w", bomFile.Path, err)
+		}
 	}
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/BurntSushi/toml"
 	"github.com/apex/log"
 	"github.com/apex/log/handlers/discard"
 	"github.com/apex/log/handlers/memory"
@@ -19,6 +20,7 @@ import (
 	"github.com/buildpacks/lifecycle"
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/internal/extend"
 	llog "github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform/files"
 	h "github.com/buildpacks/lifecycle/testhelpers"
@@ -267,7 +269,7 @@ func testGenerator(t *testing.T, when spec.G, it spec.S) {
 			buildDockerfilePathA := filepath.Join(tmpDir, "A", "build.Dockerfile")
 			h.Mkfile(t, "some-build.Dockerfile-content-A", buildDockerfilePathA)
 			extendConfigPathA := filepath.Join(tmpDir, "A", "extend-config.toml")
-			h.Mkfile(t, "some-extend-config.toml-content-A", extendConfigPathA)
+			h.Mkfile(t, "[[build.args]]\nname = \"some-arg-A\"\nvalue = \"some-value-A\"\n", extendConfigPathA)
 			executor.EXPECT().Generate(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
 				func(d buildpack.ExtDescriptor, inputs buildpack.GenerateInputs, _ *log.Logger) (buildpack.GenerateOutputs, error) {
 					h.AssertContains(t, inputs.Env.List(), "CNB_TARGET_ARCH=amd64")
@@ -299,7 +301,7 @@ func testGenerator(t *testing.T, when spec.G, it spec.S) {
 			buildDockerfilePathA := filepath.Join(tmpDir, "A", "build.Dockerfile")
 			h.Mkfile(t, "some-build.Dockerfile-content-A", buildDockerfilePathA)
 			extendConfigPathA := filepath.Join(tmpDir, "A", "extend-config.toml")
-			h.Mkfile(t, "some-extend-config.toml-content-A", extendConfigPathA)
+			h.Mkfile(t, "[[build.args]]\nname = \"some-arg-A\"\nvalue = \"some-value-A\"\n", extendConfigPathA)
 			executor.EXPECT().Generate(extA, gomock.Any(), gomock.Any()).Return(buildpack.GenerateOutputs{
 				Dockerfiles: []buildpack.DockerfileInfo{
 					{
@@ -335,7 +337,7 @@ func testGenerator(t *testing.T, when spec.G, it spec.S) {
 			runDockerfilePathC := filepath.Join(tmpDir, "C", "run.Dockerfile")
 			h.Mkfile(t, "some-run.Dockerfile-content-C", runDockerfilePathC)
 			extendConfigPathC := filepath.Join(tmpDir, "C", "extend-config.toml")
-			h.Mkfile(t, "some-extend-config.toml-content-C", extendConfigPathC)
+			h.Mkfile(t, "[[build.args]]\nname = \"some-arg-C\"\nvalue = \"some-value-C\"\n", extendConfigPathC)
 			executor.EXPECT().Generate(extC, gomock.Any(), gomock.Any()).Return(buildpack.GenerateOutputs{
 				Dockerfiles: []buildpack.DockerfileInfo{
 					{
@@ -369,11 +371,11 @@ func testGenerator(t *testing.T, when spec.G, it spec.S) {
 
 			t.Log("copies extend-config.toml files if they exist")
 			contents = h.MustReadFile(t, filepath.Join(generatedDir, "build", "A", "extend-config.toml"))
-			h.AssertEq(t, string(contents), "some-extend-config.toml-content-A")
+			h.AssertEq(t, string(contents), "[[build.args]]\nname = \"some-arg-A\"\nvalue = \"some-value-A\"\n")
 			contents = h.MustReadFile(t, filepath.Join(generatedDir, "build", "C", "extend-config.toml"))
-			h.AssertEq(t, string(contents), "some-extend-config.toml-content-C")
+			h.AssertEq(t, string(contents), "[[build.args]]\nname = \"some-arg-C\"\nvalue = \"some-value-C\"\n")
 			contents = h.MustReadFile(t, filepath.Join(generatedDir, "run", "C", "extend-config.toml"))
-			h.AssertEq(t, string(contents), "some-extend-config.toml-content-C")
+			h.AssertEq(t, string(contents), "[[build.args]]\nname = \"some-arg-C\"\nvalue = \"some-value-C\"\n")
 
 			t.Log("does not pollute the output directory")
 			h.AssertPathDoesNotExist(t, filepath.Join(generatedDir, "A", "run.Dockerfile"))
@@ -382,6 +384,126 @@ func testGenerator(t *testing.T, when spec.G, it spec.S) {
 			h.AssertPathDoesNotExist(t, filepath.Join(generatedDir, "C", "build.Dockerfile"))
 		})
 
+		it("resolves extend-config.toml args sourced from the build plan", func() {
+			generator.Extensions = generator.Extensions[:1]
+			generator.Plan = files.Plan{
+				Entries: []files.BuildPlanEntry{
+					{
+						Providers: []buildpack.GroupElement{
+							{ID: "A", Version: "v1", Extension: true},
+						},
+						Requires: []buildpack.Require{
+							{Name: "os-packages", Metadata: map[string]interface{}{"packages": []interface{}{"curl", "git"}}},
+							{Name: "os-packages", Metadata: map[string]interface{}{"packages": []interface{}{"git", "jq"}}},
+						},
+					},
+				},
+			}
+			dirStore.EXPECT().LookupExt("A", "v1").Return(&extA, nil)
+			h.Mkdir(t, filepath.Join(tmpDir, "A"))
+			buildDockerfilePathA := filepath.Join(tmpDir, "A", "build.Dockerfile")
+			h.Mkfile(t, "some-build.Dockerfile-content-A", buildDockerfilePathA)
+			h.Mkfile(t, `
+[[build.args]]
+name = "packages"
+from-plan = { entry = "os-packages", key = "packages" }
+`, filepath.Join(tmpDir, "A", "extend-config.toml"))
+			executor.EXPECT().Generate(extA, gomock.Any(), gomock.Any()).Return(buildpack.GenerateOutputs{
+				Dockerfiles: []buildpack.DockerfileInfo{{ExtensionID: "A", Kind: "build", Path: buildDockerfilePathA}},
+			}, nil)
+
+			_, err := generator.Generate()
+			h.AssertNil(t, err)
+
+			var config extend.Config
+			_, err = toml.DecodeFile(filepath.Join(generatedDir, "build", "A", "extend-config.toml"), &config)
+			h.AssertNil(t, err)
+			h.AssertEq(t, config.Build.Args, []extend.Arg{{Name: "packages", Value: "curl git jq"}})
+		})
+
+		when("dry run", func() {
+			it("writes the args for each Dockerfile", func() {
+				generator.DryRun = true
+				generator.Extensions = generator.Extensions[:1]
+				dirStore.EXPECT().LookupExt("A", "v1").Return(&extA, nil)
+				h.Mkdir(t, filepath.Join(tmpDir, "A"))
+				buildDockerfilePathA := filepath.Join(tmpDir, "A", "build.Dockerfile")
+				h.Mkfile(t, "some-build.Dockerfile-content-A", buildDockerfilePathA)
+				runDockerfilePathA := filepath.Join(tmpDir, "A", "run.Dockerfile")
+				h.Mkfile(t, "some-run.Dockerfile-content-A", runDockerfilePathA)
+				h.Mkfile(t, "[[build.args]]\nname = \"some-build-arg\"\nvalue = \"some-build-value\"\n",
+					filepath.Join(tmpDir, "A", "extend-config.toml"))
+				executor.EXPECT().Generate(extA, gomock.Any(), gomock.Any()).Return(buildpack.GenerateOutputs{
+					Dockerfiles: []buildpack.DockerfileInfo{
+						{ExtensionID: "A", Kind: "build", Path: buildDockerfilePathA},
+						{ExtensionID: "A", Kind: "run", Path: runDockerfilePathA},
+					},
+				}, nil)
+
+				_, err := generator.Generate()
+				h.AssertNil(t, err)
+
+				var args extend.BuildConfig
+				_, err = toml.DecodeFile(filepath.Join(generatedDir, "build", "A", "args.toml"), &args)
+				h.AssertNil(t, err)
+				h.AssertEq(t, args.Args, []extend.Arg{{Name: "some-build-arg", Value: "some-build-value"}})
+				h.AssertEq(t, h.Rdfile(t, filepath.Join(generatedDir, "build", "A", "Dockerfile")), "some-build.Dockerfile-content-A")
+
+				args = extend.BuildConfig{}
+				_, err = toml.DecodeFile(filepath.Join(generatedDir, "run", "A", "args.toml"), &args)
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(args.Args), 0)
+			})
+		})
+
+		it("copies SBOM files to the correct locations", func() {
+			generator.Extensions = generator.Extensions[:2]
+
+			// extension A has a build SBOM, and a run SBOM for a run.Dockerfile that is ignored
+			dirStore.EXPECT().LookupExt("A", "v1").Return(&extA, nil)
+			h.Mkdir(t, filepath.Join(tmpDir, "A"))
+			runDockerfilePathA := filepath.Join(tmpDir, "A", "run.Dockerfile")
+			h.Mkfile(t, "FROM some-run-image", runDockerfilePathA)
+			buildSBOMPathA := filepath.Join(tmpDir, "A", "build.sbom.cdx.json")
+			h.Mkfile(t, `{"key": "some-build-bom-content-A"}`, buildSBOMPathA)
+			runSBOMPathA := filepath.Join(tmpDir, "A", "run.sbom.cdx.json")
+			h.Mkfile(t, `{"key": "some-run-bom-content-A"}`, runSBOMPathA)
+			executor.EXPECT().Generate(extA, gomock.Any(), gomock.Any()).Return(buildpack.GenerateOutputs{
+				BOMFiles: []buildpack.BOMFile{
+					{BuildpackID: "A", LayerType: buildpack.LayerTypeBuild, Path: buildSBOMPathA},
+					{BuildpackID: "A", LayerType: buildpack.LayerTypeLaunch, Path: runSBOMPathA},
+				},
+				Dockerfiles: []buildpack.DockerfileInfo{
+					{ExtensionID: "A", Kind: "run", Path: runDockerfilePathA, WithBase: "some-run-image"},
+				},
+			}, nil)
+
+			// extension B has a run SBOM for a run.Dockerfile that switches the run image
+			dirStore.EXPECT().LookupExt("ext/B", "v2").Return(&extB, nil)
+			h.Mkdir(t, filepath.Join(tmpDir, "B"))
+			runDockerfilePathB := filepath.Join(tmpDir, "B", "run.Dockerfile")
+			h.Mkfile(t, "FROM some-other-run-image", runDockerfilePathB)
+			runSBOMPathB := filepath.Join(tmpDir, "B", "run.sbom.spdx.json")
+			h.Mkfile(t, `{"key": "some-run-bom-content-B"}`, runSBOMPathB)
+			executor.EXPECT().Generate(extB, gomock.Any(), gomock.Any()).Return(buildpack.GenerateOutputs{
+				BOMFiles: []buildpack.BOMFile{
+					{BuildpackID: "ext/B", LayerType: buildpack.LayerTypeLaunch, Path: runSBOMPathB},
+				},
+				Dockerfiles: []buildpack.DockerfileInfo{
+					{ExtensionID: "ext/B", Kind: "run", Path: runDockerfilePathB, WithBase: "some-other-run-image"},
+				},
+			}, nil)
+
+			_, err := generator.Generate()
+			h.AssertNil(t, err)
+
+			contents := h.MustReadFile(t, filepath.Join(generatedDir, "sbom", "build", "A", "sbom.cdx.json"))
+			h.AssertEq(t, string(contents), `{"key": "some-build-bom-content-A"}`)
+			contents = h.MustReadFile(t, filepath.Join(generatedDir, "sbom", "launch", "ext_B", "sbom.spdx.json"))
+			h.AssertEq(t, string(contents), `{"key": "some-run-bom-content-B"}`)
+			h.AssertPathDoesNotExist(t, filepath.Join(generatedDir, "sbom", "launch", "A"))
+		})
+
 		when("returning run image metadata", func() {
 			var (
 				runDockerfilePathA = filepath.Join(tmpDir, "run.Dockerfile.A")
@@ -18,6 +18,7 @@ require (
 	github.com/moby/buildkit v0.11.6
 	github.com/pkg/errors v0.9.1
 	github.com/sclevine/spec v1.4.0
+	golang.org/x/net v0.11.0
 	golang.org/x/sync v0.3.0
 	golang.org/x/sys v0.10.0
 )
@@ -112,7 +113,6 @@ require (
 	go.etcd.io/etcd/raft/v3 v3.5.9 // indirect
 	golang.org/x/crypto v0.10.0 // indirect
 	golang.org/x/mod v0.10.0 // indirect
-	golang.org/x/net v0.11.0 // indirect
 	golang.org/x/oauth2 v0.9.0 // indirect
 	golang.org/x/text v0.10.0 // indirect
 	golang.org/x/time v0.3.0 // indirect
@@ -3,8 +3,6 @@ package lifecycle
 import (
 	"fmt"
 
-	"github.com/BurntSushi/toml"
-
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform/files"
@@ -65,13 +63,7 @@ func (h *DefaultConfigHandler) ReadGroup(path string) (buildpackGroup []buildpac
 }
 
 func ReadGroup(path string) (buildpack.Group, error) {
-	var group buildpack.Group
-	_, err := toml.DecodeFile(path, &group)
-	for e := range group.GroupExtensions {
-		group.GroupExtensions[e].Extension = true
-		group.GroupExtensions[e].Optional = true
-	}
-	return group, err
+	return files.ReadGroup(path)
 }
 
 func (h *DefaultConfigHandler) ReadOrder(path string) (buildpack.Order, buildpack.Order, error) {
@@ -83,22 +75,7 @@ func (h *DefaultConfigHandler) ReadOrder(path string) (buildpack.Order, buildpac
 }
 
 func ReadOrder(path string) (buildpack.Order, buildpack.Order, error) {
-	var order struct {
-		Order           buildpack.Order `toml:"order"`
-		OrderExtensions buildpack.Order `toml:"order-extensions"`
-	}
-	_, err := toml.DecodeFile(path, &order)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read order file: %w", err)
-	}
-	for g, group := range order.OrderExtensions {
-		for e := range group.Group {
-			group.Group[e].Extension = true
-			group.Group[e].Optional = true
-		}
-		order.OrderExtensions[g] = group
-	}
-	return order.Order, order.OrderExtensions, err
+	return files.ReadOrder(path)
 }
 
 func (h *DefaultConfigHandler) ReadRun(runPath string, logger log.Logger) (files.Run, error) {
@@ -5,6 +5,8 @@ import (
 
 	"github.com/buildpacks/imgutil"
 	"github.com/pkg/errors"
+
+	lerrors "github.com/buildpacks/lifecycle/errors"
 )
 
 func DecodeLabel(image imgutil.Image, label string, v interface{}) error {
@@ -19,7 +21,7 @@ func DecodeLabel(image imgutil.Image, label string, v interface{}) error {
 		return nil
 	}
 	if err := json.Unmarshal([]byte(contents), v); err != nil {
-		return errors.Wrapf(err, "failed to unmarshal context of label '%s'", label)
+		return errors.Wrapf(lerrors.NewDecodeError(label, err), "failed to unmarshal context of label '%s'", label)
 	}
 	return nil
 }
@@ -4,6 +4,9 @@ import (
 	"github.com/buildpacks/imgutil"
 	"github.com/buildpacks/imgutil/remote"
 	"github.com/google/go-containerregistry/pkg/authn"
+
+	lerrors "github.com/buildpacks/lifecycle/errors"
+	"github.com/buildpacks/lifecycle/internal/network"
 )
 
 const RemoteKind = "remote"
@@ -17,11 +20,15 @@ func (h *RemoteHandler) InitImage(imageRef string) (imgutil.Image, error) {
 		return nil, nil
 	}
 
-	return remote.NewImage(
+	img, err := remote.NewImage(
 		imageRef,
 		h.keychain,
-		remote.FromBaseImage(imageRef),
+		remote.FromBaseImage(network.PullRef(imageRef, h.keychain)),
 	)
+	if err != nil {
+		return nil, lerrors.WrapRegistryError(imageRef, lerrors.AccessRead, err)
+	}
+	return img, nil
 }
 
 func (h *RemoteHandler) Kind() string {
@@ -0,0 +1,125 @@
+// Package attest attaches in-toto attestations to application images as OCI 1.1 referrer artifacts.
+package attest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// ArtifactType is the artifact type (config media type) of the pushed attestation manifests.
+	ArtifactType = "application/vnd.in-toto+json"
+	// EnvelopeMediaType is the media type of the attestation layer, which is an unsigned DSSE envelope.
+	EnvelopeMediaType = "application/vnd.dsse.envelope.v1+json"
+	// PredicateTypeAnnotation records the predicate type of the attestation on the attestation layer.
+	PredicateTypeAnnotation = "in-toto.io/predicate-type"
+
+	PredicateTypeBuildMetadata = "https://buildpacks.io/lifecycle/build-metadata/v1"
+	PredicateTypeCycloneDX     = "https://cyclonedx.org/bom"
+
+	payloadType   = "application/vnd.in-toto+json"
+	statementType = "https://in-toto.io/Statement/v0.1"
+)
+
+// Attestation is an in-toto predicate about the application image.
+type Attestation struct {
+	PredicateType string
+	Predicate     json.RawMessage
+}
+
+type statement struct {
+	Type          string          `json:"_type"`
+	Subject       []subject       `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+type subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type envelope struct {
+	PayloadType string        `json:"payloadType"`
+	Payload     string        `json:"payload"`
+	Signatures  []interface{} `json:"signatures"`
+}
+
+// Attach pushes each attestation as an OCI artifact whose subject is the image at imageRef,
+// so that tools such as cosign and oras can discover it using the referrers API (or the referrers tag schema,
+// for registries that don't support the API).
+// The image reference must include the digest of the image. It returns the digests of the pushed artifacts.
+func Attach(imageRef string, attestations []Attestation, keychain authn.Keychain) ([]string, error) {
+	ref, err := name.NewDigest(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("parsing image reference '%s': %w", imageRef, err)
+	}
+	opt := remote.WithAuthFromKeychain(keychain)
+	desc, err := remote.Head(ref, opt)
+	if err != nil {
+		return nil, fmt.Errorf("getting descriptor for '%s': %w", imageRef, err)
+	}
+	var digests []string
+	for _, attestation := range attestations {
+		artifact, err := newArtifact(ref, *desc, attestation)
+		if err != nil {
+			return nil, fmt.Errorf("creating %s attestation: %w", attestation.PredicateType, err)
+		}
+		digest, err := artifact.Digest()
+		if err != nil {
+			return nil, err
+		}
+		if err = remote.Write(ref.Context().Digest(digest.String()), artifact, opt); err != nil {
+			return nil, fmt.Errorf("pushing %s attestation: %w", attestation.PredicateType, err)
+		}
+		digests = append(digests, digest.String())
+	}
+	return digests, nil
+}
+
+func newArtifact(ref name.Digest, desc v1.Descriptor, attestation Attestation) (v1.Image, error) {
+	payload, err := json.Marshal(statement{
+		Type: statementType,
+		Subject: []subject{{
+			Name:   ref.Context().Name(),
+			Digest: map[string]string{desc.Digest.Algorithm: desc.Digest.Hex},
+		}},
+		PredicateType: attestation.PredicateType,
+		Predicate:     attestation.Predicate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	contents, err := json.Marshal(envelope{
+		PayloadType: payloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []interface{}{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	image, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(contents, EnvelopeMediaType),
+		Annotations: map[string]string{PredicateTypeAnnotation: attestation.PredicateType},
+	})
+	if err != nil {
+		return nil, err
+	}
+	image = mutate.MediaType(image, types.OCIManifestSchema1)
+	image = mutate.ConfigMediaType(image, ArtifactType)
+	return mutate.Subject(image, v1.Descriptor{
+		MediaType: desc.MediaType,
+		Size:      desc.Size,
+		Digest:    desc.Digest,
+	}).(v1.Image), nil
+}
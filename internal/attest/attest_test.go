@@ -0,0 +1,102 @@
+package attest_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/attest"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestAttest(t *testing.T) {
+	spec.Run(t, "Attest", testAttest, spec.Report(report.Terminal{}))
+}
+
+func testAttest(t *testing.T, when spec.G, it spec.S) {
+	var (
+		server   *httptest.Server
+		imageRef name.Digest
+	)
+
+	it.Before(func() {
+		server = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile)), registry.WithReferrersSupport(true)))
+		repo := strings.TrimPrefix(server.URL, "http://") + "/some-app"
+		image, err := random.Image(10, 1)
+		h.AssertNil(t, err)
+		tag, err := name.NewTag(repo + ":latest")
+		h.AssertNil(t, err)
+		h.AssertNil(t, remote.Write(tag, image))
+		digest, err := image.Digest()
+		h.AssertNil(t, err)
+		imageRef = tag.Context().Digest(digest.String())
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	when("#Attach", func() {
+		it("pushes the attestations as referrers of the image", func() {
+			digests, err := attest.Attach(imageRef.String(), []attest.Attestation{
+				{PredicateType: attest.PredicateTypeCycloneDX, Predicate: json.RawMessage(`{"bomFormat":"CycloneDX"}`)},
+				{PredicateType: attest.PredicateTypeBuildMetadata, Predicate: json.RawMessage(`{"buildpacks":[]}`)},
+			}, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(digests), 2)
+
+			referrers, err := remote.Referrers(imageRef)
+			h.AssertNil(t, err)
+			manifest, err := referrers.IndexManifest()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(manifest.Manifests), 2)
+			for _, desc := range manifest.Manifests {
+				h.AssertEq(t, desc.ArtifactType, attest.ArtifactType)
+			}
+
+			artifact, err := remote.Image(imageRef.Context().Digest(digests[0]))
+			h.AssertNil(t, err)
+			artifactManifest, err := artifact.Manifest()
+			h.AssertNil(t, err)
+			h.AssertEq(t, artifactManifest.Subject.Digest.String(), imageRef.DigestStr())
+			h.AssertEq(t, artifactManifest.Layers[0].Annotations[attest.PredicateTypeAnnotation], attest.PredicateTypeCycloneDX)
+
+			layers, err := artifact.Layers()
+			h.AssertNil(t, err)
+			rc, err := layers[0].Uncompressed()
+			h.AssertNil(t, err)
+			defer rc.Close()
+			var envelope struct {
+				PayloadType string `json:"payloadType"`
+				Payload     string `json:"payload"`
+			}
+			h.AssertNil(t, json.NewDecoder(rc).Decode(&envelope))
+			h.AssertEq(t, envelope.PayloadType, "application/vnd.in-toto+json")
+			payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+			h.AssertNil(t, err)
+			h.AssertJSONEq(t, string(payload), `{
+				"_type": "https://in-toto.io/Statement/v0.1",
+				"subject": [{"name": "`+imageRef.Context().Name()+`", "digest": {"sha256": "`+strings.TrimPrefix(imageRef.DigestStr(), "sha256:")+`"}}],
+				"predicateType": "https://cyclonedx.org/bom",
+				"predicate": {"bomFormat": "CycloneDX"}
+			}`)
+		})
+
+		it("errors if the image reference has no digest", func() {
+			_, err := attest.Attach(imageRef.Context().Tag("latest").String(), nil, authn.DefaultKeychain)
+			h.AssertNotNil(t, err)
+		})
+	})
+}
@@ -0,0 +1,191 @@
+// Package chaos injects failures into the lifecycle, so that platform integrators can test their retry and error handling
+// against realistic lifecycle failures: failed registry requests, layers with corrupt digests, and slow buildpacks.
+//
+// Failures are only injected by lifecycle binaries built with the chaos build tag (e.g., GOFLAGS=-tags=chaos make build),
+// and configured with the CNB_CHAOS_* environment variables. In other builds, the hooks do nothing.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// EnvFailRegistryRequest is the number N of the registry request (starting at 1, including retries) that fails
+	// with a connection error, in each phase.
+	EnvFailRegistryRequest = "CNB_CHAOS_FAIL_REGISTRY_REQUEST"
+	// EnvCorruptLayerDigest is a pattern (see path.Match) of the IDs of the layers whose digest is corrupted when they are exported
+	// (or cached), e.g., "buildpacksio/lifecycle:launcher" or "some/buildpack:*".
+	EnvCorruptLayerDigest = "CNB_CHAOS_CORRUPT_LAYER_DIGEST"
+	// EnvDelayBuildpack is a comma-separated list of <buildpack ID>=<duration> delaying the build of each buildpack, e.g., "some/buildpack=30s".
+	EnvDelayBuildpack = "CNB_CHAOS_DELAY_BUILDPACK"
+)
+
+// Config describes the failures to inject.
+type Config struct {
+	// FailRegistryRequest is the number of the registry request that fails, or 0 to not fail registry requests.
+	FailRegistryRequest int64
+	// CorruptLayers is the pattern of the IDs of the layers whose digest is corrupted, or empty to not corrupt digests.
+	CorruptLayers string
+	// BuildpackDelays maps the ID of a buildpack to how long its build is delayed.
+	BuildpackDelays map[string]time.Duration
+}
+
+// ConfigFromEnv returns the failures to inject from the provided environment lookup function.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := Config{BuildpackDelays: map[string]time.Duration{}}
+	if val := getenv(EnvFailRegistryRequest); val != "" {
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || n < 1 {
+			return Config{}, fmt.Errorf("invalid %s '%s': must be a positive integer", EnvFailRegistryRequest, val)
+		}
+		config.FailRegistryRequest = n
+	}
+	if val := getenv(EnvCorruptLayerDigest); val != "" {
+		if _, err := path.Match(val, ""); err != nil {
+			return Config{}, fmt.Errorf("invalid %s '%s': %w", EnvCorruptLayerDigest, val, err)
+		}
+		config.CorruptLayers = val
+	}
+	for _, entry := range strings.Split(getenv(EnvDelayBuildpack), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, val, found := strings.Cut(entry, "=")
+		delay, err := time.ParseDuration(val)
+		if !found || id == "" || err != nil || delay < 0 {
+			return Config{}, fmt.Errorf("invalid %s entry '%s': must be <buildpack ID>=<duration>", EnvDelayBuildpack, entry)
+		}
+		config.BuildpackDelays[id] = delay
+	}
+	return config, nil
+}
+
+// Active returns a description of each failure to inject.
+func (c Config) Active() []string {
+	var active []string
+	if c.FailRegistryRequest > 0 {
+		active = append(active, fmt.Sprintf("registry request %d fails", c.FailRegistryRequest))
+	}
+	if c.CorruptLayers != "" {
+		active = append(active, fmt.Sprintf("digests of layers '%s' are corrupted", c.CorruptLayers))
+	}
+	var ids []string
+	for id := range c.BuildpackDelays {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		active = append(active, fmt.Sprintf("build of buildpack '%s' is delayed by %s", id, c.BuildpackDelays[id]))
+	}
+	return active
+}
+
+// Transport returns a transport failing the configured registry request, and passing the other requests to the provided transport.
+func (c Config) Transport(inner http.RoundTripper) http.RoundTripper {
+	if c.FailRegistryRequest == 0 {
+		return inner
+	}
+	return &failingTransport{inner: inner, failAt: c.FailRegistryRequest}
+}
+
+type failingTransport struct {
+	inner  http.RoundTripper
+	failAt int64
+	count  int64
+}
+
+func (t *failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if n := atomic.AddInt64(&t.count, 1); n == t.failAt {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, fmt.Errorf("chaos: injected failure of registry request %d (%s %s): connection reset by peer", n, req.Method, req.URL.Redacted())
+	}
+	return t.inner.RoundTrip(req)
+}
+
+// LayerDigest returns the provided digest of the layer with the provided ID, corrupted if the layer matches the configured pattern.
+// The corrupted digest has the same algorithm and length as the digest.
+func (c Config) LayerDigest(id, digest string) string {
+	if c.CorruptLayers == "" {
+		return digest
+	}
+	if matched, _ := path.Match(c.CorruptLayers, id); !matched {
+		return digest
+	}
+	algorithm, hex, found := strings.Cut(digest, ":")
+	if !found || hex == "" {
+		return digest
+	}
+	corrupted := strings.Repeat("0", len(hex))
+	if corrupted == hex {
+		corrupted = strings.Repeat("f", len(hex))
+	}
+	return algorithm + ":" + corrupted
+}
+
+// DelayBuildpack waits for the configured delay of the buildpack with the provided ID, if any,
+// returning early with the error of the context if it is done.
+func (c Config) DelayBuildpack(ctx context.Context, id string) error {
+	delay, ok := c.BuildpackDelays[id]
+	if !ok {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var current Config
+
+// Configure reads the failures to inject from the provided environment lookup function, returning a description of each.
+// It does nothing unless the lifecycle is built with the chaos build tag.
+func Configure(getenv func(string) string) ([]string, error) {
+	if !Enabled {
+		return nil, nil
+	}
+	config, err := ConfigFromEnv(getenv)
+	if err != nil {
+		return nil, err
+	}
+	current = config
+	return config.Active(), nil
+}
+
+// Transport wraps the provided registry transport to inject the configured failures (see Config.Transport).
+func Transport(inner http.RoundTripper) http.RoundTripper {
+	if !Enabled {
+		return inner
+	}
+	return current.Transport(inner)
+}
+
+// LayerDigest returns the provided layer digest, corrupted if configured (see Config.LayerDigest).
+func LayerDigest(id, digest string) string {
+	if !Enabled {
+		return digest
+	}
+	return current.LayerDigest(id, digest)
+}
+
+// DelayBuildpack delays the build of the buildpack with the provided ID, if configured (see Config.DelayBuildpack).
+func DelayBuildpack(ctx context.Context, id string) error {
+	if !Enabled {
+		return nil
+	}
+	return current.DelayBuildpack(ctx, id)
+}
@@ -0,0 +1,130 @@
+package chaos_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/chaos"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestChaos(t *testing.T) {
+	spec.Run(t, "Chaos", testChaos, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testChaos(t *testing.T, when spec.G, it spec.S) {
+	var env map[string]string
+
+	getenv := func(key string) string {
+		return env[key]
+	}
+
+	it.Before(func() {
+		env = map[string]string{}
+	})
+
+	when("#ConfigFromEnv", func() {
+		it("injects no failures when nothing is provided", func() {
+			config, err := chaos.ConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(config.Active()), 0)
+		})
+
+		it("reads the provided failures", func() {
+			env[chaos.EnvFailRegistryRequest] = "3"
+			env[chaos.EnvCorruptLayerDigest] = "some/buildpack:*"
+			env[chaos.EnvDelayBuildpack] = "some/buildpack=30s, other/buildpack=1m"
+
+			config, err := chaos.ConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+			h.AssertEq(t, config, chaos.Config{
+				FailRegistryRequest: 3,
+				CorruptLayers:       "some/buildpack:*",
+				BuildpackDelays: map[string]time.Duration{
+					"some/buildpack":  30 * time.Second,
+					"other/buildpack": time.Minute,
+				},
+			})
+			h.AssertEq(t, config.Active(), []string{
+				"registry request 3 fails",
+				"digests of layers 'some/buildpack:*' are corrupted",
+				"build of buildpack 'other/buildpack' is delayed by 1m0s",
+				"build of buildpack 'some/buildpack' is delayed by 30s",
+			})
+		})
+
+		it("errors for invalid failures", func() {
+			env[chaos.EnvFailRegistryRequest] = "0"
+			_, err := chaos.ConfigFromEnv(getenv)
+			h.AssertError(t, err, "invalid CNB_CHAOS_FAIL_REGISTRY_REQUEST '0': must be a positive integer")
+
+			env[chaos.EnvFailRegistryRequest] = ""
+			env[chaos.EnvDelayBuildpack] = "some/buildpack"
+			_, err = chaos.ConfigFromEnv(getenv)
+			h.AssertError(t, err, "invalid CNB_CHAOS_DELAY_BUILDPACK entry 'some/buildpack': must be <buildpack ID>=<duration>")
+		})
+	})
+
+	when("#Transport", func() {
+		it("fails the configured registry request", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+			defer server.Close()
+			client := &http.Client{Transport: chaos.Config{FailRegistryRequest: 2}.Transport(http.DefaultTransport)}
+
+			for i := 1; i <= 3; i++ {
+				resp, err := client.Get(server.URL + "/v2/")
+				if i == 2 {
+					h.AssertError(t, err, "chaos: injected failure of registry request 2")
+					continue
+				}
+				h.AssertNil(t, err)
+				h.AssertNil(t, resp.Body.Close())
+			}
+		})
+	})
+
+	when("#LayerDigest", func() {
+		it("corrupts the digests of the matching layers", func() {
+			config := chaos.Config{CorruptLayers: "some/buildpack:*"}
+			digest := "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+			corrupted := config.LayerDigest("some/buildpack:some-layer", digest)
+			h.AssertEq(t, corrupted, "sha256:0000000000000000000000000000000000000000000000000000000000000000")
+			h.AssertEq(t, config.LayerDigest("other/buildpack:some-layer", digest), digest)
+			h.AssertEq(t, config.LayerDigest("some/buildpack:some-layer", corrupted), "sha256:ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		})
+	})
+
+	when("#DelayBuildpack", func() {
+		it("delays the configured buildpacks until the context is done", func() {
+			config := chaos.Config{BuildpackDelays: map[string]time.Duration{"some/buildpack": time.Hour}}
+			h.AssertNil(t, config.DelayBuildpack(context.Background(), "other/buildpack"))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			h.AssertError(t, config.DelayBuildpack(ctx, "some/buildpack"), "context deadline exceeded")
+		})
+	})
+
+	when("not built with the chaos build tag", func() {
+		it.Before(func() {
+			if chaos.Enabled {
+				t.Skip("failure injection is enabled")
+			}
+		})
+
+		it("injects no failures", func() {
+			env[chaos.EnvCorruptLayerDigest] = "*"
+			failures, err := chaos.Configure(getenv)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(failures), 0)
+			h.AssertEq(t, chaos.LayerDigest("some-layer", "sha256:some-digest"), "sha256:some-digest")
+		})
+	})
+}
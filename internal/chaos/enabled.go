@@ -0,0 +1,6 @@
+//go:build chaos
+
+package chaos
+
+// Enabled is true when the lifecycle is built with the chaos build tag, allowing failures to be injected.
+const Enabled = true
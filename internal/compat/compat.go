@@ -0,0 +1,285 @@
+// Package compat compares the ABI of the run image of an application image before and after a rebase
+// (the distribution in /etc/os-release, the C library and the declared mixins),
+// as changes that are invisible in the image metadata, such as a glibc upgrade, can break running applications.
+package compat
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	CheckOSRelease = "os-release"
+	CheckLibc      = "libc"
+	CheckMixins    = "mixins"
+
+	LibcGlibc = "glibc"
+	LibcMusl  = "musl"
+
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+var (
+	osReleasePaths     = []string{"etc/os-release", "usr/lib/os-release"}
+	glibcFileVersion   = regexp.MustCompile(`^libc-(\d+\.\d+)\.so$`)
+	glibcBinaryVersion = regexp.MustCompile(`GNU C Library [^\n]* release version (\d+\.\d+)`)
+	muslLoader         = regexp.MustCompile(`^ld-musl-.+\.so\.1$`)
+)
+
+// Base describes the ABI of a run image. Fields that could not be determined are empty.
+type Base struct {
+	Image       string   `toml:"image,omitempty"`
+	OSID        string   `toml:"os-id,omitempty"`
+	OSVersionID string   `toml:"os-version-id,omitempty"`
+	Libc        Libc     `toml:"libc,omitempty"`
+	Mixins      []string `toml:"mixins,omitempty"`
+}
+
+// Libc is the C library of a run image, e.g., glibc 2.35.
+type Libc struct {
+	Name    string `toml:"name,omitempty"`
+	Version string `toml:"version,omitempty"`
+}
+
+func (l Libc) String() string {
+	return strings.TrimSpace(l.Name + " " + l.Version)
+}
+
+// Issue is an incompatibility between the previous and the new run image.
+type Issue struct {
+	Check   string `toml:"check"`
+	Message string `toml:"message"`
+}
+
+// Report is the result of comparing the previous and the new run image of an application image.
+type Report struct {
+	Compatible bool    `toml:"compatible"`
+	Forced     bool    `toml:"forced,omitempty"`
+	Previous   Base    `toml:"previous"`
+	New        Base    `toml:"new"`
+	Issues     []Issue `toml:"issues,omitempty"`
+}
+
+// Summary returns the messages of the issues in the report.
+func (r Report) Summary() string {
+	var messages []string
+	for _, issue := range r.Issues {
+		messages = append(messages, issue.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Compare compares the previous and the new run image. Values that are unknown for either image are not compared.
+func Compare(previous, next Base) Report {
+	report := Report{Previous: previous, New: next}
+	addIssue := func(check, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, Issue{Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case previous.OSID != "" && next.OSID != "" && previous.OSID != next.OSID:
+		addIssue(CheckOSRelease, "distribution changed from '%s' to '%s'", previous.OSID, next.OSID)
+	case previous.OSVersionID != "" && next.OSVersionID != "" && previous.OSVersionID != next.OSVersionID:
+		addIssue(CheckOSRelease, "distribution version changed from '%s' to '%s'", previous.OSVersionID, next.OSVersionID)
+	}
+
+	switch {
+	case previous.Libc.Name != "" && next.Libc.Name != "" && previous.Libc.Name != next.Libc.Name:
+		addIssue(CheckLibc, "C library changed from '%s' to '%s'", previous.Libc, next.Libc)
+	case previous.Libc.Version != "" && next.Libc.Version != "" && previous.Libc.Version != next.Libc.Version:
+		change := "upgraded"
+		if compareVersions(next.Libc.Version, previous.Libc.Version) < 0 {
+			change = "downgraded"
+		}
+		addIssue(CheckLibc, "%s %s from %s to %s", next.Libc.Name, change, previous.Libc.Version, next.Libc.Version)
+	}
+
+	if missing := missingMixins(previous.Mixins, next.Mixins); len(missing) > 0 {
+		addIssue(CheckMixins, "missing mixin(s): %s", strings.Join(missing, ", "))
+	}
+
+	report.Compatible = len(report.Issues) == 0
+	return report
+}
+
+func missingMixins(previous, next []string) []string {
+	provided := map[string]bool{}
+	for _, mixin := range next {
+		provided[mixin] = true
+	}
+	var missing []string
+	for _, mixin := range previous {
+		if !provided[mixin] {
+			missing = append(missing, mixin)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// compareVersions compares dot-separated numeric versions, e.g., 2.35 and 2.4.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Inspect reads the os-release file and the C library of the filesystem made of the provided layers (bottom first).
+// Layers are read from the top, and reading stops once both are found.
+func Inspect(layers []v1.Layer) (Base, error) {
+	s := &scanner{hidden: map[string]bool{}}
+	for i := len(layers) - 1; i >= 0 && !s.done(); i-- {
+		rc, err := layers[i].Uncompressed()
+		if err != nil {
+			return Base{}, err
+		}
+		err = s.scanLayer(rc)
+		_ = rc.Close()
+		if err != nil {
+			return Base{}, err
+		}
+	}
+	return s.base, nil
+}
+
+type scanner struct {
+	base        Base
+	foundOS     bool
+	foundLibc   bool
+	hidden      map[string]bool // hidden are the paths removed by whiteouts in upper layers
+	opaque      []string        // opaque are the directories whose lower contents are removed by upper layers
+	layerHidden []string
+	layerOpaque []string
+}
+
+func (s *scanner) done() bool {
+	return s.foundOS && s.foundLibc
+}
+
+func (s *scanner) scanLayer(r io.Reader) error {
+	s.layerHidden, s.layerOpaque = nil, nil
+	tr := tar.NewReader(r)
+	for !s.done() {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		dir, base := path.Split(name)
+		dir = path.Clean(dir)
+		switch {
+		case base == opaqueWhiteout:
+			s.layerOpaque = append(s.layerOpaque, dir)
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			s.layerHidden = append(s.layerHidden, path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+			continue
+		}
+		if header.Typeflag != tar.TypeReg || s.isHidden(name) {
+			continue
+		}
+		if err = s.inspectFile(name, base, tr); err != nil {
+			return err
+		}
+	}
+	// whiteouts only apply to lower layers
+	for _, p := range s.layerHidden {
+		s.hidden[p] = true
+	}
+	s.opaque = append(s.opaque, s.layerOpaque...)
+	return nil
+}
+
+func (s *scanner) isHidden(name string) bool {
+	for p := name; p != "." && p != "/"; p = path.Dir(p) {
+		if s.hidden[p] {
+			return true
+		}
+	}
+	for _, dir := range s.opaque {
+		if strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *scanner) inspectFile(name, base string, r io.Reader) error {
+	if !s.foundOS && (name == osReleasePaths[0] || name == osReleasePaths[1]) {
+		contents, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		s.base.OSID, s.base.OSVersionID = parseOSRelease(contents)
+		s.foundOS = true
+		return nil
+	}
+	if s.foundLibc {
+		return nil
+	}
+	switch {
+	case glibcFileVersion.MatchString(base):
+		s.base.Libc = Libc{Name: LibcGlibc, Version: glibcFileVersion.FindStringSubmatch(base)[1]}
+		s.foundLibc = true
+	case base == "libc.so.6":
+		contents, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if match := glibcBinaryVersion.FindSubmatch(contents); match != nil {
+			s.base.Libc = Libc{Name: LibcGlibc, Version: string(match[1])}
+			s.foundLibc = true
+		}
+	case muslLoader.MatchString(base):
+		s.base.Libc = Libc{Name: LibcMusl}
+		s.foundLibc = true
+	}
+	return nil
+}
+
+// parseOSRelease returns the ID and VERSION_ID in the contents of an os-release file.
+func parseOSRelease(contents []byte) (id, versionID string) {
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			id = value
+		case "VERSION_ID":
+			versionID = value
+		}
+	}
+	return id, versionID
+}
@@ -0,0 +1,141 @@
+package compat_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/compat"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestCompat(t *testing.T) {
+	spec.Run(t, "Compat", testCompat, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testCompat(t *testing.T, when spec.G, it spec.S) {
+	when("#Inspect", func() {
+		it("reads the distribution and the glibc version", func() {
+			base, err := compat.Inspect([]v1.Layer{
+				layer(t, map[string]string{
+					"etc/os-release":                    "NAME=\"Ubuntu\"\nID=ubuntu\nVERSION_ID=\"22.04\"\n",
+					"lib/x86_64-linux-gnu/libc.so.6":    "\x7fELF...GNU C Library (Ubuntu GLIBC 2.35-0ubuntu3.1) stable release version 2.35.\x00...",
+					"usr/share/doc/libc6/copyright":     "some-copyright",
+					"lib/x86_64-linux-gnu/libm.so.6":    "\x7fELF...",
+					"lib/x86_64-linux-gnu/libc-only.so": "\x7fELF...",
+				}),
+			})
+			h.AssertNil(t, err)
+			h.AssertEq(t, base, compat.Base{OSID: "ubuntu", OSVersionID: "22.04", Libc: compat.Libc{Name: "glibc", Version: "2.35"}})
+		})
+
+		it("reads the glibc version from the name of the library", func() {
+			base, err := compat.Inspect([]v1.Layer{layer(t, map[string]string{"lib/x86_64-linux-gnu/libc-2.31.so": "\x7fELF..."})})
+			h.AssertNil(t, err)
+			h.AssertEq(t, base.Libc, compat.Libc{Name: "glibc", Version: "2.31"})
+		})
+
+		it("detects musl", func() {
+			base, err := compat.Inspect([]v1.Layer{layer(t, map[string]string{
+				"etc/os-release":          "ID=alpine\nVERSION_ID=3.18.4\n",
+				"lib/ld-musl-x86_64.so.1": "\x7fELF...",
+			})})
+			h.AssertNil(t, err)
+			h.AssertEq(t, base, compat.Base{OSID: "alpine", OSVersionID: "3.18.4", Libc: compat.Libc{Name: "musl"}})
+		})
+
+		it("prefers the files in upper layers and honors whiteouts", func() {
+			base, err := compat.Inspect([]v1.Layer{
+				layer(t, map[string]string{
+					"etc/os-release":                 "ID=ubuntu\nVERSION_ID=20.04\n",
+					"lib/x86_64-linux-gnu/libc.so.6": "GNU C Library (Ubuntu GLIBC 2.31-0ubuntu9) stable release version 2.31.",
+				}),
+				layer(t, map[string]string{
+					"usr/lib/os-release":                 "ID=ubuntu\nVERSION_ID=22.04\n",
+					"etc/.wh.os-release":                 "",
+					"lib/x86_64-linux-gnu/.wh.libc.so.6": "",
+				}),
+			})
+			h.AssertNil(t, err)
+			h.AssertEq(t, base, compat.Base{OSID: "ubuntu", OSVersionID: "22.04"})
+		})
+	})
+
+	when("#Compare", func() {
+		var previous compat.Base
+
+		it.Before(func() {
+			previous = compat.Base{
+				OSID:        "ubuntu",
+				OSVersionID: "22.04",
+				Libc:        compat.Libc{Name: "glibc", Version: "2.35"},
+				Mixins:      []string{"some-mixin", "other-mixin"},
+			}
+		})
+
+		it("is compatible if nothing changed", func() {
+			report := compat.Compare(previous, previous)
+			h.AssertEq(t, report.Compatible, true)
+			h.AssertEq(t, len(report.Issues), 0)
+		})
+
+		it("ignores unknown values", func() {
+			report := compat.Compare(previous, compat.Base{Mixins: previous.Mixins})
+			h.AssertEq(t, report.Compatible, true)
+		})
+
+		it("reports a change of distribution", func() {
+			next := previous
+			next.OSVersionID = "24.04"
+			report := compat.Compare(previous, next)
+			h.AssertEq(t, report.Compatible, false)
+			h.AssertEq(t, report.Issues, []compat.Issue{{Check: "os-release", Message: "distribution version changed from '22.04' to '24.04'"}})
+
+			next.OSID = "debian"
+			h.AssertEq(t, compat.Compare(previous, next).Summary(), "distribution changed from 'ubuntu' to 'debian'")
+		})
+
+		it("reports a change of libc", func() {
+			next := previous
+			next.Libc.Version = "2.39"
+			h.AssertEq(t, compat.Compare(previous, next).Summary(), "glibc upgraded from 2.35 to 2.39")
+
+			next.Libc.Version = "2.4"
+			h.AssertEq(t, compat.Compare(previous, next).Summary(), "glibc downgraded from 2.35 to 2.4")
+
+			next.Libc = compat.Libc{Name: "musl"}
+			h.AssertEq(t, compat.Compare(previous, next).Summary(), "C library changed from 'glibc 2.35' to 'musl'")
+		})
+
+		it("reports missing mixins", func() {
+			next := previous
+			next.Mixins = []string{"some-mixin"}
+			report := compat.Compare(previous, next)
+			h.AssertEq(t, report.Compatible, false)
+			h.AssertEq(t, report.Summary(), "missing mixin(s): other-mixin")
+		})
+	})
+}
+
+func layer(t *testing.T, files map[string]string) v1.Layer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for name, contents := range files {
+		h.AssertNil(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(contents))}))
+		_, err := tw.Write([]byte(contents))
+		h.AssertNil(t, err)
+	}
+	h.AssertNil(t, tw.Close())
+	l, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	h.AssertNil(t, err)
+	return l
+}
@@ -0,0 +1,108 @@
+// Package deprecation collects notices about deprecated APIs and file formats encountered during a build,
+// so that they can be reported to the platform in report.toml in addition to being logged.
+package deprecation
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/buildpacks/lifecycle/internal/encoding"
+)
+
+const (
+	// KindPlatformAPI is a deprecated (or unset) Platform API.
+	KindPlatformAPI = "platform-api"
+	// KindBuildpackAPI is a deprecated Buildpack API requested by a buildpack or image extension.
+	KindBuildpackAPI = "buildpack-api"
+	// KindLegacyFormat is a deprecated key or file format written by a buildpack or image extension.
+	KindLegacyFormat = "legacy-format"
+)
+
+// Notice describes a deprecated API or file format that was encountered.
+type Notice struct {
+	// Kind is the kind of deprecation, e.g., "platform-api".
+	Kind string `toml:"kind" json:"kind"`
+	// Subject identifies what is deprecated, e.g., the ID of the buildpack requesting a deprecated Buildpack API.
+	Subject string `toml:"subject,omitempty" json:"subject,omitempty"`
+	// API is the API version in use when the deprecation was encountered.
+	API string `toml:"api,omitempty" json:"api,omitempty"`
+	// Message describes the deprecation and how to address it.
+	Message string `toml:"message" json:"message"`
+}
+
+var notices struct {
+	sync.Mutex
+	list []Notice
+}
+
+// Record records the provided notice, ignoring duplicates.
+func Record(notice Notice) {
+	notices.Lock()
+	defer notices.Unlock()
+	record(notice)
+}
+
+func record(notice Notice) {
+	for _, n := range notices.list {
+		if n == notice {
+			return
+		}
+	}
+	notices.list = append(notices.list, notice)
+}
+
+// Notices returns the recorded notices, in the order they were first recorded.
+func Notices() []Notice {
+	notices.Lock()
+	defer notices.Unlock()
+	if len(notices.list) == 0 {
+		return nil
+	}
+	return append([]Notice{}, notices.list...)
+}
+
+// Reset removes all recorded notices.
+func Reset() {
+	notices.Lock()
+	defer notices.Unlock()
+	notices.list = nil
+}
+
+type noticesFile struct {
+	Notices []Notice `toml:"deprecations"`
+}
+
+// Load records the notices in the file at path (written by Save in a previous phase), if it exists.
+func Load(path string) error {
+	var contents noticesFile
+	if _, err := toml.DecodeFile(path, &contents); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	notices.Lock()
+	defer notices.Unlock()
+	previous := notices.list
+	notices.list = nil
+	for _, n := range append(contents.Notices, previous...) {
+		record(n)
+	}
+	return nil
+}
+
+// Save writes the recorded notices to the file at path, so that they can be loaded by later phases.
+// Nothing is written if there are no notices or if the parent directory does not exist.
+func Save(path string) error {
+	list := Notices()
+	if len(list) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Dir(path)); err != nil {
+		return nil
+	}
+	return encoding.WriteTOML(path, noticesFile{Notices: list})
+}
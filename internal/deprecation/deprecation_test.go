@@ -0,0 +1,85 @@
+package deprecation_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/deprecation"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestDeprecation(t *testing.T) {
+	spec.Run(t, "Deprecation", testDeprecation, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testDeprecation(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir string
+		notice = deprecation.Notice{
+			Kind:    deprecation.KindBuildpackAPI,
+			Subject: "some-buildpack",
+			API:     "0.6",
+			Message: "some-message",
+		}
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle.test")
+		h.AssertNil(t, err)
+		deprecation.Reset()
+	})
+
+	it.After(func() {
+		deprecation.Reset()
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	when("#Record", func() {
+		it("ignores duplicates", func() {
+			deprecation.Record(notice)
+			deprecation.Record(notice)
+
+			h.AssertEq(t, deprecation.Notices(), []deprecation.Notice{notice})
+		})
+	})
+
+	when("#Save", func() {
+		it("writes the notices to be loaded by a later phase", func() {
+			path := filepath.Join(tmpDir, "deprecations.toml")
+			deprecation.Record(notice)
+			h.AssertNil(t, deprecation.Save(path))
+
+			deprecation.Reset()
+			other := deprecation.Notice{Kind: deprecation.KindPlatformAPI, API: "0.7", Message: "other-message"}
+			deprecation.Record(other)
+			h.AssertNil(t, deprecation.Load(path))
+
+			h.AssertEq(t, deprecation.Notices(), []deprecation.Notice{notice, other})
+		})
+
+		it("doesn't write a file when there are no notices", func() {
+			path := filepath.Join(tmpDir, "deprecations.toml")
+			h.AssertNil(t, deprecation.Save(path))
+			h.AssertPathDoesNotExist(t, path)
+		})
+
+		it("doesn't write a file when the parent directory doesn't exist", func() {
+			path := filepath.Join(tmpDir, "some-missing-dir", "deprecations.toml")
+			deprecation.Record(notice)
+			h.AssertNil(t, deprecation.Save(path))
+			h.AssertPathDoesNotExist(t, path)
+		})
+	})
+
+	when("#Load", func() {
+		it("ignores a missing file", func() {
+			h.AssertNil(t, deprecation.Load(filepath.Join(tmpDir, "deprecations.toml")))
+			h.AssertEq(t, len(deprecation.Notices()), 0)
+		})
+	})
+}
@@ -0,0 +1,352 @@
+// Package buildkit provides a Dockerfile applier that delegates to a BuildKit daemon through the `buildctl` CLI.
+// Unlike kaniko, BuildKit does not snapshot the filesystem of the running container,
+// and supports Dockerfile features such as cache mounts (`RUN --mount=type=cache`) and secrets (`RUN --mount=type=secret`).
+package buildkit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+
+	"github.com/buildpacks/lifecycle/internal/extend"
+	"github.com/buildpacks/lifecycle/log"
+)
+
+const (
+	baseContextName = "base"
+	buildKind       = "build"
+)
+
+type DockerfileApplier struct {
+	buildctl string
+	cacheDir string // BuildKit cache is exported to and imported from a directory for each kind of image within this directory
+	baseDir  string // sparse base images are saved here by the restorer
+	rootDir  string // new layers of the extended build image are unpacked here
+	secrets  map[string]string
+	workDir  string
+	keychain authn.Keychain
+	rootless bool // if true, unpacked files are owned by the current user as ownership cannot be set without root privileges
+
+	// The extender may modify the config of the images it is given (e.g., to normalize history),
+	// so images are matched by the diffIDs of their layers rather than by their digests.
+	baseRefs map[string]string    // maps layer diffIDs to the registry references they were read from
+	layouts  map[string]ociLayout // maps layer diffIDs to the OCI layouts they were written to
+	count    int
+}
+
+type ociLayout struct {
+	dir    string
+	digest v1.Hash
+}
+
+// NewDockerfileApplier returns a BuildKit applier that uses the provided directory (typically the kaniko directory)
+// to find base images and persist its cache.
+// Each file in secretsDir (if provided) is exposed to Dockerfiles as a secret with the file name as its ID.
+// Credentials for base images are resolved from the provided keychain and passed to `buildctl` in a temporary Docker config.
+// In rootless mode, `buildctl` is expected to connect to a rootless BuildKit daemon, and files unpacked from the extended build image
+// are owned by the current user.
+func NewDockerfileApplier(dir, secretsDir string, keychain authn.Keychain, rootless bool) (*DockerfileApplier, error) {
+	buildctl, err := exec.LookPath("buildctl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find buildctl: %w", err)
+	}
+	applier, err := newDockerfileApplier(buildctl, dir, "/", secretsDir, keychain)
+	if err != nil {
+		return nil, err
+	}
+	applier.rootless = rootless
+	return applier, nil
+}
+
+func newDockerfileApplier(buildctl, dir, rootDir, secretsDir string, keychain authn.Keychain) (*DockerfileApplier, error) {
+	secrets, err := readSecrets(secretsDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Join(dir, "cache", "buildkit"), 0755); err != nil {
+		return nil, err
+	}
+	workDir, err := os.MkdirTemp(dir, "work.dir")
+	if err != nil {
+		return nil, err
+	}
+	if keychain == nil {
+		keychain = authn.DefaultKeychain
+	}
+	return &DockerfileApplier{
+		buildctl: buildctl,
+		cacheDir: filepath.Join(dir, "cache", "buildkit"),
+		baseDir:  filepath.Join(dir, "cache", "base"),
+		rootDir:  rootDir,
+		secrets:  secrets,
+		workDir:  workDir,
+		keychain: keychain,
+		baseRefs: map[string]string{},
+		layouts:  map[string]ociLayout{},
+	}, nil
+}
+
+func readSecrets(dir string) (map[string]string, error) {
+	secrets := map[string]string{}
+	if dir == "" {
+		return secrets, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		// skip hidden entries, e.g., the `..data` symlink in a mounted Kubernetes secret
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		fi, err := os.Stat(path) // follows symlinks
+		if err != nil {
+			return nil, err
+		}
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		secrets[entry.Name()] = path
+	}
+	return secrets, nil
+}
+
+func (a *DockerfileApplier) ImageFor(reference string) (v1.Image, error) {
+	image, err := extend.ReadBaseImage(a.baseDir, reference)
+	if err != nil {
+		return nil, err
+	}
+	key, err := layersKey(image)
+	if err != nil {
+		return nil, err
+	}
+	a.baseRefs[key] = reference
+	return image, nil
+}
+
+func (a *DockerfileApplier) Apply(dockerfile extend.Dockerfile, toBaseImage v1.Image, withBuildOptions extend.Options, logger log.Logger) (v1.Image, error) {
+	key, err := layersKey(toBaseImage)
+	if err != nil {
+		return nil, err
+	}
+	base, err := a.baseFor(key)
+	if err != nil {
+		return nil, err
+	}
+	a.count++
+	outputDir := filepath.Join(a.workDir, strconv.Itoa(a.count))
+	if cacheDir := a.cacheDirFor(withBuildOptions.Kind); cacheDir != "" {
+		if err = os.MkdirAll(cacheDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Debugf("Applying Dockerfile at %s to '%s' with BuildKit...", dockerfile.Path, base.context)
+	cmd := exec.Command(a.buildctl, a.buildArgs(dockerfile, base, outputDir, withBuildOptions)...) // #nosec G204
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if base.ref != "" {
+		configDir, err := a.writeDockerConfig(base.ref)
+		if err != nil {
+			return nil, fmt.Errorf("writing registry credentials: %w", err)
+		}
+		cmd.Env = append(os.Environ(), "DOCKER_CONFIG="+configDir)
+	}
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running buildctl: %w", err)
+	}
+
+	extendedImage, err := imageFrom(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading extended image: %w", err)
+	}
+	extendedImage, err = mutate.CreatedAt(extendedImage, v1.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if err = layout.Path(outputDir).AppendImage(extendedImage); err != nil {
+		return nil, fmt.Errorf("saving extended image: %w", err)
+	}
+	extendedDigest, err := extendedImage.Digest()
+	if err != nil {
+		return nil, err
+	}
+	extendedKey, err := layersKey(extendedImage)
+	if err != nil {
+		return nil, err
+	}
+	a.layouts[extendedKey] = ociLayout{dir: outputDir, digest: extendedDigest}
+
+	if withBuildOptions.Kind == buildKind {
+		// BuildKit does not modify the filesystem of the running container,
+		// so new layers must be unpacked for the build phase to use them
+		if err = unpackNewLayers(a.rootDir, toBaseImage, extendedImage, withBuildOptions.IgnorePaths, a.rootless); err != nil {
+			return nil, fmt.Errorf("unpacking extended image layers: %w", err)
+		}
+	}
+	return extendedImage, nil
+}
+
+// layersKey identifies an image by its layers, which (unlike its digest) are preserved when its config is modified.
+func layersKey(image v1.Image) (string, error) {
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return "", fmt.Errorf("getting config file: %w", err)
+	}
+	var diffIDs []string
+	for _, diffID := range configFile.RootFS.DiffIDs {
+		diffIDs = append(diffIDs, diffID.String())
+	}
+	return strings.Join(diffIDs, ","), nil
+}
+
+type baseImage struct {
+	context   string // value for the named build context
+	layoutDir string // if the base image is on disk, the OCI layout directory it is in
+	ref       string // if the base image is in a registry, the reference it is pulled from
+}
+
+func (a *DockerfileApplier) baseFor(key string) (baseImage, error) {
+	// layouts are checked first, as a Dockerfile that only modifies config results in an image with the same layers as its base
+	if found, ok := a.layouts[key]; ok {
+		return baseImage{
+			context:   fmt.Sprintf("oci-layout://%s@%s", baseContextName, found.digest),
+			layoutDir: found.dir,
+		}, nil
+	}
+	if ref, ok := a.baseRefs[key]; ok {
+		digestRef, err := name.NewDigest(ref)
+		if err != nil {
+			return baseImage{}, err
+		}
+		return baseImage{context: "docker-image://" + digestRef.String(), ref: ref}, nil
+	}
+	return baseImage{}, fmt.Errorf("failed to find base image with layers '%s'", key)
+}
+
+type dockerConfig struct {
+	Auths map[string]dockerAuth `json:"auths"`
+}
+
+type dockerAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+	RegistryToken string `json:"registrytoken,omitempty"`
+}
+
+// writeDockerConfig writes a Docker config containing credentials for the registry of the provided reference (if any),
+// so that BuildKit can pull the base image with the same credentials as the rest of the lifecycle.
+func (a *DockerfileApplier) writeDockerConfig(reference string) (string, error) {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return "", err
+	}
+	authenticator, err := a.keychain.Resolve(ref.Context())
+	if err != nil {
+		return "", err
+	}
+	authConfig, err := authenticator.Authorization()
+	if err != nil {
+		return "", err
+	}
+	auths := map[string]dockerAuth{}
+	if *authConfig != (authn.AuthConfig{}) {
+		auth := authConfig.Auth
+		if authConfig.Username != "" || authConfig.Password != "" {
+			auth = base64.StdEncoding.EncodeToString([]byte(authConfig.Username + ":" + authConfig.Password))
+		}
+		auths[ref.Context().RegistryStr()] = dockerAuth{
+			Auth:          auth,
+			IdentityToken: authConfig.IdentityToken,
+			RegistryToken: authConfig.RegistryToken,
+		}
+	}
+	contents, err := json.Marshal(dockerConfig{Auths: auths})
+	if err != nil {
+		return "", err
+	}
+	configDir := filepath.Join(a.workDir, "docker")
+	if err = os.MkdirAll(configDir, 0700); err != nil {
+		return "", err
+	}
+	return configDir, os.WriteFile(filepath.Join(configDir, "config.json"), contents, 0600)
+}
+
+func (a *DockerfileApplier) buildArgs(dockerfile extend.Dockerfile, base baseImage, outputDir string, options extend.Options) []string {
+	args := []string{
+		"build",
+		"--frontend", "dockerfile.v0",
+		"--local", "context=" + options.BuildContext,
+		"--local", "dockerfile=" + filepath.Dir(dockerfile.Path),
+		"--opt", "filename=" + filepath.Base(dockerfile.Path),
+		"--opt", fmt.Sprintf("build-arg:base_image=%s", baseContextName),
+		"--opt", fmt.Sprintf("context:%s=%s", baseContextName, base.context),
+	}
+	if base.layoutDir != "" {
+		args = append(args, "--oci-layout", fmt.Sprintf("%s=%s", baseContextName, base.layoutDir))
+	}
+	for _, arg := range dockerfile.Args {
+		args = append(args, "--opt", fmt.Sprintf("build-arg:%s=%s", arg.Name, arg.Value))
+	}
+	var secretIDs []string
+	for id := range a.secrets {
+		secretIDs = append(secretIDs, id)
+	}
+	sort.Strings(secretIDs)
+	for _, id := range secretIDs {
+		args = append(args, "--secret", fmt.Sprintf("id=%s,src=%s", id, a.secrets[id]))
+	}
+	if cacheDir := a.cacheDirFor(options.Kind); cacheDir != "" {
+		args = append(args,
+			"--import-cache", "type=local,src="+cacheDir,
+			"--export-cache", "type=local,mode=max,dest="+cacheDir,
+		)
+	}
+	return append(args, "--output", "type=oci,tar=false,dest="+outputDir)
+}
+
+func imageFrom(layoutDir string) (v1.Image, error) {
+	layoutPath, err := layout.FromPath(layoutDir)
+	if err != nil {
+		return nil, err
+	}
+	index, err := layoutPath.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	if len(manifest.Manifests) != 1 {
+		return nil, fmt.Errorf("expected 1 image in '%s', found %d", layoutDir, len(manifest.Manifests))
+	}
+	return index.Image(manifest.Manifests[0].Digest)
+}
+
+// cacheDirFor returns the directory of the BuildKit cache of the provided kind of image,
+// so that the build and run images can be extended concurrently without exporting their caches to the same directory.
+func (a *DockerfileApplier) cacheDirFor(kind string) string {
+	if a.cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(a.cacheDir, kind)
+}
+
+func (a *DockerfileApplier) Cleanup() error {
+	return os.RemoveAll(a.workDir)
+}
@@ -0,0 +1,388 @@
+package buildkit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/buildpacks/imgutil"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/extend"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestDockerfileApplier(t *testing.T) {
+	spec.Run(t, "DockerfileApplier", testDockerfileApplier, spec.Report(report.Terminal{}))
+}
+
+func testDockerfileApplier(t *testing.T, when spec.G, it spec.S) {
+	var (
+		applier    *DockerfileApplier
+		dockerfile = extend.Dockerfile{
+			Path: "/some/generated/build/some-ext/Dockerfile",
+			Args: []extend.Arg{{Name: "arg1", Value: "val1"}},
+		}
+		options = extend.Options{BuildContext: "/some/app", Kind: "build"}
+	)
+
+	it.Before(func() {
+		applier = &DockerfileApplier{
+			cacheDir: "/some/cache",
+			secrets:  map[string]string{"b-secret": "/secrets/b-secret", "a-secret": "/secrets/a-secret"},
+		}
+	})
+
+	when("#buildArgs", func() {
+		it("provides the Dockerfile, build context, and args", func() {
+			args := applier.buildArgs(dockerfile, baseImage{context: "docker-image://some-registry/some-repo@sha256:abc"}, "/some/output", options)
+
+			h.AssertContains(t, args,
+				"context=/some/app",
+				"dockerfile=/some/generated/build/some-ext",
+				"filename=Dockerfile",
+				"build-arg:arg1=val1",
+				"build-arg:base_image=base",
+				"context:base=docker-image://some-registry/some-repo@sha256:abc",
+			)
+			h.AssertEq(t, args[len(args)-2:], []string{"--output", "type=oci,tar=false,dest=/some/output"})
+		})
+
+		when("the base image is in OCI layout format", func() {
+			it("provides the layout directory", func() {
+				args := applier.buildArgs(dockerfile, baseImage{context: "oci-layout://base@sha256:abc", layoutDir: "/some/layout"}, "/some/output", options)
+
+				h.AssertContains(t, args, "context:base=oci-layout://base@sha256:abc", "base=/some/layout")
+			})
+		})
+
+		it("provides secrets in a stable order", func() {
+			args := applier.buildArgs(dockerfile, baseImage{}, "/some/output", options)
+
+			var secrets []string
+			for i, arg := range args {
+				if arg == "--secret" {
+					secrets = append(secrets, args[i+1])
+				}
+			}
+			h.AssertEq(t, secrets, []string{"id=a-secret,src=/secrets/a-secret", "id=b-secret,src=/secrets/b-secret"})
+		})
+
+		it("imports and exports the cache of the kind of image", func() {
+			args := applier.buildArgs(dockerfile, baseImage{}, "/some/output", options)
+			h.AssertContains(t, args, "type=local,src=/some/cache/build", "type=local,mode=max,dest=/some/cache/build")
+
+			args = applier.buildArgs(dockerfile, baseImage{}, "/some/output", extend.Options{BuildContext: "/some/app", Kind: "run"})
+			h.AssertContains(t, args, "type=local,src=/some/cache/run", "type=local,mode=max,dest=/some/cache/run")
+		})
+	})
+
+	when("#readSecrets", func() {
+		var secretsDir string
+
+		it.Before(func() {
+			secretsDir = t.TempDir()
+			h.Mkfile(t, "some-value", filepath.Join(secretsDir, "some-secret"))
+			h.Mkdir(t, filepath.Join(secretsDir, "some-dir"))
+		})
+
+		it("returns the regular files in the directory", func() {
+			secrets, err := readSecrets(secretsDir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, secrets, map[string]string{"some-secret": filepath.Join(secretsDir, "some-secret")})
+		})
+
+		when("the directory is a mounted Kubernetes secret", func() {
+			it.Before(func() {
+				h.SkipIf(t, runtime.GOOS == "windows", "symlinks are not supported on windows")
+				// Kubernetes mounts each key as a symlink through the `..data` symlink to a timestamped directory
+				h.Mkdir(t, filepath.Join(secretsDir, "..2023_01_01"))
+				h.Mkfile(t, "other-value", filepath.Join(secretsDir, "..2023_01_01", "other-secret"))
+				h.AssertNil(t, os.Symlink("..2023_01_01", filepath.Join(secretsDir, "..data")))
+				h.AssertNil(t, os.Symlink(filepath.Join("..data", "other-secret"), filepath.Join(secretsDir, "other-secret")))
+			})
+
+			it("follows symlinks and skips hidden entries", func() {
+				secrets, err := readSecrets(secretsDir)
+				h.AssertNil(t, err)
+				h.AssertEq(t, secrets, map[string]string{
+					"other-secret": filepath.Join(secretsDir, "other-secret"),
+					"some-secret":  filepath.Join(secretsDir, "some-secret"),
+				})
+			})
+		})
+
+		when("no directory is provided", func() {
+			it("returns no secrets", func() {
+				secrets, err := readSecrets("")
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(secrets), 0)
+			})
+		})
+	})
+
+	when("#imageFrom", func() {
+		var layoutDir string
+
+		it.Before(func() {
+			layoutDir = t.TempDir()
+		})
+
+		it("returns the image in the layout", func() {
+			image := writeLayout(t, layoutDir, randomImage(t))
+
+			found, err := imageFrom(layoutDir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, mustDigest(t, found), mustDigest(t, image))
+		})
+
+		when("the layout has more than one image", func() {
+			it("errors", func() {
+				writeLayout(t, layoutDir, randomImage(t))
+				h.AssertNil(t, layout.Path(layoutDir).AppendImage(randomImage(t)))
+
+				_, err := imageFrom(layoutDir)
+				h.AssertError(t, err, fmt.Sprintf("expected 1 image in '%s', found 2", layoutDir))
+			})
+		})
+	})
+
+	when("#baseFor", func() {
+		it.Before(func() {
+			applier.baseRefs = map[string]string{}
+			applier.layouts = map[string]ociLayout{}
+		})
+
+		when("the image was read from a registry", func() {
+			it("returns a docker image context", func() {
+				applier.baseRefs["some-key"] = "some-registry.io/some-repo@sha256:" + strings.Repeat("a", 64)
+
+				base, err := applier.baseFor("some-key")
+				h.AssertNil(t, err)
+				h.AssertEq(t, base.context, "docker-image://some-registry.io/some-repo@sha256:"+strings.Repeat("a", 64))
+				h.AssertEq(t, base.layoutDir, "")
+			})
+		})
+
+		when("the image was written to an OCI layout", func() {
+			it("prefers the layout", func() {
+				digest, err := v1.NewHash("sha256:" + strings.Repeat("b", 64))
+				h.AssertNil(t, err)
+				applier.baseRefs["some-key"] = "some-registry.io/some-repo@sha256:" + strings.Repeat("a", 64)
+				applier.layouts["some-key"] = ociLayout{dir: "/some/layout", digest: digest}
+
+				base, err := applier.baseFor("some-key")
+				h.AssertNil(t, err)
+				h.AssertEq(t, base.context, "oci-layout://base@"+digest.String())
+				h.AssertEq(t, base.layoutDir, "/some/layout")
+			})
+		})
+
+		when("the image is unknown", func() {
+			it("errors", func() {
+				_, err := applier.baseFor("some-key")
+				h.AssertError(t, err, "failed to find base image with layers 'some-key'")
+			})
+		})
+	})
+
+	when("#ImageFor and #Apply", func() {
+		var (
+			kanikoDir, rootDir, outputsDir, recordDir string
+			baseRef                                   string
+			base                                      v1.Image
+			logger                                    *log.Logger
+		)
+
+		it.Before(func() {
+			h.SkipIf(t, runtime.GOOS == "windows", "the fake buildctl is a shell script")
+
+			kanikoDir = t.TempDir()
+			rootDir = t.TempDir()
+			outputsDir = t.TempDir()
+			recordDir = t.TempDir()
+			logger = &log.Logger{Handler: memory.New()}
+
+			// like most images, the base image has history for instructions that did not create layers
+			base = randomImage(t)
+			configFile, err := base.ConfigFile()
+			h.AssertNil(t, err)
+			configFile.History = append([]v1.History{{CreatedBy: "ENV SOME_VAR=some-val", EmptyLayer: true}}, configFile.History...)
+			base, err = mutate.ConfigFile(base, configFile)
+			h.AssertNil(t, err)
+			baseDigest := mustDigest(t, base)
+			baseRef = "some-registry.io/some-repo@" + baseDigest.String()
+			writeLayout(t, filepath.Join(kanikoDir, "cache", "base", baseDigest.String()), base)
+
+			// the fake buildctl records its arguments and Docker config, then "builds" the next prepared output
+			buildctl := filepath.Join(t.TempDir(), "buildctl")
+			script := fmt.Sprintf(`#!/usr/bin/env bash
+set -e
+count=$(ls %[1]s | grep -c '^args' || true)
+echo "$@" > %[1]s/args-$((count+1))
+if [ -n "$DOCKER_CONFIG" ]; then cp "$DOCKER_CONFIG/config.json" %[1]s/config-$((count+1)).json; fi
+for arg in "$@"; do dest="${arg##*dest=}"; done
+mkdir -p "$dest"
+cp -r %[2]s/$((count+1))/. "$dest"
+`, recordDir, outputsDir)
+			h.Mkfile(t, script, buildctl)
+			h.AssertNil(t, os.Chmod(buildctl, 0755))
+
+			applier, err = newDockerfileApplier(buildctl, kanikoDir, rootDir, "", &fakeKeychain{})
+			h.AssertNil(t, err)
+		})
+
+		it.After(func() {
+			h.AssertNil(t, applier.Cleanup())
+		})
+
+		it("finds the base image when the extender has modified its config", func() {
+			extended := appendFileLayer(t, base, "some-file", "some-content")
+			writeLayout(t, filepath.Join(outputsDir, "1"), extended)
+
+			image, err := applier.ImageFor(baseRef)
+			h.AssertNil(t, err)
+			// the extender normalizes history before applying Dockerfiles, which changes the image digest
+			image, err = imgutil.OverrideHistoryIfNeeded(image)
+			h.AssertNil(t, err)
+			h.AssertEq(t, mustDigest(t, image) != mustDigest(t, base), true)
+
+			result, err := applier.Apply(dockerfile, image, extend.Options{BuildContext: "/some/app", Kind: "run"}, logger)
+			h.AssertNil(t, err)
+
+			args := h.Rdfile(t, filepath.Join(recordDir, "args-1"))
+			h.AssertStringContains(t, args, "context:base=docker-image://"+baseRef)
+			resultLayers, err := result.Layers()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(resultLayers), 2)
+		})
+
+		it("provides registry credentials to buildctl", func() {
+			writeLayout(t, filepath.Join(outputsDir, "1"), appendFileLayer(t, base, "some-file", "some-content"))
+
+			image, err := applier.ImageFor(baseRef)
+			h.AssertNil(t, err)
+			_, err = applier.Apply(dockerfile, image, extend.Options{Kind: "run"}, logger)
+			h.AssertNil(t, err)
+
+			h.AssertJSONEq(t,
+				`{"auths": {"some-registry.io": {"auth": "c29tZS11c2VyOnNvbWUtcGFzc3dvcmQ="}}}`, // some-user:some-password
+				h.Rdfile(t, filepath.Join(recordDir, "config-1.json")),
+			)
+		})
+
+		when("there are multiple Dockerfiles", func() {
+			it("uses the output of each Dockerfile as the base for the next", func() {
+				firstExtended := appendFileLayer(t, base, "some-file", "some-content")
+				writeLayout(t, filepath.Join(outputsDir, "1"), firstExtended)
+				writeLayout(t, filepath.Join(outputsDir, "2"), appendFileLayer(t, firstExtended, "other-file", "other-content"))
+
+				image, err := applier.ImageFor(baseRef)
+				h.AssertNil(t, err)
+				image, err = imgutil.OverrideHistoryIfNeeded(image)
+				h.AssertNil(t, err)
+				first, err := applier.Apply(dockerfile, image, extend.Options{Kind: "run"}, logger)
+				h.AssertNil(t, err)
+				first, err = imgutil.OverrideHistoryIfNeeded(first)
+				h.AssertNil(t, err)
+				second, err := applier.Apply(dockerfile, first, extend.Options{Kind: "run"}, logger)
+				h.AssertNil(t, err)
+
+				firstLayout := applier.layouts[mustLayersKey(t, first)]
+				args := h.Rdfile(t, filepath.Join(recordDir, "args-2"))
+				h.AssertStringContains(t, args, "context:base=oci-layout://base@"+firstLayout.digest.String())
+				h.AssertStringContains(t, args, "--oci-layout base="+filepath.Join(applier.workDir, "1"))
+				h.AssertPathDoesNotExist(t, filepath.Join(recordDir, "config-2.json"))
+				secondLayers, err := second.Layers()
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(secondLayers), 3)
+			})
+		})
+
+		when("extending the build image", func() {
+			it.Before(func() {
+				h.SkipIf(t, runtime.GOOS != "linux", "unpacking layers is only supported on linux")
+			})
+
+			it("unpacks new layers to the root directory", func() {
+				extended := appendFileLayer(t, base, "some-dir/some-file", "some-content")
+				extended = appendFileLayer(t, extended, "ignored-dir/some-file", "some-content")
+				writeLayout(t, filepath.Join(outputsDir, "1"), extended)
+
+				image, err := applier.ImageFor(baseRef)
+				h.AssertNil(t, err)
+				_, err = applier.Apply(dockerfile, image, extend.Options{
+					IgnorePaths: []string{filepath.Join(rootDir, "ignored-dir")},
+					Kind:        "build",
+				}, logger)
+				h.AssertNil(t, err)
+
+				h.AssertEq(t, h.Rdfile(t, filepath.Join(rootDir, "some-dir", "some-file")), "some-content")
+				h.AssertPathDoesNotExist(t, filepath.Join(rootDir, "ignored-dir"))
+			})
+		})
+	})
+}
+
+type fakeKeychain struct{}
+
+func (k *fakeKeychain) Resolve(r authn.Resource) (authn.Authenticator, error) {
+	if r.RegistryStr() == "some-registry.io" {
+		return authn.FromConfig(authn.AuthConfig{Username: "some-user", Password: "some-password"}), nil
+	}
+	return authn.Anonymous, nil
+}
+
+func randomImage(t *testing.T) v1.Image {
+	image, err := random.Image(1024, 1)
+	h.AssertNil(t, err)
+	return image
+}
+
+func appendFileLayer(t *testing.T, image v1.Image, path, contents string) v1.Image {
+	tr, err := h.CreateSingleFileTar(path, contents)
+	h.AssertNil(t, err)
+	b, err := io.ReadAll(tr)
+	h.AssertNil(t, err)
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	})
+	h.AssertNil(t, err)
+	image, err = mutate.AppendLayers(image, layer)
+	h.AssertNil(t, err)
+	return image
+}
+
+func writeLayout(t *testing.T, dir string, image v1.Image) v1.Image {
+	layoutPath, err := layout.Write(dir, empty.Index)
+	h.AssertNil(t, err)
+	h.AssertNil(t, layoutPath.AppendImage(image))
+	return image
+}
+
+func mustDigest(t *testing.T, image v1.Image) v1.Hash {
+	digest, err := image.Digest()
+	h.AssertNil(t, err)
+	return digest
+}
+
+func mustLayersKey(t *testing.T, image v1.Image) string {
+	key, err := layersKey(image)
+	h.AssertNil(t, err)
+	return key
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package buildkit
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+
+	"github.com/GoogleContainerTools/kaniko/pkg/util"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// unpackNewLayers extracts the layers of the extended image that are not in the base image to the provided root directory,
+// honoring whiteouts, kaniko's default ignore list (e.g., /proc, /sys, mounted volumes), and the provided ignore paths.
+// If rootless is true, extracted files are owned by the current user instead of the owner recorded in the layer.
+func unpackNewLayers(rootDir string, baseImage, extendedImage v1.Image, ignorePaths []string, rootless bool) error {
+	baseLayers, err := baseImage.Layers()
+	if err != nil {
+		return err
+	}
+	extendedLayers, err := extendedImage.Layers()
+	if err != nil {
+		return err
+	}
+	if len(extendedLayers) <= len(baseLayers) {
+		return nil
+	}
+	for _, p := range ignorePaths {
+		util.AddToDefaultIgnoreList(util.IgnoreListEntry{
+			Path:            p,
+			PrefixMatchOnly: false,
+		})
+	}
+	extract := util.ExtractFile
+	if rootless {
+		extract = extractAsCurrentUser
+	}
+	_, err = util.GetFSFromLayers(rootDir, extendedLayers[len(baseLayers):], util.ExtractFunc(extract))
+	return err
+}
+
+// extractAsCurrentUser extracts the provided file without changing its ownership,
+// which is not permitted in a user namespace that maps a single user.
+func extractAsCurrentUser(dest string, hdr *tar.Header, tr io.Reader) error {
+	owned := *hdr
+	owned.Uid, owned.Gid = os.Getuid(), os.Getgid()
+	return util.ExtractFile(dest, &owned, tr)
+}
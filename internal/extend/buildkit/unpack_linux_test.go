@@ -0,0 +1,87 @@
+//go:build linux
+// +build linux
+
+package buildkit
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestUnpackLinux(t *testing.T) {
+	spec.Run(t, "UnpackLinux", testUnpackLinux, spec.Report(report.Terminal{}))
+}
+
+func testUnpackLinux(t *testing.T, when spec.G, it spec.S) {
+	var (
+		rootDir  string
+		base     v1.Image
+		extended v1.Image
+	)
+
+	it.Before(func() {
+		var err error
+		rootDir, err = os.MkdirTemp("", "buildkit-unpack")
+		h.AssertNil(t, err)
+		base = randomImage(t)
+		extended = appendOwnedFileLayer(t, base, "some-file", 1234, 1234)
+	})
+
+	it.After(func() {
+		_ = os.RemoveAll(rootDir)
+	})
+
+	when("rootless", func() {
+		it("unpacks files as the current user", func() {
+			h.AssertNil(t, unpackNewLayers(rootDir, base, extended, nil, true))
+
+			fi, err := os.Stat(filepath.Join(rootDir, "some-file"))
+			h.AssertNil(t, err)
+			stat := fi.Sys().(*syscall.Stat_t)
+			h.AssertEq(t, int(stat.Uid), os.Getuid())
+			h.AssertEq(t, int(stat.Gid), os.Getgid())
+		})
+	})
+
+	when("not rootless", func() {
+		it("preserves file ownership", func() {
+			h.SkipIf(t, os.Getuid() != 0, "changing file ownership requires root")
+			h.AssertNil(t, unpackNewLayers(rootDir, base, extended, nil, false))
+
+			fi, err := os.Stat(filepath.Join(rootDir, "some-file"))
+			h.AssertNil(t, err)
+			stat := fi.Sys().(*syscall.Stat_t)
+			h.AssertEq(t, int(stat.Uid), 1234)
+			h.AssertEq(t, int(stat.Gid), 1234)
+		})
+	})
+}
+
+func appendOwnedFileLayer(t *testing.T, image v1.Image, path string, uid, gid int) v1.Image {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	h.AssertNil(t, tw.WriteHeader(&tar.Header{Name: path, Size: int64(len("some-content")), Mode: 0644, Uid: uid, Gid: gid}))
+	_, err := tw.Write([]byte("some-content"))
+	h.AssertNil(t, err)
+	h.AssertNil(t, tw.Close())
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	h.AssertNil(t, err)
+	image, err = mutate.AppendLayers(image, layer)
+	h.AssertNil(t, err)
+	return image
+}
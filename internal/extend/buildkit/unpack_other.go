@@ -0,0 +1,13 @@
+//go:build !linux
+
+package buildkit
+
+import (
+	"errors"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func unpackNewLayers(_ string, _, _ v1.Image, _ []string, _ bool) error {
+	return errors.New("extending the build image with BuildKit is only supported on linux")
+}
@@ -0,0 +1,228 @@
+package extend
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/buildpacks/lifecycle/archive"
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// LayerCacheDirName is the name of the directory in the lifecycle cache directory
+// where layers created by extension Dockerfiles are persisted between builds.
+// Base images to extend are not persisted; the restorer pulls them in each build.
+const LayerCacheDirName = "extend"
+
+// RestoreLayerCache copies layers cached by a previous build from the provided cache directory
+// to the directory where the Dockerfile applier looks for cached layers.
+// Cached layers are keyed by the applier (kaniko or BuildKit) using the Dockerfile instruction and the digests of its inputs,
+// so unchanged instructions (such as package installs) are not re-run.
+func RestoreLayerCache(cacheDir, layerCacheDir string) error {
+	savedDir := filepath.Join(cacheDir, LayerCacheDirName)
+	if _, err := os.Stat(savedDir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.RemoveAll(layerCacheDir); err != nil {
+		return fmt.Errorf("removing layer cache directory: %w", err)
+	}
+	if err := fsutil.Copy(savedDir, layerCacheDir); err != nil {
+		return fmt.Errorf("restoring extension layer cache: %w", err)
+	}
+	return nil
+}
+
+// SaveLayerCache replaces the layers saved in the provided cache directory with the contents of the layer cache directory.
+func SaveLayerCache(layerCacheDir, cacheDir string) error {
+	if _, err := os.Stat(layerCacheDir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	savedDir := filepath.Join(cacheDir, LayerCacheDirName)
+	stagingDir := savedDir + ".staging"
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return err
+	}
+	if err := fsutil.Copy(layerCacheDir, stagingDir); err != nil {
+		return fmt.Errorf("saving extension layer cache: %w", err)
+	}
+	if err := os.RemoveAll(savedDir); err != nil {
+		return err
+	}
+	return fsutil.RenameWithWindowsFallback(stagingDir, savedDir)
+}
+
+// PullLayerCache replaces the contents of the layer cache directory with layers cached in the provided registry image,
+// so that builders sharing the image don't each need to re-create the layers created by extension Dockerfiles.
+// Only the layer cache is shared; the base images in <kaniko-dir>/cache/base are still pulled by the restorer in each build.
+// The image is expected to have been created by PushLayerCache; it is not an error for the image not to exist.
+// The returned value is the diffID of the cache layer, or the empty string if no image was found.
+func PullLayerCache(cacheImageRef, layerCacheDir string, keychain authn.Keychain) (string, error) {
+	ref, err := name.ParseReference(cacheImageRef)
+	if err != nil {
+		return "", fmt.Errorf("parsing cache image reference '%s': %w", cacheImageRef, err)
+	}
+	image, err := remote.Image(ref, remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		if isNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("getting cache image '%s': %w", cacheImageRef, err)
+	}
+	imageLayers, err := image.Layers()
+	if err != nil {
+		return "", fmt.Errorf("getting cache image layers: %w", err)
+	}
+	if len(imageLayers) != 1 {
+		return "", fmt.Errorf("expected cache image '%s' to have 1 layer, found %d", cacheImageRef, len(imageLayers))
+	}
+	diffID, err := imageLayers[0].DiffID()
+	if err != nil {
+		return "", fmt.Errorf("getting cache layer diffID: %w", err)
+	}
+	rc, err := imageLayers[0].Uncompressed()
+	if err != nil {
+		return "", fmt.Errorf("getting cache layer: %w", err)
+	}
+	defer rc.Close()
+	if err = os.RemoveAll(layerCacheDir); err != nil {
+		return "", fmt.Errorf("removing layer cache directory: %w", err)
+	}
+	if err = archive.Extract(&scopedTarReader{TarReader: tar.NewReader(rc), dir: filepath.Clean(layerCacheDir)}); err != nil {
+		return "", fmt.Errorf("extracting cache layer: %w", err)
+	}
+	return diffID.String(), nil
+}
+
+// PushLayerCache writes the contents of the layer cache directory as the single layer of the provided registry image.
+// Nothing is pushed if the layer cache directory doesn't exist or if its contents match the provided diffID,
+// which is the diffID of the layer previously pulled by PullLayerCache.
+func PushLayerCache(layerCacheDir, cacheImageRef string, keychain authn.Keychain, pulledDiffID string, logger log.Logger) error {
+	if _, err := os.Stat(layerCacheDir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	ref, err := name.ParseReference(cacheImageRef)
+	if err != nil {
+		return fmt.Errorf("parsing cache image reference '%s': %w", cacheImageRef, err)
+	}
+	artifactsDir, err := os.MkdirTemp("", "extend-cache")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(artifactsDir)
+	tarPath := filepath.Join(artifactsDir, "extend-cache.tar")
+	if err = writeLayerCacheTar(layerCacheDir, tarPath); err != nil {
+		return fmt.Errorf("creating cache layer: %w", err)
+	}
+	layer, err := tarball.LayerFromFile(tarPath)
+	if err != nil {
+		return fmt.Errorf("reading cache layer: %w", err)
+	}
+	diffID, err := layer.DiffID()
+	if err != nil {
+		return fmt.Errorf("getting cache layer diffID: %w", err)
+	}
+	if diffID.String() == pulledDiffID {
+		logger.Debugf("Extension layer cache is unchanged, skipping push to '%s'", cacheImageRef)
+		return nil
+	}
+	image, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		return fmt.Errorf("creating cache image: %w", err)
+	}
+	logger.Debugf("Pushing extension layer cache to '%s'", cacheImageRef)
+	if err = remote.Write(ref, image, remote.WithAuthFromKeychain(keychain)); err != nil {
+		return fmt.Errorf("pushing cache image '%s': %w", cacheImageRef, err)
+	}
+	return nil
+}
+
+// writeLayerCacheTar writes the contents of the layer cache directory to a tarball at tarPath,
+// with paths relative to the layer cache directory, so that the cache can be pulled into a different directory.
+// Modification times, UIDs and GIDs are normalized so that an unchanged layer cache has the same diffID.
+func writeLayerCacheTar(layerCacheDir, tarPath string) error {
+	f, err := os.Create(tarPath) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tw := archive.NewNormalizingTarWriter(tar.NewWriter(f))
+	tw.WithUID(0)
+	tw.WithGID(0)
+	tw.WithModTime(archive.NormalizedModTime)
+	if err = archive.AddDirToArchive(&relativeTarWriter{TarWriter: tw, dir: filepath.Clean(layerCacheDir)}, layerCacheDir); err != nil {
+		return err
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// relativeTarWriter writes the paths of entries relative to the provided directory, omitting the entry for the directory itself.
+type relativeTarWriter struct {
+	archive.TarWriter
+	dir string
+}
+
+func (w *relativeTarWriter) WriteHeader(hdr *tar.Header) error {
+	rel, err := filepath.Rel(w.dir, hdr.Name)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	hdr.Name = filepath.ToSlash(rel)
+	return w.TarWriter.WriteHeader(hdr)
+}
+
+func isNotFound(err error) bool {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		return terr.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// scopedTarReader extracts the entries of a cache image (whose paths are relative to the layer cache directory)
+// under the provided directory. It skips any links, and resolves the paths of entries as if the directory were the root,
+// so that a cache image can't be used to write elsewhere on the filesystem.
+type scopedTarReader struct {
+	archive.TarReader
+	dir string
+}
+
+func (r *scopedTarReader) Next() (*tar.Header, error) {
+	for {
+		hdr, err := r.TarReader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeDir && hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		hdr.Name = filepath.Join(r.dir, filepath.Clean(string(filepath.Separator)+filepath.FromSlash(hdr.Name)))
+		return hdr, nil
+	}
+}
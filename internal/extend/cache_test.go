@@ -0,0 +1,159 @@
+package extend_test
+
+import (
+	"archive/tar"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/extend"
+	llog "github.com/buildpacks/lifecycle/log"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestLayerCache(t *testing.T) {
+	spec.Run(t, "LayerCache", testLayerCache, spec.Report(report.Terminal{}))
+}
+
+func testLayerCache(t *testing.T, when spec.G, it spec.S) {
+	var cacheDir, layerCacheDir string
+
+	it.Before(func() {
+		cacheDir = t.TempDir()
+		layerCacheDir = filepath.Join(t.TempDir(), "layers")
+	})
+
+	when("#RestoreLayerCache", func() {
+		it("copies saved layers to the layer cache directory", func() {
+			h.Mkdir(t, filepath.Join(cacheDir, "extend"))
+			h.Mkfile(t, "some-layer", filepath.Join(cacheDir, "extend", "sha256:some-digest"))
+
+			h.AssertNil(t, extend.RestoreLayerCache(cacheDir, layerCacheDir))
+
+			h.AssertEq(t, h.Rdfile(t, filepath.Join(layerCacheDir, "sha256:some-digest")), "some-layer")
+		})
+
+		when("nothing was saved", func() {
+			it("does nothing", func() {
+				h.AssertNil(t, extend.RestoreLayerCache(cacheDir, layerCacheDir))
+
+				h.AssertPathDoesNotExist(t, layerCacheDir)
+			})
+		})
+	})
+
+	when("#SaveLayerCache", func() {
+		it("replaces previously saved layers", func() {
+			h.Mkdir(t, filepath.Join(cacheDir, "extend"), layerCacheDir)
+			h.Mkfile(t, "stale-layer", filepath.Join(cacheDir, "extend", "sha256:stale-digest"))
+			h.Mkfile(t, "some-layer", filepath.Join(layerCacheDir, "sha256:some-digest"))
+
+			h.AssertNil(t, extend.SaveLayerCache(layerCacheDir, cacheDir))
+
+			h.AssertEq(t, h.Rdfile(t, filepath.Join(cacheDir, "extend", "sha256:some-digest")), "some-layer")
+			h.AssertPathDoesNotExist(t, filepath.Join(cacheDir, "extend", "sha256:stale-digest"))
+			h.AssertPathDoesNotExist(t, filepath.Join(cacheDir, "extend.staging"))
+		})
+
+		when("there is no layer cache", func() {
+			it("does nothing", func() {
+				h.AssertNil(t, extend.SaveLayerCache(layerCacheDir, cacheDir))
+
+				h.AssertPathDoesNotExist(t, filepath.Join(cacheDir, "extend"))
+			})
+		})
+	})
+
+	when("using a cache image", func() {
+		var (
+			server        *httptest.Server
+			cacheImageRef string
+			logger        = llog.NewDefaultLogger(io.Discard)
+		)
+
+		it.Before(func() {
+			server = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			cacheImageRef = strings.TrimPrefix(server.URL, "http://") + "/some-cache-image"
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("pushes and pulls the layer cache", func() {
+			h.Mkdir(t, filepath.Join(layerCacheDir, "some-dir"))
+			h.Mkfile(t, "some-layer", filepath.Join(layerCacheDir, "some-dir", "sha256:some-digest"))
+
+			h.AssertNil(t, extend.PushLayerCache(layerCacheDir, cacheImageRef, authn.DefaultKeychain, "", logger))
+			h.AssertNil(t, os.RemoveAll(layerCacheDir))
+
+			diffID, err := extend.PullLayerCache(cacheImageRef, layerCacheDir, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			h.AssertEq(t, strings.HasPrefix(diffID, "sha256:"), true)
+			h.AssertEq(t, h.Rdfile(t, filepath.Join(layerCacheDir, "some-dir", "sha256:some-digest")), "some-layer")
+		})
+
+		it("pulls the layer cache into a different directory", func() {
+			h.Mkdir(t, layerCacheDir)
+			h.Mkfile(t, "some-layer", filepath.Join(layerCacheDir, "sha256:some-digest"))
+			h.AssertNil(t, extend.PushLayerCache(layerCacheDir, cacheImageRef, authn.DefaultKeychain, "", logger))
+
+			ref, err := name.ParseReference(cacheImageRef)
+			h.AssertNil(t, err)
+			image, err := remote.Image(ref)
+			h.AssertNil(t, err)
+			imageLayers, err := image.Layers()
+			h.AssertNil(t, err)
+			rc, err := imageLayers[0].Uncompressed()
+			h.AssertNil(t, err)
+			defer rc.Close()
+			hdr, err := tar.NewReader(rc).Next()
+			h.AssertNil(t, err)
+			h.AssertEq(t, hdr.Name, "sha256:some-digest")
+
+			otherDir := filepath.Join(t.TempDir(), "other-layers")
+			_, err = extend.PullLayerCache(cacheImageRef, otherDir, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			h.AssertEq(t, h.Rdfile(t, filepath.Join(otherDir, "sha256:some-digest")), "some-layer")
+		})
+
+		it("does not push an unchanged layer cache", func() {
+			h.Mkdir(t, layerCacheDir)
+			h.Mkfile(t, "some-layer", filepath.Join(layerCacheDir, "sha256:some-digest"))
+			h.AssertNil(t, extend.PushLayerCache(layerCacheDir, cacheImageRef, authn.DefaultKeychain, "", logger))
+			diffID, err := extend.PullLayerCache(cacheImageRef, layerCacheDir, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+
+			otherRef := cacheImageRef + "-other"
+			h.AssertNil(t, extend.PushLayerCache(layerCacheDir, otherRef, authn.DefaultKeychain, diffID, logger))
+
+			ref, err := name.ParseReference(otherRef)
+			h.AssertNil(t, err)
+			_, err = remote.Head(ref)
+			h.AssertNotNil(t, err)
+		})
+
+		when("the cache image does not exist", func() {
+			it("does nothing", func() {
+				h.Mkdir(t, layerCacheDir)
+				h.Mkfile(t, "some-layer", filepath.Join(layerCacheDir, "sha256:some-digest"))
+
+				diffID, err := extend.PullLayerCache(cacheImageRef, layerCacheDir, authn.DefaultKeychain)
+				h.AssertNil(t, err)
+				h.AssertEq(t, diffID, "")
+				h.AssertEq(t, h.Rdfile(t, filepath.Join(layerCacheDir, "sha256:some-digest")), "some-layer")
+			})
+		})
+	})
+}
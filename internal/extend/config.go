@@ -3,8 +3,13 @@ package extend
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/buildpacks/lifecycle/internal/encoding"
+	"github.com/buildpacks/lifecycle/internal/fsutil"
 )
 
 type Config struct {
@@ -16,6 +21,16 @@ type BuildConfig struct {
 	Args []Arg `toml:"args"`
 }
 
+// PlanSource identifies build plan metadata to use as the value of an arg,
+// e.g., a list of OS packages required by buildpacks.
+type PlanSource struct {
+	Entry string `toml:"entry"`
+	Key   string `toml:"key"`
+}
+
+// PlanLookup returns the values for the provided metadata key of each plan entry with the provided name.
+type PlanLookup func(entry, key string) []interface{}
+
 var argsProvidedByLifecycle = []string{"build_id", "user_id", "group_id"}
 
 func ValidateConfig(configPath string) error {
@@ -34,6 +49,15 @@ func ValidateConfig(configPath string) error {
 					return fmt.Errorf("invalid content: arg with name %q is not allowed", invalid)
 				}
 			}
+			if arg.FromPlan == nil {
+				continue
+			}
+			if arg.Value != "" {
+				return fmt.Errorf("invalid content: arg with name %q must not provide both a value and a plan source", arg.Name)
+			}
+			if arg.FromPlan.Entry == "" || arg.FromPlan.Key == "" {
+				return fmt.Errorf("invalid content: plan source for arg with name %q must provide an entry and a key", arg.Name)
+			}
 		}
 		return nil
 	}
@@ -45,3 +69,70 @@ func ValidateConfig(configPath string) error {
 	}
 	return nil
 }
+
+// ResolveConfig reads the extend config at fromPath, replaces the plan source of each arg with the value found
+// using the provided lookup, and writes the result to toPath.
+// Values from multiple plan entries are de-duplicated and joined with spaces, as are list values.
+// If no args are sourced from the plan, the config is copied as-is.
+func ResolveConfig(fromPath, toPath string, lookup PlanLookup) error {
+	var config Config
+	if _, err := toml.DecodeFile(fromPath, &config); err != nil {
+		return fmt.Errorf("reading extend config: %w", err)
+	}
+	var resolved bool
+	for _, args := range [][]Arg{config.Build.Args, config.Run.Args} {
+		for i := range args {
+			if args[i].FromPlan == nil {
+				continue
+			}
+			resolved = true
+			value, err := planValue(lookup(args[i].FromPlan.Entry, args[i].FromPlan.Key))
+			if err != nil {
+				return fmt.Errorf("resolving arg with name %q from plan entry %q: %w", args[i].Name, args[i].FromPlan.Entry, err)
+			}
+			args[i].Value = value
+			args[i].FromPlan = nil
+		}
+	}
+	if !resolved {
+		if err := os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+			return err
+		}
+		return fsutil.Copy(fromPath, toPath)
+	}
+	return encoding.WriteTOML(toPath, config)
+}
+
+func planValue(found []interface{}) (string, error) {
+	var (
+		values []string
+		seen   = map[string]bool{}
+	)
+	add := func(v interface{}) error {
+		switch v.(type) {
+		case string, bool, int, int64, float64:
+		default:
+			return fmt.Errorf("unsupported value type %T", v)
+		}
+		s := fmt.Sprintf("%v", v)
+		if !seen[s] {
+			seen[s] = true
+			values = append(values, s)
+		}
+		return nil
+	}
+	for _, f := range found {
+		if list, ok := f.([]interface{}); ok {
+			for _, v := range list {
+				if err := add(v); err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+		if err := add(f); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(values, " "), nil
+}
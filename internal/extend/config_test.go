@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/BurntSushi/toml"
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
@@ -75,6 +76,107 @@ value = "some-run-base-arg-value"
 					}
 				})
 			})
+
+			when("contains invalid plan source", func() {
+				it("errors", func() {
+					invalidContent := map[string]string{
+						`
+						[[build.args]]
+						name = "some-arg"
+						value = "some-value"
+						from-plan = { entry = "some-entry", key = "some-key" }
+						`: `invalid content: arg with name "some-arg" must not provide both a value and a plan source`,
+						`
+						[[run.args]]
+						name = "some-arg"
+						from-plan = { entry = "some-entry" }
+						`: `invalid content: plan source for arg with name "some-arg" must provide an entry and a key`,
+					}
+					for c, expected := range invalidContent {
+						config := filepath.Join(tmpDir, "extend-config.toml")
+						h.Mkfile(t, c, config)
+						h.AssertError(t, extend.ValidateConfig(config), expected)
+					}
+				})
+			})
+		})
+	})
+
+	when("#ResolveConfig", func() {
+		var (
+			fromPath, toPath string
+			plan             = map[string][]interface{}{
+				"some-entry.packages": {[]interface{}{"curl", "git"}, []interface{}{"git", "jq"}},
+				"some-entry.version":  {int64(3)},
+				"some-entry.nested":   {map[string]interface{}{"some-key": "some-value"}},
+			}
+			lookup = func(entry, key string) []interface{} {
+				return plan[entry+"."+key]
+			}
+		)
+
+		it.Before(func() {
+			fromPath = filepath.Join(tmpDir, "from", "extend-config.toml")
+			toPath = filepath.Join(tmpDir, "to", "extend-config.toml")
+			h.Mkdir(t, filepath.Dir(fromPath))
+		})
+
+		it("sets the values of args sourced from the plan", func() {
+			h.Mkfile(t, `
+[[build.args]]
+name = "packages"
+from-plan = { entry = "some-entry", key = "packages" }
+
+[[build.args]]
+name = "some-arg"
+value = "some-value"
+
+[[run.args]]
+name = "version"
+from-plan = { entry = "some-entry", key = "version" }
+
+[[run.args]]
+name = "missing"
+from-plan = { entry = "other-entry", key = "packages" }
+`, fromPath)
+
+			h.AssertNil(t, extend.ResolveConfig(fromPath, toPath, lookup))
+
+			var config extend.Config
+			_, err := toml.DecodeFile(toPath, &config)
+			h.AssertNil(t, err)
+			h.AssertEq(t, config.Build.Args, []extend.Arg{
+				{Name: "packages", Value: "curl git jq"},
+				{Name: "some-arg", Value: "some-value"},
+			})
+			h.AssertEq(t, config.Run.Args, []extend.Arg{
+				{Name: "version", Value: "3"},
+				{Name: "missing", Value: ""},
+			})
+		})
+
+		when("no args are sourced from the plan", func() {
+			it("copies the config", func() {
+				content := "[[build.args]]\nname = \"some-arg\" # some-comment\nvalue = \"some-value\"\n"
+				h.Mkfile(t, content, fromPath)
+
+				h.AssertNil(t, extend.ResolveConfig(fromPath, toPath, lookup))
+
+				h.AssertEq(t, h.Rdfile(t, toPath), content)
+			})
+		})
+
+		when("the plan value is not supported", func() {
+			it("errors", func() {
+				h.Mkfile(t, `
+[[build.args]]
+name = "nested"
+from-plan = { entry = "some-entry", key = "nested" }
+`, fromPath)
+
+				err := extend.ResolveConfig(fromPath, toPath, lookup)
+				h.AssertError(t, err, `resolving arg with name "nested" from plan entry "some-entry": unsupported value type map[string]interface {}`)
+			})
 		})
 	})
 }
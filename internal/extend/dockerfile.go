@@ -7,6 +7,7 @@ type Dockerfile struct {
 }
 
 type Arg struct {
-	Name  string `toml:"name"`
-	Value string `toml:"value"`
+	Name     string      `toml:"name"`
+	Value    string      `toml:"value"`
+	FromPlan *PlanSource `toml:"from-plan,omitempty"`
 }
@@ -4,69 +4,38 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/GoogleContainerTools/kaniko/pkg/config"
 	"github.com/containerd/containerd/platforms"
-	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
-	"github.com/google/go-containerregistry/pkg/v1/layout"
 
 	"github.com/buildpacks/lifecycle/internal/extend"
 )
 
-const (
-	kanikoDir = "/kaniko"
-	ociPrefix = "oci:"
-)
-
-var (
-	kanikoCacheImageRef = filepath.Join(ociPrefix, kanikoDir, "cache", "layers", "cached")
-)
-
 type DockerfileApplier struct {
-	workDir string
+	kanikoDir string
+	workDir   string
 }
 
-func NewDockerfileApplier() (*DockerfileApplier, error) {
+// NewDockerfileApplier returns a DockerfileApplier that keeps its cache and working files in kanikoDir.
+func NewDockerfileApplier(kanikoDir string) (*DockerfileApplier, error) {
 	workDir, err := os.MkdirTemp(kanikoDir, "work.dir")
 	if err != nil {
 		return nil, err
 	}
 	return &DockerfileApplier{
-		workDir: workDir,
+		kanikoDir: kanikoDir,
+		workDir:   workDir,
 	}, nil
 }
 
 func (a *DockerfileApplier) ImageFor(reference string) (v1.Image, error) {
-	digest, err := name.NewDigest(reference)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get digest for reference '%s': %w", reference, err)
-	}
-	baseImage, err := readOCI(ociPrefix + filepath.Join(kanikoDir, "cache", "base", digest.DigestStr()))
-	if err != nil {
-		return nil, fmt.Errorf("getting base image for digest '%s': %w", digest, err)
-	}
-	return baseImage, nil
+	return extend.ReadBaseImage(filepath.Join(a.kanikoDir, "cache", "base"), reference)
 }
 
-func readOCI(path string) (v1.Image, error) {
-	if !strings.HasPrefix(path, "oci:") {
-		return nil, fmt.Errorf("expected '%s' to have prefix 'oci:'", path)
-	}
-	layoutPath, err := layout.FromPath(strings.TrimPrefix(path, "oci:"))
-	if err != nil {
-		return nil, fmt.Errorf("getting layout from path: %w", err)
-	}
-	hash, err := v1.NewHash(filepath.Base(path))
-	if err != nil {
-		return nil, fmt.Errorf("getting hash from reference '%s': %w", path, err)
-	}
-	v1Image, err := layoutPath.Image(hash) // FIXME: we may want to implement path.Image(h) in the imgutil 'sparse' package so that trying to access layers on this image errors with a helpful message
-	if err != nil {
-		return nil, fmt.Errorf("getting image from hash '%s': %w", hash.String(), err)
-	}
-	return v1Image, nil
+// cacheImageRef returns the reference of the layout where kaniko caches the layers it creates.
+func (a *DockerfileApplier) cacheImageRef() string {
+	return filepath.Join(extend.OCIPrefix, a.kanikoDir, "cache", "layers", "cached")
 }
 
 func createOptions(baseImageRef string, dockerfile extend.Dockerfile, options extend.Options) config.KanikoOptions {
@@ -75,7 +44,6 @@ func createOptions(baseImageRef string, dockerfile extend.Dockerfile, options ex
 		Cache:             true,
 		CacheOptions:      config.CacheOptions{CacheTTL: options.CacheTTL},
 		CacheRunLayers:    true,
-		CacheRepo:         kanikoCacheImageRef,
 		Cleanup:           false,
 		CustomPlatform:    platforms.DefaultString(),
 		DockerfilePath:    dockerfile.Path,
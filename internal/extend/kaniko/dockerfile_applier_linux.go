@@ -37,6 +37,7 @@ func (a *DockerfileApplier) Apply(dockerfile extend.Dockerfile, toBaseImage v1.I
 	}
 	baseImageRef := fmt.Sprintf("base@%s", digestToExtend)
 	opts := createOptions(baseImageRef, dockerfile, withBuildOptions)
+	opts.CacheRepo = a.cacheImageRef()
 
 	// update ignore paths; kaniko does this here:
 	// https://github.com/GoogleContainerTools/kaniko/blob/v1.9.2/cmd/executor/cmd/root.go#L124
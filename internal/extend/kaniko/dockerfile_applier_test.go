@@ -1,6 +1,7 @@
 package kaniko
 
 import (
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -80,4 +81,18 @@ func testDockerApplier(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 	})
+
+	when("#NewDockerfileApplier", func() {
+		it("keeps its working files and cache in the provided kaniko dir", func() {
+			kanikoDir := t.TempDir()
+
+			applier, err := NewDockerfileApplier(kanikoDir)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, filepath.Dir(applier.workDir), kanikoDir)
+			h.AssertEq(t, applier.cacheImageRef(), "oci:"+filepath.Join(kanikoDir, "cache", "layers", "cached"))
+			h.AssertNil(t, applier.Cleanup())
+			h.AssertPathDoesNotExist(t, applier.workDir)
+		})
+	})
 }
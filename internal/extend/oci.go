@@ -0,0 +1,47 @@
+package extend
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+)
+
+const OCIPrefix = "oci:"
+
+// ReadBaseImage returns the (sparse) base image for the provided digest reference
+// from the provided directory, where it is saved by the restorer in OCI layout format.
+func ReadBaseImage(baseCacheDir, reference string) (v1.Image, error) {
+	digest, err := name.NewDigest(reference)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest for reference '%s': %w", reference, err)
+	}
+	baseImage, err := ReadOCI(OCIPrefix + filepath.Join(baseCacheDir, digest.DigestStr()))
+	if err != nil {
+		return nil, fmt.Errorf("getting base image for digest '%s': %w", digest, err)
+	}
+	return baseImage, nil
+}
+
+// ReadOCI reads the image in OCI layout format at the provided path; the final path component must be the image digest.
+func ReadOCI(path string) (v1.Image, error) {
+	if !strings.HasPrefix(path, OCIPrefix) {
+		return nil, fmt.Errorf("expected '%s' to have prefix '%s'", path, OCIPrefix)
+	}
+	layoutPath, err := layout.FromPath(strings.TrimPrefix(path, OCIPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("getting layout from path: %w", err)
+	}
+	hash, err := v1.NewHash(filepath.Base(path))
+	if err != nil {
+		return nil, fmt.Errorf("getting hash from reference '%s': %w", path, err)
+	}
+	v1Image, err := layoutPath.Image(hash) // FIXME: we may want to implement path.Image(h) in the imgutil 'sparse' package so that trying to access layers on this image errors with a helpful message
+	if err != nil {
+		return nil, fmt.Errorf("getting image from hash '%s': %w", hash.String(), err)
+	}
+	return v1Image, nil
+}
@@ -6,4 +6,5 @@ type Options struct {
 	BuildContext string
 	IgnorePaths  []string
 	CacheTTL     time.Duration
+	Kind         string // the kind of base image being extended (build or run)
 }
@@ -0,0 +1,38 @@
+//go:build linux
+
+package fsutil
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// noReflink holds the device IDs of the filesystems that do not support reflinks,
+// so that cloning is only attempted once per filesystem.
+var noReflink sync.Map
+
+// reflink clones the contents of src into dst (which must be empty), sharing their extents until either file is modified,
+// if the filesystem supports it (e.g., XFS or btrfs). It returns false if the contents must be copied instead.
+func reflink(dst, src *os.File) bool {
+	var dev uint64
+	if fi, err := dst.Stat(); err == nil {
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			dev = uint64(st.Dev)
+		}
+	}
+	if _, unsupported := noReflink.Load(dev); unsupported {
+		return false
+	}
+	err := unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOSYS) {
+		noReflink.Store(dev, true)
+	}
+	return false
+}
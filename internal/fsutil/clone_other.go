@@ -0,0 +1,10 @@
+//go:build !linux
+
+package fsutil
+
+import "os"
+
+// reflink returns false, as cloning files is only supported on Linux.
+func reflink(_, _ *os.File) bool {
+	return false
+}
@@ -0,0 +1,135 @@
+package fsutil
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// CopyDirConcurrently copies the contents of src to dst (which is created if it does not exist),
+// copying regular files with up to the provided number of concurrent workers.
+// Paths that match ignore (relative to src) are skipped, including the contents of ignored directories.
+// The modes and modification times of the copied files and directories are preserved, and symlinks are copied as-is.
+// It returns the slash-separated relative paths that were skipped.
+func CopyDirConcurrently(src, dst string, ignore *Patterns, workers int) ([]string, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetLimit(workers)
+
+	var (
+		skipped []string
+		dirs    []copiedDir
+	)
+	walkErr := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			// a worker failed, its error is returned by g.Wait
+			return filepath.SkipAll
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return os.MkdirAll(dst, 0755)
+		}
+		if ignore != nil && ignore.Matches(filepath.ToSlash(rel), d.IsDir()) {
+			skipped = append(skipped, filepath.ToSlash(rel))
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			// the mode is applied once the contents are copied, in case the directory is not writable
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, copiedDir{path: target, info: fi})
+		case d.Type().IsRegular():
+			g.Go(func() error {
+				return copyFileWithInfo(path, target, fi)
+			})
+		case d.Type()&os.ModeSymlink != 0:
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err := copySymlink(path, target); err != nil {
+				return err
+			}
+		default:
+			// ignore edge cases (unix socket, named pipe, etc.)
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	// copying the contents changes the modification times of the directories, so they are set last, deepest first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := dirs[i].restore(); err != nil {
+			return nil, err
+		}
+	}
+	return skipped, nil
+}
+
+type copiedDir struct {
+	path string
+	info fs.FileInfo
+}
+
+func (d copiedDir) restore() error {
+	if err := os.Chmod(d.path, d.info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(d.path, d.info.ModTime(), d.info.ModTime())
+}
+
+// copyFileWithInfo copies the regular file src to dst, preserving the mode and modification time in fi.
+func copyFileWithInfo(src, dst string, fi fs.FileInfo) error {
+	in, err := os.Open(src) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// an existing file may be read-only, so it is replaced rather than truncated
+	if err = os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) // #nosec G304
+	if err != nil {
+		return err
+	}
+	if !reflink(out, in) {
+		if _, err = io.Copy(out, in); err != nil {
+			_ = out.Close()
+			return err
+		}
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(dst, fi.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}
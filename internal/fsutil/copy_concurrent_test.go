@@ -0,0 +1,106 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestCopyDirConcurrently(t *testing.T) {
+	spec.Run(t, "CopyDirConcurrently", testCopyDirConcurrently, spec.Report(report.Terminal{}))
+}
+
+func testCopyDirConcurrently(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir string
+		src    string
+		dst    string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle.test")
+		h.AssertNil(t, err)
+		src = filepath.Join(tmpDir, "src")
+		dst = filepath.Join(tmpDir, "dst")
+		for _, path := range []string{"main.go", "app.log", "src/lib.go", "node_modules/dep/index.js", ".git/HEAD"} {
+			h.AssertNil(t, os.MkdirAll(filepath.Dir(filepath.Join(src, path)), 0755))
+			h.Mkfile(t, "contents of "+path, filepath.Join(src, path))
+		}
+	})
+
+	it.After(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	it("copies the directory", func() {
+		skipped, err := fsutil.CopyDirConcurrently(src, dst, nil, 4)
+		h.AssertNil(t, err)
+		h.AssertEq(t, len(skipped), 0)
+
+		for _, path := range []string{"main.go", "app.log", "src/lib.go", "node_modules/dep/index.js", ".git/HEAD"} {
+			h.AssertEq(t, string(h.MustReadFile(t, filepath.Join(dst, path))), "contents of "+path)
+		}
+	})
+
+	it("skips ignored files and directories", func() {
+		patterns, err := fsutil.NewPatterns([]string{"node_modules/", ".git", "*.log"})
+		h.AssertNil(t, err)
+
+		skipped, err := fsutil.CopyDirConcurrently(src, dst, patterns, 4)
+		h.AssertNil(t, err)
+		h.AssertEq(t, skipped, []string{".git", "app.log", "node_modules"})
+
+		h.AssertPathExists(t, filepath.Join(dst, "main.go"))
+		h.AssertPathExists(t, filepath.Join(dst, "src", "lib.go"))
+		h.AssertPathDoesNotExist(t, filepath.Join(dst, "app.log"))
+		h.AssertPathDoesNotExist(t, filepath.Join(dst, "node_modules"))
+		h.AssertPathDoesNotExist(t, filepath.Join(dst, ".git"))
+	})
+
+	it("preserves modes, modification times and symlinks", func() {
+		h.SkipIf(t, runtime.GOOS == "windows", "file modes and symlinks are not preserved on windows")
+		mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		h.AssertNil(t, os.Chmod(filepath.Join(src, "main.go"), 0750))
+		h.AssertNil(t, os.Chtimes(filepath.Join(src, "main.go"), mtime, mtime))
+		h.AssertNil(t, os.Symlink("lib.go", filepath.Join(src, "src", "link.go")))
+		h.AssertNil(t, os.Chmod(filepath.Join(src, "src"), 0555))
+		h.AssertNil(t, os.Chtimes(filepath.Join(src, "src"), mtime, mtime))
+		defer os.Chmod(filepath.Join(src, "src"), 0755)
+
+		_, err := fsutil.CopyDirConcurrently(src, dst, nil, 4)
+		h.AssertNil(t, err)
+		defer os.Chmod(filepath.Join(dst, "src"), 0755)
+
+		fi, err := os.Stat(filepath.Join(dst, "main.go"))
+		h.AssertNil(t, err)
+		h.AssertEq(t, fi.Mode().Perm(), os.FileMode(0750))
+		h.AssertEq(t, fi.ModTime().UTC(), mtime)
+		fi, err = os.Stat(filepath.Join(dst, "src"))
+		h.AssertNil(t, err)
+		h.AssertEq(t, fi.Mode().Perm(), os.FileMode(0555))
+		h.AssertEq(t, fi.ModTime().UTC(), mtime)
+		target, err := os.Readlink(filepath.Join(dst, "src", "link.go"))
+		h.AssertNil(t, err)
+		h.AssertEq(t, target, "lib.go")
+	})
+
+	it("fails if a file cannot be read", func() {
+		h.SkipIf(t, runtime.GOOS == "windows", "file modes are not enforced on windows")
+		h.AssertNil(t, os.Chmod(filepath.Join(src, "main.go"), 0000))
+		if _, err := os.ReadFile(filepath.Join(src, "main.go")); err == nil {
+			t.Skip("file permissions are not enforced for the current user")
+		}
+
+		_, err := fsutil.CopyDirConcurrently(src, dst, nil, 4)
+		h.AssertError(t, err, "permission denied")
+	})
+}
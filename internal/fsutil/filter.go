@@ -0,0 +1,184 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Patterns matches slash-separated relative paths against gitignore-style patterns:
+//   - blank lines and lines starting with "#" are ignored
+//   - a leading "!" negates the pattern; the last matching pattern wins
+//   - a trailing "/" matches only directories
+//   - a pattern containing "/" is relative to the root; otherwise it matches at any depth
+//   - "*" and "?" do not match "/", while "**" matches any number of directories
+type Patterns struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// NewPatterns compiles the provided gitignore-style patterns.
+func NewPatterns(lines []string) (*Patterns, error) {
+	p := &Patterns{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var pat pattern
+		if strings.HasPrefix(line, "!") {
+			pat.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			pat.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", line, err)
+		}
+		pat.re = re
+		p.patterns = append(p.patterns, pat)
+	}
+	return p, nil
+}
+
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// Matches returns true if the provided path (relative to the root, slash-separated) matches the patterns.
+func (p *Patterns) Matches(path string, isDir bool) bool {
+	matched := false
+	for _, pat := range p.patterns {
+		if pat.dirOnly && !isDir {
+			continue
+		}
+		if pat.re.MatchString(path) {
+			matched = !pat.negate
+		}
+	}
+	return matched
+}
+
+// Filter selects the files of a directory according to gitignore-style include and exclude patterns:
+// when include patterns are provided, only files that match (or are in a matching directory) are selected;
+// otherwise, files that match the exclude patterns (or are in a matching directory) are not selected.
+type Filter struct {
+	patterns *Patterns
+	include  bool
+}
+
+// NewFilter returns a filter for the provided patterns, or nil if no patterns are provided.
+func NewFilter(include, exclude []string) (*Filter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+	lines := make([]string, 0, len(include)+len(exclude))
+	lines = append(append(lines, include...), exclude...)
+	patterns, err := NewPatterns(lines)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{patterns: patterns, include: len(include) > 0}, nil
+}
+
+// Walk walks the directory like filepath.Walk, but only calls fn for the root and the selected files.
+// When include patterns are provided, the directories that contain selected files are also selected.
+// The directory is not modified. A nil filter selects every file.
+func (f *Filter) Walk(dir string, fn filepath.WalkFunc) error {
+	if f == nil {
+		return filepath.Walk(dir, fn)
+	}
+	type entry struct {
+		path string
+		fi   os.FileInfo
+	}
+	var (
+		pending []entry // directories that do not match the include patterns, selected once they contain a selected file
+		keptDir string  // a directory that matches the include patterns, in which every file is selected
+	)
+	selectPath := func(path string, fi os.FileInfo) error {
+		for _, dir := range pending {
+			if err := fn(dir.path, dir.fi, nil); err != nil && err != filepath.SkipDir {
+				return err
+			}
+		}
+		pending = pending[:0]
+		return fn(path, fi, nil)
+	}
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || path == dir {
+			return fn(path, fi, err)
+		}
+		if keptDir != "" {
+			if inDir(path, keptDir) {
+				return selectPath(path, fi)
+			}
+			keptDir = ""
+		}
+		for len(pending) > 0 && !inDir(path, pending[len(pending)-1].path) {
+			pending = pending[:len(pending)-1]
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		matches := f.patterns.Matches(filepath.ToSlash(rel), fi.IsDir())
+		switch {
+		case f.include && matches:
+			if fi.IsDir() {
+				keptDir = path
+			}
+			return selectPath(path, fi)
+		case f.include && fi.IsDir():
+			pending = append(pending, entry{path: path, fi: fi})
+			return nil
+		case f.include:
+			return nil
+		case matches && fi.IsDir():
+			return filepath.SkipDir
+		case matches:
+			return nil
+		}
+		return selectPath(path, fi)
+	})
+}
+
+func inDir(path, dir string) bool {
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -0,0 +1,113 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestFilter(t *testing.T) {
+	spec.Run(t, "Filter", testFilter, spec.Report(report.Terminal{}))
+}
+
+func testFilter(t *testing.T, when spec.G, it spec.S) {
+	when("#Patterns", func() {
+		it("matches gitignore-style patterns", func() {
+			patterns, err := fsutil.NewPatterns([]string{
+				"# a comment",
+				"*.log",
+				"/build/",
+				"docs/**/*.md",
+				"!keep.log",
+			})
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, patterns.Matches("app.log", false), true)
+			h.AssertEq(t, patterns.Matches("some/dir/app.log", false), true)
+			h.AssertEq(t, patterns.Matches("some/dir/keep.log", false), false)
+			h.AssertEq(t, patterns.Matches("build", true), true)
+			h.AssertEq(t, patterns.Matches("build", false), false)
+			h.AssertEq(t, patterns.Matches("src/build", true), false)
+			h.AssertEq(t, patterns.Matches("docs/README.md", false), true)
+			h.AssertEq(t, patterns.Matches("docs/a/b/README.md", false), true)
+			h.AssertEq(t, patterns.Matches("README.md", false), false)
+		})
+	})
+
+	when("#Filter", func() {
+		var tmpDir string
+
+		it.Before(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "lifecycle.test")
+			h.AssertNil(t, err)
+			for _, path := range []string{"main.go", "app.log", "src/lib.go", "src/debug.log", "tmp/cache/file"} {
+				h.AssertNil(t, os.MkdirAll(filepath.Dir(filepath.Join(tmpDir, path)), 0755))
+				h.Mkfile(t, "some-contents", filepath.Join(tmpDir, path))
+			}
+		})
+
+		it.After(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		walk := func(filter *fsutil.Filter) []string {
+			var selected []string
+			h.AssertNil(t, filter.Walk(tmpDir, func(path string, fi os.FileInfo, err error) error {
+				h.AssertNil(t, err)
+				if path != tmpDir {
+					rel, err := filepath.Rel(tmpDir, path)
+					h.AssertNil(t, err)
+					selected = append(selected, filepath.ToSlash(rel))
+				}
+				return nil
+			}))
+			return selected
+		}
+
+		it("does not select excluded files and directories", func() {
+			filter, err := fsutil.NewFilter(nil, []string{"*.log", "tmp/"})
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, walk(filter), []string{"main.go", "src", "src/lib.go"})
+		})
+
+		it("selects included files and the directories that contain them", func() {
+			filter, err := fsutil.NewFilter([]string{"*.go"}, nil)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, walk(filter), []string{"main.go", "src", "src/lib.go"})
+		})
+
+		it("selects the contents of included directories", func() {
+			filter, err := fsutil.NewFilter([]string{"/src"}, nil)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, walk(filter), []string{"src", "src/debug.log", "src/lib.go"})
+		})
+
+		it("selects every file without patterns", func() {
+			filter, err := fsutil.NewFilter(nil, nil)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, walk(filter), []string{"app.log", "main.go", "src", "src/debug.log", "src/lib.go", "tmp", "tmp/cache", "tmp/cache/file"})
+		})
+
+		it("does not modify the directory or the patterns", func() {
+			include := make([]string, 1, 2)
+			include[0] = "*.go"
+			exclude := []string{"*.log"}
+			_, err := fsutil.NewFilter(include, exclude)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, include[:cap(include)], []string{"*.go", ""})
+			h.AssertPathExists(t, filepath.Join(tmpDir, "app.log"))
+		})
+	})
+}
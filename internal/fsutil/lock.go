@@ -0,0 +1,185 @@
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultLockStaleAfter is how long after it was last refreshed a lock is considered stale.
+const DefaultLockStaleAfter = 30 * time.Second
+
+const lockRetryInterval = 100 * time.Millisecond
+
+// Lock is an advisory lock on a resource shared between processes, possibly on different hosts (e.g., a cache volume).
+// It is held by exclusively creating a lock file, which records the holder of the lock.
+// While the lock is held, the modification time of the lock file is refreshed, so that a lock file
+// left behind by a holder that did not release it (e.g., that was killed) is recognized as stale and removed.
+type Lock struct {
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// LockOptions configure the acquisition of a lock.
+type LockOptions struct {
+	// Timeout is how long to wait for another holder to release the lock.
+	Timeout time.Duration
+	// StaleAfter is how long after it was last refreshed a lock is considered stale. It defaults to DefaultLockStaleAfter.
+	StaleAfter time.Duration
+}
+
+// LockStats describe the acquisition of a lock.
+type LockStats struct {
+	// Contended is true if the lock was held by another holder.
+	Contended bool
+	// Recovered is true if a stale lock was removed.
+	Recovered bool
+	// Waited is how long it took to acquire the lock.
+	Waited time.Duration
+}
+
+// LockTimeoutError is returned when a lock is not released by its holder in time.
+type LockTimeoutError struct {
+	Path    string
+	Holder  string
+	Timeout time.Duration
+}
+
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for lock '%s' held by %s", e.Timeout, e.Path, e.Holder)
+}
+
+// AcquireLock acquires the lock with the provided lock file, waiting for another holder to release it
+// or for it to become stale.
+func AcquireLock(path string, opts LockOptions) (*Lock, LockStats, error) {
+	if opts.StaleAfter <= 0 {
+		opts.StaleAfter = DefaultLockStaleAfter
+	}
+	var stats LockStats
+	start := time.Now()
+	for {
+		acquired, err := createLockFile(path)
+		if err != nil {
+			return nil, stats, err
+		}
+		if acquired {
+			stats.Waited = time.Since(start)
+			return newLock(path, opts.StaleAfter/3), stats, nil
+		}
+		stats.Contended = true
+		removed, err := removeIfStale(path, opts.StaleAfter)
+		if err != nil {
+			return nil, stats, err
+		}
+		if removed {
+			stats.Recovered = true
+			continue
+		}
+		if time.Since(start) >= opts.Timeout {
+			stats.Waited = time.Since(start)
+			return nil, stats, &LockTimeoutError{Path: path, Holder: lockHolder(path), Timeout: opts.Timeout}
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// Release releases the lock.
+func (l *Lock) Release() error {
+	close(l.stop)
+	<-l.done
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func newLock(path string, refreshInterval time.Duration) *Lock {
+	l := &Lock{path: path, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				_ = os.Chtimes(l.path, now, now)
+			}
+		}
+	}()
+	return l
+}
+
+// createLockFile returns true if it created the lock file, recording the holder,
+// and false if the lock file already exists.
+func createLockFile(path string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666) // #nosec G302 -- lock files of shared volumes may be recovered by other users
+	if os.IsExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("creating lock file: %w", err)
+	}
+	hostname, _ := os.Hostname()
+	_, err = fmt.Fprintf(f, "pid %d on %s since %s\n", os.Getpid(), hostname, time.Now().UTC().Format(time.RFC3339))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return false, fmt.Errorf("writing lock file: %w", err)
+	}
+	return true, nil
+}
+
+// removeIfStale removes the lock file if it has not been refreshed for staleAfter, returning true if it was removed.
+// To avoid removing a lock file that was just created by another holder,
+// the lock file is first moved aside and only removed if it is still stale.
+func removeIfStale(path string, staleAfter time.Duration) (bool, error) {
+	if stale, err := isStale(path, staleAfter); err != nil || !stale {
+		return false, ignoreNotExist(err)
+	}
+	aside := fmt.Sprintf("%s.stale-%d", path, os.Getpid())
+	if err := os.Rename(path, aside); err != nil {
+		return false, ignoreNotExist(err)
+	}
+	stale, err := isStale(aside, staleAfter)
+	if err == nil && !stale {
+		// another holder acquired the lock since it was checked; restore its lock file (unless the lock was acquired again)
+		if err = os.Link(aside, path); err != nil && !os.IsExist(err) {
+			return false, fmt.Errorf("restoring lock file: %w", err)
+		}
+	}
+	if err := os.Remove(aside); err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("removing stale lock file: %w", err)
+	}
+	return stale, nil
+}
+
+func isStale(path string, staleAfter time.Duration) (bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return time.Since(fi.ModTime()) > staleAfter, nil
+}
+
+func lockHolder(path string) string {
+	contents, err := os.ReadFile(path)
+	if err != nil || len(contents) == 0 {
+		return "another process"
+	}
+	return strings.TrimSpace(string(contents))
+}
+
+func ignoreNotExist(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
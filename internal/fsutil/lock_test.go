@@ -0,0 +1,104 @@
+package fsutil_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestLock(t *testing.T) {
+	spec.Run(t, "Lock", testLock, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testLock(t *testing.T, when spec.G, it spec.S) {
+	var lockPath string
+
+	it.Before(func() {
+		lockPath = filepath.Join(t.TempDir(), ".lock")
+	})
+
+	when("#AcquireLock", func() {
+		it("creates the lock file until the lock is released", func() {
+			lock, stats, err := fsutil.AcquireLock(lockPath, fsutil.LockOptions{})
+			h.AssertNil(t, err)
+			h.AssertEq(t, stats.Contended, false)
+
+			contents, err := os.ReadFile(lockPath)
+			h.AssertNil(t, err)
+			h.AssertStringContains(t, string(contents), fmt.Sprintf("pid %d on ", os.Getpid()))
+
+			h.AssertNil(t, lock.Release())
+			h.AssertPathDoesNotExist(t, lockPath)
+		})
+
+		when("the lock is held", func() {
+			var held *fsutil.Lock
+
+			it.Before(func() {
+				var err error
+				held, _, err = fsutil.AcquireLock(lockPath, fsutil.LockOptions{})
+				h.AssertNil(t, err)
+			})
+
+			it("times out with the holder of the lock", func() {
+				_, stats, err := fsutil.AcquireLock(lockPath, fsutil.LockOptions{Timeout: 200 * time.Millisecond})
+				var timeoutErr *fsutil.LockTimeoutError
+				h.AssertEq(t, errors.As(err, &timeoutErr), true)
+				h.AssertStringContains(t, timeoutErr.Holder, fmt.Sprintf("pid %d on ", os.Getpid()))
+				h.AssertEq(t, stats.Contended, true)
+				h.AssertEq(t, stats.Recovered, false)
+				h.AssertNil(t, held.Release())
+			})
+
+			it("waits for the lock to be released", func() {
+				go func() {
+					time.Sleep(200 * time.Millisecond)
+					_ = held.Release()
+				}()
+
+				lock, stats, err := fsutil.AcquireLock(lockPath, fsutil.LockOptions{Timeout: 10 * time.Second})
+				h.AssertNil(t, err)
+				h.AssertEq(t, stats.Contended, true)
+				h.AssertEq(t, stats.Waited >= 100*time.Millisecond, true)
+				h.AssertNil(t, lock.Release())
+			})
+
+			it("refreshes the lock so that it never becomes stale", func() {
+				h.AssertNil(t, held.Release())
+				opts := fsutil.LockOptions{Timeout: time.Second, StaleAfter: 300 * time.Millisecond}
+				lock, _, err := fsutil.AcquireLock(lockPath, opts)
+				h.AssertNil(t, err)
+
+				_, stats, err := fsutil.AcquireLock(lockPath, opts)
+				h.AssertNotNil(t, err)
+				h.AssertEq(t, stats.Recovered, false)
+				h.AssertNil(t, lock.Release())
+			})
+		})
+
+		when("the lock is stale", func() {
+			it.Before(func() {
+				h.Mkfile(t, "pid 1 on some-host", lockPath)
+				past := time.Now().Add(-time.Hour)
+				h.AssertNil(t, os.Chtimes(lockPath, past, past))
+			})
+
+			it("recovers the lock", func() {
+				lock, stats, err := fsutil.AcquireLock(lockPath, fsutil.LockOptions{Timeout: time.Second})
+				h.AssertNil(t, err)
+				h.AssertEq(t, stats.Contended, true)
+				h.AssertEq(t, stats.Recovered, true)
+				h.AssertNil(t, lock.Release())
+			})
+		})
+	})
+}
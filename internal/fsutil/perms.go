@@ -0,0 +1,44 @@
+package fsutil
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const setuidBits = fs.ModeSetuid | fs.ModeSetgid
+
+// StripSetuid removes the setuid and setgid bits from the regular files in the provided directories
+// (which are skipped if they do not exist), and returns the number of files that were changed.
+func StripSetuid(dirs ...string) (int, error) {
+	var stripped int
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.Mode()&setuidBits == 0 {
+				return nil
+			}
+			if err = os.Chmod(path, info.Mode()&^setuidBits); err != nil {
+				return err
+			}
+			stripped++
+			return nil
+		})
+		if err != nil {
+			return stripped, err
+		}
+	}
+	return stripped, nil
+}
@@ -0,0 +1,58 @@
+//go:build !windows
+
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestPerms(t *testing.T) {
+	spec.Run(t, "Perms", testPerms, spec.Report(report.Terminal{}))
+}
+
+func testPerms(t *testing.T, when spec.G, it spec.S) {
+	when("#StripSetuid", func() {
+		var appDir, layersDir string
+
+		it.Before(func() {
+			appDir = t.TempDir()
+			layersDir = t.TempDir()
+			h.Mkdir(t, filepath.Join(appDir, "bin"))
+			h.Mkfile(t, "some-contents", filepath.Join(appDir, "bin", "setuid"), filepath.Join(layersDir, "setgid"), filepath.Join(appDir, "plain"))
+			h.AssertNil(t, os.Chmod(filepath.Join(appDir, "bin", "setuid"), 0755|os.ModeSetuid))
+			h.AssertNil(t, os.Chmod(filepath.Join(layersDir, "setgid"), 0750|os.ModeSetgid))
+			h.AssertNil(t, os.Chmod(filepath.Join(appDir, "plain"), 0644))
+		})
+
+		assertMode := func(path string, expected os.FileMode) {
+			t.Helper()
+			fi, err := os.Stat(path)
+			h.AssertNil(t, err)
+			h.AssertEq(t, fi.Mode(), expected)
+		}
+
+		it("removes the setuid and setgid bits and keeps the permissions", func() {
+			stripped, err := fsutil.StripSetuid(appDir, layersDir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, stripped, 2)
+
+			assertMode(filepath.Join(appDir, "bin", "setuid"), 0755)
+			assertMode(filepath.Join(layersDir, "setgid"), 0750)
+			assertMode(filepath.Join(appDir, "plain"), 0644)
+		})
+
+		it("skips directories that do not exist", func() {
+			stripped, err := fsutil.StripSetuid(filepath.Join(appDir, "missing"))
+			h.AssertNil(t, err)
+			h.AssertEq(t, stripped, 0)
+		})
+	})
+}
@@ -0,0 +1,36 @@
+package fsutil
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// StreamBufferSize is the size of the buffers used to stream the contents of files, e.g., into layer tarballs and hashes.
+// It is larger than the 32 KiB buffers of io.Copy, so that exporting images with many large binaries takes fewer syscalls.
+const StreamBufferSize = 1 << 20
+
+var streamBuffers = sync.Pool{New: func() interface{} {
+	b := make([]byte, StreamBufferSize)
+	return &b
+}}
+
+// OpenSequential opens the file at path to read it once, from start to end (e.g., to add it to a layer tarball).
+// On Linux, the kernel is advised that the file is read sequentially, so that it reads ahead more aggressively.
+func OpenSequential(path string) (*os.File, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	adviseSequential(f)
+	return f, nil
+}
+
+// Stream copies src to dst (e.g., a file to a tar writer or a hash), returning the number of bytes copied.
+// The contents are copied with a pooled buffer of StreamBufferSize, so that streaming many files does not allocate a buffer for each.
+func Stream(dst io.Writer, src io.Reader) (int64, error) {
+	buf := streamBuffers.Get().(*[]byte)
+	defer streamBuffers.Put(buf)
+	// dst and src are wrapped so that io.CopyBuffer uses the buffer, rather than any ReadFrom or WriteTo method with a smaller buffer
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, *buf)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package fsutil
+
+import "os"
+
+// adviseSequential does nothing, as advising the kernel about file access is only supported on Linux.
+func adviseSequential(_ *os.File) {}
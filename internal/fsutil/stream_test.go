@@ -0,0 +1,81 @@
+package fsutil_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestStream(t *testing.T) {
+	spec.Run(t, "Stream", testStream, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testStream(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir  string
+		src     string
+		content string
+	)
+
+	it.Before(func() {
+		tmpDir = t.TempDir()
+		src = filepath.Join(tmpDir, "src")
+		// larger than the stream buffer, so that it is copied in several chunks
+		content = strings.Repeat("some-content", fsutil.StreamBufferSize/4)
+		h.Mkfile(t, content, src)
+	})
+
+	when("#Stream", func() {
+		it("copies a file to a file", func() {
+			in, err := fsutil.OpenSequential(src)
+			h.AssertNil(t, err)
+			defer in.Close()
+			out, err := os.Create(filepath.Join(tmpDir, "dst"))
+			h.AssertNil(t, err)
+
+			n, err := fsutil.Stream(out, in)
+			h.AssertNil(t, err)
+			h.AssertNil(t, out.Close())
+			h.AssertEq(t, n, int64(len(content)))
+			h.AssertEq(t, h.Rdfile(t, filepath.Join(tmpDir, "dst")), content)
+		})
+
+		it("copies a file to a writer", func() {
+			in, err := fsutil.OpenSequential(src)
+			h.AssertNil(t, err)
+			defer in.Close()
+			hasher := sha256.New()
+
+			n, err := fsutil.Stream(hasher, in)
+			h.AssertNil(t, err)
+			h.AssertEq(t, n, int64(len(content)))
+			expected := sha256.Sum256([]byte(content))
+			h.AssertEq(t, hasher.Sum(nil), expected[:])
+		})
+
+		it("copies a reader to a writer", func() {
+			out := &bytes.Buffer{}
+
+			n, err := fsutil.Stream(out, strings.NewReader(content))
+			h.AssertNil(t, err)
+			h.AssertEq(t, n, int64(len(content)))
+			h.AssertEq(t, out.String(), content)
+		})
+	})
+
+	when("#OpenSequential", func() {
+		it("errors if the file does not exist", func() {
+			_, err := fsutil.OpenSequential(filepath.Join(tmpDir, "missing"))
+			h.AssertNotNil(t, err)
+		})
+	})
+}
@@ -0,0 +1,113 @@
+package fsutil
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// SymlinkPolicy determines how symlinks in a directory that point outside of it are handled.
+type SymlinkPolicy string
+
+const (
+	// SymlinkPreserve keeps the symlinks as they are (they may dangle in an image).
+	SymlinkPreserve SymlinkPolicy = "preserve"
+	// SymlinkFollow replaces the symlinks with copies of their targets.
+	SymlinkFollow SymlinkPolicy = "follow"
+	// SymlinkError rejects the symlinks.
+	SymlinkError SymlinkPolicy = "error"
+)
+
+// ExternalSymlinks returns the symlinks in dir whose targets are outside of it, relative to dir (with forward slashes), sorted.
+func ExternalSymlinks(dir string) ([]string, error) {
+	roots := []string{filepath.Clean(dir)}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil && resolved != roots[0] {
+		roots = append(roots, resolved)
+	}
+	var links []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&os.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		if isWithin(filepath.Clean(target), roots) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		links = append(links, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(links)
+	return links, err
+}
+
+func isWithin(path string, roots []string) bool {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplySymlinkPolicy applies the policy to the symlinks in dir whose targets are outside of it, and returns their paths (see ExternalSymlinks).
+// An empty policy is equivalent to SymlinkPreserve, in which case the directory is not walked.
+func ApplySymlinkPolicy(dir string, policy SymlinkPolicy) ([]string, error) {
+	switch policy {
+	case "", SymlinkPreserve:
+		return nil, nil
+	case SymlinkFollow, SymlinkError:
+	default:
+		return nil, fmt.Errorf("unsupported symlink policy '%s'", policy)
+	}
+	links, err := ExternalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	if policy == SymlinkError && len(links) > 0 {
+		return links, fmt.Errorf("symlink '%s' points outside of '%s'", links[0], dir)
+	}
+	for _, link := range links {
+		if err = followSymlink(filepath.Join(dir, filepath.FromSlash(link))); err != nil {
+			return links, err
+		}
+	}
+	return links, nil
+}
+
+// followSymlink replaces the symlink at path with a copy of its (fully resolved) target, preserving its modes.
+func followSymlink(path string) error {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("following symlink '%s': %w", path, err)
+	}
+	fi, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		_, err = CopyDirConcurrently(target, path, nil, runtime.NumCPU())
+		return err
+	}
+	return copyFileWithInfo(target, path, fi)
+}
@@ -0,0 +1,106 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestSymlinks(t *testing.T) {
+	spec.Run(t, "Symlinks", testSymlinks, spec.Report(report.Terminal{}))
+}
+
+func testSymlinks(t *testing.T, when spec.G, it spec.S) {
+	var (
+		appDir     string
+		outsideDir string
+	)
+
+	it.Before(func() {
+		h.SkipIf(t, runtime.GOOS == "windows", "symlinks require privileges on windows")
+		tmpDir := t.TempDir()
+		appDir = filepath.Join(tmpDir, "workspace")
+		outsideDir = filepath.Join(tmpDir, "shared")
+		h.AssertNil(t, os.MkdirAll(filepath.Join(appDir, "src"), 0755))
+		h.AssertNil(t, os.MkdirAll(filepath.Join(outsideDir, "config"), 0755))
+		h.Mkfile(t, "some-app-contents", filepath.Join(appDir, "src", "main.go"))
+		h.Mkfile(t, "some-shared-contents", filepath.Join(outsideDir, "shared.txt"))
+		h.Mkfile(t, "some-config-contents", filepath.Join(outsideDir, "config", "app.toml"))
+
+		h.AssertNil(t, os.Symlink(filepath.Join(outsideDir, "shared.txt"), filepath.Join(appDir, "shared.txt")))
+		h.AssertNil(t, os.Symlink(filepath.Join("..", "..", "shared", "config"), filepath.Join(appDir, "src", "config")))
+		h.AssertNil(t, os.Symlink(filepath.Join("src", "main.go"), filepath.Join(appDir, "main.go")))
+	})
+
+	when("#ExternalSymlinks", func() {
+		it("returns the symlinks pointing outside of the directory", func() {
+			links, err := fsutil.ExternalSymlinks(appDir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, links, []string{"shared.txt", "src/config"})
+		})
+	})
+
+	when("#ApplySymlinkPolicy", func() {
+		it("preserves the symlinks by default", func() {
+			for _, policy := range []fsutil.SymlinkPolicy{"", fsutil.SymlinkPreserve} {
+				links, err := fsutil.ApplySymlinkPolicy(appDir, policy)
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(links), 0)
+				assertSymlink(t, filepath.Join(appDir, "shared.txt"))
+				assertSymlink(t, filepath.Join(appDir, "src", "config"))
+			}
+		})
+
+		it("errors for symlinks pointing outside of the directory when the policy is error", func() {
+			_, err := fsutil.ApplySymlinkPolicy(appDir, fsutil.SymlinkError)
+			h.AssertError(t, err, "symlink 'shared.txt' points outside of '"+appDir+"'")
+		})
+
+		it("replaces the symlinks with copies of their targets when the policy is follow", func() {
+			links, err := fsutil.ApplySymlinkPolicy(appDir, fsutil.SymlinkFollow)
+			h.AssertNil(t, err)
+			h.AssertEq(t, links, []string{"shared.txt", "src/config"})
+
+			h.AssertEq(t, string(h.MustReadFile(t, filepath.Join(appDir, "shared.txt"))), "some-shared-contents")
+			h.AssertEq(t, string(h.MustReadFile(t, filepath.Join(appDir, "src", "config", "app.toml"))), "some-config-contents")
+			for _, path := range []string{"shared.txt", "src/config"} {
+				fi, err := os.Lstat(filepath.Join(appDir, path))
+				h.AssertNil(t, err)
+				h.AssertEq(t, fi.Mode()&os.ModeSymlink, os.FileMode(0))
+			}
+			// the targets are left in place
+			h.AssertPathExists(t, filepath.Join(outsideDir, "shared.txt"))
+		})
+
+		it("keeps symlinks pointing inside of the directory", func() {
+			h.AssertNil(t, os.Remove(filepath.Join(appDir, "shared.txt")))
+			h.AssertNil(t, os.Remove(filepath.Join(appDir, "src", "config")))
+
+			for _, policy := range []fsutil.SymlinkPolicy{fsutil.SymlinkError, fsutil.SymlinkFollow} {
+				links, err := fsutil.ApplySymlinkPolicy(appDir, policy)
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(links), 0)
+				assertSymlink(t, filepath.Join(appDir, "main.go"))
+			}
+		})
+
+		it("errors for unsupported policies", func() {
+			_, err := fsutil.ApplySymlinkPolicy(appDir, "some-policy")
+			h.AssertError(t, err, "unsupported symlink policy 'some-policy'")
+		})
+	})
+}
+
+func assertSymlink(t *testing.T, path string) {
+	t.Helper()
+	fi, err := os.Lstat(path)
+	h.AssertNil(t, err)
+	h.AssertEq(t, fi.Mode()&os.ModeSymlink, os.ModeSymlink)
+}
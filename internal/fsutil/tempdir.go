@@ -0,0 +1,44 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// SetTempDir configures the directory returned by os.TempDir for the current process, and for any processes it starts
+// that inherit the environment, creating the directory if needed.
+// A newly created directory is writable by all users (like /tmp), as phases may drop privileges after it is created.
+func SetTempDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		if err = os.Chmod(dir, os.ModePerm|os.ModeSticky); err != nil {
+			return fmt.Errorf("failed to set permissions on temporary directory: %w", err)
+		}
+	} else if err != nil {
+		return err
+	}
+	for _, key := range tempDirEnvKeys() {
+		if err = os.Setenv(key, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tempDirEnvKeys() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"TMP", "TEMP"}
+	}
+	return []string{"TMPDIR"}
+}
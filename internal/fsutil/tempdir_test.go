@@ -0,0 +1,69 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/fsutil"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestTempDir(t *testing.T) {
+	spec.Run(t, "TempDir", testTempDir, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testTempDir(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir      string
+		originalEnv = map[string]string{}
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle.test")
+		h.AssertNil(t, err)
+		for _, key := range []string{"TMPDIR", "TMP", "TEMP"} {
+			originalEnv[key] = os.Getenv(key)
+		}
+	})
+
+	it.After(func() {
+		for key, val := range originalEnv {
+			_ = os.Setenv(key, val)
+		}
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	when("#SetTempDir", func() {
+		it("creates the directory and uses it for temporary files", func() {
+			dir := filepath.Join(tmpDir, "some", "tmp")
+
+			h.AssertNil(t, fsutil.SetTempDir(dir))
+
+			h.AssertEq(t, os.TempDir(), dir)
+			fi, err := os.Stat(dir)
+			h.AssertNil(t, err)
+			if runtime.GOOS != "windows" {
+				h.AssertEq(t, fi.Mode().Perm(), os.ModePerm)
+				h.AssertEq(t, fi.Mode()&os.ModeSticky != 0, true)
+			}
+			f, err := os.CreateTemp("", "some-file")
+			h.AssertNil(t, err)
+			h.AssertNil(t, f.Close())
+			h.AssertEq(t, filepath.Dir(f.Name()), dir)
+		})
+
+		it("does nothing when the directory is not provided", func() {
+			before := os.TempDir()
+
+			h.AssertNil(t, fsutil.SetTempDir(""))
+
+			h.AssertEq(t, os.TempDir(), before)
+		})
+	})
+}
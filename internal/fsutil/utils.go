@@ -8,7 +8,20 @@ import (
 	"strings"
 )
 
+// Copy copies src to dst, recursively for directories.
+// Regular files are cloned rather than copied on filesystems that support reflinks (e.g., XFS or btrfs),
+// so that copying large files is nearly free and uses no additional disk space until either copy is modified.
 func Copy(src, dst string) error {
+	return copyPath(src, dst, false)
+}
+
+// LinkOrCopy is like Copy, but hard links regular files when src and dst are on the same filesystem.
+// It must only be used for files that are never modified in place afterwards, at either location.
+func LinkOrCopy(src, dst string) error {
+	return copyPath(src, dst, true)
+}
+
+func copyPath(src, dst string, link bool) error {
 	fi, err := os.Lstat(src)
 	if err != nil {
 		return err
@@ -16,7 +29,11 @@ func Copy(src, dst string) error {
 
 	switch {
 	case fi.Mode().IsDir():
-		if err := copyDir(src, dst); err != nil {
+		if err := copyDir(src, dst, link); err != nil {
+			return err
+		}
+	case fi.Mode().IsRegular() && link:
+		if err := linkFile(src, dst); err != nil {
 			return err
 		}
 	case fi.Mode().IsRegular():
@@ -33,7 +50,7 @@ func Copy(src, dst string) error {
 	return nil
 }
 
-func copyDir(src, dst string) error {
+func copyDir(src, dst string, link bool) error {
 	if err := os.MkdirAll(dst, 0755); err != nil {
 		return err
 	}
@@ -46,7 +63,7 @@ func copyDir(src, dst string) error {
 	for _, child := range children {
 		srcPath := filepath.Join(src, child.Name())
 		dstPath := filepath.Join(dst, child.Name())
-		if err := Copy(srcPath, dstPath); err != nil {
+		if err := copyPath(srcPath, dstPath, link); err != nil {
 			return err
 		}
 	}
@@ -86,10 +103,24 @@ func copyFile(src, dst string) error {
 	}
 	defer out.Close()
 
+	if reflink(out, in) {
+		return nil
+	}
 	_, err = io.Copy(out, in)
 	return err
 }
 
+func linkFile(src, dst string) error {
+	// remove dst first, so that an existing link to src is never truncated by falling back to a copy
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
+
 func copySymlink(src, dst string) error {
 	target, err := os.Readlink(src)
 	if err != nil {
@@ -104,7 +135,7 @@ func RenameWithWindowsFallback(src, dst string) error {
 		case runtime.GOOS == "windows":
 			// On Windows, when using process isolation, we could encounter https://github.com/moby/moby/issues/38256
 			// which causes renames inside mounted volumes to fail for an unknown reason.
-			if err = copyDir(src, dst); err != nil {
+			if err = copyDir(src, dst, false); err != nil {
 				return err
 			}
 			return os.RemoveAll(src)
@@ -70,6 +70,47 @@ func testIO(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#LinkOrCopy", func() {
+		it("links source files to destination", func() {
+			src := filepath.Join(tmpDir, "src.txt")
+			dst := filepath.Join(tmpDir, "dest.txt")
+			h.Mkfile(t, "some-file-content", src)
+
+			h.AssertNil(t, fsutil.LinkOrCopy(src, dst))
+
+			srcInfo, err := os.Stat(src)
+			h.AssertNil(t, err)
+			dstInfo, err := os.Stat(dst)
+			h.AssertNil(t, err)
+			h.AssertEq(t, os.SameFile(srcInfo, dstInfo), true)
+		})
+
+		it("does not truncate an existing link to the source", func() {
+			src := filepath.Join(tmpDir, "src.txt")
+			dst := filepath.Join(tmpDir, "dest.txt")
+			h.Mkfile(t, "some-file-content", src)
+			h.AssertNil(t, os.Link(src, dst))
+
+			h.AssertNil(t, fsutil.LinkOrCopy(src, dst))
+
+			h.AssertEq(t, string(h.MustReadFile(t, src)), "some-file-content")
+			h.AssertEq(t, string(h.MustReadFile(t, dst)), "some-file-content")
+		})
+
+		it("copies source directories, linking files where possible", func() {
+			src := filepath.Join("testdata", "some_dir")
+			dst := filepath.Join(tmpDir, "dest_dir")
+
+			h.AssertNil(t, fsutil.LinkOrCopy(src, dst))
+
+			contents := h.MustReadFile(t, filepath.Join(dst, "some_file"))
+			h.AssertEq(t, string(contents), "some-content\n")
+			target, err := os.Readlink(filepath.Join(dst, "some_link"))
+			h.AssertNil(t, err)
+			h.AssertEq(t, target, "some_file")
+		})
+	})
+
 	when("#RenameWithWindowsFallback", func() {
 		when("directory does not exist", func() {
 			it("returns not exist error", func() {
@@ -78,6 +78,19 @@ func (r *DefaultMetadataRestorer) restoreLayerMetadata(layerSHAStore SHAStore, a
 				r.Logger.Debugf("Not restoring metadata for %q, marked as launch=false", identifier)
 				continue
 			}
+			if layer.Squashed {
+				// The layer was squashed with other layers in the app image, so it cannot be reused on its own.
+				// If it is cache=true, its metadata (and SHA) is restored from the cache, so that the restorer restores its contents.
+				if cacheLayer, ok := cachedLayers[layerName]; ok && layer.Cache && cacheLayer.Cache {
+					r.Logger.Infof("Restoring metadata for %q from cache", identifier)
+					if err := r.writeLayerMetadata(layerSHAStore, buildpackDir, layerName, cacheLayer, bp.ID); err != nil {
+						return err
+					}
+					continue
+				}
+				r.Logger.Debugf("Not restoring metadata for %q, squashed with other layers", identifier)
+				continue
+			}
 			if layer.Build && !layer.Cache {
 				// layer is launch=true, build=true. Because build=true, the layer contents must be present in the build container.
 				// There is no reason to restore the metadata file, because the buildpack will always recreate the layer.
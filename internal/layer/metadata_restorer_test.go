@@ -317,6 +317,36 @@ func testLayerMetadataRestorer(t *testing.T, when spec.G, it spec.S) {
 				}
 			})
 
+			when("layers were squashed with other layers in the app image", func() {
+				it.Before(func() {
+					bpLayers := layersMetadata.LayersMetadataFor("metadata.buildpack").Layers
+					for _, name := range []string{"launch", "launch-cache"} {
+						squashed := bpLayers[name]
+						squashed.SHA = "squashed-sha"
+						squashed.Squashed = true
+						bpLayers[name] = squashed
+					}
+				})
+
+				it("does not restore launch=true, cache=false layer metadata", func() {
+					err := layerMetadataRestorer.Restore(buildpacks, layersMetadata, cacheMetadata, layerSHAStore)
+					h.AssertNil(t, err)
+
+					h.AssertPathDoesNotExist(t, filepath.Join(layerDir, "metadata.buildpack", "launch.toml"))
+					h.AssertPathDoesNotExist(t, filepath.Join(layerDir, "metadata.buildpack", "launch.sha"))
+				})
+
+				it("restores cache=true layer metadata from the cache", func() {
+					err := layerMetadataRestorer.Restore(buildpacks, layersMetadata, cacheMetadata, layerSHAStore)
+					h.AssertNil(t, err)
+
+					got := h.MustReadFile(t, filepath.Join(layerDir, "metadata.buildpack", "launch-cache.toml"))
+					h.AssertStringContains(t, string(got), "launch-cache-key = \"cache-specific-value\"")
+					got = h.MustReadFile(t, filepath.Join(layerDir, "metadata.buildpack", "launch-cache.sha"))
+					h.AssertEq(t, string(got), "launch-cache-old-sha")
+				})
+			})
+
 			when("app and cache metadata are inconsistent with each other", func() { // cache was manipulated or deleted
 				it.Before(func() {
 					metadata := h.MustReadFile(t, filepath.Join("testdata", "cache_inconsistent_metadata.json"))
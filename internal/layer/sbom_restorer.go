@@ -13,11 +13,13 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/archive"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/internal/fsutil"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform"
 )
 
 //go:generate mockgen -package testmock -destination ../../testmock/sbom_restorer.go github.com/buildpacks/lifecycle/internal/layer SBOMRestorer
@@ -32,9 +34,10 @@ type Cache interface {
 }
 
 type SBOMRestorerOpts struct {
-	LayersDir string
-	Logger    log.Logger
-	Nop       bool
+	LayersDir         string
+	Logger            log.Logger
+	Nop               bool
+	LenientExtraction bool
 }
 
 func NewSBOMRestorer(opts SBOMRestorerOpts, platformAPI *api.Version) SBOMRestorer {
@@ -42,14 +45,18 @@ func NewSBOMRestorer(opts SBOMRestorerOpts, platformAPI *api.Version) SBOMRestor
 		return &NopSBOMRestorer{}
 	}
 	return &DefaultSBOMRestorer{
-		LayersDir: opts.LayersDir,
-		Logger:    opts.Logger,
+		LayersDir:         opts.LayersDir,
+		Logger:            opts.Logger,
+		LenientExtraction: opts.LenientExtraction,
 	}
 }
 
 type DefaultSBOMRestorer struct {
 	LayersDir string
 	Logger    log.Logger
+	// LenientExtraction skips the entries of SBOM layers that would be written outside of the layers directory,
+	// rather than failing the restore.
+	LenientExtraction bool
 }
 
 func (r *DefaultSBOMRestorer) RestoreFromPrevious(image imgutil.Image, layerDigest string) error {
@@ -70,7 +77,29 @@ func (r *DefaultSBOMRestorer) RestoreFromPrevious(image imgutil.Image, layerDige
 	}
 	defer rc.Close()
 
-	return layers.Extract(rc, "")
+	if err = r.extract(rc); err != nil {
+		return err
+	}
+	r.savePreviousSBOM()
+	return nil
+}
+
+// savePreviousSBOM merges the launch SBOM files from the previous image into <layers>/previous-sbom.cdx.json,
+// so that the exporter can report the changes in the components of the image.
+// It is called by RestoreFromPrevious, which only the analyzer uses.
+// As the file is informational, any errors are logged rather than returned.
+func (r *DefaultSBOMRestorer) savePreviousSBOM() {
+	sources, err := buildpack.SBOMSourcesIn(filepath.Join(r.LayersDir, "sbom", "launch"), nil)
+	if err != nil || len(sources) == 0 {
+		return
+	}
+	contents, err := buildpack.MergeSBOMs(sources, "previous")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(r.LayersDir, platform.DefaultPreviousSBOMFile), contents, 0600)
+	}
+	if err != nil {
+		r.Logger.Warnf("Failed to save SBOM of previous image: %s", err)
+	}
 }
 
 func (r *DefaultSBOMRestorer) RestoreFromCache(cache Cache, layerDigest string) error {
@@ -86,7 +115,19 @@ func (r *DefaultSBOMRestorer) RestoreFromCache(cache Cache, layerDigest string)
 	}
 	defer rc.Close()
 
-	return layers.Extract(rc, "")
+	return r.extract(rc)
+}
+
+// extract extracts the SBOM layer, which must be within the layers directory.
+func (r *DefaultSBOMRestorer) extract(rc io.Reader) error {
+	report, err := layers.ExtractWithOptions(rc, "", archive.ExtractOptions{Root: r.LayersDir, Lenient: r.LenientExtraction})
+	if err != nil {
+		return err
+	}
+	for _, rejection := range report.Rejections {
+		r.Logger.Warnf("Skipped unsafe entry of SBOM layer: %s", rejection)
+	}
+	return nil
 }
 
 func (r *DefaultSBOMRestorer) RestoreToBuildpackLayers(detectedBps []buildpack.GroupElement) error {
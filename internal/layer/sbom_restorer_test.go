@@ -118,6 +118,27 @@ func testSBOMRestorer(t *testing.T, when spec.G, it spec.S) {
 			h.AssertEq(t, string(got), want)
 		})
 
+		when("the layer contains buildpack SBOM files", func() {
+			it.Before(func() {
+				sbomDir := filepath.Join(layersDir, "sbom", "launch", "some-buildpack")
+				h.Mkdir(t, sbomDir)
+				h.Mkfile(t, `{"components": [{"type": "library", "name": "some-dep", "version": "1.0"}]}`, filepath.Join(sbomDir, "sbom.cdx.json"))
+				factory := &layers.Factory{ArtifactsDir: artifactsDir}
+				layer, err := factory.DirLayer("launch.sbom", filepath.Join(layersDir, "sbom", "launch"), "")
+				h.AssertNil(t, err)
+				layerDigest = layer.Digest
+				h.AssertNil(t, image.AddLayerWithDiffID(layer.TarPath, layer.Digest))
+				h.AssertNil(t, os.RemoveAll(filepath.Join(layersDir, "sbom")))
+			})
+
+			it("saves the merged SBOM of the previous image", func() {
+				h.AssertNil(t, sbomRestorer.RestoreFromPrevious(image, layerDigest))
+
+				got := h.MustReadFile(t, filepath.Join(layersDir, "previous-sbom.cdx.json"))
+				h.AssertStringContains(t, string(got), `"name": "some-dep"`)
+			})
+		})
+
 		when("image is empty", func() {
 			it("errors", func() {
 				h.AssertError(t,
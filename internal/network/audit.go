@@ -0,0 +1,182 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvRegistryAuditLog is the location of a file to which every registry request is appended as a JSON line (see AuditEntry).
+// Each phase appends to the file, so that a single file can account for all of the registry requests made during a build.
+const EnvRegistryAuditLog = "CNB_REGISTRY_AUDIT_LOG"
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+	AuditOutcomeError   = "error"
+)
+
+// AuditConfig is the configuration for the registry audit log.
+type AuditConfig struct {
+	// Path is the location of the audit log. If empty, registry requests are not audited.
+	Path string
+	// Phase is the phase making the requests, recorded in each entry.
+	Phase string
+}
+
+// AuditConfigFromEnv returns the audit log configuration from the provided environment lookup function.
+func AuditConfigFromEnv(getenv func(string) string) AuditConfig {
+	return AuditConfig{Path: getenv(EnvRegistryAuditLog)}
+}
+
+// AuditEntry describes a registry request.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Phase      string    `json:"phase,omitempty"`
+	Method     string    `json:"method"`
+	Registry   string    `json:"registry"`
+	Repository string    `json:"repository,omitempty"`
+	// Digest is the digest of the manifest or blob that was requested, if known.
+	Digest        string        `json:"digest,omitempty"`
+	BytesSent     int64         `json:"bytes-sent"`
+	BytesReceived int64         `json:"bytes-received"`
+	Duration      time.Duration `json:"duration-ns"`
+	StatusCode    int           `json:"status-code,omitempty"`
+	// Outcome is success (2xx or 3xx), failure (any other status) or error (no response, or the response body could not be read).
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewAuditTransport returns a transport that appends an entry to the audit log for every request.
+// Entries are written once the response body is closed (or fully read), so that the duration and the bytes received
+// account for the whole download.
+func NewAuditTransport(inner http.RoundTripper, w io.Writer, phase string) http.RoundTripper {
+	return &auditTransport{inner: inner, w: w, phase: phase}
+}
+
+type auditTransport struct {
+	inner http.RoundTripper
+	phase string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := AuditEntry{
+		Time:     time.Now(),
+		Phase:    t.phase,
+		Method:   req.Method,
+		Registry: req.URL.Host,
+	}
+	entry.Repository, entry.Digest = parseRegistryPath(req.URL.Path)
+	if entry.Digest == "" {
+		// blob uploads are completed (or mounted) by digest
+		query := req.URL.Query()
+		entry.Digest = query.Get("digest")
+		if entry.Digest == "" {
+			entry.Digest = query.Get("mount")
+		}
+	}
+	if req.ContentLength > 0 {
+		entry.BytesSent = req.ContentLength
+	}
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		entry.Outcome = AuditOutcomeError
+		entry.Error = err.Error()
+		entry.Duration = time.Since(entry.Time)
+		t.write(entry)
+		return resp, err
+	}
+	entry.StatusCode = resp.StatusCode
+	entry.Outcome = AuditOutcomeSuccess
+	if resp.StatusCode >= http.StatusBadRequest {
+		entry.Outcome = AuditOutcomeFailure
+	}
+	if entry.Digest == "" {
+		entry.Digest = resp.Header.Get("Docker-Content-Digest")
+	}
+	resp.Body = &auditBody{ReadCloser: resp.Body, entry: entry, transport: t}
+	return resp, nil
+}
+
+func (t *auditTransport) write(entry AuditEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// the audit log must not fail registry requests
+	_, _ = t.w.Write(append(line, '\n'))
+}
+
+// auditBody counts the bytes received and writes the audit entry when the body is fully read or closed.
+type auditBody struct {
+	io.ReadCloser
+	entry     AuditEntry
+	transport *auditTransport
+	once      sync.Once
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.entry.BytesReceived += int64(n)
+	switch {
+	case err == io.EOF:
+		b.done(nil)
+	case err != nil:
+		b.done(err)
+	}
+	return n, err
+}
+
+func (b *auditBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done(nil)
+	return err
+}
+
+func (b *auditBody) done(err error) {
+	b.once.Do(func() {
+		b.entry.Duration = time.Since(b.entry.Time)
+		if err != nil {
+			b.entry.Outcome = AuditOutcomeError
+			b.entry.Error = err.Error()
+		}
+		b.transport.write(b.entry)
+	})
+}
+
+// parseRegistryPath returns the repository and (if the request is for a specific digest) the digest
+// from the path of a registry API request, e.g., /v2/<repository>/blobs/<digest>.
+func parseRegistryPath(path string) (string, string) {
+	path = strings.TrimPrefix(path, "/v2/")
+	for _, kind := range []string{"/manifests/", "/blobs/uploads/", "/blobs/", "/tags/", "/referrers/"} {
+		idx := strings.LastIndex(path, kind)
+		if idx < 0 {
+			continue
+		}
+		repository, ref := path[:idx], path[idx+len(kind):]
+		if strings.Contains(ref, ":") && !strings.Contains(ref, "/") {
+			return repository, ref
+		}
+		return repository, ""
+	}
+	return "", ""
+}
+
+// openAuditLog opens the audit log for appending.
+func openAuditLog(path string) (io.Writer, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644) // #nosec G302 G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to open registry audit log: %w", err)
+	}
+	return f, nil
+}
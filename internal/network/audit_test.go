@@ -0,0 +1,134 @@
+package network_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/network"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestAudit(t *testing.T) {
+	spec.Run(t, "Audit", testAudit, spec.Report(report.Terminal{}))
+}
+
+func testAudit(t *testing.T, when spec.G, it spec.S) {
+	var (
+		auditLog  *bytes.Buffer
+		transport http.RoundTripper
+		host      string
+	)
+
+	it.Before(func() {
+		server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+		t.Cleanup(server.Close)
+		host = strings.TrimPrefix(server.URL, "http://")
+		auditLog = &bytes.Buffer{}
+		transport = network.NewAuditTransport(http.DefaultTransport, auditLog, "some-phase")
+	})
+
+	entries := func() []network.AuditEntry {
+		var ret []network.AuditEntry
+		for _, line := range strings.Split(strings.TrimSpace(auditLog.String()), "\n") {
+			var entry network.AuditEntry
+			h.AssertNil(t, json.Unmarshal([]byte(line), &entry))
+			ret = append(ret, entry)
+		}
+		return ret
+	}
+
+	when("#NewAuditTransport", func() {
+		it("records every registry request", func() {
+			img, err := random.Image(1024, 1)
+			h.AssertNil(t, err)
+			ref, err := name.ParseReference(host + "/some-repo:some-tag")
+			h.AssertNil(t, err)
+			h.AssertNil(t, remote.Write(ref, img, remote.WithTransport(transport)))
+			layers, err := img.Layers()
+			h.AssertNil(t, err)
+			layerDigest, err := layers[0].Digest()
+			h.AssertNil(t, err)
+			auditLog.Reset()
+
+			pulled, err := remote.Image(ref, remote.WithTransport(transport))
+			h.AssertNil(t, err)
+			rc, err := pulled.LayerByDigest(layerDigest)
+			h.AssertNil(t, err)
+			compressed, err := rc.Compressed()
+			h.AssertNil(t, err)
+			n, err := io.Copy(io.Discard, compressed)
+			h.AssertNil(t, err)
+			h.AssertNil(t, compressed.Close())
+
+			var blob *network.AuditEntry
+			for _, entry := range entries() {
+				h.AssertEq(t, entry.Phase, "some-phase")
+				h.AssertEq(t, entry.Registry, host)
+				if entry.Digest == layerDigest.String() {
+					blob = &entry
+				}
+			}
+			h.AssertNotNil(t, blob)
+			h.AssertEq(t, blob.Method, http.MethodGet)
+			h.AssertEq(t, blob.Repository, "some-repo")
+			h.AssertEq(t, blob.BytesReceived, n)
+			h.AssertEq(t, blob.StatusCode, http.StatusOK)
+			h.AssertEq(t, blob.Outcome, network.AuditOutcomeSuccess)
+		})
+
+		it("records failed requests", func() {
+			ref, err := name.ParseReference(host + "/some-repo:missing")
+			h.AssertNil(t, err)
+			_, err = remote.Image(ref, remote.WithTransport(transport))
+			h.AssertNotNil(t, err)
+
+			recorded := entries()
+			last := recorded[len(recorded)-1]
+			h.AssertEq(t, last.Repository, "some-repo")
+			h.AssertEq(t, last.StatusCode, http.StatusNotFound)
+			h.AssertEq(t, last.Outcome, network.AuditOutcomeFailure)
+		})
+
+		it("records requests without a response", func() {
+			transport = network.NewAuditTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("some-error")
+			}), auditLog, "some-phase")
+			req, err := http.NewRequest(http.MethodHead, "https://some-registry.io/v2/some/repo/manifests/some-tag", nil)
+			h.AssertNil(t, err)
+
+			_, err = transport.RoundTrip(req) //nolint:bodyclose
+			h.AssertError(t, err, "some-error")
+
+			h.AssertEq(t, entries(), []network.AuditEntry{{
+				Time:       entries()[0].Time,
+				Phase:      "some-phase",
+				Method:     http.MethodHead,
+				Registry:   "some-registry.io",
+				Repository: "some/repo",
+				Duration:   entries()[0].Duration,
+				Outcome:    network.AuditOutcomeError,
+				Error:      "some-error",
+			}})
+		})
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,212 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// EnvRegistryAddressFamily is the address family used to connect to registries:
+	// "ipv4" or "ipv6" to only use that family, "prefer-ipv4" or "prefer-ipv6" to try that family first,
+	// or "any" (the default) to use the addresses in the order returned by the resolver.
+	EnvRegistryAddressFamily = "CNB_REGISTRY_ADDRESS_FAMILY"
+	// EnvRegistryHappyEyeballsDelay is how long to wait for a connection using the first address family
+	// before also trying the other address family (see RFC 6555), e.g., "100ms", or "off" to only try the other family
+	// once the first one fails. If not provided, the Go default (300ms) is used.
+	EnvRegistryHappyEyeballsDelay = "CNB_REGISTRY_HAPPY_EYEBALLS_DELAY"
+	// EnvRegistryConnectTimeouts overrides the connect timeout for specific registries, as a comma-separated list of
+	// <registry>=<duration> (e.g., "registry.internal=5s"). The default connect timeout is 30s.
+	EnvRegistryConnectTimeouts = "CNB_REGISTRY_CONNECT_TIMEOUTS"
+
+	AddressFamilyAny        = "any"
+	AddressFamilyIPv4       = "ipv4"
+	AddressFamilyIPv6       = "ipv6"
+	AddressFamilyPreferIPv4 = "prefer-ipv4"
+	AddressFamilyPreferIPv6 = "prefer-ipv6"
+
+	// DefaultConnectTimeout is the connect timeout for registries without a specific connect timeout.
+	DefaultConnectTimeout = 30 * time.Second
+)
+
+// DialConfig is the configuration for connecting to registries.
+type DialConfig struct {
+	// AddressFamily is the address family to use or prefer, see EnvRegistryAddressFamily.
+	AddressFamily string
+	// HappyEyeballsDelay is how long to wait before trying the other address family;
+	// zero means the Go default, and a negative value means to only try the other family once the first one fails.
+	HappyEyeballsDelay time.Duration
+	// ConnectTimeouts maps a registry host to the connect timeout to use for that registry.
+	ConnectTimeouts map[string]time.Duration
+}
+
+// DialConfigFromEnv returns the dial configuration from the provided environment lookup function.
+func DialConfigFromEnv(getenv func(string) string) (DialConfig, error) {
+	config := DialConfig{AddressFamily: AddressFamilyAny}
+	switch family := getenv(EnvRegistryAddressFamily); family {
+	case "":
+	case AddressFamilyAny, AddressFamilyIPv4, AddressFamilyIPv6, AddressFamilyPreferIPv4, AddressFamilyPreferIPv6:
+		config.AddressFamily = family
+	default:
+		return DialConfig{}, fmt.Errorf("invalid %s '%s': must be one of %s", EnvRegistryAddressFamily, family,
+			strings.Join([]string{AddressFamilyAny, AddressFamilyIPv4, AddressFamilyIPv6, AddressFamilyPreferIPv4, AddressFamilyPreferIPv6}, ", "))
+	}
+	switch delay := getenv(EnvRegistryHappyEyeballsDelay); delay {
+	case "":
+	case "off":
+		config.HappyEyeballsDelay = -1
+	default:
+		d, err := time.ParseDuration(delay)
+		if err != nil || d <= 0 {
+			return DialConfig{}, fmt.Errorf("invalid %s '%s': must be a positive duration or 'off'", EnvRegistryHappyEyeballsDelay, delay)
+		}
+		config.HappyEyeballsDelay = d
+	}
+	timeouts, err := parseConnectTimeouts(getenv(EnvRegistryConnectTimeouts))
+	if err != nil {
+		return DialConfig{}, err
+	}
+	config.ConnectTimeouts = timeouts
+	return config, nil
+}
+
+func parseConnectTimeouts(val string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		registry, timeout, found := strings.Cut(entry, "=")
+		if !found || registry == "" {
+			return nil, fmt.Errorf("invalid %s entry '%s': must be <registry>=<duration>", EnvRegistryConnectTimeouts, entry)
+		}
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s entry '%s': must be a positive duration", EnvRegistryConnectTimeouts, entry)
+		}
+		timeouts[registry] = d
+	}
+	return timeouts, nil
+}
+
+// Dialer returns the dialer for registry connections, without the registry-specific settings.
+func (c DialConfig) Dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:       DefaultConnectTimeout,
+		KeepAlive:     30 * time.Second,
+		FallbackDelay: c.HappyEyeballsDelay,
+	}
+}
+
+// DialContext returns a function that connects to the registry being dialed using the address family
+// and connect timeout configured for the registry.
+func (c DialConfig) DialContext() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialer := c.Dialer()
+		for _, key := range registryKeys(addr) {
+			if timeout, ok := c.ConnectTimeouts[key]; ok {
+				dialer.Timeout = timeout
+				break
+			}
+		}
+		switch c.AddressFamily {
+		case AddressFamilyIPv4:
+			return dialer.DialContext(ctx, withFamily(network, "4"), addr)
+		case AddressFamilyIPv6:
+			return dialer.DialContext(ctx, withFamily(network, "6"), addr)
+		case AddressFamilyPreferIPv4:
+			return dialPreferring(ctx, dialer, network, "4", "6", addr)
+		case AddressFamilyPreferIPv6:
+			return dialPreferring(ctx, dialer, network, "6", "4", addr)
+		default:
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+}
+
+// withFamily restricts a "tcp" network to the provided address family ("4" or "6").
+func withFamily(network, family string) string {
+	if network == "tcp" {
+		return network + family
+	}
+	return network
+}
+
+type dialResult struct {
+	conn    net.Conn
+	err     error
+	primary bool
+}
+
+// dialPreferring connects using the primary address family, also trying the fallback family if the primary one fails,
+// or if the primary one has not connected after the happy eyeballs delay.
+func dialPreferring(ctx context.Context, dialer *net.Dialer, network, primary, fallback, addr string) (net.Conn, error) {
+	if network != "tcp" {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, 2)
+	dial := func(family string, isPrimary bool) {
+		conn, err := dialer.DialContext(ctx, network+family, addr)
+		results <- dialResult{conn: conn, err: err, primary: isPrimary}
+	}
+	go dial(primary, true)
+
+	delay := dialer.FallbackDelay
+	if delay == 0 {
+		delay = 300 * time.Millisecond
+	}
+	var timer <-chan time.Time
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	var (
+		pending         = 1
+		fallbackStarted bool
+		primaryErr      error
+	)
+	startFallback := func() {
+		if !fallbackStarted {
+			fallbackStarted = true
+			pending++
+			go dial(fallback, false)
+		}
+	}
+	for {
+		select {
+		case <-timer:
+			startFallback()
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				// close any other connection that is established after this one
+				go func(remaining int) {
+					for i := 0; i < remaining; i++ {
+						if other := <-results; other.conn != nil {
+							other.conn.Close()
+						}
+					}
+				}(pending)
+				return result.conn, nil
+			}
+			if result.primary {
+				primaryErr = result.err
+				startFallback()
+			}
+			if pending == 0 {
+				if primaryErr != nil {
+					return nil, primaryErr
+				}
+				return nil, result.err
+			}
+		}
+	}
+}
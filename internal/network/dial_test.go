@@ -0,0 +1,124 @@
+package network_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/network"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestDial(t *testing.T) {
+	spec.Run(t, "Dial", testDial, spec.Report(report.Terminal{}))
+}
+
+func testDial(t *testing.T, when spec.G, it spec.S) {
+	var env map[string]string
+
+	getenv := func(key string) string {
+		return env[key]
+	}
+
+	it.Before(func() {
+		env = map[string]string{}
+	})
+
+	when("#DialConfigFromEnv", func() {
+		it("uses the defaults when nothing is provided", func() {
+			config, err := network.DialConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+			h.AssertEq(t, config, network.DialConfig{AddressFamily: network.AddressFamilyAny, ConnectTimeouts: map[string]time.Duration{}})
+		})
+
+		it("reads the provided settings", func() {
+			env[network.EnvRegistryAddressFamily] = "prefer-ipv6"
+			env[network.EnvRegistryHappyEyeballsDelay] = "100ms"
+			env[network.EnvRegistryConnectTimeouts] = "some-registry.io=5s, localhost:5000=1s"
+
+			config, err := network.DialConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+			h.AssertEq(t, config, network.DialConfig{
+				AddressFamily:      network.AddressFamilyPreferIPv6,
+				HappyEyeballsDelay: 100 * time.Millisecond,
+				ConnectTimeouts: map[string]time.Duration{
+					"some-registry.io": 5 * time.Second,
+					"localhost:5000":   time.Second,
+				},
+			})
+
+			env[network.EnvRegistryHappyEyeballsDelay] = "off"
+			config, err = network.DialConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+			h.AssertEq(t, config.HappyEyeballsDelay < 0, true)
+		})
+
+		it("errors for invalid settings", func() {
+			env[network.EnvRegistryAddressFamily] = "ipv5"
+			_, err := network.DialConfigFromEnv(getenv)
+			h.AssertError(t, err, "invalid CNB_REGISTRY_ADDRESS_FAMILY 'ipv5'")
+
+			env[network.EnvRegistryAddressFamily] = ""
+			env[network.EnvRegistryHappyEyeballsDelay] = "soon"
+			_, err = network.DialConfigFromEnv(getenv)
+			h.AssertError(t, err, "invalid CNB_REGISTRY_HAPPY_EYEBALLS_DELAY 'soon'")
+
+			env[network.EnvRegistryHappyEyeballsDelay] = ""
+			env[network.EnvRegistryConnectTimeouts] = "some-registry.io"
+			_, err = network.DialConfigFromEnv(getenv)
+			h.AssertError(t, err, "invalid CNB_REGISTRY_CONNECT_TIMEOUTS entry 'some-registry.io'")
+		})
+	})
+
+	when("#DialContext", func() {
+		var addr string
+
+		it.Before(func() {
+			// the registry only listens on IPv4
+			listener, err := net.Listen("tcp4", "127.0.0.1:0")
+			h.AssertNil(t, err)
+			t.Cleanup(func() { listener.Close() })
+			go func() {
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					conn.Close()
+				}
+			}()
+			_, port, err := net.SplitHostPort(listener.Addr().String())
+			h.AssertNil(t, err)
+			addr = net.JoinHostPort("localhost", port)
+		})
+
+		dial := func(config network.DialConfig, addr string) error {
+			conn, err := config.DialContext()(context.Background(), "tcp", addr)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}
+
+		it("only uses the configured address family", func() {
+			h.AssertNil(t, dial(network.DialConfig{AddressFamily: network.AddressFamilyIPv4}, addr))
+			h.AssertNotNil(t, dial(network.DialConfig{AddressFamily: network.AddressFamilyIPv6}, addr))
+		})
+
+		it("falls back to the other address family", func() {
+			h.AssertNil(t, dial(network.DialConfig{AddressFamily: network.AddressFamilyPreferIPv6}, addr))
+			h.AssertNil(t, dial(network.DialConfig{AddressFamily: network.AddressFamilyPreferIPv6, HappyEyeballsDelay: -1}, addr))
+		})
+
+		it("uses the connect timeout for the registry", func() {
+			err := dial(network.DialConfig{ConnectTimeouts: map[string]time.Duration{"localhost": time.Nanosecond}}, addr)
+			h.AssertError(t, err, "timeout")
+
+			h.AssertNil(t, dial(network.DialConfig{ConnectTimeouts: map[string]time.Duration{"some-registry.io": time.Nanosecond}}, addr))
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package network
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// EgressProxy is a forward proxy that denies every request, recording the host of each.
+// The builder routes the network traffic of the buildpacks that may not access the network through it,
+// so that their requests fail (rather than reaching the network) and are recorded as violations of the network policy.
+// Clients that do not honor the standard proxy environment variables are not covered.
+type EgressProxy struct {
+	listener net.Listener
+	server   *http.Server
+
+	mu    sync.Mutex
+	hosts []string
+}
+
+// StartEgressProxy starts an EgressProxy listening on the loopback interface.
+func StartEgressProxy() (*EgressProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &EgressProxy{listener: listener}
+	p.server = &http.Server{Handler: http.HandlerFunc(p.deny)} // #nosec G112 -- requests are denied without reading them
+	go func() {
+		_ = p.server.Serve(listener)
+	}()
+	return p, nil
+}
+
+func (p *EgressProxy) deny(w http.ResponseWriter, req *http.Request) {
+	host := req.Host
+	if host == "" && req.URL != nil {
+		host = req.URL.Host
+	}
+	p.mu.Lock()
+	p.hosts = append(p.hosts, host)
+	p.mu.Unlock()
+	http.Error(w, "network access is not allowed by the network policy of the build", http.StatusForbidden)
+}
+
+// Env returns the environment variables that route the requests of a process through the proxy.
+// Hosts excluded from proxying by the build environment (i.e., NO_PROXY) are included again.
+func (p *EgressProxy) Env() []string {
+	proxyURL := "http://" + p.listener.Addr().String()
+	var env []string
+	for _, key := range []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "http_proxy", "https_proxy", "all_proxy"} {
+		env = append(env, key+"="+proxyURL)
+	}
+	return append(env, "NO_PROXY=", "no_proxy=")
+}
+
+// Attempts returns the hosts of the requests denied since the last call, in the order they were made.
+func (p *EgressProxy) Attempts() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hosts := p.hosts
+	p.hosts = nil
+	return hosts
+}
+
+// Close stops the proxy.
+func (p *EgressProxy) Close() error {
+	if err := p.server.Close(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
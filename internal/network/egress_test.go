@@ -0,0 +1,66 @@
+package network_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/network"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestEgressProxy(t *testing.T) {
+	spec.Run(t, "EgressProxy", testEgressProxy, spec.Report(report.Terminal{}))
+}
+
+func testEgressProxy(t *testing.T, when spec.G, it spec.S) {
+	var (
+		proxy  *network.EgressProxy
+		client *http.Client
+	)
+
+	it.Before(func() {
+		var err error
+		proxy, err = network.StartEgressProxy()
+		h.AssertNil(t, err)
+
+		var proxyURL *url.URL
+		for _, kv := range proxy.Env() {
+			if val, ok := strings.CutPrefix(kv, "HTTP_PROXY="); ok {
+				proxyURL, err = url.Parse(val)
+				h.AssertNil(t, err)
+			}
+		}
+		h.AssertNotNil(t, proxyURL)
+		client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	})
+
+	it.After(func() {
+		h.AssertNil(t, proxy.Close())
+	})
+
+	it("denies requests and records their hosts", func() {
+		resp, err := client.Get("http://some-host.invalid/some-path")
+		h.AssertNil(t, err)
+		h.AssertNil(t, resp.Body.Close())
+		h.AssertEq(t, resp.StatusCode, http.StatusForbidden)
+
+		_, err = client.Get("https://other-host.invalid/other-path")
+		h.AssertNotNil(t, err)
+
+		h.AssertEq(t, proxy.Attempts(), []string{"some-host.invalid", "other-host.invalid:443"})
+		h.AssertEq(t, len(proxy.Attempts()), 0)
+	})
+
+	when("#Env", func() {
+		it("overrides the proxy settings of the environment", func() {
+			env := proxy.Env()
+			h.AssertContains(t, env, "NO_PROXY=", "no_proxy=")
+			h.AssertEq(t, len(env), 8)
+		})
+	})
+}
@@ -0,0 +1,96 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/buildpacks/lifecycle/telemetry/metrics"
+	"github.com/buildpacks/lifecycle/telemetry/progress"
+	"github.com/buildpacks/lifecycle/telemetry/tracing"
+)
+
+// newInstrumentedTransport returns a transport that records a span and metrics for every request (including any retries),
+// and emits progress events for the bytes transferred, if tracing, metrics or the progress event stream are enabled.
+// The span ends when the response body is closed (or fully read), so that it accounts for the whole download.
+func newInstrumentedTransport(inner http.RoundTripper) http.RoundTripper {
+	return &instrumentedTransport{inner: inner}
+}
+
+type instrumentedTransport struct {
+	inner http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !tracing.Enabled() && !metrics.Enabled() && !progress.Enabled() {
+		return t.inner.RoundTrip(req)
+	}
+	registry := metrics.L("registry", req.URL.Host)
+	metrics.Add(metrics.RegistryRequests, 1, registry)
+	if req.ContentLength > 0 {
+		metrics.Add(metrics.RegistryBytes, float64(req.ContentLength), registry, metrics.L("direction", metrics.DirectionPushed))
+		progress.BytesTransferred(req.URL.Host, progress.DirectionPushed, req.ContentLength)
+	}
+	repository, digest := parseRegistryPath(req.URL.Path)
+	span := tracing.Start("registry "+req.Method,
+		tracing.String("http.method", req.Method),
+		tracing.String("cnb.registry", req.URL.Host),
+		tracing.String("cnb.repository", repository),
+	)
+	if digest != "" {
+		span.SetAttributes(tracing.String("cnb.digest", digest))
+	}
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		span.End(err)
+		return resp, err
+	}
+	span.SetAttributes(tracing.Int("http.status_code", resp.StatusCode))
+	var spanErr error
+	if resp.StatusCode >= http.StatusBadRequest {
+		spanErr = fmt.Errorf("unexpected status code %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	resp.Body = &instrumentedBody{ReadCloser: resp.Body, span: span, err: spanErr, registry: registry}
+	return resp, nil
+}
+
+type instrumentedBody struct {
+	io.ReadCloser
+	span     *tracing.Span
+	err      error
+	registry metrics.Label
+	received int64
+	once     sync.Once
+}
+
+func (b *instrumentedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.received += int64(n)
+	switch {
+	case err == io.EOF:
+		b.done(nil)
+	case err != nil:
+		b.done(err)
+	}
+	return n, err
+}
+
+func (b *instrumentedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done(nil)
+	return err
+}
+
+func (b *instrumentedBody) done(err error) {
+	b.once.Do(func() {
+		if err == nil {
+			err = b.err
+		}
+		b.span.End(err)
+		if b.received > 0 {
+			metrics.Add(metrics.RegistryBytes, float64(b.received), b.registry, metrics.L("direction", metrics.DirectionPulled))
+			progress.BytesTransferred(b.registry.Value, progress.DirectionPulled, b.received)
+		}
+	})
+}
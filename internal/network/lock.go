@@ -0,0 +1,89 @@
+package network
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// EnvImageLockPath is the location of a TOML file provided by the platform that pins image tags to digests, e.g.:
+//
+//	[images]
+//	"cnbs/some-run-image:latest" = "sha256:..."
+//
+// When provided, base images (e.g., the run image) are resolved strictly from the file:
+// base images referenced by a tag that is not in the file cannot be used, and tags are never resolved using the registry.
+const EnvImageLockPath = "CNB_IMAGE_LOCK_PATH"
+
+// ImageLock pins image tags to digests.
+type ImageLock struct {
+	// Images maps a fully qualified tag reference (e.g., "index.docker.io/cnbs/some-run-image:latest") to a digest.
+	Images map[string]string
+}
+
+// defaultLock is the image lock used by ResolveImage and PullRef.
+var defaultLock *ImageLock
+
+// ImageLockFromEnv returns the image lock from the provided environment lookup function, or nil if no lock file was provided.
+func ImageLockFromEnv(getenv func(string) string) (*ImageLock, error) {
+	path := getenv(EnvImageLockPath)
+	if path == "" {
+		return nil, nil
+	}
+	return ReadImageLock(path)
+}
+
+// ReadImageLock reads the image lock file at the provided path.
+func ReadImageLock(path string) (*ImageLock, error) {
+	var contents struct {
+		Images map[string]string `toml:"images"`
+	}
+	if _, err := toml.DecodeFile(path, &contents); err != nil {
+		return nil, fmt.Errorf("failed to read image lock: %w", err)
+	}
+	lock := &ImageLock{Images: map[string]string{}}
+	for imageRef, digest := range contents.Images {
+		tag, err := name.NewTag(imageRef, name.WeakValidation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image lock entry '%s': %w", imageRef, err)
+		}
+		if _, err = name.NewDigest(tag.Context().Name()+"@"+digest, name.WeakValidation); err != nil {
+			return nil, fmt.Errorf("invalid digest '%s' for image '%s': %w", digest, imageRef, err)
+		}
+		lock.Images[tag.Name()] = digest
+	}
+	return lock, nil
+}
+
+// Resolve returns the digest reference for the provided image reference.
+// Digest references are returned as-is, and tag references are resolved from the lock.
+// It returns false if the image is referenced by a tag that is not in the lock.
+func (l *ImageLock) Resolve(imageRef string) (string, bool) {
+	ref, err := name.ParseReference(imageRef, name.WeakValidation)
+	if err != nil {
+		return "", false
+	}
+	if _, ok := ref.(name.Digest); ok {
+		return imageRef, true
+	}
+	digest, ok := l.Images[ref.Name()]
+	if !ok {
+		return "", false
+	}
+	return ref.Context().Name() + "@" + digest, true
+}
+
+// ResolveImage returns the reference to use for the provided base image, using the image lock provided to Configure (if any):
+// the image reference itself if there is no lock, or the digest reference from the lock.
+// It errors if the image is referenced by a tag that is not in the lock.
+func ResolveImage(imageRef string) (string, error) {
+	if defaultLock == nil {
+		return imageRef, nil
+	}
+	resolved, ok := defaultLock.Resolve(imageRef)
+	if !ok {
+		return "", fmt.Errorf("image '%s' is not in the image lock file; base images must be pinned to a digest in %s", imageRef, EnvImageLockPath)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,80 @@
+package network_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/network"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestImageLock(t *testing.T) {
+	spec.Run(t, "ImageLock", testImageLock, spec.Report(report.Terminal{}))
+}
+
+func testImageLock(t *testing.T, when spec.G, it spec.S) {
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000001"
+
+	when("#ReadImageLock", func() {
+		it("reads the pinned images using fully qualified references", func() {
+			path := filepath.Join(t.TempDir(), "lock.toml")
+			h.Mkfile(t, `[images]
+"cnbs/some-run-image:latest" = "`+digest+`"
+"some-registry.io/some-build-image:some-tag" = "`+digest+`"
+`, path)
+
+			lock, err := network.ReadImageLock(path)
+			h.AssertNil(t, err)
+			h.AssertEq(t, lock.Images, map[string]string{
+				"index.docker.io/cnbs/some-run-image:latest": digest,
+				"some-registry.io/some-build-image:some-tag": digest,
+			})
+		})
+
+		it("errors for invalid digests", func() {
+			path := filepath.Join(t.TempDir(), "lock.toml")
+			h.Mkfile(t, `[images]
+"cnbs/some-run-image:latest" = "latest"
+`, path)
+
+			_, err := network.ReadImageLock(path)
+			h.AssertError(t, err, "invalid digest 'latest' for image 'cnbs/some-run-image:latest'")
+		})
+	})
+
+	when("#ImageLockFromEnv", func() {
+		it("returns nil if no lock file was provided", func() {
+			lock, err := network.ImageLockFromEnv(func(string) string { return "" })
+			h.AssertNil(t, err)
+			h.AssertNil(t, lock)
+		})
+	})
+
+	when("#Resolve", func() {
+		lock := &network.ImageLock{Images: map[string]string{
+			"index.docker.io/cnbs/some-run-image:latest": digest,
+		}}
+
+		it("resolves pinned tags to digest references", func() {
+			for _, imageRef := range []string{"cnbs/some-run-image", "cnbs/some-run-image:latest", "docker.io/cnbs/some-run-image:latest"} {
+				resolved, ok := lock.Resolve(imageRef)
+				h.AssertEq(t, ok, true)
+				h.AssertEq(t, resolved, "index.docker.io/cnbs/some-run-image@"+digest)
+			}
+		})
+
+		it("returns digest references as-is", func() {
+			resolved, ok := lock.Resolve("some-registry.io/some-image@" + digest)
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, resolved, "some-registry.io/some-image@"+digest)
+		})
+
+		it("returns false for tags that are not pinned", func() {
+			_, ok := lock.Resolve("cnbs/some-run-image:other-tag")
+			h.AssertEq(t, ok, false)
+		})
+	})
+}
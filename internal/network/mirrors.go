@@ -0,0 +1,125 @@
+package network
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// EnvRegistryMirrors is the location of a TOML file with pull-through mirrors for specific registries
+// (similar to containerd's hosts.toml), e.g.:
+//
+//	[registries."index.docker.io"]
+//	mirrors = ["mirror.gcr.io", "registry.internal/docker-hub"]
+//
+// A mirror is a registry host, optionally followed by a repository prefix.
+// Images are pulled from the first mirror that has them, falling back to the registry itself.
+// Image references (e.g., in the application image metadata) are not rewritten.
+const EnvRegistryMirrors = "CNB_REGISTRY_MIRRORS"
+
+// MirrorConfig is the mirror configuration for pulling images.
+type MirrorConfig struct {
+	// Registries holds the mirrors for specific registries, keyed by registry host (with or without the port).
+	Registries map[string]RegistryMirrorConfig
+}
+
+// RegistryMirrorConfig holds the mirrors for a specific registry.
+type RegistryMirrorConfig struct {
+	// Mirrors are the mirrors to pull images from, in order of preference.
+	Mirrors []string `toml:"mirrors"`
+}
+
+// defaultMirrors is the mirror configuration used by PullRef.
+var defaultMirrors MirrorConfig
+
+// MirrorConfigFromEnv returns the mirror configuration from the provided environment lookup function.
+func MirrorConfigFromEnv(getenv func(string) string) (MirrorConfig, error) {
+	var config MirrorConfig
+	if err := config.ReadRegistries(getenv(EnvRegistryMirrors)); err != nil {
+		return MirrorConfig{}, err
+	}
+	return config, nil
+}
+
+// ReadRegistries reads the mirrors for specific registries from the provided file (if any).
+func (c *MirrorConfig) ReadRegistries(path string) error {
+	if path == "" {
+		return nil
+	}
+	var contents struct {
+		Registries map[string]RegistryMirrorConfig `toml:"registries"`
+	}
+	if _, err := toml.DecodeFile(path, &contents); err != nil {
+		return fmt.Errorf("failed to read registry mirrors: %w", err)
+	}
+	for registry, settings := range contents.Registries {
+		for _, mirror := range settings.Mirrors {
+			if _, err := name.NewRepository(mirror+"/some-repo", name.WeakValidation); err != nil {
+				return fmt.Errorf("invalid mirror '%s' for registry '%s': %w", mirror, registry, err)
+			}
+		}
+	}
+	c.Registries = contents.Registries
+	return nil
+}
+
+// MirrorRefs returns the references to the image in the mirrors of its registry, in order of preference.
+func (c MirrorConfig) MirrorRefs(imageRef string) []string {
+	if len(c.Registries) == 0 {
+		return nil
+	}
+	ref, err := name.ParseReference(imageRef, name.WeakValidation)
+	if err != nil {
+		return nil
+	}
+	keys := registryKeys(ref.Context().RegistryStr())
+	if ref.Context().RegistryStr() == name.DefaultRegistry {
+		keys = append(keys, "docker.io")
+	}
+	suffix := ":" + ref.Identifier()
+	if _, ok := ref.(name.Digest); ok {
+		suffix = "@" + ref.Identifier()
+	}
+	for _, key := range keys {
+		settings, ok := c.Registries[key]
+		if !ok {
+			continue
+		}
+		var refs []string
+		for _, mirror := range settings.Mirrors {
+			refs = append(refs, strings.TrimSuffix(mirror, "/")+"/"+ref.Context().RepositoryStr()+suffix)
+		}
+		return refs
+	}
+	return nil
+}
+
+// PullRef returns the reference to pull the image from: the first mirror that has the image,
+// or the image reference itself if no mirror has it.
+func (c MirrorConfig) PullRef(imageRef string, keychain authn.Keychain) string {
+	for _, mirrorRef := range c.MirrorRefs(imageRef) {
+		ref, err := name.ParseReference(mirrorRef, name.WeakValidation)
+		if err != nil {
+			continue
+		}
+		if _, err = remote.Head(ref, remote.WithAuthFromKeychain(keychain), remote.WithTransport(remote.DefaultTransport)); err == nil {
+			return mirrorRef
+		}
+	}
+	return imageRef
+}
+
+// PullRef returns the reference to pull the image from, using the image lock and the mirror configuration provided to Configure.
+// Images pinned in the image lock are pulled by digest.
+func PullRef(imageRef string, keychain authn.Keychain) string {
+	if defaultLock != nil {
+		if resolved, ok := defaultLock.Resolve(imageRef); ok {
+			imageRef = resolved
+		}
+	}
+	return defaultMirrors.PullRef(imageRef, keychain)
+}
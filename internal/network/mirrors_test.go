@@ -0,0 +1,112 @@
+package network_test
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/network"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestMirrors(t *testing.T) {
+	spec.Run(t, "Mirrors", testMirrors, spec.Report(report.Terminal{}))
+}
+
+func testMirrors(t *testing.T, when spec.G, it spec.S) {
+	var config network.MirrorConfig
+
+	when("#ReadRegistries", func() {
+		it("reads the mirrors for specific registries", func() {
+			path := filepath.Join(t.TempDir(), "mirrors.toml")
+			h.Mkfile(t, `[registries."docker.io"]
+mirrors = ["mirror.gcr.io", "registry.internal:5000/docker-hub"]
+`, path)
+
+			h.AssertNil(t, config.ReadRegistries(path))
+			h.AssertEq(t, config.Registries, map[string]network.RegistryMirrorConfig{
+				"docker.io": {Mirrors: []string{"mirror.gcr.io", "registry.internal:5000/docker-hub"}},
+			})
+		})
+
+		it("errors for invalid mirrors", func() {
+			path := filepath.Join(t.TempDir(), "mirrors.toml")
+			h.Mkfile(t, `[registries."docker.io"]
+mirrors = ["Not A Mirror"]
+`, path)
+
+			h.AssertError(t, config.ReadRegistries(path), "invalid mirror 'Not A Mirror' for registry 'docker.io'")
+		})
+	})
+
+	when("#MirrorRefs", func() {
+		it.Before(func() {
+			config = network.MirrorConfig{Registries: map[string]network.RegistryMirrorConfig{
+				"docker.io":        {Mirrors: []string{"mirror.gcr.io", "registry.internal:5000/docker-hub/"}},
+				"some-registry.io": {Mirrors: []string{"some-mirror.io"}},
+			}}
+		})
+
+		it("returns the references to the image in the mirrors of its registry", func() {
+			h.AssertEq(t, config.MirrorRefs("busybox"), []string{
+				"mirror.gcr.io/library/busybox:latest",
+				"registry.internal:5000/docker-hub/library/busybox:latest",
+			})
+			h.AssertEq(t, config.MirrorRefs("some-registry.io/some-repo@sha256:"+strings.Repeat("a", 64)), []string{
+				"some-mirror.io/some-repo@sha256:" + strings.Repeat("a", 64),
+			})
+		})
+
+		it("returns nothing for registries without mirrors", func() {
+			h.AssertEq(t, config.MirrorRefs("other-registry.io/some-repo"), []string(nil))
+		})
+	})
+
+	when("#PullRef", func() {
+		var originRegistry, mirrorRegistry string
+
+		it.Before(func() {
+			origin := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			mirror := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			t.Cleanup(origin.Close)
+			t.Cleanup(mirror.Close)
+			originRegistry = strings.TrimPrefix(origin.URL, "http://")
+			mirrorRegistry = strings.TrimPrefix(mirror.URL, "http://")
+
+			img, err := random.Image(10, 1)
+			h.AssertNil(t, err)
+			ref, err := name.ParseReference(mirrorRegistry + "/some-prefix/some-repo:some-tag")
+			h.AssertNil(t, err)
+			h.AssertNil(t, remote.Write(ref, img))
+
+			config = network.MirrorConfig{Registries: map[string]network.RegistryMirrorConfig{
+				originRegistry: {Mirrors: []string{"127.0.0.1:1/unavailable", mirrorRegistry + "/some-prefix"}},
+			}}
+		})
+
+		it("returns the first mirror that has the image", func() {
+			h.AssertEq(t,
+				config.PullRef(originRegistry+"/some-repo:some-tag", authn.DefaultKeychain),
+				mirrorRegistry+"/some-prefix/some-repo:some-tag",
+			)
+		})
+
+		it("falls back to the image reference if no mirror has the image", func() {
+			h.AssertEq(t,
+				config.PullRef(originRegistry+"/some-repo:other-tag", authn.DefaultKeychain),
+				originRegistry+"/some-repo:other-tag",
+			)
+		})
+	})
+}
@@ -0,0 +1,123 @@
+// Package network configures the transport used by the lifecycle for all registry requests,
+// so that every phase that touches the network behaves the same way behind a proxy or with custom TLS settings.
+package network
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/buildpacks/lifecycle/internal/chaos"
+)
+
+// Config is the network configuration for registry requests.
+type Config struct {
+	Proxy   ProxyConfig
+	TLS     TLSConfig
+	Mirrors MirrorConfig
+	Dial    DialConfig
+	Lock    *ImageLock
+	Audit   AuditConfig
+	Retry   RetryConfig
+	// PlainDefaultTransport leaves http.DefaultTransport as an *http.Transport, without auditing or retries,
+	// for phases running kaniko (which requires http.DefaultTransport to be an *http.Transport).
+	PlainDefaultTransport bool
+}
+
+// ConfigFromEnv returns the network configuration from the provided environment lookup function.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	proxyConfig, err := ProxyConfigFromEnv(getenv)
+	if err != nil {
+		return Config{}, err
+	}
+	tlsConfig, err := TLSConfigFromEnv(getenv)
+	if err != nil {
+		return Config{}, err
+	}
+	mirrorConfig, err := MirrorConfigFromEnv(getenv)
+	if err != nil {
+		return Config{}, err
+	}
+	dialConfig, err := DialConfigFromEnv(getenv)
+	if err != nil {
+		return Config{}, err
+	}
+	lock, err := ImageLockFromEnv(getenv)
+	if err != nil {
+		return Config{}, err
+	}
+	retryConfig, err := RetryConfigFromEnv(getenv)
+	if err != nil {
+		return Config{}, err
+	}
+	return Config{
+		Proxy:   proxyConfig,
+		TLS:     tlsConfig,
+		Mirrors: mirrorConfig,
+		Dial:    dialConfig,
+		Lock:    lock,
+		Audit:   AuditConfigFromEnv(getenv),
+		Retry:   retryConfig,
+	}, nil
+}
+
+// Transport returns a transport using the network configuration,
+// with the same settings as the default transport for registry requests.
+func (c Config) Transport() (*http.Transport, error) {
+	dial := c.Dial.DialContext()
+	transport := &http.Transport{
+		Proxy:                 c.Proxy.ProxyFunc(),
+		DialContext:           dial,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConnsPerHost:   50,
+	}
+	tlsConfig, err := c.TLS.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	if transport.DialTLSContext, err = c.TLS.DialTLSContext(dial); err != nil {
+		return nil, err
+	}
+	return transport, nil
+}
+
+// Configure uses the network configuration for all registry requests made by the lifecycle,
+// including requests made through imgutil and kaniko (which use http.DefaultTransport)
+// and go-containerregistry (which uses remote.DefaultTransport).
+// Failed registry requests are retried using the retry policy, and registry requests made through go-containerregistry
+// (e.g., pushing the app image) can also be retried after the registry token expires.
+// If an audit log is configured, every registry request (including each retry) is also appended to the audit log,
+// and if tracing or metrics are enabled, every registry request is recorded as a span and counted in the metrics.
+// Requests made through kaniko are neither retried by the retry policy, audited nor instrumented (see Config.PlainDefaultTransport).
+// It also stores the mirror configuration and the image lock used by PullRef and ResolveImage.
+func Configure(c Config) error {
+	transport, err := c.Transport()
+	if err != nil {
+		return err
+	}
+	// injected failures (if any) are retried like other failures
+	inner := chaos.Transport(transport)
+	if c.Audit.Path != "" {
+		w, err := openAuditLog(c.Audit.Path)
+		if err != nil {
+			return err
+		}
+		inner = NewAuditTransport(inner, w, c.Audit.Phase)
+	}
+	inner = newInstrumentedTransport(NewRetryTransport(inner, c.Retry))
+	if c.PlainDefaultTransport {
+		http.DefaultTransport = transport
+	} else {
+		http.DefaultTransport = inner
+	}
+	remote.DefaultTransport = NewReauthTransport(inner)
+	defaultMirrors = c.Mirrors
+	defaultLock = c.Lock
+	return nil
+}
@@ -0,0 +1,199 @@
+package network_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/network"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestNetwork(t *testing.T) {
+	spec.Run(t, "Network", testNetwork, spec.Report(report.Terminal{}))
+}
+
+func testNetwork(t *testing.T, when spec.G, it spec.S) {
+	var (
+		env    map[string]string
+		tmpDir string
+	)
+
+	getenv := func(key string) string {
+		return env[key]
+	}
+
+	it.Before(func() {
+		env = map[string]string{
+			"HTTPS_PROXY": "http://some-proxy:3128",
+			"no_proxy":    "some-internal-registry.com",
+		}
+		tmpDir = t.TempDir()
+	})
+
+	when("proxies", func() {
+		proxyFor := func(config network.Config, rawURL string) string {
+			req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+			h.AssertNil(t, err)
+			proxyURL, err := config.Proxy.ProxyFunc()(req)
+			h.AssertNil(t, err)
+			if proxyURL == nil {
+				return ""
+			}
+			return proxyURL.String()
+		}
+
+		it("uses the standard proxy configuration", func() {
+			config, err := network.ConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, proxyFor(config, "https://some-registry.com/v2/"), "http://some-proxy:3128")
+			h.AssertEq(t, proxyFor(config, "https://some-internal-registry.com/v2/"), "")
+		})
+
+		it("prefers registry-specific proxies", func() {
+			env[network.EnvRegistryProxies] = "some-registry.com=http://other-proxy:8080, some-direct-registry.com:5000="
+
+			config, err := network.ConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, proxyFor(config, "https://some-registry.com/v2/"), "http://other-proxy:8080")
+			h.AssertEq(t, proxyFor(config, "https://some-direct-registry.com:5000/v2/"), "")
+			h.AssertEq(t, proxyFor(config, "https://some-other-registry.com/v2/"), "http://some-proxy:3128")
+		})
+
+		it("errors for invalid registry proxies", func() {
+			env[network.EnvRegistryProxies] = "some-registry.com"
+
+			_, err := network.ConfigFromEnv(getenv)
+			h.AssertError(t, err, "invalid CNB_REGISTRY_PROXIES entry 'some-registry.com'")
+		})
+	})
+
+	when("TLS", func() {
+		var server *httptest.Server
+
+		it.Before(func() {
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			env = map[string]string{}
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		get := func(config network.Config) error {
+			transport, err := config.Transport()
+			h.AssertNil(t, err)
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		}
+
+		serverHost := func() string {
+			u, err := url.Parse(server.URL)
+			h.AssertNil(t, err)
+			return u.Host
+		}
+
+		it("uses the default configuration when nothing is provided", func() {
+			config, err := network.ConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+
+			tlsConfig, err := config.TLS.ClientConfig()
+			h.AssertNil(t, err)
+			h.AssertNil(t, tlsConfig)
+			h.AssertNotNil(t, get(config))
+		})
+
+		it("trusts the provided CA certificates", func() {
+			caPath := filepath.Join(tmpDir, "ca.pem")
+			h.AssertNil(t, os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+			env[network.EnvRegistryCACerts] = caPath
+
+			config, err := network.ConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+			h.AssertNil(t, get(config))
+		})
+
+		it("skips verification only for insecure registries", func() {
+			tlsConfigPath := filepath.Join(tmpDir, "registries.toml")
+			h.Mkfile(t, `[registries."`+serverHost()+`"]
+insecure = true
+`, tlsConfigPath)
+			env[network.EnvRegistryTLSConfig] = tlsConfigPath
+
+			config, err := network.ConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+			h.AssertNil(t, get(config))
+
+			h.Mkfile(t, `[registries."some-other-registry.com"]
+insecure = true
+`, tlsConfigPath)
+			config, err = network.ConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+			h.AssertNotNil(t, get(config))
+		})
+
+		it("errors when a CA certificate is invalid", func() {
+			caPath := filepath.Join(tmpDir, "ca.pem")
+			h.AssertNil(t, os.WriteFile(caPath, []byte("not a certificate"), 0600))
+			env[network.EnvProxyCACert] = caPath
+
+			config, err := network.ConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+			_, err = config.Transport()
+			h.AssertError(t, err, "no certificates found")
+		})
+
+		it("presents the client certificate", func() {
+			certPath, keyPath := newClientCert(t, tmpDir)
+			env[network.EnvRegistryClientCert] = certPath
+			env[network.EnvRegistryClientKey] = keyPath
+
+			config, err := network.ConfigFromEnv(getenv)
+			h.AssertNil(t, err)
+			tlsConfig, err := config.TLS.ClientConfig()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(tlsConfig.Certificates), 1)
+		})
+	})
+}
+
+func newClientCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	h.AssertNil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "some-client"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	h.AssertNil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	h.AssertNil(t, err)
+
+	certPath, keyPath := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	h.AssertNil(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	h.AssertNil(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certPath, keyPath
+}
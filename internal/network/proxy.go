@@ -0,0 +1,100 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+const (
+	// EnvRegistryProxies overrides the proxy for specific registries, as a comma-separated list of <registry>=<proxy URL>.
+	// An empty proxy URL (e.g., "registry.internal=") means that the registry is contacted directly.
+	EnvRegistryProxies = "CNB_REGISTRY_PROXIES"
+	// EnvProxyCACert is the location of a PEM-encoded certificate bundle to trust in addition to the system roots,
+	// e.g., for proxies that intercept TLS connections. It is trusted like the certificates of EnvRegistryCACerts (see TLSConfig).
+	EnvProxyCACert = "CNB_PROXY_CA_CERT"
+)
+
+// ProxyConfig is the proxy configuration for registry requests.
+type ProxyConfig struct {
+	httpproxy.Config
+	// RegistryProxies maps a registry host to the proxy URL to use for that registry, or "" to not use a proxy.
+	RegistryProxies map[string]string
+}
+
+// ProxyConfigFromEnv returns the proxy configuration from the provided environment lookup function,
+// honoring both upper- and lower-case forms of the standard proxy environment variables.
+func ProxyConfigFromEnv(getenv func(string) string) (ProxyConfig, error) {
+	registryProxies, err := parseRegistryProxies(getenv(EnvRegistryProxies))
+	if err != nil {
+		return ProxyConfig{}, err
+	}
+	return ProxyConfig{
+		Config: httpproxy.Config{
+			HTTPProxy:  firstOf(getenv, "HTTP_PROXY", "http_proxy"),
+			HTTPSProxy: firstOf(getenv, "HTTPS_PROXY", "https_proxy"),
+			NoProxy:    firstOf(getenv, "NO_PROXY", "no_proxy"),
+		},
+		RegistryProxies: registryProxies,
+	}, nil
+}
+
+func firstOf(getenv func(string) string, keys ...string) string {
+	for _, key := range keys {
+		if val := getenv(key); val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+func parseRegistryProxies(val string) (map[string]string, error) {
+	proxies := map[string]string{}
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		registry, proxyURL, found := strings.Cut(entry, "=")
+		if !found || registry == "" {
+			return nil, fmt.Errorf("invalid %s entry '%s': must be <registry>=<proxy URL>", EnvRegistryProxies, entry)
+		}
+		if proxyURL != "" {
+			if _, err := url.Parse(proxyURL); err != nil {
+				return nil, fmt.Errorf("invalid %s entry '%s': %w", EnvRegistryProxies, entry, err)
+			}
+		}
+		proxies[registry] = proxyURL
+	}
+	return proxies, nil
+}
+
+// ProxyFunc returns a function that selects the proxy for a request,
+// preferring any registry-specific proxy over the standard proxy configuration.
+func (c ProxyConfig) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	defaultFunc := c.Config.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		for _, key := range registryKeys(req.URL.Host) {
+			if proxyURL, ok := c.RegistryProxies[key]; ok {
+				if proxyURL == "" {
+					return nil, nil
+				}
+				return url.Parse(proxyURL)
+			}
+		}
+		return defaultFunc(req.URL)
+	}
+}
+
+// registryKeys returns the keys that may be used to configure the provided host, with and without the port.
+func registryKeys(host string) []string {
+	keys := []string{host}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		keys = append(keys, hostname)
+	}
+	return keys
+}
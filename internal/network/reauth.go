@@ -0,0 +1,43 @@
+package network
+
+import (
+	"net/http"
+	"sync"
+)
+
+// NewReauthTransport returns a transport that resends the full body of a request after a 401 Unauthorized response.
+//
+// When a registry token expires during a push (e.g., while uploading a large layer), go-containerregistry refreshes the token
+// and sends the same request again, but the body of the request has already been consumed by the failed attempt.
+// When the request is sent again, the transport sends a clone of it with a new body from the request's GetBody,
+// so that the failed blob upload is retried with the fresh token instead of failing the export.
+// Requests without GetBody (e.g., the uploads of streamed layers) cannot be sent again, and fail as they would without it.
+func NewReauthTransport(inner http.RoundTripper) http.RoundTripper {
+	return &reauthTransport{inner: inner}
+}
+
+type reauthTransport struct {
+	inner        http.RoundTripper
+	unauthorized sync.Map // the requests with a body that received a 401 Unauthorized response, and may be sent again
+}
+
+func (t *reauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := t.unauthorized.LoadAndDelete(req); ok {
+		if body, err := req.GetBody(); err == nil {
+			if req.Body != nil {
+				_ = req.Body.Close()
+			}
+			clone := req.Clone(req.Context())
+			clone.Body = body
+			return t.inner.RoundTrip(clone)
+		}
+	}
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody != nil {
+		t.unauthorized.Store(req, struct{}{})
+	}
+	return resp, nil
+}
@@ -0,0 +1,120 @@
+package network_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/network"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestReauthTransport(t *testing.T) {
+	spec.Run(t, "ReauthTransport", testReauthTransport, spec.Report(report.Terminal{}))
+}
+
+func testReauthTransport(t *testing.T, when spec.G, it spec.S) {
+	var (
+		server   *httptest.Server
+		tokens   int
+		uploaded []string
+	)
+
+	it.Before(func() {
+		tokens = 0
+		uploaded = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			challenge := fmt.Sprintf(`Bearer realm="%s/token",service="some-registry"`, "http://"+r.Host)
+			switch r.URL.Path {
+			case "/v2/":
+				w.Header().Set("WWW-Authenticate", challenge)
+				w.WriteHeader(http.StatusUnauthorized)
+			case "/token":
+				tokens++
+				fmt.Fprintf(w, `{"token": "token-%d"}`, tokens)
+			case "/v2/some-repo/blobs/uploads/some-upload":
+				body, err := io.ReadAll(r.Body)
+				h.AssertNil(t, err)
+				uploaded = append(uploaded, string(body))
+				// the first token expires during the upload
+				if r.Header.Get("Authorization") == "Bearer token-1" {
+					w.Header().Set("WWW-Authenticate", challenge)
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}
+		}))
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	newTransport := func() http.RoundTripper {
+		registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+		h.AssertNil(t, err)
+		tr, err := transport.NewWithContext(
+			context.Background(),
+			registry,
+			authn.Anonymous,
+			network.NewReauthTransport(http.DefaultTransport),
+			[]string{"repository:some-repo:push,pull"},
+		)
+		h.AssertNil(t, err)
+		return tr
+	}
+
+	// like go-containerregistry, stream the blob
+	blob := func() io.ReadCloser {
+		return io.NopCloser(struct{ io.Reader }{strings.NewReader("some-blob")})
+	}
+
+	newUpload := func() *http.Request {
+		req, err := http.NewRequest(http.MethodPatch, server.URL+"/v2/some-repo/blobs/uploads/some-upload", blob())
+		h.AssertNil(t, err)
+		req.ContentLength = int64(len("some-blob"))
+		return req
+	}
+
+	when("the token expires during an upload", func() {
+		it("sends the full body again after the registry token is refreshed", func() {
+			req := newUpload()
+			// like go-containerregistry, provide GetBody to allow the upload to be retried
+			req.GetBody = func() (io.ReadCloser, error) {
+				return blob(), nil
+			}
+			body := req.Body
+
+			resp, err := newTransport().RoundTrip(req)
+			h.AssertNil(t, err)
+			h.AssertNil(t, resp.Body.Close())
+
+			h.AssertEq(t, resp.StatusCode, http.StatusAccepted)
+			h.AssertEq(t, tokens, 2)
+			h.AssertEq(t, uploaded, []string{"some-blob", "some-blob"})
+			t.Log("does not modify the request")
+			h.AssertEq(t, req.Body == body, true)
+		})
+
+		it("fails the upload if the body cannot be provided again", func() {
+			req := newUpload()
+
+			_, err := newTransport().RoundTrip(req)
+			h.AssertNotNil(t, err)
+
+			h.AssertEq(t, tokens, 2)
+			h.AssertEq(t, uploaded, []string{"some-blob"})
+		})
+	})
+}
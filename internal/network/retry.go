@@ -0,0 +1,202 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// EnvRegistryRetryMaxAttempts is the maximum number of attempts for a registry request, including the first attempt.
+	// Use 1 to disable retries.
+	EnvRegistryRetryMaxAttempts = "CNB_REGISTRY_RETRY_MAX_ATTEMPTS"
+	// EnvRegistryRetryBackoff is how long to wait before the first retry, e.g., "500ms". The wait doubles after each attempt.
+	EnvRegistryRetryBackoff = "CNB_REGISTRY_RETRY_BACKOFF"
+	// EnvRegistryRetryMaxBackoff is the maximum time to wait between attempts, including waits requested by the registry
+	// with a Retry-After header.
+	EnvRegistryRetryMaxBackoff = "CNB_REGISTRY_RETRY_MAX_BACKOFF"
+	// EnvRegistryRetryStatusCodes is a comma-separated list of the response status codes that are retried, e.g., "429,503".
+	EnvRegistryRetryStatusCodes = "CNB_REGISTRY_RETRY_STATUS_CODES"
+)
+
+// DefaultRetryConfig is the retry policy used when no retry settings are provided.
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts: 3,
+	Backoff:     time.Second,
+	MaxBackoff:  30 * time.Second,
+	StatusCodes: []int{
+		http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// RetryConfig is the retry policy for registry requests.
+type RetryConfig struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	StatusCodes []int
+}
+
+// RetryConfigFromEnv returns the retry policy from the provided environment lookup function,
+// using DefaultRetryConfig for the settings that are not provided.
+func RetryConfigFromEnv(getenv func(string) string) (RetryConfig, error) {
+	config := DefaultRetryConfig
+	if val := getenv(EnvRegistryRetryMaxAttempts); val != "" {
+		attempts, err := strconv.Atoi(val)
+		if err != nil || attempts < 1 {
+			return RetryConfig{}, fmt.Errorf("invalid %s '%s': must be a positive integer", EnvRegistryRetryMaxAttempts, val)
+		}
+		config.MaxAttempts = attempts
+	}
+	for key, d := range map[string]*time.Duration{
+		EnvRegistryRetryBackoff:    &config.Backoff,
+		EnvRegistryRetryMaxBackoff: &config.MaxBackoff,
+	} {
+		val := getenv(key)
+		if val == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(val)
+		if err != nil || parsed < 0 {
+			return RetryConfig{}, fmt.Errorf("invalid %s '%s': must be a duration", key, val)
+		}
+		*d = parsed
+	}
+	if val := getenv(EnvRegistryRetryStatusCodes); val != "" {
+		config.StatusCodes = nil
+		for _, s := range strings.Split(val, ",") {
+			code, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || code < 100 || code > 599 {
+				return RetryConfig{}, fmt.Errorf("invalid %s '%s': must be a comma-separated list of status codes", EnvRegistryRetryStatusCodes, val)
+			}
+			config.StatusCodes = append(config.StatusCodes, code)
+		}
+	}
+	return config, nil
+}
+
+// backoff returns how long to wait before the provided attempt (starting at 2 for the first retry).
+func (c RetryConfig) backoff(attempt int, resp *http.Response) time.Duration {
+	wait := c.Backoff
+	for i := 2; i < attempt && wait < c.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+	}
+	if wait > c.MaxBackoff {
+		wait = c.MaxBackoff
+	}
+	return wait
+}
+
+func (c RetryConfig) retryableStatus(code int) bool {
+	for _, retryable := range c.StatusCodes {
+		if code == retryable {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryError is returned when a registry request still fails after retrying.
+// It does not wrap the last failure, so that go-containerregistry does not retry the request again
+// on top of the retry policy.
+type RetryError struct {
+	Attempts int
+	Message  string
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s (after %d attempt(s))", e.Message, e.Attempts)
+}
+
+// NewRetryTransport returns a transport that retries failed registry requests using the retry policy.
+//
+// go-containerregistry wraps every transport in its own retry logic with fixed settings; requests that still fail after
+// the retry policy are returned as a RetryError (rather than a retryable response or error) so that the retry policy
+// is the only one that applies.
+// Requests with a body that cannot be replayed (see http.Request.GetBody) are not retried.
+func NewRetryTransport(inner http.RoundTripper, config RetryConfig) http.RoundTripper {
+	return &retryTransport{inner: inner, config: config}
+}
+
+type retryTransport struct {
+	inner  http.RoundTripper
+	config RetryConfig
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := t.inner.RoundTrip(req)
+		var message string
+		switch {
+		case err != nil && req.Context().Err() != nil:
+			return nil, err
+		case err != nil && retryableError(err):
+			message = err.Error()
+		case err != nil:
+			return nil, err
+		case t.config.retryableStatus(resp.StatusCode):
+			message = fmt.Sprintf("%s %s: unexpected status code %d %s", req.Method, req.URL.Redacted(), resp.StatusCode, http.StatusText(resp.StatusCode))
+		default:
+			return resp, nil
+		}
+		if attempt >= t.config.MaxAttempts || !replayable {
+			if resp != nil {
+				drain(resp)
+			}
+			return nil, &RetryError{Attempts: attempt, Message: message}
+		}
+		wait := t.config.backoff(attempt+1, resp)
+		if resp != nil {
+			drain(resp)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryableError returns true for connection errors that may succeed on retry.
+func retryableError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, net.ErrClosed)
+}
+
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,140 @@
+package network_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/network"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestRetry(t *testing.T) {
+	spec.Run(t, "Retry", testRetry, spec.Report(report.Terminal{}))
+}
+
+func testRetry(t *testing.T, when spec.G, it spec.S) {
+	when("#RetryConfigFromEnv", func() {
+		it("uses the defaults if no retry settings are provided", func() {
+			config, err := network.RetryConfigFromEnv(func(string) string { return "" })
+			h.AssertNil(t, err)
+			h.AssertEq(t, config, network.DefaultRetryConfig)
+		})
+
+		it("reads the retry settings", func() {
+			env := map[string]string{
+				network.EnvRegistryRetryMaxAttempts: "5",
+				network.EnvRegistryRetryBackoff:     "10ms",
+				network.EnvRegistryRetryMaxBackoff:  "1s",
+				network.EnvRegistryRetryStatusCodes: "429, 503",
+			}
+			config, err := network.RetryConfigFromEnv(func(key string) string { return env[key] })
+			h.AssertNil(t, err)
+			h.AssertEq(t, config, network.RetryConfig{
+				MaxAttempts: 5,
+				Backoff:     10 * time.Millisecond,
+				MaxBackoff:  time.Second,
+				StatusCodes: []int{429, 503},
+			})
+		})
+
+		it("errors for invalid settings", func() {
+			_, err := network.RetryConfigFromEnv(func(key string) string {
+				if key == network.EnvRegistryRetryMaxAttempts {
+					return "0"
+				}
+				return ""
+			})
+			h.AssertError(t, err, "invalid CNB_REGISTRY_RETRY_MAX_ATTEMPTS '0'")
+
+			_, err = network.RetryConfigFromEnv(func(key string) string {
+				if key == network.EnvRegistryRetryStatusCodes {
+					return "503,unavailable"
+				}
+				return ""
+			})
+			h.AssertError(t, err, "invalid CNB_REGISTRY_RETRY_STATUS_CODES '503,unavailable'")
+		})
+	})
+
+	when("#NewRetryTransport", func() {
+		var (
+			server    *httptest.Server
+			attempts  int32
+			failures  int32
+			bodies    []string
+			transport http.RoundTripper
+		)
+
+		it.Before(func() {
+			attempts, bodies = 0, nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				if atomic.AddInt32(&attempts, 1) <= atomic.LoadInt32(&failures) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			t.Cleanup(server.Close)
+			transport = network.NewRetryTransport(http.DefaultTransport, network.RetryConfig{
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+				MaxBackoff:  10 * time.Millisecond,
+				StatusCodes: []int{http.StatusServiceUnavailable},
+			})
+		})
+
+		it("retries requests with retryable status codes, replaying the body", func() {
+			failures = 2
+			req, err := http.NewRequest(http.MethodPut, server.URL+"/v2/some-repo/manifests/some-tag", bytes.NewReader([]byte("some-manifest")))
+			h.AssertNil(t, err)
+
+			resp, err := transport.RoundTrip(req)
+			h.AssertNil(t, err)
+			defer resp.Body.Close()
+			h.AssertEq(t, resp.StatusCode, http.StatusCreated)
+			h.AssertEq(t, bodies, []string{"some-manifest", "some-manifest", "some-manifest"})
+		})
+
+		it("gives up after the maximum number of attempts", func() {
+			failures = 5
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/v2/some-repo/manifests/some-tag", nil)
+			h.AssertNil(t, err)
+
+			_, err = transport.RoundTrip(req) //nolint:bodyclose
+			h.AssertError(t, err, "unexpected status code 503 Service Unavailable (after 3 attempt(s))")
+			h.AssertEq(t, atomic.LoadInt32(&attempts), int32(3))
+		})
+
+		it("does not retry requests whose body cannot be replayed", func() {
+			failures = 1
+			req, err := http.NewRequest(http.MethodPatch, server.URL+"/v2/some-repo/blobs/uploads/some-upload", io.NopCloser(bytes.NewReader([]byte("some-layer"))))
+			h.AssertNil(t, err)
+
+			_, err = transport.RoundTrip(req) //nolint:bodyclose
+			h.AssertError(t, err, "(after 1 attempt(s))")
+			h.AssertEq(t, atomic.LoadInt32(&attempts), int32(1))
+		})
+
+		it("does not retry other status codes", func() {
+			failures = 0
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/v2/some-repo/manifests/some-tag", nil)
+			h.AssertNil(t, err)
+
+			resp, err := transport.RoundTrip(req)
+			h.AssertNil(t, err)
+			defer resp.Body.Close()
+			h.AssertEq(t, resp.StatusCode, http.StatusCreated)
+			h.AssertEq(t, atomic.LoadInt32(&attempts), int32(1))
+		})
+	})
+}
@@ -0,0 +1,192 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// EnvRegistryCACerts is a comma-separated list of PEM-encoded certificate bundles to trust for all registries,
+	// in addition to the system roots.
+	EnvRegistryCACerts = "CNB_REGISTRY_CA_CERTS"
+	// EnvRegistryClientCert and EnvRegistryClientKey are the locations of a PEM-encoded client certificate and key,
+	// presented to registries that request a client certificate (mutual TLS).
+	EnvRegistryClientCert = "CNB_REGISTRY_CLIENT_CERT"
+	EnvRegistryClientKey  = "CNB_REGISTRY_CLIENT_KEY"
+	// EnvRegistryTLSConfig is the location of a TOML file with TLS settings for specific registries, e.g.:
+	//
+	//	[registries."registry.internal"]
+	//	ca-certs = ["/platform/certs/internal.pem"]
+	//
+	//	[registries."localhost:5000"]
+	//	insecure = true
+	//
+	// Certificate verification may only be skipped for the registries named in this file.
+	EnvRegistryTLSConfig = "CNB_REGISTRY_TLS_CONFIG"
+)
+
+// TLSConfig is the TLS configuration for registry requests.
+type TLSConfig struct {
+	// CACertPaths are the locations of additional certificates to trust for all registries.
+	CACertPaths []string
+	// ClientCertPath and ClientKeyPath are the locations of the client certificate and key (if any).
+	ClientCertPath string
+	ClientKeyPath  string
+	// Registries holds the TLS settings for specific registries, keyed by registry host (with or without the port).
+	Registries map[string]RegistryTLSConfig
+}
+
+// RegistryTLSConfig holds the TLS settings for a specific registry.
+type RegistryTLSConfig struct {
+	// CACerts are the locations of additional certificates to trust for the registry.
+	CACerts []string `toml:"ca-certs"`
+	// Insecure if true skips certificate verification for the registry.
+	Insecure bool `toml:"insecure"`
+}
+
+// TLSConfigFromEnv returns the TLS configuration from the provided environment lookup function.
+func TLSConfigFromEnv(getenv func(string) string) (TLSConfig, error) {
+	config := TLSConfig{
+		CACertPaths:    SplitPaths(getenv(EnvRegistryCACerts)),
+		ClientCertPath: getenv(EnvRegistryClientCert),
+		ClientKeyPath:  getenv(EnvRegistryClientKey),
+	}
+	if proxyCACert := getenv(EnvProxyCACert); proxyCACert != "" {
+		config.CACertPaths = append(config.CACertPaths, proxyCACert)
+	}
+	if err := config.ReadRegistries(getenv(EnvRegistryTLSConfig)); err != nil {
+		return TLSConfig{}, err
+	}
+	return config, nil
+}
+
+// SplitPaths splits a comma-separated list of paths.
+func SplitPaths(val string) []string {
+	var paths []string
+	for _, path := range strings.Split(val, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ReadRegistries reads the TLS settings for specific registries from the provided file (if any).
+func (c *TLSConfig) ReadRegistries(path string) error {
+	if path == "" {
+		return nil
+	}
+	var contents struct {
+		Registries map[string]RegistryTLSConfig `toml:"registries"`
+	}
+	if _, err := toml.DecodeFile(path, &contents); err != nil {
+		return fmt.Errorf("failed to read registry TLS config: %w", err)
+	}
+	c.Registries = contents.Registries
+	return nil
+}
+
+// ClientConfig returns the client TLS configuration for all registries,
+// or nil if the default configuration should be used.
+func (c TLSConfig) ClientConfig() (*tls.Config, error) {
+	if len(c.CACertPaths) == 0 && c.ClientCertPath == "" && c.ClientKeyPath == "" {
+		return nil, nil
+	}
+	return c.clientConfig(nil)
+}
+
+func (c TLSConfig) clientConfig(settings *RegistryTLSConfig) (*tls.Config, error) {
+	paths := c.CACertPaths
+	if settings != nil {
+		paths = append(append([]string{}, c.CACertPaths...), settings.CACerts...)
+	}
+	roots, err := certPool(paths)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	if settings != nil && settings.Insecure {
+		config.InsecureSkipVerify = true //nolint:gosec
+	}
+	if c.ClientCertPath != "" || c.ClientKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCertPath, c.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// DialTLSContext returns a function that establishes TLS connections using the settings for the registry being dialed,
+// or nil if there are no registry-specific settings.
+// Registry-specific settings apply to registries that are contacted directly (i.e., not through a proxy);
+// connections through a proxy use the configuration for all registries.
+func (c TLSConfig) DialTLSContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	if len(c.Registries) == 0 {
+		return nil, nil
+	}
+	defaultConfig, err := c.clientConfig(nil)
+	if err != nil {
+		return nil, err
+	}
+	registryConfigs := map[string]*tls.Config{}
+	for registry, settings := range c.Registries {
+		settings := settings
+		if registryConfigs[registry], err = c.clientConfig(&settings); err != nil {
+			return nil, err
+		}
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		config := defaultConfig
+		for _, key := range registryKeys(addr) {
+			if registryConfig, ok := registryConfigs[key]; ok {
+				config = registryConfig
+				break
+			}
+		}
+		hostname, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		config = config.Clone()
+		config.ServerName = hostname
+		config.NextProtos = []string{"h2", "http/1.1"}
+
+		rawConn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		conn := tls.Client(rawConn, config)
+		if err = conn.HandshakeContext(ctx); err != nil {
+			rawConn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}, nil
+}
+
+// certPool returns the system roots along with the certificates at the provided paths.
+func certPool(paths []string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(contents) {
+			return nil, fmt.Errorf("failed to read CA certificate: no certificates found in '%s'", path)
+		}
+	}
+	return pool, nil
+}
@@ -0,0 +1,120 @@
+// Package profile writes pprof profiles of the lifecycle, so that performance issues seen in platform builds
+// can be diagnosed without a custom build of the lifecycle.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+)
+
+const (
+	// KindCPU profiles CPU usage for the duration of the phase.
+	KindCPU = "cpu"
+	// KindMem profiles heap memory in use when the phase exits.
+	KindMem = "mem"
+	// KindAllocs profiles all memory allocated during the phase.
+	KindAllocs = "allocs"
+)
+
+// Spec is a request to write a profile of the given kind to the given path.
+type Spec struct {
+	Kind string
+	Path string
+}
+
+// ParseSpec parses a profile request in the form <kind>:<path>, e.g., cpu:/tmp/detect.cpu.pprof.
+func ParseSpec(s string) (Spec, error) {
+	kind, path, ok := strings.Cut(s, ":")
+	if !ok || path == "" {
+		return Spec{}, fmt.Errorf("invalid profile '%s', must be of the form <kind>:<path>", s)
+	}
+	switch kind {
+	case KindCPU, KindMem, KindAllocs:
+		return Spec{Kind: kind, Path: path}, nil
+	default:
+		return Spec{}, fmt.Errorf("invalid profile kind '%s', must be one of: %s, %s, %s", kind, KindCPU, KindMem, KindAllocs)
+	}
+}
+
+// Profiler writes the requested profiles.
+type Profiler struct {
+	specs   []Spec
+	cpuFile *os.File
+}
+
+// Start begins profiling for each of the provided specs.
+// CPU profiles are recorded until Stop is called; memory profiles are written when Stop is called.
+func Start(specs []Spec) (*Profiler, error) {
+	var cpuSpec *Spec
+	for i, spec := range specs {
+		if spec.Kind != KindCPU {
+			continue
+		}
+		if cpuSpec != nil {
+			return nil, errors.New("only one cpu profile may be requested")
+		}
+		cpuSpec = &specs[i]
+	}
+	p := &Profiler{specs: specs}
+	if cpuSpec != nil {
+		f, err := create(cpuSpec.Path)
+		if err != nil {
+			return nil, err
+		}
+		if err = pprof.StartCPUProfile(f); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to start cpu profile: %w", err)
+		}
+		p.cpuFile = f
+	}
+	return p, nil
+}
+
+// Stop finishes the CPU profile (if any) and writes any memory profiles.
+func (p *Profiler) Stop() error {
+	var errs []error
+	if p.cpuFile != nil {
+		pprof.StopCPUProfile()
+		errs = append(errs, p.cpuFile.Close())
+		p.cpuFile = nil
+	}
+	for _, spec := range p.specs {
+		switch spec.Kind {
+		case KindMem:
+			errs = append(errs, writeProfile("heap", spec.Path))
+		case KindAllocs:
+			errs = append(errs, writeProfile("allocs", spec.Path))
+		}
+	}
+	p.specs = nil
+	return errors.Join(errs...)
+}
+
+func writeProfile(name, path string) error {
+	f, err := create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	runtime.GC() // get up-to-date statistics
+	if err = pprof.Lookup(name).WriteTo(f, 0); err != nil {
+		return fmt.Errorf("failed to write %s profile: %w", name, err)
+	}
+	return nil
+}
+
+func create(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to create profile '%s': %w", path, err)
+	}
+	return f, nil
+}
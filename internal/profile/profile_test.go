@@ -0,0 +1,78 @@
+package profile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/profile"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestProfile(t *testing.T) {
+	spec.Run(t, "Profile", testProfile, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testProfile(t *testing.T, when spec.G, it spec.S) {
+	var tmpDir string
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle.test")
+		h.AssertNil(t, err)
+	})
+
+	it.After(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	when("#ParseSpec", func() {
+		it("parses the kind and path", func() {
+			spec, err := profile.ParseSpec("mem:/some/path:with-colon")
+			h.AssertNil(t, err)
+			h.AssertEq(t, spec, profile.Spec{Kind: profile.KindMem, Path: "/some/path:with-colon"})
+		})
+
+		it("errors for unknown kinds", func() {
+			_, err := profile.ParseSpec("block:/some/path")
+			h.AssertError(t, err, "invalid profile kind 'block'")
+		})
+
+		it("errors when the path is missing", func() {
+			_, err := profile.ParseSpec("cpu")
+			h.AssertError(t, err, "must be of the form <kind>:<path>")
+		})
+	})
+
+	when("#Start", func() {
+		it("writes the requested profiles when stopped", func() {
+			specs := []profile.Spec{
+				{Kind: profile.KindCPU, Path: filepath.Join(tmpDir, "cpu.pprof")},
+				{Kind: profile.KindMem, Path: filepath.Join(tmpDir, "some-dir", "mem.pprof")},
+				{Kind: profile.KindAllocs, Path: filepath.Join(tmpDir, "allocs.pprof")},
+			}
+			profiler, err := profile.Start(specs)
+			h.AssertNil(t, err)
+			h.AssertNil(t, profiler.Stop())
+
+			for _, spec := range specs {
+				fi, err := os.Stat(spec.Path)
+				h.AssertNil(t, err)
+				if spec.Kind != profile.KindCPU {
+					h.AssertEq(t, fi.Size() > 0, true)
+				}
+			}
+		})
+
+		it("errors when more than one cpu profile is requested", func() {
+			_, err := profile.Start([]profile.Spec{
+				{Kind: profile.KindCPU, Path: filepath.Join(tmpDir, "cpu.pprof")},
+				{Kind: profile.KindCPU, Path: filepath.Join(tmpDir, "other-cpu.pprof")},
+			})
+			h.AssertError(t, err, "only one cpu profile may be requested")
+		})
+	})
+}
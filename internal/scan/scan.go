@@ -0,0 +1,141 @@
+// Package scan runs a platform-provided vulnerability scanner against the SBOM of an application image.
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	VerdictFail = "fail"
+	VerdictPass = "pass"
+	VerdictWarn = "warn"
+
+	// Timeout is the maximum duration of a scan, so that an unresponsive scanner does not block the export indefinitely.
+	Timeout = 5 * time.Minute
+)
+
+var client = &http.Client{Timeout: Timeout}
+
+// Result is the outcome of a scan, as reported by the scanner.
+type Result struct {
+	// Scanner is the scanner executable or endpoint that produced the result.
+	Scanner string `json:"-"`
+	// Image identifies the scanned image (a digest reference, or an image ID for images exported to a daemon).
+	Image string `json:"-"`
+	// Verdict is one of "pass", "warn" or "fail".
+	Verdict string `json:"verdict"`
+	// Summary is the number of findings, keyed by severity (e.g., "critical", "high").
+	Summary map[string]int `json:"summary,omitempty"`
+	// Message is an optional human-readable description of the result.
+	Message string `json:"message,omitempty"`
+}
+
+func (r Result) String() string {
+	s := r.Verdict
+	if len(r.Summary) > 0 {
+		var severities []string
+		for severity := range r.Summary {
+			severities = append(severities, severity)
+		}
+		sort.Strings(severities)
+		var counts []string
+		for _, severity := range severities {
+			counts = append(counts, fmt.Sprintf("%s: %d", severity, r.Summary[severity]))
+		}
+		s += " (" + strings.Join(counts, ", ") + ")"
+	}
+	if r.Message != "" {
+		s += ": " + r.Message
+	}
+	return s
+}
+
+// Run scans the image using the SBOM at sbomPath (a CycloneDX document).
+//
+// If the scanner is an http(s) URL, the image and the SBOM are sent as a JSON object ({"image": ..., "sbom": ...})
+// in a POST request, and the result is read from the response body.
+// Otherwise, the scanner is an executable that is run with the SBOM path and the image as arguments,
+// and the result is read from its standard output.
+// In both cases, the result is a JSON object like {"verdict": "fail", "summary": {"critical": 1}, "message": "..."}.
+// The scan fails if it does not complete within Timeout, or when the context is done.
+func Run(ctx context.Context, scanner, sbomPath, image string) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	var (
+		out []byte
+		err error
+	)
+	if strings.HasPrefix(scanner, "http://") || strings.HasPrefix(scanner, "https://") {
+		out, err = post(ctx, scanner, sbomPath, image)
+	} else {
+		out, err = execute(ctx, scanner, sbomPath, image)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	var result Result
+	if err = json.Unmarshal(out, &result); err != nil {
+		return Result{}, fmt.Errorf("parsing scan result: %w", err)
+	}
+	switch result.Verdict {
+	case VerdictFail, VerdictPass, VerdictWarn:
+	default:
+		return Result{}, fmt.Errorf("invalid scan verdict '%s': must be one of '%s', '%s' or '%s'", result.Verdict, VerdictPass, VerdictWarn, VerdictFail)
+	}
+	result.Scanner = scanner
+	result.Image = image
+	return result, nil
+}
+
+func execute(ctx context.Context, scanner, sbomPath, image string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, scanner, sbomPath, image) // #nosec G204
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running scanner '%s': %w: %s", scanner, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func post(ctx context.Context, endpoint, sbomPath, image string) ([]byte, error) {
+	sbom, err := os.ReadFile(sbomPath) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(struct {
+		Image string          `json:"image"`
+		SBOM  json.RawMessage `json:"sbom"`
+	}{Image: image, SBOM: sbom})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling scanner '%s': %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("calling scanner '%s': unexpected status %s: %s", endpoint, resp.Status, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
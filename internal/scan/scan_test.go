@@ -0,0 +1,132 @@
+package scan_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/scan"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestScan(t *testing.T) {
+	spec.Run(t, "Scan", testScan, spec.Report(report.Terminal{}))
+}
+
+func testScan(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir   string
+		sbomPath string
+	)
+
+	it.Before(func() {
+		tmpDir = t.TempDir()
+		sbomPath = filepath.Join(tmpDir, "sbom.cdx.json")
+		h.Mkfile(t, `{"bomFormat": "CycloneDX"}`, sbomPath)
+	})
+
+	when("the scanner is an executable", func() {
+		it.Before(func() {
+			if runtime.GOOS == "windows" {
+				t.Skip("scanner scripts are not supported on windows")
+			}
+		})
+
+		writeScanner := func(script string) string {
+			path := filepath.Join(tmpDir, "scanner")
+			h.AssertNil(t, os.WriteFile(path, []byte("#!/usr/bin/env bash\n"+script), 0755)) // #nosec G306
+			return path
+		}
+
+		it("returns the result written by the scanner", func() {
+			scanner := writeScanner(`[[ "$1" == *sbom.cdx.json && "$2" == "some-image@sha256:abc" ]] || exit 1
+echo '{"verdict": "fail", "summary": {"critical": 1, "high": 2}, "message": "some-message"}'`)
+
+			result, err := scan.Run(context.Background(), scanner, sbomPath, "some-image@sha256:abc")
+			h.AssertNil(t, err)
+			h.AssertEq(t, result, scan.Result{
+				Scanner: scanner,
+				Image:   "some-image@sha256:abc",
+				Verdict: scan.VerdictFail,
+				Summary: map[string]int{"critical": 1, "high": 2},
+				Message: "some-message",
+			})
+		})
+
+		it("summarizes the result", func() {
+			result := scan.Result{Verdict: scan.VerdictWarn, Summary: map[string]int{"low": 3, "high": 1}, Message: "some-message"}
+			h.AssertEq(t, result.String(), "warn (high: 1, low: 3): some-message")
+		})
+
+		it("errors if the scanner fails", func() {
+			scanner := writeScanner(`echo some-error >&2; exit 3`)
+
+			_, err := scan.Run(context.Background(), scanner, sbomPath, "some-image")
+			h.AssertError(t, err, "exit status 3: some-error")
+		})
+
+		it("errors if the verdict is invalid", func() {
+			scanner := writeScanner(`echo '{"verdict": "maybe"}'`)
+
+			_, err := scan.Run(context.Background(), scanner, sbomPath, "some-image")
+			h.AssertError(t, err, "invalid scan verdict 'maybe'")
+		})
+	})
+
+	when("the scanner is an endpoint", func() {
+		it("posts the image and the SBOM and returns the result", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					Image string                 `json:"image"`
+					SBOM  map[string]interface{} `json:"sbom"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Image != "some-image" || req.SBOM["bomFormat"] != "CycloneDX" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				_, _ = w.Write([]byte(`{"verdict": "pass"}`))
+			}))
+			defer server.Close()
+
+			result, err := scan.Run(context.Background(), server.URL, sbomPath, "some-image")
+			h.AssertNil(t, err)
+			h.AssertEq(t, result.Verdict, scan.VerdictPass)
+		})
+
+		it("errors for unsuccessful responses", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			_, err := scan.Run(context.Background(), server.URL, sbomPath, "some-image")
+			h.AssertError(t, err, "unexpected status 500")
+		})
+
+		it("errors when the context is done before the scanner responds", func() {
+			done := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-done:
+				}
+			}))
+			defer server.Close()
+			defer close(done)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, err := scan.Run(ctx, server.URL, sbomPath, "some-image")
+			h.AssertError(t, err, "context deadline exceeded")
+		})
+	})
+}
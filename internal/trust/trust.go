@@ -0,0 +1,181 @@
+// Package trust verifies the cosign signatures of base images against public keys provided by the platform.
+package trust
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+const (
+	// SignatureAnnotation records the base64-encoded signature of the payload of a cosign signature layer.
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// SimpleSigningMediaType is the media type of cosign signature layers, whose contents are the signed payload.
+	SimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+
+	signatureTagSuffix = ".sig"
+)
+
+// Payload is the (simple signing) payload signed by cosign, identifying the signed image by its manifest digest.
+type Payload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+// SignatureTag returns the tag that cosign pushes the signatures of the image with the provided digest to, in the repository of the image.
+func SignatureTag(repo name.Repository, digest v1.Hash) name.Tag {
+	return repo.Tag(fmt.Sprintf("%s-%s%s", digest.Algorithm, digest.Hex, signatureTagSuffix))
+}
+
+// Verify verifies that the image at imageRef has a cosign signature made with one of the public keys at keyPaths.
+// It returns the digest of the verified image, which should be used to read the image so that a tag that is moved
+// after verification is not trusted.
+func Verify(imageRef string, keyPaths []string, keychain authn.Keychain) (v1.Hash, error) {
+	keys, err := readPublicKeys(keyPaths)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	ref, err := name.ParseReference(imageRef, name.WeakValidation)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("parsing image reference '%s': %w", imageRef, err)
+	}
+	opt := remote.WithAuthFromKeychain(keychain)
+	desc, err := remote.Head(ref, opt)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("getting descriptor for '%s': %w", imageRef, err)
+	}
+	if digest, ok := ref.(name.Digest); ok && digest.DigestStr() != desc.Digest.String() {
+		return v1.Hash{}, fmt.Errorf("image '%s' has digest '%s'", imageRef, desc.Digest)
+	}
+	signatures, err := remote.Image(SignatureTag(ref.Context(), desc.Digest), opt)
+	if err != nil {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound {
+			return v1.Hash{}, fmt.Errorf("image '%s' is not signed", imageRef)
+		}
+		return v1.Hash{}, fmt.Errorf("getting signatures for '%s': %w", imageRef, err)
+	}
+	manifest, err := signatures.Manifest()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("getting signatures for '%s': %w", imageRef, err)
+	}
+	for _, layerDesc := range manifest.Layers {
+		if layerDesc.MediaType != SimpleSigningMediaType {
+			continue
+		}
+		layer, err := signatures.LayerByDigest(layerDesc.Digest)
+		if err != nil {
+			return v1.Hash{}, err
+		}
+		payload, err := readPayload(layer)
+		if err != nil {
+			return v1.Hash{}, fmt.Errorf("reading signature for '%s': %w", imageRef, err)
+		}
+		if verifyPayload(payload, layerDesc.Annotations[SignatureAnnotation], desc.Digest, keys) {
+			return desc.Digest, nil
+		}
+	}
+	return v1.Hash{}, fmt.Errorf("image '%s' is not signed by a trusted key", imageRef)
+}
+
+func readPayload(layer v1.Layer) ([]byte, error) {
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// verifyPayload returns true if the payload identifies the image with the provided digest, and the signature is valid for one of the keys.
+func verifyPayload(payload []byte, signature string, digest v1.Hash, keys []crypto.PublicKey) bool {
+	var p Payload
+	if err := json.Unmarshal(payload, &p); err != nil || p.Critical.Image.DockerManifestDigest != digest.String() {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	for _, key := range keys {
+		if verifySignature(key, payload, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignature verifies the signature with the key the way cosign signs payloads:
+// ECDSA and RSA (PKCS #1 v1.5) signatures are made over the SHA-256 digest of the payload, and Ed25519 signatures over the payload itself.
+func verifySignature(key crypto.PublicKey, payload, sig []byte) bool {
+	sum := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, sum[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, sig)
+	default:
+		return false
+	}
+}
+
+func readPublicKeys(paths []string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading public key: %w", err)
+		}
+		key, err := ParsePublicKey(contents)
+		if err != nil {
+			return nil, fmt.Errorf("parsing public key '%s': %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ParsePublicKey parses a PEM-encoded (PKIX) ECDSA, RSA or Ed25519 public key, such as one generated by cosign.
+func ParsePublicKey(contents []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(contents)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("no PEM-encoded public key found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
@@ -0,0 +1,162 @@
+package trust_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/trust"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestTrust(t *testing.T) {
+	spec.Run(t, "Trust", testTrust, spec.Report(report.Terminal{}))
+}
+
+func testTrust(t *testing.T, when spec.G, it spec.S) {
+	var (
+		server   *httptest.Server
+		imageRef name.Reference
+		digest   v1.Hash
+		key      *ecdsa.PrivateKey
+		keyPath  string
+	)
+
+	it.Before(func() {
+		server = httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+		var err error
+		imageRef, err = name.ParseReference(strings.TrimPrefix(server.URL, "http://") + "/some-stack/run:latest")
+		h.AssertNil(t, err)
+		image, err := random.Image(1024, 1)
+		h.AssertNil(t, err)
+		h.AssertNil(t, remote.Write(imageRef, image))
+		digest, err = image.Digest()
+		h.AssertNil(t, err)
+
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		h.AssertNil(t, err)
+		keyPath = writePublicKey(t, key.Public())
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	sign := func(signer crypto.Signer, payloadDigest v1.Hash) {
+		var payload trust.Payload
+		payload.Critical.Identity.DockerReference = imageRef.Context().Name()
+		payload.Critical.Image.DockerManifestDigest = payloadDigest.String()
+		payload.Critical.Type = "cosign container image signature"
+		contents, err := json.Marshal(payload)
+		h.AssertNil(t, err)
+		var sig []byte
+		if _, ok := signer.(ed25519.PrivateKey); ok {
+			sig, err = signer.Sign(rand.Reader, contents, crypto.Hash(0))
+		} else {
+			sum := sha256.Sum256(contents)
+			sig, err = signer.Sign(rand.Reader, sum[:], crypto.SHA256)
+		}
+		h.AssertNil(t, err)
+		signatures, err := mutate.Append(empty.Image, mutate.Addendum{
+			Layer:       static.NewLayer(contents, trust.SimpleSigningMediaType),
+			Annotations: map[string]string{trust.SignatureAnnotation: base64.StdEncoding.EncodeToString(sig)},
+		})
+		h.AssertNil(t, err)
+		h.AssertNil(t, remote.Write(trust.SignatureTag(imageRef.Context(), digest), signatures))
+	}
+
+	when("#Verify", func() {
+		it("returns the digest of an image signed by a trusted key", func() {
+			sign(key, digest)
+
+			verified, err := trust.Verify(imageRef.String(), []string{keyPath}, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			h.AssertEq(t, verified, digest)
+		})
+
+		it("accepts signatures made with any of the keys", func() {
+			_, edKey, err := ed25519.GenerateKey(rand.Reader)
+			h.AssertNil(t, err)
+			sign(edKey, digest)
+
+			_, err = trust.Verify(imageRef.String(), []string{keyPath, writePublicKey(t, edKey.Public())}, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+		})
+
+		it("refuses unsigned images", func() {
+			_, err := trust.Verify(imageRef.String(), []string{keyPath}, authn.DefaultKeychain)
+			h.AssertError(t, err, "image '"+imageRef.String()+"' is not signed")
+		})
+
+		it("refuses images signed by other keys", func() {
+			otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			h.AssertNil(t, err)
+			sign(otherKey, digest)
+
+			_, err = trust.Verify(imageRef.String(), []string{keyPath}, authn.DefaultKeychain)
+			h.AssertError(t, err, "image '"+imageRef.String()+"' is not signed by a trusted key")
+		})
+
+		it("refuses signatures of other images", func() {
+			sign(key, v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("0", 64)})
+
+			_, err := trust.Verify(imageRef.String(), []string{keyPath}, authn.DefaultKeychain)
+			h.AssertError(t, err, "is not signed by a trusted key")
+		})
+
+		it("errors if a key cannot be parsed", func() {
+			badKeyPath := filepath.Join(t.TempDir(), "bad.pub")
+			h.Mkfile(t, "not a key", badKeyPath)
+
+			_, err := trust.Verify(imageRef.String(), []string{badKeyPath}, authn.DefaultKeychain)
+			h.AssertError(t, err, "no PEM-encoded public key found")
+		})
+	})
+
+	when("#ParsePublicKey", func() {
+		it("parses cosign public keys", func() {
+			contents, err := os.ReadFile(filepath.Join("..", "..", "cosign.pub"))
+			h.AssertNil(t, err)
+
+			key, err := trust.ParsePublicKey(contents)
+			h.AssertNil(t, err)
+			_, ok := key.(*ecdsa.PublicKey)
+			h.AssertEq(t, ok, true)
+		})
+	})
+}
+
+func writePublicKey(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	h.AssertNil(t, err)
+	path := filepath.Join(t.TempDir(), "key.pub")
+	h.AssertNil(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	return path
+}
@@ -0,0 +1,55 @@
+//go:build unix
+
+package usage
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// maxRSSUnit is the unit of the peak resident memory reported by getrusage, which is kilobytes except on darwin.
+var maxRSSUnit int64 = 1024
+
+func init() {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		maxRSSUnit = 1
+	}
+}
+
+func processUsage(state *os.ProcessState) Usage {
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return Usage{CPUSeconds: (state.UserTime() + state.SystemTime()).Seconds()}
+	}
+	return fromRusage(ru)
+}
+
+// selfUsage returns the usage of the current process and its child processes that have exited.
+func selfUsage() Usage {
+	var self, children syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &self); err != nil {
+		return Usage{}
+	}
+	if err := syscall.Getrusage(syscall.RUSAGE_CHILDREN, &children); err != nil {
+		return fromRusage(&self)
+	}
+	u := fromRusage(&self)
+	c := fromRusage(&children)
+	u.CPUSeconds += c.CPUSeconds
+	if c.PeakMemoryBytes > u.PeakMemoryBytes {
+		u.PeakMemoryBytes = c.PeakMemoryBytes
+	}
+	return u
+}
+
+func fromRusage(ru *syscall.Rusage) Usage {
+	return Usage{
+		PeakMemoryBytes: int64(ru.Maxrss) * maxRSSUnit,
+		CPUSeconds:      seconds(ru.Utime) + seconds(ru.Stime),
+	}
+}
+
+func seconds(tv syscall.Timeval) float64 {
+	return float64(tv.Sec) + float64(tv.Usec)/1e6
+}
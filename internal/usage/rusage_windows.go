@@ -0,0 +1,12 @@
+package usage
+
+import "os"
+
+func processUsage(state *os.ProcessState) Usage {
+	return Usage{CPUSeconds: (state.UserTime() + state.SystemTime()).Seconds()}
+}
+
+// selfUsage returns an empty usage, as the usage of the current process is not measured on Windows.
+func selfUsage() Usage {
+	return Usage{}
+}
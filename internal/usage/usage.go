@@ -0,0 +1,168 @@
+// Package usage records the resources (peak memory, CPU time and disk) used by each lifecycle phase and each buildpack,
+// so that they can be reported to the platform in report.toml, e.g., to right-size build containers.
+package usage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/buildpacks/lifecycle/internal/encoding"
+)
+
+// Usage describes the resources used by a phase or a buildpack. Resources that could not be measured are omitted.
+type Usage struct {
+	// PeakMemoryBytes is the peak resident memory of the process (for a phase, of the lifecycle or any of its child processes).
+	PeakMemoryBytes int64 `toml:"peak-memory-bytes,omitzero" json:"peak-memory-bytes,omitempty"`
+	// CPUSeconds is the user and system CPU time of the process, including its child processes.
+	CPUSeconds float64 `toml:"cpu-seconds,omitzero" json:"cpu-seconds,omitempty"`
+	// DiskBytes is the size of the files in the layers directory (for a buildpack, of its layers) when the process finished.
+	DiskBytes int64 `toml:"disk-bytes,omitzero" json:"disk-bytes,omitempty"`
+}
+
+// Phase is the resource usage of a lifecycle phase.
+type Phase struct {
+	Name string `toml:"name" json:"name"`
+	Usage
+}
+
+// Buildpack is the resource usage of a buildpack (or image extension) during a step, e.g., "build".
+type Buildpack struct {
+	ID      string `toml:"id" json:"id"`
+	Version string `toml:"version" json:"version"`
+	Step    string `toml:"step" json:"step"`
+	Usage
+}
+
+// Report is the resource usage of the phases and buildpacks of a build.
+type Report struct {
+	Phases     []Phase     `toml:"phases,omitempty" json:"phases,omitempty"`
+	Buildpacks []Buildpack `toml:"buildpacks,omitempty" json:"buildpacks,omitempty"`
+}
+
+var recorded struct {
+	sync.Mutex
+	report    Report
+	phase     string // the current phase, if started
+	layersDir string
+}
+
+// Start records the usage of the current process as the provided phase, measuring the disk usage of the layers directory.
+func Start(phase, layersDir string) {
+	recorded.Lock()
+	defer recorded.Unlock()
+	recorded.phase = phase
+	recorded.layersDir = layersDir
+}
+
+// RecordProcess records the usage of a buildpack process that has exited,
+// measuring the disk usage of the provided directory (if not empty).
+func RecordProcess(id, version, step string, state *os.ProcessState, dir string) {
+	if state == nil {
+		return
+	}
+	u := processUsage(state)
+	if dir != "" {
+		u.DiskBytes = DiskUsage(dir)
+	}
+	recorded.Lock()
+	defer recorded.Unlock()
+	recorded.report.Buildpacks = append(recorded.report.Buildpacks, Buildpack{ID: id, Version: version, Step: step, Usage: u})
+}
+
+// Collect returns the usage recorded by previous phases and the current process, including the usage of the current phase so far,
+// or nil if nothing was recorded.
+func Collect() *Report {
+	recorded.Lock()
+	defer recorded.Unlock()
+	report := Report{
+		Phases:     append([]Phase{}, recorded.report.Phases...),
+		Buildpacks: append([]Buildpack{}, recorded.report.Buildpacks...),
+	}
+	if recorded.phase != "" {
+		report.Phases = withPhase(report.Phases, currentPhase())
+	}
+	if len(report.Phases) == 0 && len(report.Buildpacks) == 0 {
+		return nil
+	}
+	return &report
+}
+
+// currentPhase returns the usage of the current phase so far; recorded must be locked.
+func currentPhase() Phase {
+	u := selfUsage()
+	if recorded.layersDir != "" {
+		u.DiskBytes = DiskUsage(recorded.layersDir)
+	}
+	return Phase{Name: recorded.phase, Usage: u}
+}
+
+// withPhase adds the phase to the list, replacing any previous usage of the same phase (e.g., if the phase was retried).
+func withPhase(phases []Phase, phase Phase) []Phase {
+	for i, p := range phases {
+		if p.Name == phase.Name {
+			phases[i] = phase
+			return phases
+		}
+	}
+	return append(phases, phase)
+}
+
+// Reset removes all recorded usage.
+func Reset() {
+	recorded.Lock()
+	defer recorded.Unlock()
+	recorded.report = Report{}
+	recorded.phase = ""
+	recorded.layersDir = ""
+}
+
+// Load records the usage in the file at path (written by Save in a previous phase), if it exists.
+func Load(path string) error {
+	var contents Report
+	if _, err := toml.DecodeFile(path, &contents); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	recorded.Lock()
+	defer recorded.Unlock()
+	for _, p := range contents.Phases {
+		recorded.report.Phases = withPhase(recorded.report.Phases, p)
+	}
+	recorded.report.Buildpacks = append(contents.Buildpacks, recorded.report.Buildpacks...)
+	return nil
+}
+
+// Save writes the recorded usage, including the usage of the current phase, to the file at path,
+// so that it can be loaded by later phases. Nothing is written if the parent directory does not exist.
+func Save(path string) error {
+	report := Collect()
+	if report == nil {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Dir(path)); err != nil {
+		return nil
+	}
+	return encoding.WriteTOML(path, report)
+}
+
+// DiskUsage returns the total size of the regular files in the provided directory,
+// ignoring files that cannot be read, as disk usage is only informational.
+func DiskUsage(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			total += fi.Size()
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,101 @@
+package usage_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/internal/usage"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestUsage(t *testing.T) {
+	spec.Run(t, "Usage", testUsage, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testUsage(t *testing.T, when spec.G, it spec.S) {
+	var layersDir string
+
+	it.Before(func() {
+		var err error
+		layersDir, err = os.MkdirTemp("", "lifecycle.test")
+		h.AssertNil(t, err)
+		h.AssertNil(t, os.MkdirAll(filepath.Join(layersDir, "some_buildpack", "some-layer"), 0755))
+		h.Mkfile(t, "0123456789", filepath.Join(layersDir, "some_buildpack", "some-layer", "some-file"))
+		usage.Reset()
+	})
+
+	it.After(func() {
+		usage.Reset()
+		_ = os.RemoveAll(layersDir)
+	})
+
+	when("nothing was recorded", func() {
+		it("returns nil", func() {
+			h.AssertNil(t, usage.Collect())
+		})
+	})
+
+	when("#Start", func() {
+		it("records the usage of the current phase", func() {
+			h.SkipIf(t, runtime.GOOS == "windows", "the usage of the current process is not measured on windows")
+			usage.Start("builder", layersDir)
+
+			report := usage.Collect()
+			h.AssertEq(t, len(report.Phases), 1)
+			h.AssertEq(t, report.Phases[0].Name, "builder")
+			h.AssertEq(t, report.Phases[0].DiskBytes, int64(10))
+			h.AssertEq(t, report.Phases[0].PeakMemoryBytes > 0, true)
+		})
+	})
+
+	when("#RecordProcess", func() {
+		it("records the usage of the buildpack", func() {
+			cmd := exec.Command("go", "version")
+			h.AssertNil(t, cmd.Run())
+			usage.RecordProcess("some/buildpack", "v1", "build", cmd.ProcessState, filepath.Join(layersDir, "some_buildpack"))
+
+			report := usage.Collect()
+			h.AssertEq(t, len(report.Buildpacks), 1)
+			bp := report.Buildpacks[0]
+			h.AssertEq(t, []string{bp.ID, bp.Version, bp.Step}, []string{"some/buildpack", "v1", "build"})
+			h.AssertEq(t, bp.DiskBytes, int64(10))
+			if runtime.GOOS != "windows" {
+				h.AssertEq(t, bp.PeakMemoryBytes > 0, true)
+			}
+		})
+	})
+
+	when("#Save and #Load", func() {
+		it("shares the usage between phases", func() {
+			path := filepath.Join(layersDir, "usage.toml")
+			usage.Start("detector", layersDir)
+			cmd := exec.Command("go", "version")
+			h.AssertNil(t, cmd.Run())
+			usage.RecordProcess("some/buildpack", "v1", "detect", cmd.ProcessState, "")
+			h.AssertNil(t, usage.Save(path))
+
+			usage.Reset()
+			h.AssertNil(t, usage.Load(path))
+			usage.Start("builder", layersDir)
+
+			report := usage.Collect()
+			h.AssertEq(t, len(report.Phases), 2)
+			h.AssertEq(t, report.Phases[0].Name, "detector")
+			h.AssertEq(t, report.Phases[0].DiskBytes, int64(10))
+			h.AssertEq(t, report.Phases[1].Name, "builder")
+			h.AssertEq(t, len(report.Buildpacks), 1)
+			h.AssertEq(t, report.Buildpacks[0].Step, "detect")
+		})
+
+		it("does not fail if the file does not exist", func() {
+			h.AssertNil(t, usage.Load(filepath.Join(layersDir, "usage.toml")))
+			h.AssertNil(t, usage.Save(filepath.Join(layersDir, "missing-dir", "usage.toml")))
+		})
+	})
+}
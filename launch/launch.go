@@ -14,15 +14,19 @@ import (
 )
 
 // Process represents a process to launch at runtime.
+// The Description (e.g., "serves HTTP on $PORT") and Metadata (e.g., an icon) of a process are provided by buildpacks
+// for platforms to display, and are not used by the lifecycle.
 type Process struct {
-	Type             string       `toml:"type" json:"type"`
-	Command          RawCommand   `toml:"command" json:"command"`
-	Args             []string     `toml:"args" json:"args"`
-	Direct           bool         `toml:"direct" json:"direct"`
-	Default          bool         `toml:"default,omitempty" json:"default,omitempty"`
-	BuildpackID      string       `toml:"buildpack-id" json:"buildpackID"`
-	WorkingDirectory string       `toml:"working-dir,omitempty" json:"working-dir,omitempty"`
-	PlatformAPI      *api.Version `toml:"-" json:"-"`
+	Type             string            `toml:"type" json:"type"`
+	Command          RawCommand        `toml:"command" json:"command"`
+	Args             []string          `toml:"args" json:"args"`
+	Direct           bool              `toml:"direct" json:"direct"`
+	Default          bool              `toml:"default,omitempty" json:"default,omitempty"`
+	BuildpackID      string            `toml:"buildpack-id" json:"buildpackID"`
+	WorkingDirectory string            `toml:"working-dir,omitempty" json:"working-dir,omitempty"`
+	Description      string            `toml:"description,omitempty" json:"description,omitempty"`
+	Metadata         map[string]string `toml:"metadata,omitempty" json:"metadata,omitempty"`
+	PlatformAPI      *api.Version      `toml:"-" json:"-"`
 }
 
 func (p Process) NoDefault() Process {
@@ -148,7 +152,34 @@ func ProcessPath(pType string) string {
 
 type Metadata struct {
 	Processes  []Process   `toml:"processes" json:"processes"`
+	Tasks      []Task      `toml:"tasks,omitempty" json:"tasks,omitempty"`
 	Buildpacks []Buildpack `toml:"buildpacks" json:"buildpacks"`
+	// EnvOrder is the IDs of the buildpacks in the order their env contributions are applied, if it differs from the order of Buildpacks.
+	EnvOrder []string `toml:"env-order,omitempty" json:"env-order,omitempty"`
+}
+
+// BuildpacksInEnvOrder returns the buildpacks in the order their env contributions (env files and exec.d executables) are applied.
+func (m Metadata) BuildpacksInEnvOrder() []Buildpack {
+	if len(m.EnvOrder) == 0 {
+		return m.Buildpacks
+	}
+	byID := make(map[string]Buildpack, len(m.Buildpacks))
+	for _, bp := range m.Buildpacks {
+		byID[bp.ID] = bp
+	}
+	var ordered []Buildpack
+	for _, id := range m.EnvOrder {
+		if bp, ok := byID[id]; ok {
+			ordered = append(ordered, bp)
+			delete(byID, id)
+		}
+	}
+	for _, bp := range m.Buildpacks {
+		if _, ok := byID[bp.ID]; ok {
+			ordered = append(ordered, bp)
+		}
+	}
+	return ordered
 }
 
 // Matches is used by goMock to compare two Metadata objects in tests
@@ -159,8 +190,11 @@ func (m Metadata) Matches(x interface{}) bool {
 		return false
 	}
 
-	// don't compare Processes directly, we will compare those individually next
-	if s := cmp.Diff(metadatax, m, cmpopts.IgnoreFields(Metadata{}, "Processes")); s != "" {
+	// don't compare Processes and Tasks directly, we will compare those individually next
+	if s := cmp.Diff(metadatax, m, cmpopts.IgnoreFields(Metadata{}, "Processes", "Tasks")); s != "" {
+		return false
+	}
+	if len(metadatax.Processes) != len(m.Processes) || len(metadatax.Tasks) != len(m.Tasks) {
 		return false
 	}
 
@@ -173,6 +207,13 @@ func (m Metadata) Matches(x interface{}) bool {
 			return false
 		}
 	}
+	for i, t := range m.Tasks {
+		if s := cmp.Diff(metadatax.Tasks[i], t,
+			cmpopts.IgnoreFields(Process{}, "PlatformAPI"),
+			cmpopts.IgnoreFields(RawCommand{}, "PlatformAPI")); s != "" {
+			return false
+		}
+	}
 
 	return true
 }
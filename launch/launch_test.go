@@ -20,6 +20,23 @@ func TestLaunch(t *testing.T) {
 }
 
 func testLaunch(t *testing.T, when spec.G, it spec.S) {
+	when("Metadata", func() {
+		when("#BuildpacksInEnvOrder", func() {
+			it("returns the buildpacks in env order", func() {
+				md := launch.Metadata{
+					Buildpacks: []launch.Buildpack{{ID: "A"}, {ID: "B"}, {ID: "C"}},
+					EnvOrder:   []string{"B", "A"},
+				}
+				h.AssertEq(t, md.BuildpacksInEnvOrder(), []launch.Buildpack{{ID: "B"}, {ID: "A"}, {ID: "C"}})
+			})
+
+			it("returns the buildpacks in group order if there is no env order", func() {
+				md := launch.Metadata{Buildpacks: []launch.Buildpack{{ID: "A"}, {ID: "B"}}}
+				h.AssertEq(t, md.BuildpacksInEnvOrder(), md.Buildpacks)
+			})
+		})
+	})
+
 	when("Process", func() {
 		when("MarshalTOML", func() {
 			it("output command is array", func() {
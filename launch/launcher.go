@@ -17,6 +17,10 @@ var (
 	LauncherPath = filepath.Join(LifecycleDir, "launcher"+exe)
 )
 
+// ExplainEnvFlag invokes the launcher to print the environment of a process type rather than launch it,
+// with the env files that contributed to each variable, e.g., `launcher -explain-env web`.
+const ExplainEnvFlag = "-explain-env"
+
 type Launcher struct {
 	AppDir             string
 	Buildpacks         []Buildpack
@@ -95,6 +99,12 @@ func (l *Launcher) launchDirect(proc Process) error {
 	return nil
 }
 
+// ComposeEnv modifies the environment with the layers of each buildpack, as when launching a process of the provided type.
+// Executables in exec.d directories are not run.
+func (l *Launcher) ComposeEnv(procType string) error {
+	return l.doEnv(procType)
+}
+
 func (l *Launcher) doEnv(procType string) error {
 	return l.eachBuildpack(func(bpAPI *api.Version, bpDir string) error {
 		if err := eachLayer(bpDir, l.doLayerRoot()); err != nil {
@@ -0,0 +1,60 @@
+package launch
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TaskRunExport tasks are run by the platform once the image is exported (e.g., to migrate a database before rolling out the image).
+	TaskRunExport = "export"
+	// TaskRunFirstBoot tasks are run by the launcher before the first process it launches in a container.
+	TaskRunFirstBoot = "first-boot"
+
+	// TaskFlag invokes the launcher to run a task rather than a process, e.g., `launcher -task migrate`.
+	TaskFlag = "-task"
+)
+
+// Task is a process that buildpacks declare to be run once (rather than launched as a process of the image).
+// Tasks are launched like processes, with the environment of the process type matching the task type.
+type Task struct {
+	Process
+	// Run is when the task runs, either TaskRunExport or TaskRunFirstBoot.
+	Run string `toml:"run" json:"run"`
+}
+
+// FindTask returns the task with the provided type.
+func (m Metadata) FindTask(tType string) (Task, bool) {
+	for _, t := range m.Tasks {
+		if t.Type == tType {
+			return t, true
+		}
+	}
+	return Task{}, false
+}
+
+// RunFirstBootTasks runs each first-boot task that has not already completed, in order, using run,
+// and records its completion in stateDir, so that it is not run again when the container restarts.
+func RunFirstBootTasks(tasks []Task, stateDir string, run func(Task) error) error {
+	for _, task := range tasks {
+		if task.Run != TaskRunFirstBoot {
+			continue
+		}
+		marker := filepath.Join(stateDir, EscapeID(task.Type)+".done")
+		if _, err := os.Stat(marker); err == nil {
+			continue
+		}
+		if err := run(task); err != nil {
+			return errors.Wrapf(err, "run first-boot task '%s'", task.Type)
+		}
+		if err := os.MkdirAll(stateDir, 0755); err != nil {
+			return errors.Wrap(err, "create task state directory")
+		}
+		if err := os.WriteFile(marker, nil, 0644); err != nil { // #nosec G306 -- the marker is not sensitive
+			return errors.Wrapf(err, "record completion of task '%s'", task.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package launch_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/launch"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestTask(t *testing.T) {
+	spec.Run(t, "Task", testTask, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testTask(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir   string
+		stateDir string
+		tasks    []launch.Task
+		ran      []string
+		run      func(launch.Task) error
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle.test")
+		h.AssertNil(t, err)
+		stateDir = filepath.Join(tmpDir, "tasks")
+		tasks = []launch.Task{
+			{Process: launch.Process{Type: "migrate", BuildpackID: "some/bp"}, Run: launch.TaskRunExport},
+			{Process: launch.Process{Type: "seed", BuildpackID: "some/bp"}, Run: launch.TaskRunFirstBoot},
+			{Process: launch.Process{Type: "warm/cache", BuildpackID: "other/bp"}, Run: launch.TaskRunFirstBoot},
+		}
+		ran = nil
+		run = func(task launch.Task) error {
+			ran = append(ran, task.Type)
+			return nil
+		}
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.RemoveAll(tmpDir))
+	})
+
+	when("#RunFirstBootTasks", func() {
+		it("runs the first-boot tasks in order", func() {
+			h.AssertNil(t, launch.RunFirstBootTasks(tasks, stateDir, run))
+
+			h.AssertEq(t, ran, []string{"seed", "warm/cache"})
+			h.AssertPathExists(t, filepath.Join(stateDir, "seed.done"))
+			h.AssertPathExists(t, filepath.Join(stateDir, "warm_cache.done"))
+		})
+
+		it("does not run tasks that have already completed", func() {
+			h.AssertNil(t, launch.RunFirstBootTasks(tasks, stateDir, run))
+			ran = nil
+
+			h.AssertNil(t, launch.RunFirstBootTasks(tasks, stateDir, run))
+			h.AssertEq(t, len(ran), 0)
+		})
+
+		when("a task fails", func() {
+			it("returns an error and runs the task again next time", func() {
+				err := launch.RunFirstBootTasks(tasks, stateDir, func(task launch.Task) error {
+					if task.Type == "warm/cache" {
+						return errors.New("some-error")
+					}
+					return run(task)
+				})
+				h.AssertError(t, err, "run first-boot task 'warm/cache': some-error")
+				h.AssertPathExists(t, filepath.Join(stateDir, "seed.done"))
+				h.AssertPathDoesNotExist(t, filepath.Join(stateDir, "warm_cache.done"))
+
+				ran = nil
+				h.AssertNil(t, launch.RunFirstBootTasks(tasks, stateDir, run))
+				h.AssertEq(t, ran, []string{"warm/cache"})
+			})
+		})
+	})
+
+	when("#FindTask", func() {
+		it("returns the task with the provided type", func() {
+			md := launch.Metadata{Tasks: tasks}
+
+			task, ok := md.FindTask("seed")
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, task.Run, launch.TaskRunFirstBoot)
+
+			_, ok = md.FindTask("missing")
+			h.AssertEq(t, ok, false)
+		})
+	})
+
+	when("decoding metadata.toml", func() {
+		it("decodes the tasks", func() {
+			path := filepath.Join(tmpDir, "metadata.toml")
+			h.Mkfile(t,
+				`[[tasks]]`+"\n"+
+					`type = "migrate"`+"\n"+
+					`command = ["rake", "db:migrate"]`+"\n"+
+					`run = "export"`+"\n"+
+					`buildpack-id = "some/bp"`+"\n",
+				path,
+			)
+
+			var md launch.Metadata
+			_, err := toml.DecodeFile(path, &md)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(md.Tasks), 1)
+			h.AssertEq(t, md.Tasks[0].Type, "migrate")
+			h.AssertEq(t, md.Tasks[0].Command.Entries, []string{"rake", "db:migrate"})
+			h.AssertEq(t, md.Tasks[0].Run, launch.TaskRunExport)
+			h.AssertEq(t, md.Tasks[0].BuildpackID, "some/bp")
+		})
+	})
+}
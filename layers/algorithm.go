@@ -0,0 +1,65 @@
+package layers
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DigestAlgorithm is a hash algorithm for the digests of layers, which are formatted as "<name>:<hex>".
+type DigestAlgorithm struct {
+	Name string
+	New  func() hash.Hash
+}
+
+var (
+	// SHA256 is the default digest algorithm, required for images in a registry or in the daemon.
+	SHA256 = DigestAlgorithm{Name: "sha256", New: sha256.New}
+	// SHA512 is a digest algorithm registered by the OCI image spec.
+	SHA512 = DigestAlgorithm{Name: "sha512", New: sha512.New}
+)
+
+var digestAlgorithms = struct {
+	sync.RWMutex
+	byName map[string]DigestAlgorithm
+}{byName: map[string]DigestAlgorithm{SHA256.Name: SHA256, SHA512.Name: SHA512}}
+
+// RegisterDigestAlgorithm makes the provided algorithm available to DigestAlgorithmFor,
+// so that platforms using the lifecycle as a library can provide other algorithms (e.g., blake3 for local caches).
+func RegisterDigestAlgorithm(algorithm DigestAlgorithm) {
+	digestAlgorithms.Lock()
+	defer digestAlgorithms.Unlock()
+	digestAlgorithms.byName[algorithm.Name] = algorithm
+}
+
+// DigestAlgorithmFor returns the registered algorithm with the provided name.
+func DigestAlgorithmFor(name string) (DigestAlgorithm, error) {
+	digestAlgorithms.RLock()
+	defer digestAlgorithms.RUnlock()
+	algorithm, ok := digestAlgorithms.byName[name]
+	if !ok {
+		var names []string
+		for n := range digestAlgorithms.byName {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return DigestAlgorithm{}, fmt.Errorf("unsupported digest algorithm '%s'; supported algorithms are: %s", name, strings.Join(names, ", "))
+	}
+	return algorithm, nil
+}
+
+// Matches returns true if the digest was computed with the algorithm.
+func (a DigestAlgorithm) Matches(digest string) bool {
+	return strings.HasPrefix(digest, a.Name+":")
+}
+
+func (f *Factory) digestAlgorithm() DigestAlgorithm {
+	if f.DigestAlgorithm.New == nil {
+		return SHA256
+	}
+	return f.DigestAlgorithm
+}
@@ -0,0 +1,71 @@
+package layers
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dependencyDirNames are the names of directories that contain the dependencies of an app.
+// Dependencies change less frequently than the source of the app, and are often much larger.
+var dependencyDirNames = []string{
+	".venv",
+	"Pods",
+	"__pypackages__",
+	"bower_components",
+	"jspm_packages",
+	"node_modules",
+	"vendor",
+	"venv",
+}
+
+// DependencySlices returns a slice for each kind of dependency directory found in dir or in its subdirectories (e.g., node_modules
+// and frontend/node_modules), sorted by the name of the directory. It is used when the app does not declare slices,
+// so that a change to the source of the app does not change the layers containing its dependencies,
+// which are then reused from the previous image.
+func DependencySlices(dir string) ([]Slice, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var slices []Slice
+	for _, name := range dependencyDirNames {
+		var paths []string
+		if isDir(filepath.Join(dir, name)) {
+			paths = append(paths, name)
+		}
+		for _, entry := range entries {
+			if strings.ContainsAny(entry.Name(), `*?[\`) || isDependencyDirName(entry.Name()) {
+				// the paths of slices are patterns, and nested dependency dirs are in the slice of their parent
+				continue
+			}
+			if entry.IsDir() && isDir(filepath.Join(dir, entry.Name(), name)) {
+				paths = append(paths, filepath.Join(entry.Name(), name))
+			}
+		}
+		if len(paths) > 0 {
+			sort.Strings(paths)
+			slices = append(slices, Slice{Paths: paths})
+		}
+	}
+	return slices, nil
+}
+
+func isDependencyDirName(name string) bool {
+	for _, depName := range dependencyDirNames {
+		if name == depName {
+			return true
+		}
+	}
+	return false
+}
+
+// isDir returns true if path is a directory, and not a symlink to a directory.
+func isDir(path string) bool {
+	fi, err := os.Lstat(path)
+	return err == nil && fi.IsDir()
+}
@@ -0,0 +1,64 @@
+package layers_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/layers"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestDependencySlices(t *testing.T) {
+	spec.Run(t, "DependencySlices", testDependencySlices, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testDependencySlices(t *testing.T, when spec.G, it spec.S) {
+	var appDir string
+
+	it.Before(func() {
+		var err error
+		appDir, err = os.MkdirTemp("", "layers.auto-slices")
+		h.AssertNil(t, err)
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.RemoveAll(appDir))
+	})
+
+	when("#DependencySlices", func() {
+		it("returns a slice for each kind of dependency directory", func() {
+			h.Mkdir(t,
+				filepath.Join(appDir, "node_modules", "some-module", "node_modules"),
+				filepath.Join(appDir, "frontend", "node_modules"),
+				filepath.Join(appDir, "vendor"),
+				filepath.Join(appDir, "src"),
+			)
+
+			slices, err := layers.DependencySlices(appDir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, slices, []layers.Slice{
+				{Paths: []string{filepath.Join("frontend", "node_modules"), "node_modules"}},
+				{Paths: []string{"vendor"}},
+			})
+		})
+
+		it("ignores files with the names of dependency directories", func() {
+			h.Mkdir(t, filepath.Join(appDir, "src"))
+			h.Mkfile(t, "some-content", filepath.Join(appDir, "vendor"))
+
+			slices, err := layers.DependencySlices(appDir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(slices), 0)
+		})
+
+		it("returns no slices if the dir does not exist", func() {
+			slices, err := layers.DependencySlices(filepath.Join(appDir, "missing"))
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(slices), 0)
+		})
+	})
+}
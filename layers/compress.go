@@ -0,0 +1,113 @@
+package layers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	// AdaptiveCompression selects the gzip compression level of each layer from the entropy of the start of its tarball
+	// (see CompressionLevelFor), rather than using a fixed level.
+	AdaptiveCompression = -100
+
+	// entropySampleSize is the size of the start of a layer tarball used to estimate the entropy of its contents.
+	entropySampleSize = 4 << 20
+
+	// compressedEntropy and textEntropy are the entropies (in bits per byte) above which content is considered
+	// already compressed (e.g., jars, wheels or archives), and below which it is considered text-heavy.
+	compressedEntropy = 7.0
+	textEntropy       = 5.5
+)
+
+// WriteCompressed writes the tarball of the layer, compressed with gzip at the provided level, next to TarPath
+// (streaming it if it has not been written), and returns its path and the level that was used.
+// The level may be AdaptiveCompression.
+func (l Layer) WriteCompressed(level int) (string, int, error) {
+	rc, err := l.Open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer rc.Close()
+
+	var r io.Reader = rc
+	if level == AdaptiveCompression {
+		sample := make([]byte, entropySampleSize)
+		n, err := io.ReadFull(rc, sample)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", 0, err
+		}
+		level = CompressionLevelFor(sample[:n])
+		r = io.MultiReader(bytes.NewReader(sample[:n]), rc)
+	}
+
+	path := l.TarPath + ".gz"
+	if err = writeCompressed(path, r, level); err != nil {
+		_ = os.Remove(path)
+		return "", 0, err
+	}
+	return path, level, nil
+}
+
+func writeCompressed(path string, r io.Reader, level int) error {
+	// like the tarball, an existing file may be linked elsewhere, so it is replaced rather than truncated
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	zw, err := gzip.NewWriterLevel(file, level)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(zw, r); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// CompressionLevelFor returns the gzip compression level for a layer, given a sample of its tarball.
+// Already-compressed content is only entropy coded, which costs little more than storing it and never grows it noticeably,
+// text-heavy content (which compresses well) uses the default level, and other content uses the fastest level.
+func CompressionLevelFor(sample []byte) int {
+	if len(sample) == 0 {
+		return gzip.BestSpeed
+	}
+	switch entropy := Entropy(sample); {
+	case entropy >= compressedEntropy:
+		return gzip.HuffmanOnly
+	case entropy < textEntropy:
+		return gzip.DefaultCompression
+	default:
+		return gzip.BestSpeed
+	}
+}
+
+// Entropy returns the Shannon entropy of the bytes in p, in bits per byte (from 0 to 8).
+func Entropy(p []byte) float64 {
+	if len(p) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range p {
+		counts[b]++
+	}
+	var entropy float64
+	total := float64(len(p))
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		freq := float64(count) / total
+		entropy -= freq * math.Log2(freq)
+	}
+	return entropy
+}
@@ -0,0 +1,109 @@
+package layers_test
+
+import (
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/layers"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestCompress(t *testing.T) {
+	spec.Run(t, "Compress", testCompress, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testCompress(t *testing.T, when spec.G, it spec.S) {
+	when("#CompressionLevelFor", func() {
+		it("only entropy codes already-compressed content", func() {
+			sample := make([]byte, 1<<20)
+			_, err := rand.Read(sample)
+			h.AssertNil(t, err)
+			h.AssertEq(t, layers.CompressionLevelFor(sample), gzip.HuffmanOnly)
+		})
+
+		it("uses the default level for text", func() {
+			sample := []byte(strings.Repeat("func main() {\n\tfmt.Println(\"hello world\")\n}\n", 1000))
+			h.AssertEq(t, layers.CompressionLevelFor(sample), gzip.DefaultCompression)
+		})
+
+		it("uses the fastest level for other content", func() {
+			sample := make([]byte, 1<<20)
+			_, err := rand.Read(sample)
+			h.AssertNil(t, err)
+			for i := range sample {
+				sample[i] &= 0x3f // 6 bits per byte
+			}
+			h.AssertEq(t, layers.CompressionLevelFor(sample), gzip.BestSpeed)
+			h.AssertEq(t, layers.CompressionLevelFor(nil), gzip.BestSpeed)
+		})
+	})
+
+	when("#WriteCompressed", func() {
+		var (
+			factory *layers.Factory
+			dir     string
+		)
+
+		it.Before(func() {
+			tmpDir := t.TempDir()
+			h.AssertNil(t, os.MkdirAll(filepath.Join(tmpDir, "artifacts"), 0755))
+			dir = filepath.Join(tmpDir, "some-layer")
+			h.AssertNil(t, os.MkdirAll(dir, 0755))
+			h.AssertNil(t, os.WriteFile(filepath.Join(dir, "some-file.txt"), []byte(strings.Repeat("some-contents\n", 1000)), 0600))
+			factory = &layers.Factory{
+				ArtifactsDir: filepath.Join(tmpDir, "artifacts"),
+				Logger:       &log.Logger{Handler: memory.New()},
+				Streaming:    true,
+			}
+		})
+
+		for _, level := range []int{layers.AdaptiveCompression, gzip.NoCompression, gzip.BestCompression} {
+			level := level
+			it(fmt.Sprintf("writes the compressed tarball of the layer with level %d", level), func() {
+				layer, err := factory.DirLayer("some/layer", dir, "some-created-by")
+				h.AssertNil(t, err)
+
+				path, used, err := layer.WriteCompressed(level)
+				h.AssertNil(t, err)
+				h.AssertEq(t, path, layer.TarPath+".gz")
+				if level == layers.AdaptiveCompression {
+					h.AssertEq(t, used, gzip.DefaultCompression)
+				} else {
+					h.AssertEq(t, used, level)
+				}
+				h.AssertEq(t, layer.Written(), false)
+
+				f, err := os.Open(path)
+				h.AssertNil(t, err)
+				defer f.Close()
+				zr, err := gzip.NewReader(f)
+				h.AssertNil(t, err)
+				contents, err := io.ReadAll(zr)
+				h.AssertNil(t, err)
+				h.AssertEq(t, fmt.Sprintf("sha256:%x", sha256.Sum256(contents)), layer.Digest)
+			})
+		}
+
+		it("fails if the contents of the layer changed", func() {
+			layer, err := factory.DirLayer("some/layer", dir, "some-created-by")
+			h.AssertNil(t, err)
+			h.AssertNil(t, os.WriteFile(filepath.Join(dir, "some-file.txt"), []byte("other-contents"), 0600))
+
+			_, _, err = layer.WriteCompressed(layers.AdaptiveCompression)
+			h.AssertError(t, err, "contents of layer 'some/layer' changed while exporting")
+			h.AssertPathDoesNotExist(t, layer.TarPath+".gz")
+		})
+	})
+}
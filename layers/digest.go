@@ -10,18 +10,23 @@ import (
 type concurrentHasher struct {
 	hash    hash.Hash
 	wg      sync.WaitGroup
-	buffers chan []byte
+	buffers chan *[]byte
 }
 
+// hashBuffers holds the copies of the writes to concurrentHashers, which are reused once they are hashed,
+// rather than allocating a copy of each write.
+var hashBuffers sync.Pool
+
 func newConcurrentHasher(h hash.Hash) *concurrentHasher {
 	ch := &concurrentHasher{
 		hash:    h,
-		buffers: make(chan []byte, 10),
+		buffers: make(chan *[]byte, 10),
 	}
 
 	go func() {
 		for b := range ch.buffers {
-			_, _ = ch.hash.Write(b)
+			_, _ = ch.hash.Write(*b)
+			hashBuffers.Put(b)
 			ch.wg.Done()
 		}
 	}()
@@ -30,8 +35,8 @@ func newConcurrentHasher(h hash.Hash) *concurrentHasher {
 }
 
 func (ch *concurrentHasher) Write(p []byte) (int, error) {
-	cp := make([]byte, len(p))
-	copy(cp, p)
+	cp := hashBuffer(len(p))
+	copy(*cp, p)
 
 	ch.wg.Add(1)
 	ch.buffers <- cp
@@ -44,3 +49,13 @@ func (ch *concurrentHasher) Sum(b []byte) []byte {
 	close(ch.buffers)
 	return ch.hash.Sum(b)
 }
+
+// hashBuffer returns a buffer of length n, reused from hashBuffers if possible.
+func hashBuffer(n int) *[]byte {
+	if b, ok := hashBuffers.Get().(*[]byte); ok && cap(*b) >= n {
+		*b = (*b)[:n]
+		return b
+	}
+	b := make([]byte, n)
+	return &b
+}
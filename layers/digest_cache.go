@@ -0,0 +1,196 @@
+package layers
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/buildpacks/lifecycle/archive"
+)
+
+// DigestCacheFile is the name of the file in the layers directory that holds the digest cache.
+const DigestCacheFile = "digest-cache.json"
+
+// maxTarHeadSize bounds the start of a restored tarball that is kept by TarHead.
+// The parent entries of a layer tarball are a few headers of 512 bytes each.
+const maxTarHeadSize = 64 * 1024
+
+// errFingerprintUnsupported is returned on platforms that do not provide the file metadata used in fingerprints.
+var errFingerprintUnsupported = errors.New("fingerprints are not supported on this platform")
+
+// DigestCache records the digests of layer directories, keyed by a fingerprint of the metadata of every file in them
+// (path, type, mode, ownership, size, modification and change times, device and inode), so that layers whose contents
+// are unchanged since they were restored from the cache do not need to be hashed again when they are exported.
+//
+// The digest of a layer also depends on the options of the Factory that creates its tarball, so the normalized UID and GID
+// of its entries and the prefixes of the preserved extended attributes are recorded with the digest, and must match the Factory.
+//
+// Any change to the metadata of a file invalidates the digest of its layer. The change time (ctime) of a file cannot be
+// set by buildpacks, so modifying a file always invalidates the digest, even if its size and modification time are preserved.
+type DigestCache struct {
+	path string
+
+	mu      sync.Mutex
+	Entries map[string]DigestCacheEntry `json:"layers"`
+}
+
+// DigestCacheEntry is the digest of a layer directory, the fingerprint of its contents when the digest was recorded,
+// and the options of the Factory (see Factory.UID, Factory.GID and Factory.Xattrs) that produce a tarball with this digest.
+type DigestCacheEntry struct {
+	Fingerprint string   `json:"fingerprint"`
+	Digest      string   `json:"digest"`
+	UID         int      `json:"uid"`
+	GID         int      `json:"gid"`
+	Xattrs      []string `json:"xattrs,omitempty"`
+}
+
+// ReadDigestCache reads the digest cache at the provided path.
+// A missing or unreadable digest cache is treated as empty, as it is only an optimization.
+func ReadDigestCache(path string) *DigestCache {
+	c := &DigestCache{path: path}
+	if contents, err := os.ReadFile(path); err == nil { // #nosec G304
+		_ = json.Unmarshal(contents, c)
+	}
+	if c.Entries == nil {
+		c.Entries = map[string]DigestCacheEntry{}
+	}
+	return c
+}
+
+// DigestCacheFor returns the digest cache in the provided layers directory, or nil if the digest cache is disabled.
+func DigestCacheFor(layersDir string, disabled bool) *DigestCache {
+	if disabled {
+		return nil
+	}
+	return ReadDigestCache(filepath.Join(layersDir, DigestCacheFile))
+}
+
+// RecordRestored records the digest of a layer directory that was extracted from the tarball with the provided digest,
+// preserving the extended attributes matching the provided prefixes.
+// The tarball includes entries for the parents of the directory, which may have changed since the tarball was created,
+// so the digest is only recorded if head, the start of the tarball (see TarHead), matches the current parents.
+// The UID and GID of the entries of the directory are read from the entry for the directory that follows its parents.
+func (c *DigestCache) RecordRestored(dir, digest string, head []byte, xattrs []string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	fp, err := fingerprint(dir)
+	if errors.Is(err, errFingerprintUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	expected, err := parentEntries(dir)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(head, expected) {
+		return nil
+	}
+	hdr, err := tar.NewReader(bytes.NewReader(head[len(expected):])).Next()
+	if err != nil {
+		// the entry for the directory is missing or truncated, so its ownership is unknown
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[dir] = DigestCacheEntry{
+		Fingerprint: fp,
+		Digest:      digest,
+		UID:         hdr.Uid,
+		GID:         hdr.Gid,
+		Xattrs:      append([]string(nil), xattrs...),
+	}
+	return nil
+}
+
+// Lookup returns the recorded digest of the provided layer directory, if the metadata of its contents is unchanged
+// and the digest was recorded for a tarball with the provided UID, GID and extended attribute prefixes.
+func (c *DigestCache) Lookup(dir string, uid, gid int, xattrs []string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	c.mu.Lock()
+	entry, ok := c.Entries[dir]
+	c.mu.Unlock()
+	if !ok || entry.UID != uid || entry.GID != gid || !equalStrings(entry.Xattrs, xattrs) {
+		return "", false
+	}
+	fp, err := fingerprint(dir)
+	if err != nil || fp != entry.Fingerprint {
+		return "", false
+	}
+	return entry.Digest, true
+}
+
+// Save writes the digest cache.
+func (c *DigestCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	contents, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, contents, 0600)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parentEntries returns the entries for the parents of the provided directory, as they are written at the start of its layer tarball.
+func parentEntries(dir string) ([]byte, error) {
+	parentDirs, err := parents(dir)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	tw := tarWriter(&buf)
+	if err := archive.AddFilesToArchive(tw, parentDirs); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// TarHead keeps the start of a layer tarball as it is read (e.g., while it is extracted), see DigestCache.RecordRestored.
+type TarHead struct {
+	r    io.Reader
+	head []byte
+}
+
+// NewTarHead returns a TarHead that reads from r.
+func NewTarHead(r io.Reader) *TarHead {
+	return &TarHead{r: r}
+}
+
+func (t *TarHead) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if keep := maxTarHeadSize - len(t.head); keep > 0 {
+		if n < keep {
+			keep = n
+		}
+		t.head = append(t.head, p[:keep]...)
+	}
+	return n, err
+}
+
+// Bytes returns the start of the tarball that has been read.
+func (t *TarHead) Bytes() []byte {
+	return t.head
+}
@@ -0,0 +1,164 @@
+package layers_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/layers"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestDigestCache(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("digest cache is only supported on linux")
+	}
+	spec.Run(t, "DigestCache", testDigestCache, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testDigestCache(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir  string
+		dir     string
+		cache   *layers.DigestCache
+		factory *layers.Factory
+		digest  string
+		head    []byte
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "layers.digest-cache")
+		h.AssertNil(t, err)
+		dir = filepath.Join(tmpDir, "some-layer")
+		h.AssertNil(t, os.MkdirAll(filepath.Join(dir, "some-dir"), 0755))
+		h.AssertNil(t, os.WriteFile(filepath.Join(dir, "some-dir", "some-file.txt"), []byte("some-contents"), 0600))
+		factory = &layers.Factory{ArtifactsDir: t.TempDir(), Logger: &log.Logger{Handler: memory.New()}}
+
+		layer, err := factory.DirLayer("some-layer", dir, "")
+		h.AssertNil(t, err)
+		digest = layer.Digest
+		f, err := os.Open(layer.TarPath)
+		h.AssertNil(t, err)
+		defer f.Close()
+		th := layers.NewTarHead(f)
+		_, err = io.Copy(io.Discard, th)
+		h.AssertNil(t, err)
+		head = th.Bytes()
+
+		cache = layers.DigestCacheFor(tmpDir, false)
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.RemoveAll(tmpDir))
+	})
+
+	when("#RecordRestored", func() {
+		it("records the digest of an unchanged layer", func() {
+			h.AssertNil(t, cache.RecordRestored(dir, digest, head, nil))
+			h.AssertNil(t, cache.Save())
+
+			found, ok := layers.ReadDigestCache(filepath.Join(tmpDir, layers.DigestCacheFile)).Lookup(dir, 0, 0, nil)
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, found, digest)
+		})
+
+		it("records the UID and GID of the entries of the layer", func() {
+			factory = &layers.Factory{ArtifactsDir: t.TempDir(), UID: 1000, GID: 1001, Logger: &log.Logger{Handler: memory.New()}}
+			layer, err := factory.DirLayer("some-layer", dir, "")
+			h.AssertNil(t, err)
+			contents, err := os.ReadFile(layer.TarPath)
+			h.AssertNil(t, err)
+			h.AssertNil(t, cache.RecordRestored(dir, layer.Digest, contents, nil))
+
+			_, ok := cache.Lookup(dir, 0, 0, nil)
+			h.AssertEq(t, ok, false)
+			found, ok := cache.Lookup(dir, 1000, 1001, nil)
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, found, layer.Digest)
+		})
+
+		it("does not record the digest if the parents of the layer changed", func() {
+			h.AssertNil(t, os.Chmod(tmpDir, 0750))
+			h.AssertNil(t, cache.RecordRestored(dir, digest, head, nil))
+
+			_, ok := cache.Lookup(dir, 0, 0, nil)
+			h.AssertEq(t, ok, false)
+		})
+	})
+
+	when("#Lookup", func() {
+		it.Before(func() {
+			h.AssertNil(t, cache.RecordRestored(dir, digest, head, nil))
+		})
+
+		it("does not return the digest if a file was modified", func() {
+			file := filepath.Join(dir, "some-dir", "some-file.txt")
+			fi, err := os.Stat(file)
+			h.AssertNil(t, err)
+			h.AssertNil(t, os.WriteFile(file, []byte("same-length!!"), 0600))
+			// restoring the size and modification time does not hide the change
+			h.AssertNil(t, os.Chtimes(file, time.Now(), fi.ModTime()))
+
+			_, ok := cache.Lookup(dir, 0, 0, nil)
+			h.AssertEq(t, ok, false)
+		})
+
+		it("does not return the digest for a different UID or GID", func() {
+			_, ok := cache.Lookup(dir, 1000, 0, nil)
+			h.AssertEq(t, ok, false)
+			_, ok = cache.Lookup(dir, 0, 1000, nil)
+			h.AssertEq(t, ok, false)
+		})
+
+		it("does not return the digest for different extended attribute prefixes", func() {
+			_, ok := cache.Lookup(dir, 0, 0, []string{"security.capability"})
+			h.AssertEq(t, ok, false)
+		})
+
+		it("is not used by a factory with a different UID", func() {
+			factory = &layers.Factory{ArtifactsDir: t.TempDir(), UID: 1000, Streaming: true, DigestCache: cache, Logger: &log.Logger{Handler: memory.New()}}
+			layer, err := factory.DirLayer("some-layer", dir, "")
+			h.AssertNil(t, err)
+			h.AssertEq(t, layer.Digest != digest, true)
+		})
+
+		it("does not return the digest if a file was added", func() {
+			h.AssertNil(t, os.WriteFile(filepath.Join(dir, "other-file.txt"), []byte("other-contents"), 0600))
+
+			_, ok := cache.Lookup(dir, 0, 0, nil)
+			h.AssertEq(t, ok, false)
+		})
+
+		it("is used by the factory when streaming", func() {
+			factory.Streaming = true
+			factory.DigestCache = cache
+			layer, err := factory.DirLayer("some-layer", dir, "")
+			h.AssertNil(t, err)
+			h.AssertEq(t, layer.Digest, digest)
+
+			rc, err := layer.Open()
+			h.AssertNil(t, err)
+			defer rc.Close()
+			contents, err := io.ReadAll(rc)
+			h.AssertNil(t, err)
+			h.AssertEq(t, fmt.Sprintf("sha256:%x", sha256.Sum256(contents)), digest)
+		})
+	})
+
+	when("the digest cache is disabled", func() {
+		it("returns nil", func() {
+			h.AssertNil(t, layers.DigestCacheFor(tmpDir, true))
+		})
+	})
+}
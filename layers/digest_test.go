@@ -25,3 +25,21 @@ func TestConcurrentHasher(t *testing.T) {
 		t.Fatalf(`both digests should be the same, got %s and %s`, digest, cDigest)
 	}
 }
+
+func TestConcurrentHasherReusesBuffers(t *testing.T) {
+	hasher := sha256.New()
+	cHasher := newConcurrentHasher(sha256.New())
+	buf := make([]byte, 1024)
+	for i := 0; i < 100; i++ {
+		// the buffer is modified after each write, as it is by the tar writer
+		for j := range buf {
+			buf[j] = byte(i + j)
+		}
+		_, err := hasher.Write(buf[:i*10])
+		h.AssertNil(t, err)
+		_, err = cHasher.Write(buf[:i*10])
+		h.AssertNil(t, err)
+	}
+
+	h.AssertEq(t, hex.EncodeToString(cHasher.Sum(nil)), hex.EncodeToString(hasher.Sum(nil)))
+}
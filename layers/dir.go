@@ -1,7 +1,9 @@
 package layers
 
 import (
+	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/buildpacks/lifecycle/archive"
 )
@@ -9,7 +11,7 @@ import (
 // DirLayer creates a layer from the given directory
 // DirLayer will set the UID and GID of entries describing dir and its children (but not its parents)
 //
-//	to Factory.UID and Factory.GID
+//	to Factory.UID and Factory.GID, and preserve their extended attributes matching Factory.Xattrs
 func (f *Factory) DirLayer(id string, dir string, createdBy string) (layer Layer, err error) {
 	dir, err = filepath.Abs(dir)
 	if err != nil {
@@ -19,12 +21,111 @@ func (f *Factory) DirLayer(id string, dir string, createdBy string) (layer Layer
 	if err != nil {
 		return Layer{}, err
 	}
-	return f.writeLayer(id, createdBy, func(tw *archive.NormalizingTarWriter) error {
+	var knownDigest string
+	if f.Streaming && f.DigestCache != nil {
+		if digest, ok := f.DigestCache.Lookup(dir, f.UID, f.GID, f.Xattrs); ok && f.digestAlgorithm().Matches(digest) {
+			f.Logger.Debugf("Using cached digest for layer %q: %s\n", id, digest)
+			knownDigest = digest
+		}
+	}
+	return f.writeLayerWithDigest(id, createdBy, knownDigest, func(tw *archive.NormalizingTarWriter) error {
 		if err := archive.AddFilesToArchive(tw, parents); err != nil {
 			return err
 		}
 		tw.WithUID(f.UID)
 		tw.WithGID(f.GID)
+		tw.WithXattrs(f.Xattrs)
 		return archive.AddDirToArchive(tw, dir)
 	})
 }
+
+// DirsLayer creates a single layer from the given directories, e.g., to squash several buildpack layers into one.
+// Like DirLayer, it normalizes the UID and GID of the entries describing each dir and its children (but not their parents).
+func (f *Factory) DirsLayer(id string, dirs []string, createdBy string) (layer Layer, err error) {
+	var absDirs []string
+	for _, dir := range dirs {
+		dir, err = filepath.Abs(dir)
+		if err != nil {
+			return Layer{}, err
+		}
+		absDirs = append(absDirs, dir)
+	}
+	allParents, err := commonParents(absDirs)
+	if err != nil {
+		return Layer{}, err
+	}
+	return f.writeLayer(id, createdBy, func(tw *archive.NormalizingTarWriter) error {
+		if err := archive.AddFilesToArchive(tw, allParents); err != nil {
+			return err
+		}
+		tw.WithUID(f.UID)
+		tw.WithGID(f.GID)
+		tw.WithXattrs(f.Xattrs)
+		for _, dir := range absDirs {
+			if err := archive.AddDirToArchive(tw, dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FilesLayer creates a layer from the given files, at their paths, e.g., to add build artifacts to an image.
+// It normalizes the UID and GID of the entries describing the files (but not their parents).
+func (f *Factory) FilesLayer(id string, files []string, createdBy string) (layer Layer, err error) {
+	var (
+		absFiles []string
+		entries  []archive.PathInfo
+	)
+	for _, file := range files {
+		file, err = filepath.Abs(file)
+		if err != nil {
+			return Layer{}, err
+		}
+		fi, err := os.Lstat(file)
+		if err != nil {
+			return Layer{}, err
+		}
+		absFiles = append(absFiles, file)
+		entries = append(entries, archive.PathInfo{Path: file, Info: fi})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	allParents, err := commonParents(absFiles)
+	if err != nil {
+		return Layer{}, err
+	}
+	return f.writeLayer(id, createdBy, func(tw *archive.NormalizingTarWriter) error {
+		if err := archive.AddFilesToArchive(tw, allParents); err != nil {
+			return err
+		}
+		tw.WithUID(f.UID)
+		tw.WithGID(f.GID)
+		return archive.AddFilesToArchive(tw, entries)
+	})
+}
+
+// commonParents returns the parents of all the provided paths, without duplicates, sorted by path.
+func commonParents(paths []string) ([]archive.PathInfo, error) {
+	var (
+		allParents []archive.PathInfo
+		seen       = map[string]bool{}
+	)
+	for _, path := range paths {
+		parents, err := parents(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			if !seen[parent.Path] {
+				seen[parent.Path] = true
+				allParents = append(allParents, parent)
+			}
+		}
+	}
+	sort.SliceStable(allParents, func(i, j int) bool {
+		return allParents[i].Path < allParents[j].Path
+	})
+	return allParents, nil
+}
@@ -143,6 +143,90 @@ func testDirs(t *testing.T, when spec.G, it spec.S) {
 			)
 		})
 	})
+
+	when("#DirsLayer", func() {
+		it("creates a layer from the directories", func() {
+			otherDir := filepath.Join(dir, "other-dir")
+			someDir := filepath.Join(dir, "some-dir")
+
+			dirsLayer, err := factory.DirsLayer("some-layer-id", []string{otherDir, someDir}, "some-created-by")
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, dirsLayer.ID, "some-layer-id")
+			assertTarEntries(t, dirsLayer.TarPath, append(parents(t, otherDir), []*tar.Header{
+				{
+					Name:     tarPath(otherDir),
+					Uid:      factory.UID,
+					Gid:      factory.GID,
+					Typeflag: tar.TypeDir,
+				},
+				{
+					Name:     tarPath(filepath.Join(otherDir, "other-file.md")),
+					Uid:      factory.UID,
+					Gid:      factory.GID,
+					Typeflag: tar.TypeReg,
+				},
+				{
+					Name:     tarPath(filepath.Join(otherDir, "other-file.txt")),
+					Uid:      factory.UID,
+					Gid:      factory.GID,
+					Typeflag: tar.TypeReg,
+				},
+				{
+					Name:     tarPath(someDir),
+					Uid:      factory.UID,
+					Gid:      factory.GID,
+					Typeflag: tar.TypeDir,
+				},
+				{
+					Name:     tarPath(filepath.Join(someDir, "file.md")),
+					Uid:      factory.UID,
+					Gid:      factory.GID,
+					Typeflag: tar.TypeReg,
+				},
+				{
+					Name:     tarPath(filepath.Join(someDir, "some-file.txt")),
+					Uid:      factory.UID,
+					Gid:      factory.GID,
+					Typeflag: tar.TypeReg,
+				},
+			}...))
+			h.AssertEq(t, dirsLayer.History.CreatedBy, "some-created-by")
+		})
+	})
+
+	when("#FilesLayer", func() {
+		it("creates a layer from the files", func() {
+			someFile := filepath.Join(dir, "some-dir", "some-file.txt")
+			otherFile := filepath.Join(dir, "other-dir", "other-file.txt")
+
+			filesLayer, err := factory.FilesLayer("some-layer-id", []string{someFile, otherFile}, "some-created-by")
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, filesLayer.ID, "some-layer-id")
+			otherDirInfo, err := os.Stat(filepath.Join(dir, "other-dir"))
+			h.AssertNil(t, err)
+			someDirInfo, err := os.Stat(filepath.Join(dir, "some-dir"))
+			h.AssertNil(t, err)
+			assertTarEntries(t, filesLayer.TarPath, append(parents(t, filepath.Join(dir, "some-dir")), []*tar.Header{
+				parentHeader(filepath.Join(dir, "other-dir"), otherDirInfo),
+				parentHeader(filepath.Join(dir, "some-dir"), someDirInfo),
+				{
+					Name:     tarPath(otherFile),
+					Uid:      factory.UID,
+					Gid:      factory.GID,
+					Typeflag: tar.TypeReg,
+				},
+				{
+					Name:     tarPath(someFile),
+					Uid:      factory.UID,
+					Gid:      factory.GID,
+					Typeflag: tar.TypeReg,
+				},
+			}...))
+			h.AssertEq(t, filesLayer.History.CreatedBy, "some-created-by")
+		})
+	})
 }
 
 func assertTarEntries(t *testing.T, tarPath string, expectedEntries []*tar.Header) {
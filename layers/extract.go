@@ -16,6 +16,13 @@ func Extract(r io.Reader, dest string) error {
 	return archive.Extract(tr)
 }
 
+// ExtractWithOptions is like Extract, but entries that would be written outside of opts.Root are rejected
+// (see archive.ExtractWithOptions).
+func ExtractWithOptions(r io.Reader, dest string, opts archive.ExtractOptions) (archive.ExtractReport, error) {
+	tr := tarReader(r, dest)
+	return archive.ExtractWithOptions(tr, opts)
+}
+
 func tarReader(r io.Reader, dest string) archive.TarReader {
 	tr := archive.NewNormalizingTarReader(tar.NewReader(r))
 	if runtime.GOOS == "windows" {
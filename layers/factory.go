@@ -1,33 +1,60 @@
 package layers
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 
 	"github.com/buildpacks/lifecycle/archive"
+	"github.com/buildpacks/lifecycle/internal/chaos"
 	"github.com/buildpacks/lifecycle/log"
 )
 
 const (
 	AppLayerName            = "Application Layer"
 	BuildpackLayerName      = "Layer: '%s', Created by buildpack: %s"
+	DebugArtifactsLayerName = "Build Artifacts"
 	ExtensionLayerName      = "Layer: '%s', Created by extension: %s"
 	LauncherConfigLayerName = "Buildpacks Launcher Config"
 	LauncherLayerName       = "Buildpacks Application Launcher"
 	ProcessTypesLayerName   = "Buildpacks Process Types"
 	SBOMLayerName           = "Software Bill-of-Materials"
 	SliceLayerName          = "Application Slice: %d"
+	SquashedLayerName       = "Squashed Buildpack Layers"
 )
 
 type Factory struct {
 	ArtifactsDir string // ArtifactsDir is the directory where layer files are written
 	UID, GID     int    // UID and GID are used to normalize layer entries
 	Logger       log.Logger
+	// Streaming defers writing layer tarballs to ArtifactsDir: the digest of each layer is computed as its tarball
+	// is generated, without writing it. The tarball is only written when it is needed (see Layer.WriteTar),
+	// or generated again as it is read (see Layer.Open), so that reused layers never use disk space.
+	// Layers that are added to an image are still written to ArtifactsDir, as imgutil adds layers from files.
+	// The tarball of a layer that is not reused is therefore generated twice: once to compute its digest,
+	// and once to write (or compress) it. Layers that are known to be added regardless of their digest
+	// should be created with the factory returned by Eager, which writes their tarballs as they are hashed.
+	Streaming bool
+	// DigestCache provides the digests of layer directories that are unchanged since they were restored,
+	// so that they are not hashed again. It is only used when Streaming, as otherwise the tarballs are written anyway.
+	DigestCache *DigestCache
+	// Xattrs are the prefixes of the names of the extended attributes (e.g., "security.capability" for file capabilities)
+	// that are preserved in the tarballs of directory layers. By default, extended attributes are not preserved.
+	Xattrs []string
+	// DigestAlgorithm is the algorithm of the digests of the layers. By default, digests are sha256 digests,
+	// as required for images in a registry or in the daemon.
+	DigestAlgorithm DigestAlgorithm
+	// AppInclude and AppExclude are the gitignore-style patterns of the project descriptor that select the files
+	// of the app directory added to the app layers. By default, every file is added. The app directory is not modified.
+	AppInclude, AppExclude []string
 
-	tarHashes map[string]string // tarHases Stores hashes of layer tarballs for reuse between the export and cache steps.
+	tarHashes map[string]string       // tarHases Stores hashes of layer tarballs for reuse between the export and cache steps.
+	deferred  map[string]*DeferredTar // deferred stores the tarballs of streamed layers for reuse between the export and cache steps.
 }
 
 type Layer struct {
@@ -35,23 +62,89 @@ type Layer struct {
 	TarPath string
 	Digest  string
 	History v1.History
+
+	// Deferred is set for layers created by a streaming Factory, whose tarball is written when it is needed.
+	Deferred *DeferredTar
 }
 
-func (f *Factory) writeLayer(id, createdBy string, addEntries func(tw *archive.NormalizingTarWriter) error) (layer Layer, err error) {
-	tarPath := filepath.Join(f.ArtifactsDir, escape(id)+".tar")
+// WriteTar ensures that the tarball of the layer exists at TarPath.
+// For layers created by a streaming Factory, the tarball is written the first time WriteTar is called.
+func (l Layer) WriteTar() error {
+	if l.Deferred == nil {
+		return nil
+	}
+	return l.Deferred.write()
+}
+
+// Written returns true if the tarball of the layer exists at TarPath.
+func (l Layer) Written() bool {
+	return l.Deferred == nil || l.Deferred.isWritten()
+}
+
+// Open returns the uncompressed tarball of the layer.
+// If the tarball of a streamed layer has not been written, it is generated as it is read,
+// and reading it fails if its contents no longer match the digest of the layer.
+func (l Layer) Open() (io.ReadCloser, error) {
+	if l.Written() {
+		return os.Open(l.TarPath)
+	}
+	return l.Deferred.stream(), nil
+}
+
+// Eager returns a factory that writes the tarballs of the layers it creates as they are hashed, like a factory that is not Streaming,
+// for layers that are added to an image regardless of their digest (e.g., when the previous image has no layer to reuse).
+// The returned factory shares the layers created by f, so that a layer created by either is not generated again by the other.
+func (f *Factory) Eager() *Factory {
+	f.initTarballs()
+	eager := *f
+	eager.Streaming = false
+	return &eager
+}
+
+func (f *Factory) initTarballs() {
 	if f.tarHashes == nil {
 		f.tarHashes = make(map[string]string)
+		f.deferred = make(map[string]*DeferredTar)
 	}
+}
+
+func (f *Factory) writeLayer(id, createdBy string, addEntries func(tw *archive.NormalizingTarWriter) error) (layer Layer, err error) {
+	return f.writeLayerWithDigest(id, createdBy, "", addEntries)
+}
+
+// writeLayerWithDigest is like writeLayer, but uses the provided digest (if any) rather than hashing the tarball when Streaming.
+func (f *Factory) writeLayerWithDigest(id, createdBy, knownDigest string, addEntries func(tw *archive.NormalizingTarWriter) error) (layer Layer, err error) {
+	tarPath := filepath.Join(f.ArtifactsDir, escape(id)+".tar")
+	f.initTarballs()
 	if sha, ok := f.tarHashes[tarPath]; ok {
 		f.Logger.Debugf("Reusing tarball for layer %q with SHA: %s\n", id, sha)
 		return Layer{
-			ID:      id,
-			TarPath: tarPath,
-			Digest:  sha,
-			History: v1.History{CreatedBy: createdBy},
+			ID:       id,
+			TarPath:  tarPath,
+			Digest:   sha,
+			History:  v1.History{CreatedBy: createdBy},
+			Deferred: f.deferred[tarPath],
+		}, nil
+	}
+	if f.Streaming {
+		digest := knownDigest
+		if digest == "" {
+			if digest, err = writeTar(newLayerWriter(nopWriteCloser{io.Discard}, f.digestAlgorithm()), addEntries); err != nil {
+				return Layer{}, err
+			}
+		}
+		digest = chaos.LayerDigest(id, digest)
+		f.tarHashes[tarPath] = digest
+		f.deferred[tarPath] = &DeferredTar{id: id, path: tarPath, digest: digest, algorithm: f.digestAlgorithm(), addEntries: addEntries}
+		return Layer{
+			ID:       id,
+			Digest:   digest,
+			TarPath:  tarPath,
+			History:  v1.History{CreatedBy: createdBy},
+			Deferred: f.deferred[tarPath],
 		}, nil
 	}
-	lw, err := newFileLayerWriter(tarPath)
+	lw, err := newFileLayerWriter(tarPath, f.digestAlgorithm())
 	if err != nil {
 		return Layer{}, err
 	}
@@ -60,15 +153,11 @@ func (f *Factory) writeLayer(id, createdBy string, addEntries func(tw *archive.N
 			err = closeErr
 		}
 	}()
-	tw := tarWriter(lw)
-	if err := addEntries(tw); err != nil {
-		return Layer{}, err
-	}
-
-	if err := tw.Close(); err != nil {
+	digest, err := writeTar(lw, addEntries)
+	if err != nil {
 		return Layer{}, err
 	}
-	digest := lw.Digest()
+	digest = chaos.LayerDigest(id, digest)
 	f.tarHashes[tarPath] = digest
 	return Layer{
 		ID:      id,
@@ -78,6 +167,68 @@ func (f *Factory) writeLayer(id, createdBy string, addEntries func(tw *archive.N
 	}, err
 }
 
+// DeferredTar is the tarball of a layer created by a streaming Factory, which is generated again when it is needed.
+type DeferredTar struct {
+	id         string
+	path       string
+	digest     string
+	algorithm  DigestAlgorithm
+	addEntries func(tw *archive.NormalizingTarWriter) error
+
+	mu      sync.Mutex
+	written bool
+}
+
+func (d *DeferredTar) isWritten() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.written
+}
+
+func (d *DeferredTar) write() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.written {
+		return nil
+	}
+	lw, err := newFileLayerWriter(d.path, d.algorithm)
+	if err != nil {
+		return err
+	}
+	digest, err := writeTar(lw, d.addEntries)
+	if closeErr := lw.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = d.verify(digest)
+	}
+	if err != nil {
+		_ = os.Remove(d.path)
+		return err
+	}
+	d.written = true
+	return nil
+}
+
+func (d *DeferredTar) stream() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		digest, err := writeTar(newLayerWriter(pw, d.algorithm), d.addEntries)
+		if err == nil {
+			err = d.verify(digest)
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+func (d *DeferredTar) verify(digest string) error {
+	if digest != d.digest {
+		return fmt.Errorf("contents of layer '%s' changed while exporting: expected digest %s, found %s", d.id, d.digest, digest)
+	}
+	return nil
+}
+
 func escape(id string) string {
 	return strings.ReplaceAll(id, "/", "_")
 }
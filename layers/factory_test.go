@@ -0,0 +1,179 @@
+package layers_test
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/layers"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestFactory(t *testing.T) {
+	spec.Run(t, "Factory", testFactory, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testFactory(t *testing.T, when spec.G, it spec.S) {
+	var (
+		factory *layers.Factory
+		tmpDir  string
+		dir     string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "layers.factory")
+		h.AssertNil(t, err)
+		h.AssertNil(t, os.MkdirAll(filepath.Join(tmpDir, "artifacts"), 0755))
+		dir = filepath.Join(tmpDir, "some-layer")
+		h.AssertNil(t, os.MkdirAll(filepath.Join(dir, "some-dir"), 0755))
+		h.AssertNil(t, os.WriteFile(filepath.Join(dir, "some-dir", "some-file.txt"), []byte("some-contents"), 0600))
+		factory = &layers.Factory{
+			ArtifactsDir: filepath.Join(tmpDir, "artifacts"),
+			Logger:       &log.Logger{Handler: memory.New()},
+			Streaming:    true,
+		}
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.RemoveAll(tmpDir))
+	})
+
+	it("replaces an existing tarball rather than modifying it", func() {
+		factory.Streaming = false
+		tarPath := filepath.Join(factory.ArtifactsDir, "some_layer.tar")
+		h.AssertNil(t, os.WriteFile(tarPath, []byte("some-tarball"), 0600))
+		h.AssertNil(t, os.Link(tarPath, filepath.Join(tmpDir, "linked.tar")))
+
+		layer, err := factory.DirLayer("some/layer", dir, "some-created-by")
+		h.AssertNil(t, err)
+		h.AssertEq(t, digestOf(t, layer.TarPath), layer.Digest)
+		h.AssertEq(t, string(h.MustReadFile(t, filepath.Join(tmpDir, "linked.tar"))), "some-tarball")
+	})
+
+	when("DigestAlgorithm", func() {
+		it("computes the digests with the provided algorithm", func() {
+			factory.DigestAlgorithm = layers.SHA512
+
+			layer, err := factory.DirLayer("some/layer", dir, "some-created-by")
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, layer.WriteTar())
+			contents := h.MustReadFile(t, layer.TarPath)
+			h.AssertEq(t, layer.Digest, fmt.Sprintf("sha512:%x", sha512.Sum512(contents)))
+		})
+
+		it("uses registered algorithms", func() {
+			layers.RegisterDigestAlgorithm(layers.DigestAlgorithm{Name: "some-algorithm", New: sha256.New})
+
+			algorithm, err := layers.DigestAlgorithmFor("some-algorithm")
+			h.AssertNil(t, err)
+			factory.DigestAlgorithm = algorithm
+			layer, err := factory.DirLayer("some/layer", dir, "some-created-by")
+			h.AssertNil(t, err)
+			h.AssertEq(t, algorithm.Matches(layer.Digest), true)
+		})
+	})
+
+	when("Streaming", func() {
+		it("computes the digest without writing the tarball", func() {
+			layer, err := factory.DirLayer("some/layer", dir, "some-created-by")
+			h.AssertNil(t, err)
+
+			written, err := (&layers.Factory{ArtifactsDir: t.TempDir(), Logger: factory.Logger}).DirLayer("some/layer", dir, "some-created-by")
+			h.AssertNil(t, err)
+			h.AssertEq(t, layer.Digest, written.Digest)
+			h.AssertEq(t, layer.TarPath, filepath.Join(factory.ArtifactsDir, "some_layer.tar"))
+			h.AssertEq(t, layer.Written(), false)
+			h.AssertPathDoesNotExist(t, layer.TarPath)
+		})
+
+		it("writes the tarball when it is needed", func() {
+			layer, err := factory.DirLayer("some/layer", dir, "some-created-by")
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, layer.WriteTar())
+			h.AssertEq(t, layer.Written(), true)
+			h.AssertEq(t, digestOf(t, layer.TarPath), layer.Digest)
+
+			// the layer is reused between the export and cache steps
+			reused, err := factory.DirLayer("some/layer", dir, "some-created-by")
+			h.AssertNil(t, err)
+			h.AssertEq(t, reused.Written(), true)
+		})
+
+		it("streams the tarball if it has not been written", func() {
+			layer, err := factory.DirLayer("some/layer", dir, "some-created-by")
+			h.AssertNil(t, err)
+
+			rc, err := layer.Open()
+			h.AssertNil(t, err)
+			contents, err := io.ReadAll(rc)
+			h.AssertNil(t, err)
+			h.AssertNil(t, rc.Close())
+			h.AssertEq(t, fmt.Sprintf("sha256:%x", sha256.Sum256(contents)), layer.Digest)
+			h.AssertPathDoesNotExist(t, layer.TarPath)
+		})
+
+		when("#Eager", func() {
+			it("writes the tarball as the digest is computed", func() {
+				layer, err := factory.Eager().DirLayer("some/layer", dir, "some-created-by")
+				h.AssertNil(t, err)
+
+				h.AssertEq(t, layer.Written(), true)
+				h.AssertEq(t, digestOf(t, layer.TarPath), layer.Digest)
+			})
+
+			it("shares the layers with the streaming factory", func() {
+				layer, err := factory.Eager().DirLayer("some/layer", dir, "some-created-by")
+				h.AssertNil(t, err)
+
+				reused, err := factory.DirLayer("some/layer", dir, "some-created-by")
+				h.AssertNil(t, err)
+				h.AssertEq(t, reused.Digest, layer.Digest)
+				h.AssertEq(t, reused.Written(), true)
+			})
+		})
+
+		when("the contents of the layer change", func() {
+			var layer layers.Layer
+
+			it.Before(func() {
+				var err error
+				layer, err = factory.DirLayer("some/layer", dir, "some-created-by")
+				h.AssertNil(t, err)
+				h.AssertNil(t, os.WriteFile(filepath.Join(dir, "some-dir", "some-file.txt"), []byte("other-contents"), 0600))
+			})
+
+			it("fails to write the tarball", func() {
+				err := layer.WriteTar()
+				h.AssertError(t, err, "contents of layer 'some/layer' changed while exporting")
+				h.AssertPathDoesNotExist(t, layer.TarPath)
+			})
+
+			it("fails to stream the tarball", func() {
+				rc, err := layer.Open()
+				h.AssertNil(t, err)
+				defer rc.Close()
+				_, err = io.ReadAll(rc)
+				h.AssertError(t, err, "contents of layer 'some/layer' changed while exporting")
+			})
+		})
+	})
+}
+
+func digestOf(t *testing.T, path string) string {
+	t.Helper()
+	contents, err := os.ReadFile(path)
+	h.AssertNil(t, err)
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(contents))
+}
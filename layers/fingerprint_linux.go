@@ -0,0 +1,59 @@
+//go:build linux
+
+package layers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// fingerprint returns a hash of the metadata of the provided directory, its contents and its parents.
+// Parents are only described by the metadata that is recorded in the layer tarball, as creating or removing other layers
+// changes their modification times.
+func fingerprint(dir string) (string, error) {
+	h := sha256.New()
+	parentDirs, err := parents(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, parent := range parentDirs {
+		st, ok := parent.Info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return "", errFingerprintUnsupported
+		}
+		fmt.Fprintf(h, "%s\x00%o\x00%d\x00%d\n", parent.Path, parent.Info.Mode(), st.Uid, st.Gid)
+	}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		st, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			return errFingerprintUnsupported
+		}
+		var target string
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if target, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(h, "%s\x00%o\x00%d\x00%d\x00%d\x00%d.%d\x00%d.%d\x00%d\x00%d\x00%s\n",
+			path, fi.Mode(), st.Uid, st.Gid, fi.Size(),
+			st.Mtim.Sec, st.Mtim.Nsec, st.Ctim.Sec, st.Ctim.Nsec,
+			st.Dev, st.Ino, target,
+		)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
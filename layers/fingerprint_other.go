@@ -0,0 +1,8 @@
+//go:build !linux
+
+package layers
+
+// fingerprint returns errFingerprintUnsupported, so that the digest cache is never used outside of Linux.
+func fingerprint(_ string) (string, error) {
+	return "", errFingerprintUnsupported
+}
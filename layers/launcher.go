@@ -3,7 +3,6 @@ package layers
 import (
 	"archive/tar"
 	"fmt"
-	"io"
 	"os"
 	"runtime"
 	"strings"
@@ -11,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/lifecycle/archive"
+	"github.com/buildpacks/lifecycle/internal/fsutil"
 	"github.com/buildpacks/lifecycle/launch"
 )
 
@@ -47,12 +47,12 @@ func (f *Factory) LauncherLayer(path string) (layer Layer, err error) {
 			return errors.Wrap(err, "failed to write header for launcher")
 		}
 
-		lf, err := os.Open(path)
+		lf, err := fsutil.OpenSequential(path)
 		if err != nil {
 			return fmt.Errorf("failed to open launcher at path '%s': %w", path, err)
 		}
 		defer lf.Close()
-		if _, err := io.Copy(tw, lf); err != nil {
+		if _, err := fsutil.Stream(tw, lf); err != nil {
 			return errors.Wrap(err, "failed to write launcher to layer")
 		}
 		return nil
@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/lifecycle/archive"
+	"github.com/buildpacks/lifecycle/internal/fsutil"
 )
 
 type Slice struct {
@@ -20,6 +22,7 @@ type Slice struct {
 // * Given n slices SliceLayers will return n+1 layers
 // * The first n layers will contain files matched by the any Path in the nth Slice
 // * The final layer will contain any files in dir that were not included in a previous layer
+// Files not selected by AppInclude and AppExclude are not added to any layer
 // Some layers may be empty
 func (f *Factory) SliceLayers(dir string, slices []Slice) ([]Layer, error) {
 	var sliceLayers []Layer
@@ -27,7 +30,11 @@ func (f *Factory) SliceLayers(dir string, slices []Slice) ([]Layer, error) {
 	if err != nil {
 		return nil, err
 	}
-	sdir, err := newSlicableDir(dir)
+	filter, err := fsutil.NewFilter(f.AppInclude, f.AppExclude)
+	if err != nil {
+		return nil, err
+	}
+	sdir, err := newSlicableDir(dir, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -69,34 +76,58 @@ func (f *Factory) createLayerFromSlice(slice Slice, sdir *sliceableDir, id int)
 	return f.createLayerFromFiles(id, createdBy, sdir, sdir.sliceFiles(matches))
 }
 
+// glob returns the paths in the sliceable dir that match the pattern.
+// Rather than walking the dir for every pattern, it only considers the indexed paths that can match:
+// as wildcards do not match path separators, a match has as many path elements as the pattern,
+// and starts with the elements of the pattern that do not contain wildcards.
 func glob(sdir *sliceableDir, pattern string) ([]string, error) {
 	pattern = filepath.Clean(pattern)
-	var matches []string
-	if err := filepath.Walk(sdir.path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if path == sdir.path {
-			return nil
-		}
-		relPath, err := filepath.Rel(sdir.path, path)
-		if err != nil {
-			return err
+	var candidates []string
+	if strings.Contains(pattern, "[") {
+		// character classes may match path separators
+		candidates = sdir.relPaths
+	} else {
+		candidates = sdir.byDepth[depth(pattern)]
+		if prefix := literalPrefix(pattern); prefix != "" {
+			start := sort.SearchStrings(candidates, prefix)
+			end := start + sort.Search(len(candidates)-start, func(i int) bool {
+				return !strings.HasPrefix(candidates[start+i], prefix)
+			})
+			candidates = candidates[start:end]
 		}
+	}
+	var matches []string
+	for _, relPath := range candidates {
 		match, err := filepath.Match(pattern, relPath)
 		if err != nil {
-			return errors.Wrapf(err, "failed to check if '%s' matches '%s'", relPath, pattern)
+			return nil, errors.Wrapf(err, "failed to check if '%s' matches '%s'", relPath, pattern)
 		}
 		if match {
-			matches = append(matches, path)
+			matches = append(matches, filepath.Join(sdir.path, relPath))
 		}
-		return nil
-	}); err != nil {
-		return nil, err
 	}
 	return matches, nil
 }
 
+// depth returns the number of elements in the relative path.
+func depth(relPath string) int {
+	return strings.Count(relPath, string(filepath.Separator)) + 1
+}
+
+// literalPrefix returns the leading elements of the pattern that do not contain wildcards (with a trailing separator),
+// or the whole pattern if it does not contain wildcards.
+func literalPrefix(pattern string) string {
+	meta := "*?["
+	if runtime.GOOS != "windows" {
+		meta += `\`
+	}
+	idx := strings.IndexAny(pattern, meta)
+	if idx == -1 {
+		return pattern
+	}
+	return pattern[:strings.LastIndex(pattern[:idx], string(filepath.Separator))+1]
+}
+
 func (f *Factory) createLayerFromFiles(id int, createdBy string, sdir *sliceableDir, files []archive.PathInfo) (layer Layer, err error) {
 	sort.SliceStable(files, func(i, j int) bool {
 		return files[i].Path < files[j].Path
@@ -121,34 +152,43 @@ type sliceableDir struct {
 	pathInfos   map[string]os.FileInfo // map of path to file info
 	subDirs     map[string][]string    // map dirs to children
 	parentDirs  []archive.PathInfo     // parents of the slicableDir
+	relPaths    []string               // paths relative to the slicableDir, in walk order
+	byDepth     map[int][]string       // relative paths by number of path elements, sorted
 }
 
-func newSlicableDir(appDir string) (*sliceableDir, error) {
+func newSlicableDir(appDir string, filter *fsutil.Filter) (*sliceableDir, error) {
 	sdir := &sliceableDir{
 		path:        appDir,
 		slicedFiles: map[string]bool{},
 		pathInfos:   map[string]os.FileInfo{},
 		subDirs:     map[string][]string{},
+		byDepth:     map[int][]string{},
 	}
-	if err := filepath.Walk(appDir, func(path string, fi os.FileInfo, err error) error {
+	// the dir is walked once, and the index is shared by all slices
+	if err := filter.Walk(appDir, func(path string, fi os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		sdir.slicedFiles[path] = false
 		sdir.pathInfos[path] = fi
-		if fi.IsDir() {
-			children, err := os.ReadDir(path)
-			if err != nil {
-				return err
-			}
-			for _, child := range children {
-				sdir.subDirs[path] = append(sdir.subDirs[path], filepath.Join(path, child.Name()))
-			}
+		if path == appDir {
+			return nil
 		}
+		parent := filepath.Dir(path)
+		sdir.subDirs[parent] = append(sdir.subDirs[parent], path)
+		relPath, err := filepath.Rel(appDir, path)
+		if err != nil {
+			return err
+		}
+		sdir.relPaths = append(sdir.relPaths, relPath)
+		sdir.byDepth[depth(relPath)] = append(sdir.byDepth[depth(relPath)], relPath)
 		return nil
 	}); err != nil {
 		return nil, err
 	}
+	for _, relPaths := range sdir.byDepth {
+		sort.Strings(relPaths)
+	}
 	parentDirs, err := parents(appDir)
 	if err != nil {
 		return nil, err
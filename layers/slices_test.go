@@ -401,5 +401,93 @@ func testSlices(t *testing.T, when spec.G, it spec.S) {
 				}...))
 			})
 		})
+
+		when("patterns match paths below the dir", func() {
+			it("matches the paths with the elements of the pattern", func() {
+				sep := string(filepath.Separator)
+				sliceLayers, err := factory.SliceLayers(dirToSlice, []layers.Slice{
+					{Paths: []string{"other-dir" + sep + "*.md", "*.md"}},
+					{Paths: []string{"some-dir[" + sep + "]file.md"}}, // character classes may match separators
+				})
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(sliceLayers), 3)
+				assertTarEntries(t, sliceLayers[0].TarPath, append(parents(t, dirToSlice), []*tar.Header{
+					{
+						Name:     tarPath(dirToSlice),
+						Uid:      factory.UID,
+						Gid:      factory.GID,
+						Typeflag: tar.TypeDir,
+					},
+					{
+						Name:     tarPath(filepath.Join(dirToSlice, "other-dir")),
+						Uid:      factory.UID,
+						Gid:      factory.GID,
+						Typeflag: tar.TypeDir,
+					},
+					{
+						Name:     tarPath(filepath.Join(dirToSlice, "other-dir", "other-file.md")),
+						Uid:      factory.UID,
+						Gid:      factory.GID,
+						Typeflag: tar.TypeReg,
+					},
+				}...))
+				assertTarEntries(t, sliceLayers[1].TarPath, append(parents(t, dirToSlice), []*tar.Header{
+					{
+						Name:     tarPath(dirToSlice),
+						Uid:      factory.UID,
+						Gid:      factory.GID,
+						Typeflag: tar.TypeDir,
+					},
+					{
+						Name:     tarPath(filepath.Join(dirToSlice, "some-dir")),
+						Uid:      factory.UID,
+						Gid:      factory.GID,
+						Typeflag: tar.TypeDir,
+					},
+					{
+						Name:     tarPath(filepath.Join(dirToSlice, "some-dir", "file.md")),
+						Uid:      factory.UID,
+						Gid:      factory.GID,
+						Typeflag: tar.TypeReg,
+					},
+				}...))
+			})
+		})
+
+		when("the project descriptor excludes files", func() {
+			it("does not add the excluded files to the layers", func() {
+				factory.AppExclude = []string{"*.md", "some-dir/", "*-link*"}
+				sliceLayers, err := factory.SliceLayers(dirToSlice, []layers.Slice{})
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(sliceLayers), 1)
+				assertTarEntries(t, sliceLayers[0].TarPath, append(parents(t, dirToSlice), []*tar.Header{
+					{
+						Name:     tarPath(dirToSlice),
+						Uid:      factory.UID,
+						Gid:      factory.GID,
+						Typeflag: tar.TypeDir,
+					},
+					{
+						Name:     tarPath(filepath.Join(dirToSlice, "file.txt")),
+						Uid:      factory.UID,
+						Gid:      factory.GID,
+						Typeflag: tar.TypeReg,
+					},
+					{
+						Name:     tarPath(filepath.Join(dirToSlice, "other-dir")),
+						Uid:      factory.UID,
+						Gid:      factory.GID,
+						Typeflag: tar.TypeDir,
+					},
+					{
+						Name:     tarPath(filepath.Join(dirToSlice, "other-dir", "other-file.txt")),
+						Uid:      factory.UID,
+						Gid:      factory.GID,
+						Typeflag: tar.TypeReg,
+					},
+				}...))
+				h.AssertPathExists(t, filepath.Join(dirToSlice, "some-dir", "file.md"))
+			})
+		})
 	})
 }
@@ -2,7 +2,7 @@ package layers
 
 import (
 	"archive/tar"
-	"crypto/sha256"
+	"bufio"
 	"fmt"
 	"io"
 	"os"
@@ -16,31 +16,80 @@ import (
 type layerWriter struct {
 	io.Writer
 	io.Closer
-	hasher *concurrentHasher
-	path   string
+	hasher    *concurrentHasher
+	algorithm DigestAlgorithm
 }
 
-func newFileLayerWriter(dest string) (*layerWriter, error) {
-	hasher := newConcurrentHasher(sha256.New())
+func newFileLayerWriter(dest string, algorithm DigestAlgorithm) (*layerWriter, error) {
+	// an existing tarball may be linked into the cache (see fsutil.LinkOrCopy), so it is replaced rather than truncated
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
 	file, err := os.Create(dest)
 	if err != nil {
 		return nil, err
 	}
-	w := io.MultiWriter(hasher, file)
-	return &layerWriter{w, file, hasher, dest}, nil
+	return newLayerWriter(&bufferedFile{Writer: bufio.NewWriterSize(file, fileBufferSize), file: file}, algorithm), nil
+}
+
+// fileBufferSize is the size of the buffer of layer tarballs, coalescing the writes of tar headers and padding
+// (of 512 bytes each) into fewer syscalls for layers with many small files.
+const fileBufferSize = 64 << 10
+
+// bufferedFile buffers the writes to a file, flushing them when it is closed.
+type bufferedFile struct {
+	*bufio.Writer
+	file *os.File
+}
+
+func (b *bufferedFile) Close() error {
+	if err := b.Flush(); err != nil {
+		_ = b.file.Close()
+		return err
+	}
+	return b.file.Close()
+}
+
+// newLayerWriter returns a writer that hashes everything written to w with the provided algorithm.
+func newLayerWriter(w io.WriteCloser, algorithm DigestAlgorithm) *layerWriter {
+	hasher := newConcurrentHasher(algorithm.New())
+	return &layerWriter{io.MultiWriter(hasher, w), w, hasher, algorithm}
 }
 
 func (lw *layerWriter) Digest() string {
-	return fmt.Sprintf("sha256:%x", lw.hasher.Sum(nil))
+	return fmt.Sprintf("%s:%x", lw.algorithm.Name, lw.hasher.Sum(nil))
 }
 
-func tarWriter(lw *layerWriter) *archive.NormalizingTarWriter {
+func tarWriter(w io.Writer) *archive.NormalizingTarWriter {
 	var tw *archive.NormalizingTarWriter
 	if runtime.GOOS == "windows" {
-		tw = archive.NewNormalizingTarWriter(layer.NewWindowsWriter(lw))
+		tw = archive.NewNormalizingTarWriter(layer.NewWindowsWriter(w))
 	} else {
-		tw = archive.NewNormalizingTarWriter(tar.NewWriter(lw))
+		tw = archive.NewNormalizingTarWriter(tar.NewWriter(w))
 	}
 	tw.WithModTime(archive.NormalizedModTime)
 	return tw
 }
+
+// writeTar writes the tarball with the provided entries to lw, and returns its digest.
+// It does not close lw.
+func writeTar(lw *layerWriter, addEntries func(tw *archive.NormalizingTarWriter) error) (string, error) {
+	tw := tarWriter(lw)
+	err := addEntries(tw)
+	if err == nil {
+		err = tw.Close()
+	}
+	digest := lw.Digest() // stops the hasher, even if the tarball could not be written
+	if err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
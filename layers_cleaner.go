@@ -0,0 +1,233 @@
+package lifecycle
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/internal/usage"
+	"github.com/buildpacks/lifecycle/launch"
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// reservedLayersDirs are the directories of the layers directory that are written by the lifecycle rather than by buildpacks.
+var reservedLayersDirs = map[string]bool{
+	"apps":      true,
+	"config":    true,
+	"extended":  true,
+	"generated": true,
+	"sbom":      true,
+}
+
+// buildpackFiles are the metadata files of a buildpack (rather than of one of its layers) in its layers directory.
+var buildpackFiles = map[string]bool{
+	"build":  true,
+	"launch": true,
+	"store":  true,
+}
+
+// lifecycleTempPrefixes are the prefixes of the temporary files and directories created by lifecycle phases.
+var lifecycleTempPrefixes = []string{
+	"cnb-extensions-generated.",
+	"extend-cache",
+	"lifecycle.",
+	"plan.",
+}
+
+// LayersCleaner removes content left in a reused layers directory by previous builds, so that it does not confuse later phases:
+// the directories of buildpacks that are not in the current group, layer directories without metadata,
+// metadata and SBOM files without layer directories, and temporary directories left behind by phases that did not exit cleanly.
+// As the restorer writes layer metadata without layer directories, it must run before the restorer (e.g., after the detector).
+type LayersCleaner struct {
+	LayersDir string
+	// BuildpacksDir and ExtensionsDir are the directories of the buildpacks and extensions of the builder.
+	// Only the directories of the layers directory named after a buildpack or an extension in these directories are removed,
+	// so that other directories (e.g., volumes mounted by the platform) are kept.
+	BuildpacksDir string
+	ExtensionsDir string
+	// TmpDir is the temporary directory of the lifecycle (see platform.EnvTmpDir); lifecycle temporary directories are removed from it if provided.
+	// As the names of these directories are not unique to the lifecycle, it must not be a temporary directory shared with other processes.
+	TmpDir string
+	Group  buildpack.Group
+	// DryRun reports what would be removed without removing it.
+	DryRun bool
+	Logger log.Logger
+}
+
+// CleanReport describes the entries removed by the cleaner.
+type CleanReport struct {
+	Removed []RemovedEntry
+}
+
+// RemovedEntry is a file or directory removed by the cleaner.
+type RemovedEntry struct {
+	Path   string
+	Reason string
+	Bytes  int64
+}
+
+// Bytes returns the total size of the removed entries.
+func (r CleanReport) Bytes() int64 {
+	var total int64
+	for _, entry := range r.Removed {
+		total += entry.Bytes
+	}
+	return total
+}
+
+// Clean removes the stale content of the layers directory and the temporary directory.
+func (c *LayersCleaner) Clean() (CleanReport, error) {
+	var report CleanReport
+	bpDirs := make(map[string]bool)
+	var prefixes []string
+	for _, bp := range c.Group.Group {
+		bpDirs[launch.EscapeID(bp.ID)] = true
+		prefixes = append(prefixes, launch.EscapeID(bp.ID)+"-")
+	}
+	for _, ext := range c.Group.GroupExtensions {
+		prefixes = append(prefixes, launch.EscapeID(ext.ID)+"-")
+	}
+
+	entries, err := os.ReadDir(c.LayersDir)
+	if err != nil {
+		return CleanReport{}, errors.Wrap(err, "reading layers directory")
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || reservedLayersDirs[name] || strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(c.LayersDir, name)
+		if !bpDirs[name] {
+			if !c.isModuleDir(name) {
+				c.Logger.Debugf("Keeping '%s': not the directory of a buildpack or an extension", path)
+				continue
+			}
+			if err := c.remove(&report, path, "buildpack is not in the group"); err != nil {
+				return CleanReport{}, err
+			}
+			continue
+		}
+		if err := c.cleanBuildpackDir(&report, path); err != nil {
+			return CleanReport{}, err
+		}
+	}
+
+	if c.TmpDir != "" {
+		if err := c.cleanTmpDir(&report, append(prefixes, lifecycleTempPrefixes...)); err != nil {
+			return CleanReport{}, err
+		}
+	}
+	return report, nil
+}
+
+// isModuleDir returns true if the provided directory of the layers directory is named after a buildpack or an extension of the builder.
+func (c *LayersCleaner) isModuleDir(name string) bool {
+	for _, dir := range []string{c.BuildpacksDir, c.ExtensionsDir} {
+		if dir == "" {
+			continue
+		}
+		if fi, err := os.Stat(filepath.Join(dir, name)); err == nil && fi.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanBuildpackDir removes the layer directories without metadata (and vice versa) from the layers directory of a buildpack.
+func (c *LayersCleaner) cleanBuildpackDir(report *CleanReport, bpDir string) error {
+	entries, err := os.ReadDir(bpDir)
+	if err != nil {
+		return errors.Wrapf(err, "reading buildpack directory '%s'", bpDir)
+	}
+	layerDirs := make(map[string]bool)
+	layerTOMLs := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir():
+			layerDirs[name] = true
+		case strings.HasSuffix(name, ".toml") && !buildpackFiles[strings.TrimSuffix(name, ".toml")]:
+			layerTOMLs[strings.TrimSuffix(name, ".toml")] = true
+		}
+	}
+
+	var stale []RemovedEntry
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir():
+			if !layerTOMLs[name] {
+				stale = append(stale, RemovedEntry{Path: name, Reason: "layer has no metadata"})
+			}
+		case strings.HasSuffix(name, ".toml"):
+			if layerName := strings.TrimSuffix(name, ".toml"); layerTOMLs[layerName] && !layerDirs[layerName] {
+				stale = append(stale, RemovedEntry{Path: name, Reason: "layer directory does not exist"})
+			}
+		case strings.Contains(name, ".sbom."):
+			layerName := name[:strings.Index(name, ".sbom.")]
+			if !buildpackFiles[layerName] && !(layerDirs[layerName] && layerTOMLs[layerName]) {
+				stale = append(stale, RemovedEntry{Path: name, Reason: "layer does not exist"})
+			}
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Path < stale[j].Path
+	})
+	for _, entry := range stale {
+		if err := c.remove(report, filepath.Join(bpDir, entry.Path), entry.Reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanTmpDir removes the temporary directories created by lifecycle phases (or for buildpacks in the group).
+func (c *LayersCleaner) cleanTmpDir(report *CleanReport, prefixes []string) error {
+	entries, err := os.ReadDir(c.TmpDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "reading temporary directory")
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(entry.Name(), prefix) {
+				if err := c.remove(report, filepath.Join(c.TmpDir, entry.Name()), "leftover temporary directory"); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (c *LayersCleaner) remove(report *CleanReport, path, reason string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return errors.Wrapf(err, "reading '%s'", path)
+	}
+	size := fi.Size()
+	if fi.IsDir() {
+		size = usage.DiskUsage(path)
+	}
+	report.Removed = append(report.Removed, RemovedEntry{Path: path, Reason: reason, Bytes: size})
+	if c.DryRun {
+		c.Logger.Infof("Would remove '%s': %s", path, reason)
+		return nil
+	}
+	c.Logger.Debugf("Removing '%s': %s", path, reason)
+	if err := os.RemoveAll(path); err != nil {
+		return errors.Wrapf(err, "removing '%s'", path)
+	}
+	return nil
+}
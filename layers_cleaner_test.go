@@ -0,0 +1,190 @@
+package lifecycle_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestLayersCleaner(t *testing.T) {
+	spec.Run(t, "LayersCleaner", testLayersCleaner, spec.Report(report.Terminal{}))
+}
+
+func testLayersCleaner(t *testing.T, when spec.G, it spec.S) {
+	var (
+		cleaner    *lifecycle.LayersCleaner
+		layersDir  string
+		modulesDir string
+		tmpDir     string
+		logHandler *memory.Handler
+	)
+
+	it.Before(func() {
+		var err error
+		layersDir, err = os.MkdirTemp("", "lifecycle.layers")
+		h.AssertNil(t, err)
+		tmpDir, err = os.MkdirTemp("", "lifecycle.tmp")
+		h.AssertNil(t, err)
+		modulesDir, err = os.MkdirTemp("", "lifecycle.modules")
+		h.AssertNil(t, err)
+		logHandler = memory.New()
+		cleaner = &lifecycle.LayersCleaner{
+			LayersDir:     layersDir,
+			BuildpacksDir: filepath.Join(modulesDir, "buildpacks"),
+			ExtensionsDir: filepath.Join(modulesDir, "extensions"),
+			TmpDir:        tmpDir,
+			Group: buildpack.Group{
+				Group:           []buildpack.GroupElement{{ID: "some/bp", Version: "v1"}},
+				GroupExtensions: []buildpack.GroupElement{{ID: "some/ext", Version: "v1"}},
+			},
+			Logger: &log.Logger{Handler: logHandler, Level: log.DebugLevel},
+		}
+
+		h.Mkdir(t,
+			filepath.Join(modulesDir, "buildpacks", "some_bp", "v1"),
+			filepath.Join(modulesDir, "buildpacks", "other_bp", "v1"),
+			filepath.Join(modulesDir, "extensions", "some_ext", "v1"),
+			filepath.Join(layersDir, "other_bp", "some-layer"),
+			filepath.Join(layersDir, "some-platform-mount"),
+			filepath.Join(layersDir, "some_bp", "kept-layer"),
+			filepath.Join(layersDir, "some_bp", "stale-layer"),
+			filepath.Join(layersDir, "config"),
+			filepath.Join(layersDir, "generated", "some_ext"),
+			filepath.Join(tmpDir, "lifecycle.exporter.layer123"),
+			filepath.Join(tmpDir, "some_bp-123"),
+			filepath.Join(tmpDir, "some_ext-123"),
+			filepath.Join(tmpDir, "unrelated"),
+		)
+		h.Mkfile(t, "[types]", filepath.Join(layersDir, "other_bp", "some-layer.toml"))
+		h.Mkfile(t, "some-content", filepath.Join(layersDir, "other_bp", "some-layer", "some-file"))
+		h.Mkfile(t, "[types]", filepath.Join(layersDir, "some_bp", "kept-layer.toml"))
+		h.Mkfile(t, "{}", filepath.Join(layersDir, "some_bp", "kept-layer.sbom.cdx.json"))
+		h.Mkfile(t, "some-content", filepath.Join(layersDir, "some_bp", "kept-layer", "some-file"))
+		h.Mkfile(t, "[types]", filepath.Join(layersDir, "some_bp", "orphaned-layer.toml"))
+		h.Mkfile(t, "{}", filepath.Join(layersDir, "some_bp", "orphaned-layer.sbom.cdx.json"))
+		h.Mkfile(t, "some-content", filepath.Join(layersDir, "some_bp", "stale-layer", "some-file"))
+		h.Mkfile(t, "[[processes]]", filepath.Join(layersDir, "some_bp", "launch.toml"))
+		h.Mkfile(t, "{}", filepath.Join(layersDir, "some_bp", "launch.sbom.cdx.json"))
+		h.Mkfile(t, "[metadata]", filepath.Join(layersDir, "some_bp", "store.toml"))
+		h.Mkfile(t, "[[group]]", filepath.Join(layersDir, "group.toml"))
+		h.Mkfile(t, "[[buildpacks]]", filepath.Join(layersDir, "config", "metadata.toml"))
+		h.Mkfile(t, "some-content", filepath.Join(layersDir, "generated", "some_ext", "Dockerfile"))
+		h.Mkfile(t, "some-content", filepath.Join(tmpDir, "lifecycle.exporter.layer123", "some-file"))
+		h.Mkfile(t, "some-content", filepath.Join(tmpDir, "some_bp-123", "plan.toml"))
+		h.Mkfile(t, "some-content", filepath.Join(tmpDir, "some_ext-123", "plan.toml"))
+		h.Mkfile(t, "some-content", filepath.Join(tmpDir, "unrelated", "some-file"))
+		h.Mkfile(t, "some-content", filepath.Join(layersDir, "some-platform-mount", "some-file"))
+	})
+
+	it.After(func() {
+		_ = os.RemoveAll(modulesDir)
+		_ = os.RemoveAll(layersDir)
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	when("#Clean", func() {
+		it("removes the directories of buildpacks that are not in the group", func() {
+			_, err := cleaner.Clean()
+			h.AssertNil(t, err)
+
+			h.AssertPathDoesNotExist(t, filepath.Join(layersDir, "other_bp"))
+		})
+
+		it("removes layer directories without metadata and metadata without layer directories", func() {
+			_, err := cleaner.Clean()
+			h.AssertNil(t, err)
+
+			h.AssertPathDoesNotExist(t, filepath.Join(layersDir, "some_bp", "orphaned-layer.toml"))
+			h.AssertPathDoesNotExist(t, filepath.Join(layersDir, "some_bp", "orphaned-layer.sbom.cdx.json"))
+			h.AssertPathDoesNotExist(t, filepath.Join(layersDir, "some_bp", "stale-layer"))
+			h.AssertPathExists(t, filepath.Join(layersDir, "some_bp", "kept-layer.toml"))
+			h.AssertPathExists(t, filepath.Join(layersDir, "some_bp", "kept-layer.sbom.cdx.json"))
+			h.AssertPathExists(t, filepath.Join(layersDir, "some_bp", "kept-layer", "some-file"))
+			h.AssertPathExists(t, filepath.Join(layersDir, "some_bp", "launch.toml"))
+			h.AssertPathExists(t, filepath.Join(layersDir, "some_bp", "launch.sbom.cdx.json"))
+			h.AssertPathExists(t, filepath.Join(layersDir, "some_bp", "store.toml"))
+		})
+
+		it("keeps the files of the lifecycle", func() {
+			_, err := cleaner.Clean()
+			h.AssertNil(t, err)
+
+			h.AssertPathExists(t, filepath.Join(layersDir, "group.toml"))
+			h.AssertPathExists(t, filepath.Join(layersDir, "config", "metadata.toml"))
+			h.AssertPathExists(t, filepath.Join(layersDir, "generated", "some_ext", "Dockerfile"))
+		})
+
+		it("keeps the directories that are not named after a buildpack or an extension", func() {
+			_, err := cleaner.Clean()
+			h.AssertNil(t, err)
+
+			h.AssertPathExists(t, filepath.Join(layersDir, "some-platform-mount", "some-file"))
+		})
+
+		it("does not clean a temporary directory if none is provided", func() {
+			cleaner.TmpDir = ""
+
+			_, err := cleaner.Clean()
+			h.AssertNil(t, err)
+
+			h.AssertPathExists(t, filepath.Join(tmpDir, "lifecycle.exporter.layer123"))
+			h.AssertPathExists(t, filepath.Join(tmpDir, "some_bp-123"))
+		})
+
+		it("removes leftover temporary directories", func() {
+			_, err := cleaner.Clean()
+			h.AssertNil(t, err)
+
+			h.AssertPathDoesNotExist(t, filepath.Join(tmpDir, "lifecycle.exporter.layer123"))
+			h.AssertPathDoesNotExist(t, filepath.Join(tmpDir, "some_bp-123"))
+			h.AssertPathDoesNotExist(t, filepath.Join(tmpDir, "some_ext-123"))
+			h.AssertPathExists(t, filepath.Join(tmpDir, "unrelated", "some-file"))
+		})
+
+		it("reports the removed entries", func() {
+			report, err := cleaner.Clean()
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, len(report.Removed), 7)
+			h.AssertEq(t, report.Removed[0], lifecycle.RemovedEntry{
+				Path:   filepath.Join(layersDir, "other_bp"),
+				Reason: "buildpack is not in the group",
+				Bytes:  int64(len("[types]") + len("some-content")),
+			})
+			h.AssertEq(t, report.Bytes() > 0, true)
+		})
+
+		when("dry run", func() {
+			it.Before(func() {
+				cleaner.DryRun = true
+			})
+
+			it("does not remove anything", func() {
+				report, err := cleaner.Clean()
+				h.AssertNil(t, err)
+
+				h.AssertEq(t, len(report.Removed), 7)
+				h.AssertPathExists(t, filepath.Join(layersDir, "other_bp"))
+				h.AssertPathExists(t, filepath.Join(layersDir, "some_bp", "stale-layer"))
+				h.AssertPathExists(t, filepath.Join(tmpDir, "some_bp-123"))
+				h.AssertLogEntry(t, logHandler, "Would remove '"+filepath.Join(layersDir, "other_bp")+"': buildpack is not in the group")
+			})
+		})
+
+		it("fails if the layers directory does not exist", func() {
+			cleaner.LayersDir = filepath.Join(layersDir, "missing")
+
+			_, err := cleaner.Clean()
+			h.AssertNotNil(t, err)
+		})
+	})
+}
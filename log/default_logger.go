@@ -3,7 +3,9 @@ package log
 import (
 	"fmt"
 	"io"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/heroku/color"
@@ -36,9 +38,53 @@ func (l *DefaultLogger) LogLevel() log.Level {
 }
 
 func (l *DefaultLogger) Phase(name string) {
+	if h, ok := l.Handler.(*jsonHandler); ok {
+		h.setPhase(name)
+		l.Infof("===> %s", name)
+		return
+	}
 	l.Infof(phaseStyle("===> %s", name))
 }
 
+// SetFormat sets the format of log records to either FormatHuman (the default) or FormatJSON.
+// JSON records are tagged with the provided phase name until the next call to Phase.
+func (l *DefaultLogger) SetFormat(requested string, phase string) error {
+	writer := l.writer()
+	switch requested {
+	case FormatHuman:
+		l.Handler = &handler{writer: writer}
+	case FormatJSON:
+		l.Handler = &jsonHandler{writer: writer, phase: strings.ToLower(phase), now: time.Now}
+	default:
+		return fmt.Errorf("failed to parse log format: '%s' (must be one of '%s' or '%s')", requested, FormatHuman, FormatJSON)
+	}
+	return nil
+}
+
+// SetWriter sets the writer that log records are written to, e.g., to redact them.
+func (l *DefaultLogger) SetWriter(writer io.Writer) {
+	switch h := l.Handler.(type) {
+	case *handler:
+		h.mu.Lock()
+		h.writer = writer
+		h.mu.Unlock()
+	case *jsonHandler:
+		h.mu.Lock()
+		h.writer = writer
+		h.mu.Unlock()
+	}
+}
+
+func (l *DefaultLogger) writer() io.Writer {
+	switch h := l.Handler.(type) {
+	case *handler:
+		return h.writer
+	case *jsonHandler:
+		return h.writer
+	}
+	return io.Discard
+}
+
 func (l *DefaultLogger) SetLevel(requested string) error {
 	var err error
 	l.Level, err = log.ParseLevel(requested)
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/apex/log"
+)
+
+// FieldBuildpack is the field used to tag log records with the ID of the buildpack or extension that produced them.
+const FieldBuildpack = "buildpack"
+
+// IsStructured returns true if the provided logger emits machine-readable records.
+func IsStructured(logger Logger) bool {
+	if l, ok := logger.(*DefaultLogger); ok {
+		_, ok = l.Handler.(*jsonHandler)
+		return ok
+	}
+	return false
+}
+
+// WithBuildpack returns a logger that tags records with the provided buildpack or extension ID,
+// if the provided logger supports fields.
+func WithBuildpack(logger Logger, id string) Logger {
+	if l, ok := logger.(log.Interface); ok {
+		return l.WithField(FieldBuildpack, id)
+	}
+	return logger
+}
+
+// NewWriter returns a writer that logs each line written to it at info level,
+// so that output from buildpack and extension processes can be included in structured logs.
+// Close must be called to log any trailing partial line.
+func NewWriter(logger Logger) io.WriteCloser {
+	return &lineWriter{logger: logger}
+}
+
+type lineWriter struct {
+	mu     sync.Mutex
+	logger Logger
+	buf    bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		idx := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if idx < 0 {
+			break
+		}
+		line := w.buf.Next(idx + 1)
+		w.logger.Info(string(bytes.TrimSuffix(line, []byte("\n"))))
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf.Len() > 0 {
+		w.logger.Info(w.buf.String())
+		w.buf.Reset()
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+)
+
+const (
+	FormatHuman = "human"
+	FormatJSON  = "json"
+)
+
+var _ log.Handler = &jsonHandler{}
+
+// jsonHandler writes each log entry as a single line of JSON, for consumption by log aggregation systems.
+type jsonHandler struct {
+	mu     sync.Mutex
+	writer io.Writer
+	phase  string
+	now    func() time.Time
+}
+
+type jsonRecord struct {
+	Time      string                 `json:"time"`
+	Level     string                 `json:"level"`
+	Phase     string                 `json:"phase,omitempty"`
+	Buildpack string                 `json:"buildpack,omitempty"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (h *jsonHandler) HandleLog(entry *log.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	record := jsonRecord{
+		Time:    h.now().UTC().Format(time.RFC3339Nano),
+		Level:   entry.Level.String(),
+		Phase:   h.phase,
+		Message: strings.TrimSuffix(entry.Message, "\n"),
+	}
+	for name, val := range entry.Fields {
+		if name == FieldBuildpack {
+			record.Buildpack = fmt.Sprint(val)
+			continue
+		}
+		if record.Fields == nil {
+			record.Fields = map[string]interface{}{}
+		}
+		record.Fields[name] = val
+	}
+	return json.NewEncoder(h.writer).Encode(record)
+}
+
+func (h *jsonHandler) setPhase(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.phase = strings.ToLower(name)
+}
@@ -0,0 +1,115 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/log"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestJSONHandler(t *testing.T) {
+	spec.Run(t, "JSONHandler", testJSONHandler, spec.Report(report.Terminal{}))
+}
+
+func testJSONHandler(t *testing.T, when spec.G, it spec.S) {
+	var (
+		buf    *bytes.Buffer
+		logger *log.DefaultLogger
+	)
+
+	it.Before(func() {
+		buf = &bytes.Buffer{}
+		logger = log.NewDefaultLogger(buf)
+		h.AssertNil(t, logger.SetLevel("debug"))
+		h.AssertNil(t, logger.SetFormat(log.FormatJSON, "detector"))
+	})
+
+	records := func() []map[string]interface{} {
+		var out []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var record map[string]interface{}
+			h.AssertNil(t, json.Unmarshal([]byte(line), &record))
+			out = append(out, record)
+		}
+		return out
+	}
+
+	when("#SetFormat", func() {
+		it("writes leveled, timestamped records tagged with the phase", func() {
+			logger.Warnf("some-%s", "warning")
+
+			record := records()[0]
+			h.AssertEq(t, record["level"], "warn")
+			h.AssertEq(t, record["message"], "some-warning")
+			h.AssertEq(t, record["phase"], "detector")
+			h.AssertEq(t, record["time"] != "", true)
+		})
+
+		it("writes human readable output by default", func() {
+			h.AssertNil(t, logger.SetFormat(log.FormatHuman, "detector"))
+
+			logger.Warn("some-warning")
+
+			h.AssertStringContains(t, buf.String(), "some-warning")
+			h.AssertEq(t, strings.HasPrefix(buf.String(), "{"), false)
+		})
+
+		it("errors for unknown formats", func() {
+			h.AssertError(t, logger.SetFormat("xml", "detector"), "failed to parse log format: 'xml'")
+		})
+	})
+
+	when("#Phase", func() {
+		it("tags subsequent records with the new phase", func() {
+			logger.Phase("BUILDING")
+			logger.Info("some-message")
+
+			all := records()
+			h.AssertEq(t, all[0]["message"], "===> BUILDING")
+			h.AssertEq(t, all[1]["phase"], "building")
+		})
+	})
+
+	when("#WithBuildpack", func() {
+		it("tags records with the buildpack ID", func() {
+			log.WithBuildpack(logger, "some/buildpack").Info("some-message")
+
+			record := records()[0]
+			h.AssertEq(t, record["buildpack"], "some/buildpack")
+			h.AssertEq(t, record["phase"], "detector")
+		})
+	})
+
+	when("#NewWriter", func() {
+		it("logs each line written", func() {
+			w := log.NewWriter(log.WithBuildpack(logger, "some/buildpack"))
+			_, err := fmt.Fprint(w, "first line\nsecond ")
+			h.AssertNil(t, err)
+			_, err = fmt.Fprint(w, "line\npartial")
+			h.AssertNil(t, err)
+			h.AssertNil(t, w.Close())
+
+			all := records()
+			h.AssertEq(t, len(all), 3)
+			h.AssertEq(t, all[0]["message"], "first line")
+			h.AssertEq(t, all[1]["message"], "second line")
+			h.AssertEq(t, all[2]["message"], "partial")
+			h.AssertEq(t, all[2]["buildpack"], "some/buildpack")
+		})
+	})
+
+	when("#IsStructured", func() {
+		it("is true for the JSON format only", func() {
+			h.AssertEq(t, log.IsStructured(logger), true)
+			h.AssertNil(t, logger.SetFormat(log.FormatHuman, "detector"))
+			h.AssertEq(t, log.IsStructured(logger), false)
+		})
+	})
+}
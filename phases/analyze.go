@@ -0,0 +1,61 @@
+package phases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform"
+)
+
+// Analyze reads metadata from the previous image and the run image, recording it in State.Analyzed.
+// For Platform API 0.7 and above, analyze runs before detect; for older Platform APIs, it must run after detect.
+// If the platform provides a trust policy, the run image is verified with Keychain first.
+type Analyze struct {
+	Factory  *lifecycle.AnalyzerFactory
+	Inputs   *platform.LifecycleInputs
+	Keychain authn.Keychain
+	Logger   log.Logger
+}
+
+func (a *Analyze) Name() string {
+	return "ANALYZING"
+}
+
+func (a *Analyze) Run(ctx context.Context, state *State) error {
+	if _, err := verifyRunImage(a.Inputs, a.Keychain, a.Logger); err != nil {
+		return err
+	}
+	var legacyCacheDir string
+	if a.Inputs.PlatformAPI.LessThan("0.7") {
+		legacyCacheDir = a.Inputs.CacheDir
+	}
+	analyzer, err := a.Factory.NewAnalyzer(
+		a.Inputs.AdditionalTags,
+		a.Inputs.CacheImageRef,
+		a.Inputs.LaunchCacheDir,
+		a.Inputs.LayersDir,
+		legacyCacheDir,
+		state.Group,
+		"",
+		a.Inputs.OutputImageRef,
+		a.Inputs.PreviousImageRef,
+		a.Inputs.RunImageRef,
+		a.Inputs.SkipLayers,
+		a.Logger,
+	)
+	if err != nil {
+		return fmt.Errorf("initializing analyzer: %w", err)
+	}
+	state.Analyzed, err = analyzer.AnalyzeContext(ctx)
+	if err != nil {
+		return err
+	}
+	if state.Analyzed.RunImage != nil {
+		state.Analyzed.RunImage.Selection = a.Inputs.RunImageSelection
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package phases
+
+import (
+	"context"
+	"io"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/internal/encoding"
+	"github.com/buildpacks/lifecycle/launch"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform"
+)
+
+// Build runs the buildpacks in State.Group, recording the build metadata in State.BuildMetadata.
+// The build metadata is also written to the layers directory, where it is read by the exporter.
+type Build struct {
+	Inputs   *platform.LifecycleInputs
+	Logger   log.Logger
+	Out, Err io.Writer
+}
+
+func (b *Build) Name() string {
+	return "BUILDING"
+}
+
+func (b *Build) Run(ctx context.Context, state *State) error {
+	builder, err := lifecycle.NewBuilderFromInputs(b.Inputs, state.Group, state.Plan, state.Analyzed, b.Logger, b.Out, b.Err)
+	if err != nil {
+		return err
+	}
+	md, err := builder.BuildContext(ctx)
+	if err != nil {
+		return err
+	}
+	state.BuildMetadata = *md
+	return encoding.WriteTOML(launch.GetMetadataFilePath(b.Inputs.LayersDir), md)
+}
@@ -0,0 +1,48 @@
+package phases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
+)
+
+// Detect chooses the buildpacks for the build, recording them in State.Group and their requirements in State.Plan.
+type Detect struct {
+	Factory *lifecycle.DetectorFactory
+	Inputs  *platform.LifecycleInputs
+	Logger  log.LoggerHandlerWithLevel
+}
+
+func (d *Detect) Name() string {
+	return "DETECTING"
+}
+
+func (d *Detect) Run(ctx context.Context, state *State) error {
+	if d.Inputs.AppSourceDir != "" {
+		if err := lifecycle.CopyAppSource(d.Inputs.AppSourceDir, d.Inputs.AppDir, d.Logger); err != nil {
+			return err
+		}
+	}
+	detector, err := d.Factory.NewDetector(state.Analyzed, d.Inputs.AppDir, d.Inputs.BuildConfigDir, d.Inputs.OrderPath, d.Inputs.PlatformDir, d.Logger)
+	if err != nil {
+		return fmt.Errorf("initializing detector: %w", err)
+	}
+	detector.AppSymlinks = d.Inputs.AppSymlinks
+	if d.Inputs.ConstraintsPath != "" {
+		if detector.Constraints, err = files.ReadConstraints(d.Inputs.ConstraintsPath); err != nil {
+			return err
+		}
+	}
+	state.Group, state.Plan, err = detector.DetectContext(ctx)
+	if err != nil {
+		return err
+	}
+	if len(state.Group.GroupExtensions) > 0 {
+		return fmt.Errorf("image extensions are not supported when running phases in-process")
+	}
+	return nil
+}
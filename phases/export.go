@@ -0,0 +1,91 @@
+package phases
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
+)
+
+// Export exports the application image, recording the report in State.Report, and saves the cache (if provided).
+// The platform provides the working image (e.g., a remote image based on the run image),
+// so that it can control how images are constructed and where they are saved.
+// The platform may also provide a debug image, to save the build-only layers and the build artifacts to.
+// Keychain is used to push to additional names and attach attestations when exporting to a registry,
+// and to verify the run image if the platform provides a trust policy.
+type Export struct {
+	Cache          lifecycle.Cache
+	DebugImage     imgutil.Image
+	Inputs         *platform.LifecycleInputs
+	Keychain       authn.Keychain
+	LauncherConfig lifecycle.LauncherConfig
+	Logger         log.Logger
+	RunImageID     string
+	WorkingImage   imgutil.Image
+}
+
+func (e *Export) Name() string {
+	return "EXPORTING"
+}
+
+func (e *Export) Run(ctx context.Context, state *State) error {
+	if err := e.verifyRunImage(); err != nil {
+		return err
+	}
+	artifactsDir, err := os.MkdirTemp("", "lifecycle.exporter.layer")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(artifactsDir)
+
+	exporter, err := lifecycle.NewExporterFromInputs(e.Inputs, state.Group, artifactsDir, e.Logger)
+	if err != nil {
+		return err
+	}
+	opts, err := lifecycle.NewExportOptions(e.Inputs, e.Keychain, e.Logger)
+	if err != nil {
+		return err
+	}
+	opts.DebugImage = e.DebugImage
+	opts.LauncherConfig = e.LauncherConfig
+	opts.OrigMetadata = state.Analyzed.LayersMetadata
+	opts.RunImageRef = e.RunImageID
+	opts.WorkingImage = e.WorkingImage
+	state.Report, err = exporter.ExportContext(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if e.Inputs.ReportPath != "" {
+		if err = files.NewHandler(e.Inputs.PlatformAPI, e.Logger).WriteReport(e.Inputs.ReportPath, state.Report); err != nil {
+			return err
+		}
+	}
+	if e.Cache != nil {
+		if cacheErr := exporter.CacheContext(ctx, e.Inputs.LayersDir, e.Cache); cacheErr != nil {
+			e.Logger.Warnf("Failed to export cache: %v\n", cacheErr)
+		}
+	}
+	return nil
+}
+
+// verifyRunImage verifies the run image against the trust policy provided by the platform (if any),
+// and ensures that the working image is based on the verified run image, identified by RunImageID.
+func (e *Export) verifyRunImage() error {
+	verifiedDigest, err := verifyRunImage(e.Inputs, e.Keychain, e.Logger)
+	if err != nil || verifiedDigest == "" {
+		return err
+	}
+	runImage, err := name.NewDigest(e.RunImageID)
+	if err != nil || runImage.DigestStr() != verifiedDigest {
+		return fmt.Errorf("run image '%s' is not the verified run image '%s@%s'", e.RunImageID, e.Inputs.RunImageRef, verifiedDigest)
+	}
+	return nil
+}
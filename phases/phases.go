@@ -0,0 +1,106 @@
+// Package phases runs the lifecycle phases in-process, for platforms written in Go that would otherwise
+// execute the lifecycle binaries in separate containers.
+//
+// Phases share a State, so that the outputs of one phase (e.g., the group and plan from detect)
+// are provided to later phases without being written to and read from platform files.
+// Image extensions are not supported when running phases in-process.
+package phases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
+	"github.com/buildpacks/lifecycle/telemetry"
+)
+
+// State holds the outputs of each phase.
+// Platforms may pre-populate the state to skip phases, e.g., providing a group and plan instead of running detect.
+type State struct {
+	Analyzed      files.Analyzed
+	Group         buildpack.Group
+	Plan          files.Plan
+	BuildMetadata files.BuildMetadata
+	Report        files.Report
+}
+
+// Phase is a lifecycle phase that can be run in-process.
+type Phase interface {
+	// Name is the name of the phase, e.g., "DETECTING".
+	Name() string
+	// Run runs the phase, reading the outputs of previous phases from the provided state
+	// and recording its own outputs in the state.
+	Run(ctx context.Context, state *State) error
+}
+
+type phaseLogger interface {
+	Phase(name string)
+}
+
+// Run runs the provided phases in order, stopping at the first error.
+// The outcome of each phase is reported to any hooks registered with the telemetry package.
+// The context is checked before each phase, and is passed to the phase so that e.g. a running buildpack is stopped when it is done.
+func Run(ctx context.Context, state *State, logger log.Logger, phases ...Phase) error {
+	for _, phase := range phases {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if l, ok := logger.(phaseLogger); ok {
+			l.Phase(phase.Name())
+		}
+		if err := runPhase(ctx, state, phase); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPhase runs the phase, reporting its outcome to any registered telemetry hooks.
+func runPhase(ctx context.Context, state *State, phase Phase) error {
+	if !telemetry.Enabled() {
+		return phase.Run(ctx, state)
+	}
+	timer := telemetry.Start(strings.ToLower(phase.Name()), cmd.Version, "")
+	err := phase.Run(ctx, state)
+	code, class := 0, ""
+	if err != nil {
+		code = cmd.CodeForFailed
+		var failErr *cmd.ErrorFail
+		if errors.As(err, &failErr) {
+			code = failErr.Code
+		}
+		class = cmd.ErrorClassFor(err, code)
+	}
+	_ = timer.Complete(code, class)
+	return err
+}
+
+// verifyRunImage verifies the run image against the trust policy provided by the platform (if any),
+// and returns the digest of the verified image, or an empty string if there is no trust policy.
+func verifyRunImage(inputs *platform.LifecycleInputs, keychain authn.Keychain, logger log.Logger) (string, error) {
+	if inputs.TrustPolicyPath == "" || inputs.RunImageRef == "" {
+		return "", nil
+	}
+	policy, err := files.ReadTrustPolicy(inputs.TrustPolicyPath)
+	if err != nil {
+		return "", err
+	}
+	verifiedRef, err := platform.VerifyBaseImage(policy, inputs.RunImageRef, keychain, logger)
+	if err != nil {
+		return "", fmt.Errorf("verifying run image: %w", err)
+	}
+	digest, err := name.NewDigest(verifiedRef)
+	if err != nil {
+		return "", err
+	}
+	return digest.DigestStr(), nil
+}
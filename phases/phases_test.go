@@ -0,0 +1,127 @@
+package phases_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/phases"
+	"github.com/buildpacks/lifecycle/telemetry"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestPhases(t *testing.T) {
+	spec.Run(t, "Phases", testPhases, spec.Report(report.Terminal{}))
+}
+
+type fakePhase struct {
+	name string
+	err  error
+	run  func(state *phases.State)
+	ran  bool
+}
+
+func (p *fakePhase) Name() string {
+	return p.name
+}
+
+func (p *fakePhase) Run(_ context.Context, state *phases.State) error {
+	p.ran = true
+	if p.run != nil {
+		p.run(state)
+	}
+	return p.err
+}
+
+func testPhases(t *testing.T, when spec.G, it spec.S) {
+	var (
+		logOut *bytes.Buffer
+		logger *log.DefaultLogger
+	)
+
+	it.Before(func() {
+		logOut = &bytes.Buffer{}
+		logger = log.NewDefaultLogger(logOut)
+	})
+
+	when("#Run", func() {
+		it("runs phases in order with shared state", func() {
+			detect := &fakePhase{name: "DETECTING", run: func(state *phases.State) {
+				state.Group = buildpack.Group{Group: []buildpack.GroupElement{{ID: "some-buildpack"}}}
+			}}
+			var foundGroup buildpack.Group
+			build := &fakePhase{name: "BUILDING", run: func(state *phases.State) {
+				foundGroup = state.Group
+			}}
+
+			h.AssertNil(t, phases.Run(context.Background(), &phases.State{}, logger, detect, build))
+
+			h.AssertEq(t, foundGroup.Group[0].ID, "some-buildpack")
+			h.AssertStringContains(t, logOut.String(), "===> DETECTING")
+			h.AssertStringContains(t, logOut.String(), "===> BUILDING")
+		})
+
+		it("stops at the first error", func() {
+			detect := &fakePhase{name: "DETECTING", err: errors.New("some-error")}
+			build := &fakePhase{name: "BUILDING"}
+
+			err := phases.Run(context.Background(), &phases.State{}, logger, detect, build)
+
+			h.AssertError(t, err, "some-error")
+			h.AssertEq(t, build.ran, false)
+		})
+
+		it("stops when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			detect := &fakePhase{name: "DETECTING", run: func(_ *phases.State) {
+				cancel()
+			}}
+			build := &fakePhase{name: "BUILDING"}
+
+			err := phases.Run(ctx, &phases.State{}, logger, detect, build)
+
+			h.AssertEq(t, errors.Is(err, context.Canceled), true)
+			h.AssertEq(t, detect.ran, true)
+			h.AssertEq(t, build.ran, false)
+		})
+
+		when("telemetry hooks are registered", func() {
+			var events []telemetry.Event
+
+			it.Before(func() {
+				events = nil
+				telemetry.Register(telemetry.HookFunc(func(event telemetry.Event) error {
+					events = append(events, event)
+					return nil
+				}))
+			})
+
+			it.After(func() {
+				telemetry.Reset()
+			})
+
+			it("reports the outcome of each phase", func() {
+				detect := &fakePhase{name: "DETECTING"}
+				build := &fakePhase{name: "BUILDING", err: cmd.FailErrCode(errors.New("some-error"), 51, "build")}
+
+				err := phases.Run(context.Background(), &phases.State{}, logger, detect, build)
+				h.AssertNotNil(t, err)
+
+				h.AssertEq(t, len(events), 2)
+				h.AssertEq(t, events[0].Phase, "detecting")
+				h.AssertEq(t, events[0].Outcome, telemetry.OutcomeSuccess)
+				h.AssertEq(t, events[1].Phase, "building")
+				h.AssertEq(t, events[1].Outcome, telemetry.OutcomeFailure)
+				h.AssertEq(t, events[1].ExitCode, 51)
+				h.AssertEq(t, events[1].ErrorClass, cmd.ErrorClassLifecycle)
+			})
+		})
+	})
+}
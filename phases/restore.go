@@ -0,0 +1,43 @@
+package phases
+
+import (
+	"context"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/internal/layer"
+	"github.com/buildpacks/lifecycle/layers"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform"
+)
+
+// Restore restores layer metadata from State.Analyzed, and layers from the cache (if provided).
+type Restore struct {
+	Cache  lifecycle.Cache
+	Inputs *platform.LifecycleInputs
+	Logger log.Logger
+}
+
+func (r *Restore) Name() string {
+	return "RESTORING"
+}
+
+func (r *Restore) Run(ctx context.Context, state *State) error {
+	restorer := &lifecycle.Restorer{
+		LayersDir:             r.Inputs.LayersDir,
+		Buildpacks:            state.Group.Group,
+		Logger:                r.Logger,
+		PlatformAPI:           r.Inputs.PlatformAPI,
+		LayerMetadataRestorer: layer.NewDefaultMetadataRestorer(r.Inputs.LayersDir, r.Inputs.SkipLayers, r.Logger),
+		LayersMetadata:        state.Analyzed.LayersMetadata,
+		SBOMRestorer: layer.NewSBOMRestorer(layer.SBOMRestorerOpts{
+			LayersDir:         r.Inputs.LayersDir,
+			Logger:            r.Logger,
+			Nop:               r.Inputs.SkipLayers,
+			LenientExtraction: r.Inputs.LenientExtraction,
+		}, r.Inputs.PlatformAPI),
+		DigestCache:       layers.DigestCacheFor(r.Inputs.LayersDir, r.Inputs.NoDigestCache),
+		LenientExtraction: r.Inputs.LenientExtraction,
+		Xattrs:            r.Inputs.XattrPrefixes(),
+	}
+	return restorer.RestoreContext(ctx, r.Cache)
+}
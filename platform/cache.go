@@ -8,6 +8,8 @@ import (
 type CacheMetadata struct {
 	BOM        files.LayerMetadata        `json:"sbom"`
 	Buildpacks []buildpack.LayersMetadata `json:"buildpacks"`
+	// Apps is the metadata of each app in a cache that is shared by the apps in apps.toml, by name (see cache.SharedCache).
+	Apps map[string]CacheMetadata `json:"apps,omitempty"`
 }
 
 func (cm *CacheMetadata) MetadataForBuildpack(id string) buildpack.LayersMetadata {
@@ -0,0 +1,87 @@
+package platform
+
+import (
+	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/buildpack"
+)
+
+// Capabilities describes what the lifecycle supports, so that platforms can negotiate behavior
+// instead of maintaining tables of lifecycle versions.
+type Capabilities struct {
+	Version        string    `json:"version"`
+	APIs           APIsInfo  `json:"apis"`
+	Features       []Feature `json:"features"`
+	ExtendBackends []string  `json:"extend-backends"`
+	SBOMFormats    []string  `json:"sbom-formats"`
+}
+
+type APIsInfo struct {
+	Buildpack APIInfo `json:"buildpack"`
+	Platform  APIInfo `json:"platform"`
+}
+
+type APIInfo struct {
+	Supported  []string `json:"supported"`
+	Deprecated []string `json:"deprecated"`
+}
+
+// Feature is an optional capability of the lifecycle, available when the platform requests at least MinPlatformAPI.
+// Experimental features are subject to EnvExperimentalMode.
+type Feature struct {
+	Name           string `json:"name"`
+	MinPlatformAPI string `json:"min-platform-api"`
+	Experimental   bool   `json:"experimental,omitempty"`
+}
+
+const (
+	FeatureNameBuildImageExtension = "build-image-extension"
+	FeatureNameCleanLayers         = "clean-layers"
+	FeatureNameRunImageExtension   = "run-image-extension"
+	FeatureNameLayoutExport        = "layout-export"
+	FeatureNameGenerateDryRun      = "generate-dry-run"
+	FeatureNameProjectDescriptor   = "project-descriptor"
+	FeatureNameVerifyReproducible  = "verify-reproducible"
+)
+
+// GetCapabilities returns the capabilities of the lifecycle with the provided version.
+func GetCapabilities(lifecycleVersion string) Capabilities {
+	return Capabilities{
+		Version: lifecycleVersion,
+		APIs: APIsInfo{
+			Buildpack: apiInfoFor(api.Buildpack),
+			Platform:  apiInfoFor(api.Platform),
+		},
+		Features: []Feature{
+			{Name: FeatureNameBuildImageExtension, MinPlatformAPI: "0.10", Experimental: true},
+			{Name: FeatureNameCleanLayers, MinPlatformAPI: "0.12"},
+			{Name: FeatureNameGenerateDryRun, MinPlatformAPI: "0.10", Experimental: true},
+			{Name: FeatureNameLayoutExport, MinPlatformAPI: "0.12", Experimental: true},
+			{Name: FeatureNameProjectDescriptor, MinPlatformAPI: "0.12"},
+			{Name: FeatureNameRunImageExtension, MinPlatformAPI: "0.12", Experimental: true},
+			{Name: FeatureNameVerifyReproducible, MinPlatformAPI: "0.12"},
+		},
+		ExtendBackends: []string{ExtendBackendKaniko, ExtendBackendBuildKit},
+		SBOMFormats:    []string{buildpack.MediaTypeCycloneDX, buildpack.MediaTypeSPDX, buildpack.MediaTypeSyft},
+	}
+}
+
+func apiInfoFor(apis api.APIs) APIInfo {
+	info := APIInfo{Supported: []string{}, Deprecated: []string{}}
+	for _, v := range apis.Supported {
+		info.Supported = append(info.Supported, v.String())
+	}
+	for _, v := range apis.Deprecated {
+		info.Deprecated = append(info.Deprecated, v.String())
+	}
+	return info
+}
+
+// Supports returns true if the provided feature is available for the provided Platform API.
+func (c Capabilities) Supports(feature string, platformAPI *api.Version) bool {
+	for _, f := range c.Features {
+		if f.Name == feature {
+			return platformAPI.AtLeast(f.MinPlatformAPI)
+		}
+	}
+	return false
+}
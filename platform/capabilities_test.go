@@ -0,0 +1,54 @@
+package platform_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/platform"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestCapabilities(t *testing.T) {
+	spec.Run(t, "Capabilities", testCapabilities, spec.Report(report.Terminal{}))
+}
+
+func testCapabilities(t *testing.T, when spec.G, it spec.S) {
+	when("#GetCapabilities", func() {
+		it("lists supported and deprecated APIs", func() {
+			capabilities := platform.GetCapabilities("some-version")
+
+			h.AssertEq(t, capabilities.Version, "some-version")
+			h.AssertEq(t, len(capabilities.APIs.Platform.Supported), len(api.Platform.Supported))
+			h.AssertEq(t, capabilities.APIs.Platform.Supported[len(api.Platform.Supported)-1], api.Platform.Latest().String())
+			h.AssertEq(t, len(capabilities.APIs.Buildpack.Deprecated), len(api.Buildpack.Deprecated))
+		})
+
+		it("is encoded as JSON", func() {
+			contents, err := json.Marshal(platform.GetCapabilities("some-version"))
+			h.AssertNil(t, err)
+
+			var decoded map[string]interface{}
+			h.AssertNil(t, json.Unmarshal(contents, &decoded))
+			h.AssertEq(t, decoded["version"], "some-version")
+			h.AssertEq(t, decoded["extend-backends"], []interface{}{"kaniko", "buildkit"})
+		})
+	})
+
+	when("#Supports", func() {
+		it("returns true if the Platform API is high enough", func() {
+			capabilities := platform.GetCapabilities("some-version")
+
+			h.AssertEq(t, capabilities.Supports(platform.FeatureNameRunImageExtension, api.MustParse("0.12")), true)
+			h.AssertEq(t, capabilities.Supports(platform.FeatureNameRunImageExtension, api.MustParse("0.11")), false)
+			h.AssertEq(t, capabilities.Supports(platform.FeatureNameCleanLayers, api.MustParse("0.12")), true)
+			h.AssertEq(t, capabilities.Supports(platform.FeatureNameVerifyReproducible, api.MustParse("0.11")), false)
+			h.AssertEq(t, capabilities.Supports(platform.FeatureNameProjectDescriptor, api.MustParse("0.12")), true)
+			h.AssertEq(t, capabilities.Supports(platform.FeatureNameProjectDescriptor, api.MustParse("0.11")), false)
+			h.AssertEq(t, capabilities.Supports("some-unknown-feature", api.MustParse("0.12")), false)
+		})
+	})
+}
@@ -0,0 +1,149 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+
+	lerrors "github.com/buildpacks/lifecycle/errors"
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// EnvLifecycleConfig is the location of a TOML file providing values for any of the environment variables
+// read by the lifecycle phases, keyed by environment variable name, e.g.:
+//
+//	CNB_LAYERS_DIR = "/layers"
+//	CNB_LOG_LEVEL = "debug"
+//	CNB_USE_DAEMON = true
+//
+// Values are used when the corresponding environment variable is unset; command-line flags take precedence over both.
+// Registry credentials (CNB_REGISTRY_AUTH) must still be provided in the environment.
+//
+// Flags without a corresponding environment variable (e.g., -tag) are provided in the [flags] table,
+// keyed by flag name, with a list of values for flags that may be repeated:
+//
+//	[flags]
+//	tag = ["registry.example.com/app:v1", "registry.example.com/app:latest"]
+//
+// Values in the [flags] table are used when the flag is provided neither on the command line nor by its environment variable
+// (so that the environment still takes precedence over the config file), and are ignored by phases
+// that do not define the flag, so that a single config file can be used for every phase.
+const EnvLifecycleConfig = "CNB_LIFECYCLE_CONFIG"
+
+// lifecycleConfigFlagsTable is the name of the table of the lifecycle config file that provides values for flags.
+const lifecycleConfigFlagsTable = "flags"
+
+type lifecycleConfigValues struct {
+	env   map[string]string
+	flags map[string][]string
+}
+
+var lifecycleConfig struct {
+	sync.Mutex
+	path   string
+	values *lifecycleConfigValues
+	err    error
+}
+
+// Getenv returns the value of the provided environment variable,
+// or the value for the variable in the lifecycle config file if the variable is unset.
+func Getenv(key string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	values, err := readLifecycleConfig()
+	if err != nil {
+		return ""
+	}
+	return values.env[key]
+}
+
+// LifecycleConfigFlags returns the values of the [flags] table of the lifecycle config file (if provided), keyed by flag name.
+func LifecycleConfigFlags() (map[string][]string, error) {
+	values, err := readLifecycleConfig()
+	if err != nil {
+		return nil, err
+	}
+	return values.flags, nil
+}
+
+// ValidateLifecycleConfig returns an error if the lifecycle config file (if provided) could not be read.
+func ValidateLifecycleConfig(_ *LifecycleInputs, _ log.Logger) error {
+	_, err := readLifecycleConfig()
+	return err
+}
+
+// readLifecycleConfig reads the lifecycle config file, if provided.
+// The file is read once for each path, as the lifecycle phases read their inputs many times during startup.
+func readLifecycleConfig() (*lifecycleConfigValues, error) {
+	lifecycleConfig.Lock()
+	defer lifecycleConfig.Unlock()
+	path := os.Getenv(EnvLifecycleConfig)
+	if path == lifecycleConfig.path && (lifecycleConfig.values != nil || lifecycleConfig.err != nil) {
+		return lifecycleConfig.values, lifecycleConfig.err
+	}
+	lifecycleConfig.path = path
+	lifecycleConfig.values, lifecycleConfig.err = decodeLifecycleConfig(path)
+	return lifecycleConfig.values, lifecycleConfig.err
+}
+
+func decodeLifecycleConfig(path string) (*lifecycleConfigValues, error) {
+	values := &lifecycleConfigValues{env: map[string]string{}, flags: map[string][]string{}}
+	if path == "" {
+		return values, nil
+	}
+	var contents map[string]interface{}
+	if _, err := toml.DecodeFile(path, &contents); err != nil {
+		return nil, fmt.Errorf("failed to read lifecycle config file '%s': %w", path, lerrors.NewDecodeError(path, err))
+	}
+	for key, val := range contents {
+		if key == lifecycleConfigFlagsTable {
+			if err := decodeLifecycleConfigFlags(val, values.flags); err != nil {
+				return nil, fmt.Errorf("failed to read lifecycle config file '%s': %w", path, err)
+			}
+			continue
+		}
+		scalar, ok := lifecycleConfigScalar(val)
+		if !ok {
+			return nil, fmt.Errorf("failed to read lifecycle config file '%s': unsupported value for '%s'", path, key)
+		}
+		values.env[key] = scalar
+	}
+	return values, nil
+}
+
+func decodeLifecycleConfigFlags(val interface{}, flags map[string][]string) error {
+	table, ok := val.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("'%s' must be a table", lifecycleConfigFlagsTable)
+	}
+	for name, val := range table {
+		if list, ok := val.([]interface{}); ok {
+			for _, elem := range list {
+				scalar, ok := lifecycleConfigScalar(elem)
+				if !ok {
+					return fmt.Errorf("unsupported value for flag '%s'", name)
+				}
+				flags[name] = append(flags[name], scalar)
+			}
+			continue
+		}
+		scalar, ok := lifecycleConfigScalar(val)
+		if !ok {
+			return fmt.Errorf("unsupported value for flag '%s'", name)
+		}
+		flags[name] = []string{scalar}
+	}
+	return nil
+}
+
+func lifecycleConfigScalar(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case string, bool, int64, float64:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,113 @@
+package platform_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/platform"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestConfigFile(t *testing.T) {
+	spec.Run(t, "ConfigFile", testConfigFile, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testConfigFile(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir     string
+		configPath string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle-config")
+		h.AssertNil(t, err)
+		configPath = filepath.Join(tmpDir, "lifecycle.toml")
+		h.AssertNil(t, os.Setenv(platform.EnvLifecycleConfig, configPath))
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.Unsetenv(platform.EnvLifecycleConfig))
+		h.AssertNil(t, os.RemoveAll(tmpDir))
+	})
+
+	when("#Getenv", func() {
+		it.Before(func() {
+			h.Mkfile(t, `CNB_LOG_LEVEL = "debug"
+CNB_USE_DAEMON = true
+CNB_GROUP_ID = 1000
+`, configPath)
+		})
+
+		it("uses values from the config file when the environment variable is unset", func() {
+			h.AssertEq(t, platform.Getenv(platform.EnvLogLevel), "debug")
+			h.AssertEq(t, platform.Getenv(platform.EnvUseDaemon), "true")
+			h.AssertEq(t, platform.Getenv(platform.EnvGID), "1000")
+			h.AssertEq(t, platform.Getenv(platform.EnvLayersDir), "")
+		})
+
+		it("prefers the environment", func() {
+			h.AssertNil(t, os.Setenv(platform.EnvLogLevel, "warn"))
+			defer os.Unsetenv(platform.EnvLogLevel)
+
+			h.AssertEq(t, platform.Getenv(platform.EnvLogLevel), "warn")
+		})
+
+		it("provides defaults for the lifecycle inputs", func() {
+			inputs := platform.NewLifecycleInputs(api.Platform.Latest())
+
+			h.AssertEq(t, inputs.LogLevel, "debug")
+			h.AssertEq(t, inputs.UseDaemon, true)
+			h.AssertEq(t, inputs.GID, 1000)
+		})
+	})
+
+	when("#LifecycleConfigFlags", func() {
+		it("returns the values of the flags table", func() {
+			h.Mkfile(t, `CNB_LOG_LEVEL = "debug"
+
+[flags]
+tag = ["some-tag", "other-tag"]
+image = "some-run-image"
+`, configPath)
+
+			flags, err := platform.LifecycleConfigFlags()
+			h.AssertNil(t, err)
+			h.AssertEq(t, flags, map[string][]string{
+				"tag":   {"some-tag", "other-tag"},
+				"image": {"some-run-image"},
+			})
+			h.AssertEq(t, platform.Getenv("flags"), "")
+			h.AssertEq(t, platform.Getenv(platform.EnvLogLevel), "debug")
+		})
+
+		it("errors when a flag has an unsupported value", func() {
+			h.Mkfile(t, `[flags]
+tag = [{ name = "some-tag" }]
+`, configPath)
+
+			_, err := platform.LifecycleConfigFlags()
+			h.AssertError(t, err, "unsupported value for flag 'tag'")
+		})
+	})
+
+	when("#ValidateLifecycleConfig", func() {
+		it("errors when the config file is missing", func() {
+			err := platform.ValidateLifecycleConfig(nil, cmd.DefaultLogger)
+			h.AssertError(t, err, "failed to read lifecycle config file")
+		})
+
+		it("errors when the config file has unsupported values", func() {
+			h.Mkfile(t, `CNB_RUN_IMAGE = ["some-run-image"]`, configPath)
+
+			err := platform.ValidateLifecycleConfig(nil, cmd.DefaultLogger)
+			h.AssertError(t, err, "unsupported value for 'CNB_RUN_IMAGE'")
+		})
+	})
+}
@@ -0,0 +1,156 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// ContractViolationError is returned by ResolveInputs and VerifyInputs when the inputs of a phase do not satisfy the platform contract.
+// It reports every violation found, instead of the first one; a single violation is reported with its own message.
+type ContractViolationError struct {
+	Violations []error
+}
+
+func (e *ContractViolationError) Error() string {
+	if len(e.Violations) == 1 {
+		return e.Violations[0].Error()
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("found %d contract violations:", len(e.Violations)))
+	for _, violation := range e.Violations {
+		sb.WriteString("\n  - " + violation.Error())
+	}
+	return sb.String()
+}
+
+func (e *ContractViolationError) Unwrap() []error {
+	return e.Violations
+}
+
+// VerifyInputs resolves the inputs of the provided phase like ResolveInputs, and then ensures that the directories and files
+// required by the phase are present and that the files can be parsed.
+// Instead of failing on the first invalid input, it returns a ContractViolationError naming every missing or invalid input.
+func VerifyInputs(phase LifecyclePhase, i *LifecycleInputs, logger log.Logger) error {
+	return runOperations(append(resolveOperations(phase, i), contractOperations(phase, i)...), i, logger)
+}
+
+// contractOperations returns the operations that verify the directories and files that the provided phase reads.
+// The operations are given pointers to the inputs, as the paths are resolved by the preceding operations.
+func contractOperations(phase LifecyclePhase, i *LifecycleInputs) []LifecycleInputsOperation {
+	ops := []LifecycleInputsOperation{ValidateModes}
+	switch phase {
+	case Analyze:
+		ops = append(ops, requireDir(&i.LayersDir, "-layers", EnvLayersDir))
+	case Build:
+		ops = append(ops,
+			requireDir(&i.AppDir, "-app", EnvAppDir),
+			requireDir(&i.BuildpacksDir, "-buildpacks", EnvBuildpacksDir),
+			requireDir(&i.LayersDir, "-layers", EnvLayersDir),
+			requireFile(&i.GroupPath, "-group", EnvGroupPath),
+			requireFile(&i.PlanPath, "-plan", EnvPlanPath),
+			optionalFile(&i.AnalyzedPath, "-analyzed", EnvAnalyzedPath),
+		)
+	case Create:
+		ops = append(ops,
+			requireDir(&i.AppDir, "-app", EnvAppDir),
+			requireDir(&i.BuildpacksDir, "-buildpacks", EnvBuildpacksDir),
+			requireDir(&i.LayersDir, "-layers", EnvLayersDir),
+		)
+		if i.SkipDetect {
+			ops = append(ops, requireFile(&i.GroupPath, "-group", EnvGroupPath), requireFile(&i.PlanPath, "-plan", EnvPlanPath))
+		} else {
+			ops = append(ops, requireFile(&i.OrderPath, "-order", EnvOrderPath))
+		}
+	case Detect:
+		ops = append(ops,
+			requireDir(&i.AppDir, "-app", EnvAppDir),
+			requireDir(&i.BuildpacksDir, "-buildpacks", EnvBuildpacksDir),
+			requireFile(&i.OrderPath, "-order", EnvOrderPath),
+			optionalFile(&i.AnalyzedPath, "-analyzed", EnvAnalyzedPath),
+		)
+	case Export:
+		ops = append(ops,
+			requireDir(&i.AppDir, "-app", EnvAppDir),
+			requireDir(&i.LayersDir, "-layers", EnvLayersDir),
+			requireFile(&i.GroupPath, "-group", EnvGroupPath),
+			requireFile(&i.AnalyzedPath, "-analyzed", EnvAnalyzedPath),
+		)
+	case Extend:
+		ops = append(ops,
+			requireDir(&i.LayersDir, "-layers", EnvLayersDir),
+			requireFile(&i.GroupPath, "-group", EnvGroupPath),
+			requireFile(&i.AnalyzedPath, "-analyzed", EnvAnalyzedPath),
+		)
+	case Restore:
+		ops = append(ops,
+			requireDir(&i.LayersDir, "-layers", EnvLayersDir),
+			requireFile(&i.GroupPath, "-group", EnvGroupPath),
+			optionalFile(&i.AnalyzedPath, "-analyzed", EnvAnalyzedPath),
+		)
+	}
+	return ops
+}
+
+// ValidateModes ensures that the modes for deprecated APIs and experimental features (if provided) are known modes.
+func ValidateModes(_ *LifecycleInputs, _ log.Logger) error {
+	var invalid []string
+	for _, envVar := range []string{EnvDeprecationMode, EnvExperimentalMode} {
+		switch mode := Getenv(envVar); mode {
+		case "", ModeQuiet, ModeWarn, ModeError:
+		default:
+			invalid = append(invalid, fmt.Sprintf("%s=%s", envVar, mode))
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("unsupported mode %s; supported modes are: %s, %s, %s", strings.Join(invalid, ", "), ModeQuiet, ModeWarn, ModeError)
+	}
+	return nil
+}
+
+func requireDir(dir *string, flag, envVar string) LifecycleInputsOperation {
+	return func(_ *LifecycleInputs, _ log.Logger) error {
+		fi, err := os.Stat(*dir)
+		switch {
+		case os.IsNotExist(err):
+			return fmt.Errorf("required directory '%s' (%s, %s) does not exist", *dir, flag, envVar)
+		case err != nil:
+			return fmt.Errorf("failed to access required directory '%s' (%s, %s): %w", *dir, flag, envVar, err)
+		case !fi.IsDir():
+			return fmt.Errorf("required directory '%s' (%s, %s) is not a directory", *dir, flag, envVar)
+		default:
+			return nil
+		}
+	}
+}
+
+func requireFile(path *string, flag, envVar string) LifecycleInputsOperation {
+	return func(_ *LifecycleInputs, _ log.Logger) error {
+		if _, err := os.Stat(*path); os.IsNotExist(err) {
+			return fmt.Errorf("required file '%s' (%s, %s) does not exist", *path, flag, envVar)
+		}
+		return checkTOML(*path, flag, envVar)
+	}
+}
+
+// optionalFile returns an operation that ensures the file can be parsed, if it exists.
+func optionalFile(path *string, flag, envVar string) LifecycleInputsOperation {
+	return func(_ *LifecycleInputs, _ log.Logger) error {
+		if _, err := os.Stat(*path); os.IsNotExist(err) {
+			return nil
+		}
+		return checkTOML(*path, flag, envVar)
+	}
+}
+
+func checkTOML(path, flag, envVar string) error {
+	var contents map[string]interface{}
+	if _, err := toml.DecodeFile(path, &contents); err != nil {
+		return fmt.Errorf("failed to parse file '%s' (%s, %s): %w", path, flag, envVar, err)
+	}
+	return nil
+}
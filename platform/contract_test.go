@@ -0,0 +1,131 @@
+package platform_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/api"
+	llog "github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestContract(t *testing.T) {
+	spec.Run(t, "Contract", testContract, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testContract(t *testing.T, when spec.G, it spec.S) {
+	var (
+		inputs *platform.LifecycleInputs
+		logger llog.Logger
+		tmpDir string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle.test")
+		h.AssertNil(t, err)
+		inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+		inputs.AppDir = filepath.Join(tmpDir, "workspace")
+		inputs.BuildpacksDir = filepath.Join(tmpDir, "buildpacks")
+		inputs.LayersDir = filepath.Join(tmpDir, "layers")
+		inputs.GroupPath = filepath.Join(tmpDir, "layers", "group.toml")
+		inputs.PlanPath = filepath.Join(tmpDir, "layers", "plan.toml")
+		inputs.AnalyzedPath = filepath.Join(tmpDir, "layers", "analyzed.toml")
+		logger = &log.Logger{Handler: memory.New()}
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.RemoveAll(tmpDir))
+	})
+
+	when("#VerifyInputs", func() {
+		when("the required inputs are present", func() {
+			it.Before(func() {
+				h.Mkdir(t, inputs.AppDir, inputs.BuildpacksDir, inputs.LayersDir)
+				h.Mkfile(t, "[[group]]\nid = \"some/bp\"\nversion = \"1.0\"\n", inputs.GroupPath)
+				h.Mkfile(t, "", inputs.PlanPath)
+			})
+
+			it("succeeds", func() {
+				h.AssertNil(t, platform.VerifyInputs(platform.Build, inputs, logger))
+			})
+
+			when("a file cannot be parsed", func() {
+				it("names the file", func() {
+					h.Mkfile(t, "[[group]\n", inputs.GroupPath)
+
+					err := platform.VerifyInputs(platform.Build, inputs, logger)
+					h.AssertError(t, err, "failed to parse file '"+inputs.GroupPath+"' (-group, CNB_GROUP_PATH)")
+				})
+			})
+
+			when("an optional file is present", func() {
+				it("ensures it can be parsed", func() {
+					h.Mkfile(t, "key = = 1\n", inputs.AnalyzedPath)
+
+					err := platform.VerifyInputs(platform.Build, inputs, logger)
+					h.AssertError(t, err, "failed to parse file '"+inputs.AnalyzedPath+"' (-analyzed, CNB_ANALYZED_PATH)")
+				})
+			})
+
+			when("a mode is unknown", func() {
+				it.Before(func() {
+					h.AssertNil(t, os.Setenv(platform.EnvDeprecationMode, "some-mode"))
+				})
+
+				it.After(func() {
+					h.AssertNil(t, os.Unsetenv(platform.EnvDeprecationMode))
+				})
+
+				it("errors", func() {
+					err := platform.VerifyInputs(platform.Build, inputs, logger)
+					h.AssertError(t, err, "unsupported mode CNB_DEPRECATION_MODE=some-mode")
+				})
+			})
+		})
+
+		when("the required inputs are missing", func() {
+			it("reports every missing input", func() {
+				h.Mkdir(t, inputs.AppDir)
+				h.Mkfile(t, "", filepath.Join(tmpDir, "buildpacks"))
+
+				err := platform.VerifyInputs(platform.Build, inputs, logger)
+				var contractErr *platform.ContractViolationError
+				h.AssertEq(t, errors.As(err, &contractErr), true)
+				h.AssertEq(t, len(contractErr.Violations), 4)
+				h.AssertError(t, err, "found 4 contract violations:")
+				h.AssertError(t, err, "required directory '"+inputs.BuildpacksDir+"' (-buildpacks, CNB_BUILDPACKS_DIR) is not a directory")
+				h.AssertError(t, err, "required directory '"+inputs.LayersDir+"' (-layers, CNB_LAYERS_DIR) does not exist")
+				h.AssertError(t, err, "required file '"+inputs.GroupPath+"' (-group, CNB_GROUP_PATH) does not exist")
+				h.AssertError(t, err, "required file '"+inputs.PlanPath+"' (-plan, CNB_PLAN_PATH) does not exist")
+			})
+		})
+	})
+
+	when("#ResolveInputs", func() {
+		it("reports every invalid input", func() {
+			inputs.SBOMValidation = "some-validation"
+			inputs.Umask = "999"
+
+			err := platform.ResolveInputs(platform.Build, inputs, logger)
+			h.AssertError(t, err, "found 2 contract violations:")
+			h.AssertError(t, err, "unsupported SBOM validation mode 'some-validation'")
+			h.AssertError(t, err, "invalid umask '999'")
+		})
+
+		it("reports a single invalid input with its own message", func() {
+			inputs.Umask = "999"
+
+			err := platform.ResolveInputs(platform.Build, inputs, logger)
+			h.AssertEq(t, err.Error(), "invalid umask '999'; must be an octal value such as 022")
+		})
+	})
+}
@@ -31,8 +31,16 @@ const (
 	EnvLogLevel     = "CNB_LOG_LEVEL"
 	DefaultLogLevel = "info"
 
+	// EnvLogFormat is the format of log records: "human" (the default) or "json".
+	EnvLogFormat     = "CNB_LOG_FORMAT"
+	DefaultLogFormat = "human"
+
 	EnvNoColor = "CNB_NO_COLOR"
 
+	// EnvTelemetryReporter is the location of an executable that receives the anonymized timing and outcome of each phase.
+	// See telemetry.ExecHook for the contract that the executable must satisfy.
+	EnvTelemetryReporter = "CNB_TELEMETRY_REPORTER"
+
 	// EnvDeprecationMode is the desired behavior when deprecated APIs (either Platform or Buildpack) are requested.
 	EnvDeprecationMode = "CNB_DEPRECATION_MODE" // defaults to ModeQuiet
 
@@ -52,6 +60,8 @@ const (
 // EnvUseDaemon configures the lifecycle to export the application image to a daemon satisfying the Docker socket interface (e.g., docker, podman).
 // If not provided, the default behavior is to export to an OCI registry.
 // When exporting to a daemon, the socket must be available in the build environment and the lifecycle must be run as root.
+// The socket is found from DOCKER_HOST, CONTAINER_HOST (Podman), or the default Docker and Podman socket locations, see [priv.DockerHost].
+// containerd (e.g., as used by nerdctl) does not satisfy the Docker socket interface and is not supported.
 // When exporting to an OCI registry, registry credentials must be provided either on-disk (e.g., `~/.docker/config.json`),
 // via a credential helper, or via the `CNB_REGISTRY_AUTH` environment variable. See [auth.DefaultKeychain] for further information.
 const EnvUseDaemon = "CNB_USE_DAEMON"
@@ -121,6 +131,68 @@ const (
 	EnvPlatformDir = "CNB_PLATFORM_DIR"
 )
 
+// EnvAppSourceDir is the location of the application source, if it is not provided in the app directory.
+// When provided, the detector copies the source to the app directory before buildpacks run,
+// skipping the paths that match the gitignore-style patterns in <app-source>/.cnbignore (if present).
+const EnvAppSourceDir = "CNB_APP_SOURCE_DIR"
+
+// EnvAppSymlinks controls how symlinks in the app directory that point outside of it are handled,
+// identically by the detector, the builder and the exporter (including slices): "preserve" keeps them as-is
+// (they may dangle in the application image), "follow" replaces them with copies of their targets, and "error" fails the phase.
+const (
+	EnvAppSymlinks     = "CNB_APP_SYMLINKS"
+	DefaultAppSymlinks = AppSymlinksPreserve
+
+	AppSymlinksError    = "error"
+	AppSymlinksFollow   = "follow"
+	AppSymlinksPreserve = "preserve"
+)
+
+// EnvAppsPath is the location of apps.toml, which configures the creator to build each of the apps it declares
+// (directories within the app directory) and export them to separate images, e.g., for the services of a monorepo.
+// The layers of each app are in <layers>/apps/<name>. The apps share the cache (directory or image), in which a layer cached by several apps is stored once,
+// and the run image, which is verified and fetched once; each app is analyzed, built and exported on its own.
+const EnvAppsPath = "CNB_APPS_PATH"
+
+// EnvExplainEnv configures the detector and the builder to log the environment that each buildpack runs with,
+// with the env files (and layer directories) that contributed to each variable, to debug the environment composed from buildpack layers.
+const EnvExplainEnv = "CNB_EXPLAIN_ENV"
+
+// EnvNetworkBuildpacks is a comma-separated list of the IDs of the buildpacks that may access the network during build
+// (e.g., only the buildpack fetching dependencies). When provided, the builder denies the requests of the other buildpacks,
+// with a proxy configured through the standard proxy environment variables, and records them as violations in metadata.toml.
+// If not provided, all buildpacks may access the network.
+const EnvNetworkBuildpacks = "CNB_NETWORK_BUILDPACKS"
+
+// EnvConstraintsPath is the location of a constraints file overriding the versions of the dependencies requested by buildpacks
+// (e.g., to pin a runtime fleet-wide). The detector and the builder set the version of each plan entry requiring a constrained dependency,
+// marking it as enforced by the platform. If not provided, the plan is not changed.
+const EnvConstraintsPath = "CNB_CONSTRAINTS_PATH"
+
+// EnvTrustPolicyPath is the location of a trust policy file listing the public keys that base images (i.e., the build and run images)
+// must be signed by, with cosign. When provided, the analyzer, the restorer, the exporter and the rebaser refuse base images
+// that are unsigned or not signed by a trusted key, and pull verified images by digest. It is only supported for images in a registry.
+const EnvTrustPolicyPath = "CNB_TRUST_POLICY_PATH"
+
+// The following normalize the ownership and permissions of the files that buildpacks see,
+// so that platforms do not have to prepare the app directory and volumes (e.g., with a chown init container).
+const (
+	// EnvNormalizeOwnership when true instructs the lifecycle (while it has privileges) to recursively chown
+	// the app directory and the layers directory to the CNB user and group.
+	EnvNormalizeOwnership = "CNB_NORMALIZE_OWNERSHIP"
+	// EnvStripSetuid when true instructs the lifecycle to remove the setuid and setgid bits
+	// from the files in the app directory and the layers directory.
+	EnvStripSetuid = "CNB_STRIP_SETUID"
+	// EnvUmask is the umask (in octal, e.g., 022) of the builder and the creator, which applies to the files created by buildpacks.
+	EnvUmask = "CNB_UMASK"
+)
+
+// EnvProjectDescriptorPath is the location of the project descriptor, typically <app>/project.toml.
+// When provided, the detector applies the buildpack group in the descriptor, the env vars declared in the descriptor
+// are provided to buildpacks during detect and build, and the exporter adds only the app files selected by
+// the include and exclude filters in the descriptor to the app layers (the app directory is not modified).
+const EnvProjectDescriptorPath = "CNB_PROJECT_DESCRIPTOR_PATH"
+
 // The following are the default locations of input directories if not specified.
 var (
 	DefaultAppDir      = filepath.Join(path.RootDir, "workspace")
@@ -148,28 +220,79 @@ const (
 
 	// EnvGeneratedDir is the location of the directory where the lifecycle should copy any Dockerfiles
 	// output by image extensions during the `generate` phase.
+	// SBOM files output by image extensions are copied to the `sbom` subdirectory, and copied from there to <layers>/sbom by the builder.
 	EnvGeneratedDir     = "CNB_GENERATED_DIR"
 	DefaultGeneratedDir = "generated"
 
+	// EnvGenerateDryRunDir is the location of a directory where the detector should write the Dockerfiles rendered
+	// by image extensions, along with the args for each Dockerfile (in args.toml), instead of continuing the build.
+	// When provided, no group, plan, or analyzed metadata is written, so that extension authors can iterate
+	// on their output without running a full build.
+	EnvGenerateDryRunDir = "CNB_GENERATE_DRY_RUN_DIR"
+
 	// EnvExtendedDir is the location of the directory where the lifecycle should copy any image layers
 	// created from applying generated Dockerfiles to a build- or run-time base image.
 	EnvExtendedDir     = "CNB_EXTENDED_DIR"
 	DefaultExtendedDir = "extended"
 
+	// EnvFailurePath is the location of the failure file, written by any phase that fails.
+	// It contains the exit code, the error class (e.g., "user", "platform", or "infra"), the failing component,
+	// and a remediation hint, so that platforms can categorize failures without parsing the lifecycle output.
+	EnvFailurePath     = "CNB_FAILURE_PATH"
+	DefaultFailureFile = "failure.toml"
+
+	// EnvArtifactsDir is the directory where each phase copies the files it generated (e.g., group.toml, analyzed.toml,
+	// report.toml or the Dockerfiles of image extensions), prefixed with the name of the phase,
+	// so that builds can be debugged after the fact. If not provided, no files are copied.
+	EnvArtifactsDir = "CNB_ARTIFACTS_DIR"
+
+	// EnvSupportBundlePath is the location of a gzipped tarball written by any phase that fails, for attaching to bug reports.
+	// It contains the versions of the lifecycle and its APIs, the environment, the files provided to and generated by the phase,
+	// the registries it accessed, and the end of its logs, with secret values redacted. If not provided, no support bundle is written.
+	EnvSupportBundlePath = "CNB_SUPPORT_BUNDLE_PATH"
+
+	// DefaultDeprecationsFile is the name of the file in the layers directory where each phase records the deprecated APIs
+	// and file formats it encountered, so that they can be included in the report file.
+	DefaultDeprecationsFile = "deprecations.toml"
+
+	// DefaultRedactionsFile is the name of the file in the layers directory where each phase records the number of
+	// secret values redacted from the output of each buildpack, so that they can be included in the report file.
+	DefaultRedactionsFile = "redactions.toml"
+
+	// DefaultUsageFile is the name of the file in the layers directory where each phase records the resources
+	// (peak memory, CPU time and disk) used by the phase and its buildpacks, so that they can be included in the report file.
+	DefaultUsageFile = "usage.toml"
+
+	// DefaultPreviousSBOMFile is the name of the file in the layers directory where the merged launch SBOM of the previous image
+	// is saved, so that the exporter can report the components that were added, removed or upgraded.
+	// It is written by the SBOM restorer of the `analyze` phase when it retrieves the SBOM layer of the previous image
+	// (Platform API 0.8+, unless layers are skipped); the `restore` phase only restores SBOM files from the cache.
+	DefaultPreviousSBOMFile = "previous-sbom.cdx.json"
+
 	// EnvReportPath is the location of the report file, an output of the `export` phase.
 	// It contains information about the output application image.
 	EnvReportPath     = "CNB_REPORT_PATH"
 	DefaultReportFile = "report.toml"
+
+	// EnvTmpDir is the location of the directory used for temporary files and intermediate artifacts by all lifecycle phases,
+	// e.g., when the default temporary directory is a small tmpfs. If not provided, the system default is used.
+	EnvTmpDir = "CNB_TMP_DIR"
 )
 
 // The following are configuration options with respect to caching.
 const (
 	// EnvCacheDir is the location of the cache directory. Only one of cache directory or cache image may be used.
 	// The cache is used to store buildpack-generated layers that are needed at build-time for future builds.
+	// When provided to the `extend` phase, layers created by extension Dockerfiles are also cached here.
 	EnvCacheDir = "CNB_CACHE_DIR"
 
+	// EnvCacheLockTimeout is how long to wait for another build to release the lock of the cache directory (or launch cache),
+	// when concurrent builds share a cache volume.
+	EnvCacheLockTimeout = "CNB_CACHE_LOCK_TIMEOUT"
+
 	// EnvCacheImage is a reference to the cache image in an OCI registry. Only one of cache directory or cache image may be used.
 	// The cache is used to store buildpack-generated layers that are needed at build-time for future builds.
+	// Layers created by extension Dockerfiles are not cached in the cache image (see EnvExtendCacheImage).
 	// Cache images in a daemon are disallowed (for performance reasons).
 	EnvCacheImage = "CNB_CACHE_IMAGE"
 
@@ -177,6 +300,11 @@ const (
 	// The launch cache is used when exporting to a daemon to store buildpack-generated layers, in order to speed up data retrieval for future builds.
 	EnvLaunchCacheDir = "CNB_LAUNCH_CACHE_DIR"
 
+	// EnvLaunchCacheImage is a registry image (e.g., the previous image, as published by the platform) used as the launch cache
+	// when exporting to a daemon without a launch cache directory, e.g., on ephemeral CI runners.
+	// The layers reused by the exporter are fetched lazily from the image, by diff ID.
+	EnvLaunchCacheImage = "CNB_LAUNCH_CACHE_IMAGE"
+
 	// EnvSkipLayers when true will instruct the lifecycle to ignore layers from a previously built image.
 	EnvSkipLayers = "CNB_SKIP_LAYERS"
 
@@ -184,13 +312,64 @@ const (
 	// the restorer in the 5-phase invocation.
 	EnvSkipRestore = "CNB_SKIP_RESTORE"
 
+	// EnvSkipAnalyze, EnvSkipDetect and EnvSkipBuild are used when running the creator, to skip the corresponding phase
+	// and instead read its output (analyzed.toml, group.toml and plan.toml, or the layers directory) from a previous invocation of the phase.
+	EnvSkipAnalyze = "CNB_SKIP_ANALYZE"
+	EnvSkipDetect  = "CNB_SKIP_DETECT"
+	EnvSkipBuild   = "CNB_SKIP_BUILD"
+
 	// EnvKanikoCacheTTL is the amount of time to persist layers cached by kaniko during the `extend` phase.
 	EnvKanikoCacheTTL = "CNB_KANIKO_CACHE_TTL"
+
+	// EnvKanikoDir is the location of the directory where the restorer saves the base images to extend,
+	// and where the extender caches layers and keeps its working files.
+	// It must be writable, e.g., a volume when the root filesystem is read-only.
+	EnvKanikoDir = "CNB_KANIKO_DIR"
 )
 
+// The following are configuration options for the `extend` phase.
+const (
+	// EnvExtendBackend is the tool used to apply Dockerfiles during the `extend` phase (kaniko or buildkit).
+	// When using BuildKit, the `buildctl` binary must be available in the build environment and `BUILDKIT_HOST`
+	// must point to a running BuildKit daemon. Registry credentials for the base image (e.g., from `CNB_REGISTRY_AUTH`)
+	// are passed to `buildctl` in a temporary Docker config, which replaces any `DOCKER_CONFIG` in the environment.
+	EnvExtendBackend     = "CNB_EXTEND_BACKEND"
+	DefaultExtendBackend = ExtendBackendKaniko
+
+	ExtendBackendKaniko   = "kaniko"
+	ExtendBackendBuildKit = "buildkit"
+
+	// EnvExtendCacheImage is a registry image used to share layers cached during the `extend` phase between builders.
+	// Only layers created by extension Dockerfiles are shared, not the base images to extend.
+	// The layer cache directory is primed from the image before extending (taking precedence over the cache directory),
+	// and the image is updated afterwards if the cached layers changed.
+	EnvExtendCacheImage = "CNB_EXTEND_CACHE_IMAGE"
+
+	// EnvExtendParallel configures the extender to extend the run image concurrently with the build image
+	// when extending the build image, so that a separate `extend` invocation for the run image is not needed.
+	// Only supported by the BuildKit backend, as kaniko modifies the filesystem of the running container.
+	EnvExtendParallel = "CNB_EXTEND_PARALLEL"
+
+	// EnvExtendRootless configures the extender to run without root privileges on the host.
+	// Only supported by the BuildKit backend, which must point to a rootless BuildKit daemon.
+	// Extending the build image additionally requires running as uid 0 in a user namespace (e.g., `unshare --user --map-root-user`)
+	// so that new layers can be unpacked; unpacked files are owned by the current user.
+	EnvExtendRootless = "CNB_EXTEND_ROOTLESS"
+
+	// EnvExtendSecretsDir is the location of a directory containing secrets to expose to extension Dockerfiles
+	// (via `RUN --mount=type=secret,id=<filename>`). Secrets are only supported by the BuildKit backend.
+	EnvExtendSecretsDir = "CNB_EXTEND_SECRETS_DIR"
+)
+
+// DefaultCacheLockTimeout is the default timeout to acquire the lock of a cache directory.
+var DefaultCacheLockTimeout = 10 * time.Minute
+
 // DefaultKanikoCacheTTL is the default kaniko cache TTL (2 weeks).
 var DefaultKanikoCacheTTL = 14 * (24 * time.Hour)
 
+// DefaultKanikoDir is the default location of the kaniko directory.
+var DefaultKanikoDir = filepath.Join(path.RootDir, "kaniko")
+
 // The following are images used by the lifecycle during the build.
 const (
 	// EnvPreviousImage is a reference to a previously built image; if not provided, it defaults to the output image reference.
@@ -203,6 +382,12 @@ const (
 
 	// EnvBuildImage is a reference to the build-time base image. It is needed when image extensions are used to extend the build-time base image.
 	EnvBuildImage = "CNB_BUILD_IMAGE"
+
+	// EnvAnonymousFallback configures the lifecycle to pull images (e.g., the run image) anonymously from registries
+	// that reject the provided credentials, instead of failing, which allows public images to be used when the credentials are invalid.
+	// The registries that fell back to anonymous access are reported in the logs and recorded in analyzed.toml,
+	// and the restorer, extender and exporter access them anonymously too.
+	EnvAnonymousFallback = "CNB_ANONYMOUS_FALLBACK"
 )
 
 // The following are configuration options for the output application image.
@@ -210,6 +395,124 @@ const (
 	// EnvProcessType is the default process for the application image, the entrypoint in the output image config.
 	EnvProcessType = "CNB_PROCESS_TYPE"
 
+	// EnvProcessTypeFallback is a comma-separated list of process types, in order of preference, to use as the default process
+	// when neither the platform (CNB_PROCESS_TYPE) nor a buildpack specifies one, e.g., "web,worker,*".
+	// The special value "*" selects the first process type (in alphabetical order).
+	EnvProcessTypeFallback = "CNB_PROCESS_TYPE_FALLBACK"
+	ProcessTypeFallbackAny = "*"
+
+	// EnvMergedSBOMPath is the location where the exporter should write a single document merging the SBOM files
+	// for all buildpack-provided layers, so that scanners don't need to understand the layout of the SBOM layer.
+	// The document is SPDX if the path ends in .spdx.json, or CycloneDX otherwise, regardless of the formats written by buildpacks.
+	// If not provided, no merged SBOM is written.
+	EnvMergedSBOMPath = "CNB_MERGED_SBOM_PATH"
+
+	// EnvSBOMValidation controls how the builder handles SBOM files written by buildpacks that do not have the structure
+	// of CycloneDX, SPDX or syft JSON documents (required fields and the types of the fields identifying packages;
+	// this is not a complete schema validation): "warn" logs the problems, "fail" fails the build, and "off" skips the checks.
+	EnvSBOMValidation     = "CNB_SBOM_VALIDATION"
+	DefaultSBOMValidation = SBOMValidationWarn
+
+	SBOMValidationFail = "fail"
+	SBOMValidationOff  = "off"
+	SBOMValidationWarn = "warn"
+
+	// EnvSBOMCompression controls how the exporter stores the SBOM files in the application image:
+	// "gzip" compresses each file (as sbom.<ext>.gz), and "none" stores them as-is.
+	EnvSBOMCompression     = "CNB_SBOM_COMPRESSION"
+	DefaultSBOMCompression = SBOMCompressionNone
+
+	SBOMCompressionGzip = "gzip"
+	SBOMCompressionNone = "none"
+
+	// EnvLayerCompression controls the gzip compression level of the layers the exporter adds to images exported
+	// to a registry or a layout: "auto" chooses the level of each layer from the entropy of its contents,
+	// only entropy coding already-compressed content (e.g., jars or wheels) and compressing text-heavy content harder,
+	// while "0" to "9" use a fixed level for all layers.
+	EnvLayerCompression     = "CNB_LAYER_COMPRESSION"
+	DefaultLayerCompression = LayerCompressionAuto
+
+	LayerCompressionAuto = "auto"
+
+	// EnvDigestAlgorithm is the algorithm of the digests that identify the layers the exporter only adds to a cache directory
+	// (launch=false layers and the cached SBOM). Exported layers, and the layers of a cache image, are always identified
+	// by sha256 digests, as their digests are the diffIDs of an image.
+	EnvDigestAlgorithm     = "CNB_DIGEST_ALGORITHM"
+	DefaultDigestAlgorithm = "sha256"
+
+	// EnvSBOMPolicyPath is the location of a policy file restricting the licenses and packages in the application image.
+	// The exporter evaluates the merged SBOM of the image against the policy, reporting violations in the report file,
+	// and failing the export if the policy is enforced. If not provided, no policy is evaluated.
+	EnvSBOMPolicyPath = "CNB_SBOM_POLICY_PATH"
+
+	// EnvPruneLaunchSBOM configures the exporter to remove build-only entries (e.g., build tools and test dependencies)
+	// and build-time metadata (e.g., the tools that generated each SBOM and the launcher source) from the application image.
+	// The unpruned launch SBOM files are kept in <layers>/sbom/launch-unpruned for the platform to save off.
+	EnvPruneLaunchSBOM = "CNB_PRUNE_LAUNCH_SBOM"
+
+	// EnvDedupeSBOM configures the exporter to store identical SBOM files in the application image once,
+	// replacing the duplicates with symlinks. Tools that read the SBOM layer must follow the symlinks.
+	EnvDedupeSBOM = "CNB_DEDUPE_SBOM"
+
+	// EnvCreateWorkingDirs configures the exporter to create the working directories of processes that do not exist
+	// in the app directory or in a launch layer. By default, such processes fail the export.
+	EnvCreateWorkingDirs = "CNB_CREATE_WORKING_DIRS"
+
+	// EnvSquashLayersBelow configures the exporter to squash the launch layers whose contents are smaller than the provided size (in bytes)
+	// into a single layer, e.g., for registries or runtimes that limit the number of layers. By default, no layers are squashed.
+	EnvSquashLayersBelow = "CNB_SQUASH_LAYERS_BELOW"
+
+	// EnvSquashBuildpacks is a comma-separated list of the IDs of the buildpacks whose launch layers the exporter squashes
+	// into a single layer, along with the layers selected by CNB_SQUASH_LAYERS_BELOW (if provided).
+	EnvSquashBuildpacks = "CNB_SQUASH_BUILDPACKS"
+
+	// EnvDebugImage is a reference to a debug image that the exporter saves alongside the application image (in the same way, i.e., to a registry,
+	// the daemon or a layout), containing the build-only layers, the build log (if CNB_BUILD_LOG_PATH is provided) and the group, plan and analyzed files,
+	// so that the application image stays slim while the debugging artifacts remain retrievable. If not provided, no debug image is saved.
+	EnvDebugImage = "CNB_DEBUG_IMAGE"
+
+	// EnvBuildLogPath is the location where the builder writes the output of the buildpacks (in addition to the output of the lifecycle),
+	// e.g., to add it to the debug image. If not provided, no build log is written.
+	EnvBuildLogPath = "CNB_BUILD_LOG_PATH"
+
+	// EnvNoDigestCache disables the digest cache: by default, the restorer records the digests of the layers it restores,
+	// keyed by the metadata of their files, so that the exporter does not hash unchanged layers again.
+	// Platforms that do not trust file metadata to detect changes should disable it.
+	EnvNoDigestCache = "CNB_NO_DIGEST_CACHE"
+
+	// EnvLenientExtraction configures the restorer to skip the entries of cached layers that would be written outside of the layers directory
+	// (e.g., through path traversal, symlinks, or hard links), reporting them as warnings. By default, such entries fail the restore,
+	// as caches may come from registries that are less trusted than the builder.
+	EnvLenientExtraction = "CNB_LENIENT_EXTRACTION"
+
+	// EnvPreserveXattrs is a comma-separated list of prefixes of the names of the extended attributes that are preserved
+	// in the layers of the application image and the cache, and restored from the cache (e.g., "security.capability,user.").
+	// By default, only file capabilities are preserved, so that binaries that need them (e.g., to bind to privileged ports) keep working.
+	// Set it to "none" to preserve no extended attributes.
+	EnvPreserveXattrs     = "CNB_PRESERVE_XATTRS"
+	DefaultPreserveXattrs = "security.capability"
+
+	// EnvAttachAttestations configures the exporter to attach the merged SBOM and the build metadata to the application image
+	// as in-toto attestations, pushed as OCI 1.1 referrer artifacts so that tools such as cosign and oras can discover them.
+	// It only applies when exporting to a registry, and is in addition to the SBOM layer.
+	EnvAttachAttestations = "CNB_ATTACH_ATTESTATIONS"
+
+	// EnvScanner is a vulnerability scanner that the exporter runs with the merged SBOM of the saved image:
+	// either an executable, which is invoked with the SBOM path and the image (a digest reference, or an image ID) as arguments,
+	// or an http(s) endpoint, which is sent the image and the SBOM in a POST request.
+	// The scanner returns a JSON verdict ("pass", "warn" or "fail") and summary that is recorded in the report file.
+	// If not provided, no scan is performed.
+	EnvScanner = "CNB_SCANNER"
+	// EnvScannerEnforce fails the export if the scanner cannot be run (or does not complete within 5 minutes) or returns a failing verdict.
+	// The image is scanned by digest after it is saved, so a failed scan leaves the image published under all of its tags:
+	// platforms must rely on the exit code (and the report file) rather than the presence of the image to gate deployments.
+	EnvScannerEnforce = "CNB_SCANNER_ENFORCE"
+
+	// EnvSourceSBOMPath is the location of a pre-generated SBOM describing the application source (e.g., from scanning
+	// the source repository), which the exporter adds to the SBOM layer for the application and merges with the buildpack-provided SBOM files.
+	// The format is determined by the extension: .cdx.json, .spdx.json or .syft.json. If not provided, no source SBOM is added.
+	EnvSourceSBOMPath = "CNB_SOURCE_SBOM_PATH"
+
 	// EnvProjectMetadataPath is the location of the project metadata file. It contains information about the source repository
 	// that is added as metadata to the application image.
 	EnvProjectMetadataPath     = "CNB_PROJECT_METADATA_PATH"
@@ -220,6 +523,30 @@ const (
 const (
 	// EnvForceRebase is used to force the rebaser to rebase the app image even if the operation is unsafe.
 	EnvForceRebase = "CNB_FORCE_REBASE"
+
+	// EnvRebaseDryRun configures the rebaser to rebase the app image without saving it,
+	// reporting the digest and logging the manifest the rebased image would have.
+	EnvRebaseDryRun = "CNB_REBASE_DRY_RUN"
+
+	// EnvRebaseSnapshot configures the rebaser to tag the app image as it was before the rebase
+	// with pre-rebase-<timestamp> in its repository, so that operators can roll back if the new run image misbehaves.
+	// It is only supported for images in a registry.
+	EnvRebaseSnapshot = "CNB_REBASE_SNAPSHOT"
+
+	// EnvRebaseImagesPath is the location of a file listing app images to rebase onto the same run image (one per line),
+	// e.g., to respond to a CVE in the run image across many applications. Each image is saved under its own reference.
+	// "-" reads the list from standard input.
+	EnvRebaseImagesPath = "CNB_REBASE_IMAGES_PATH"
+
+	// EnvRebaseParallelism is the maximum number of images rebased at a time when rebasing many images.
+	EnvRebaseParallelism     = "CNB_REBASE_PARALLELISM"
+	DefaultRebaseParallelism = 4
+)
+
+// The following are configuration options for cleaning the layers directory.
+const (
+	// EnvCleanDryRun configures clean-layers to report the stale content of the layers directory without removing it.
+	EnvCleanDryRun = "CNB_CLEAN_DRY_RUN"
 )
 
 var (
@@ -1,5 +1,7 @@
 package platform
 
+import "github.com/buildpacks/lifecycle/cmd"
+
 type LifecycleExitError int
 
 const (
@@ -24,6 +26,15 @@ const (
 
 type Exiter interface {
 	CodeFor(errType LifecycleExitError) int
+	ErrorClassFor(code int) string
+}
+
+// errorClasses are the error classes for phase-specific errors that aren't generic failures of the lifecycle.
+var errorClasses = map[LifecycleExitError]string{
+	FailedDetect:             cmd.ErrorClassUser,
+	FailedDetectWithErrors:   cmd.ErrorClassBuildpack,
+	FailedBuildWithErrors:    cmd.ErrorClassBuildpack,
+	FailedGenerateWithErrors: cmd.ErrorClassBuildpack,
 }
 
 func NewExiter(platformAPI string) Exiter {
@@ -74,6 +85,10 @@ func (e *DefaultExiter) CodeFor(errType LifecycleExitError) int {
 	return codeFor(errType, defaultExitCodes)
 }
 
+func (e *DefaultExiter) ErrorClassFor(code int) string {
+	return errorClassFor(code, defaultExitCodes)
+}
+
 type LegacyExiter struct{}
 
 var legacyExitCodes = map[LifecycleExitError]int{
@@ -113,9 +128,26 @@ func (e *LegacyExiter) CodeFor(errType LifecycleExitError) int {
 	return codeFor(errType, legacyExitCodes)
 }
 
+func (e *LegacyExiter) ErrorClassFor(code int) string {
+	return errorClassFor(code, legacyExitCodes)
+}
+
 func codeFor(errType LifecycleExitError, exitCodes map[LifecycleExitError]int) int {
 	if code, ok := exitCodes[errType]; ok {
 		return code
 	}
 	return CodeForFailed
 }
+
+// errorClassFor returns the error class for the provided exit code, or an empty string if the exit code is not specific to a phase.
+func errorClassFor(code int, exitCodes map[LifecycleExitError]int) string {
+	if code == CodeForFailed {
+		return ""
+	}
+	for errType, c := range exitCodes {
+		if c == code {
+			return errorClasses[errType]
+		}
+	}
+	return ""
+}
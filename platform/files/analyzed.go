@@ -7,6 +7,7 @@ import (
 	"github.com/BurntSushi/toml"
 
 	"github.com/buildpacks/lifecycle/buildpack"
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/log"
 )
 
@@ -22,6 +23,9 @@ type Analyzed struct {
 	// It is recorded for use by the restorer in the case that image extensions are used
 	// to extend the build image.
 	BuildImage *ImageIdentifier `toml:"build-image,omitempty"`
+	// BuildTarget is the target of the build image that the analyzer ran in, including its distribution (from /etc/os-release),
+	// so that builds on a build image with a different distribution than the run image can be identified.
+	BuildTarget *TargetMetadata `toml:"build-target,omitempty"`
 	// LayersMetadata holds information about previously built layers.
 	// It is used by the exporter to determine if any layers from the current build are unchanged,
 	// to avoid re-uploading the same data to the export target,
@@ -31,6 +35,10 @@ type Analyzed struct {
 	// It is used to validate that buildpacks satisfy os/arch constraints,
 	// and to provide information about the export target to buildpacks.
 	RunImage *RunImage `toml:"run-image,omitempty"`
+	// AnonymousRegistries holds the registries that rejected the provided credentials
+	// and fell back to anonymous access (see CNB_ANONYMOUS_FALLBACK).
+	// It is used by later phases to access these registries anonymously too.
+	AnonymousRegistries []string `toml:"anonymous-registries,omitempty"`
 }
 
 func ReadAnalyzed(path string, logger log.Logger) (Analyzed, error) {
@@ -40,11 +48,28 @@ func ReadAnalyzed(path string, logger log.Logger) (Analyzed, error) {
 			logger.Warnf("no analyzed metadata found at path '%s'", path)
 			return Analyzed{}, nil
 		}
-		return Analyzed{}, err
+		return Analyzed{}, lerrors.NewDecodeError(path, err)
+	}
+	if analyzed.RunImage != nil {
+		analyzed.RunImage.translateStack()
 	}
 	return analyzed, nil
 }
 
+// translateStack fills in the target of the run image from its stack, or the stack from its target,
+// when analyzed.toml was written with only one of them.
+func (r *RunImage) translateStack() {
+	if r.TargetMetadata == nil {
+		if target, ok := TargetForStackID(r.StackID); ok {
+			r.TargetMetadata = &target
+		}
+		return
+	}
+	if r.StackID == "" {
+		r.StackID = StackIDForTarget(*r.TargetMetadata)
+	}
+}
+
 func (a Analyzed) PreviousImageRef() string {
 	if a.PreviousImage == nil {
 		return ""
@@ -123,6 +148,29 @@ type RunImageForRebase struct {
 	TopLayer  string `json:"topLayer" toml:"top-layer"`
 	Reference string `json:"reference" toml:"reference"`
 	RunImageForExport
+	// ExtensionLayers are the layers added to the run image by image extensions, bottom first, if the run image was extended.
+	// They are above TopLayer in the app image, and were built against the original run image.
+	ExtensionLayers []ExtensionLayer `json:"extensionLayers,omitempty" toml:"extension-layers,omitempty"`
+}
+
+// ExtensionLayer is a layer added to the run image by an image extension.
+type ExtensionLayer struct {
+	ExtensionID string `json:"extensionID" toml:"extension-id"`
+	DiffID      string `json:"diffID" toml:"diff-id"`
+}
+
+// ExtensionIDs returns the IDs of the extensions that extended the run image, in order and without duplicates.
+func (r *RunImageForRebase) ExtensionIDs() []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, layer := range r.ExtensionLayers {
+		if seen[layer.ExtensionID] {
+			continue
+		}
+		seen[layer.ExtensionID] = true
+		ids = append(ids, layer.ExtensionID)
+	}
+	return ids
 }
 
 // Contains returns true if the provided image reference is found in the existing metadata,
@@ -149,6 +197,21 @@ type RunImage struct {
 	// Extend if true indicates that the run image should be extended by the extender.
 	Extend         bool            `toml:"extend,omitempty"`
 	TargetMetadata *TargetMetadata `json:"target,omitempty" toml:"target,omitempty"`
+	// StackID (deprecated as of Platform API 0.12) is the stack of the run image, from its label or translated from its target.
+	// It is recorded alongside the target so that platforms and lifecycles that predate targets can read analyzed.toml.
+	StackID string `json:"stack-id,omitempty" toml:"stack-id,omitempty"`
+	// Selection records which run image in run.toml was selected by the lifecycle, and why.
+	// It is empty when the platform provided the run image.
+	Selection *RunImageSelection `json:"selection,omitempty" toml:"selection,omitempty"`
+}
+
+// RunImageSelection describes how a run image was selected from run.toml.
+type RunImageSelection struct {
+	// Index is the position of the selected image in run.toml.
+	Index  int    `json:"index" toml:"index"`
+	Image  string `json:"image" toml:"image"`
+	Policy string `json:"policy" toml:"policy"`
+	Reason string `json:"reason" toml:"reason"`
 }
 
 type TargetMetadata struct {
@@ -94,5 +94,36 @@ func testAnalyzed(t *testing.T, when spec.G, it spec.S) {
 				h.AssertEq(t, amd.BuildImage, amd2.BuildImage)
 			})
 		})
+
+		when("the run image has a stack or a target", func() {
+			it("translates the target from the stack", func() {
+				f := h.TempFile(t, "", "")
+				h.AssertNil(t, encoding.WriteTOML(f, files.Analyzed{RunImage: &files.RunImage{StackID: "io.buildpacks.stacks.bionic"}}))
+
+				amd, err := files.ReadAnalyzed(f, nil)
+				h.AssertNil(t, err)
+				h.AssertEq(t, amd.RunImageTarget(), files.TargetMetadata{OS: "linux", Arch: "amd64", Distribution: &files.OSDistribution{Name: "ubuntu", Version: "18.04"}})
+			})
+
+			it("translates the stack from the target", func() {
+				f := h.TempFile(t, "", "")
+				h.AssertNil(t, encoding.WriteTOML(f, files.Analyzed{RunImage: &files.RunImage{
+					TargetMetadata: &files.TargetMetadata{OS: "linux", Arch: "amd64", Distribution: &files.OSDistribution{Name: "ubuntu", Version: "22.04"}},
+				}}))
+
+				amd, err := files.ReadAnalyzed(f, nil)
+				h.AssertNil(t, err)
+				h.AssertEq(t, amd.RunImage.StackID, "io.buildpacks.stacks.jammy")
+			})
+
+			it("does not translate unknown stacks", func() {
+				f := h.TempFile(t, "", "")
+				h.AssertNil(t, encoding.WriteTOML(f, files.Analyzed{RunImage: &files.RunImage{StackID: "some-stack"}}))
+
+				amd, err := files.ReadAnalyzed(f, nil)
+				h.AssertNil(t, err)
+				h.AssertNil(t, amd.RunImage.TargetMetadata)
+			})
+		})
 	})
 }
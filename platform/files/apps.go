@@ -0,0 +1,69 @@
+package files
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	lerrors "github.com/buildpacks/lifecycle/errors"
+)
+
+// Apps is provided by the platform as apps.toml to build several apps (e.g., the services of a monorepo)
+// in a single invocation of the creator.
+// The location of the file can be specified by providing `-apps <path>` to the creator.
+type Apps struct {
+	Apps []App `toml:"apps"`
+}
+
+// App is an app in apps.toml. Each app is built from its own directory and exported to its own image.
+type App struct {
+	// Name identifies the app, e.g., in the layers and cache directories. It defaults to the base name of Path.
+	Name string `toml:"name,omitempty"`
+	// Path is the directory of the app, relative to the app directory.
+	Path string `toml:"path"`
+	// Image is the output image of the app.
+	Image string `toml:"image"`
+	// Tags are additional tags for the output image.
+	Tags []string `toml:"tags,omitempty"`
+	// PreviousImage is the image to reuse layers from. It defaults to Image.
+	PreviousImage string `toml:"previous-image,omitempty"`
+}
+
+var appName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// ReadApps reads apps.toml, defaulting the names of the apps and ensuring that they are valid.
+func ReadApps(appsPath string) (Apps, error) {
+	var apps Apps
+	if _, err := toml.DecodeFile(appsPath, &apps); err != nil {
+		return Apps{}, lerrors.NewDecodeError(appsPath, err)
+	}
+	if len(apps.Apps) == 0 {
+		return Apps{}, fmt.Errorf("no apps found in %s", appsPath)
+	}
+	names := map[string]bool{}
+	for i := range apps.Apps {
+		app := &apps.Apps[i]
+		if app.Path == "" || app.Image == "" {
+			return Apps{}, fmt.Errorf("app %d in %s must have a path and an image", i+1, appsPath)
+		}
+		cleaned := path.Clean(filepath.ToSlash(app.Path))
+		if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return Apps{}, fmt.Errorf("path '%s' of app %d must be within the app directory", app.Path, i+1)
+		}
+		if app.Name == "" {
+			app.Name = path.Base(cleaned)
+		}
+		if !appName.MatchString(app.Name) {
+			return Apps{}, fmt.Errorf("invalid app name '%s'; provide a name made of letters, digits, '.', '_' and '-'", app.Name)
+		}
+		if names[app.Name] {
+			return Apps{}, fmt.Errorf("duplicate app name '%s'", app.Name)
+		}
+		names[app.Name] = true
+	}
+	return apps, nil
+}
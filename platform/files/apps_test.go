@@ -0,0 +1,82 @@
+package files_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/platform/files"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestApps(t *testing.T) {
+	spec.Run(t, "Apps", testApps, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testApps(t *testing.T, when spec.G, it spec.S) {
+	var appsPath string
+
+	it.Before(func() {
+		appsPath = filepath.Join(t.TempDir(), "apps.toml")
+	})
+
+	writeApps := func(contents string) {
+		h.AssertNil(t, os.WriteFile(appsPath, []byte(contents), 0600))
+	}
+
+	when("#ReadApps", func() {
+		it("reads the apps and defaults their names", func() {
+			writeApps(`
+[[apps]]
+path = "services/api"
+image = "some-registry/api"
+tags = ["some-registry/api:v1"]
+
+[[apps]]
+name = "some-worker"
+path = "./services/worker/"
+image = "some-registry/worker"
+previous-image = "some-registry/worker:previous"
+`)
+			apps, err := files.ReadApps(appsPath)
+			h.AssertNil(t, err)
+			h.AssertEq(t, apps.Apps, []files.App{
+				{Name: "api", Path: "services/api", Image: "some-registry/api", Tags: []string{"some-registry/api:v1"}},
+				{Name: "some-worker", Path: "./services/worker/", Image: "some-registry/worker", PreviousImage: "some-registry/worker:previous"},
+			})
+		})
+
+		it("errors if there are no apps", func() {
+			writeApps("")
+			_, err := files.ReadApps(appsPath)
+			h.AssertError(t, err, "no apps found")
+		})
+
+		it("errors if an app has no image", func() {
+			writeApps("[[apps]]\npath = \"services/api\"\n")
+			_, err := files.ReadApps(appsPath)
+			h.AssertError(t, err, "app 1 in "+appsPath+" must have a path and an image")
+		})
+
+		it("errors if an app is outside of the app directory", func() {
+			writeApps("[[apps]]\npath = \"services/../../api\"\nimage = \"some-registry/api\"\n")
+			_, err := files.ReadApps(appsPath)
+			h.AssertError(t, err, "path 'services/../../api' of app 1 must be within the app directory")
+		})
+
+		it("errors if the name of an app cannot be defaulted", func() {
+			writeApps("[[apps]]\npath = \".\"\nimage = \"some-registry/api\"\n")
+			_, err := files.ReadApps(appsPath)
+			h.AssertError(t, err, "invalid app name '.'")
+		})
+
+		it("errors if apps have the same name", func() {
+			writeApps("[[apps]]\npath = \"api\"\nimage = \"some-registry/api\"\n\n[[apps]]\npath = \"other/api\"\nimage = \"some-registry/other-api\"\n")
+			_, err := files.ReadApps(appsPath)
+			h.AssertError(t, err, "duplicate app name 'api'")
+		})
+	})
+}
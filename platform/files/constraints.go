@@ -0,0 +1,106 @@
+package files
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	lerrors "github.com/buildpacks/lifecycle/errors"
+)
+
+const (
+	// VersionSourceKey is the key of the metadata of a plan entry recording where its version comes from.
+	VersionSourceKey = "version-source"
+	// VersionSourcePlatform marks the version of a plan entry as enforced by the platform (see Constraints).
+	VersionSourcePlatform = "platform"
+	// RequestedVersionKey is the key of the metadata of a plan entry recording the version requested by the buildpack,
+	// when it is overridden by the platform.
+	RequestedVersionKey = "requested-version"
+)
+
+// Constraints is provided by the platform to override the versions of the dependencies requested by buildpacks,
+// e.g., to pin a runtime to a major version fleet-wide.
+// The detector (and the builder) set the version of every plan entry that requires a constrained dependency,
+// marking the version as enforced by the platform.
+//
+//	[[constraints]]
+//	name = "node"
+//	version = "18.*"
+type Constraints struct {
+	Entries []Constraint `toml:"constraints"`
+}
+
+type Constraint struct {
+	Name    string `toml:"name"`
+	Version string `toml:"version"`
+}
+
+// ReadConstraints reads the constraints file at path.
+func ReadConstraints(path string) (Constraints, error) {
+	var constraints Constraints
+	if _, err := toml.DecodeFile(path, &constraints); err != nil {
+		return Constraints{}, fmt.Errorf("failed to read constraints: %w", lerrors.NewDecodeError(path, err))
+	}
+	seen := map[string]bool{}
+	for i, constraint := range constraints.Entries {
+		if constraint.Name == "" || constraint.Version == "" {
+			return Constraints{}, fmt.Errorf("invalid constraints: constraints[%d] must provide a name and a version", i)
+		}
+		if seen[constraint.Name] {
+			return Constraints{}, fmt.Errorf("invalid constraints: dependency '%s' is constrained more than once", constraint.Name)
+		}
+		seen[constraint.Name] = true
+	}
+	return constraints, nil
+}
+
+func (c Constraints) versionFor(name string) (string, bool) {
+	for _, constraint := range c.Entries {
+		if constraint.Name == name {
+			return constraint.Version, true
+		}
+	}
+	return "", false
+}
+
+// WithConstraints returns the plan with the version of each requirement of a constrained dependency set to the version of the constraint,
+// and marked as enforced by the platform. The version requested by the buildpack (if any, and different) is kept as the requested version.
+// Applying the same constraints again does not change the plan.
+func (p Plan) WithConstraints(constraints Constraints) Plan {
+	if len(constraints.Entries) == 0 {
+		return p
+	}
+	out := Plan{Entries: make([]BuildPlanEntry, 0, len(p.Entries))}
+	for _, entry := range p.Entries {
+		requires := make([]buildpack.Require, 0, len(entry.Requires))
+		for _, require := range entry.Requires {
+			if version, ok := constraints.versionFor(require.Name); ok {
+				require = constrain(require, version)
+			}
+			requires = append(requires, require)
+		}
+		entry.Requires = requires
+		out.Entries = append(out.Entries, entry)
+	}
+	return out
+}
+
+func constrain(require buildpack.Require, version string) buildpack.Require {
+	metadata := make(map[string]interface{}, len(require.Metadata)+2)
+	for k, v := range require.Metadata {
+		metadata[k] = v
+	}
+	requested, hasVersion := metadata["version"]
+	if require.Version != "" {
+		requested, hasVersion = require.Version, true
+	}
+	if hasVersion && fmt.Sprintf("%v", requested) != version && metadata[VersionSourceKey] != VersionSourcePlatform {
+		metadata[RequestedVersionKey] = requested
+	}
+	metadata["version"] = version
+	metadata[VersionSourceKey] = VersionSourcePlatform
+	require.Metadata = metadata
+	require.Version = ""
+	return require
+}
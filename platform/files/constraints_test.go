@@ -0,0 +1,100 @@
+package files_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/platform/files"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestConstraints(t *testing.T) {
+	spec.Run(t, "Constraints", testConstraints, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testConstraints(t *testing.T, when spec.G, it spec.S) {
+	when("#ReadConstraints", func() {
+		var constraintsPath string
+
+		it.Before(func() {
+			constraintsPath = filepath.Join(t.TempDir(), "constraints.toml")
+		})
+
+		writeConstraints := func(contents string) {
+			h.AssertNil(t, os.WriteFile(constraintsPath, []byte(contents), 0600))
+		}
+
+		it("reads the constraints", func() {
+			writeConstraints("[[constraints]]\nname = \"node\"\nversion = \"18.*\"\n")
+
+			constraints, err := files.ReadConstraints(constraintsPath)
+			h.AssertNil(t, err)
+			h.AssertEq(t, constraints.Entries, []files.Constraint{{Name: "node", Version: "18.*"}})
+		})
+
+		it("errors if a constraint has no version", func() {
+			writeConstraints("[[constraints]]\nname = \"node\"\n")
+
+			_, err := files.ReadConstraints(constraintsPath)
+			h.AssertError(t, err, "invalid constraints: constraints[0] must provide a name and a version")
+		})
+
+		it("errors if a dependency is constrained more than once", func() {
+			writeConstraints("[[constraints]]\nname = \"node\"\nversion = \"18.*\"\n\n[[constraints]]\nname = \"node\"\nversion = \"20.*\"\n")
+
+			_, err := files.ReadConstraints(constraintsPath)
+			h.AssertError(t, err, "invalid constraints: dependency 'node' is constrained more than once")
+		})
+	})
+
+	when("#WithConstraints", func() {
+		var (
+			plan        files.Plan
+			constraints files.Constraints
+		)
+
+		it.Before(func() {
+			plan = files.Plan{Entries: []files.BuildPlanEntry{
+				{
+					Providers: []buildpack.GroupElement{{ID: "some/bp", Version: "v1"}},
+					Requires: []buildpack.Require{
+						{Name: "node", Metadata: map[string]interface{}{"version": "16.x", "launch": true}},
+						{Name: "python"},
+						{Name: "other-dep", Metadata: map[string]interface{}{"version": "some-version"}},
+					},
+				},
+			}}
+			constraints = files.Constraints{Entries: []files.Constraint{{Name: "node", Version: "18.x"}, {Name: "python", Version: "3.11.*"}}}
+		})
+
+		it("overrides the requested versions and marks them as enforced by the platform", func() {
+			requires := plan.WithConstraints(constraints).Entries[0].Requires
+
+			h.AssertEq(t, requires[0].Metadata, map[string]interface{}{
+				"version":           "18.x",
+				"version-source":    "platform",
+				"requested-version": "16.x",
+				"launch":            true,
+			})
+			h.AssertEq(t, requires[1].Metadata, map[string]interface{}{"version": "3.11.*", "version-source": "platform"})
+			h.AssertEq(t, requires[2].Metadata, map[string]interface{}{"version": "some-version"})
+		})
+
+		it("does not modify the provided plan", func() {
+			plan.WithConstraints(constraints)
+
+			h.AssertEq(t, plan.Entries[0].Requires[0].Metadata, map[string]interface{}{"version": "16.x", "launch": true})
+		})
+
+		it("does not change a plan that is already constrained", func() {
+			constrained := plan.WithConstraints(constraints)
+
+			h.AssertEq(t, constrained.WithConstraints(constraints), constrained)
+		})
+	})
+}
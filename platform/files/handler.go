@@ -0,0 +1,220 @@
+package files
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/buildpack"
+	lerrors "github.com/buildpacks/lifecycle/errors"
+	"github.com/buildpacks/lifecycle/internal/encoding"
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// Handler reads and writes platform files in the representation used by the requested Platform API.
+// Platforms that embed the lifecycle should use a Handler instead of decoding platform files directly,
+// so that the files they read and write match the files read and written by the lifecycle binaries.
+type Handler struct {
+	PlatformAPI *api.Version
+	Logger      log.Logger
+}
+
+// NewHandler returns a Handler for the provided Platform API.
+func NewHandler(platformAPI *api.Version, logger log.Logger) *Handler {
+	return &Handler{PlatformAPI: platformAPI, Logger: logger}
+}
+
+// analyzed.toml
+
+// ReadAnalyzed reads analyzed.toml, returning empty metadata if the file does not exist.
+func (h *Handler) ReadAnalyzed(path string) (Analyzed, error) {
+	analyzed, err := ReadAnalyzed(path, h.Logger)
+	if err != nil {
+		return Analyzed{}, err
+	}
+	return analyzed.ForPlatformAPI(h.PlatformAPI), nil
+}
+
+// WriteAnalyzed writes analyzed.toml, omitting any keys not supported by the Platform API.
+func (h *Handler) WriteAnalyzed(path string, analyzed Analyzed) error {
+	return encoding.WriteTOML(path, analyzed.ForPlatformAPI(h.PlatformAPI))
+}
+
+// ForPlatformAPI returns a copy of the metadata without any keys that are not supported by the provided Platform API.
+func (a Analyzed) ForPlatformAPI(platformAPI *api.Version) Analyzed {
+	if platformAPI.LessThan("0.10") {
+		a.BuildImage = nil
+	}
+	if platformAPI.LessThan("0.12") && a.RunImage != nil {
+		a.RunImage = &RunImage{Reference: a.RunImage.Reference}
+	}
+	return a
+}
+
+// group.toml
+
+// ReadGroup reads group.toml.
+func (h *Handler) ReadGroup(path string) (buildpack.Group, error) {
+	return ReadGroup(path)
+}
+
+// WriteGroup writes group.toml.
+func (h *Handler) WriteGroup(path string, group buildpack.Group) error {
+	return encoding.WriteTOML(path, group)
+}
+
+// ReadGroup reads group.toml, marking image extensions as such.
+func ReadGroup(path string) (buildpack.Group, error) {
+	var group buildpack.Group
+	_, err := toml.DecodeFile(path, &group)
+	err = lerrors.NewDecodeError(path, err)
+	for e := range group.GroupExtensions {
+		group.GroupExtensions[e].Extension = true
+		group.GroupExtensions[e].Optional = true
+	}
+	return group, err
+}
+
+// order.toml
+
+// ReadOrder reads order.toml, returning the buildpack order and the image extension order.
+func (h *Handler) ReadOrder(path string) (buildpack.Order, buildpack.Order, error) {
+	return ReadOrder(path)
+}
+
+// ReadOrder reads order.toml, returning the buildpack order and the image extension order.
+func ReadOrder(path string) (buildpack.Order, buildpack.Order, error) {
+	var order struct {
+		Order           buildpack.Order `toml:"order"`
+		OrderExtensions buildpack.Order `toml:"order-extensions"`
+	}
+	_, err := toml.DecodeFile(path, &order)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read order file: %w", lerrors.NewDecodeError(path, err))
+	}
+	for g, group := range order.OrderExtensions {
+		for e := range group.Group {
+			group.Group[e].Extension = true
+			group.Group[e].Optional = true
+		}
+		order.OrderExtensions[g] = group
+	}
+	return order.Order, order.OrderExtensions, err
+}
+
+// plan.toml
+
+// ReadPlan reads plan.toml.
+func (h *Handler) ReadPlan(path string) (Plan, error) {
+	var plan Plan
+	if _, err := toml.DecodeFile(path, &plan); err != nil {
+		return Plan{}, lerrors.NewDecodeError(path, err)
+	}
+	return plan, nil
+}
+
+// WritePlan writes plan.toml.
+func (h *Handler) WritePlan(path string, plan Plan) error {
+	return encoding.WriteTOML(path, plan)
+}
+
+// metadata.toml
+
+// ReadBuildMetadata reads <layers>/config/metadata.toml.
+func (h *Handler) ReadBuildMetadata(path string) (BuildMetadata, error) {
+	var buildMD BuildMetadata
+	if err := DecodeBuildMetadata(path, h.PlatformAPI, &buildMD); err != nil {
+		return BuildMetadata{}, err
+	}
+	return buildMD, nil
+}
+
+// WriteBuildMetadata writes <layers>/config/metadata.toml.
+func (h *Handler) WriteBuildMetadata(path string, buildMD BuildMetadata) error {
+	buildMD.PlatformAPI = h.PlatformAPI
+	return encoding.WriteTOML(path, buildMD)
+}
+
+// project-metadata.toml
+
+// ReadProjectMetadata reads project-metadata.toml, returning empty metadata if the file does not exist.
+func (h *Handler) ReadProjectMetadata(path string) (ProjectMetadata, error) {
+	var projectMD ProjectMetadata
+	if _, err := toml.DecodeFile(path, &projectMD); err != nil {
+		if os.IsNotExist(err) {
+			h.Logger.Debugf("no project metadata found at path '%s', project metadata will not be exported", path)
+			return ProjectMetadata{}, nil
+		}
+		return ProjectMetadata{}, lerrors.NewDecodeError(path, err)
+	}
+	return projectMD, nil
+}
+
+// WriteProjectMetadata writes project-metadata.toml.
+func (h *Handler) WriteProjectMetadata(path string, projectMD ProjectMetadata) error {
+	return encoding.WriteTOML(path, projectMD)
+}
+
+// report.toml
+
+// ReadReport reads report.toml.
+func (h *Handler) ReadReport(path string) (Report, error) {
+	var report Report
+	if _, err := toml.DecodeFile(path, &report); err != nil {
+		return Report{}, lerrors.NewDecodeError(path, err)
+	}
+	return report, nil
+}
+
+// WriteReport writes report.toml, omitting the deprecated build BOM for Platform API 0.9 and above.
+func (h *Handler) WriteReport(path string, report Report) error {
+	if h.PlatformAPI.AtLeast("0.9") {
+		report.Build = BuildReport{}
+	}
+	return encoding.WriteTOML(path, report)
+}
+
+// run.toml and stack.toml
+
+// ReadRun reads the run images provided by the platform.
+// For Platform API 0.12 and above, the run images are read from run.toml at runPath;
+// for older Platform APIs, they are read from the (deprecated) stack.toml at stackPath.
+func (h *Handler) ReadRun(runPath, stackPath string) (Run, error) {
+	if h.PlatformAPI.LessThan("0.12") {
+		stackMD, err := ReadStack(stackPath, h.Logger)
+		if err != nil {
+			return Run{}, err
+		}
+		return stackMD.ToRun(), nil
+	}
+	return ReadRun(runPath, h.Logger)
+}
+
+// WriteRun writes the run images for use by the lifecycle,
+// to run.toml at runPath for Platform API 0.12 and above, or to stack.toml at stackPath for older Platform APIs.
+func (h *Handler) WriteRun(runPath, stackPath string, run Run) error {
+	if h.PlatformAPI.LessThan("0.12") {
+		return encoding.WriteTOML(stackPath, run.ToStack())
+	}
+	return encoding.WriteTOML(runPath, run)
+}
+
+// ToRun returns the run image in stack.toml as run.toml.
+func (s Stack) ToRun() Run {
+	if s.RunImage.Image == "" {
+		return Run{}
+	}
+	return Run{Images: []RunImageForExport{s.RunImage}}
+}
+
+// ToStack returns the first run image in run.toml as stack.toml.
+func (r Run) ToStack() Stack {
+	if len(r.Images) == 0 {
+		return Stack{}
+	}
+	runImage := r.Images[0]
+	runImage.Target = nil
+	return Stack{RunImage: runImage}
+}
@@ -0,0 +1,165 @@
+package files_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/buildpack"
+	lerrors "github.com/buildpacks/lifecycle/errors"
+	"github.com/buildpacks/lifecycle/platform/files"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestHandler(t *testing.T) {
+	spec.Run(t, "Handler", testHandler, spec.Report(report.Terminal{}))
+}
+
+func testHandler(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir string
+		logger *log.Logger
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle-files")
+		h.AssertNil(t, err)
+		logger = &log.Logger{Handler: memory.New()}
+	})
+
+	it.After(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	when("analyzed.toml", func() {
+		analyzed := files.Analyzed{
+			BuildImage: &files.ImageIdentifier{Reference: "some-build-image"},
+			RunImage: &files.RunImage{
+				Reference:      "some-run-image-ref",
+				Image:          "some-run-image",
+				TargetMetadata: &files.TargetMetadata{OS: "linux", Arch: "amd64"},
+			},
+		}
+
+		it("round trips all keys for the latest Platform API", func() {
+			handler := files.NewHandler(api.Platform.Latest(), logger)
+			path := filepath.Join(tmpDir, "analyzed.toml")
+
+			h.AssertNil(t, handler.WriteAnalyzed(path, analyzed))
+			found, err := handler.ReadAnalyzed(path)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, found.BuildImage.Reference, "some-build-image")
+			h.AssertEq(t, found.RunImage.Image, "some-run-image")
+			h.AssertEq(t, found.RunImageTarget().OS, "linux")
+		})
+
+		it("omits keys not supported by older Platform APIs", func() {
+			handler := files.NewHandler(api.MustParse("0.9"), logger)
+			path := filepath.Join(tmpDir, "analyzed.toml")
+
+			h.AssertNil(t, handler.WriteAnalyzed(path, analyzed))
+
+			contents, err := os.ReadFile(path)
+			h.AssertNil(t, err)
+			h.AssertStringDoesNotContain(t, string(contents), "build-image")
+			h.AssertStringDoesNotContain(t, string(contents), "target")
+			h.AssertStringContains(t, string(contents), "some-run-image-ref")
+		})
+	})
+
+	when("group.toml", func() {
+		it("marks image extensions", func() {
+			handler := files.NewHandler(api.Platform.Latest(), logger)
+			path := filepath.Join(tmpDir, "group.toml")
+
+			h.AssertNil(t, handler.WriteGroup(path, buildpack.Group{
+				Group:           []buildpack.GroupElement{{ID: "some-buildpack", Version: "v1"}},
+				GroupExtensions: []buildpack.GroupElement{{ID: "some-extension", Version: "v1"}},
+			}))
+			found, err := handler.ReadGroup(path)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, found.Group[0].ID, "some-buildpack")
+			h.AssertEq(t, found.GroupExtensions[0].Extension, true)
+		})
+
+		it("returns a decode error if the file is invalid", func() {
+			handler := files.NewHandler(api.Platform.Latest(), logger)
+			path := filepath.Join(tmpDir, "group.toml")
+			h.Mkfile(t, "[[group]]\nid = \"some-buildpack\"\nversion = \n", path)
+
+			_, err := handler.ReadGroup(path)
+
+			var decodeErr *lerrors.DecodeError
+			h.AssertEq(t, errors.As(err, &decodeErr), true)
+			h.AssertEq(t, decodeErr.File, path)
+		})
+	})
+
+	when("report.toml", func() {
+		it("omits the build BOM for Platform API 0.9 and above", func() {
+			handler := files.NewHandler(api.MustParse("0.9"), logger)
+			path := filepath.Join(tmpDir, "report.toml")
+
+			h.AssertNil(t, handler.WriteReport(path, files.Report{
+				Build: files.BuildReport{BOM: []buildpack.BOMEntry{{Require: buildpack.Require{Name: "some-dep"}}}},
+				Image: files.ImageReport{Tags: []string{"some-tag"}},
+			}))
+			found, err := handler.ReadReport(path)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, len(found.Build.BOM), 0)
+			h.AssertEq(t, found.Image.Tags, []string{"some-tag"})
+		})
+	})
+
+	when("run.toml", func() {
+		var (
+			runPath   string
+			stackPath string
+			run       = files.Run{Images: []files.RunImageForExport{{Image: "some-run-image", Mirrors: []string{"some-mirror"}}}}
+		)
+
+		it.Before(func() {
+			runPath = filepath.Join(tmpDir, "run.toml")
+			stackPath = filepath.Join(tmpDir, "stack.toml")
+		})
+
+		it("uses run.toml for Platform API 0.12 and above", func() {
+			handler := files.NewHandler(api.MustParse("0.12"), logger)
+
+			h.AssertNil(t, handler.WriteRun(runPath, stackPath, run))
+			h.AssertPathExists(t, runPath)
+			h.AssertPathDoesNotExist(t, stackPath)
+
+			found, err := handler.ReadRun(runPath, stackPath)
+			h.AssertNil(t, err)
+			h.AssertEq(t, found, run)
+		})
+
+		it("uses stack.toml for older Platform APIs", func() {
+			handler := files.NewHandler(api.MustParse("0.11"), logger)
+
+			h.AssertNil(t, handler.WriteRun(runPath, stackPath, run))
+			h.AssertPathExists(t, stackPath)
+			h.AssertPathDoesNotExist(t, runPath)
+
+			stackMD, err := files.ReadStack(stackPath, logger)
+			h.AssertNil(t, err)
+			h.AssertEq(t, stackMD.RunImage.Image, "some-run-image")
+
+			found, err := handler.ReadRun(runPath, stackPath)
+			h.AssertNil(t, err)
+			h.AssertEq(t, found, run)
+		})
+	})
+}
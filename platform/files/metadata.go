@@ -7,6 +7,7 @@ import (
 
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/buildpack"
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/layers"
 )
@@ -18,22 +19,46 @@ type BuildMetadata struct {
 	BOM []buildpack.BOMEntry `toml:"bom,omitempty" json:"bom"`
 	// Buildpacks are the buildpacks used in the build.
 	Buildpacks []buildpack.GroupElement `toml:"buildpacks" json:"buildpacks"`
+	// EnvOrder is the IDs of the buildpacks in the order their env contributions are applied at launch,
+	// if it differs from the order of Buildpacks because of env order constraints declared in <layer>.toml files.
+	EnvOrder []string `toml:"env-order,omitempty" json:"env-order,omitempty"`
 	// Extensions are the image extensions used in the build.
 	Extensions []buildpack.GroupElement `toml:"extensions,omitempty" json:"extensions,omitempty"`
 	// Labels are labels provided by buildpacks.
 	Labels []buildpack.Label `toml:"labels" json:"-"`
 	// Launcher is metadata to describe the launcher.
 	Launcher LauncherMetadata `toml:"-" json:"launcher"`
+	// NetworkViolations are the attempts of buildpacks to access the network that were denied by the network policy of the build.
+	NetworkViolations []NetworkViolation `toml:"network-violations,omitempty" json:"-"`
 	// Processes are processes provided by buildpacks.
 	Processes []launch.Process `toml:"processes" json:"processes"`
 	// Slices are application slices provided by buildpacks,
 	// used by the exporter to "slice" the application directory into distinct layers.
 	Slices []layers.Slice `toml:"slices" json:"-"`
+	// Tasks are processes provided by buildpacks that are run once, after export or on first boot, rather than launched.
+	Tasks []launch.Task `toml:"tasks,omitempty" json:"tasks,omitempty"`
 	// BuildpackDefaultProcessType is the buildpack-provided default process type.
 	// It will be the default process type for the image unless overridden by the end user.
 	BuildpackDefaultProcessType string `toml:"buildpack-default-process-type,omitempty" json:"buildpack-default-process-type,omitempty"`
 	// PlatformAPI is the Platform API version used for the build.
 	PlatformAPI *api.Version `toml:"-" json:"-"`
+	// External points to the complete build metadata when it is too large for the `io.buildpacks.build.metadata` label.
+	External *ExternalBuildMetadata `toml:"-" json:"external,omitempty"`
+}
+
+// ExternalBuildMetadataFile is the name of the file in <layers>/config that holds the complete build metadata (as JSON)
+// when it is too large for the `io.buildpacks.build.metadata` label.
+const ExternalBuildMetadataFile = "build-metadata.json"
+
+// ExternalBuildMetadata is recorded in the `io.buildpacks.build.metadata` label in place of the BOM
+// when the build metadata is too large for the label (the other fields, including the processes, are kept in the label).
+// The complete build metadata (including the BOM) is the JSON file at Path in the image filesystem,
+// which is added by the layer with the diffID Layer (the launcher config layer).
+type ExternalBuildMetadata struct {
+	// Path is the location of the complete build metadata in the image.
+	Path string `json:"path"`
+	// Layer is the diffID of the layer containing the complete build metadata.
+	Layer string `json:"layer"`
 }
 
 // DecodeBuildMetadata reads a metadata.toml file
@@ -41,7 +66,7 @@ func DecodeBuildMetadata(path string, platformAPI *api.Version, buildmd *BuildMe
 	// decode the common bits
 	_, err := toml.DecodeFile(path, &buildmd)
 	if err != nil {
-		return err
+		return lerrors.NewDecodeError(path, err)
 	}
 
 	// set the platform API on all the appropriate fields
@@ -71,7 +96,9 @@ func (m *BuildMetadata) MarshalJSON() ([]byte, error) {
 
 func (m BuildMetadata) ToLaunchMD() launch.Metadata {
 	lmd := launch.Metadata{
+		EnvOrder:  m.EnvOrder,
 		Processes: m.Processes,
+		Tasks:     m.Tasks,
 	}
 	for _, bp := range m.Buildpacks {
 		lmd.Buildpacks = append(lmd.Buildpacks, launch.Buildpack{
@@ -82,6 +109,12 @@ func (m BuildMetadata) ToLaunchMD() launch.Metadata {
 	return lmd
 }
 
+// NetworkViolation describes a request to the network, by a buildpack that may not access the network, denied during build.
+type NetworkViolation struct {
+	Buildpack string `toml:"buildpack"`
+	Host      string `toml:"host"`
+}
+
 type LauncherMetadata struct {
 	Version string         `json:"version"`
 	Source  SourceMetadata `json:"source"`
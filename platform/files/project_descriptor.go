@@ -0,0 +1,112 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+
+	lerrors "github.com/buildpacks/lifecycle/errors"
+	"github.com/buildpacks/lifecycle/log"
+)
+
+// ProjectDescriptor is the Buildpacks project descriptor (project.toml) provided by the application developer.
+// It is normalized from either schema version 0.1 (keys under [build]) or 0.2 (keys under [io.buildpacks]).
+// See https://github.com/buildpacks/spec/blob/main/extensions/project-descriptor.md.
+type ProjectDescriptor struct {
+	SchemaVersion string
+	Include       []string
+	Exclude       []string
+	Buildpacks    []ProjectBuildpack
+	Env           []ProjectEnvVar
+}
+
+type ProjectBuildpack struct {
+	ID      string `toml:"id"`
+	Version string `toml:"version"`
+	URI     string `toml:"uri"`
+}
+
+type ProjectEnvVar struct {
+	Name  string `toml:"name"`
+	Value string `toml:"value"`
+}
+
+type projectDescriptorTOML struct {
+	Project struct {
+		SchemaVersion string `toml:"schema-version"`
+	} `toml:"_"`
+	Build projectBuildTOML `toml:"build"`
+	IO    struct {
+		Buildpacks projectBuildTOML `toml:"buildpacks"`
+	} `toml:"io"`
+}
+
+type projectBuildTOML struct {
+	Include []string `toml:"include"`
+	Exclude []string `toml:"exclude"`
+	// Buildpacks and Env are used for schema version 0.1
+	Buildpacks []ProjectBuildpack `toml:"buildpacks"`
+	Env        []ProjectEnvVar    `toml:"env"`
+	// Group and Build.Env are used for schema version 0.2
+	Group []ProjectBuildpack `toml:"group"`
+	Build struct {
+		Env []ProjectEnvVar `toml:"env"`
+	} `toml:"build"`
+}
+
+// ReadProjectDescriptor reads project.toml, returning an empty descriptor if the path is not provided or the file does not exist.
+func ReadProjectDescriptor(path string, logger log.Logger) (ProjectDescriptor, error) {
+	if path == "" {
+		return ProjectDescriptor{}, nil
+	}
+	var descriptor projectDescriptorTOML
+	if _, err := toml.DecodeFile(path, &descriptor); err != nil {
+		if os.IsNotExist(err) {
+			logger.Debugf("no project descriptor found at path '%s'", path)
+			return ProjectDescriptor{}, nil
+		}
+		return ProjectDescriptor{}, fmt.Errorf("failed to read project descriptor: %w", lerrors.NewDecodeError(path, err))
+	}
+
+	var result ProjectDescriptor
+	switch schemaVersion := descriptor.Project.SchemaVersion; schemaVersion {
+	case "", "0.1":
+		result = ProjectDescriptor{
+			SchemaVersion: "0.1",
+			Include:       descriptor.Build.Include,
+			Exclude:       descriptor.Build.Exclude,
+			Buildpacks:    descriptor.Build.Buildpacks,
+			Env:           descriptor.Build.Env,
+		}
+	case "0.2":
+		result = ProjectDescriptor{
+			SchemaVersion: schemaVersion,
+			Include:       descriptor.IO.Buildpacks.Include,
+			Exclude:       descriptor.IO.Buildpacks.Exclude,
+			Buildpacks:    descriptor.IO.Buildpacks.Group,
+			Env:           descriptor.IO.Buildpacks.Build.Env,
+		}
+	default:
+		return ProjectDescriptor{}, fmt.Errorf("unsupported project descriptor schema version '%s'", schemaVersion)
+	}
+	if len(result.Include) > 0 && len(result.Exclude) > 0 {
+		return ProjectDescriptor{}, errors.New("project descriptor must not provide both include and exclude")
+	}
+	for _, bp := range result.Buildpacks {
+		if bp.ID == "" {
+			return ProjectDescriptor{}, fmt.Errorf("buildpack '%s' in project descriptor must have an id; buildpack URIs must be resolved by the platform", bp.URI)
+		}
+	}
+	return result, nil
+}
+
+// EnvList returns the environment variables declared in the project descriptor, as "NAME=value".
+func (d ProjectDescriptor) EnvList() []string {
+	var result []string
+	for _, e := range d.Env {
+		result = append(result, e.Name+"="+e.Value)
+	}
+	return result
+}
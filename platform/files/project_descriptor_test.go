@@ -0,0 +1,120 @@
+package files_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform/files"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestProjectDescriptor(t *testing.T) {
+	spec.Run(t, "ProjectDescriptor", testProjectDescriptor, spec.Report(report.Terminal{}))
+}
+
+func testProjectDescriptor(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir string
+		path   string
+		logger = log.NewDefaultLogger(io.Discard)
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "lifecycle.test")
+		h.AssertNil(t, err)
+		path = filepath.Join(tmpDir, "project.toml")
+	})
+
+	it.After(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	when("#ReadProjectDescriptor", func() {
+		it("reads schema version 0.2", func() {
+			h.Mkfile(t, `[_]
+schema-version = "0.2"
+
+[io.buildpacks]
+exclude = ["*.log"]
+
+[[io.buildpacks.group]]
+id = "some-buildpack"
+version = "some-version"
+
+[[io.buildpacks.build.env]]
+name = "SOME_VAR"
+value = "some-value"
+`, path)
+
+			descriptor, err := files.ReadProjectDescriptor(path, logger)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, descriptor.SchemaVersion, "0.2")
+			h.AssertEq(t, descriptor.Exclude, []string{"*.log"})
+			h.AssertEq(t, descriptor.Buildpacks, []files.ProjectBuildpack{{ID: "some-buildpack", Version: "some-version"}})
+			h.AssertEq(t, descriptor.EnvList(), []string{"SOME_VAR=some-value"})
+		})
+
+		it("reads schema version 0.1", func() {
+			h.Mkfile(t, `[build]
+include = ["src/"]
+
+[[build.buildpacks]]
+id = "some-buildpack"
+
+[[build.env]]
+name = "SOME_VAR"
+value = "some-value"
+`, path)
+
+			descriptor, err := files.ReadProjectDescriptor(path, logger)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, descriptor.SchemaVersion, "0.1")
+			h.AssertEq(t, descriptor.Include, []string{"src/"})
+			h.AssertEq(t, descriptor.Buildpacks, []files.ProjectBuildpack{{ID: "some-buildpack"}})
+			h.AssertEq(t, descriptor.EnvList(), []string{"SOME_VAR=some-value"})
+		})
+
+		it("returns an empty descriptor when the file does not exist", func() {
+			descriptor, err := files.ReadProjectDescriptor(path, logger)
+			h.AssertNil(t, err)
+			h.AssertEq(t, descriptor, files.ProjectDescriptor{})
+		})
+
+		it("errors when both include and exclude are provided", func() {
+			h.Mkfile(t, `[build]
+include = ["src/"]
+exclude = ["*.log"]
+`, path)
+
+			_, err := files.ReadProjectDescriptor(path, logger)
+			h.AssertError(t, err, "project descriptor must not provide both include and exclude")
+		})
+
+		it("errors for buildpacks without an id", func() {
+			h.Mkfile(t, `[[build.buildpacks]]
+uri = "some-uri"
+`, path)
+
+			_, err := files.ReadProjectDescriptor(path, logger)
+			h.AssertError(t, err, "buildpack 'some-uri' in project descriptor must have an id")
+		})
+
+		it("errors for unsupported schema versions", func() {
+			h.Mkfile(t, `[_]
+schema-version = "9.9"
+`, path)
+
+			_, err := files.ReadProjectDescriptor(path, logger)
+			h.AssertError(t, err, "unsupported project descriptor schema version '9.9'")
+		})
+	})
+}
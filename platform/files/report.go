@@ -1,6 +1,10 @@
 package files
 
-import "github.com/buildpacks/lifecycle/buildpack"
+import (
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/internal/redact"
+	"github.com/buildpacks/lifecycle/internal/usage"
+)
 
 // Report is written by the exporter as report.toml to record information about the build.
 // It is not included in the output image, but can be saved off by the platform before the build container exits.
@@ -8,6 +12,24 @@ import "github.com/buildpacks/lifecycle/buildpack"
 type Report struct {
 	Build BuildReport `toml:"build,omitempty"`
 	Image ImageReport `toml:"image"`
+	// DebugImage is the debug image saved alongside the application image, if requested by the platform.
+	DebugImage *ImageReport `toml:"debug-image,omitempty"`
+	// Deprecations are the deprecated APIs and file formats encountered during the build (by any phase),
+	// so that platforms can surface upgrade guidance.
+	Deprecations []Deprecation `toml:"deprecations,omitempty"`
+	// SBOMDiff summarizes the components added, removed or upgraded since the previous image,
+	// if the SBOM of the previous image was available.
+	SBOMDiff *SBOMDiff `toml:"sbom-diff,omitempty"`
+	// PolicyViolations are the components of the image that violate the SBOM policy provided by the platform, if any.
+	PolicyViolations []SBOMPolicyViolation `toml:"policy-violations,omitempty"`
+	// Scan is the result of scanning the image with the platform-provided scanner, if any.
+	Scan *ScanResult `toml:"scan,omitempty"`
+	// Usage is the resource usage (peak memory, CPU time and disk) of each phase and buildpack,
+	// so that platforms can right-size build containers.
+	Usage *ResourceUsage `toml:"usage,omitempty"`
+	// Redactions are the number of secret values and credentials redacted from the output of each buildpack
+	// (and from the logs of the lifecycle, as "lifecycle"), so that platforms can flag builds that leak secrets.
+	Redactions []Redaction `toml:"redactions,omitempty"`
 }
 
 type BuildReport struct {
@@ -20,3 +42,39 @@ type ImageReport struct {
 	Digest       string   `toml:"digest,omitempty"`
 	ManifestSize int64    `toml:"manifest-size,omitzero"`
 }
+
+// Deprecation describes a deprecated API or file format encountered during the build,
+// e.g., a deprecated Buildpack API requested by a buildpack.
+type Deprecation struct {
+	// Kind is the kind of deprecation: "platform-api", "buildpack-api" or "legacy-format".
+	Kind string `toml:"kind"`
+	// Subject identifies what is deprecated, e.g., the ID of the buildpack requesting a deprecated Buildpack API.
+	Subject string `toml:"subject,omitempty"`
+	// API is the API version in use when the deprecation was encountered.
+	API string `toml:"api,omitempty"`
+	// Message describes the deprecation and how to address it.
+	Message string `toml:"message"`
+}
+
+// Redaction is the number of values redacted from the output of a buildpack.
+type Redaction = redact.Count
+
+// ResourceUsage is the resource usage of the phases and buildpacks of a build.
+type ResourceUsage = usage.Report
+
+// ScanResult is the verdict and summary of findings reported by a vulnerability scanner.
+type ScanResult struct {
+	// Scanner is the scanner executable or endpoint that produced the result.
+	Scanner string `toml:"scanner"`
+	// Image identifies the scanned image (a digest reference, or an image ID for images exported to a daemon).
+	Image string `toml:"image"`
+	// Verdict is one of "pass", "warn" or "fail".
+	Verdict string `toml:"verdict"`
+	// Summary is the number of findings, keyed by severity (e.g., "critical", "high").
+	Summary map[string]int `toml:"summary,omitempty"`
+	// Message is an optional human-readable description of the result.
+	Message string `toml:"message,omitempty"`
+}
+
+// SBOMDiff describes the changes in the components of the image since the previous image.
+type SBOMDiff = buildpack.SBOMDiff
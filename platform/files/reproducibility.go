@@ -0,0 +1,25 @@
+package files
+
+// ReproducibilityReport is written by `lifecycle verify-reproducible` to record whether a rebuild of an image
+// (e.g., by the creator, with the same inputs and source date epoch) produced the same layers as the image.
+// It can be used as the predicate of a reproducible-build attestation.
+type ReproducibilityReport struct {
+	Image        string `toml:"image"`
+	Rebuilt      string `toml:"rebuilt"`
+	Reproducible bool   `toml:"reproducible"`
+	// Differences are the layers whose diff IDs differ between the image and the rebuilt image.
+	Differences []LayerDifference `toml:"differences,omitempty"`
+}
+
+// LayerDifference describes a layer that differs between an image and its rebuild.
+type LayerDifference struct {
+	// Layer is the name of the layer, i.e., "run-image", "launcher", "config", "process-types", "sbom", "app" (or "app-<n>" for slices),
+	// or the name of a buildpack-provided layer, or "extension-<n>" for the layers of the extended run image.
+	Layer string `toml:"layer"`
+	// Producer is the ID of the buildpack (or extension) that produced the layer, if any.
+	Producer string `toml:"producer,omitempty"`
+	// Expected is the diff ID of the layer in the image, or empty if the layer is only in the rebuilt image.
+	Expected string `toml:"expected"`
+	// Actual is the diff ID of the layer in the rebuilt image, or empty if the layer is only in the image.
+	Actual string `toml:"actual"`
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/BurntSushi/toml"
 
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	"github.com/buildpacks/lifecycle/log"
 )
 
@@ -14,7 +15,27 @@ import (
 // on the output image for use during rebase.
 // The location of the file can be specified by providing `-run <path>` to the lifecycle.
 type Run struct {
-	Images []RunImageForExport `json:"-" toml:"images"`
+	Images    []RunImageForExport `json:"-" toml:"images"`
+	Selection RunSelection        `json:"-" toml:"selection,omitempty"`
+}
+
+const (
+	// RunImagePolicyFirst selects the first image in run.toml.
+	RunImagePolicyFirst = "first"
+	// RunImagePolicyTarget selects the first image in run.toml whose target matches the build target.
+	RunImagePolicyTarget = "target"
+
+	// MirrorPolicyRegistry prefers the image or mirror on the same registry as the output image.
+	MirrorPolicyRegistry = "registry"
+	// MirrorPolicyOrdered selects the first accessible image or mirror, in the order provided.
+	MirrorPolicyOrdered = "ordered"
+)
+
+// RunSelection configures how the lifecycle selects a run image from run.toml when the platform does not provide one.
+// When unset, the first image is selected, preferring the mirror on the same registry as the output image.
+type RunSelection struct {
+	Policy  string `toml:"policy,omitempty"`
+	Mirrors string `toml:"mirrors,omitempty"`
 }
 
 // Contains returns true if the provided image reference is found in the existing metadata,
@@ -35,7 +56,7 @@ func ReadRun(runPath string, logger log.Logger) (Run, error) {
 			logger.Infof("no run metadata found at path '%s'\n", runPath)
 			return Run{}, nil
 		}
-		return Run{}, err
+		return Run{}, lerrors.NewDecodeError(runPath, err)
 	}
 	return runMD, nil
 }
@@ -0,0 +1,34 @@
+package files
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	lerrors "github.com/buildpacks/lifecycle/errors"
+)
+
+// SBOMPolicy is provided by the platform to restrict the licenses and packages that may be included in the application image.
+// The exporter evaluates the merged SBOM of the image against it.
+type SBOMPolicy = buildpack.SBOMPolicy
+
+// SBOMPolicyViolation describes a component of the image that violates the SBOM policy.
+type SBOMPolicyViolation = buildpack.SBOMPolicyViolation
+
+// ReadSBOMPolicy reads the SBOM policy file at path.
+func ReadSBOMPolicy(path string) (SBOMPolicy, error) {
+	var policy SBOMPolicy
+	if _, err := toml.DecodeFile(path, &policy); err != nil {
+		return SBOMPolicy{}, fmt.Errorf("failed to read SBOM policy: %w", lerrors.NewDecodeError(path, err))
+	}
+	for i, pkg := range policy.Packages {
+		if pkg.Name == "" && pkg.PURL == "" {
+			return SBOMPolicy{}, fmt.Errorf("invalid SBOM policy: packages[%d] must provide a name or purl", i)
+		}
+		if !pkg.Deny && pkg.MinVersion == "" {
+			return SBOMPolicy{}, fmt.Errorf("invalid SBOM policy: packages[%d] must provide deny or min-version", i)
+		}
+	}
+	return policy, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/BurntSushi/toml"
 
+	lerrors "github.com/buildpacks/lifecycle/errors"
 	iname "github.com/buildpacks/lifecycle/internal/name"
 	"github.com/buildpacks/lifecycle/log"
 )
@@ -21,6 +22,9 @@ type Stack struct {
 type RunImageForExport struct {
 	Image   string   `toml:"image,omitempty" json:"image,omitempty"`
 	Mirrors []string `toml:"mirrors,omitempty" json:"mirrors,omitempty"`
+	// Target is the target of the run image in run.toml, used when selecting a run image by target.
+	// Empty fields match any build target.
+	Target *TargetMetadata `toml:"target,omitempty" json:"-"`
 }
 
 // Contains returns true if the provided image reference is found in the existing metadata,
@@ -45,7 +49,42 @@ func ReadStack(stackPath string, logger log.Logger) (Stack, error) {
 			logger.Infof("no stack metadata found at path '%s'\n", stackPath)
 			return Stack{}, nil
 		}
-		return Stack{}, err
+		return Stack{}, lerrors.NewDecodeError(stackPath, err)
 	}
 	return stackMD, nil
 }
+
+// stackTargets are the targets of the stacks that are translated to targets (and back) during the deprecation of stacks,
+// so that images and metadata written by older lifecycles and platforms can be used with newer ones.
+var stackTargets = map[string]TargetMetadata{
+	"io.buildpacks.stacks.bionic": {OS: "linux", Arch: "amd64", Distribution: &OSDistribution{Name: "ubuntu", Version: "18.04"}},
+	"io.buildpacks.stacks.focal":  {OS: "linux", Arch: "amd64", Distribution: &OSDistribution{Name: "ubuntu", Version: "20.04"}},
+	"io.buildpacks.stacks.jammy":  {OS: "linux", Arch: "amd64", Distribution: &OSDistribution{Name: "ubuntu", Version: "22.04"}},
+}
+
+// TargetForStackID returns the target of the provided stack, and false if the stack cannot be translated to a target.
+func TargetForStackID(stackID string) (TargetMetadata, bool) {
+	target, ok := stackTargets[stackID]
+	if !ok {
+		return TargetMetadata{}, false
+	}
+	distro := *target.Distribution
+	target.Distribution = &distro
+	return target, true
+}
+
+// StackIDForTarget returns the stack with the provided target, or an empty string if the target cannot be translated to a stack.
+// The os, architecture, and distribution of the target must be provided.
+func StackIDForTarget(target TargetMetadata) string {
+	if target.Distribution == nil {
+		return ""
+	}
+	for stackID, stackTarget := range stackTargets {
+		if target.OS == stackTarget.OS &&
+			target.Arch == stackTarget.Arch &&
+			*target.Distribution == *stackTarget.Distribution {
+			return stackID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package files
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/BurntSushi/toml"
+
+	lerrors "github.com/buildpacks/lifecycle/errors"
+)
+
+// TrustPolicy is provided by the platform to restrict the base images (i.e., the build and run images) that the lifecycle uses
+// to images signed (with cosign) by trusted keys. Base images that do not match any rule are refused.
+//
+//	[[rules]]
+//	images = ["registry.example.com/stacks/*"]
+//	keys = ["/platform/keys/stacks.pub"]
+type TrustPolicy struct {
+	Rules []TrustRule `toml:"rules"`
+}
+
+// TrustRule requires the images in the repositories matching one of the patterns (see path.Match) to be signed by one of the keys,
+// which are paths to PEM-encoded public keys.
+type TrustRule struct {
+	Images []string `toml:"images"`
+	Keys   []string `toml:"keys"`
+}
+
+// ReadTrustPolicy reads the trust policy file at path.
+func ReadTrustPolicy(policyPath string) (TrustPolicy, error) {
+	var policy TrustPolicy
+	if _, err := toml.DecodeFile(policyPath, &policy); err != nil {
+		return TrustPolicy{}, fmt.Errorf("failed to read trust policy: %w", lerrors.NewDecodeError(policyPath, err))
+	}
+	for i, rule := range policy.Rules {
+		if len(rule.Images) == 0 || len(rule.Keys) == 0 {
+			return TrustPolicy{}, fmt.Errorf("invalid trust policy: rules[%d] must provide images and keys", i)
+		}
+		for _, pattern := range rule.Images {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return TrustPolicy{}, fmt.Errorf("invalid trust policy: rules[%d] has an invalid pattern '%s'", i, pattern)
+			}
+		}
+	}
+	return policy, nil
+}
+
+// RuleFor returns the first rule with a pattern matching the repository (e.g., "index.docker.io/library/ubuntu").
+func (p TrustPolicy) RuleFor(repository string) (TrustRule, bool) {
+	for _, rule := range p.Rules {
+		for _, pattern := range rule.Images {
+			if ok, _ := path.Match(pattern, repository); ok {
+				return rule, true
+			}
+		}
+	}
+	return TrustRule{}, false
+}
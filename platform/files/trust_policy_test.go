@@ -0,0 +1,73 @@
+package files_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/platform/files"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestTrustPolicy(t *testing.T) {
+	spec.Run(t, "TrustPolicy", testTrustPolicy, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testTrustPolicy(t *testing.T, when spec.G, it spec.S) {
+	var policyPath string
+
+	it.Before(func() {
+		policyPath = filepath.Join(t.TempDir(), "trust-policy.toml")
+	})
+
+	writePolicy := func(contents string) {
+		h.AssertNil(t, os.WriteFile(policyPath, []byte(contents), 0600))
+	}
+
+	when("#ReadTrustPolicy", func() {
+		it("reads the rules", func() {
+			writePolicy("[[rules]]\nimages = [\"registry.example.com/stacks/*\"]\nkeys = [\"/platform/keys/stacks.pub\"]\n")
+
+			policy, err := files.ReadTrustPolicy(policyPath)
+			h.AssertNil(t, err)
+			h.AssertEq(t, policy.Rules, []files.TrustRule{{Images: []string{"registry.example.com/stacks/*"}, Keys: []string{"/platform/keys/stacks.pub"}}})
+		})
+
+		it("errors if a rule has no keys", func() {
+			writePolicy("[[rules]]\nimages = [\"registry.example.com/stacks/*\"]\n")
+
+			_, err := files.ReadTrustPolicy(policyPath)
+			h.AssertError(t, err, "invalid trust policy: rules[0] must provide images and keys")
+		})
+
+		it("errors if a pattern is invalid", func() {
+			writePolicy("[[rules]]\nimages = [\"registry.example.com/[stacks\"]\nkeys = [\"/platform/keys/stacks.pub\"]\n")
+
+			_, err := files.ReadTrustPolicy(policyPath)
+			h.AssertError(t, err, "invalid trust policy: rules[0] has an invalid pattern 'registry.example.com/[stacks'")
+		})
+	})
+
+	when("#RuleFor", func() {
+		it("returns the first rule matching the repository", func() {
+			policy := files.TrustPolicy{Rules: []files.TrustRule{
+				{Images: []string{"registry.example.com/stacks/*"}, Keys: []string{"stacks.pub"}},
+				{Images: []string{"registry.example.com/*/*", "index.docker.io/library/ubuntu"}, Keys: []string{"other.pub"}},
+			}}
+
+			rule, ok := policy.RuleFor("registry.example.com/stacks/run")
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, rule.Keys, []string{"stacks.pub"})
+
+			rule, ok = policy.RuleFor("index.docker.io/library/ubuntu")
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, rule.Keys, []string{"other.pub"})
+
+			_, ok = policy.RuleFor("registry.example.com/run")
+			h.AssertEq(t, ok, false)
+		})
+	})
+}
@@ -2,6 +2,7 @@ package platform
 
 const (
 	BuildMetadataLabel     = "io.buildpacks.build.metadata"
+	DebugAppImageLabel     = "io.buildpacks.debug.app-image"
 	LifecycleMetadataLabel = "io.buildpacks.lifecycle.metadata"
 	MixinsLabel            = "io.buildpacks.stack.mixins"
 	ProjectMetadataLabel   = "io.buildpacks.project.metadata"
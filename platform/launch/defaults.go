@@ -1,6 +1,7 @@
 package launch
 
 import (
+	"os"
 	"path/filepath"
 
 	"github.com/buildpacks/lifecycle/internal/path"
@@ -12,6 +13,9 @@ const (
 	EnvNoColor     = "CNB_NO_COLOR" // defaults to false
 	EnvPlatformAPI = "CNB_PLATFORM_API"
 	EnvProcessType = "CNB_PROCESS_TYPE"
+	// EnvTaskStateDir is the directory where the launcher records the first-boot tasks that completed,
+	// so that they are run once per container (or once per volume, if the directory is on a volume).
+	EnvTaskStateDir = "CNB_TASK_STATE_DIR"
 
 	DefaultPlatformAPI = ""
 	DefaultProcessType = "web"
@@ -22,4 +26,6 @@ const (
 var (
 	DefaultAppDir    = filepath.Join(path.RootDir, "workspace")
 	DefaultLayersDir = filepath.Join(path.RootDir, "layers")
+	// DefaultTaskStateDir is in the temporary directory, which is new in each container.
+	DefaultTaskStateDir = filepath.Join(os.TempDir(), "cnb-tasks")
 )
@@ -12,53 +12,111 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 
 	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/internal/network"
 	"github.com/buildpacks/lifecycle/internal/str"
+	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform/files"
 )
 
 // LifecycleInputs holds the values of command-line flags and args i.e., platform inputs to the lifecycle.
 // Fields are the cumulative total of inputs across all lifecycle phases and all supported Platform APIs.
 type LifecycleInputs struct {
-	PlatformAPI           *api.Version
-	AnalyzedPath          string
-	AppDir                string
-	BuildConfigDir        string
-	BuildImageRef         string
-	BuildpacksDir         string
-	CacheDir              string
-	CacheImageRef         string
-	DefaultProcessType    string
-	DeprecatedRunImageRef string
-	ExtendKind            string
-	ExtendedDir           string
-	ExtensionsDir         string
-	GeneratedDir          string
-	GroupPath             string
-	KanikoDir             string
-	LaunchCacheDir        string
-	LauncherPath          string
-	LauncherSBOMDir       string
-	LayersDir             string
-	LayoutDir             string
-	LogLevel              string
-	OrderPath             string
-	OutputImageRef        string
-	PlanPath              string
-	PlatformDir           string
-	PreviousImageRef      string
-	ProjectMetadataPath   string
-	ReportPath            string
-	RunImageRef           string
-	RunPath               string
-	StackPath             string
-	UID                   int
-	GID                   int
-	ForceRebase           bool
-	SkipLayers            bool
-	UseDaemon             bool
-	UseLayout             bool
-	AdditionalTags        str.Slice // str.Slice satisfies the `Value` interface required by the `flag` package
-	KanikoCacheTTL        time.Duration
+	PlatformAPI                *api.Version
+	AnalyzedPath               string
+	AppDir                     string
+	AppsPath                   string
+	AppSourceDir               string
+	AppSymlinks                string
+	ArtifactsDir               string
+	BuildConfigDir             string
+	BuildImageRef              string
+	BuildLogPath               string
+	BuildpacksDir              string
+	CacheDir                   string
+	CacheImageRef              string
+	CacheLockTimeout           time.Duration
+	ConstraintsPath            string
+	TrustPolicyPath            string
+	DefaultProcessType         string
+	DefaultProcessTypeFallback string
+	DebugImageRef              string
+	DeprecatedRunImageRef      string
+	DigestAlgorithm            string
+	ExtendBackend              string
+	ExtendCacheImage           string
+	ExtendKind                 string
+	ExtendParallel             bool
+	ExtendRootless             bool
+	ExtendSecretsDir           string
+	ExtendedDir                string
+	ExtensionsDir              string
+	FailurePath                string
+	GenerateDryRunDir          string
+	GeneratedDir               string
+	GroupPath                  string
+	KanikoDir                  string
+	LaunchCacheDir             string
+	LaunchCacheImageRef        string
+	LauncherPath               string
+	LauncherSBOMDir            string
+	LayerCompression           string
+	LayersDir                  string
+	LayoutDir                  string
+	LenientExtraction          bool
+	LogFormat                  string
+	LogLevel                   string
+	MergedSBOMPath             string
+	NetworkBuildpacks          string
+	NormalizeOwnership         bool
+	OrderPath                  string
+	OutputImageRef             string
+	PlanPath                   string
+	PlatformDir                string
+	PreserveXattrs             string
+	PreviousImageRef           string
+	ProjectDescriptorPath      string
+	ProjectMetadataPath        string
+	ReportPath                 string
+	RunImageRef                string
+	RunPath                    string
+	SBOMCompression            string
+	SBOMPolicyPath             string
+	SBOMValidation             string
+	Scanner                    string
+	SourceSBOMPath             string
+	SquashBuildpacks           string
+	SquashLayersBelow          int
+	StackPath                  string
+	StripSetuid                bool
+	SupportBundlePath          string
+	TmpDir                     string
+	UID                        int
+	Umask                      string
+	GID                        int
+	AnonymousFallback          bool
+	AttachAttestations         bool
+	CleanDryRun                bool
+	ExplainEnv                 bool
+	ForceRebase                bool
+	RebaseDryRun               bool
+	RebaseImagesPath           string
+	RebaseParallelism          int
+	RebaseSnapshot             bool
+	PruneLaunchSBOM            bool
+	DedupeSBOM                 bool
+	CreateWorkingDirs          bool
+	NoDigestCache              bool
+	ScannerEnforce             bool
+	SkipAnalyze                bool
+	SkipBuild                  bool
+	SkipDetect                 bool
+	SkipLayers                 bool
+	UseDaemon                  bool
+	UseLayout                  bool
+	AdditionalTags             str.Slice // str.Slice satisfies the `Value` interface required by the `flag` package
+	KanikoCacheTTL             time.Duration
+	RunImageSelection          *files.RunImageSelection // the run image selected from run.toml, if the platform did not provide one
 }
 
 const PlaceholderLayers = "<layers>"
@@ -85,11 +143,19 @@ func NewLifecycleInputs(platformAPI *api.Version) *LifecycleInputs {
 	inputs := &LifecycleInputs{
 		// Operator config
 
-		LogLevel:    envOrDefault(EnvLogLevel, DefaultLogLevel),
-		PlatformAPI: platformAPI,
-		ExtendKind:  envOrDefault(EnvExtendKind, DefaultExtendKind),
-		UseDaemon:   boolEnv(EnvUseDaemon),
-		UseLayout:   boolEnv(EnvUseLayout),
+		LogFormat:        envOrDefault(EnvLogFormat, DefaultLogFormat),
+		LogLevel:         envOrDefault(EnvLogLevel, DefaultLogLevel),
+		PlatformAPI:      platformAPI,
+		ExtendKind:       envOrDefault(EnvExtendKind, DefaultExtendKind),
+		ExtendBackend:    envOrDefault(EnvExtendBackend, DefaultExtendBackend),
+		ExtendParallel:   boolEnv(EnvExtendParallel),
+		ExtendRootless:   boolEnv(EnvExtendRootless),
+		SBOMCompression:  envOrDefault(EnvSBOMCompression, DefaultSBOMCompression),
+		LayerCompression: envOrDefault(EnvLayerCompression, DefaultLayerCompression),
+		DigestAlgorithm:  envOrDefault(EnvDigestAlgorithm, DefaultDigestAlgorithm),
+		SBOMValidation:   envOrDefault(EnvSBOMValidation, DefaultSBOMValidation),
+		UseDaemon:        boolEnv(EnvUseDaemon),
+		UseLayout:        boolEnv(EnvUseLayout),
 
 		// Provided by the base image
 
@@ -106,48 +172,108 @@ func NewLifecycleInputs(platformAPI *api.Version) *LifecycleInputs {
 
 		// Provided at build time
 
-		AppDir:      envOrDefault(EnvAppDir, DefaultAppDir),
-		LayersDir:   envOrDefault(EnvLayersDir, DefaultLayersDir),
-		LayoutDir:   os.Getenv(EnvLayoutDir),
-		OrderPath:   envOrDefault(EnvOrderPath, filepath.Join(PlaceholderLayers, DefaultOrderFile)),
-		PlatformDir: envOrDefault(EnvPlatformDir, DefaultPlatformDir),
+		AppDir:           envOrDefault(EnvAppDir, DefaultAppDir),
+		AppSourceDir:     Getenv(EnvAppSourceDir),
+		AppSymlinks:      envOrDefault(EnvAppSymlinks, DefaultAppSymlinks),
+		AppsPath:         Getenv(EnvAppsPath),
+		ExtendCacheImage: Getenv(EnvExtendCacheImage),
+		ExtendSecretsDir: Getenv(EnvExtendSecretsDir),
+		LayersDir:        envOrDefault(EnvLayersDir, DefaultLayersDir),
+		LayoutDir:        Getenv(EnvLayoutDir),
+		OrderPath:        envOrDefault(EnvOrderPath, filepath.Join(PlaceholderLayers, DefaultOrderFile)),
+		PlatformDir:      envOrDefault(EnvPlatformDir, DefaultPlatformDir),
+		ExplainEnv:       boolEnv(EnvExplainEnv),
+		ConstraintsPath:  Getenv(EnvConstraintsPath),
+		TrustPolicyPath:  Getenv(EnvTrustPolicyPath),
+
+		ProjectDescriptorPath: Getenv(EnvProjectDescriptorPath),
+		NetworkBuildpacks:     Getenv(EnvNetworkBuildpacks),
+
+		// Normalization of the ownership and permissions of the app and layers directories
+
+		NormalizeOwnership: boolEnv(EnvNormalizeOwnership),
+		StripSetuid:        boolEnv(EnvStripSetuid),
+		Umask:              Getenv(EnvUmask),
 
 		// The following instruct the lifecycle where to write files and data during the build
 
-		AnalyzedPath: envOrDefault(EnvAnalyzedPath, filepath.Join(PlaceholderLayers, DefaultAnalyzedFile)),
-		ExtendedDir:  envOrDefault(EnvExtendedDir, filepath.Join(PlaceholderLayers, DefaultExtendedDir)),
-		GeneratedDir: envOrDefault(EnvGeneratedDir, filepath.Join(PlaceholderLayers, DefaultGeneratedDir)),
-		GroupPath:    envOrDefault(EnvGroupPath, filepath.Join(PlaceholderLayers, DefaultGroupFile)),
-		PlanPath:     envOrDefault(EnvPlanPath, filepath.Join(PlaceholderLayers, DefaultPlanFile)),
-		ReportPath:   envOrDefault(EnvReportPath, filepath.Join(PlaceholderLayers, DefaultReportFile)),
+		AnalyzedPath:      envOrDefault(EnvAnalyzedPath, filepath.Join(PlaceholderLayers, DefaultAnalyzedFile)),
+		ArtifactsDir:      Getenv(EnvArtifactsDir),
+		ExtendedDir:       envOrDefault(EnvExtendedDir, filepath.Join(PlaceholderLayers, DefaultExtendedDir)),
+		FailurePath:       envOrDefault(EnvFailurePath, filepath.Join(PlaceholderLayers, DefaultFailureFile)),
+		GeneratedDir:      envOrDefault(EnvGeneratedDir, filepath.Join(PlaceholderLayers, DefaultGeneratedDir)),
+		GenerateDryRunDir: "", // only for Platform API 0.10+
+		GroupPath:         envOrDefault(EnvGroupPath, filepath.Join(PlaceholderLayers, DefaultGroupFile)),
+		PlanPath:          envOrDefault(EnvPlanPath, filepath.Join(PlaceholderLayers, DefaultPlanFile)),
+		ReportPath:        envOrDefault(EnvReportPath, filepath.Join(PlaceholderLayers, DefaultReportFile)),
+		SupportBundlePath: Getenv(EnvSupportBundlePath),
+		TmpDir:            Getenv(EnvTmpDir),
 
 		// Configuration options with respect to caching
 
-		CacheDir:       os.Getenv(EnvCacheDir),
-		CacheImageRef:  os.Getenv(EnvCacheImage),
-		KanikoCacheTTL: timeEnvOrDefault(EnvKanikoCacheTTL, DefaultKanikoCacheTTL),
-		KanikoDir:      "/kaniko",
-		LaunchCacheDir: os.Getenv(EnvLaunchCacheDir),
-		SkipLayers:     skipLayers,
+		CacheDir:            Getenv(EnvCacheDir),
+		CacheImageRef:       Getenv(EnvCacheImage),
+		CacheLockTimeout:    timeEnvOrDefault(EnvCacheLockTimeout, DefaultCacheLockTimeout),
+		KanikoCacheTTL:      timeEnvOrDefault(EnvKanikoCacheTTL, DefaultKanikoCacheTTL),
+		KanikoDir:           envOrDefault(EnvKanikoDir, DefaultKanikoDir),
+		LaunchCacheDir:      Getenv(EnvLaunchCacheDir),
+		LaunchCacheImageRef: Getenv(EnvLaunchCacheImage),
+		SkipLayers:          skipLayers,
+
+		// Phases skipped by the creator
+
+		SkipAnalyze: boolEnv(EnvSkipAnalyze),
+		SkipBuild:   boolEnv(EnvSkipBuild),
+		SkipDetect:  boolEnv(EnvSkipDetect),
 
 		// Images used by the lifecycle during the build
 
 		AdditionalTags:        nil, // no default
-		BuildImageRef:         os.Getenv(EnvBuildImage),
+		AnonymousFallback:     boolEnv(EnvAnonymousFallback),
+		BuildImageRef:         Getenv(EnvBuildImage),
 		DeprecatedRunImageRef: "", // no default
 		OutputImageRef:        "", // no default
-		PreviousImageRef:      os.Getenv(EnvPreviousImage),
-		RunImageRef:           os.Getenv(EnvRunImage),
+		PreviousImageRef:      Getenv(EnvPreviousImage),
+		RunImageRef:           Getenv(EnvRunImage),
 
 		// Configuration options for the output application image
 
-		DefaultProcessType:  os.Getenv(EnvProcessType),
-		LauncherPath:        DefaultLauncherPath,
-		LauncherSBOMDir:     DefaultBuildpacksioSBOMDir,
-		ProjectMetadataPath: envOrDefault(EnvProjectMetadataPath, filepath.Join(PlaceholderLayers, DefaultProjectMetadataFile)),
+		AttachAttestations:         boolEnv(EnvAttachAttestations),
+		BuildLogPath:               Getenv(EnvBuildLogPath),
+		DebugImageRef:              Getenv(EnvDebugImage),
+		DefaultProcessType:         Getenv(EnvProcessType),
+		DefaultProcessTypeFallback: Getenv(EnvProcessTypeFallback),
+		LauncherPath:               DefaultLauncherPath,
+		LauncherSBOMDir:            DefaultBuildpacksioSBOMDir,
+		MergedSBOMPath:             Getenv(EnvMergedSBOMPath),
+		ProjectMetadataPath:        envOrDefault(EnvProjectMetadataPath, filepath.Join(PlaceholderLayers, DefaultProjectMetadataFile)),
+		PruneLaunchSBOM:            boolEnv(EnvPruneLaunchSBOM),
+		DedupeSBOM:                 boolEnv(EnvDedupeSBOM),
+		CreateWorkingDirs:          boolEnv(EnvCreateWorkingDirs),
+		SquashBuildpacks:           Getenv(EnvSquashBuildpacks),
+		SquashLayersBelow:          intEnv(EnvSquashLayersBelow),
+		NoDigestCache:              boolEnv(EnvNoDigestCache),
+		LenientExtraction:          boolEnv(EnvLenientExtraction),
+		PreserveXattrs:             envOrDefault(EnvPreserveXattrs, DefaultPreserveXattrs),
+		SBOMPolicyPath:             Getenv(EnvSBOMPolicyPath),
+		Scanner:                    Getenv(EnvScanner),
+		ScannerEnforce:             boolEnv(EnvScannerEnforce),
+		SourceSBOMPath:             Getenv(EnvSourceSBOMPath),
 
 		// Configuration options for rebasing
-		ForceRebase: boolEnv(EnvForceRebase),
+		ForceRebase:       boolEnv(EnvForceRebase),
+		RebaseDryRun:      boolEnv(EnvRebaseDryRun),
+		RebaseImagesPath:  Getenv(EnvRebaseImagesPath),
+		RebaseParallelism: intEnvOrDefault(EnvRebaseParallelism, DefaultRebaseParallelism),
+		RebaseSnapshot:    boolEnv(EnvRebaseSnapshot),
+
+		// Configuration options for cleaning the layers directory
+		CleanDryRun: boolEnv(EnvCleanDryRun),
+	}
+
+	if platformAPI.AtLeast("0.10") {
+		// like the -generate-dry-run flag
+		inputs.GenerateDryRunDir = Getenv(EnvGenerateDryRunDir)
 	}
 
 	if platformAPI.LessThan("0.6") {
@@ -176,7 +302,12 @@ func (i *LifecycleInputs) AccessChecker() CheckReadAccess {
 	}
 	// remote access checker
 	return func(repo string, keychain authn.Keychain) (bool, error) {
-		img, err := remote.NewImage(repo, keychain)
+		// images that are not pinned in the image lock file (if provided) cannot be used
+		resolved, err := network.ResolveImage(repo)
+		if err != nil {
+			return false, err
+		}
+		img, err := remote.NewImage(resolved, keychain)
 		if err != nil {
 			return false, fmt.Errorf("failed to get remote image: %w", err)
 		}
@@ -190,6 +321,7 @@ func (i *LifecycleInputs) DestinationImages() []string {
 	var ret []string
 	ret = appendOnce(ret, i.OutputImageRef)
 	ret = appendOnce(ret, i.AdditionalTags...)
+	ret = appendOnce(ret, i.DebugImageRef)
 	return ret
 }
 
@@ -204,12 +336,82 @@ func (i *LifecycleInputs) RegistryImages() []string {
 	var ret []string
 	ret = appendOnce(ret, i.CacheImageRef)
 	if i.UseDaemon {
-		return ret
+		return appendOnce(ret, i.LaunchCacheImageRef)
 	}
 	ret = appendOnce(ret, i.Images()...)
 	return ret
 }
 
+// UmaskValue returns the umask provided by the platform, and false if it was not provided.
+// It assumes that the inputs were validated using ValidateUmask.
+func (i *LifecycleInputs) UmaskValue() (int, bool) {
+	if i.Umask == "" {
+		return 0, false
+	}
+	mask, err := strconv.ParseUint(i.Umask, 8, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int(mask), true
+}
+
+// CacheDigestAlgorithm returns the algorithm of the digests of the layers that are only added to a cache directory,
+// or sha256 if the algorithm is not supported (see ValidateDigestAlgorithm).
+func (i *LifecycleInputs) CacheDigestAlgorithm() layers.DigestAlgorithm {
+	algorithm, err := layers.DigestAlgorithmFor(i.DigestAlgorithm)
+	if err != nil {
+		return layers.SHA256
+	}
+	return algorithm
+}
+
+// XattrPrefixes returns the prefixes of the names of the extended attributes to preserve in layers.
+func (i *LifecycleInputs) XattrPrefixes() []string {
+	if i.PreserveXattrs == "none" {
+		return nil
+	}
+	var prefixes []string
+	for _, prefix := range strings.Split(i.PreserveXattrs, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// SquashBuildpackIDs returns the IDs of the buildpacks whose launch layers are squashed into a single layer.
+func (i *LifecycleInputs) SquashBuildpackIDs() []string {
+	return splitIDs(i.SquashBuildpacks)
+}
+
+// NetworkBuildpackIDs returns the IDs of the buildpacks that may access the network during build,
+// or nil if network access is not restricted.
+func (i *LifecycleInputs) NetworkBuildpackIDs() []string {
+	return splitIDs(i.NetworkBuildpacks)
+}
+
+func splitIDs(list string) []string {
+	var ids []string
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// SplitProcessTypes returns the process types in the provided comma-separated list (such as CNB_PROCESS_TYPE_FALLBACK),
+// ignoring empty values.
+func SplitProcessTypes(list string) []string {
+	var processTypes []string
+	for _, processType := range strings.Split(list, ",") {
+		if processType = strings.TrimSpace(processType); processType != "" {
+			processTypes = append(processTypes, processType)
+		}
+	}
+	return processTypes
+}
+
 func appendOnce(list []string, els ...string) []string {
 	for _, el := range els {
 		if el == "" {
@@ -234,7 +436,7 @@ func notIn(list []string, str string) bool {
 // shared helpers
 
 func boolEnv(k string) bool {
-	v := os.Getenv(k)
+	v := Getenv(k)
 	b, err := strconv.ParseBool(v)
 	if err != nil {
 		return false
@@ -243,14 +445,14 @@ func boolEnv(k string) bool {
 }
 
 func envOrDefault(key string, defaultVal string) string {
-	if envVal := os.Getenv(key); envVal != "" {
+	if envVal := Getenv(key); envVal != "" {
 		return envVal
 	}
 	return defaultVal
 }
 
 func intEnv(k string) int {
-	v := os.Getenv(k)
+	v := Getenv(k)
 	d, err := strconv.Atoi(v)
 	if err != nil {
 		return 0
@@ -258,8 +460,15 @@ func intEnv(k string) int {
 	return d
 }
 
+func intEnvOrDefault(k string, defaultVal int) int {
+	if d, err := strconv.Atoi(Getenv(k)); err == nil {
+		return d
+	}
+	return defaultVal
+}
+
 func timeEnvOrDefault(key string, defaultVal time.Duration) time.Duration {
-	envTTL := os.Getenv(key)
+	envTTL := Getenv(key)
 	if envTTL == "" {
 		return defaultVal
 	}
@@ -309,6 +518,7 @@ func (i *LifecycleInputs) placeholderPaths() []*string {
 	return []*string{
 		&i.AnalyzedPath,
 		&i.ExtendedDir,
+		&i.FailurePath,
 		&i.GeneratedDir,
 		&i.GroupPath,
 		&i.OrderPath,
@@ -336,9 +546,12 @@ func ResolveAbsoluteDirPaths(i *LifecycleInputs, _ log.Logger) error {
 func (i *LifecycleInputs) directoryPaths() []*string {
 	return []*string{
 		&i.AppDir,
+		&i.AppSourceDir,
+		&i.ArtifactsDir,
 		&i.BuildConfigDir,
 		&i.BuildpacksDir,
 		&i.CacheDir,
+		&i.ExtendSecretsDir,
 		&i.ExtensionsDir,
 		&i.GeneratedDir,
 		&i.KanikoDir,
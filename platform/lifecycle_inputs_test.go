@@ -7,8 +7,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/internal/str"
+	llog "github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform"
 	h "github.com/buildpacks/lifecycle/testhelpers"
 
@@ -31,21 +35,36 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 			inputs = platform.NewLifecycleInputs(platformAPI)
 
 			h.AssertEq(t, inputs.AdditionalTags, str.Slice(nil))
+			h.AssertEq(t, inputs.AnonymousFallback, false)
 			h.AssertEq(t, inputs.AppDir, platform.DefaultAppDir)
+			h.AssertEq(t, inputs.AppSymlinks, "preserve")
+			h.AssertEq(t, inputs.AttachAttestations, false)
 			h.AssertEq(t, inputs.BuildConfigDir, platform.DefaultBuildConfigDir)
 			h.AssertEq(t, inputs.BuildImageRef, "")
+			h.AssertEq(t, inputs.BuildLogPath, "")
+			h.AssertEq(t, inputs.ArtifactsDir, "")
+			h.AssertEq(t, inputs.SupportBundlePath, "")
+			h.AssertEq(t, inputs.DebugImageRef, "")
 			h.AssertEq(t, inputs.BuildpacksDir, platform.DefaultBuildpacksDir)
 			h.AssertEq(t, inputs.CacheDir, "")
 			h.AssertEq(t, inputs.CacheImageRef, "")
 			h.AssertEq(t, inputs.DefaultProcessType, "")
 			h.AssertEq(t, inputs.DeprecatedRunImageRef, "")
 			h.AssertEq(t, inputs.ExtendKind, "build")
+			h.AssertEq(t, inputs.ExtendBackend, "kaniko")
 			h.AssertEq(t, inputs.ExtensionsDir, platform.DefaultExtensionsDir)
 			h.AssertEq(t, inputs.ForceRebase, false)
+			h.AssertEq(t, inputs.CleanDryRun, false)
+			h.AssertEq(t, inputs.ExplainEnv, false)
+			h.AssertEq(t, inputs.ConstraintsPath, "")
+			h.AssertEq(t, inputs.TrustPolicyPath, "")
 			h.AssertEq(t, inputs.GID, 0)
+			h.AssertEq(t, inputs.CacheLockTimeout, platform.DefaultCacheLockTimeout)
 			h.AssertEq(t, inputs.KanikoCacheTTL, platform.DefaultKanikoCacheTTL)
-			h.AssertEq(t, inputs.KanikoDir, "/kaniko")
+			h.AssertEq(t, inputs.KanikoDir, platform.DefaultKanikoDir)
+			h.AssertEq(t, inputs.XattrPrefixes(), []string{"security.capability"})
 			h.AssertEq(t, inputs.LaunchCacheDir, "")
+			h.AssertEq(t, inputs.LaunchCacheImageRef, "")
 			h.AssertEq(t, inputs.LauncherPath, platform.DefaultLauncherPath)
 			h.AssertEq(t, inputs.LauncherSBOMDir, platform.DefaultBuildpacksioSBOMDir)
 			h.AssertEq(t, inputs.LayersDir, platform.DefaultLayersDir)
@@ -54,11 +73,30 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 			h.AssertEq(t, inputs.PlatformAPI, platformAPI) // from constructor
 			h.AssertEq(t, inputs.PlatformDir, platform.DefaultPlatformDir)
 			h.AssertEq(t, inputs.PreviousImageRef, "")
+			h.AssertEq(t, inputs.NetworkBuildpackIDs(), []string(nil))
+			h.AssertEq(t, inputs.NormalizeOwnership, false)
+			h.AssertEq(t, inputs.PruneLaunchSBOM, false)
+			h.AssertEq(t, inputs.CreateWorkingDirs, false)
 			h.AssertEq(t, inputs.RunImageRef, "")
 			h.AssertEq(t, inputs.RunPath, platform.DefaultRunPath)
+			h.AssertEq(t, inputs.LayerCompression, "auto")
+			h.AssertEq(t, inputs.DigestAlgorithm, "sha256")
+			h.AssertEq(t, inputs.SBOMCompression, "none")
+			h.AssertEq(t, inputs.SBOMValidation, "warn")
+			h.AssertEq(t, inputs.Scanner, "")
+			h.AssertEq(t, inputs.ScannerEnforce, false)
+			h.AssertEq(t, inputs.SkipAnalyze, false)
+			h.AssertEq(t, inputs.SkipBuild, false)
+			h.AssertEq(t, inputs.SkipDetect, false)
 			h.AssertEq(t, inputs.SkipLayers, false)
+			h.AssertEq(t, inputs.SourceSBOMPath, "")
+			h.AssertEq(t, inputs.SquashBuildpackIDs(), []string(nil))
+			h.AssertEq(t, inputs.SquashLayersBelow, 0)
 			h.AssertEq(t, inputs.StackPath, platform.DefaultStackPath)
+			h.AssertEq(t, inputs.StripSetuid, false)
+			h.AssertEq(t, inputs.LenientExtraction, false)
 			h.AssertEq(t, inputs.UID, 0)
+			h.AssertEq(t, inputs.Umask, "")
 			h.AssertEq(t, inputs.UseDaemon, false)
 			h.AssertEq(t, inputs.UseLayout, false)
 		})
@@ -67,8 +105,13 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 			it.Before(func() {
 				h.AssertNil(t, os.Setenv(platform.EnvAnalyzedPath, "some-analyzed-path"))
 				h.AssertNil(t, os.Setenv(platform.EnvAppDir, "some-app-dir"))
+				h.AssertNil(t, os.Setenv(platform.EnvAppSymlinks, "follow"))
 				h.AssertNil(t, os.Setenv(platform.EnvBuildConfigDir, "some-build-config-dir"))
 				h.AssertNil(t, os.Setenv(platform.EnvBuildImage, "some-build-image"))
+				h.AssertNil(t, os.Setenv(platform.EnvBuildLogPath, "some-build-log-path"))
+				h.AssertNil(t, os.Setenv(platform.EnvArtifactsDir, "some-artifacts-dir"))
+				h.AssertNil(t, os.Setenv(platform.EnvSupportBundlePath, "some-support-bundle-path"))
+				h.AssertNil(t, os.Setenv(platform.EnvDebugImage, "some-debug-image"))
 				h.AssertNil(t, os.Setenv(platform.EnvBuildpacksDir, "some-buildpacks-dir"))
 				h.AssertNil(t, os.Setenv(platform.EnvCacheDir, "some-cache-dir"))
 				h.AssertNil(t, os.Setenv(platform.EnvCacheImage, "some-cache-image"))
@@ -76,14 +119,24 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 				h.AssertNil(t, os.Setenv(platform.EnvExtensionsDir, "some-extensions-dir"))
 				h.AssertNil(t, os.Setenv(platform.EnvGID, "5678"))
 				h.AssertNil(t, os.Setenv(platform.EnvForceRebase, "true"))
+				h.AssertNil(t, os.Setenv(platform.EnvCleanDryRun, "true"))
+				h.AssertNil(t, os.Setenv(platform.EnvExplainEnv, "true"))
+				h.AssertNil(t, os.Setenv(platform.EnvConstraintsPath, "some-constraints-path"))
+				h.AssertNil(t, os.Setenv(platform.EnvTrustPolicyPath, "some-trust-policy-path"))
 				h.AssertNil(t, os.Setenv(platform.EnvGeneratedDir, "some-generated-dir"))
 				h.AssertNil(t, os.Setenv(platform.EnvGroupPath, "some-group-path"))
+				h.AssertNil(t, os.Setenv(platform.EnvCacheLockTimeout, "30s"))
 				h.AssertNil(t, os.Setenv(platform.EnvKanikoCacheTTL, "1h0m0s"))
+				h.AssertNil(t, os.Setenv(platform.EnvKanikoDir, "some-kaniko-dir"))
+				h.AssertNil(t, os.Setenv(platform.EnvPreserveXattrs, "security.capability, user."))
 				h.AssertNil(t, os.Setenv(platform.EnvLaunchCacheDir, "some-launch-cache-dir"))
+				h.AssertNil(t, os.Setenv(platform.EnvLaunchCacheImage, "some-launch-cache-image"))
 				h.AssertNil(t, os.Setenv(platform.EnvLayersDir, "some-layers-dir"))
 				h.AssertNil(t, os.Setenv(platform.EnvLayoutDir, "some-layout-dir"))
 				h.AssertNil(t, os.Setenv(platform.EnvLogLevel, "debug"))
 				h.AssertNil(t, os.Setenv(platform.EnvOrderPath, "some-order-path"))
+				h.AssertNil(t, os.Setenv(platform.EnvNetworkBuildpacks, "some/fetching-bp"))
+				h.AssertNil(t, os.Setenv(platform.EnvNormalizeOwnership, "true"))
 				h.AssertNil(t, os.Setenv(platform.EnvPlanPath, "some-plan-path"))
 				h.AssertNil(t, os.Setenv(platform.EnvPlatformDir, "some-platform-dir"))
 				h.AssertNil(t, os.Setenv(platform.EnvPreviousImage, "some-previous-image"))
@@ -91,9 +144,17 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 				h.AssertNil(t, os.Setenv(platform.EnvReportPath, "some-report-path"))
 				h.AssertNil(t, os.Setenv(platform.EnvRunImage, "some-run-image"))
 				h.AssertNil(t, os.Setenv(platform.EnvRunPath, "some-run-path"))
+				h.AssertNil(t, os.Setenv(platform.EnvSkipAnalyze, "true"))
+				h.AssertNil(t, os.Setenv(platform.EnvSkipBuild, "true"))
+				h.AssertNil(t, os.Setenv(platform.EnvSkipDetect, "true"))
 				h.AssertNil(t, os.Setenv(platform.EnvSkipLayers, "true"))
+				h.AssertNil(t, os.Setenv(platform.EnvSquashBuildpacks, "some/bp, other/bp"))
+				h.AssertNil(t, os.Setenv(platform.EnvSquashLayersBelow, "1048576"))
 				h.AssertNil(t, os.Setenv(platform.EnvStackPath, "some-stack-path"))
+				h.AssertNil(t, os.Setenv(platform.EnvStripSetuid, "true"))
+				h.AssertNil(t, os.Setenv(platform.EnvLenientExtraction, "true"))
 				h.AssertNil(t, os.Setenv(platform.EnvUID, "1234"))
+				h.AssertNil(t, os.Setenv(platform.EnvUmask, "022"))
 				h.AssertNil(t, os.Setenv(platform.EnvUseDaemon, "true"))
 				h.AssertNil(t, os.Setenv(platform.EnvUseLayout, "true"))
 			})
@@ -101,23 +162,38 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 			it.After(func() {
 				h.AssertNil(t, os.Unsetenv(platform.EnvAnalyzedPath))
 				h.AssertNil(t, os.Unsetenv(platform.EnvAppDir))
+				h.AssertNil(t, os.Unsetenv(platform.EnvAppSymlinks))
 				h.AssertNil(t, os.Unsetenv(platform.EnvBuildConfigDir))
 				h.AssertNil(t, os.Unsetenv(platform.EnvBuildImage))
+				h.AssertNil(t, os.Unsetenv(platform.EnvBuildLogPath))
+				h.AssertNil(t, os.Unsetenv(platform.EnvArtifactsDir))
+				h.AssertNil(t, os.Unsetenv(platform.EnvSupportBundlePath))
+				h.AssertNil(t, os.Unsetenv(platform.EnvDebugImage))
 				h.AssertNil(t, os.Unsetenv(platform.EnvBuildpacksDir))
 				h.AssertNil(t, os.Unsetenv(platform.EnvCacheDir))
 				h.AssertNil(t, os.Unsetenv(platform.EnvCacheImage))
 				h.AssertNil(t, os.Unsetenv(platform.EnvExtendKind))
 				h.AssertNil(t, os.Unsetenv(platform.EnvExtensionsDir))
 				h.AssertNil(t, os.Unsetenv(platform.EnvForceRebase))
+				h.AssertNil(t, os.Unsetenv(platform.EnvCleanDryRun))
+				h.AssertNil(t, os.Unsetenv(platform.EnvExplainEnv))
+				h.AssertNil(t, os.Unsetenv(platform.EnvConstraintsPath))
+				h.AssertNil(t, os.Unsetenv(platform.EnvTrustPolicyPath))
 				h.AssertNil(t, os.Unsetenv(platform.EnvGID))
 				h.AssertNil(t, os.Unsetenv(platform.EnvGeneratedDir))
 				h.AssertNil(t, os.Unsetenv(platform.EnvGroupPath))
+				h.AssertNil(t, os.Unsetenv(platform.EnvCacheLockTimeout))
 				h.AssertNil(t, os.Unsetenv(platform.EnvKanikoCacheTTL))
+				h.AssertNil(t, os.Unsetenv(platform.EnvKanikoDir))
+				h.AssertNil(t, os.Unsetenv(platform.EnvPreserveXattrs))
 				h.AssertNil(t, os.Unsetenv(platform.EnvLaunchCacheDir))
+				h.AssertNil(t, os.Unsetenv(platform.EnvLaunchCacheImage))
 				h.AssertNil(t, os.Unsetenv(platform.EnvLayersDir))
 				h.AssertNil(t, os.Unsetenv(platform.EnvLayoutDir))
 				h.AssertNil(t, os.Unsetenv(platform.EnvLogLevel))
 				h.AssertNil(t, os.Unsetenv(platform.EnvOrderPath))
+				h.AssertNil(t, os.Unsetenv(platform.EnvNetworkBuildpacks))
+				h.AssertNil(t, os.Unsetenv(platform.EnvNormalizeOwnership))
 				h.AssertNil(t, os.Unsetenv(platform.EnvPlanPath))
 				h.AssertNil(t, os.Unsetenv(platform.EnvPlatformDir))
 				h.AssertNil(t, os.Unsetenv(platform.EnvPreviousImage))
@@ -125,9 +201,17 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 				h.AssertNil(t, os.Unsetenv(platform.EnvReportPath))
 				h.AssertNil(t, os.Unsetenv(platform.EnvRunImage))
 				h.AssertNil(t, os.Unsetenv(platform.EnvRunPath))
+				h.AssertNil(t, os.Unsetenv(platform.EnvSkipAnalyze))
+				h.AssertNil(t, os.Unsetenv(platform.EnvSkipBuild))
+				h.AssertNil(t, os.Unsetenv(platform.EnvSkipDetect))
 				h.AssertNil(t, os.Unsetenv(platform.EnvSkipLayers))
+				h.AssertNil(t, os.Unsetenv(platform.EnvSquashBuildpacks))
+				h.AssertNil(t, os.Unsetenv(platform.EnvSquashLayersBelow))
 				h.AssertNil(t, os.Unsetenv(platform.EnvStackPath))
+				h.AssertNil(t, os.Unsetenv(platform.EnvStripSetuid))
+				h.AssertNil(t, os.Unsetenv(platform.EnvLenientExtraction))
 				h.AssertNil(t, os.Unsetenv(platform.EnvUID))
+				h.AssertNil(t, os.Unsetenv(platform.EnvUmask))
 				h.AssertNil(t, os.Unsetenv(platform.EnvUseDaemon))
 				h.AssertNil(t, os.Unsetenv(platform.EnvUseLayout))
 			})
@@ -138,8 +222,13 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 				h.AssertEq(t, inputs.AdditionalTags, str.Slice(nil))
 				h.AssertEq(t, inputs.AnalyzedPath, "some-analyzed-path")
 				h.AssertEq(t, inputs.AppDir, "some-app-dir")
+				h.AssertEq(t, inputs.AppSymlinks, "follow")
 				h.AssertEq(t, inputs.BuildConfigDir, "some-build-config-dir")
 				h.AssertEq(t, inputs.BuildImageRef, "some-build-image")
+				h.AssertEq(t, inputs.BuildLogPath, "some-build-log-path")
+				h.AssertEq(t, inputs.ArtifactsDir, "some-artifacts-dir")
+				h.AssertEq(t, inputs.SupportBundlePath, "some-support-bundle-path")
+				h.AssertEq(t, inputs.DebugImageRef, "some-debug-image")
 				h.AssertEq(t, inputs.BuildpacksDir, "some-buildpacks-dir")
 				h.AssertEq(t, inputs.CacheDir, "some-cache-dir")
 				h.AssertEq(t, inputs.CacheImageRef, "some-cache-image")
@@ -148,11 +237,19 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 				h.AssertEq(t, inputs.ExtendKind, "run")
 				h.AssertEq(t, inputs.ExtensionsDir, "some-extensions-dir")
 				h.AssertEq(t, inputs.ForceRebase, true)
+				h.AssertEq(t, inputs.CleanDryRun, true)
+				h.AssertEq(t, inputs.ExplainEnv, true)
+				h.AssertEq(t, inputs.ConstraintsPath, "some-constraints-path")
+				h.AssertEq(t, inputs.TrustPolicyPath, "some-trust-policy-path")
 				h.AssertEq(t, inputs.GID, 5678)
 				h.AssertEq(t, inputs.GeneratedDir, "some-generated-dir")
 				h.AssertEq(t, inputs.GroupPath, "some-group-path")
+				h.AssertEq(t, inputs.CacheLockTimeout, 30*time.Second)
 				h.AssertEq(t, inputs.KanikoCacheTTL, 1*time.Hour)
+				h.AssertEq(t, inputs.KanikoDir, "some-kaniko-dir")
+				h.AssertEq(t, inputs.XattrPrefixes(), []string{"security.capability", "user."})
 				h.AssertEq(t, inputs.LaunchCacheDir, "some-launch-cache-dir")
+				h.AssertEq(t, inputs.LaunchCacheImageRef, "some-launch-cache-image")
 				h.AssertEq(t, inputs.LauncherPath, platform.DefaultLauncherPath)
 				h.AssertEq(t, inputs.LauncherSBOMDir, platform.DefaultBuildpacksioSBOMDir)
 				h.AssertEq(t, inputs.LayersDir, "some-layers-dir")
@@ -160,6 +257,8 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 				h.AssertEq(t, inputs.LogLevel, "debug")
 				h.AssertEq(t, inputs.OrderPath, "some-order-path")
 				h.AssertEq(t, inputs.OutputImageRef, "")
+				h.AssertEq(t, inputs.NetworkBuildpackIDs(), []string{"some/fetching-bp"})
+				h.AssertEq(t, inputs.NormalizeOwnership, true)
 				h.AssertEq(t, inputs.PlanPath, "some-plan-path")
 				h.AssertEq(t, inputs.PlatformAPI, platformAPI) // from constructor
 				h.AssertEq(t, inputs.PlatformDir, "some-platform-dir")
@@ -167,9 +266,17 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 				h.AssertEq(t, inputs.ReportPath, "some-report-path")
 				h.AssertEq(t, inputs.RunImageRef, "some-run-image")
 				h.AssertEq(t, inputs.RunPath, "some-run-path")
+				h.AssertEq(t, inputs.SkipAnalyze, true)
+				h.AssertEq(t, inputs.SkipBuild, true)
+				h.AssertEq(t, inputs.SkipDetect, true)
 				h.AssertEq(t, inputs.SkipLayers, true)
+				h.AssertEq(t, inputs.SquashBuildpackIDs(), []string{"some/bp", "other/bp"})
+				h.AssertEq(t, inputs.SquashLayersBelow, 1048576)
 				h.AssertEq(t, inputs.StackPath, "some-stack-path")
+				h.AssertEq(t, inputs.StripSetuid, true)
+				h.AssertEq(t, inputs.LenientExtraction, true)
 				h.AssertEq(t, inputs.UID, 1234)
+				h.AssertEq(t, inputs.Umask, "022")
 				h.AssertEq(t, inputs.UseDaemon, true)
 				h.AssertEq(t, inputs.UseLayout, true)
 			})
@@ -187,6 +294,21 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 			h.AssertEq(t, inputs.ReportPath, filepath.Join("<layers>", "report.toml"))
 		})
 
+		when("the generate dry run directory is set", func() {
+			it.Before(func() {
+				h.AssertNil(t, os.Setenv(platform.EnvGenerateDryRunDir, "some-dry-run-dir"))
+			})
+
+			it.After(func() {
+				h.AssertNil(t, os.Unsetenv(platform.EnvGenerateDryRunDir))
+			})
+
+			it("is only read for Platform API 0.10+", func() {
+				h.AssertEq(t, platform.NewLifecycleInputs(api.MustParse("0.10")).GenerateDryRunDir, "some-dry-run-dir")
+				h.AssertEq(t, platform.NewLifecycleInputs(api.MustParse("0.9")).GenerateDryRunDir, "")
+			})
+		})
+
 		when("Platform API = 0.5", func() {
 			platformAPI = api.MustParse("0.5")
 
@@ -227,6 +349,13 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#SplitProcessTypes", func() {
+		it("splits the comma-separated list, ignoring empty values", func() {
+			h.AssertEq(t, platform.SplitProcessTypes("web, worker,,*"), []string{"web", "worker", "*"})
+			h.AssertEq(t, len(platform.SplitProcessTypes("")), 0)
+		})
+	})
+
 	when("#UpdatePlaceholderPaths", func() {
 		var (
 			platformAPI = api.Platform.Latest()
@@ -310,6 +439,409 @@ func testLifecycleInputs(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#ValidateExtendBackend", func() {
+		var (
+			inputs     *platform.LifecycleInputs
+			logHandler *memory.Handler
+			logger     llog.Logger
+		)
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+			logHandler = memory.New()
+			logger = &log.Logger{Handler: logHandler}
+		})
+
+		when("the backend is supported", func() {
+			it("does not return an error", func() {
+				for _, backend := range []string{"kaniko", "buildkit"} {
+					inputs.ExtendBackend = backend
+					h.AssertNil(t, platform.ValidateExtendBackend(inputs, logger))
+				}
+				h.AssertEq(t, len(logHandler.Entries), 0)
+			})
+		})
+
+		when("secrets are provided", func() {
+			it.Before(func() {
+				inputs.ExtendSecretsDir = "some-secrets-dir"
+			})
+
+			when("the backend is kaniko", func() {
+				it("warns that secrets are ignored", func() {
+					inputs.ExtendBackend = "kaniko"
+					h.AssertNil(t, platform.ValidateExtendBackend(inputs, logger))
+					h.AssertEq(t, len(logHandler.Entries), 1)
+					h.AssertEq(t, logHandler.Entries[0].Level, log.WarnLevel)
+					h.AssertEq(t, logHandler.Entries[0].Message, platform.MsgIgnoringExtendSecrets)
+				})
+			})
+
+			when("the backend is buildkit", func() {
+				it("does not warn", func() {
+					inputs.ExtendBackend = "buildkit"
+					h.AssertNil(t, platform.ValidateExtendBackend(inputs, logger))
+					h.AssertEq(t, len(logHandler.Entries), 0)
+				})
+			})
+		})
+
+		when("parallel extension is requested", func() {
+			it.Before(func() {
+				inputs.ExtendParallel = true
+			})
+
+			when("the backend is kaniko", func() {
+				it("warns and disables parallel extension", func() {
+					inputs.ExtendBackend = "kaniko"
+					h.AssertNil(t, platform.ValidateExtendBackend(inputs, logger))
+					h.AssertEq(t, inputs.ExtendParallel, false)
+					h.AssertEq(t, len(logHandler.Entries), 1)
+					h.AssertEq(t, logHandler.Entries[0].Message, platform.MsgIgnoringExtendParallel)
+				})
+			})
+
+			when("the backend is buildkit", func() {
+				it("keeps parallel extension enabled", func() {
+					inputs.ExtendBackend = "buildkit"
+					h.AssertNil(t, platform.ValidateExtendBackend(inputs, logger))
+					h.AssertEq(t, inputs.ExtendParallel, true)
+					h.AssertEq(t, len(logHandler.Entries), 0)
+				})
+			})
+		})
+
+		when("rootless extension is requested", func() {
+			it.Before(func() {
+				inputs.ExtendRootless = true
+			})
+
+			when("the backend is kaniko", func() {
+				it("errors", func() {
+					inputs.ExtendBackend = "kaniko"
+					err := platform.ValidateExtendBackend(inputs, logger)
+					h.AssertError(t, err, platform.ErrExtendRootlessUnsupported)
+				})
+			})
+
+			when("the backend is buildkit", func() {
+				it("does not error", func() {
+					inputs.ExtendBackend = "buildkit"
+					h.AssertNil(t, platform.ValidateExtendBackend(inputs, logger))
+				})
+			})
+		})
+
+		when("the backend is unsupported", func() {
+			it("errors", func() {
+				inputs.ExtendBackend = "some-backend"
+				err := platform.ValidateExtendBackend(inputs, logger)
+				h.AssertError(t, err, "unsupported extend backend 'some-backend'")
+			})
+		})
+	})
+
+	when("#ValidateSBOMValidation", func() {
+		var inputs *platform.LifecycleInputs
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+		})
+
+		it("accepts the supported modes", func() {
+			for _, mode := range []string{"fail", "off", "warn"} {
+				inputs.SBOMValidation = mode
+				h.AssertNil(t, platform.ValidateSBOMValidation(inputs, nil))
+			}
+		})
+
+		it("errors for unsupported modes", func() {
+			inputs.SBOMValidation = "some-mode"
+			err := platform.ValidateSBOMValidation(inputs, nil)
+			h.AssertError(t, err, "unsupported SBOM validation mode 'some-mode'")
+		})
+	})
+
+	when("#ValidateTrustPolicy", func() {
+		var inputs *platform.LifecycleInputs
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+			inputs.TrustPolicyPath = "some-trust-policy-path"
+		})
+
+		it("accepts a trust policy for images in a registry", func() {
+			h.AssertNil(t, platform.ValidateTrustPolicy(inputs, nil))
+		})
+
+		it("errors for images in a daemon or a layout", func() {
+			inputs.UseDaemon = true
+			h.AssertError(t, platform.ValidateTrustPolicy(inputs, nil), "-trust-policy is only supported for images in a registry")
+			inputs.UseDaemon, inputs.UseLayout = false, true
+			h.AssertError(t, platform.ValidateTrustPolicy(inputs, nil), "-trust-policy is only supported for images in a registry")
+		})
+	})
+
+	when("#ValidateAppSymlinks", func() {
+		var inputs *platform.LifecycleInputs
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+		})
+
+		it("accepts the supported policies", func() {
+			for _, policy := range []string{"error", "follow", "preserve"} {
+				inputs.AppSymlinks = policy
+				h.AssertNil(t, platform.ValidateAppSymlinks(inputs, nil))
+			}
+		})
+
+		it("errors for unsupported policies", func() {
+			inputs.AppSymlinks = "some-policy"
+			err := platform.ValidateAppSymlinks(inputs, nil)
+			h.AssertError(t, err, "unsupported app symlink policy 'some-policy'")
+		})
+	})
+
+	when("#ValidateAppSourceDir", func() {
+		var inputs *platform.LifecycleInputs
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+			inputs.AppDir = filepath.Join("some", "workspace")
+		})
+
+		it("accepts a separate app source directory", func() {
+			h.AssertNil(t, platform.ValidateAppSourceDir(inputs, nil))
+			inputs.AppSourceDir = filepath.Join("some", "workspace-source")
+			h.AssertNil(t, platform.ValidateAppSourceDir(inputs, nil))
+		})
+
+		it("errors for app source directories that overlap the app directory", func() {
+			for _, dir := range []string{
+				filepath.Join("some", "workspace"),
+				filepath.Join("some", "workspace", "source"),
+				"some",
+			} {
+				inputs.AppSourceDir = dir
+				err := platform.ValidateAppSourceDir(inputs, nil)
+				h.AssertError(t, err, "must not contain, or be within, the app directory")
+			}
+		})
+	})
+
+	when("#ValidateSBOMCompression", func() {
+		var inputs *platform.LifecycleInputs
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+		})
+
+		it("accepts the supported compressions", func() {
+			for _, compression := range []string{"gzip", "none"} {
+				inputs.SBOMCompression = compression
+				h.AssertNil(t, platform.ValidateSBOMCompression(inputs, nil))
+			}
+		})
+
+		it("errors for unsupported compressions", func() {
+			inputs.SBOMCompression = "zstd"
+			err := platform.ValidateSBOMCompression(inputs, nil)
+			h.AssertError(t, err, "unsupported SBOM compression 'zstd'")
+		})
+	})
+
+	when("#ValidateLayerCompression", func() {
+		var inputs *platform.LifecycleInputs
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+		})
+
+		it("accepts auto and the gzip levels", func() {
+			for _, compression := range []string{"auto", "0", "6", "9"} {
+				inputs.LayerCompression = compression
+				h.AssertNil(t, platform.ValidateLayerCompression(inputs, nil))
+			}
+		})
+
+		it("errors for unsupported values", func() {
+			for _, compression := range []string{"10", "-1", "zstd"} {
+				inputs.LayerCompression = compression
+				err := platform.ValidateLayerCompression(inputs, nil)
+				h.AssertError(t, err, "unsupported layer compression '"+compression+"'")
+			}
+		})
+	})
+
+	when("#ValidateDigestAlgorithm", func() {
+		var inputs *platform.LifecycleInputs
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+		})
+
+		it("accepts sha256", func() {
+			h.AssertNil(t, platform.ValidateDigestAlgorithm(inputs, nil))
+			h.AssertEq(t, inputs.CacheDigestAlgorithm().Name, "sha256")
+		})
+
+		it("accepts other algorithms with a cache directory", func() {
+			inputs.DigestAlgorithm = "sha512"
+			inputs.CacheDir = "some-cache-dir"
+			h.AssertNil(t, platform.ValidateDigestAlgorithm(inputs, nil))
+			h.AssertEq(t, inputs.CacheDigestAlgorithm().Name, "sha512")
+		})
+
+		it("errors for other algorithms with a cache image", func() {
+			inputs.DigestAlgorithm = "sha512"
+			inputs.CacheImageRef = "some-cache-image"
+			err := platform.ValidateDigestAlgorithm(inputs, nil)
+			h.AssertError(t, err, "digest algorithm 'sha512' is only supported with a cache directory")
+		})
+
+		it("errors for unsupported algorithms", func() {
+			inputs.DigestAlgorithm = "md5"
+			err := platform.ValidateDigestAlgorithm(inputs, nil)
+			h.AssertError(t, err, "unsupported digest algorithm 'md5'; supported algorithms are: sha256, sha512")
+		})
+	})
+
+	when("#ValidateBulkRebase", func() {
+		var inputs *platform.LifecycleInputs
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+			inputs.RebaseImagesPath = "some-images-path"
+			inputs.RunImageRef = "some-run-image"
+		})
+
+		it("accepts a run image and no image arguments", func() {
+			h.AssertEq(t, inputs.RebaseParallelism, platform.DefaultRebaseParallelism)
+			h.AssertNil(t, platform.ValidateBulkRebase(inputs, nil))
+		})
+
+		it("requires the run image", func() {
+			inputs.RunImageRef = ""
+			h.AssertError(t, platform.ValidateBulkRebase(inputs, nil), "-run-image is required when rebasing a list of images")
+		})
+
+		it("errors for image arguments", func() {
+			inputs.OutputImageRef = "some-app-image"
+			h.AssertError(t, platform.ValidateBulkRebase(inputs, nil), "image arguments and -previous-image are not supported")
+		})
+
+		it("errors for images in a daemon", func() {
+			inputs.UseDaemon = true
+			h.AssertError(t, platform.ValidateBulkRebase(inputs, nil), "only supported for images in a registry")
+		})
+
+		it("errors for an invalid parallelism", func() {
+			inputs.RebaseParallelism = 0
+			h.AssertError(t, platform.ValidateBulkRebase(inputs, nil), "invalid rebase parallelism 0")
+		})
+	})
+
+	when("#ValidateApps", func() {
+		var inputs *platform.LifecycleInputs
+
+		it.Before(func() {
+			inputs = platform.NewLifecycleInputs(api.Platform.Latest())
+			inputs.AppsPath = "some-apps-path"
+		})
+
+		it("accepts inputs without images", func() {
+			inputs.CacheDir = "some-cache-dir"
+			h.AssertNil(t, platform.ValidateApps(inputs, nil))
+		})
+
+		it("errors for images that are provided in apps.toml", func() {
+			inputs.OutputImageRef = "some-app-image"
+			h.AssertError(t, platform.ValidateApps(inputs, nil), "image arguments, -tag and -previous-image are not supported with -apps")
+
+			inputs.OutputImageRef = ""
+			inputs.PreviousImageRef = "some-previous-image"
+			h.AssertError(t, platform.ValidateApps(inputs, nil), "image arguments, -tag and -previous-image are not supported with -apps")
+		})
+
+		it("accepts cache images, which are shared by the apps", func() {
+			inputs.CacheImageRef = "some-cache-image"
+			inputs.LaunchCacheImageRef = "some-launch-cache-image"
+			h.AssertNil(t, platform.ValidateApps(inputs, nil))
+		})
+	})
+
+	when("#ValidateCreatorSkips", func() {
+		it("requires skipping detection to skip the build", func() {
+			inputs := platform.NewLifecycleInputs(api.Platform.Latest())
+			inputs.SkipAnalyze = true
+			h.AssertNil(t, platform.ValidateCreatorSkips(inputs, nil))
+
+			inputs.SkipBuild = true
+			h.AssertError(t, platform.ValidateCreatorSkips(inputs, nil), "-skip-build requires -skip-detect")
+
+			inputs.SkipDetect = true
+			h.AssertNil(t, platform.ValidateCreatorSkips(inputs, nil))
+		})
+	})
+
+	when("#ValidateUmask", func() {
+		it("accepts octal umasks", func() {
+			inputs := platform.NewLifecycleInputs(api.Platform.Latest())
+			h.AssertNil(t, platform.ValidateUmask(inputs, nil))
+			_, ok := inputs.UmaskValue()
+			h.AssertEq(t, ok, false)
+
+			inputs.Umask = "027"
+			h.AssertNil(t, platform.ValidateUmask(inputs, nil))
+			mask, ok := inputs.UmaskValue()
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, mask, 0027)
+		})
+
+		it("errors for invalid umasks", func() {
+			inputs := platform.NewLifecycleInputs(api.Platform.Latest())
+			for _, umask := range []string{"abc", "089", "1777"} {
+				inputs.Umask = umask
+				h.AssertError(t, platform.ValidateUmask(inputs, nil), "invalid umask '"+umask+"'")
+			}
+		})
+	})
+
+	when("#ValidatePreserveXattrs", func() {
+		it("accepts prefixes within known namespaces", func() {
+			inputs := platform.NewLifecycleInputs(api.Platform.Latest())
+			inputs.PreserveXattrs = "security.capability,user.,trusted.overlay"
+			h.AssertNil(t, platform.ValidatePreserveXattrs(inputs, nil))
+		})
+
+		it("accepts none", func() {
+			inputs := platform.NewLifecycleInputs(api.Platform.Latest())
+			inputs.PreserveXattrs = "none"
+			h.AssertNil(t, platform.ValidatePreserveXattrs(inputs, nil))
+			h.AssertEq(t, len(inputs.XattrPrefixes()), 0)
+		})
+
+		it("errors for unknown namespaces", func() {
+			inputs := platform.NewLifecycleInputs(api.Platform.Latest())
+			inputs.PreserveXattrs = "security.capability,capability"
+			h.AssertError(t, platform.ValidatePreserveXattrs(inputs, nil), "invalid extended attribute prefix 'capability'")
+		})
+	})
+
+	when("#ValidateRebaseSnapshot", func() {
+		it("errors for images in a daemon", func() {
+			inputs := platform.NewLifecycleInputs(api.Platform.Latest())
+			h.AssertNil(t, platform.ValidateRebaseSnapshot(inputs, nil))
+
+			inputs.RebaseSnapshot = true
+			h.AssertNil(t, platform.ValidateRebaseSnapshot(inputs, nil))
+
+			inputs.UseDaemon = true
+			h.AssertError(t, platform.ValidateRebaseSnapshot(inputs, nil), "-snapshot is only supported for images in a registry")
+		})
+	})
+
 	when("#ValidateSameRegistry", func() {
 		when("multiple registries are provided", func() {
 			it("errors as unsupported", func() {
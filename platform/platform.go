@@ -1,7 +1,13 @@
 package platform
 
 import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+
 	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/launch"
 )
 
 type LifecyclePhase int
@@ -15,6 +21,15 @@ const (
 	Export
 	Create
 	Rebase
+	Clean
+)
+
+var (
+	_ cmd.FailureReporter    = &Platform{}
+	_ cmd.DeprecationTracker = &Platform{}
+	_ cmd.RedactionTracker   = &Platform{}
+	_ cmd.ArtifactsTracker   = &Platform{}
+	_ cmd.SupportBundler     = &Platform{}
 )
 
 // Platform holds lifecycle inputs and outputs for a given Platform API version and lifecycle phase.
@@ -34,3 +49,105 @@ func NewPlatformFor(platformAPI string) *Platform {
 func (p *Platform) API() *api.Version {
 	return p.PlatformAPI
 }
+
+// Inputs returns the lifecycle inputs, e.g., so that the flags common to all commands can set them.
+func (p *Platform) Inputs() *LifecycleInputs {
+	return p.LifecycleInputs
+}
+
+// FailureFile returns the location of the failure file, or an empty string if there is no failure file.
+// Inputs may not have been resolved when a phase fails while parsing its arguments,
+// so the layers directory placeholder is replaced here.
+func (p *Platform) FailureFile() string {
+	if !isPlaceholder(p.FailurePath) {
+		return p.FailurePath
+	}
+	if p.LayersDir == "" {
+		return ""
+	}
+	return strings.Replace(p.FailurePath, PlaceholderLayers, p.LayersDir, 1)
+}
+
+// UsageFile returns the location of the file used to share resource usage between phases,
+// or an empty string if the layers directory is not known.
+func (p *Platform) UsageFile() string {
+	if p.LayersDir == "" {
+		return ""
+	}
+	return filepath.Join(p.LayersDir, DefaultUsageFile)
+}
+
+// RedactionsFile returns the location of the file used to share the counts of redacted values between phases,
+// or an empty string if the layers directory is not known.
+func (p *Platform) RedactionsFile() string {
+	if p.LayersDir == "" {
+		return ""
+	}
+	return filepath.Join(p.LayersDir, DefaultRedactionsFile)
+}
+
+// DeprecationsFile returns the location of the file used to share deprecation notices between phases,
+// or an empty string if the layers directory is not known.
+func (p *Platform) DeprecationsFile() string {
+	if p.LayersDir == "" {
+		return ""
+	}
+	return filepath.Join(p.LayersDir, DefaultDeprecationsFile)
+}
+
+// ArtifactFiles returns the directory where the files generated by a phase are copied, and the files that may have been generated,
+// by the names used for their copies. The Dockerfiles (and config files) of image extensions are named after their location
+// in the generated directory, e.g., "generated-build-some_ext-Dockerfile".
+func (p *Platform) ArtifactFiles() (string, map[string]string) {
+	if p.ArtifactsDir == "" {
+		return "", nil
+	}
+	return p.ArtifactsDir, p.generatedFiles()
+}
+
+// SupportBundle returns the location of the support bundle written when a phase fails (or an empty string if it is not written),
+// the files provided to and generated by the phase by the names used in the bundle, and the references of the images used by the phase.
+func (p *Platform) SupportBundle() (string, map[string]string, []string) {
+	if p.SupportBundlePath == "" {
+		return "", nil, nil
+	}
+	files := p.generatedFiles()
+	for name, path := range map[string]string{
+		DefaultOrderFile: p.OrderPath,
+		"project.toml":   p.ProjectDescriptorPath,
+		"run.toml":       p.RunPath,
+		"stack.toml":     p.StackPath,
+	} {
+		files[name] = path
+	}
+	imageRefs := append([]string{p.BuildImageRef, p.CacheImageRef, p.OutputImageRef, p.PreviousImageRef, p.RunImageRef}, p.AdditionalTags...)
+	return p.SupportBundlePath, files, imageRefs
+}
+
+// generatedFiles returns the files that may have been generated by a phase, by the names used for their copies.
+func (p *Platform) generatedFiles() map[string]string {
+	artifacts := map[string]string{
+		DefaultAnalyzedFile:        p.AnalyzedPath,
+		DefaultGroupFile:           p.GroupPath,
+		DefaultPlanFile:            p.PlanPath,
+		DefaultProjectMetadataFile: p.ProjectMetadataPath,
+		DefaultReportFile:          p.ReportPath,
+	}
+	if p.LayersDir != "" {
+		artifacts["metadata.toml"] = launch.GetMetadataFilePath(p.LayersDir)
+	}
+	if p.GeneratedDir != "" {
+		_ = filepath.WalkDir(p.GeneratedDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(p.GeneratedDir, path)
+			if err != nil {
+				return nil
+			}
+			artifacts["generated-"+strings.ReplaceAll(rel, string(filepath.Separator), "-")] = path
+			return nil
+		})
+	}
+	return artifacts
+}
@@ -1,12 +1,14 @@
 package platform_test
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
 	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/cmd"
 	"github.com/buildpacks/lifecycle/platform"
 	h "github.com/buildpacks/lifecycle/testhelpers"
 )
@@ -54,5 +56,116 @@ func testPlatform(platformAPI *api.Version) func(t *testing.T, when spec.G, it s
 				})
 			})
 		})
+
+		when("#FailureFile", func() {
+			it("defaults to a file in the layers directory", func() {
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+				foundPlatform.LayersDir = "some-layers-dir"
+
+				h.AssertEq(t, foundPlatform.FailureFile(), filepath.Join("some-layers-dir", "failure.toml"))
+			})
+
+			it("is empty when there is no layers directory", func() {
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+				foundPlatform.LayersDir = ""
+
+				h.AssertEq(t, foundPlatform.FailureFile(), "")
+			})
+		})
+
+		when("#DeprecationsFile", func() {
+			it("is a file in the layers directory", func() {
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+				foundPlatform.LayersDir = "some-layers-dir"
+
+				h.AssertEq(t, foundPlatform.DeprecationsFile(), filepath.Join("some-layers-dir", "deprecations.toml"))
+			})
+
+			it("is empty when there is no layers directory", func() {
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+				foundPlatform.LayersDir = ""
+
+				h.AssertEq(t, foundPlatform.DeprecationsFile(), "")
+			})
+		})
+
+		when("#RedactionsFile", func() {
+			it("is a file in the layers directory", func() {
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+				foundPlatform.LayersDir = "some-layers-dir"
+
+				h.AssertEq(t, foundPlatform.RedactionsFile(), filepath.Join("some-layers-dir", "redactions.toml"))
+			})
+
+			it("is empty when there is no layers directory", func() {
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+				foundPlatform.LayersDir = ""
+
+				h.AssertEq(t, foundPlatform.RedactionsFile(), "")
+			})
+		})
+
+		when("#ArtifactFiles", func() {
+			it("lists the files generated by the phases", func() {
+				tmpDir := t.TempDir()
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+				foundPlatform.ArtifactsDir = "some-artifacts-dir"
+				foundPlatform.LayersDir = "some-layers-dir"
+				foundPlatform.GroupPath = "some-group-path"
+				foundPlatform.GeneratedDir = filepath.Join(tmpDir, "generated")
+				h.Mkdir(t, filepath.Join(tmpDir, "generated", "build", "some_ext"))
+				h.Mkfile(t, "FROM some-image", filepath.Join(tmpDir, "generated", "build", "some_ext", "Dockerfile"))
+
+				dir, files := foundPlatform.ArtifactFiles()
+				h.AssertEq(t, dir, "some-artifacts-dir")
+				h.AssertEq(t, files["group.toml"], "some-group-path")
+				h.AssertEq(t, files["metadata.toml"], "some-layers-dir/config/metadata.toml")
+				h.AssertEq(t, files["generated-build-some_ext-Dockerfile"], filepath.Join(tmpDir, "generated", "build", "some_ext", "Dockerfile"))
+			})
+
+			it("is empty when there is no artifacts directory", func() {
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+				foundPlatform.ArtifactsDir = ""
+
+				dir, files := foundPlatform.ArtifactFiles()
+				h.AssertEq(t, dir, "")
+				h.AssertEq(t, len(files), 0)
+			})
+		})
+
+		when("#SupportBundle", func() {
+			it("lists the files and images used by the phases", func() {
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+				foundPlatform.SupportBundlePath = "some-support-bundle-path"
+				foundPlatform.GroupPath = "some-group-path"
+				foundPlatform.OrderPath = "some-order-path"
+				foundPlatform.OutputImageRef = "some-registry.io/some-app"
+				foundPlatform.AdditionalTags = []string{"other-registry.io/some-app"}
+
+				path, files, imageRefs := foundPlatform.SupportBundle()
+				h.AssertEq(t, path, "some-support-bundle-path")
+				h.AssertEq(t, files["group.toml"], "some-group-path")
+				h.AssertEq(t, files["order.toml"], "some-order-path")
+				h.AssertContains(t, imageRefs, "some-registry.io/some-app", "other-registry.io/some-app")
+			})
+
+			it("is empty when there is no support bundle path", func() {
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+
+				path, files, _ := foundPlatform.SupportBundle()
+				h.AssertEq(t, path, "")
+				h.AssertEq(t, len(files), 0)
+			})
+		})
+
+		when("#ErrorClassFor", func() {
+			it("returns error classes for phase-specific exit codes", func() {
+				foundPlatform := platform.NewPlatformFor(platformAPI.String())
+
+				h.AssertEq(t, foundPlatform.ErrorClassFor(foundPlatform.CodeFor(platform.FailedDetect)), cmd.ErrorClassUser)
+				h.AssertEq(t, foundPlatform.ErrorClassFor(foundPlatform.CodeFor(platform.FailedBuildWithErrors)), cmd.ErrorClassBuildpack)
+				h.AssertEq(t, foundPlatform.ErrorClassFor(foundPlatform.CodeFor(platform.BuildError)), "")
+			})
+		})
 	}
 }
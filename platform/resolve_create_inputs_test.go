@@ -79,6 +79,21 @@ func testResolveCreateInputs(platformAPI string) func(t *testing.T, when spec.G,
 					})
 				})
 			})
+
+			when("apps are provided", func() {
+				it.Before(func() {
+					inputs.AppsPath = "some-apps-path"
+					inputs.OutputImageRef = ""
+					inputs.RunImageRef = ""
+				})
+
+				it("doesn't resolve the images", func() {
+					err := platform.ResolveInputs(platform.Create, inputs, logger)
+					h.AssertNil(t, err)
+					h.AssertEq(t, inputs.RunImageRef, "")
+					h.AssertEq(t, inputs.PreviousImageRef, "")
+				})
+			})
 		})
 
 		when("Platform API 0.7 to 0.11", func() {
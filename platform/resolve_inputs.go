@@ -2,10 +2,15 @@ package platform
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
 
+	"github.com/buildpacks/lifecycle/internal/network"
+	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform/files"
 )
@@ -18,11 +23,21 @@ var (
 	ErrRunImageUnsupported           = "-run-image is unsupported"
 	ErrImageUnsupported              = "-image is unsupported"
 	MsgIgnoringLaunchCache           = "Ignoring -launch-cache, only intended for use with -daemon"
+	MsgIgnoringLaunchCacheImage      = "Ignoring -launch-cache-image, only intended for use with -daemon"
+	MsgIgnoringExtendSecrets         = "Ignoring extend secrets, only supported when using the buildkit extend backend"
+	MsgIgnoringExtendParallel        = "Ignoring parallel extension, only supported when using the buildkit extend backend"
+	ErrExtendRootlessUnsupported     = "rootless extension is only supported when using the buildkit extend backend"
 )
 
+// ResolveInputs fills in default values for the inputs of the provided phase and validates them.
+// Every operation is run, and the errors are returned together in a ContractViolationError.
 func ResolveInputs(phase LifecyclePhase, i *LifecycleInputs, logger log.Logger) error {
+	return runOperations(resolveOperations(phase, i), i, logger)
+}
+
+func resolveOperations(phase LifecyclePhase, i *LifecycleInputs) []LifecycleInputsOperation {
 	// order of operations is important
-	ops := []LifecycleInputsOperation{UpdatePlaceholderPaths, ResolveAbsoluteDirPaths}
+	ops := []LifecycleInputsOperation{ValidateLifecycleConfig, UpdatePlaceholderPaths, ResolveAbsoluteDirPaths}
 	switch phase {
 	case Analyze:
 		if i.PlatformAPI.LessThan("0.7") {
@@ -30,27 +45,54 @@ func ResolveInputs(phase LifecyclePhase, i *LifecycleInputs, logger log.Logger)
 		}
 		ops = append(ops,
 			FillAnalyzeImages,
+			ValidateImageLock,
+			ValidateTrustPolicy,
 			ValidateOutputImageProvided,
 			CheckLaunchCache,
 			ValidateImageRefs,
 			ValidateTargetsAreSameRegistry,
 		)
 	case Build:
-		// nop
+		ops = append(ops, ValidateAppSymlinks, ValidateSBOMValidation, ValidateUmask)
 	case Create:
 		ops = append(ops,
-			FillCreateImages,
-			ValidateOutputImageProvided,
-			CheckCache,
-			CheckLaunchCache,
-			ValidateImageRefs,
-			ValidateTargetsAreSameRegistry,
+			ValidateAppSourceDir,
+			ValidateAppSymlinks,
+			ValidateSBOMValidation,
+			ValidateSBOMCompression,
+			ValidateLayerCompression,
+			ValidateDigestAlgorithm,
+			ValidateCreatorSkips,
+			ValidateUmask,
+			ValidatePreserveXattrs,
+			ValidateTrustPolicy,
 		)
+		if i.AppsPath != "" {
+			// the images and the cache are resolved with the inputs of each app
+			ops = append(ops, ValidateApps)
+		} else {
+			ops = append(ops,
+				FillCreateImages,
+				ValidateImageLock,
+				ValidateOutputImageProvided,
+				CheckCache,
+				CheckLaunchCache,
+				ValidateImageRefs,
+				ValidateTargetsAreSameRegistry,
+			)
+		}
 	case Detect:
-		// nop
+		ops = append(ops, ValidateAppSourceDir, ValidateAppSymlinks)
 	case Export:
 		ops = append(ops,
+			ValidateAppSymlinks,
+			ValidateSBOMCompression,
+			ValidateLayerCompression,
+			ValidateDigestAlgorithm,
+			ValidatePreserveXattrs,
 			FillExportRunImage,
+			ValidateImageLock,
+			ValidateTrustPolicy,
 			ValidateOutputImageProvided,
 			CheckCache,
 			CheckLaunchCache,
@@ -58,23 +100,42 @@ func ResolveInputs(phase LifecyclePhase, i *LifecycleInputs, logger log.Logger)
 			ValidateTargetsAreSameRegistry,
 		)
 	case Extend:
-		// nop
+		ops = append(ops, ValidateExtendBackend)
 	case Rebase:
+		ops = append(ops, ValidateRebaseRunImage, ValidateRebaseSnapshot, ValidateImageLock, ValidateTrustPolicy)
+		if i.RebaseImagesPath != "" {
+			ops = append(ops, ValidateBulkRebase)
+		} else {
+			ops = append(ops, ValidateOutputImageProvided)
+		}
 		ops = append(ops,
-			ValidateRebaseRunImage,
-			ValidateOutputImageProvided,
 			ValidateImageRefs,
 			ValidateTargetsAreSameRegistry,
 		)
 	case Restore:
-		ops = append(ops, CheckCache)
+		ops = append(ops, CheckCache, ValidateImageLock, ValidatePreserveXattrs)
 	}
 
-	var err error
+	return ops
+}
+
+// runOperations runs the provided operations in order, collecting the errors instead of stopping at the first one.
+// As operations may fail for the same reason (e.g., a missing file), repeated errors are reported once.
+func runOperations(ops []LifecycleInputsOperation, i *LifecycleInputs, logger log.Logger) error {
+	var (
+		violations []error
+		seen       = map[string]bool{}
+	)
 	for _, op := range ops {
-		if err = op(i, logger); err != nil {
-			return err
+		err := op(i, logger)
+		if err == nil || seen[err.Error()] {
+			continue
 		}
+		seen[err.Error()] = true
+		violations = append(violations, err)
+	}
+	if len(violations) > 0 {
+		return &ContractViolationError{Violations: violations}
 	}
 	return nil
 }
@@ -94,6 +155,9 @@ func CheckLaunchCache(i *LifecycleInputs, logger log.Logger) error {
 	if !i.UseDaemon && i.LaunchCacheDir != "" {
 		logger.Warn(MsgIgnoringLaunchCache)
 	}
+	if !i.UseDaemon && i.LaunchCacheImageRef != "" {
+		logger.Warn(MsgIgnoringLaunchCacheImage)
+	}
 	return nil
 }
 
@@ -115,7 +179,7 @@ func FillCreateImages(i *LifecycleInputs, logger log.Logger) error {
 		i.PreviousImageRef = i.OutputImageRef
 	}
 	switch {
-	case i.DeprecatedRunImageRef != "" && i.RunImageRef != os.Getenv(EnvRunImage):
+	case i.DeprecatedRunImageRef != "" && i.RunImageRef != Getenv(EnvRunImage):
 		return errors.New(ErrSupplyOnlyOneRunImage)
 	case i.DeprecatedRunImageRef != "":
 		i.RunImageRef = i.DeprecatedRunImageRef
@@ -131,7 +195,7 @@ func FillExportRunImage(i *LifecycleInputs, logger log.Logger) error {
 	supportsRunImageFlag := i.PlatformAPI.LessThan("0.7")
 	if supportsRunImageFlag {
 		switch {
-		case i.DeprecatedRunImageRef != "" && i.RunImageRef != os.Getenv(EnvRunImage):
+		case i.DeprecatedRunImageRef != "" && i.RunImageRef != Getenv(EnvRunImage):
 			return errors.New(ErrSupplyOnlyOneRunImage)
 		case i.RunImageRef != "":
 			return nil
@@ -143,7 +207,7 @@ func FillExportRunImage(i *LifecycleInputs, logger log.Logger) error {
 		}
 	} else {
 		switch {
-		case i.RunImageRef != "" && i.RunImageRef != os.Getenv(EnvRunImage):
+		case i.RunImageRef != "" && i.RunImageRef != Getenv(EnvRunImage):
 			return errors.New(ErrRunImageUnsupported)
 		case i.DeprecatedRunImageRef != "":
 			return errors.New(ErrImageUnsupported)
@@ -162,8 +226,8 @@ func FillExportRunImage(i *LifecycleInputs, logger log.Logger) error {
 }
 
 // fillRunImageFromRunTOMLIfNeeded updates the provided lifecycle inputs to include the run image from run.toml if the run image input it is missing.
-// When there are multiple images in run.toml, the first image is selected.
-// When there are registry mirrors for the selected image, the image with registry matching the output image is selected.
+// The run image is selected according to the selection policies in run.toml (see SelectRunImage),
+// and the selection is recorded in the inputs so that it can be written to analyzed.toml.
 func fillRunImageFromRunTOMLIfNeeded(i *LifecycleInputs, logger log.Logger) error {
 	if i.RunImageRef != "" {
 		return nil
@@ -176,11 +240,16 @@ func fillRunImageFromRunTOMLIfNeeded(i *LifecycleInputs, logger log.Logger) erro
 	if err != nil {
 		return err
 	}
-	if len(runMD.Images) == 0 {
-		return errors.New(ErrRunImageRequiredWhenNoRunMD)
+	var buildTarget files.TargetMetadata
+	if runMD.Selection.Policy == files.RunImagePolicyTarget {
+		buildTarget = GetBuildTarget(logger)
 	}
-	i.RunImageRef, err = BestRunImageMirrorFor(targetRegistry, runMD.Images[0], i.AccessChecker())
-	return err
+	i.RunImageRef, i.RunImageSelection, err = SelectRunImage(runMD, targetRegistry, buildTarget, i.AccessChecker())
+	if err != nil {
+		return err
+	}
+	logger.Debugf("Selected run image '%s' (%s)", i.RunImageRef, i.RunImageSelection.Reason)
+	return nil
 }
 
 // fillRunImageFromStackTOMLIfNeeded updates the provided lifecycle inputs to include the run image from stack.toml if the run image input it is missing.
@@ -223,6 +292,110 @@ func ValidateImageRefs(i *LifecycleInputs, _ log.Logger) error {
 	return nil
 }
 
+// ValidateImageLock ensures the provided base images are pinned in the image lock file, if one was provided.
+// The previous image and the cache image are written by the lifecycle and are therefore not required to be pinned.
+func ValidateImageLock(i *LifecycleInputs, _ log.Logger) error {
+	if i.UseDaemon || i.UseLayout {
+		return nil
+	}
+	for _, imageRef := range []string{i.BuildImageRef, i.RunImageRef} {
+		if imageRef == "" {
+			continue
+		}
+		if _, err := network.ResolveImage(imageRef); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateExtendBackend ensures the requested extender backend is supported.
+func ValidateExtendBackend(i *LifecycleInputs, logger log.Logger) error {
+	switch i.ExtendBackend {
+	case ExtendBackendKaniko:
+		if i.ExtendSecretsDir != "" {
+			logger.Warn(MsgIgnoringExtendSecrets)
+		}
+		if i.ExtendParallel {
+			logger.Warn(MsgIgnoringExtendParallel)
+			i.ExtendParallel = false
+		}
+		if i.ExtendRootless {
+			return errors.New(ErrExtendRootlessUnsupported)
+		}
+		return nil
+	case ExtendBackendBuildKit:
+		return nil
+	default:
+		return fmt.Errorf("unsupported extend backend '%s'; supported backends are: %s, %s", i.ExtendBackend, ExtendBackendKaniko, ExtendBackendBuildKit)
+	}
+}
+
+func ValidateAppSourceDir(i *LifecycleInputs, _ log.Logger) error {
+	if i.AppSourceDir == "" {
+		return nil
+	}
+	if isWithin(i.AppDir, i.AppSourceDir) || isWithin(i.AppSourceDir, i.AppDir) {
+		return fmt.Errorf("app source directory '%s' must not contain, or be within, the app directory '%s'", i.AppSourceDir, i.AppDir)
+	}
+	return nil
+}
+
+// isWithin returns true if path is dir, or a descendant of dir.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func ValidateAppSymlinks(i *LifecycleInputs, _ log.Logger) error {
+	switch i.AppSymlinks {
+	case AppSymlinksError, AppSymlinksFollow, AppSymlinksPreserve:
+		return nil
+	default:
+		return fmt.Errorf("unsupported app symlink policy '%s'; supported policies are: %s, %s, %s", i.AppSymlinks, AppSymlinksError, AppSymlinksFollow, AppSymlinksPreserve)
+	}
+}
+
+func ValidateSBOMValidation(i *LifecycleInputs, _ log.Logger) error {
+	switch i.SBOMValidation {
+	case SBOMValidationFail, SBOMValidationOff, SBOMValidationWarn:
+		return nil
+	default:
+		return fmt.Errorf("unsupported SBOM validation mode '%s'; supported modes are: %s, %s, %s", i.SBOMValidation, SBOMValidationFail, SBOMValidationOff, SBOMValidationWarn)
+	}
+}
+
+func ValidateSBOMCompression(i *LifecycleInputs, _ log.Logger) error {
+	switch i.SBOMCompression {
+	case SBOMCompressionGzip, SBOMCompressionNone:
+		return nil
+	default:
+		return fmt.Errorf("unsupported SBOM compression '%s'; supported compressions are: %s, %s", i.SBOMCompression, SBOMCompressionGzip, SBOMCompressionNone)
+	}
+}
+
+func ValidateLayerCompression(i *LifecycleInputs, _ log.Logger) error {
+	if i.LayerCompression == LayerCompressionAuto {
+		return nil
+	}
+	if level, err := strconv.Atoi(i.LayerCompression); err == nil && level >= 0 && level <= 9 {
+		return nil
+	}
+	return fmt.Errorf("unsupported layer compression '%s'; supported values are: %s, or a level from 0 to 9", i.LayerCompression, LayerCompressionAuto)
+}
+
+// ValidateDigestAlgorithm ensures that the digest algorithm is supported, and that it is sha256 when using a cache image,
+// as the digests of the layers of an image are its diffIDs.
+func ValidateDigestAlgorithm(i *LifecycleInputs, _ log.Logger) error {
+	if _, err := layers.DigestAlgorithmFor(i.DigestAlgorithm); err != nil {
+		return err
+	}
+	if i.DigestAlgorithm != layers.SHA256.Name && i.CacheImageRef != "" {
+		return fmt.Errorf("digest algorithm '%s' is only supported with a cache directory; the layers of a cache image require %s", i.DigestAlgorithm, layers.SHA256.Name)
+	}
+	return nil
+}
+
 func ValidateOutputImageProvided(i *LifecycleInputs, _ log.Logger) error {
 	if i.OutputImageRef == "" {
 		return errors.New(ErrOutputImageRequired)
@@ -230,9 +403,88 @@ func ValidateOutputImageProvided(i *LifecycleInputs, _ log.Logger) error {
 	return nil
 }
 
+// ValidateBulkRebase ensures that the inputs are consistent with rebasing the images listed at RebaseImagesPath,
+// which are all rebased onto the provided run image and saved under their own references.
+func ValidateBulkRebase(i *LifecycleInputs, _ log.Logger) error {
+	switch {
+	case i.UseDaemon:
+		return errors.New("rebasing a list of images is only supported for images in a registry")
+	case i.RunImageRef == "":
+		return errors.New("-run-image is required when rebasing a list of images")
+	case i.OutputImageRef != "" || len(i.AdditionalTags) > 0 || i.PreviousImageRef != "":
+		return errors.New("image arguments and -previous-image are not supported when rebasing a list of images")
+	case i.RebaseParallelism < 1:
+		return fmt.Errorf("invalid rebase parallelism %d; must be at least 1", i.RebaseParallelism)
+	default:
+		return nil
+	}
+}
+
+// ValidateApps ensures that the inputs that are provided for each app in apps.toml are not also provided to the creator.
+// The caches (if provided) are shared by the apps.
+func ValidateApps(i *LifecycleInputs, _ log.Logger) error {
+	if i.OutputImageRef != "" || len(i.AdditionalTags) > 0 || i.PreviousImageRef != "" {
+		return errors.New("image arguments, -tag and -previous-image are not supported with -apps; provide them for each app in apps.toml")
+	}
+	return nil
+}
+
+// ValidateCreatorSkips ensures that the creator can resume from the outputs of the skipped phases.
+func ValidateCreatorSkips(i *LifecycleInputs, _ log.Logger) error {
+	if i.SkipBuild && !i.SkipDetect {
+		return errors.New("-skip-build requires -skip-detect, as the exported group must be the group that was built")
+	}
+	return nil
+}
+
+// ValidateUmask ensures that the umask (if provided) is an octal permission mask.
+func ValidateUmask(i *LifecycleInputs, _ log.Logger) error {
+	if i.Umask == "" {
+		return nil
+	}
+	if mask, err := strconv.ParseUint(i.Umask, 8, 32); err != nil || mask > 0777 {
+		return fmt.Errorf("invalid umask '%s'; must be an octal value such as 022", i.Umask)
+	}
+	return nil
+}
+
+// xattrNamespaces are the namespaces of extended attributes on linux.
+var xattrNamespaces = []string{"security.", "system.", "trusted.", "user."}
+
+// ValidatePreserveXattrs ensures that the extended attributes to preserve are within a known namespace.
+func ValidatePreserveXattrs(i *LifecycleInputs, _ log.Logger) error {
+	for _, prefix := range i.XattrPrefixes() {
+		valid := false
+		for _, namespace := range xattrNamespaces {
+			if strings.HasPrefix(prefix, namespace) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid extended attribute prefix '%s'; must start with one of %s", prefix, strings.Join(xattrNamespaces, ", "))
+		}
+	}
+	return nil
+}
+
+func ValidateTrustPolicy(i *LifecycleInputs, _ log.Logger) error {
+	if i.TrustPolicyPath != "" && (i.UseDaemon || i.UseLayout) {
+		return errors.New("-trust-policy is only supported for images in a registry")
+	}
+	return nil
+}
+
+func ValidateRebaseSnapshot(i *LifecycleInputs, _ log.Logger) error {
+	if i.RebaseSnapshot && i.UseDaemon {
+		return errors.New("-snapshot is only supported for images in a registry")
+	}
+	return nil
+}
+
 func ValidateRebaseRunImage(i *LifecycleInputs, _ log.Logger) error {
 	switch {
-	case i.DeprecatedRunImageRef != "" && i.RunImageRef != os.Getenv(EnvRunImage):
+	case i.DeprecatedRunImageRef != "" && i.RunImageRef != Getenv(EnvRunImage):
 		return errors.New(ErrSupplyOnlyOneRunImage)
 	case i.DeprecatedRunImageRef != "":
 		i.RunImageRef = i.DeprecatedRunImageRef
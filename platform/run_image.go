@@ -3,13 +3,16 @@ package platform
 import (
 	"errors"
 	"fmt"
+	"runtime"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 
 	"github.com/buildpacks/lifecycle/auth"
 	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/internal/fsutil"
 	"github.com/buildpacks/lifecycle/launch"
+	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform/files"
 )
 
@@ -20,32 +23,48 @@ const (
 )
 
 func BestRunImageMirrorFor(targetRegistry string, runImageMD files.RunImageForExport, checkReadAccess CheckReadAccess) (string, error) {
+	ref, _, err := bestRunImageMirrorFor(targetRegistry, runImageMD, files.MirrorPolicyRegistry, checkReadAccess)
+	return ref, err
+}
+
+// bestRunImageMirrorFor returns the image or mirror to use for the provided run image, and the reason it was selected.
+func bestRunImageMirrorFor(targetRegistry string, runImageMD files.RunImageForExport, mirrorPolicy string, checkReadAccess CheckReadAccess) (string, string, error) {
 	var runImageMirrors []string
 	if runImageMD.Image == "" {
-		return "", errors.New("missing run image metadata")
+		return "", "", errors.New("missing run image metadata")
 	}
 	runImageMirrors = append(runImageMirrors, runImageMD.Image)
 	runImageMirrors = append(runImageMirrors, runImageMD.Mirrors...)
 
 	keychain, err := auth.DefaultKeychain(runImageMirrors...)
 	if err != nil {
-		return "", fmt.Errorf("unable to create keychain: %w", err)
+		return "", "", fmt.Errorf("unable to create keychain: %w", err)
 	}
 
 	// Try to select run image on the same registry as the target
-	runImageRef := byRegistry(targetRegistry, runImageMirrors, checkReadAccess, keychain)
-	if runImageRef != "" {
-		return runImageRef, nil
+	if mirrorPolicy != files.MirrorPolicyOrdered {
+		runImageRef := byRegistry(targetRegistry, runImageMirrors, checkReadAccess, keychain)
+		if runImageRef != "" {
+			return runImageRef, fmt.Sprintf("on the same registry as the output image (%s)", targetRegistry), nil
+		}
 	}
 
 	// Select the first run image we have access to
+	var accessErrs []error
 	for _, image := range runImageMirrors {
-		if ok, _ := checkReadAccess(image, keychain); ok {
-			return image, nil
+		ok, err := checkReadAccess(image, keychain)
+		if ok {
+			return image, "first accessible image or mirror", nil
+		}
+		if err != nil {
+			accessErrs = append(accessErrs, fmt.Errorf("checking read access to '%s': %w", image, err))
 		}
 	}
 
-	return "", errors.New("failed to find accessible run image")
+	if len(accessErrs) > 0 {
+		return "", "", fmt.Errorf("failed to find accessible run image: %w", errors.Join(accessErrs...))
+	}
+	return "", "", errors.New("failed to find accessible run image")
 }
 
 func byRegistry(reg string, images []string, checkReadAccess CheckReadAccess, keychain authn.Keychain) string {
@@ -63,6 +82,101 @@ func byRegistry(reg string, images []string, checkReadAccess CheckReadAccess, ke
 	return ""
 }
 
+// SelectRunImage selects a run image from run.toml according to the selection policies in run.toml,
+// returning the image or mirror to use and a record of the selection for analyzed.toml.
+//   - With the "first" image policy (the default), the first image is selected.
+//   - With the "target" image policy, the first image whose target matches the build target is selected;
+//     images that are not accessible are skipped, and the errors encountered are returned if no image is selected.
+//   - With the "registry" mirror policy (the default), the image or mirror on the same registry as the output image is preferred;
+//     otherwise the first accessible image or mirror is selected.
+func SelectRunImage(runMD files.Run, targetRegistry string, buildTarget files.TargetMetadata, checkReadAccess CheckReadAccess) (string, *files.RunImageSelection, error) {
+	if len(runMD.Images) == 0 {
+		return "", nil, errors.New(ErrRunImageRequiredWhenNoRunMD)
+	}
+	imagePolicy := runMD.Selection.Policy
+	if imagePolicy == "" {
+		imagePolicy = files.RunImagePolicyFirst
+	}
+	mirrorPolicy := runMD.Selection.Mirrors
+	if mirrorPolicy == "" {
+		mirrorPolicy = files.MirrorPolicyRegistry
+	}
+	if mirrorPolicy != files.MirrorPolicyRegistry && mirrorPolicy != files.MirrorPolicyOrdered {
+		return "", nil, fmt.Errorf("unsupported run image mirror policy '%s'", mirrorPolicy)
+	}
+
+	switch imagePolicy {
+	case files.RunImagePolicyFirst:
+		ref, mirrorReason, err := bestRunImageMirrorFor(targetRegistry, runMD.Images[0], mirrorPolicy, checkReadAccess)
+		if err != nil {
+			return "", nil, err
+		}
+		return ref, &files.RunImageSelection{
+			Index:  0,
+			Image:  runMD.Images[0].Image,
+			Policy: imagePolicy,
+			Reason: "first image in run.toml; " + mirrorReason,
+		}, nil
+	case files.RunImagePolicyTarget:
+		var errs []error
+		for idx, runImage := range runMD.Images {
+			if !RunTargetMatches(runImage.Target, buildTarget) {
+				continue
+			}
+			ref, mirrorReason, err := bestRunImageMirrorFor(targetRegistry, runImage, mirrorPolicy, checkReadAccess)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("run image '%s': %w", runImage.Image, err))
+				continue
+			}
+			imageReason := "matches the build target"
+			if runImage.Target == nil {
+				imageReason = "no target provided"
+			}
+			return ref, &files.RunImageSelection{
+				Index:  idx,
+				Image:  runImage.Image,
+				Policy: imagePolicy,
+				Reason: imageReason + "; " + mirrorReason,
+			}, nil
+		}
+		if len(errs) > 0 {
+			return "", nil, fmt.Errorf("failed to find accessible run image matching build target (%s): %w", buildTarget.String(), errors.Join(errs...))
+		}
+		return "", nil, fmt.Errorf("failed to find accessible run image matching build target (%s)", buildTarget.String())
+	default:
+		return "", nil, fmt.Errorf("unsupported run image selection policy '%s'", imagePolicy)
+	}
+}
+
+// RunTargetMatches returns true if the target of a run image in run.toml matches the build target.
+// Fields that are empty in either target are treated as wildcards, and a missing run image target matches any build target.
+func RunTargetMatches(runTarget *files.TargetMetadata, buildTarget files.TargetMetadata) bool {
+	if runTarget == nil {
+		return true
+	}
+	if !fieldMatches(runTarget.OS, buildTarget.OS) ||
+		!fieldMatches(runTarget.Arch, buildTarget.Arch) ||
+		!fieldMatches(runTarget.ArchVariant, buildTarget.ArchVariant) {
+		return false
+	}
+	if runTarget.Distribution == nil || buildTarget.Distribution == nil {
+		return true
+	}
+	return fieldMatches(runTarget.Distribution.Name, buildTarget.Distribution.Name) &&
+		fieldMatches(runTarget.Distribution.Version, buildTarget.Distribution.Version)
+}
+
+func fieldMatches(a, b string) bool {
+	return a == "" || b == "" || a == b
+}
+
+// GetBuildTarget returns the target of the build image that the lifecycle is running in.
+func GetBuildTarget(logger log.Logger) files.TargetMetadata {
+	tm := files.TargetMetadata{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	GetTargetOSFromFileSystem(&fsutil.Detect{}, &tm, logger)
+	return tm
+}
+
 // GetRunImageForExport takes platform inputs and returns run image information
 // for populating the io.buildpacks.lifecycle.metadata on the exported app image.
 // The run image information is read from:
@@ -1,6 +1,7 @@
 package platform_test
 
 import (
+	"fmt"
 	"path/filepath"
 	"testing"
 
@@ -237,4 +238,136 @@ func testRunImage(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 	})
+
+	when(".SelectRunImage", func() {
+		var (
+			runMD       files.Run
+			buildTarget = files.TargetMetadata{
+				OS:           "linux",
+				Arch:         "amd64",
+				Distribution: &files.OSDistribution{Name: "ubuntu", Version: "22.04"},
+			}
+			inaccessible       []string
+			checkInaccessibles = func(image string, _ authn.Keychain) (bool, error) {
+				for _, i := range inaccessible {
+					if i == image {
+						return false, nil
+					}
+				}
+				return true, nil
+			}
+		)
+
+		it.Before(func() {
+			inaccessible = nil
+			runMD = files.Run{Images: []files.RunImageForExport{
+				{
+					Image:   "first.com/org/arm",
+					Mirrors: []string{"gcr.io/org/arm"},
+					Target:  &files.TargetMetadata{OS: "linux", Arch: "arm64"},
+				},
+				{
+					Image:   "first.com/org/jammy",
+					Mirrors: []string{"gcr.io/org/jammy"},
+					Target:  &files.TargetMetadata{OS: "linux", Arch: "amd64", Distribution: &files.OSDistribution{Name: "ubuntu", Version: "22.04"}},
+				},
+				{
+					Image: "first.com/org/any",
+				},
+			}}
+		})
+
+		when("no policy is provided", func() {
+			it("selects the first image, preferring the mirror on the output image registry", func() {
+				ref, selection, err := platform.SelectRunImage(runMD, "gcr.io", buildTarget, checkInaccessibles)
+				h.AssertNil(t, err)
+				h.AssertEq(t, ref, "gcr.io/org/arm")
+				h.AssertEq(t, selection.Index, 0)
+				h.AssertEq(t, selection.Image, "first.com/org/arm")
+				h.AssertEq(t, selection.Policy, files.RunImagePolicyFirst)
+				h.AssertStringContains(t, selection.Reason, "first image in run.toml")
+				h.AssertStringContains(t, selection.Reason, "on the same registry as the output image (gcr.io)")
+			})
+		})
+
+		when("the mirror policy is ordered", func() {
+			it("selects the first accessible image or mirror", func() {
+				runMD.Selection.Mirrors = files.MirrorPolicyOrdered
+				inaccessible = []string{"first.com/org/arm"}
+
+				ref, selection, err := platform.SelectRunImage(runMD, "first.com", buildTarget, checkInaccessibles)
+				h.AssertNil(t, err)
+				h.AssertEq(t, ref, "gcr.io/org/arm")
+				h.AssertStringContains(t, selection.Reason, "first accessible image or mirror")
+			})
+		})
+
+		when("the policy is target", func() {
+			it.Before(func() {
+				runMD.Selection.Policy = files.RunImagePolicyTarget
+			})
+
+			it("selects the first image matching the build target", func() {
+				ref, selection, err := platform.SelectRunImage(runMD, "first.com", buildTarget, checkInaccessibles)
+				h.AssertNil(t, err)
+				h.AssertEq(t, ref, "first.com/org/jammy")
+				h.AssertEq(t, selection.Index, 1)
+				h.AssertEq(t, selection.Policy, files.RunImagePolicyTarget)
+				h.AssertStringContains(t, selection.Reason, "matches the build target")
+			})
+
+			it("skips images that are not accessible", func() {
+				inaccessible = []string{"first.com/org/jammy", "gcr.io/org/jammy"}
+
+				ref, selection, err := platform.SelectRunImage(runMD, "first.com", buildTarget, checkInaccessibles)
+				h.AssertNil(t, err)
+				h.AssertEq(t, ref, "first.com/org/any")
+				h.AssertEq(t, selection.Index, 2)
+				h.AssertStringContains(t, selection.Reason, "no target provided")
+			})
+
+			it("errors when no image matches", func() {
+				runMD.Images = runMD.Images[:1]
+
+				_, _, err := platform.SelectRunImage(runMD, "first.com", buildTarget, checkInaccessibles)
+				h.AssertError(t, err, "failed to find accessible run image matching build target")
+			})
+
+			it("returns the errors encountered when no image is accessible", func() {
+				checkErrors := func(image string, _ authn.Keychain) (bool, error) {
+					return false, fmt.Errorf("some-error for %s", image)
+				}
+
+				_, _, err := platform.SelectRunImage(runMD, "first.com", buildTarget, checkErrors)
+				h.AssertError(t, err, "failed to find accessible run image matching build target")
+				h.AssertError(t, err, "run image 'first.com/org/jammy': failed to find accessible run image: checking read access to 'first.com/org/jammy': some-error for first.com/org/jammy")
+				h.AssertError(t, err, "run image 'first.com/org/any'")
+			})
+		})
+
+		it("errors for unsupported policies", func() {
+			runMD.Selection.Policy = "some-policy"
+			_, _, err := platform.SelectRunImage(runMD, "first.com", buildTarget, checkInaccessibles)
+			h.AssertError(t, err, "unsupported run image selection policy 'some-policy'")
+
+			runMD.Selection = files.RunSelection{Mirrors: "some-policy"}
+			_, _, err = platform.SelectRunImage(runMD, "first.com", buildTarget, checkInaccessibles)
+			h.AssertError(t, err, "unsupported run image mirror policy 'some-policy'")
+		})
+	})
+
+	when(".RunTargetMatches", func() {
+		buildTarget := files.TargetMetadata{OS: "linux", Arch: "arm64", ArchVariant: "v8"}
+
+		it("treats empty fields as wildcards", func() {
+			h.AssertEq(t, platform.RunTargetMatches(nil, buildTarget), true)
+			h.AssertEq(t, platform.RunTargetMatches(&files.TargetMetadata{OS: "linux"}, buildTarget), true)
+			h.AssertEq(t, platform.RunTargetMatches(&files.TargetMetadata{OS: "linux", Arch: "arm64", Distribution: &files.OSDistribution{Name: "ubuntu"}}, buildTarget), true)
+		})
+
+		it("does not match different values", func() {
+			h.AssertEq(t, platform.RunTargetMatches(&files.TargetMetadata{OS: "windows"}, buildTarget), false)
+			h.AssertEq(t, platform.RunTargetMatches(&files.TargetMetadata{Arch: "arm64", ArchVariant: "v7"}, buildTarget), false)
+		})
+	})
 }
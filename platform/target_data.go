@@ -2,8 +2,10 @@ package platform
 
 import (
 	"github.com/buildpacks/imgutil"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 
 	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/internal/compat"
 	"github.com/buildpacks/lifecycle/internal/fsutil"
 	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform/files"
@@ -50,6 +52,9 @@ func GetTargetMetadata(fromImage imgutil.Image) (*files.TargetMetadata, error) {
 	distVersion, distVersionExists := labels[OSDistributionVersionLabel]
 	if distNameExists || distVersionExists {
 		tm.Distribution = &files.OSDistribution{Name: distName, Version: distVersion}
+	} else if stackTarget, ok := files.TargetForStackID(labels[StackIDLabel]); ok && stackTarget.OS == tm.OS && stackTarget.Arch == tm.Arch {
+		// images built for stacks do not have the distribution labels
+		tm.Distribution = stackTarget.Distribution
 	}
 	if id, exists := labels[TargetLabel]; exists {
 		tm.ID = id
@@ -58,6 +63,33 @@ func GetTargetMetadata(fromImage imgutil.Image) (*files.TargetMetadata, error) {
 	return &tm, nil
 }
 
+// GetTargetDistroFromImage populates the distribution of the target metadata from the os-release file of the image,
+// for images that do not have the distribution labels.
+// Only images whose layers can be read without saving them (i.e., remote images) are inspected.
+func GetTargetDistroFromImage(fromImage imgutil.Image, tm *files.TargetMetadata, logger log.Logger) {
+	if tm.Distribution != nil || (tm.OS != "" && tm.OS != "linux") {
+		return
+	}
+	underlying, ok := fromImage.(interface{ UnderlyingImage() v1.Image })
+	if !ok || underlying.UnderlyingImage() == nil {
+		logger.Debugf("Not reading the os-release file of image '%s', its layers cannot be read", fromImage.Name())
+		return
+	}
+	layers, err := underlying.UnderlyingImage().Layers()
+	if err != nil {
+		logger.Warnf("Encountered error trying to read the layers of image '%s': %s", fromImage.Name(), err.Error())
+		return
+	}
+	base, err := compat.Inspect(layers)
+	if err != nil {
+		logger.Warnf("Encountered error trying to read the os-release file of image '%s': %s", fromImage.Name(), err.Error())
+		return
+	}
+	if base.OSID != "" || base.OSVersionID != "" {
+		tm.Distribution = &files.OSDistribution{Name: base.OSID, Version: base.OSVersionID}
+	}
+}
+
 // GetTargetOSFromFileSystem populates the target metadata you pass in if the information is available
 // returns a boolean indicating whether it populated any data.
 func GetTargetOSFromFileSystem(d fsutil.Detector, tm *files.TargetMetadata, logger log.Logger) {
@@ -75,6 +107,12 @@ func GetTargetOSFromFileSystem(d fsutil.Detector, tm *files.TargetMetadata, logg
 	}
 }
 
+// BuildpackTarget returns the target used to select the executables of buildpacks providing them for several targets
+// (see buildpack.BpDescriptor.BinDir).
+func BuildpackTarget(tm files.TargetMetadata) buildpack.TargetMetadata {
+	return buildpack.TargetMetadata{OS: tm.OS, Arch: tm.Arch, ArchVariant: tm.ArchVariant}
+}
+
 // TargetSatisfiedForBuild treats optional fields (ArchVariant and Distributions) as wildcards if empty, returns true if all populated fields match
 func TargetSatisfiedForBuild(t files.TargetMetadata, o buildpack.TargetMetadata) bool {
 	if (o.Arch != "*" && t.Arch != o.Arch) || (o.OS != "*" && t.OS != o.OS) {
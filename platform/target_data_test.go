@@ -6,6 +6,7 @@ import (
 
 	"github.com/apex/log"
 	"github.com/apex/log/handlers/memory"
+	"github.com/buildpacks/imgutil/fakes"
 	"github.com/sclevine/spec"
 
 	"github.com/buildpacks/lifecycle/buildpack"
@@ -68,6 +69,37 @@ func testTargetData(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when(".GetTargetMetadata", func() {
+		var image *fakes.Image
+
+		it.Before(func() {
+			image = fakes.NewImage("some-image", "", nil)
+			h.AssertNil(t, image.SetLabel(platform.StackIDLabel, "io.buildpacks.stacks.jammy"))
+		})
+
+		it("translates the distribution from the stack of images without distribution labels", func() {
+			tm, err := platform.GetTargetMetadata(image)
+			h.AssertNil(t, err)
+			h.AssertEq(t, tm.Distribution, &files.OSDistribution{Name: "ubuntu", Version: "22.04"})
+		})
+
+		it("prefers the distribution labels", func() {
+			h.AssertNil(t, image.SetLabel(platform.OSDistributionNameLabel, "some-distro"))
+
+			tm, err := platform.GetTargetMetadata(image)
+			h.AssertNil(t, err)
+			h.AssertEq(t, tm.Distribution, &files.OSDistribution{Name: "some-distro"})
+		})
+
+		it("does not translate the stack of an image with another architecture", func() {
+			h.AssertNil(t, image.SetArchitecture("arm64"))
+
+			tm, err := platform.GetTargetMetadata(image)
+			h.AssertNil(t, err)
+			h.AssertNil(t, tm.Distribution)
+		})
+	})
+
 	when(".GetTargetOSFromFileSystem", func() {
 		it("populates appropriately", func() {
 			logr := &log.Logger{Handler: memory.New()}
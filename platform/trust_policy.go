@@ -0,0 +1,31 @@
+package platform
+
+import (
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/buildpacks/lifecycle/internal/trust"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform/files"
+)
+
+// VerifyBaseImage verifies that the base image at imageRef is signed by a key trusted by the policy,
+// and returns a reference to the verified digest of the image, so that a tag that is moved after verification is not used.
+func VerifyBaseImage(policy files.TrustPolicy, imageRef string, keychain authn.Keychain, logger log.Logger) (string, error) {
+	ref, err := name.ParseReference(imageRef, name.WeakValidation)
+	if err != nil {
+		return "", fmt.Errorf("parsing base image reference: %w", err)
+	}
+	rule, ok := policy.RuleFor(ref.Context().Name())
+	if !ok {
+		return "", fmt.Errorf("image '%s' does not match any rule of the trust policy", imageRef)
+	}
+	digest, err := trust.Verify(imageRef, rule.Keys, keychain)
+	if err != nil {
+		return "", err
+	}
+	logger.Infof("Verified signature of base image '%s'", imageRef)
+	return ref.Context().Digest(digest.String()).String(), nil
+}
@@ -0,0 +1,51 @@
+package priv
+
+import (
+	"context"
+	"runtime"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// compatClient smooths over the differences between daemons satisfying the Docker socket interface (e.g., docker, podman),
+// so that images can be read and exported the same way regardless of the daemon:
+//   - daemons that do not report their OS type are assumed to run on the host OS
+//   - image IDs are always returned with the digest algorithm (e.g., "sha256:")
+//   - daemons that do not implement the image history API are treated as returning no history
+type compatClient struct {
+	client.CommonAPIClient
+}
+
+func (c *compatClient) Info(ctx context.Context) (types.Info, error) {
+	info, err := c.CommonAPIClient.Info(ctx)
+	if err != nil {
+		return info, err
+	}
+	if info.OSType == "" {
+		info.OSType = runtime.GOOS
+	}
+	return info, nil
+}
+
+func (c *compatClient) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	inspect, raw, err := c.CommonAPIClient.ImageInspectWithRaw(ctx, imageID)
+	if err != nil {
+		return inspect, raw, err
+	}
+	if inspect.ID != "" && !strings.Contains(inspect.ID, ":") {
+		inspect.ID = "sha256:" + inspect.ID
+	}
+	return inspect, raw, nil
+}
+
+func (c *compatClient) ImageHistory(ctx context.Context, imageID string) ([]image.HistoryResponseItem, error) {
+	history, err := c.CommonAPIClient.ImageHistory(ctx, imageID)
+	if errdefs.IsNotImplemented(err) {
+		return nil, nil
+	}
+	return history, err
+}
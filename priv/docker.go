@@ -11,19 +11,21 @@ import (
 	"github.com/pkg/errors"
 )
 
+// EnvContainerHost is the socket of a Podman service (see `podman system service`), used if DOCKER_HOST is not set.
+const EnvContainerHost = "CONTAINER_HOST"
+
 // DockerClient constructs a client that can continue to talk to a root owned docker socket
 // * even after the process drops privileges
+// The daemon may be any daemon satisfying the Docker socket interface (e.g., docker, podman), see DockerHost.
 func DockerClient() (*client.Client, error) {
-	host := client.DefaultDockerHost
-	if envHost := os.Getenv("DOCKER_HOST"); envHost != "" {
-		host = envHost
-	}
+	host := DockerHost()
 	hostURL, err := url.Parse(host)
 	if err != nil {
 		return nil, err
 	}
 	opts := []client.Opt{
 		client.FromEnv,
+		client.WithHost(host),
 		client.WithAPIVersionNegotiation(),
 	}
 	if shouldConnectSock(hostURL) {
@@ -36,6 +38,35 @@ func DockerClient() (*client.Client, error) {
 	return client.NewClientWithOpts(opts...)
 }
 
+// DaemonClient is like DockerClient, but the returned client smooths over the differences between daemons
+// satisfying the Docker socket interface (e.g., docker, podman), so that images can be read and exported the same way.
+func DaemonClient() (client.CommonAPIClient, error) {
+	docker, err := DockerClient()
+	if err != nil {
+		return nil, err
+	}
+	return &compatClient{CommonAPIClient: docker}, nil
+}
+
+// DockerHost returns the daemon socket to use, from the following sources in order of precedence:
+// the DOCKER_HOST environment variable
+// the CONTAINER_HOST environment variable (Podman)
+// the default socket of Docker, rootful Podman or rootless Podman, whichever exists first
+// containerd (e.g., when using nerdctl) does not provide the Docker socket interface and is not supported.
+func DockerHost() string {
+	for _, env := range []string{"DOCKER_HOST", EnvContainerHost} {
+		if host := os.Getenv(env); host != "" {
+			return host
+		}
+	}
+	for _, path := range defaultSockets() {
+		if _, err := os.Stat(path); err == nil {
+			return "unix://" + path
+		}
+	}
+	return client.DefaultDockerHost
+}
+
 type unclosableConn struct {
 	net.Conn
 	inUse int32
@@ -0,0 +1,139 @@
+package priv_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/priv"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestDocker(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("daemon sockets are not supported on windows")
+	}
+	spec.Run(t, "Docker", testDocker, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testDocker(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir  string
+		origEnv = map[string]string{}
+	)
+
+	it.Before(func() {
+		var err error
+		// unix socket paths are limited in length
+		tmpDir, err = os.MkdirTemp("", "priv")
+		h.AssertNil(t, err)
+		for _, key := range []string{"DOCKER_HOST", "CONTAINER_HOST", "XDG_RUNTIME_DIR"} {
+			origEnv[key] = os.Getenv(key)
+			h.AssertNil(t, os.Unsetenv(key))
+		}
+	})
+
+	it.After(func() {
+		for key, val := range origEnv {
+			if val == "" {
+				h.AssertNil(t, os.Unsetenv(key))
+			} else {
+				h.AssertNil(t, os.Setenv(key, val))
+			}
+		}
+		h.AssertNil(t, os.RemoveAll(tmpDir))
+	})
+
+	skipIfDaemonSocketExists := func() {
+		for _, path := range []string{"/var/run/docker.sock", "/run/podman/podman.sock"} {
+			if _, err := os.Stat(path); err == nil {
+				t.Skipf("daemon socket '%s' exists", path)
+			}
+		}
+	}
+
+	when("#DockerHost", func() {
+		it("prefers DOCKER_HOST over CONTAINER_HOST", func() {
+			h.AssertNil(t, os.Setenv("CONTAINER_HOST", "unix:///some/podman.sock"))
+			host := priv.DockerHost()
+			h.AssertEq(t, host, "unix:///some/podman.sock")
+
+			h.AssertNil(t, os.Setenv("DOCKER_HOST", "unix:///some/docker.sock"))
+			host = priv.DockerHost()
+			h.AssertEq(t, host, "unix:///some/docker.sock")
+		})
+
+		it("finds the rootless podman socket", func() {
+			skipIfDaemonSocketExists()
+			h.AssertNil(t, os.Setenv("XDG_RUNTIME_DIR", tmpDir))
+			h.AssertNil(t, os.MkdirAll(filepath.Join(tmpDir, "podman"), 0755))
+			h.Mkfile(t, "", filepath.Join(tmpDir, "podman", "podman.sock"))
+
+			host := priv.DockerHost()
+			h.AssertEq(t, host, "unix://"+filepath.Join(tmpDir, "podman", "podman.sock"))
+		})
+	})
+
+	when("#DaemonClient", func() {
+		when("the daemon is podman", func() {
+			it.Before(func() {
+				listener, err := net.Listen("unix", filepath.Join(tmpDir, "podman.sock"))
+				h.AssertNil(t, err)
+				// a minimal podman service: the OS type is not reported, image IDs do not include the algorithm,
+				// and the image history API is not implemented
+				server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Api-Version", "1.41")
+					w.Header().Set("Libpod-Api-Version", "4.5.0")
+					w.Header().Set("Content-Type", "application/json")
+					switch {
+					case r.URL.Path == "/_ping":
+						_, _ = w.Write([]byte("OK"))
+					case strings.HasSuffix(r.URL.Path, "/info"):
+						_, _ = w.Write([]byte(`{"Name": "some-host"}`))
+					case strings.HasSuffix(r.URL.Path, "/images/some-image/json"):
+						_, _ = w.Write([]byte(`{"Id": "some-image-id"}`))
+					case strings.HasSuffix(r.URL.Path, "/images/some-image/history"):
+						w.WriteHeader(http.StatusNotImplemented)
+						_, _ = w.Write([]byte(`{"message": "not implemented"}`))
+					default:
+						w.WriteHeader(http.StatusNotFound)
+						_, _ = w.Write([]byte(`{"message": "not found"}`))
+					}
+				}))
+				server.Listener = listener
+				server.Start()
+				t.Cleanup(server.Close)
+				h.AssertNil(t, os.Setenv("CONTAINER_HOST", "unix://"+filepath.Join(tmpDir, "podman.sock")))
+			})
+
+			it("handles the differences from docker", func() {
+				docker, err := priv.DaemonClient()
+				h.AssertNil(t, err)
+
+				info, err := docker.Info(context.Background())
+				h.AssertNil(t, err)
+				h.AssertEq(t, info.OSType, runtime.GOOS)
+
+				inspect, _, err := docker.ImageInspectWithRaw(context.Background(), "some-image")
+				h.AssertNil(t, err)
+				h.AssertEq(t, inspect.ID, "sha256:some-image-id")
+
+				history, err := docker.ImageHistory(context.Background(), "some-image")
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(history), 0)
+
+				_, _, err = docker.ImageInspectWithRaw(context.Background(), "other-image")
+				h.AssertNotNil(t, err)
+			})
+		})
+	})
+}
@@ -0,0 +1,30 @@
+package priv
+
+import (
+	"fmt"
+	"os"
+)
+
+// CapabilityError is returned when the lifecycle needs a capability that the environment does not provide,
+// e.g., to chown a volume or to switch users when the lifecycle runs without root from the start.
+type CapabilityError struct {
+	// Operation is what the lifecycle was trying to do.
+	Operation string
+	// Reason is why the environment does not support it.
+	Reason string
+	// Remedy is what the platform can do to support it.
+	Remedy string
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("cannot %s: %s; %s", e.Operation, e.Reason, e.Remedy)
+}
+
+// RequiresRoot returns a CapabilityError for an operation that the lifecycle can only perform as root.
+func RequiresRoot(operation, remedy string) *CapabilityError {
+	return &CapabilityError{
+		Operation: operation,
+		Reason:    fmt.Sprintf("the lifecycle is running as user %d:%d without root", os.Getuid(), os.Getgid()),
+		Remedy:    remedy,
+	}
+}
@@ -6,9 +6,19 @@ package priv
 import (
 	"net/url"
 	"os"
+	"path/filepath"
 	"syscall"
 )
 
+// defaultSockets returns the default sockets of Docker, rootful Podman and rootless Podman, in order of precedence.
+func defaultSockets() []string {
+	sockets := []string{"/var/run/docker.sock", "/run/podman/podman.sock"}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		sockets = append(sockets, filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+	return sockets
+}
+
 // shouldConnectSock returns true if the docker host is a root owned unix domain socket
 func shouldConnectSock(host *url.URL) bool {
 	if host.Scheme != "unix" {
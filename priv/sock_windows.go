@@ -6,3 +6,8 @@ import "net/url"
 func shouldConnectSock(host *url.URL) bool {
 	return false
 }
+
+// defaultSockets returns nothing on windows, where the default docker host is a named pipe
+func defaultSockets() []string {
+	return nil
+}
@@ -1,15 +1,26 @@
 package priv
 
-import "os"
+import (
+	"os"
+	"syscall"
+)
 
 func EnsureOwner(uid, gid int, paths ...string) error {
 	return nil
 }
 
+func ChownAll(uid, gid int, paths ...string) error {
+	return nil
+}
+
 func IsPrivileged() bool {
 	return os.Getuid() == 0
 }
 
+func InUserNamespace() bool {
+	return false
+}
+
 func RunAs(uid, gid int) error {
 	return nil
 }
@@ -17,3 +28,7 @@ func RunAs(uid, gid int) error {
 func SetEnvironmentForUser(uid int) error {
 	return nil
 }
+
+func SetUmask(mask int) int {
+	return syscall.Umask(mask)
+}
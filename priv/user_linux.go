@@ -4,10 +4,13 @@
 package priv
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 )
 
@@ -25,13 +28,51 @@ func EnsureOwner(uid, gid int, paths ...string) error {
 			// if a dir has correct ownership, assume it's children do, for performance
 			continue
 		}
-		if err := recursiveEnsureOwner(p, uid, gid); err != nil {
+		if err := chown(p, uid, gid); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// ChownAll recursively chowns the provided paths, whether or not they are writable
+func ChownAll(uid, gid int, paths ...string) error {
+	for _, p := range paths {
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			continue
+		}
+		if err := chown(p, uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chown recursively chowns the provided path, returning a CapabilityError if the lifecycle is not able to.
+// Without root, the lifecycle can only chown files it owns to the user it runs as.
+func chown(path string, uid, gid int) error {
+	notPermitted := RequiresRoot(
+		fmt.Sprintf("chown %s to user %d:%d", path, uid, gid),
+		"make it writable by the user before running the lifecycle, or run the lifecycle as root in a user namespace (e.g., with `unshare --user --map-root-user`)",
+	)
+	if !IsPrivileged() && (uid != os.Getuid() || gid != os.Getgid()) {
+		return notPermitted
+	}
+	err := recursiveEnsureOwner(path, uid, gid)
+	switch {
+	case errors.Is(err, syscall.EPERM) && !IsPrivileged():
+		return notPermitted
+	case errors.Is(err, syscall.EINVAL) && InUserNamespace():
+		return &CapabilityError{
+			Operation: fmt.Sprintf("chown %s to user %d:%d", path, uid, gid),
+			Reason:    "the user is not mapped in the user namespace of the lifecycle",
+			Remedy:    "map the user in the user namespace (e.g., with newuidmap and newgidmap)",
+		}
+	default:
+		return err
+	}
+}
+
 const (
 	worldWrite uint32 = 0002
 	groupWrite uint32 = 0020
@@ -55,6 +96,22 @@ func IsPrivileged() bool {
 	return os.Getuid() == 0
 }
 
+// InUserNamespace returns true if the current process is in a user namespace other than the initial one,
+// in which case being privileged does not imply privileges on the host.
+func InUserNamespace() bool {
+	contents, err := os.ReadFile("/proc/self/uid_map")
+	if err != nil {
+		return false
+	}
+	return !isInitialUIDMap(string(contents))
+}
+
+// isInitialUIDMap returns true if the provided uid_map contents map the full range of user IDs to themselves.
+func isInitialUIDMap(contents string) bool {
+	fields := strings.Fields(contents)
+	return len(fields) == 3 && fields[0] == "0" && fields[1] == "0" && fields[2] == "4294967295"
+}
+
 func recursiveEnsureOwner(path string, uid, gid int) error {
 	if err := os.Chown(path, uid, gid); err != nil {
 		return err
@@ -83,6 +140,12 @@ func RunAs(uid, gid int) error {
 	if uid == os.Getuid() && gid == os.Getgid() {
 		return nil
 	}
+	if !IsPrivileged() {
+		return RequiresRoot(
+			fmt.Sprintf("run as user %d:%d", uid, gid),
+			"run the lifecycle as the user provided by CNB_USER_ID and CNB_GROUP_ID, or as root",
+		)
+	}
 
 	if err := syscall.Setresgid(gid, gid, gid); err != nil {
 		return err
@@ -94,9 +157,19 @@ func RunAs(uid, gid int) error {
 	return nil
 }
 
+// SetUmask sets the umask of the calling process and returns the previous umask.
+func SetUmask(mask int) int {
+	return syscall.Umask(mask)
+}
+
 func SetEnvironmentForUser(uid int) error {
 	user, err := user.LookupId(strconv.Itoa(uid))
 	if err != nil {
+		if uid == os.Getuid() {
+			// the lifecycle was started as a user without an entry in /etc/passwd (e.g., an arbitrary uid assigned by the platform),
+			// in which case the environment it was started with is the environment of the user
+			return nil
+		}
 		return err
 	}
 	if err := os.Setenv("HOME", user.HomeDir); err != nil {
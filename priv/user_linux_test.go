@@ -0,0 +1,89 @@
+//go:build linux
+
+package priv_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/priv"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestUser(t *testing.T) {
+	spec.Run(t, "User", testUser, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testUser(t *testing.T, when spec.G, it spec.S) {
+	var dir string
+
+	it.Before(func() {
+		dir = t.TempDir()
+		h.Mkdir(t, filepath.Join(dir, "layers", "some-layer"))
+		h.Mkfile(t, "some-contents", filepath.Join(dir, "layers", "some-layer", "some-file"))
+		h.AssertNil(t, os.Chmod(filepath.Join(dir, "layers"), 0755))
+	})
+
+	assertOwner := func(path string, uid, gid int) {
+		t.Helper()
+		fi, err := os.Lstat(path)
+		h.AssertNil(t, err)
+		stat := fi.Sys().(*syscall.Stat_t)
+		h.AssertEq(t, int(stat.Uid), uid)
+		h.AssertEq(t, int(stat.Gid), gid)
+	}
+
+	when("#EnsureOwner", func() {
+		it("recursively chowns dirs that are not writable by the user", func() {
+			h.SkipIf(t, !priv.IsPrivileged(), "chowning to another user requires root")
+
+			h.AssertNil(t, priv.EnsureOwner(1234, 5678, filepath.Join(dir, "layers"), filepath.Join(dir, "missing")))
+
+			assertOwner(filepath.Join(dir, "layers"), 1234, 5678)
+			assertOwner(filepath.Join(dir, "layers", "some-layer", "some-file"), 1234, 5678)
+		})
+
+		it("returns a capability error without root", func() {
+			h.SkipIf(t, priv.IsPrivileged(), "the lifecycle can chown with root")
+
+			err := priv.EnsureOwner(os.Getuid()+1, os.Getgid(), filepath.Join(dir, "layers"))
+			var capErr *priv.CapabilityError
+			h.AssertEq(t, errors.As(err, &capErr), true)
+			h.AssertStringContains(t, err.Error(), "without root; make it writable by the user")
+		})
+	})
+
+	when("#RunAs", func() {
+		it("does nothing for the current user", func() {
+			h.AssertNil(t, priv.RunAs(os.Getuid(), os.Getgid()))
+		})
+
+		it("returns a capability error without root", func() {
+			h.SkipIf(t, priv.IsPrivileged(), "the lifecycle can switch users with root")
+
+			err := priv.RunAs(os.Getuid()+1, os.Getgid())
+			h.AssertError(t, err, "cannot run as user")
+		})
+	})
+
+	when("#SetEnvironmentForUser", func() {
+		it("keeps the environment of a current user without an entry in /etc/passwd", func() {
+			h.SkipIf(t, priv.IsPrivileged(), "root has an entry in /etc/passwd")
+
+			h.AssertNil(t, priv.SetEnvironmentForUser(os.Getuid()))
+		})
+	})
+
+	when("CapabilityError", func() {
+		it("describes the operation, the reason and the remedy", func() {
+			err := &priv.CapabilityError{Operation: "do something", Reason: "some-reason", Remedy: "some-remedy"}
+			h.AssertEq(t, err.Error(), "cannot do something: some-reason; some-remedy")
+		})
+	})
+}
@@ -4,10 +4,18 @@ func EnsureOwner(uid, gid int, paths ...string) error {
 	return nil
 }
 
+func ChownAll(uid, gid int, paths ...string) error {
+	return nil
+}
+
 func IsPrivileged() bool {
 	return false
 }
 
+func InUserNamespace() bool {
+	return false
+}
+
 func RunAs(uid, gid int) error {
 	return nil
 }
@@ -15,3 +23,7 @@ func RunAs(uid, gid int) error {
 func SetEnvironmentForUser(uid int) error {
 	return nil
 }
+
+func SetUmask(mask int) int {
+	return 0
+}
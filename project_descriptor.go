@@ -0,0 +1,84 @@
+package lifecycle
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/env"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform/files"
+)
+
+// ApplyProjectDescriptor prepares the detector for the provided project descriptor:
+// the order is replaced with the buildpack group in the descriptor (if provided),
+// and environment variables declared in the descriptor are provided to buildpacks.
+// The include and exclude patterns of the descriptor are applied to the app layers by the exporter
+// (see layers.Factory), so the app directory is not modified.
+func (f *DetectorFactory) ApplyProjectDescriptor(detector *Detector, descriptor files.ProjectDescriptor, logger log.Logger) error {
+	if len(descriptor.Buildpacks) > 0 {
+		order, err := OrderForProject(detector.Order, descriptor.Buildpacks)
+		if err != nil {
+			return err
+		}
+		if err = f.verifyAPIs(buildpack.Order{{Group: projectGroup(order)}}, nil, logger); err != nil {
+			return err
+		}
+		logger.Debugf("Using buildpack group from project descriptor")
+		detector.Order = order
+	}
+	detector.ProjectEnv = descriptor.EnvList()
+	return nil
+}
+
+// OrderForProject returns an order containing a single group with the buildpacks in the project descriptor.
+// Buildpacks without a version are assigned the version of the buildpack with the same ID in the provided order.
+// Image extensions in the provided order are retained.
+func OrderForProject(order buildpack.Order, projectBuildpacks []files.ProjectBuildpack) (buildpack.Order, error) {
+	var group []buildpack.GroupElement
+	if len(order) > 0 && len(order[0].Group) > 0 && order[0].Group[0].OrderExtensions != nil {
+		group = append(group, order[0].Group[0])
+	}
+	for _, bp := range projectBuildpacks {
+		version := bp.Version
+		if version == "" {
+			version = versionInOrder(order, bp.ID)
+		}
+		if version == "" {
+			return nil, fmt.Errorf("buildpack '%s' in project descriptor must have a version, or be present in the order", bp.ID)
+		}
+		group = append(group, buildpack.GroupElement{ID: bp.ID, Version: version})
+	}
+	return buildpack.Order{{Group: group}}, nil
+}
+
+func versionInOrder(order buildpack.Order, id string) string {
+	for _, group := range order {
+		for _, groupEl := range group.Group {
+			if groupEl.ID == id && !groupEl.Extension {
+				return groupEl.Version
+			}
+		}
+	}
+	return ""
+}
+
+func projectGroup(order buildpack.Order) []buildpack.GroupElement {
+	var result []buildpack.GroupElement
+	for _, groupEl := range order[0].Group {
+		if groupEl.OrderExtensions == nil {
+			result = append(result, groupEl)
+		}
+	}
+	return result
+}
+
+// withProjectEnv adds environment variables declared in the project descriptor to the build environment.
+// Variables in the platform directory take precedence.
+func withProjectEnv(buildEnv *env.Env, projectEnv []string) *env.Env {
+	for _, kv := range projectEnv {
+		parts := strings.SplitN(kv, "=", 2)
+		buildEnv.Vars.Set(parts[0], parts[1])
+	}
+	return buildEnv
+}
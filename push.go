@@ -0,0 +1,131 @@
+package lifecycle
+
+import (
+	"sync"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// splitByRepository returns the additional names in the same repository as the image name,
+// and the additional names in other repositories (e.g., in other registries).
+func splitByRepository(imageName string, additionalNames []string) ([]string, []string) {
+	imageRef, err := name.ParseReference(imageName, name.WeakValidation)
+	if err != nil {
+		return additionalNames, nil
+	}
+	var sameRepo, otherRepos []string
+	for _, n := range additionalNames {
+		ref, err := name.ParseReference(n, name.WeakValidation)
+		if err != nil || ref.Context() == imageRef.Context() {
+			sameRepo = append(sameRepo, n)
+			continue
+		}
+		otherRepos = append(otherRepos, n)
+	}
+	return sameRepo, otherRepos
+}
+
+// pushToRepositories pushes the saved image to the provided names in other repositories than the image name,
+// using the keychain for the credentials of each registry, so that the pushed images have the same digest.
+// The layers are mounted from the repository of the image where the registry supports it (i.e., in the same registry,
+// or in a registry that supports mounting from the origin registry), instead of being uploaded again.
+// The names that could not be pushed are returned in an imgutil.SaveError.
+func pushToRepositories(image v1.Image, imageName string, names []string, keychain authn.Keychain) error {
+	source, err := name.ParseReference(imageName, name.WeakValidation)
+	if err != nil {
+		return err
+	}
+	mountable := &mountableImage{Image: image, source: source}
+	var diagnostics []imgutil.SaveDiagnostic
+	for _, n := range names {
+		ref, err := name.ParseReference(n, name.WeakValidation)
+		if err == nil {
+			err = ggcrremote.Write(ref, mountable, ggcrremote.WithAuthFromKeychain(keychain))
+		}
+		if err != nil {
+			diagnostics = append(diagnostics, imgutil.SaveDiagnostic{ImageName: n, Cause: err})
+		}
+	}
+	if len(diagnostics) > 0 {
+		return imgutil.SaveError{Errors: diagnostics}
+	}
+	return nil
+}
+
+// mountableImage makes the layers of the image mountable from the source repository when the image is pushed.
+type mountableImage struct {
+	v1.Image
+	source name.Reference
+}
+
+func (i *mountableImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	mountable := make([]v1.Layer, 0, len(layers))
+	for _, layer := range layers {
+		mountable = append(mountable, &ggcrremote.MountableLayer{Layer: layer, Reference: i.source})
+	}
+	return mountable, nil
+}
+
+func (i *mountableImage) LayerByDigest(digest v1.Hash) (v1.Layer, error) {
+	layer, err := i.Image.LayerByDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+	return &ggcrremote.MountableLayer{Layer: layer, Reference: i.source}, nil
+}
+
+func (i *mountableImage) ConfigLayer() (v1.Layer, error) {
+	layer, err := partial.ConfigLayer(i.Image)
+	if err != nil {
+		return nil, err
+	}
+	return &ggcrremote.MountableLayer{Layer: layer, Reference: i.source}, nil
+}
+
+// UploadedBlobs records the layers added to images exported to a registry during a build, by diff ID,
+// so that a layer shared by the app image and the cache image can be mounted into the repository of the cache image
+// rather than uploaded twice. A nil *UploadedBlobs records nothing.
+type UploadedBlobs struct {
+	mu     sync.Mutex
+	layers map[string]uploadedLayer
+}
+
+type uploadedLayer struct {
+	path      string
+	imageName string
+}
+
+func NewUploadedBlobs() *UploadedBlobs {
+	return &UploadedBlobs{layers: map[string]uploadedLayer{}}
+}
+
+// Record records that the compressed tarball at path, of the layer with the provided diff ID, is uploaded with the named image.
+func (u *UploadedBlobs) Record(diffID, path, imageName string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.layers[diffID] = uploadedLayer{path: path, imageName: imageName}
+}
+
+// Lookup returns the path of the compressed tarball of the layer with the provided diff ID and the name of the image it is uploaded with,
+// if the layer was recorded.
+func (u *UploadedBlobs) Lookup(diffID string) (string, string, bool) {
+	if u == nil {
+		return "", "", false
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	layer, ok := u.layers[diffID]
+	return layer.path, layer.imageName, ok
+}
@@ -0,0 +1,105 @@
+package lifecycle
+
+import (
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/discard"
+	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/remote"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestPush(t *testing.T) {
+	spec.Run(t, "Push", testPush, spec.Report(report.Terminal{}))
+}
+
+func testPush(t *testing.T, when spec.G, it spec.S) {
+	var (
+		server       *httptest.Server
+		registryHost string
+	)
+
+	it.Before(func() {
+		server = httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+		registryHost = strings.TrimPrefix(server.URL, "http://")
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	when("#splitByRepository", func() {
+		it("separates the names in other repositories", func() {
+			sameRepo, otherRepos := splitByRepository(
+				"some-registry.io/some-repo/app",
+				[]string{"some-registry.io/some-repo/app:other-tag", "other-registry.io/some-repo/app", "some-registry.io/other-repo/app:latest"},
+			)
+			h.AssertEq(t, sameRepo, []string{"some-registry.io/some-repo/app:other-tag"})
+			h.AssertEq(t, otherRepos, []string{"other-registry.io/some-repo/app", "some-registry.io/other-repo/app:latest"})
+		})
+	})
+
+	when("#pushToRepositories", func() {
+		it("pushes the image with the same digest", func() {
+			image, err := random.Image(1024, 2)
+			h.AssertNil(t, err)
+			source, err := name.ParseReference(registryHost + "/some-repo/app:latest")
+			h.AssertNil(t, err)
+			h.AssertNil(t, ggcrremote.Write(source, image))
+
+			h.AssertNil(t, pushToRepositories(image, source.String(), []string{registryHost + "/other-repo/app:latest"}, authn.DefaultKeychain))
+
+			digest, err := image.Digest()
+			h.AssertNil(t, err)
+			ref, err := name.ParseReference(registryHost + "/other-repo/app:latest")
+			h.AssertNil(t, err)
+			desc, err := ggcrremote.Head(ref)
+			h.AssertNil(t, err)
+			h.AssertEq(t, desc.Digest, digest)
+		})
+
+		it("reports the names that could not be pushed", func() {
+			image, err := random.Image(1024, 1)
+			h.AssertNil(t, err)
+
+			err = pushToRepositories(image, registryHost+"/some-repo/app", []string{registryHost + "/other-repo/app:latest", "not a valid name"}, authn.DefaultKeychain)
+			saveErr, ok := err.(imgutil.SaveError)
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, len(saveErr.Errors), 1)
+			h.AssertEq(t, saveErr.Errors[0].ImageName, "not a valid name")
+		})
+	})
+
+	when("#saveImageWithKeychain", func() {
+		it("saves the image to the names in every repository", func() {
+			image, err := remote.NewImage(registryHost+"/some-repo/app", authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			additionalNames := []string{registryHost + "/some-repo/app:other-tag", registryHost + "/other-repo/app:latest"}
+
+			imageReport, err := saveImageWithKeychain(image, image.Name(), additionalNames, authn.DefaultKeychain, &log.Logger{Handler: discard.New()})
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, imageReport.Tags, append([]string{image.Name()}, additionalNames...))
+			for _, n := range additionalNames {
+				ref, err := name.ParseReference(n)
+				h.AssertNil(t, err)
+				desc, err := ggcrremote.Head(ref)
+				h.AssertNil(t, err)
+				h.AssertEq(t, desc.Digest.String(), imageReport.Digest)
+			}
+		})
+	})
+}
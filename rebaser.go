@@ -5,12 +5,19 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/remote"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/image"
+	"github.com/buildpacks/lifecycle/internal/compat"
 	"github.com/buildpacks/lifecycle/internal/encoding"
 	"github.com/buildpacks/lifecycle/internal/str"
 	"github.com/buildpacks/lifecycle/log"
@@ -23,16 +30,35 @@ var (
 	msgAppImageNotMarkedRebasable       = "app image is not marked as rebasable"
 	msgRunImageMDNotContainsName        = "rebase app image: new base image '%s' not found in existing run image metadata: %s"
 	msgUnableToSatisfyTargetConstraints = "unable to satisfy target os/arch constraints; new run image: %s, old run image: %s"
+	msgIncompatibleRunImage             = "new run image is incompatible with the previous run image: %s"
+	msgRunImageExtended                 = "app image was built on a run image extended by %s: its %d extension layer(s) were built against the previous run image, and would be kept unchanged on top of the new run image"
+	msgRebuildToReapplyExtensions       = "rebuild the app image to re-apply the extensions to the new run image, or provide -force to override"
 )
 
 type Rebaser struct {
 	Logger      log.Logger
 	PlatformAPI *api.Version
 	Force       bool
+	// DryRun rebases the app image without saving it, reporting the digest the rebased image would have.
+	DryRun bool
+	// SnapshotTag, if provided, tags the app image as it was before the rebase with this tag in its repository,
+	// so that the rebase can be rolled back. It is only supported for images in a registry.
+	SnapshotTag string
+	// SnapshotKeychain is used to tag the snapshot.
+	SnapshotKeychain authn.Keychain
+
+	inspections sync.Map // inspections are the run images inspected for compatibility checks, by top layer
 }
 
 type RebaseReport struct {
-	Image files.ImageReport `toml:"image"`
+	Image        files.ImageReport   `toml:"image"`
+	Deprecations []files.Deprecation `toml:"deprecations,omitempty"`
+	// Compatibility compares the previous and the new run image, if their layers could be inspected.
+	Compatibility *compat.Report `toml:"compatibility,omitempty"`
+	// Snapshot is the reference of the app image as it was before the rebase, if a snapshot was requested.
+	Snapshot string `toml:"snapshot,omitempty"`
+	// DryRun is true if the rebased image was not saved.
+	DryRun bool `toml:"dry-run,omitempty"`
 }
 
 func (r *Rebaser) Rebase(workingImage imgutil.Image, newBaseImage imgutil.Image, outputImageRef string, additionalNames []string) (RebaseReport, error) {
@@ -61,6 +87,26 @@ func (r *Rebaser) Rebase(workingImage imgutil.Image, newBaseImage imgutil.Image,
 		return RebaseReport{}, fmt.Errorf("get image metadata: %w", err)
 	}
 
+	var compatibility *compat.Report
+	if r.PlatformAPI.AtLeast("0.12") {
+		if compatibility, err = r.checkCompatibility(workingImage, newBaseImage, origMetadata.RunImage); err != nil {
+			return RebaseReport{Compatibility: compatibility}, err
+		}
+	}
+
+	if err = r.validateExtensions(origMetadata.RunImage); err != nil {
+		return RebaseReport{Compatibility: compatibility}, err
+	}
+
+	var snapshot string
+	if r.SnapshotTag != "" {
+		if r.DryRun {
+			r.Logger.Infof("Dry run: not tagging the app image as '%s'", r.SnapshotTag)
+		} else if snapshot, err = r.snapshot(workingImage); err != nil {
+			return RebaseReport{Compatibility: compatibility}, fmt.Errorf("snapshot app image: %w", err)
+		}
+	}
+
 	// rebase
 	if err = workingImage.Rebase(origMetadata.RunImage.TopLayer, newBaseImage); err != nil {
 		return RebaseReport{}, fmt.Errorf("rebase app image: %w", err)
@@ -130,8 +176,12 @@ func (r *Rebaser) Rebase(workingImage imgutil.Image, newBaseImage imgutil.Image,
 	}
 
 	// save
-	report := RebaseReport{}
-	report.Image, err = saveImageAs(workingImage, outputImageRef, additionalNames, r.Logger)
+	report := RebaseReport{Compatibility: compatibility, Snapshot: snapshot, DryRun: r.DryRun}
+	if r.DryRun {
+		report.Image, err = dryRunImage(workingImage, outputImageRef, r.Logger)
+	} else {
+		report.Image, err = saveImageAs(workingImage, outputImageRef, additionalNames, r.Logger)
+	}
 	if err != nil {
 		return RebaseReport{}, err
 	}
@@ -139,10 +189,93 @@ func (r *Rebaser) Rebase(workingImage imgutil.Image, newBaseImage imgutil.Image,
 		// unset manifest size in report.toml for old platform API versions
 		report.Image.ManifestSize = 0
 	}
+	report.Deprecations = reportDeprecations()
 
 	return report, err
 }
 
+// BulkRebaseReport is the report of a bulk rebase, with the outcome of each app image in the order they were provided.
+type BulkRebaseReport struct {
+	Images []BulkRebaseResult `toml:"images"`
+}
+
+// BulkRebaseResult is the outcome of rebasing an app image in a bulk rebase: its report, or the error that prevented the rebase.
+type BulkRebaseResult struct {
+	Reference string `toml:"reference"`
+	Error     string `toml:"error,omitempty"`
+	RebaseReport
+}
+
+// Failed returns the number of app images that could not be rebased.
+func (r BulkRebaseReport) Failed() int {
+	var failed int
+	for _, result := range r.Images {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// RebaseAll rebases the app images with the provided references onto newBaseImage, with up to parallelism rebases at a time,
+// saving each image under its own reference. A failure to rebase an image does not stop the other rebases.
+// The new base image is shared by all the rebases, so that its layers are only fetched once,
+// and are mounted from its repository rather than uploaded when the app images are saved to the same registry.
+func (r *Rebaser) RebaseAll(refs []string, openImage func(ref string) (imgutil.Image, error), newBaseImage imgutil.Image, parallelism int) BulkRebaseReport {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	results := make([]BulkRebaseResult, len(refs))
+	var g errgroup.Group
+	g.SetLimit(parallelism)
+	for i, ref := range refs {
+		i, ref := i, ref
+		g.Go(func() error {
+			results[i] = r.rebaseRef(ref, openImage, newBaseImage)
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return BulkRebaseReport{Images: results}
+}
+
+func (r *Rebaser) rebaseRef(ref string, openImage func(ref string) (imgutil.Image, error), newBaseImage imgutil.Image) BulkRebaseResult {
+	result := BulkRebaseResult{Reference: ref}
+	r.Logger.Infof("Rebasing '%s'", ref)
+	appImage, err := openImage(ref)
+	if err == nil {
+		result.RebaseReport, err = r.Rebase(appImage, newBaseImage, ref, nil)
+	}
+	if err != nil {
+		r.Logger.Errorf("Failed to rebase '%s': %s", ref, err)
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// snapshot tags the app image manifest with SnapshotTag in its repository, and returns the reference of the tag.
+func (r *Rebaser) snapshot(appImg imgutil.Image) (string, error) {
+	identifier, err := appImg.Identifier()
+	if err != nil {
+		return "", err
+	}
+	digestIdentifier, ok := identifier.(remote.DigestIdentifier)
+	if !ok {
+		return "", errors.New("snapshots are only supported for images in a registry")
+	}
+	tag := digestIdentifier.Digest.Context().Tag(r.SnapshotTag)
+	opt := ggcrremote.WithAuthFromKeychain(r.SnapshotKeychain)
+	desc, err := ggcrremote.Get(digestIdentifier.Digest, opt)
+	if err != nil {
+		return "", err
+	}
+	if err = ggcrremote.Tag(tag, desc, opt); err != nil {
+		return "", err
+	}
+	r.Logger.Infof("Tagged the app image before rebasing as '%s'", tag)
+	return tag.String(), nil
+}
+
 func containsName(origMetadata files.LayersMetadataCompat, newBaseName string) bool {
 	if origMetadata.RunImage.Contains(newBaseName) {
 		return true
@@ -154,12 +287,12 @@ func containsName(origMetadata files.LayersMetadataCompat, newBaseName string) b
 }
 
 func validateStackID(appImg, newBaseImage imgutil.Image) error {
-	appStackID, err := appImg.Label(platform.StackIDLabel)
+	appStackID, err := stackIDOf(appImg)
 	if err != nil {
 		return fmt.Errorf("get app image stack: %w", err)
 	}
 
-	newBaseStackID, err := newBaseImage.Label(platform.StackIDLabel)
+	newBaseStackID, err := stackIDOf(newBaseImage)
 	if err != nil {
 		return fmt.Errorf("get new base image stack: %w", err)
 	}
@@ -178,6 +311,19 @@ func validateStackID(appImg, newBaseImage imgutil.Image) error {
 	return nil
 }
 
+// stackIDOf returns the stack of the image from its label, or translated from its target for images built without stacks.
+func stackIDOf(img imgutil.Image) (string, error) {
+	stackID, err := img.Label(platform.StackIDLabel)
+	if err != nil || stackID != "" {
+		return stackID, err
+	}
+	target, err := platform.GetTargetMetadata(img)
+	if err != nil {
+		return "", err
+	}
+	return files.StackIDForTarget(*target), nil
+}
+
 func validateMixins(appImg, newBaseImg imgutil.Image) error {
 	var appImageMixins []string
 	var newBaseImageMixins []string
@@ -203,6 +349,20 @@ func validateMixins(appImg, newBaseImg imgutil.Image) error {
 	return nil
 }
 
+// validateExtensions refuses to rebase app images whose run image was extended, as the extension layers may depend on
+// the contents of the previous run image (e.g., packages installed with its package manager), and the rebaser cannot re-apply the extensions.
+func (r *Rebaser) validateExtensions(runImage files.RunImageForRebase) error {
+	if len(runImage.ExtensionLayers) == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf(msgRunImageExtended, strings.Join(runImage.ExtensionIDs(), ", "), len(runImage.ExtensionLayers))
+	if !r.Force {
+		return errors.New(msg + "; " + msgRebuildToReapplyExtensions)
+	}
+	r.Logger.Warn(msg)
+	return nil
+}
+
 func (r *Rebaser) validateTarget(appImg imgutil.Image, newBaseImg imgutil.Image) error {
 	rebasable, err := appImg.Label(platform.RebasableLabel)
 	if err != nil {
@@ -244,6 +404,108 @@ func (r *Rebaser) validateTarget(appImg imgutil.Image, newBaseImg imgutil.Image)
 	return nil
 }
 
+// checkCompatibility compares the ABI of the previous run image (the layers of the app image up to the run image top layer)
+// with the new run image. An incompatible rebase fails unless forced, and the comparison is returned in either case.
+// The comparison is skipped if the layers of the images cannot be inspected (e.g., for images in a daemon).
+func (r *Rebaser) checkCompatibility(appImg, newBaseImg imgutil.Image, prevRunImage files.RunImageForRebase) (*compat.Report, error) {
+	appLayers, appOK := inspectableLayers(appImg)
+	newBaseLayers, newBaseOK := inspectableLayers(newBaseImg)
+	if !appOK || !newBaseOK {
+		r.Logger.Debug("Skipping run image compatibility checks: image layers cannot be inspected")
+		return nil, nil
+	}
+	prevBaseLayers, err := layersThrough(appLayers, prevRunImage.TopLayer)
+	if err != nil {
+		return nil, fmt.Errorf("get previous run image layers: %w", err)
+	}
+	r.Logger.Info("Checking run image compatibility...")
+	previous, err := r.inspect(prevRunImage.TopLayer, prevBaseLayers)
+	if err != nil {
+		return nil, fmt.Errorf("inspect previous run image: %w", err)
+	}
+	newTopLayer, err := newBaseImg.TopLayer()
+	if err != nil {
+		return nil, fmt.Errorf("get new run image top layer: %w", err)
+	}
+	next, err := r.inspect(newTopLayer, newBaseLayers)
+	if err != nil {
+		return nil, fmt.Errorf("inspect new run image: %w", err)
+	}
+	previous.Image = prevRunImage.Reference
+	if identifier, err := newBaseImg.Identifier(); err == nil {
+		next.Image = identifier.String()
+	}
+	// the stack labels of the previous run image are copied to the app image
+	if previous.Mixins, err = mixinsOf(appImg); err != nil {
+		return nil, fmt.Errorf("get app image mixins: %w", err)
+	}
+	if next.Mixins, err = mixinsOf(newBaseImg); err != nil {
+		return nil, fmt.Errorf("get run image mixins: %w", err)
+	}
+
+	report := compat.Compare(previous, next)
+	if report.Compatible {
+		return &report, nil
+	}
+	if !r.Force {
+		return &report, fmt.Errorf(msgIncompatibleRunImage+"; "+msgProvideForceToOverride, report.Summary())
+	}
+	report.Forced = true
+	r.Logger.Warnf(msgIncompatibleRunImage, report.Summary())
+	return &report, nil
+}
+
+type inspection struct {
+	once sync.Once
+	base compat.Base
+	err  error
+}
+
+// inspect inspects the run image with the provided top layer once, as it is shared by the images of a bulk rebase.
+func (r *Rebaser) inspect(topLayer string, layers []v1.Layer) (compat.Base, error) {
+	value, _ := r.inspections.LoadOrStore(topLayer, &inspection{})
+	in := value.(*inspection)
+	in.once.Do(func() {
+		in.base, in.err = compat.Inspect(layers)
+	})
+	return in.base, in.err
+}
+
+// inspectableLayers returns the layers of images whose contents can be read without saving them (i.e., remote images).
+func inspectableLayers(img imgutil.Image) ([]v1.Layer, bool) {
+	underlying, ok := img.(interface{ UnderlyingImage() v1.Image })
+	if !ok || underlying.UnderlyingImage() == nil {
+		return nil, false
+	}
+	layers, err := underlying.UnderlyingImage().Layers()
+	if err != nil {
+		return nil, false
+	}
+	return layers, true
+}
+
+// layersThrough returns the layers up to and including the layer with the provided diff ID.
+func layersThrough(layers []v1.Layer, diffID string) ([]v1.Layer, error) {
+	for i, layer := range layers {
+		layerDiffID, err := layer.DiffID()
+		if err != nil {
+			return nil, err
+		}
+		if layerDiffID.String() == diffID {
+			return layers[:i+1], nil
+		}
+	}
+	return nil, fmt.Errorf("layer '%s' not found", diffID)
+}
+
+func mixinsOf(img imgutil.Image) ([]string, error) {
+	var mixins []string
+	if err := image.DecodeLabel(img, platform.MixinsLabel, &mixins); err != nil {
+		return nil, err
+	}
+	return removeStagePrefixes(mixins), nil
+}
+
 func (r *Rebaser) supportsManifestSize() bool {
 	return r.PlatformAPI.AtLeast("0.6")
 }
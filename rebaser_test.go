@@ -1,21 +1,37 @@
 package lifecycle_test
 
 import (
+	"archive/tar"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/apex/log"
 	"github.com/apex/log/handlers/memory"
+	"github.com/buildpacks/imgutil"
 	"github.com/buildpacks/imgutil/fakes"
 	"github.com/buildpacks/imgutil/local"
 	"github.com/buildpacks/imgutil/remote"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
 	"github.com/buildpacks/lifecycle"
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/image"
+	"github.com/buildpacks/lifecycle/internal/compat"
 	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/platform/files"
 	h "github.com/buildpacks/lifecycle/testhelpers"
@@ -478,6 +494,88 @@ func testRebaser(t *testing.T, when spec.G, it spec.S) {
 					})
 				})
 			})
+
+			when("dry run", func() {
+				it.Before(func() {
+					rebaser.DryRun = true
+				})
+
+				it("rebases without saving", func() {
+					report, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
+					h.AssertNil(t, err)
+
+					h.AssertEq(t, fakeAppImage.IsSaved(), false)
+					h.AssertEq(t, fakeAppImage.Base(), "some-repo/new-base-image")
+					h.AssertEq(t, report.DryRun, true)
+					h.AssertEq(t, len(report.Image.Tags), 0)
+				})
+
+				when("image is in a registry", func() {
+					it("reports the digest the rebased image would have", func() {
+						underlying, err := mutate.AppendLayers(empty.Image, tarLayer(t, map[string]string{"some-file": "some-contents"}))
+						h.AssertNil(t, err)
+						appImage := &inspectableImage{Image: fakeAppImage, underlying: underlying}
+
+						report, err := rebaser.Rebase(appImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
+						h.AssertNil(t, err)
+
+						h.AssertEq(t, fakeAppImage.IsSaved(), false)
+						h.AssertStringContains(t, report.Image.Digest, "sha256:")
+						if report.Image.ManifestSize == 0 {
+							t.Fatal("expected the manifest size to be reported")
+						}
+						h.AssertLogEntry(t, logHandler, "it would have digest "+report.Image.Digest)
+					})
+				})
+
+				it("doesn't tag a snapshot", func() {
+					rebaser.SnapshotTag = "pre-rebase-some-timestamp"
+
+					report, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
+					h.AssertNil(t, err)
+
+					h.AssertEq(t, report.Snapshot, "")
+				})
+			})
+
+			when("snapshot", func() {
+				var server *httptest.Server
+
+				it.Before(func() {
+					server = httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+					rebaser.SnapshotTag = "pre-rebase-some-timestamp"
+					rebaser.SnapshotKeychain = authn.DefaultKeychain
+				})
+
+				it.After(func() {
+					server.Close()
+				})
+
+				it("tags the app image before rebasing", func() {
+					ref, err := name.ParseReference(strings.TrimPrefix(server.URL, "http://") + "/some-repo/app-image:latest")
+					h.AssertNil(t, err)
+					h.AssertNil(t, ggcrremote.Write(ref, empty.Image))
+					digest, err := empty.Image.Digest()
+					h.AssertNil(t, err)
+					fakeAppImage.SetIdentifier(remote.DigestIdentifier{Digest: ref.Context().Digest(digest.String())})
+
+					report, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
+					h.AssertNil(t, err)
+
+					tag := ref.Context().Tag("pre-rebase-some-timestamp")
+					h.AssertEq(t, report.Snapshot, tag.String())
+					desc, err := ggcrremote.Head(tag)
+					h.AssertNil(t, err)
+					h.AssertEq(t, desc.Digest, digest)
+					h.AssertEq(t, fakeAppImage.IsSaved(), true)
+				})
+
+				it("errors if the image is not in a registry", func() {
+					_, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
+					h.AssertError(t, err, "snapshots are only supported for images in a registry")
+					h.AssertEq(t, fakeAppImage.IsSaved(), false)
+				})
+			})
 		})
 
 		when("validating rebasable", func() {
@@ -553,6 +651,50 @@ func testRebaser(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 
+		when("validating extensions", func() {
+			when("the run image was extended", func() {
+				it.Before(func() {
+					metadata := files.LayersMetadata{
+						RunImage: files.RunImageForRebase{
+							TopLayer:          "some-top-layer-sha",
+							RunImageForExport: files.RunImageForExport{Image: fakeNewBaseImage.Name()},
+							ExtensionLayers: []files.ExtensionLayer{
+								{ExtensionID: "some-extension", DiffID: "sha256:some-extension-layer"},
+								{ExtensionID: "other-extension", DiffID: "sha256:other-extension-layer"},
+								{ExtensionID: "some-extension", DiffID: "sha256:another-extension-layer"},
+							},
+						},
+					}
+					label, err := json.Marshal(metadata)
+					h.AssertNil(t, err)
+					h.AssertNil(t, fakeAppImage.SetLabel(platform.LifecycleMetadataLabel, string(label)))
+				})
+
+				it("errors", func() {
+					_, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
+					h.AssertError(t, err, "app image was built on a run image extended by some-extension, other-extension: its 3 extension layer(s) were built against the previous run image")
+					h.AssertError(t, err, "rebuild the app image to re-apply the extensions")
+					h.AssertEq(t, fakeAppImage.IsSaved(), false)
+				})
+
+				when("force", func() {
+					it.Before(func() {
+						rebaser.Force = true
+					})
+
+					it("warns, allows rebase and preserves the extension layers in the metadata", func() {
+						_, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
+						h.AssertNil(t, err)
+						assertLogEntry(t, logHandler, "extended by some-extension, other-extension")
+
+						h.AssertNil(t, image.DecodeLabel(fakeAppImage, platform.LifecycleMetadataLabel, &md))
+						h.AssertEq(t, len(md.RunImage.ExtensionLayers), 3)
+						h.AssertEq(t, md.RunImage.TopLayer, "new-top-layer-sha")
+					})
+				})
+			})
+		})
+
 		when("validating mixins", func() {
 			when("mixins are missing on the run image", func() {
 				it("allows rebase", func() {
@@ -708,6 +850,16 @@ func testRebaser(t *testing.T, when spec.G, it spec.S) {
 						_, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
 						h.AssertError(t, err, "stack not defined on app image")
 					})
+
+					it("translates the stack of a new base image built without stacks from its target", func() {
+						h.AssertNil(t, fakeAppImage.SetLabel(platform.StackIDLabel, "io.buildpacks.stacks.jammy"))
+						h.AssertNil(t, fakeNewBaseImage.SetLabel(platform.StackIDLabel, ""))
+						h.AssertNil(t, fakeNewBaseImage.SetLabel(platform.OSDistributionNameLabel, "ubuntu"))
+						h.AssertNil(t, fakeNewBaseImage.SetLabel(platform.OSDistributionVersionLabel, "18.04"))
+
+						_, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
+						h.AssertError(t, err, "incompatible stack: 'io.buildpacks.stacks.bionic' is not compatible with 'io.buildpacks.stacks.jammy'")
+					})
 				})
 			})
 
@@ -774,7 +926,7 @@ func testRebaser(t *testing.T, when spec.G, it spec.S) {
 								h.AssertNil(t, fakeNewBaseImage.SetOS("notlinux"))
 
 								_, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
-								h.AssertError(t, err, `unable to satisfy target os/arch constraints; new run image: {"os":"notlinux","arch":"amd64"}, old run image: {"os":"linux","arch":"amd64"}`)
+								h.AssertError(t, err, `unable to satisfy target os/arch constraints; new run image: {"os":"notlinux","arch":"amd64"}, old run image: {"os":"linux","arch":"amd64","distribution":{"name":"ubuntu","version":"18.04"}}`)
 							})
 
 							it("errors and prevents the rebase from taking place when the architecture are different", func() {
@@ -782,7 +934,7 @@ func testRebaser(t *testing.T, when spec.G, it spec.S) {
 								h.AssertNil(t, fakeNewBaseImage.SetArchitecture("arm64"))
 
 								_, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
-								h.AssertError(t, err, `unable to satisfy target os/arch constraints; new run image: {"os":"linux","arch":"arm64"}, old run image: {"os":"linux","arch":"amd64"}`)
+								h.AssertError(t, err, `unable to satisfy target os/arch constraints; new run image: {"os":"linux","arch":"arm64"}, old run image: {"os":"linux","arch":"amd64","distribution":{"name":"ubuntu","version":"18.04"}}`)
 							})
 
 							it("errors and prevents the rebase from taking place when the architecture variant are different", func() {
@@ -790,7 +942,7 @@ func testRebaser(t *testing.T, when spec.G, it spec.S) {
 								h.AssertNil(t, fakeNewBaseImage.SetVariant("variant2"))
 
 								_, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
-								h.AssertError(t, err, `unable to satisfy target os/arch constraints; new run image: {"os":"linux","arch":"amd64","arch-variant":"variant2"}, old run image: {"os":"linux","arch":"amd64","arch-variant":"variant1"}`)
+								h.AssertError(t, err, `unable to satisfy target os/arch constraints; new run image: {"os":"linux","arch":"amd64","arch-variant":"variant2","distribution":{"name":"ubuntu","version":"18.04"}}, old run image: {"os":"linux","arch":"amd64","arch-variant":"variant1","distribution":{"name":"ubuntu","version":"18.04"}}`)
 							})
 
 							it("errors and prevents the rebase from taking place when the io.buildpacks.distribution.name are different", func() {
@@ -823,7 +975,7 @@ func testRebaser(t *testing.T, when spec.G, it spec.S) {
 							_, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
 							h.AssertNil(t, err)
 
-							assertLogEntry(t, logHandler, `unable to satisfy target os/arch constraints; new run image: {"os":"notlinux","arch":"amd64"}, old run image: {"os":"linux","arch":"amd64"}`)
+							assertLogEntry(t, logHandler, `unable to satisfy target os/arch constraints; new run image: {"os":"notlinux","arch":"amd64"}, old run image: {"os":"linux","arch":"amd64","distribution":{"name":"ubuntu","version":"18.04"}}`)
 						})
 					})
 				})
@@ -859,6 +1011,121 @@ func testRebaser(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 
+		when("checking run image compatibility", func() {
+			var (
+				appImage     *inspectableImage
+				newBaseImage *inspectableImage
+			)
+
+			baseLayer := func(glibcVersion string) v1.Layer {
+				return tarLayer(t, map[string]string{
+					"etc/os-release":                 "ID=ubuntu\nVERSION_ID=22.04\n",
+					"lib/x86_64-linux-gnu/libc.so.6": "GNU C Library (Ubuntu GLIBC) stable release version " + glibcVersion + ".",
+				})
+			}
+
+			setup := func(previousGlibc, newGlibc string) {
+				prevBase := baseLayer(previousGlibc)
+				prevTopLayer, err := prevBase.DiffID()
+				h.AssertNil(t, err)
+				appImg, err := mutate.AppendLayers(empty.Image, prevBase, tarLayer(t, map[string]string{"workspace/some-file": "some-contents"}))
+				h.AssertNil(t, err)
+				newBaseImg, err := mutate.AppendLayers(empty.Image, baseLayer(newGlibc))
+				h.AssertNil(t, err)
+
+				lifecycleMD := files.LayersMetadata{
+					RunImage: files.RunImageForRebase{TopLayer: prevTopLayer.String(), Reference: "some-run-image@sha256:some-digest"},
+					Stack:    &files.Stack{RunImage: files.RunImageForExport{Image: fakeNewBaseImage.Name()}},
+				}
+				label, err := json.Marshal(lifecycleMD)
+				h.AssertNil(t, err)
+				h.AssertNil(t, fakeAppImage.SetLabel(platform.LifecycleMetadataLabel, string(label)))
+				appImage = &inspectableImage{Image: fakeAppImage, underlying: appImg}
+				newBaseImage = &inspectableImage{Image: fakeNewBaseImage, underlying: newBaseImg}
+			}
+
+			it("reports compatible run images", func() {
+				setup("2.35", "2.35")
+				report, err := rebaser.Rebase(appImage, newBaseImage, fakeAppImage.Name(), additionalNames)
+				h.AssertNil(t, err)
+				h.AssertEq(t, report.Compatibility.Compatible, true)
+				h.AssertEq(t, report.Compatibility.Previous.Image, "some-run-image@sha256:some-digest")
+				h.AssertEq(t, report.Compatibility.New.Libc, compat.Libc{Name: "glibc", Version: "2.35"})
+				h.AssertEq(t, fakeAppImage.Base(), "some-repo/new-base-image")
+			})
+
+			when("the run images are incompatible", func() {
+				it.Before(func() {
+					setup("2.35", "2.39")
+				})
+
+				it("errors and reports the incompatibilities", func() {
+					report, err := rebaser.Rebase(appImage, newBaseImage, fakeAppImage.Name(), additionalNames)
+					h.AssertError(t, err, "new run image is incompatible with the previous run image: glibc upgraded from 2.35 to 2.39; please provide -force to override")
+					h.AssertEq(t, report.Compatibility.Compatible, false)
+					h.AssertEq(t, report.Compatibility.Issues, []compat.Issue{{Check: "libc", Message: "glibc upgraded from 2.35 to 2.39"}})
+					h.AssertEq(t, fakeAppImage.Base(), "")
+				})
+
+				it("warns and allows rebase when forced", func() {
+					rebaser.Force = true
+					report, err := rebaser.Rebase(appImage, newBaseImage, fakeAppImage.Name(), additionalNames)
+					h.AssertNil(t, err)
+					h.AssertEq(t, report.Compatibility.Forced, true)
+					assertLogEntry(t, logHandler, "new run image is incompatible with the previous run image: glibc upgraded from 2.35 to 2.39")
+					h.AssertEq(t, fakeAppImage.Base(), "some-repo/new-base-image")
+				})
+			})
+
+			it("skips the checks if the layers cannot be inspected", func() {
+				report, err := rebaser.Rebase(fakeAppImage, fakeNewBaseImage, fakeAppImage.Name(), additionalNames)
+				h.AssertNil(t, err)
+				h.AssertNil(t, report.Compatibility)
+			})
+		})
+
+		when("#RebaseAll", func() {
+			var otherAppImage *fakes.Image
+
+			it.Before(func() {
+				otherAppImage = fakes.NewImage("some-repo/other-app-image", "some-top-layer-sha", local.IDIdentifier{ImageID: "other-image-id"})
+				h.AssertNil(t, otherAppImage.SetLabel(platform.StackIDLabel, "io.buildpacks.stacks.bionic"))
+				label, err := fakeAppImage.Label(platform.LifecycleMetadataLabel)
+				h.AssertNil(t, err)
+				h.AssertNil(t, otherAppImage.SetLabel(platform.LifecycleMetadataLabel, label))
+				h.AssertNil(t, otherAppImage.SetEnv(platform.EnvPlatformAPI, api.Platform.Latest().String()))
+			})
+
+			it.After(func() {
+				h.AssertNil(t, otherAppImage.Cleanup())
+			})
+
+			it("rebases each image and reports the outcome of each image", func() {
+				images := map[string]*fakes.Image{fakeAppImage.Name(): fakeAppImage, otherAppImage.Name(): otherAppImage}
+				openImage := func(ref string) (imgutil.Image, error) {
+					if img, ok := images[ref]; ok {
+						return img, nil
+					}
+					return nil, errors.New("some-error")
+				}
+
+				report := rebaser.RebaseAll([]string{fakeAppImage.Name(), "some-repo/missing-image", otherAppImage.Name()}, openImage, fakeNewBaseImage, 2)
+				h.AssertEq(t, report.Failed(), 1)
+				h.AssertEq(t, len(report.Images), 3)
+				h.AssertEq(t, report.Images[0].Reference, fakeAppImage.Name())
+				h.AssertEq(t, report.Images[0].Error, "")
+				h.AssertContains(t, report.Images[0].Image.Tags, fakeAppImage.Name())
+				h.AssertEq(t, report.Images[1].Reference, "some-repo/missing-image")
+				h.AssertEq(t, report.Images[1].Error, "some-error")
+				h.AssertEq(t, report.Images[2].Reference, otherAppImage.Name())
+				h.AssertEq(t, report.Images[2].Error, "")
+
+				h.AssertEq(t, fakeAppImage.Base(), "some-repo/new-base-image")
+				h.AssertEq(t, otherAppImage.Base(), "some-repo/new-base-image")
+				h.AssertContains(t, otherAppImage.SavedNames(), "some-repo/other-app-image")
+			})
+		})
+
 		when("outputImageRef is different than workingImage name", func() {
 			var outputImageRef = "fizz"
 
@@ -871,3 +1138,29 @@ func testRebaser(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 }
+
+type inspectableImage struct {
+	*fakes.Image
+	underlying v1.Image
+}
+
+func (i *inspectableImage) UnderlyingImage() v1.Image {
+	return i.underlying
+}
+
+func tarLayer(t *testing.T, contents map[string]string) v1.Layer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for name, data := range contents {
+		h.AssertNil(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(data))}))
+		_, err := tw.Write([]byte(data))
+		h.AssertNil(t, err)
+	}
+	h.AssertNil(t, tw.Close())
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	h.AssertNil(t, err)
+	return layer
+}
@@ -0,0 +1,125 @@
+package lifecycle
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/image"
+	"github.com/buildpacks/lifecycle/log"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
+)
+
+// ReproducibilityVerifier compares the layers of an application image with the layers of a rebuild of the image,
+// e.g., by the creator with the same inputs (and source date epoch), to verify that the build is reproducible.
+type ReproducibilityVerifier struct {
+	Logger log.Logger
+}
+
+// Verify compares the diff IDs recorded in the lifecycle metadata label of the rebuilt image with those recorded in the label of the image,
+// and reports the layers that differ with the buildpack (or extension) that produced them.
+func (v *ReproducibilityVerifier) Verify(appImage, rebuiltImage imgutil.Image) (files.ReproducibilityReport, error) {
+	var expected, actual files.LayersMetadata
+	if err := image.DecodeLabel(appImage, platform.LifecycleMetadataLabel, &expected); err != nil {
+		return files.ReproducibilityReport{}, errors.Wrapf(err, "reading metadata of image '%s'", appImage.Name())
+	}
+	if err := image.DecodeLabel(rebuiltImage, platform.LifecycleMetadataLabel, &actual); err != nil {
+		return files.ReproducibilityReport{}, errors.Wrapf(err, "reading metadata of image '%s'", rebuiltImage.Name())
+	}
+	report := files.ReproducibilityReport{
+		Image:       appImage.Name(),
+		Rebuilt:     rebuiltImage.Name(),
+		Differences: compareLayers(expected, actual),
+	}
+	report.Reproducible = len(report.Differences) == 0
+	for _, diff := range report.Differences {
+		if diff.Producer != "" {
+			v.Logger.Warnf("Layer '%s' produced by '%s' differs: expected '%s', got '%s'", diff.Layer, diff.Producer, diff.Expected, diff.Actual)
+			continue
+		}
+		v.Logger.Warnf("Layer '%s' differs: expected '%s', got '%s'", diff.Layer, diff.Expected, diff.Actual)
+	}
+	return report, nil
+}
+
+func compareLayers(expected, actual files.LayersMetadata) []files.LayerDifference {
+	var diffs []files.LayerDifference
+	compare := func(layer, producer, expectedSHA, actualSHA string) {
+		if expectedSHA != actualSHA {
+			diffs = append(diffs, files.LayerDifference{Layer: layer, Producer: producer, Expected: expectedSHA, Actual: actualSHA})
+		}
+	}
+
+	compare("run-image", "", expected.RunImage.TopLayer, actual.RunImage.TopLayer)
+	expectedExt, actualExt := expected.RunImage.ExtensionLayers, actual.RunImage.ExtensionLayers
+	for i := 0; i < len(expectedExt) || i < len(actualExt); i++ {
+		var e, a files.ExtensionLayer
+		if i < len(expectedExt) {
+			e = expectedExt[i]
+		}
+		if i < len(actualExt) {
+			a = actualExt[i]
+		}
+		producer := e.ExtensionID
+		if producer == "" {
+			producer = a.ExtensionID
+		}
+		compare(fmt.Sprintf("extension-%d", i), producer, e.DiffID, a.DiffID)
+	}
+
+	for _, bp := range expected.Buildpacks {
+		actualBp := actual.LayersMetadataFor(bp.ID)
+		for _, name := range sortedLayerNames(bp.Layers) {
+			compare(name, bp.ID, bp.Layers[name].SHA, actualBp.Layers[name].SHA)
+		}
+	}
+	for _, bp := range actual.Buildpacks {
+		expectedBp := expected.LayersMetadataFor(bp.ID)
+		for _, name := range sortedLayerNames(bp.Layers) {
+			if _, ok := expectedBp.Layers[name]; !ok {
+				compare(name, bp.ID, "", bp.Layers[name].SHA)
+			}
+		}
+	}
+
+	for i := 0; i < len(expected.App) || i < len(actual.App); i++ {
+		var e, a files.LayerMetadata
+		if i < len(expected.App) {
+			e = expected.App[i]
+		}
+		if i < len(actual.App) {
+			a = actual.App[i]
+		}
+		layer := "app"
+		if len(expected.App) > 1 || len(actual.App) > 1 {
+			layer = fmt.Sprintf("app-%d", i)
+		}
+		compare(layer, "", e.SHA, a.SHA)
+	}
+
+	compare("launcher", "", expected.Launcher.SHA, actual.Launcher.SHA)
+	compare("config", "", expected.Config.SHA, actual.Config.SHA)
+	compare("process-types", "", expected.ProcessTypes.SHA, actual.ProcessTypes.SHA)
+	var expectedSBOM, actualSBOM string
+	if expected.BOM != nil {
+		expectedSBOM = expected.BOM.SHA
+	}
+	if actual.BOM != nil {
+		actualSBOM = actual.BOM.SHA
+	}
+	compare("sbom", "", expectedSBOM, actualSBOM)
+	return diffs
+}
+
+func sortedLayerNames(layers map[string]buildpack.LayerMetadata) []string {
+	names := make([]string, 0, len(layers))
+	for name := range layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,117 @@
+package lifecycle_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/buildpacks/imgutil/fakes"
+	"github.com/buildpacks/imgutil/local"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/platform"
+	"github.com/buildpacks/lifecycle/platform/files"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestReproducibilityVerifier(t *testing.T) {
+	spec.Run(t, "ReproducibilityVerifier", testReproducibilityVerifier, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testReproducibilityVerifier(t *testing.T, when spec.G, it spec.S) {
+	var (
+		verifier     *lifecycle.ReproducibilityVerifier
+		appImage     *fakes.Image
+		rebuiltImage *fakes.Image
+		logHandler   *memory.Handler
+	)
+
+	layersMetadata := func() files.LayersMetadata {
+		return files.LayersMetadata{
+			App: []files.LayerMetadata{{SHA: "sha256:app"}},
+			Buildpacks: []buildpack.LayersMetadata{
+				{ID: "some/buildpack", Layers: map[string]buildpack.LayerMetadata{
+					"some-layer":  {SHA: "sha256:some-layer"},
+					"other-layer": {SHA: "sha256:other-layer"},
+				}},
+			},
+			Config:       files.LayerMetadata{SHA: "sha256:config"},
+			Launcher:     files.LayerMetadata{SHA: "sha256:launcher"},
+			ProcessTypes: files.LayerMetadata{SHA: "sha256:process-types"},
+			RunImage:     files.RunImageForRebase{TopLayer: "sha256:run-image"},
+		}
+	}
+
+	setMetadata := func(image *fakes.Image, md files.LayersMetadata) {
+		contents, err := json.Marshal(md)
+		h.AssertNil(t, err)
+		h.AssertNil(t, image.SetLabel(platform.LifecycleMetadataLabel, string(contents)))
+	}
+
+	it.Before(func() {
+		appImage = fakes.NewImage("some-repo/app-image", "", local.IDIdentifier{ImageID: "some-image-id"})
+		rebuiltImage = fakes.NewImage("some-repo/app-image:rebuilt", "", local.IDIdentifier{ImageID: "other-image-id"})
+		logHandler = memory.New()
+		verifier = &lifecycle.ReproducibilityVerifier{Logger: &log.Logger{Handler: logHandler}}
+		setMetadata(appImage, layersMetadata())
+	})
+
+	it.After(func() {
+		h.AssertNil(t, appImage.Cleanup())
+		h.AssertNil(t, rebuiltImage.Cleanup())
+	})
+
+	when("the layers are the same", func() {
+		it("reports the image as reproducible", func() {
+			setMetadata(rebuiltImage, layersMetadata())
+
+			report, err := verifier.Verify(appImage, rebuiltImage)
+			h.AssertNil(t, err)
+			h.AssertEq(t, report, files.ReproducibilityReport{
+				Image:        "some-repo/app-image",
+				Rebuilt:      "some-repo/app-image:rebuilt",
+				Reproducible: true,
+			})
+		})
+	})
+
+	when("layers differ", func() {
+		it("reports the layers that differ with the buildpacks that produced them", func() {
+			md := layersMetadata()
+			md.Buildpacks[0].Layers["other-layer"] = buildpack.LayerMetadata{SHA: "sha256:changed"}
+			md.Buildpacks = append(md.Buildpacks, buildpack.LayersMetadata{ID: "other/buildpack", Layers: map[string]buildpack.LayerMetadata{
+				"new-layer": {SHA: "sha256:new-layer"},
+			}})
+			md.App = append(md.App, files.LayerMetadata{SHA: "sha256:slice"})
+			md.Config.SHA = "sha256:other-config"
+			setMetadata(rebuiltImage, md)
+
+			report, err := verifier.Verify(appImage, rebuiltImage)
+			h.AssertNil(t, err)
+			h.AssertEq(t, report.Reproducible, false)
+			h.AssertEq(t, report.Differences, []files.LayerDifference{
+				{Layer: "other-layer", Producer: "some/buildpack", Expected: "sha256:other-layer", Actual: "sha256:changed"},
+				{Layer: "new-layer", Producer: "other/buildpack", Expected: "", Actual: "sha256:new-layer"},
+				{Layer: "app-1", Expected: "", Actual: "sha256:slice"},
+				{Layer: "config", Expected: "sha256:config", Actual: "sha256:other-config"},
+			})
+			h.AssertLogEntry(t, logHandler, "Layer 'other-layer' produced by 'some/buildpack' differs: expected 'sha256:other-layer', got 'sha256:changed'")
+		})
+
+		it("reports the layers of the extended run image with the extensions that produced them", func() {
+			md := layersMetadata()
+			md.RunImage.ExtensionLayers = []files.ExtensionLayer{{ExtensionID: "some/extension", DiffID: "sha256:extension"}}
+			setMetadata(rebuiltImage, md)
+
+			report, err := verifier.Verify(appImage, rebuiltImage)
+			h.AssertNil(t, err)
+			h.AssertEq(t, report.Differences, []files.LayerDifference{
+				{Layer: "extension-0", Producer: "some/extension", Expected: "", Actual: "sha256:extension"},
+			})
+		})
+	})
+}
@@ -1,17 +1,21 @@
 package lifecycle
 
 import (
+	"context"
 	"path/filepath"
 
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/archive"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/internal/layer"
 	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/log"
 	"github.com/buildpacks/lifecycle/platform/files"
+	"github.com/buildpacks/lifecycle/telemetry/metrics"
+	"github.com/buildpacks/lifecycle/telemetry/tracing"
 )
 
 type Restorer struct {
@@ -23,12 +27,32 @@ type Restorer struct {
 	LayersMetadata        files.LayersMetadata   // Platform API >= 0.7
 	PlatformAPI           *api.Version
 	SBOMRestorer          layer.SBOMRestorer
+	// DigestCache (if provided) records the digests of the restored layers, so that the exporter does not hash them again
+	// if they are unchanged.
+	DigestCache *layers.DigestCache
+	// LenientExtraction skips the entries of cached layers that would be written outside of the layers directory,
+	// rather than failing the restore.
+	LenientExtraction bool
+	// Xattrs are the prefixes of the names of the extended attributes (e.g., "security.capability" for file capabilities)
+	// that are restored from cached layers.
+	Xattrs []string
 }
 
-// Restore restores metadata for launch and cache layers into the layers directory and attempts to restore layer data for cache=true layers, removing the layer when unsuccessful.
-// If a usable cache is not provided, Restore will not restore any cache=true layer metadata.
+// Restore is equivalent to RestoreContext with context.Background().
+//
+// Deprecated: use RestoreContext.
 func (r *Restorer) Restore(cache Cache) error {
+	return r.RestoreContext(context.Background(), cache)
+}
+
+// RestoreContext restores metadata for launch and cache layers into the layers directory and attempts to restore layer data for cache=true layers, removing the layer when unsuccessful.
+// If a usable cache is not provided, RestoreContext will not restore any cache=true layer metadata.
+// If the context is done, the extraction of the cached layers is stopped and the context error is returned.
+func (r *Restorer) RestoreContext(ctx context.Context, cache Cache) error {
 	defer log.NewMeasurement("Restorer", r.Logger)()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	cacheMeta, err := retrieveCacheMetadata(cache, r.Logger)
 	if err != nil {
 		return err
@@ -43,7 +67,10 @@ func (r *Restorer) Restore(cache Cache) error {
 		}
 	}
 
-	var g errgroup.Group
+	var (
+		g        errgroup.Group
+		restored []*restoredLayer
+	)
 	for _, bp := range r.Buildpacks {
 		cachedLayers := cacheMeta.MetadataForBuildpack(bp.ID).Layers
 
@@ -74,6 +101,7 @@ func (r *Restorer) Restore(cache Cache) error {
 			cachedLayer, exists := cachedLayers[bpLayer.Name()]
 			if !exists {
 				r.Logger.Infof("Removing %q, not in cache", bpLayer.Identifier())
+				metrics.Add(metrics.LayerCacheLookups, 1, metrics.L("cache", metrics.CacheBuild), metrics.L("result", metrics.ResultMiss))
 				if err := bpLayer.Remove(); err != nil {
 					return errors.Wrapf(err, "removing layer")
 				}
@@ -87,14 +115,22 @@ func (r *Restorer) Restore(cache Cache) error {
 
 			if layerSha != cachedLayer.SHA {
 				r.Logger.Infof("Removing %q, wrong sha", bpLayer.Identifier())
+				metrics.Add(metrics.LayerCacheLookups, 1, metrics.L("cache", metrics.CacheBuild), metrics.L("result", metrics.ResultMiss))
 				r.Logger.Debugf("Layer sha: %q, cache sha: %q", layerSha, cachedLayer.SHA)
 				if err := bpLayer.Remove(); err != nil {
 					return errors.Wrapf(err, "removing layer")
 				}
 			} else {
 				r.Logger.Infof("Restoring data for %q from cache", bpLayer.Identifier())
+				metrics.Add(metrics.LayerCacheLookups, 1, metrics.L("cache", metrics.CacheBuild), metrics.L("result", metrics.ResultHit))
+				span := tracing.Start("layer "+bpLayer.Identifier(), tracing.String("cnb.layer.id", bpLayer.Identifier()), tracing.String("cnb.layer.diff_id", cachedLayer.SHA))
+				rl := &restoredLayer{dir: bpLayer.Path(), digest: cachedLayer.SHA}
+				restored = append(restored, rl)
 				g.Go(func() error {
-					return r.restoreCacheLayer(cache, cachedLayer.SHA)
+					var err error
+					rl.head, err = r.restoreCacheLayer(ctx, cache, rl.digest)
+					span.End(err)
+					return err
 				})
 			}
 		}
@@ -113,27 +149,78 @@ func (r *Restorer) Restore(cache Cache) error {
 	}
 
 	if err := g.Wait(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		return errors.Wrap(err, "restoring data")
 	}
+	r.recordDigests(restored)
 
 	return nil
 }
 
+// restoredLayer is a layer directory that was extracted from the cached tarball with the digest,
+// and the start of the tarball (see layers.TarHead), unless the layer was not fully restored.
+type restoredLayer struct {
+	dir    string
+	digest string
+	head   []byte
+}
+
+// recordDigests records the digests of the restored layers in the digest cache, if provided.
+// Failing to do so only means that the exporter hashes the layers.
+func (r *Restorer) recordDigests(restored []*restoredLayer) {
+	if r.DigestCache == nil || len(restored) == 0 {
+		return
+	}
+	for _, rl := range restored {
+		if rl.head == nil {
+			continue
+		}
+		if err := r.DigestCache.RecordRestored(rl.dir, rl.digest, rl.head, r.Xattrs); err != nil {
+			r.Logger.Warnf("Failed to record digest of layer '%s': %s", rl.dir, err)
+		}
+	}
+	if err := r.DigestCache.Save(); err != nil {
+		r.Logger.Warnf("Failed to save digest cache: %s", err)
+	}
+}
+
 func (r *Restorer) restoresLayerMetadata() bool {
 	return r.PlatformAPI.AtLeast("0.7")
 }
 
-func (r *Restorer) restoreCacheLayer(cache Cache, sha string) error {
+// restoreCacheLayer extracts the cached layer with the provided sha, and returns the start of its tarball
+// if the layer was fully restored.
+func (r *Restorer) restoreCacheLayer(ctx context.Context, cache Cache, sha string) ([]byte, error) {
 	// Sanity check to prevent panic.
 	if cache == nil {
-		return errors.New("restoring layer: cache not provided")
+		return nil, errors.New("restoring layer: cache not provided")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 	r.Logger.Debugf("Retrieving data for %q", sha)
 	rc, err := cache.RetrieveLayer(sha)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rc.Close()
 
-	return layers.Extract(rc, "")
+	head := layers.NewTarHead(&contextReader{ctx: ctx, r: rc})
+	report, err := layers.ExtractWithOptions(head, "", archive.ExtractOptions{Root: r.LayersDir, Lenient: r.LenientExtraction, Xattrs: r.Xattrs})
+	if err != nil {
+		return nil, err
+	}
+	for _, rejection := range report.Rejections {
+		r.Logger.Warnf("Skipped unsafe entry of cached layer %q: %s", sha, rejection)
+	}
+	for _, skipped := range report.SkippedXattrs {
+		r.Logger.Warnf("Cached layer %q was not fully restored: %s", sha, skipped)
+	}
+	if !report.Complete() {
+		// the restored layer no longer matches the tarball, so its digest must not be recorded
+		return nil, nil
+	}
+	return head.Bytes(), nil
 }
@@ -1,10 +1,14 @@
 package lifecycle_test
 
 import (
+	"archive/tar"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/apex/log"
@@ -94,6 +98,18 @@ func testRestorer(buildpackAPI, platformAPI string) func(t *testing.T, when spec
 				mockCtrl.Finish()
 			})
 
+			when("the context is done", func() {
+				it("does not restore the layers and returns the context error", func() {
+					h.AssertNil(t, writeLayer(layersDir, "buildpack.id", "some-layer", "", ""))
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel()
+
+					err := restorer.RestoreContext(ctx, testCache)
+					h.AssertEq(t, errors.Is(err, context.Canceled), true)
+					h.AssertPathExists(t, filepath.Join(layersDir, "buildpack.id", "some-layer.toml"))
+				})
+			})
+
 			when("there is no cache", func() {
 				when("there is a cache=true layer", func() {
 					it.Before(func() {
@@ -345,6 +361,10 @@ func testRestorer(buildpackAPI, platformAPI string) func(t *testing.T, when spec
 							sha = cacheOnlyLayerSHA
 						}
 						h.AssertNil(t, writeLayer(layersDir, "buildpack.id", "cache-only", meta, sha))
+						// as they were when the layer was cached
+						h.AssertNil(t, os.Chmod(layersDir, 0775))
+						h.AssertNil(t, os.Chmod(filepath.Join(layersDir, "buildpack.id"), 0775))
+						restorer.DigestCache = layers.DigestCacheFor(layersDir, false)
 						h.AssertNil(t, restorer.Restore(testCache))
 					})
 
@@ -364,6 +384,34 @@ func testRestorer(buildpackAPI, platformAPI string) func(t *testing.T, when spec
 						want := "echo text from cache-only layer\n"
 						h.AssertEq(t, string(got), want)
 					})
+
+					it("records the digest of the restored layer", func() {
+						h.SkipIf(t, runtime.GOOS != "linux", "digest cache is only supported on linux")
+						layerDir := filepath.Join(layersDir, "buildpack.id", "cache-only")
+						digest, ok := layers.ReadDigestCache(filepath.Join(layersDir, layers.DigestCacheFile)).Lookup(layerDir, 0, 0, nil)
+						h.AssertEq(t, ok, true)
+						h.AssertEq(t, digest, cacheOnlyLayerSHA)
+
+						// the restored layer hashes to the recorded digest
+						lf := layers.Factory{ArtifactsDir: t.TempDir(), Logger: &log.Logger{Handler: memory.New()}}
+						layer, err := lf.DirLayer("buildpack.id:cache-only", layerDir, "")
+						h.AssertNil(t, err)
+						h.AssertEq(t, layer.Digest, digest)
+					})
+
+					when("the parents of the layer changed since it was cached", func() {
+						it.Before(func() {
+							h.AssertNil(t, os.Remove(filepath.Join(layersDir, layers.DigestCacheFile)))
+							h.AssertNil(t, os.Chmod(layersDir, 0750))
+							restorer.DigestCache = layers.DigestCacheFor(layersDir, false)
+							h.AssertNil(t, restorer.Restore(testCache))
+						})
+
+						it("does not record the digest of the restored layer", func() {
+							_, ok := layers.ReadDigestCache(filepath.Join(layersDir, layers.DigestCacheFile)).Lookup(filepath.Join(layersDir, "buildpack.id", "cache-only"), 0, 0, nil)
+							h.AssertEq(t, ok, false)
+						})
+					})
 				})
 
 				when("there is a cache=false layer", func() {
@@ -637,6 +685,69 @@ func testRestorer(buildpackAPI, platformAPI string) func(t *testing.T, when spec
 				})
 			})
 
+			when("there is a cache with an unsafe layer", func() {
+				var outsidePath string
+
+				it.Before(func() {
+					h.SkipIf(t, runtime.GOOS == "windows", "layer paths are prefixed on windows")
+					outsidePath = filepath.Join(filepath.Dir(layersDir), "outside-"+filepath.Base(layersDir))
+					layerDir := filepath.Join(layersDir, "buildpack.id", "cache-only")
+
+					tarPath := filepath.Join(t.TempDir(), "unsafe.tar")
+					f, err := os.Create(tarPath)
+					h.AssertNil(t, err)
+					tw := tar.NewWriter(f)
+					for _, hdr := range []*tar.Header{
+						{Name: layerDir, Typeflag: tar.TypeDir, Mode: 0755},
+						{Name: filepath.Join(layerDir, "file"), Typeflag: tar.TypeReg, Mode: 0644},
+						{Name: filepath.Join(layerDir, "..", "..", "..", filepath.Base(outsidePath)), Typeflag: tar.TypeReg, Mode: 0644},
+					} {
+						hdr.Name = strings.TrimPrefix(hdr.Name, "/")
+						h.AssertNil(t, tw.WriteHeader(hdr))
+					}
+					h.AssertNil(t, tw.Close())
+					h.AssertNil(t, f.Close())
+
+					h.AssertNil(t, testCache.AddLayerFile(tarPath, "sha256:unsafe"))
+					h.AssertNil(t, testCache.SetMetadata(platform.CacheMetadata{Buildpacks: []buildpack.LayersMetadata{{
+						ID: "buildpack.id",
+						Layers: map[string]buildpack.LayerMetadata{
+							"cache-only": {SHA: "sha256:unsafe", LayerMetadataFile: buildpack.LayerMetadataFile{Cache: true}},
+						},
+					}}}))
+					h.AssertNil(t, testCache.Commit())
+
+					var meta, sha string
+					if api.MustParse(buildpackAPI).LessThan("0.6") {
+						meta = "build = false\nlaunch = false\ncache = true\n"
+					}
+					if api.MustParse(platformAPI).LessThan("0.7") {
+						sha = "sha256:unsafe"
+					}
+					h.AssertNil(t, writeLayer(layersDir, "buildpack.id", "cache-only", meta, sha))
+					if api.MustParse(platformAPI).AtLeast("0.8") {
+						sbomRestorer.EXPECT().RestoreFromCache(gomock.Any(), gomock.Any()).AnyTimes()
+					}
+				})
+
+				it("fails without extracting outside of the layers directory", func() {
+					err := restorer.Restore(testCache)
+					h.AssertNotNil(t, err)
+					h.AssertStringContains(t, err.Error(), "rejected 1 entries that are unsafe to extract")
+					h.AssertPathDoesNotExist(t, outsidePath)
+				})
+
+				when("extraction is lenient", func() {
+					it("skips the unsafe entries", func() {
+						restorer.LenientExtraction = true
+						h.AssertNil(t, restorer.Restore(testCache))
+						h.AssertPathExists(t, filepath.Join(layersDir, "buildpack.id", "cache-only", "file"))
+						h.AssertPathDoesNotExist(t, outsidePath)
+						h.AssertLogEntry(t, logHandler, "Skipped unsafe entry of cached layer")
+					})
+				})
+			})
+
 			when("there is a cache with BOM information", func() {
 				var (
 					tmpDir string
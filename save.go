@@ -6,6 +6,9 @@ import (
 	"github.com/buildpacks/imgutil"
 	"github.com/buildpacks/imgutil/local"
 	"github.com/buildpacks/imgutil/remote"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/lifecycle/log"
@@ -17,15 +20,35 @@ func saveImage(image imgutil.Image, additionalNames []string, logger log.Logger)
 }
 
 func saveImageAs(image imgutil.Image, name string, additionalNames []string, logger log.Logger) (files.ImageReport, error) {
+	return saveImageWithKeychain(image, name, additionalNames, nil, logger)
+}
+
+// saveImageWithKeychain saves the image like saveImageAs. If a keychain is provided and the image is in a registry,
+// the image is only saved by imgutil to the additional names in the same repository as name,
+// and is then pushed to the additional names in other repositories (see pushToRepositories).
+func saveImageWithKeychain(image imgutil.Image, name string, additionalNames []string, keychain authn.Keychain, logger log.Logger) (files.ImageReport, error) {
 	defer log.NewMeasurement("Saving "+name+"...", logger)()
 	var saveErr error
 	imageReport := files.ImageReport{}
-	if err := image.SaveAs(name, additionalNames...); err != nil {
+	sameRepo, otherRepos := additionalNames, []string(nil)
+	if _, ok := image.(*remote.Image); ok && keychain != nil {
+		sameRepo, otherRepos = splitByRepository(name, additionalNames)
+	}
+	if err := image.SaveAs(name, sameRepo...); err != nil {
 		var ok bool
 		if saveErr, ok = err.(imgutil.SaveError); !ok {
 			return files.ImageReport{}, errors.Wrap(err, "saving image")
 		}
 	}
+	if len(otherRepos) > 0 {
+		if err := pushToRepositories(image.(*remote.Image).UnderlyingImage(), name, otherRepos, keychain); err != nil {
+			pushErr, ok := err.(imgutil.SaveError)
+			if !ok {
+				return files.ImageReport{}, errors.Wrap(err, "pushing image")
+			}
+			saveErr = mergeSaveErrors(saveErr, pushErr)
+		}
+	}
 
 	id, idErr := image.Identifier()
 	if idErr != nil {
@@ -66,6 +89,56 @@ func saveImageAs(image imgutil.Image, name string, additionalNames []string, log
 	return imageReport, saveErr
 }
 
+// dryRunImage reports the image without saving it: for images in a registry, the digest and manifest size the image
+// would have once saved (by applying the normalization that imgutil applies when saving), and its manifest is logged.
+// The image is not tagged, so the report has no tags.
+func dryRunImage(img imgutil.Image, name string, logger log.Logger) (files.ImageReport, error) {
+	underlying, ok := img.(interface{ UnderlyingImage() v1.Image })
+	if !ok {
+		logger.Infof("*** Dry run: %s was not saved; its digest is only known for images in a registry\n", name)
+		return files.ImageReport{}, nil
+	}
+	saved, err := normalizeForSave(underlying.UnderlyingImage())
+	if err != nil {
+		return files.ImageReport{}, errors.Wrap(err, "normalizing image")
+	}
+	digest, err := saved.Digest()
+	if err != nil {
+		return files.ImageReport{}, errors.Wrap(err, "getting image digest")
+	}
+	manifest, err := saved.RawManifest()
+	if err != nil {
+		return files.ImageReport{}, errors.Wrap(err, "getting image manifest")
+	}
+	logger.Infof("*** Dry run: %s was not saved; it would have digest %s\n", name, digest)
+	logger.Infof("*** Manifest:\n%s\n", manifest)
+	return files.ImageReport{Digest: digest.String(), ManifestSize: int64(len(manifest))}, nil
+}
+
+// normalizeForSave applies the changes imgutil makes to remote images without history when saving them:
+// the creation time is normalized, the history is reduced to the creation time of each layer,
+// and the docker version and container are removed.
+func normalizeForSave(img v1.Image) (v1.Image, error) {
+	created := v1.Time{Time: imgutil.NormalizedDateTime}
+	img, err := mutate.CreatedAt(img, created)
+	if err != nil {
+		return nil, err
+	}
+	if img, err = imgutil.OverrideHistoryIfNeeded(img); err != nil {
+		return nil, err
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	for i := range cfg.History {
+		cfg.History[i] = v1.History{Created: created}
+	}
+	cfg.DockerVersion = ""
+	cfg.Container = ""
+	return mutate.ConfigFile(img, cfg)
+}
+
 type MultiError struct {
 	Errors []error
 }
@@ -85,6 +158,16 @@ func shortID(identifier imgutil.Identifier) string {
 	}
 }
 
+// mergeSaveErrors returns an imgutil.SaveError with the names that could not be saved by imgutil (if any) or pushed.
+func mergeSaveErrors(saveErr error, pushErr imgutil.SaveError) imgutil.SaveError {
+	if saveErr == nil {
+		return pushErr
+	}
+	merged := saveErr.(imgutil.SaveError)
+	merged.Errors = append(append([]imgutil.SaveDiagnostic{}, merged.Errors...), pushErr.Errors...)
+	return merged
+}
+
 func getSaveStatus(err error, imageName string) (bool, string) {
 	if err != nil {
 		if saveErr, ok := err.(imgutil.SaveError); ok {
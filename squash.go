@@ -0,0 +1,113 @@
+package lifecycle
+
+import (
+	"io/fs"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/layers"
+)
+
+// SquashedLayerID is the ID of the layer that the launch layers selected by the LayerSquashPolicy are squashed into.
+const SquashedLayerID = "buildpacksio/lifecycle:squashed"
+
+// LayerSquashPolicy selects the launch layers that the exporter squashes into a single layer,
+// trading the granularity of layer reuse for a reduced layer count, e.g., for registries or runtimes that limit the number of layers.
+// A layer is selected if it matches any of the criteria. Layers reused from the previous image (i.e., without contents) are never squashed.
+// The squashed layer is reused as a whole, when none of the selected layers changed.
+type LayerSquashPolicy struct {
+	// MaxLayerSize selects the layers whose contents are smaller than MaxLayerSize bytes, if positive.
+	MaxLayerSize int64
+	// Buildpacks selects all the layers of the buildpacks with the provided IDs.
+	Buildpacks []string
+}
+
+func (p LayerSquashPolicy) selects(buildpackID, layerDir string) (bool, error) {
+	for _, id := range p.Buildpacks {
+		if id == buildpackID {
+			return true, nil
+		}
+	}
+	if p.MaxLayerSize <= 0 {
+		return false, nil
+	}
+	size, err := dirSize(layerDir)
+	if err != nil {
+		return false, err
+	}
+	return size < p.MaxLayerSize, nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// squashedLayer is a launch layer selected by the LayerSquashPolicy.
+type squashedLayer struct {
+	buildpackID string
+	layer       buildpack.Layer
+	createdBy   string
+	metadata    buildpack.LayerMetadata
+	bpLayers    map[string]buildpack.LayerMetadata // the layers metadata of the buildpack, updated once the layer is added
+}
+
+// addSquashedLayer adds the selected layers as a single layer, and records its SHA in the metadata of each selected layer.
+// A single selected layer is added on its own.
+func (e *Exporter) addSquashedLayer(opts ExportOptions, toSquash []squashedLayer) error {
+	switch len(toSquash) {
+	case 0:
+		return nil
+	case 1:
+		sl := toSquash[0]
+		sha, err := e.addLocalBuildpackLayer(opts, sl.buildpackID, sl.layer, sl.createdBy)
+		if err != nil {
+			return err
+		}
+		sl.metadata.SHA = sha
+		sl.bpLayers[sl.layer.Name()] = sl.metadata
+		return nil
+	}
+
+	var (
+		dirs        []string
+		previousSHA string
+	)
+	for _, sl := range toSquash {
+		e.Logger.Debugf("Squashing layer '%s'", sl.layer.Identifier())
+		dirs = append(dirs, sl.layer.Path())
+		if orig, ok := opts.OrigMetadata.LayersMetadataFor(sl.buildpackID).Layers[sl.layer.Name()]; ok && orig.Squashed && previousSHA == "" {
+			previousSHA = orig.SHA
+		}
+	}
+	layer, err := e.LayerFactory.DirsLayer(SquashedLayerID, dirs, layers.SquashedLayerName)
+	if err != nil {
+		return errors.Wrapf(err, "creating squashed layer")
+	}
+	sha, err := e.addOrReuseBuildpackLayer(opts.WorkingImage, layer, previousSHA, layers.SquashedLayerName)
+	if err != nil {
+		return err
+	}
+	for _, sl := range toSquash {
+		sl.metadata.SHA = sha
+		sl.metadata.Squashed = true
+		sl.bpLayers[sl.layer.Name()] = sl.metadata
+	}
+	return nil
+}
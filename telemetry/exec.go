@@ -0,0 +1,49 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// DefaultExecTimeout is the maximum time that an executable reporter may run for each event.
+const DefaultExecTimeout = 10 * time.Second
+
+// ExecHook reports events by running an executable provided by the platform.
+//
+// The reporter is run with no arguments and the event encoded as a single JSON object on stdin, e.g.:
+//
+//	{"phase":"builder","lifecycle-version":"0.17.0","platform-api":"0.12","start-time":"2023-01-01T00:00:00Z",
+//	 "duration-ns":1500000000,"outcome":"failure","exit-code":51,"error-class":"buildpack"}
+//
+// The output of the reporter is discarded, and a reporter that fails or exceeds the timeout
+// does not affect the outcome of the phase. Reporters that forward events over the network
+// should do so asynchronously, or fork, to avoid delaying the build.
+type ExecHook struct {
+	Path    string
+	Timeout time.Duration
+}
+
+// NewExecHook returns a hook that runs the executable at the provided path for each event.
+func NewExecHook(path string) *ExecHook {
+	return &ExecHook{Path: path, Timeout: DefaultExecTimeout}
+}
+
+// Report runs the reporter with the event on stdin.
+func (e *ExecHook) Report(event Event) error {
+	contents, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, e.Path) // #nosec G204
+	cmd.Stdin = bytes.NewReader(contents)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run telemetry reporter '%s': %w", e.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+// Package metrics records per-build metrics of lifecycle phases in the Prometheus text format,
+// either to a directory read by the node_exporter textfile collector, or to a Pushgateway,
+// so that platform operators can build fleet dashboards without scraping logs.
+//
+// Metrics are opt-in: nothing is recorded unless a destination is provided (see EnvMetricsTextfileDir and EnvMetricsPushgateway).
+// Like spans (see the tracing package), metrics include identifiers such as buildpack IDs.
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvMetricsTextfileDir is a directory to which each phase writes its metrics as lifecycle_<phase>.prom,
+	// e.g., the directory read by the node_exporter textfile collector.
+	EnvMetricsTextfileDir = "CNB_METRICS_TEXTFILE_DIR"
+	// EnvMetricsPushgateway is the URL of a Prometheus Pushgateway to which each phase pushes its metrics,
+	// grouped by job ("lifecycle"), phase and the labels provided in EnvMetricsLabels.
+	EnvMetricsPushgateway = "CNB_METRICS_PUSHGATEWAY_URL"
+	// EnvMetricsLabels are labels added to every metric, as a comma-separated list of name=value pairs,
+	// e.g., "build_id=1234,namespace=some-team".
+	EnvMetricsLabels = "CNB_METRICS_LABELS"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Metric descriptions; every metric also has a phase label.
+var (
+	// PhaseDuration is set when the phase completes, with an outcome label.
+	PhaseDuration = &Metric{Name: "lifecycle_phase_duration_seconds", Help: "Duration of the lifecycle phase.", Type: TypeGauge}
+	// BuildpackDuration has buildpack and version labels, and a step label (detect, build or generate).
+	BuildpackDuration = &Metric{Name: "lifecycle_buildpack_duration_seconds", Help: "Duration of running a buildpack or extension.", Type: TypeGauge}
+	// LayerCacheLookups has a cache label (build-cache or previous-image) and a result label (hit or miss).
+	LayerCacheLookups = &Metric{Name: "lifecycle_layer_cache_lookups_total", Help: "Layers that were reused from (hit) or not found in (miss) a cache.", Type: TypeCounter}
+	// RegistryRequests has a registry label.
+	RegistryRequests = &Metric{Name: "lifecycle_registry_requests_total", Help: "Registry requests, including retries.", Type: TypeCounter}
+	// CacheLockContentions has an outcome label (acquired, recovered or timed-out), and counts the times the lock
+	// of a volume cache was held by another build, e.g., when concurrent builds share a cache volume.
+	CacheLockContentions = &Metric{Name: "lifecycle_cache_lock_contentions_total", Help: "Times the lock of a volume cache was held by another build.", Type: TypeCounter}
+	// CacheLockWait is the time spent waiting for the lock of a volume cache.
+	CacheLockWait = &Metric{Name: "lifecycle_cache_lock_wait_seconds_total", Help: "Time spent waiting for the lock of a volume cache.", Type: TypeCounter}
+	// RegistryBytes has a registry label and a direction label (pushed or pulled).
+	RegistryBytes = &Metric{Name: "lifecycle_registry_bytes_total", Help: "Bytes sent to (pushed) and received from (pulled) registries.", Type: TypeCounter}
+)
+
+const (
+	TypeCounter = "counter"
+	TypeGauge   = "gauge"
+
+	CacheBuild         = "build-cache"
+	CachePreviousImage = "previous-image"
+	ResultHit          = "hit"
+	ResultMiss         = "miss"
+	DirectionPushed    = "pushed"
+	DirectionPulled    = "pulled"
+	LockAcquired       = "acquired"
+	LockRecovered      = "recovered"
+	LockTimedOut       = "timed-out"
+)
+
+// Metric is a Prometheus metric.
+type Metric struct {
+	Name string
+	Help string
+	Type string
+}
+
+// Label is a metric label.
+type Label struct {
+	Name  string
+	Value string
+}
+
+func L(name, value string) Label {
+	return Label{Name: name, Value: value}
+}
+
+type sample struct {
+	metric *Metric
+	labels []Label
+	value  float64
+}
+
+// Recorder records the metrics of a phase.
+type Recorder struct {
+	phase  string
+	labels []Label
+	start  time.Time
+
+	mu      sync.Mutex
+	samples map[string]*sample
+}
+
+// NewRecorder returns a recorder for the provided phase that adds the provided labels to every metric.
+func NewRecorder(phase string, labels ...Label) *Recorder {
+	return &Recorder{
+		phase:   phase,
+		labels:  append([]Label{L("phase", phase)}, labels...),
+		start:   time.Now(),
+		samples: map[string]*sample{},
+	}
+}
+
+// Add adds the value to the counter (or gauge) with the provided labels.
+func (r *Recorder) Add(metric *Metric, value float64, labels ...Label) {
+	r.update(metric, labels, func(s *sample) { s.value += value })
+}
+
+// Set sets the gauge with the provided labels to the value.
+func (r *Recorder) Set(metric *Metric, value float64, labels ...Label) {
+	r.update(metric, labels, func(s *sample) { s.value = value })
+}
+
+func (r *Recorder) update(metric *Metric, labels []Label, fn func(s *sample)) {
+	all := append(append([]Label{}, r.labels...), labels...)
+	key := metric.Name + format(all)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.samples[key]
+	if !ok {
+		s = &sample{metric: metric, labels: all}
+		r.samples[key] = s
+	}
+	fn(s)
+}
+
+// Complete records the duration and outcome of the phase.
+func (r *Recorder) Complete(err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeFailure
+	}
+	r.Set(PhaseDuration, time.Since(r.start).Seconds(), L("outcome", outcome))
+}
+
+// Text returns the metrics in the Prometheus text format.
+func (r *Recorder) Text() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	byMetric := map[string][]string{}
+	metrics := map[string]*Metric{}
+	for _, s := range r.samples {
+		metrics[s.metric.Name] = s.metric
+		byMetric[s.metric.Name] = append(byMetric[s.metric.Name], s.metric.Name+format(s.labels)+" "+strconv.FormatFloat(s.value, 'g', -1, 64))
+	}
+	var names []string
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, metrics[name].Help, name, metrics[name].Type)
+		lines := byMetric[name]
+		sort.Strings(lines)
+		for _, line := range lines {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+func format(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, l := range labels {
+		parts = append(parts, fmt.Sprintf("%s=%s", l.Name, strconv.Quote(l.Value)))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// ParseLabels parses a comma-separated list of name=value pairs.
+func ParseLabels(val string) ([]Label, error) {
+	var labels []Label
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, found := strings.Cut(entry, "=")
+		if !found || !validLabelName(name) {
+			return nil, fmt.Errorf("invalid %s entry '%s': must be <name>=<value>, where the name only contains letters, digits and underscores", EnvMetricsLabels, entry)
+		}
+		labels = append(labels, L(name, value))
+	}
+	return labels, nil
+}
+
+func validLabelName(name string) bool {
+	if name == "" || name == "phase" || strings.HasPrefix(name, "__") {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	defaultRecorderMu sync.Mutex
+	defaultRecorder   *Recorder
+	defaultSinks      []Sink
+)
+
+// Sink writes the metrics of a phase when it completes.
+type Sink interface {
+	Write(phase string, labels []Label, text string) error
+}
+
+// Configure enables metrics for the provided phase if a destination was provided in the environment.
+func Configure(phase string, getenv func(string) string) error {
+	labels, err := ParseLabels(getenv(EnvMetricsLabels))
+	if err != nil {
+		return err
+	}
+	var sinks []Sink
+	if dir := getenv(EnvMetricsTextfileDir); dir != "" {
+		sinks = append(sinks, &TextfileSink{Dir: dir})
+	}
+	if endpoint := getenv(EnvMetricsPushgateway); endpoint != "" {
+		sinks = append(sinks, NewPushgatewaySink(endpoint))
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	defaultRecorderMu.Lock()
+	defer defaultRecorderMu.Unlock()
+	defaultRecorder = NewRecorder(phase, labels...)
+	defaultSinks = sinks
+	return nil
+}
+
+// Reset disables metrics.
+func Reset() {
+	defaultRecorderMu.Lock()
+	defer defaultRecorderMu.Unlock()
+	defaultRecorder = nil
+	defaultSinks = nil
+}
+
+func current() *Recorder {
+	defaultRecorderMu.Lock()
+	defer defaultRecorderMu.Unlock()
+	return defaultRecorder
+}
+
+// Enabled returns true if metrics are enabled.
+func Enabled() bool {
+	return current() != nil
+}
+
+// Add adds the value to the counter with the provided labels, if metrics are enabled.
+func Add(metric *Metric, value float64, labels ...Label) {
+	if r := current(); r != nil {
+		r.Add(metric, value, labels...)
+	}
+}
+
+// Set sets the gauge with the provided labels to the value, if metrics are enabled.
+func Set(metric *Metric, value float64, labels ...Label) {
+	if r := current(); r != nil {
+		r.Set(metric, value, labels...)
+	}
+}
+
+// Timer returns a function that sets the gauge to the number of seconds since Timer was called.
+func Timer(metric *Metric, labels ...Label) func() {
+	start := time.Now()
+	return func() {
+		Set(metric, time.Since(start).Seconds(), labels...)
+	}
+}
+
+// Complete records the outcome of the phase and writes the metrics, if metrics are enabled.
+// All sinks are written to, even if some of them fail.
+func Complete(err error) error {
+	defaultRecorderMu.Lock()
+	r, sinks := defaultRecorder, defaultSinks
+	defaultRecorderMu.Unlock()
+	if r == nil {
+		return nil
+	}
+	r.Complete(err)
+	text := r.Text()
+	var errs []string
+	for _, sink := range sinks {
+		if err := sink.Write(r.phase, r.labels[1:], text); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to write metrics: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
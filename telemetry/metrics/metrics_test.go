@@ -0,0 +1,179 @@
+package metrics_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/telemetry/metrics"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestMetrics(t *testing.T) {
+	spec.Run(t, "Metrics", testMetrics, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testMetrics(t *testing.T, when spec.G, it spec.S) {
+	when("Recorder", func() {
+		when("#Text", func() {
+			it("returns the metrics in the Prometheus text format", func() {
+				r := metrics.NewRecorder("build", metrics.L("build_id", "1234"))
+				r.Add(metrics.LayerCacheLookups, 1, metrics.L("cache", metrics.CacheBuild), metrics.L("result", metrics.ResultMiss))
+				r.Add(metrics.LayerCacheLookups, 1, metrics.L("cache", metrics.CacheBuild), metrics.L("result", metrics.ResultHit))
+				r.Add(metrics.LayerCacheLookups, 1, metrics.L("cache", metrics.CacheBuild), metrics.L("result", metrics.ResultHit))
+				r.Set(metrics.BuildpackDuration, 1.5, metrics.L("buildpack", "some/bp"), metrics.L("version", "v1"), metrics.L("step", "build"))
+
+				h.AssertEq(t, r.Text(), `# HELP lifecycle_buildpack_duration_seconds Duration of running a buildpack or extension.
+# TYPE lifecycle_buildpack_duration_seconds gauge
+lifecycle_buildpack_duration_seconds{phase="build",build_id="1234",buildpack="some/bp",version="v1",step="build"} 1.5
+# HELP lifecycle_layer_cache_lookups_total Layers that were reused from (hit) or not found in (miss) a cache.
+# TYPE lifecycle_layer_cache_lookups_total counter
+lifecycle_layer_cache_lookups_total{phase="build",build_id="1234",cache="build-cache",result="hit"} 2
+lifecycle_layer_cache_lookups_total{phase="build",build_id="1234",cache="build-cache",result="miss"} 1
+`)
+			})
+
+			it("escapes label values", func() {
+				r := metrics.NewRecorder("detect")
+				r.Add(metrics.RegistryRequests, 1, metrics.L("registry", "some\"registry\n"))
+
+				h.AssertStringContains(t, r.Text(), `lifecycle_registry_requests_total{phase="detect",registry="some\"registry\n"} 1`)
+			})
+		})
+
+		when("#Complete", func() {
+			it("records the duration and outcome of the phase", func() {
+				r := metrics.NewRecorder("export")
+				r.Complete(errors.New("some error"))
+
+				h.AssertStringContains(t, r.Text(), `lifecycle_phase_duration_seconds{phase="export",outcome="failure"} `)
+			})
+		})
+	})
+
+	when("ParseLabels", func() {
+		it("parses a comma-separated list of labels", func() {
+			labels, err := metrics.ParseLabels("build_id=1234, namespace=some-team,empty=")
+			h.AssertNil(t, err)
+			h.AssertEq(t, labels, []metrics.Label{
+				metrics.L("build_id", "1234"),
+				metrics.L("namespace", "some-team"),
+				metrics.L("empty", ""),
+			})
+		})
+
+		it("fails for invalid labels", func() {
+			for _, val := range []string{"no-value", "some-name=val", "1name=val", "phase=build", "__name=val"} {
+				_, err := metrics.ParseLabels(val)
+				h.AssertError(t, err, "invalid CNB_METRICS_LABELS entry")
+			}
+		})
+	})
+
+	when("Configure", func() {
+		var (
+			tmpDir string
+			env    map[string]string
+		)
+
+		getenv := func(key string) string { return env[key] }
+
+		it.Before(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "lifecycle.metrics")
+			h.AssertNil(t, err)
+			env = map[string]string{}
+		})
+
+		it.After(func() {
+			metrics.Reset()
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		when("no destination is provided", func() {
+			it("does not record metrics", func() {
+				h.AssertNil(t, metrics.Configure("build", getenv))
+				h.AssertEq(t, metrics.Enabled(), false)
+				metrics.Add(metrics.RegistryRequests, 1, metrics.L("registry", "some-registry"))
+				h.AssertNil(t, metrics.Complete(nil))
+			})
+		})
+
+		when("a textfile directory is provided", func() {
+			it("writes the metrics of the phase when it completes", func() {
+				env[metrics.EnvMetricsTextfileDir] = filepath.Join(tmpDir, "textfiles")
+				env[metrics.EnvMetricsLabels] = "build_id=1234"
+				h.AssertNil(t, metrics.Configure("build", getenv))
+				h.AssertEq(t, metrics.Enabled(), true)
+
+				stop := metrics.Timer(metrics.BuildpackDuration, metrics.L("buildpack", "some/bp"), metrics.L("version", "v1"), metrics.L("step", "build"))
+				stop()
+				h.AssertNil(t, metrics.Complete(nil))
+
+				contents, err := os.ReadFile(filepath.Join(tmpDir, "textfiles", "lifecycle_build.prom"))
+				h.AssertNil(t, err)
+				h.AssertStringContains(t, string(contents), `lifecycle_buildpack_duration_seconds{phase="build",build_id="1234",buildpack="some/bp",version="v1",step="build"} `)
+				h.AssertStringContains(t, string(contents), `lifecycle_phase_duration_seconds{phase="build",build_id="1234",outcome="success"} `)
+
+				entries, err := os.ReadDir(filepath.Join(tmpDir, "textfiles"))
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(entries), 1)
+			})
+		})
+
+		when("a Pushgateway is provided", func() {
+			var (
+				server *httptest.Server
+				method string
+				path   string
+				body   string
+				status int
+			)
+
+			it.Before(func() {
+				status = http.StatusOK
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					method, path = r.Method, r.URL.EscapedPath()
+					contents, err := io.ReadAll(r.Body)
+					h.AssertNil(t, err)
+					body = string(contents)
+					w.WriteHeader(status)
+				}))
+				env[metrics.EnvMetricsPushgateway] = server.URL + "/"
+			})
+
+			it.After(func() {
+				server.Close()
+			})
+
+			it("pushes the metrics of the phase, grouped by phase and labels", func() {
+				env[metrics.EnvMetricsLabels] = "build_id=1234,namespace=some/team,empty="
+				h.AssertNil(t, metrics.Configure("export", getenv))
+
+				metrics.Add(metrics.RegistryBytes, 42, metrics.L("registry", "some-registry"), metrics.L("direction", metrics.DirectionPushed))
+				h.AssertNil(t, metrics.Complete(nil))
+
+				h.AssertEq(t, method, http.MethodPut)
+				h.AssertEq(t, path, "/metrics/job/lifecycle/phase/export/build_id/1234/namespace@base64/c29tZS90ZWFt/empty@base64/=")
+				h.AssertStringContains(t, body, `lifecycle_registry_bytes_total{phase="export",build_id="1234",namespace="some/team",empty="",registry="some-registry",direction="pushed"} 42`)
+				h.AssertEq(t, strings.Count(body, "# TYPE"), 2)
+			})
+
+			it("fails if the Pushgateway rejects the metrics", func() {
+				status = http.StatusBadRequest
+				h.AssertNil(t, metrics.Configure("export", getenv))
+
+				err := metrics.Complete(nil)
+				h.AssertError(t, err, "unexpected status code 400")
+			})
+		})
+	})
+}
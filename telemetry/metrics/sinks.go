@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultPushTimeout is the maximum time to wait for the Pushgateway to accept the metrics of a phase.
+const DefaultPushTimeout = 10 * time.Second
+
+// TextfileSink writes the metrics of each phase to <dir>/lifecycle_<phase>.prom.
+// The file is replaced atomically, so that collectors never read a partially written file.
+type TextfileSink struct {
+	Dir string
+}
+
+func (s *TextfileSink) Write(phase string, _ []Label, text string) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".lifecycle_"+phase+".prom.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.WriteString(text); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil { // #nosec G302
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.Dir, "lifecycle_"+phase+".prom"))
+}
+
+// PushgatewaySink pushes the metrics of each phase to a Pushgateway, replacing the metrics previously pushed
+// for the same phase and labels.
+type PushgatewaySink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewPushgatewaySink returns a sink for the Pushgateway at the provided URL.
+func NewPushgatewaySink(endpoint string) *PushgatewaySink {
+	return &PushgatewaySink{
+		URL: strings.TrimSuffix(endpoint, "/"),
+		// use a dedicated transport, so that pushing metrics is not affected by the registry network settings
+		Client: &http.Client{Timeout: DefaultPushTimeout, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+	}
+}
+
+func (s *PushgatewaySink) Write(phase string, labels []Label, text string) error {
+	path := "/metrics/job/lifecycle" + groupingKey(L("phase", phase))
+	for _, l := range labels {
+		path += groupingKey(l)
+	}
+	req, err := http.NewRequest(http.MethodPut, s.URL+path, strings.NewReader(text))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, s.URL)
+	}
+	return nil
+}
+
+// groupingKey returns the path segments for the label, using the base64 encoding for values
+// that cannot be used in a path segment as-is.
+func groupingKey(l Label) string {
+	if l.Value == "" {
+		return "/" + l.Name + "@base64/="
+	}
+	if strings.Contains(l.Value, "/") {
+		return "/" + l.Name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(l.Value))
+	}
+	return "/" + l.Name + "/" + url.PathEscape(l.Value)
+}
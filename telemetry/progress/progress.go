@@ -0,0 +1,249 @@
+// Package progress emits a stream of progress events from lifecycle phases, so that platforms (e.g., pack),
+// IDE plugins and CI systems can render the live progress of a build consistently, without parsing logs.
+//
+// The stream is opt-in: no events are emitted unless the platform provides a file descriptor (see EnvProgressFD)
+// or a unix socket (see EnvProgressSocket) to write them to.
+//
+// Events are written as newline-delimited JSON objects, one per line, e.g.:
+//
+//	{"version":"1","type":"phase-started","time":"2023-01-01T00:00:00Z","phase":"builder"}
+//	{"version":"1","type":"buildpack-started","time":"2023-01-01T00:00:01Z","phase":"builder","buildpack":"some/buildpack","buildpack-version":"1.0.0","step":"build"}
+//	{"version":"1","type":"phase-finished","time":"2023-01-01T00:00:09Z","phase":"builder","outcome":"success","duration-ns":9000000000}
+//
+// Every event includes the protocol version. Fields may be added to events, and event types may be added to the stream,
+// within a protocol version, so consumers should ignore the fields and event types they do not recognize.
+// Breaking changes to the protocol increment the version.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvProgressFD is an open file descriptor (e.g., "3") to write the progress events to.
+	EnvProgressFD = "CNB_PROGRESS_FD"
+	// EnvProgressSocket is the location of a unix socket to write the progress events to.
+	// The platform must be listening on the socket before the phase starts.
+	EnvProgressSocket = "CNB_PROGRESS_SOCKET"
+
+	// ProtocolVersion is the version of the event stream protocol.
+	ProtocolVersion = "1"
+)
+
+// Event types
+const (
+	TypePhaseStarted      = "phase-started"
+	TypePhaseFinished     = "phase-finished"
+	TypeBuildpackStarted  = "buildpack-started"
+	TypeBuildpackFinished = "buildpack-finished"
+	TypeLayerExported     = "layer-exported"
+	TypeBytesTransferred  = "bytes-transferred"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	StepDetect   = "detect"
+	StepBuild    = "build"
+	StepGenerate = "generate"
+
+	DirectionPushed = "pushed"
+	DirectionPulled = "pulled"
+)
+
+// Event is a progress event. Only the fields relevant to the type of the event are set.
+type Event struct {
+	Version string    `json:"version"`
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Phase   string    `json:"phase"`
+
+	// phase-finished and buildpack-finished
+	Outcome  string        `json:"outcome,omitempty"`
+	Duration time.Duration `json:"duration-ns,omitempty"`
+	// phase-finished
+	ExitCode int `json:"exit-code,omitempty"`
+
+	// buildpack-started and buildpack-finished; buildpacks include extensions
+	Buildpack        string `json:"buildpack,omitempty"`
+	BuildpackVersion string `json:"buildpack-version,omitempty"`
+	Step             string `json:"step,omitempty"`
+
+	// layer-exported
+	Layer  string `json:"layer,omitempty"`
+	Digest string `json:"digest,omitempty"`
+	Reused bool   `json:"reused,omitempty"`
+
+	// bytes-transferred
+	Registry  string `json:"registry,omitempty"`
+	Direction string `json:"direction,omitempty"`
+	Bytes     int64  `json:"bytes,omitempty"`
+}
+
+// Stream writes the progress events of a phase.
+// A stream that fails to write an event (e.g., because the platform stopped reading) is closed,
+// and drops all subsequent events, so that it never fails the phase.
+type Stream struct {
+	phase string
+	start time.Time
+
+	mu  sync.Mutex
+	w   io.WriteCloser
+	enc *json.Encoder
+}
+
+// NewStream returns a stream that writes the events of the provided phase to w.
+func NewStream(phase string, w io.WriteCloser) *Stream {
+	return &Stream{phase: phase, start: time.Now(), w: w, enc: json.NewEncoder(w)}
+}
+
+// Emit writes the event, setting its version, time and phase.
+func (s *Stream) Emit(event Event) {
+	event.Version = ProtocolVersion
+	event.Phase = s.phase
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return
+	}
+	if err := s.enc.Encode(event); err != nil {
+		_ = s.w.Close()
+		s.w = nil
+	}
+}
+
+// Close closes the stream.
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return nil
+	}
+	err := s.w.Close()
+	s.w = nil
+	return err
+}
+
+var (
+	defaultStreamMu sync.Mutex
+	defaultStream   *Stream
+)
+
+// Configure enables the event stream for the provided phase if a file descriptor or socket was provided in the environment,
+// and emits a phase-started event.
+func Configure(phase string, getenv func(string) string) error {
+	w, err := openFromEnv(getenv)
+	if err != nil || w == nil {
+		return err
+	}
+	stream := NewStream(phase, w)
+	stream.Emit(Event{Type: TypePhaseStarted, Time: stream.start.UTC()})
+	SetStream(stream)
+	return nil
+}
+
+func openFromEnv(getenv func(string) string) (io.WriteCloser, error) {
+	if val := getenv(EnvProgressFD); val != "" {
+		fd, err := strconv.ParseUint(val, 10, 32)
+		if err != nil || fd < 3 {
+			return nil, fmt.Errorf("invalid %s '%s': must be a file descriptor greater than 2", EnvProgressFD, val)
+		}
+		f := os.NewFile(uintptr(fd), "progress")
+		if f == nil {
+			return nil, fmt.Errorf("invalid %s '%s'", EnvProgressFD, val)
+		}
+		return f, nil
+	}
+	if path := getenv(EnvProgressSocket); path != "" {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to progress socket: %w", err)
+		}
+		return conn, nil
+	}
+	return nil, nil
+}
+
+// SetStream sets the stream used by the package functions, or disables the event stream if the stream is nil.
+func SetStream(stream *Stream) {
+	defaultStreamMu.Lock()
+	defer defaultStreamMu.Unlock()
+	defaultStream = stream
+}
+
+func current() *Stream {
+	defaultStreamMu.Lock()
+	defer defaultStreamMu.Unlock()
+	return defaultStream
+}
+
+// Enabled returns true if the event stream is enabled.
+func Enabled() bool {
+	return current() != nil
+}
+
+// Emit writes the event, if the event stream is enabled.
+func Emit(event Event) {
+	if s := current(); s != nil {
+		s.Emit(event)
+	}
+}
+
+// BuildpackStarted emits a buildpack-started event, and returns a function that emits the matching buildpack-finished event.
+func BuildpackStarted(id, version, step string) func(err error) {
+	s := current()
+	if s == nil {
+		return func(error) {}
+	}
+	start := time.Now()
+	s.Emit(Event{Type: TypeBuildpackStarted, Buildpack: id, BuildpackVersion: version, Step: step})
+	return func(err error) {
+		s.Emit(Event{
+			Type:             TypeBuildpackFinished,
+			Buildpack:        id,
+			BuildpackVersion: version,
+			Step:             step,
+			Outcome:          outcome(err),
+			Duration:         time.Since(start),
+		})
+	}
+}
+
+// LayerExported emits a layer-exported event.
+func LayerExported(id, digest string, reused bool) {
+	Emit(Event{Type: TypeLayerExported, Layer: id, Digest: digest, Reused: reused})
+}
+
+// BytesTransferred emits a bytes-transferred event for a registry request.
+func BytesTransferred(registry, direction string, bytes int64) {
+	Emit(Event{Type: TypeBytesTransferred, Registry: registry, Direction: direction, Bytes: bytes})
+}
+
+// Finish emits a phase-finished event with the outcome and exit code of the phase, and closes the stream,
+// if the event stream is enabled.
+func Finish(err error, code int) error {
+	s := current()
+	if s == nil {
+		return nil
+	}
+	s.Emit(Event{Type: TypePhaseFinished, Outcome: outcome(err), ExitCode: code, Duration: time.Since(s.start)})
+	return s.Close()
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
@@ -0,0 +1,190 @@
+package progress_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/telemetry/progress"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestProgress(t *testing.T) {
+	spec.Run(t, "Progress", testProgress, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+type writeCloser struct {
+	lines  []map[string]interface{}
+	err    error
+	closed bool
+}
+
+func (w *writeCloser) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	var line map[string]interface{}
+	if err := json.Unmarshal(p, &line); err != nil {
+		return 0, err
+	}
+	w.lines = append(w.lines, line)
+	return len(p), nil
+}
+
+func (w *writeCloser) Close() error {
+	w.closed = true
+	return nil
+}
+
+func testProgress(t *testing.T, when spec.G, it spec.S) {
+	var w *writeCloser
+
+	it.Before(func() {
+		w = &writeCloser{}
+	})
+
+	it.After(func() {
+		progress.SetStream(nil)
+	})
+
+	when("the event stream is enabled", func() {
+		it.Before(func() {
+			progress.SetStream(progress.NewStream("builder", w))
+		})
+
+		it("writes versioned events for the phase", func() {
+			finish := progress.BuildpackStarted("some/bp", "v1", progress.StepBuild)
+			finish(errors.New("some error"))
+			progress.LayerExported("some/bp:some-layer", "sha256:some-digest", true)
+			progress.BytesTransferred("some-registry", progress.DirectionPulled, 42)
+			h.AssertNil(t, progress.Finish(nil, 0))
+
+			h.AssertEq(t, len(w.lines), 5)
+			for _, line := range w.lines {
+				h.AssertEq(t, line["version"], progress.ProtocolVersion)
+				h.AssertEq(t, line["phase"], "builder")
+				_, err := time.Parse(time.RFC3339Nano, line["time"].(string))
+				h.AssertNil(t, err)
+			}
+			h.AssertEq(t, line(w.lines[0], "type", "buildpack", "buildpack-version", "step"), []interface{}{"buildpack-started", "some/bp", "v1", "build"})
+			h.AssertEq(t, line(w.lines[1], "type", "buildpack", "outcome"), []interface{}{"buildpack-finished", "some/bp", "failure"})
+			h.AssertEq(t, line(w.lines[2], "type", "layer", "digest", "reused"), []interface{}{"layer-exported", "some/bp:some-layer", "sha256:some-digest", true})
+			h.AssertEq(t, line(w.lines[3], "type", "registry", "direction", "bytes"), []interface{}{"bytes-transferred", "some-registry", "pulled", float64(42)})
+			h.AssertEq(t, line(w.lines[4], "type", "outcome"), []interface{}{"phase-finished", "success"})
+			h.AssertEq(t, w.closed, true)
+		})
+
+		it("drops the events after a write fails", func() {
+			w.err = errors.New("broken pipe")
+			progress.LayerExported("some-layer", "sha256:some-digest", false)
+			h.AssertEq(t, w.closed, true)
+
+			w.err = nil
+			progress.LayerExported("some-layer", "sha256:some-digest", false)
+			h.AssertNil(t, progress.Finish(errors.New("some error"), 1))
+			h.AssertEq(t, len(w.lines), 0)
+		})
+	})
+
+	when("the event stream is disabled", func() {
+		it("does nothing", func() {
+			h.AssertEq(t, progress.Enabled(), false)
+			progress.BuildpackStarted("some/bp", "v1", progress.StepDetect)(nil)
+			progress.LayerExported("some-layer", "sha256:some-digest", false)
+			h.AssertNil(t, progress.Finish(nil, 0))
+		})
+	})
+
+	when("Configure", func() {
+		var env map[string]string
+
+		getenv := func(key string) string { return env[key] }
+
+		it.Before(func() {
+			env = map[string]string{}
+		})
+
+		it("does not enable the event stream by default", func() {
+			h.AssertNil(t, progress.Configure("detector", getenv))
+			h.AssertEq(t, progress.Enabled(), false)
+		})
+
+		it("fails for an invalid file descriptor", func() {
+			for _, val := range []string{"some-fd", "1", "-3"} {
+				env[progress.EnvProgressFD] = val
+				h.AssertError(t, progress.Configure("detector", getenv), "invalid CNB_PROGRESS_FD")
+			}
+		})
+
+		when("a socket is provided", func() {
+			var (
+				tmpDir   string
+				listener net.Listener
+			)
+
+			it.Before(func() {
+				var err error
+				tmpDir, err = os.MkdirTemp("", "lifecycle.progress")
+				h.AssertNil(t, err)
+				env[progress.EnvProgressSocket] = filepath.Join(tmpDir, "progress.sock")
+			})
+
+			it.After(func() {
+				if listener != nil {
+					_ = listener.Close()
+				}
+				_ = os.RemoveAll(tmpDir)
+			})
+
+			it("writes the events to the socket", func() {
+				var err error
+				listener, err = net.Listen("unix", env[progress.EnvProgressSocket])
+				h.AssertNil(t, err)
+				received := make(chan []string)
+				go func() {
+					conn, err := listener.Accept()
+					if err != nil {
+						close(received)
+						return
+					}
+					defer conn.Close()
+					var types []string
+					scanner := bufio.NewScanner(conn)
+					for scanner.Scan() {
+						var event progress.Event
+						if err := json.Unmarshal(scanner.Bytes(), &event); err == nil {
+							types = append(types, event.Type)
+						}
+					}
+					received <- types
+				}()
+
+				h.AssertNil(t, progress.Configure("exporter", getenv))
+				progress.LayerExported("some-layer", "sha256:some-digest", false)
+				h.AssertNil(t, progress.Finish(nil, 0))
+
+				h.AssertEq(t, <-received, []string{"phase-started", "layer-exported", "phase-finished"})
+			})
+
+			it("fails if nothing is listening on the socket", func() {
+				h.AssertError(t, progress.Configure("exporter", getenv), "failed to connect to progress socket")
+			})
+		})
+	})
+}
+
+func line(event map[string]interface{}, keys ...string) []interface{} {
+	var vals []interface{}
+	for _, key := range keys {
+		vals = append(vals, event[key])
+	}
+	return vals
+}
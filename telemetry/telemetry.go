@@ -0,0 +1,116 @@
+// Package telemetry reports the timings and outcomes of lifecycle phases to hooks provided by the platform,
+// so that platform operators can collect build analytics across many builds.
+//
+// Telemetry is opt-in: no events are reported unless a hook is registered, either in-process with Register,
+// or for the lifecycle binaries, by providing an executable reporter (see ExecHook).
+//
+// Events are anonymized: they never include image references, paths, buildpack IDs, or error messages.
+package telemetry
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Event describes a completed lifecycle phase.
+type Event struct {
+	Phase            string        `json:"phase"`
+	LifecycleVersion string        `json:"lifecycle-version"`
+	PlatformAPI      string        `json:"platform-api,omitempty"`
+	StartTime        time.Time     `json:"start-time"`
+	Duration         time.Duration `json:"duration-ns"`
+	Outcome          string        `json:"outcome"`
+	ExitCode         int           `json:"exit-code"`
+	ErrorClass       string        `json:"error-class,omitempty"`
+}
+
+// Hook receives telemetry events.
+// Hooks are called synchronously when a phase completes, and should return promptly.
+type Hook interface {
+	Report(event Event) error
+}
+
+// HookFunc adapts a function to the Hook interface.
+type HookFunc func(event Event) error
+
+// Report calls f(event).
+func (f HookFunc) Report(event Event) error {
+	return f(event)
+}
+
+var hooks struct {
+	sync.Mutex
+	registered []Hook
+}
+
+// Register adds a hook that will receive all subsequent events.
+func Register(hook Hook) {
+	hooks.Lock()
+	defer hooks.Unlock()
+	hooks.registered = append(hooks.registered, hook)
+}
+
+// Reset removes all registered hooks.
+func Reset() {
+	hooks.Lock()
+	defer hooks.Unlock()
+	hooks.registered = nil
+}
+
+// Enabled returns true if any hooks are registered.
+func Enabled() bool {
+	hooks.Lock()
+	defer hooks.Unlock()
+	return len(hooks.registered) > 0
+}
+
+// Report sends the event to every registered hook, returning any errors returned by the hooks.
+// A failing hook does not prevent the event from being sent to the remaining hooks.
+func Report(event Event) error {
+	hooks.Lock()
+	registered := append([]Hook{}, hooks.registered...)
+	hooks.Unlock()
+
+	var errs []error
+	for _, hook := range registered {
+		if err := hook.Report(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Timer measures a phase, from when it is created until it is completed.
+type Timer struct {
+	event Event
+}
+
+// Start returns a Timer for the provided phase, e.g., "detector".
+func Start(phase, lifecycleVersion, platformAPI string) *Timer {
+	return &Timer{event: Event{
+		Phase:            phase,
+		LifecycleVersion: lifecycleVersion,
+		PlatformAPI:      platformAPI,
+		StartTime:        time.Now(),
+	}}
+}
+
+// Complete reports the outcome of the phase to the registered hooks.
+// An exit code of zero indicates success; errorClass should be provided for failures.
+func (t *Timer) Complete(exitCode int, errorClass string) error {
+	event := t.event
+	event.Duration = time.Since(event.StartTime)
+	event.ExitCode = exitCode
+	event.Outcome = OutcomeSuccess
+	if exitCode != 0 {
+		event.Outcome = OutcomeFailure
+		event.ErrorClass = errorClass
+	}
+	return Report(event)
+}
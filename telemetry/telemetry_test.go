@@ -0,0 +1,135 @@
+package telemetry_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/telemetry"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestTelemetry(t *testing.T) {
+	spec.Run(t, "Telemetry", testTelemetry, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testTelemetry(t *testing.T, when spec.G, it spec.S) {
+	var events []telemetry.Event
+
+	recordEvents := telemetry.HookFunc(func(event telemetry.Event) error {
+		events = append(events, event)
+		return nil
+	})
+
+	it.Before(func() {
+		events = nil
+	})
+
+	it.After(func() {
+		telemetry.Reset()
+	})
+
+	when("#Report", func() {
+		it("does nothing when no hooks are registered", func() {
+			h.AssertEq(t, telemetry.Enabled(), false)
+			h.AssertNil(t, telemetry.Report(telemetry.Event{Phase: "detector"}))
+		})
+
+		it("sends the event to every hook", func() {
+			telemetry.Register(telemetry.HookFunc(func(_ telemetry.Event) error {
+				return errors.New("some-error")
+			}))
+			telemetry.Register(recordEvents)
+
+			err := telemetry.Report(telemetry.Event{Phase: "detector"})
+
+			h.AssertError(t, err, "some-error")
+			h.AssertEq(t, len(events), 1)
+			h.AssertEq(t, events[0].Phase, "detector")
+		})
+	})
+
+	when("Timer", func() {
+		it.Before(func() {
+			telemetry.Register(recordEvents)
+		})
+
+		it("reports success", func() {
+			timer := telemetry.Start("builder", "some-version", "0.12")
+
+			h.AssertNil(t, timer.Complete(0, "some-ignored-class"))
+
+			h.AssertEq(t, len(events), 1)
+			h.AssertEq(t, events[0].Phase, "builder")
+			h.AssertEq(t, events[0].LifecycleVersion, "some-version")
+			h.AssertEq(t, events[0].PlatformAPI, "0.12")
+			h.AssertEq(t, events[0].Outcome, telemetry.OutcomeSuccess)
+			h.AssertEq(t, events[0].ErrorClass, "")
+			h.AssertEq(t, events[0].Duration > 0, true)
+		})
+
+		it("reports failure with the error class", func() {
+			timer := telemetry.Start("builder", "some-version", "0.12")
+
+			h.AssertNil(t, timer.Complete(51, "buildpack"))
+
+			h.AssertEq(t, events[0].Outcome, telemetry.OutcomeFailure)
+			h.AssertEq(t, events[0].ExitCode, 51)
+			h.AssertEq(t, events[0].ErrorClass, "buildpack")
+		})
+	})
+
+	when("ExecHook", func() {
+		var tmpDir string
+
+		it.Before(func() {
+			if runtime.GOOS == "windows" {
+				t.Skip("reporter scripts are not supported on windows")
+			}
+			var err error
+			tmpDir, err = os.MkdirTemp("", "lifecycle-telemetry")
+			h.AssertNil(t, err)
+		})
+
+		it.After(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		it("runs the reporter with the event on stdin", func() {
+			outPath := filepath.Join(tmpDir, "event.json")
+			reporter := filepath.Join(tmpDir, "reporter")
+			h.AssertNil(t, os.WriteFile(reporter, []byte("#!/bin/sh\ncat > "+outPath+"\n"), 0755)) // #nosec G306
+
+			h.AssertNil(t, telemetry.NewExecHook(reporter).Report(telemetry.Event{
+				Phase:      "exporter",
+				Outcome:    telemetry.OutcomeFailure,
+				ExitCode:   62,
+				ErrorClass: "infra",
+			}))
+
+			contents, err := os.ReadFile(outPath)
+			h.AssertNil(t, err)
+			var decoded map[string]interface{}
+			h.AssertNil(t, json.Unmarshal(contents, &decoded))
+			h.AssertEq(t, decoded["phase"], "exporter")
+			h.AssertEq(t, decoded["outcome"], "failure")
+			h.AssertEq(t, decoded["exit-code"], float64(62))
+			h.AssertEq(t, decoded["error-class"], "infra")
+		})
+
+		it("errors when the reporter fails", func() {
+			reporter := filepath.Join(tmpDir, "reporter")
+			h.AssertNil(t, os.WriteFile(reporter, []byte("#!/bin/sh\nexit 1\n"), 0755)) // #nosec G306
+
+			err := telemetry.NewExecHook(reporter).Report(telemetry.Event{Phase: "exporter"})
+
+			h.AssertError(t, err, "failed to run telemetry reporter")
+		})
+	})
+}
@@ -0,0 +1,191 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultExportTimeout is the maximum time to wait for the OTLP endpoint to accept the spans of a phase.
+const DefaultExportTimeout = 10 * time.Second
+
+const instrumentationScope = "github.com/buildpacks/lifecycle"
+
+// OTLPExporter sends spans to an OTLP/HTTP endpoint using the JSON encoding.
+type OTLPExporter struct {
+	URL         string
+	Headers     map[string]string
+	ServiceName string
+	Client      *http.Client
+}
+
+// OTLPExporterFromEnv returns an exporter for the OTLP endpoint provided in the environment,
+// or nil if no endpoint was provided.
+func OTLPExporterFromEnv(getenv func(string) string) (*OTLPExporter, error) {
+	endpoint := getenv(EnvOTLPTracesEndpoint)
+	if endpoint == "" {
+		if base := getenv(EnvOTLPEndpoint); base != "" {
+			endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return nil, nil
+	}
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint '%s': %w", endpoint, err)
+	}
+	headers := map[string]string{}
+	for _, entry := range strings.Split(getenv(EnvOTLPHeaders), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		key, val, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid %s entry '%s': must be <key>=<value>", EnvOTLPHeaders, entry)
+		}
+		unescaped, err := url.QueryUnescape(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry '%s': %w", EnvOTLPHeaders, entry, err)
+		}
+		headers[strings.TrimSpace(key)] = unescaped
+	}
+	serviceName := getenv(EnvServiceName)
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	return &OTLPExporter{
+		URL:         endpoint,
+		Headers:     headers,
+		ServiceName: serviceName,
+		// use a dedicated transport, so that exporting spans is not affected by the registry network settings
+		Client: &http.Client{Timeout: DefaultExportTimeout, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+	}, nil
+}
+
+// Export sends the spans to the OTLP endpoint.
+func (e *OTLPExporter) Export(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, val := range e.Headers {
+		req.Header.Set(key, val)
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to export spans: unexpected status code %d from %s", resp.StatusCode, e.URL)
+	}
+	return nil
+}
+
+// OTLP/JSON request, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeOK     = 1
+	otlpStatusCodeError  = 2
+)
+
+func (e *OTLPExporter) request(spans []*Span) otlpRequest {
+	scopeSpans := otlpScopeSpans{Scope: otlpScope{Name: instrumentationScope}}
+	for _, span := range spans {
+		span.mu.Lock()
+		s := otlpSpan{
+			TraceID:           hex.EncodeToString(span.ctx.TraceID[:]),
+			SpanID:            hex.EncodeToString(span.ctx.SpanID[:]),
+			Name:              span.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Status:            otlpStatus{Code: otlpStatusCodeOK},
+		}
+		if span.parent != [8]byte{} {
+			s.ParentSpanID = hex.EncodeToString(span.parent[:])
+		}
+		for _, attr := range span.attrs {
+			s.Attributes = append(s.Attributes, otlpAttributeFor(attr))
+		}
+		if span.err != nil {
+			s.Status = otlpStatus{Code: otlpStatusCodeError, Message: span.err.Error()}
+		}
+		span.mu.Unlock()
+		scopeSpans.Spans = append(scopeSpans.Spans, s)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{otlpAttributeFor(String("service.name", e.ServiceName))}},
+		ScopeSpans: []otlpScopeSpans{scopeSpans},
+	}}}
+}
+
+func otlpAttributeFor(attr Attribute) otlpAttribute {
+	switch v := attr.Value.(type) {
+	case int64:
+		// 64-bit integers are encoded as strings
+		return otlpAttribute{Key: attr.Key, Value: map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}}
+	case bool:
+		return otlpAttribute{Key: attr.Key, Value: map[string]interface{}{"boolValue": v}}
+	default:
+		return otlpAttribute{Key: attr.Key, Value: map[string]interface{}{"stringValue": fmt.Sprint(v)}}
+	}
+}
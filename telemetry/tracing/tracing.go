@@ -0,0 +1,317 @@
+// Package tracing records OpenTelemetry-compatible traces of lifecycle phases, with spans for each phase,
+// each buildpack or extension, each layer, and each registry request, and exports them to an OTLP endpoint
+// so that platform operators can see builds in their existing tracing stack.
+//
+// Tracing is opt-in: no spans are recorded unless an OTLP endpoint is provided using the standard OpenTelemetry environment
+// variables (see EnvOTLPEndpoint). Unlike telemetry events, spans include identifiers such as buildpack IDs and
+// image repositories.
+//
+// Each phase runs in its own process, so the trace context is propagated between phases using the W3C traceparent format,
+// either in the TRACEPARENT environment variable or in a file shared by the phases (see EnvTraceContextPath).
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvTraceParent is the W3C trace context of the span that the phase is part of, e.g., the platform's build span.
+	EnvTraceParent = "TRACEPARENT"
+	// EnvTraceContextPath is the location of a file containing the W3C trace context shared by the phases of a build.
+	// If the file does not exist (and TRACEPARENT is not provided), the phase starts a new trace and writes its own
+	// trace context to the file, so that the subsequent phases are part of the same trace.
+	EnvTraceContextPath = "CNB_TRACE_CONTEXT_PATH"
+	// EnvOTLPEndpoint is the base URL of the OTLP/HTTP endpoint to export spans to, e.g., "http://otel-collector:4318".
+	// Spans are sent to <endpoint>/v1/traces.
+	EnvOTLPEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// EnvOTLPTracesEndpoint is the full URL to export spans to, overriding EnvOTLPEndpoint.
+	EnvOTLPTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	// EnvOTLPHeaders are additional headers to send to the OTLP endpoint, as a comma-separated list of key=value pairs.
+	EnvOTLPHeaders = "OTEL_EXPORTER_OTLP_HEADERS"
+	// EnvServiceName is the service name recorded in the exported spans. The default is "lifecycle".
+	EnvServiceName = "OTEL_SERVICE_NAME"
+
+	DefaultServiceName = "lifecycle"
+)
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// ParseTraceParent parses a W3C traceparent header value, e.g., "00-<trace-id>-<span-id>-01".
+func ParseTraceParent(traceParent string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent '%s'", traceParent)
+	}
+	var (
+		ctx   SpanContext
+		flags [1]byte
+	)
+	for _, field := range []struct {
+		dst []byte
+		src string
+	}{{ctx.TraceID[:], parts[1]}, {ctx.SpanID[:], parts[2]}, {flags[:], parts[3]}} {
+		if _, err := hex.Decode(field.dst, []byte(field.src)); err != nil {
+			return SpanContext{}, fmt.Errorf("invalid traceparent '%s'", traceParent)
+		}
+	}
+	if !ctx.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent '%s'", traceParent)
+	}
+	ctx.Sampled = flags[0]&1 == 1
+	return ctx, nil
+}
+
+// TraceParent returns the W3C traceparent header value for the span context.
+func (c SpanContext) TraceParent() string {
+	flags := "00"
+	if c.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(c.TraceID[:]), hex.EncodeToString(c.SpanID[:]), flags)
+}
+
+// IsValid returns true if the trace ID and the span ID are not all zeros.
+func (c SpanContext) IsValid() bool {
+	return c.TraceID != [16]byte{} && c.SpanID != [8]byte{}
+}
+
+// Attribute is a key-value pair describing a span. Values are strings, ints, int64s or bools.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is an operation within a trace.
+// All methods of Span can be called on a nil Span, which is what Start returns when tracing is disabled.
+type Span struct {
+	tracer *Tracer
+	name   string
+	ctx    SpanContext
+	parent [8]byte
+	start  time.Time
+
+	mu    sync.Mutex
+	end   time.Time
+	attrs []Attribute
+	err   error
+}
+
+// Context returns the span context, e.g., to propagate it to a child process.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.ctx
+}
+
+// Start starts a child span of the span.
+func (s *Span) Start(name string, attrs ...Attribute) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.start(name, s.ctx, attrs)
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// End ends the span, recording the error (if any) as its status.
+// Only the first call to End has any effect.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	s.mu.Unlock()
+	s.tracer.finish(s)
+}
+
+// Exporter sends finished spans to a tracing backend.
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+// Tracer records the spans of a phase.
+type Tracer struct {
+	exporter Exporter
+	root     *Span
+
+	mu       sync.Mutex
+	finished []*Span
+}
+
+// NewTracer returns a tracer that starts the root span for the provided phase as a child of the provided parent
+// (or as the root of a new trace if the parent is not valid).
+func NewTracer(phase string, parent SpanContext, exporter Exporter) *Tracer {
+	t := &Tracer{exporter: exporter}
+	t.root = t.start(phase, parent, []Attribute{String("cnb.phase", phase)})
+	return t
+}
+
+// Root returns the root span of the phase.
+func (t *Tracer) Root() *Span {
+	return t.root
+}
+
+// Shutdown ends the root span of the phase with the provided error (if any) and exports all of the finished spans.
+func (t *Tracer) Shutdown(err error) error {
+	t.root.End(err)
+	t.mu.Lock()
+	finished := t.finished
+	t.finished = nil
+	t.mu.Unlock()
+	if len(finished) == 0 {
+		return nil
+	}
+	return t.exporter.Export(finished)
+}
+
+func (t *Tracer) start(name string, parent SpanContext, attrs []Attribute) *Span {
+	span := &Span{tracer: t, name: name, start: time.Now(), attrs: attrs}
+	if parent.IsValid() {
+		span.ctx.TraceID = parent.TraceID
+		span.parent = parent.SpanID
+		span.ctx.Sampled = parent.Sampled
+	} else {
+		_, _ = rand.Read(span.ctx.TraceID[:])
+		span.ctx.Sampled = true
+	}
+	_, _ = rand.Read(span.ctx.SpanID[:])
+	return span
+}
+
+func (t *Tracer) finish(span *Span) {
+	if !span.ctx.Sampled {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished = append(t.finished, span)
+}
+
+var (
+	defaultTracerMu sync.Mutex
+	defaultTracer   *Tracer
+)
+
+// Configure enables tracing for the provided phase if an OTLP endpoint was provided in the environment,
+// reading the parent trace context from the environment or the trace context file.
+// The provided attributes are added to the root span of the phase.
+func Configure(phase string, getenv func(string) string, attrs ...Attribute) error {
+	exporter, err := OTLPExporterFromEnv(getenv)
+	if err != nil || exporter == nil {
+		return err
+	}
+	parent, writeTo, err := parentFromEnv(getenv)
+	if err != nil {
+		return err
+	}
+	tracer := NewTracer(phase, parent, exporter)
+	tracer.Root().SetAttributes(attrs...)
+	if writeTo != "" {
+		if err := os.WriteFile(writeTo, []byte(tracer.Root().Context().TraceParent()+"\n"), 0644); err != nil { // #nosec G306
+			return fmt.Errorf("failed to write trace context: %w", err)
+		}
+	}
+	SetTracer(tracer)
+	return nil
+}
+
+// parentFromEnv returns the parent trace context, and the location to write the trace context of the phase to
+// if the trace context file does not exist yet.
+func parentFromEnv(getenv func(string) string) (SpanContext, string, error) {
+	if traceParent := getenv(EnvTraceParent); traceParent != "" {
+		parent, err := ParseTraceParent(traceParent)
+		return parent, "", err
+	}
+	path := getenv(EnvTraceContextPath)
+	if path == "" {
+		return SpanContext{}, "", nil
+	}
+	contents, err := os.ReadFile(path) // #nosec G304
+	if errors.Is(err, os.ErrNotExist) {
+		return SpanContext{}, path, nil
+	}
+	if err != nil {
+		return SpanContext{}, "", fmt.Errorf("failed to read trace context: %w", err)
+	}
+	parent, err := ParseTraceParent(string(contents))
+	return parent, "", err
+}
+
+// SetTracer sets the tracer used by Start, or disables tracing if the tracer is nil.
+func SetTracer(tracer *Tracer) {
+	defaultTracerMu.Lock()
+	defer defaultTracerMu.Unlock()
+	defaultTracer = tracer
+}
+
+func currentTracer() *Tracer {
+	defaultTracerMu.Lock()
+	defer defaultTracerMu.Unlock()
+	return defaultTracer
+}
+
+// Enabled returns true if tracing is enabled.
+func Enabled() bool {
+	return currentTracer() != nil
+}
+
+// Start starts a child span of the phase, or returns nil if tracing is disabled.
+func Start(name string, attrs ...Attribute) *Span {
+	tracer := currentTracer()
+	if tracer == nil {
+		return nil
+	}
+	return tracer.root.Start(name, attrs...)
+}
+
+// Shutdown ends the phase with the provided error (if any) and exports the trace, if tracing is enabled.
+func Shutdown(err error) error {
+	tracer := currentTracer()
+	if tracer == nil {
+		return nil
+	}
+	return tracer.Shutdown(err)
+}
@@ -0,0 +1,158 @@
+package tracing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/telemetry/tracing"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestTracing(t *testing.T) {
+	spec.Run(t, "Tracing", testTracing, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testTracing(t *testing.T, when spec.G, it spec.S) {
+	const traceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+	var (
+		server   *httptest.Server
+		requests []map[string]interface{}
+		env      map[string]string
+	)
+
+	getenv := func(key string) string { return env[key] }
+
+	it.Before(func() {
+		requests = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.AssertEq(t, r.URL.Path, "/v1/traces")
+			h.AssertEq(t, r.Header.Get("Content-Type"), "application/json")
+			h.AssertEq(t, r.Header.Get("Authorization"), "Bearer some token")
+			body, err := io.ReadAll(r.Body)
+			h.AssertNil(t, err)
+			var req map[string]interface{}
+			h.AssertNil(t, json.Unmarshal(body, &req))
+			requests = append(requests, req)
+		}))
+		env = map[string]string{
+			tracing.EnvOTLPEndpoint: server.URL,
+			tracing.EnvOTLPHeaders:  "Authorization=Bearer%20some%20token",
+		}
+	})
+
+	it.After(func() {
+		server.Close()
+		tracing.SetTracer(nil)
+	})
+
+	exportedSpans := func() map[string]map[string]interface{} {
+		h.AssertEq(t, len(requests), 1)
+		resourceSpans := requests[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		spans := map[string]map[string]interface{}{}
+		for _, s := range resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{}) {
+			span := s.(map[string]interface{})
+			spans[span["name"].(string)] = span
+		}
+		return spans
+	}
+
+	when("#ParseTraceParent", func() {
+		it("parses W3C trace contexts", func() {
+			ctx, err := tracing.ParseTraceParent(traceParent)
+			h.AssertNil(t, err)
+			h.AssertEq(t, ctx.Sampled, true)
+			h.AssertEq(t, ctx.TraceParent(), traceParent)
+		})
+
+		it("errors for invalid trace contexts", func() {
+			for _, invalid := range []string{
+				"some-trace-parent",
+				"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+				"00-0af7651916cd43dd8448eb211c80319c-b7ad6b716920333z-01",
+			} {
+				_, err := tracing.ParseTraceParent(invalid)
+				h.AssertError(t, err, "invalid traceparent")
+			}
+		})
+	})
+
+	when("#Configure", func() {
+		it("does nothing if no OTLP endpoint is provided", func() {
+			h.AssertNil(t, tracing.Configure("some-phase", func(string) string { return "" }))
+			h.AssertEq(t, tracing.Enabled(), false)
+			h.AssertNil(t, tracing.Start("some-span"))
+			h.AssertNil(t, tracing.Shutdown(nil))
+		})
+
+		it("exports the spans of the phase as children of the provided trace context", func() {
+			env[tracing.EnvTraceParent] = traceParent
+			h.AssertNil(t, tracing.Configure("some-phase", getenv, tracing.String("some-key", "some-value")))
+			h.AssertEq(t, tracing.Enabled(), true)
+
+			child := tracing.Start("some-child", tracing.Int("some-int", 1))
+			grandchild := child.Start("some-grandchild")
+			grandchild.End(errors.New("some-error"))
+			child.End(nil)
+			h.AssertNil(t, tracing.Shutdown(nil))
+
+			spans := exportedSpans()
+			h.AssertEq(t, len(spans), 3)
+			phase := spans["some-phase"]
+			h.AssertEq(t, phase["traceId"], "0af7651916cd43dd8448eb211c80319c")
+			h.AssertEq(t, phase["parentSpanId"], "b7ad6b7169203331")
+			h.AssertEq(t, phase["status"], map[string]interface{}{"code": float64(1)})
+			h.AssertEq(t, phase["attributes"], []interface{}{
+				map[string]interface{}{"key": "cnb.phase", "value": map[string]interface{}{"stringValue": "some-phase"}},
+				map[string]interface{}{"key": "some-key", "value": map[string]interface{}{"stringValue": "some-value"}},
+			})
+			h.AssertEq(t, spans["some-child"]["parentSpanId"], phase["spanId"])
+			h.AssertEq(t, spans["some-child"]["attributes"], []interface{}{
+				map[string]interface{}{"key": "some-int", "value": map[string]interface{}{"intValue": "1"}},
+			})
+			h.AssertEq(t, spans["some-grandchild"]["parentSpanId"], spans["some-child"]["spanId"])
+			h.AssertEq(t, spans["some-grandchild"]["status"], map[string]interface{}{"code": float64(2), "message": "some-error"})
+		})
+
+		it("records the error of a failed phase", func() {
+			h.AssertNil(t, tracing.Configure("some-phase", getenv))
+			h.AssertNil(t, tracing.Shutdown(errors.New("some-failure")))
+
+			h.AssertEq(t, exportedSpans()["some-phase"]["status"], map[string]interface{}{"code": float64(2), "message": "some-failure"})
+		})
+
+		it("shares the trace context between phases using the trace context file", func() {
+			path := filepath.Join(t.TempDir(), "trace-context")
+			env[tracing.EnvTraceContextPath] = path
+
+			h.AssertNil(t, tracing.Configure("some-phase", getenv))
+			contents, err := os.ReadFile(path)
+			h.AssertNil(t, err)
+			first, err := tracing.ParseTraceParent(string(contents))
+			h.AssertNil(t, err)
+			h.AssertNil(t, tracing.Shutdown(nil))
+
+			requests = nil
+			h.AssertNil(t, tracing.Configure("other-phase", getenv))
+			h.AssertNil(t, tracing.Shutdown(nil))
+			other := exportedSpans()["other-phase"]
+			h.AssertEq(t, other["traceId"], strings.Split(first.TraceParent(), "-")[1])
+			h.AssertEq(t, other["parentSpanId"], strings.Split(first.TraceParent(), "-")[2])
+		})
+
+		it("errors for invalid headers", func() {
+			env[tracing.EnvOTLPHeaders] = "some-header"
+			h.AssertError(t, tracing.Configure("some-phase", getenv), "invalid OTEL_EXPORTER_OTLP_HEADERS entry 'some-header'")
+		})
+	})
+}
@@ -51,6 +51,36 @@ func (mr *MockLayerFactoryMockRecorder) DirLayer(arg0, arg1, arg2 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DirLayer", reflect.TypeOf((*MockLayerFactory)(nil).DirLayer), arg0, arg1, arg2)
 }
 
+// DirsLayer mocks base method.
+func (m *MockLayerFactory) DirsLayer(arg0 string, arg1 []string, arg2 string) (layers.Layer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DirsLayer", arg0, arg1, arg2)
+	ret0, _ := ret[0].(layers.Layer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DirsLayer indicates an expected call of DirsLayer.
+func (mr *MockLayerFactoryMockRecorder) DirsLayer(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DirsLayer", reflect.TypeOf((*MockLayerFactory)(nil).DirsLayer), arg0, arg1, arg2)
+}
+
+// FilesLayer mocks base method.
+func (m *MockLayerFactory) FilesLayer(arg0 string, arg1 []string, arg2 string) (layers.Layer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilesLayer", arg0, arg1, arg2)
+	ret0, _ := ret[0].(layers.Layer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilesLayer indicates an expected call of FilesLayer.
+func (mr *MockLayerFactoryMockRecorder) FilesLayer(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilesLayer", reflect.TypeOf((*MockLayerFactory)(nil).FilesLayer), arg0, arg1, arg2)
+}
+
 // LauncherLayer mocks base method.
 func (m *MockLayerFactory) LauncherLayer(arg0 string) (layers.Layer, error) {
 	m.ctrl.T.Helper()
@@ -5,7 +5,7 @@ import (
 )
 
 func TruncateSha(sha string) string {
-	rawSha := strings.TrimPrefix(sha, "sha256:")
+	rawSha := sha[strings.Index(sha, ":")+1:] // without the algorithm, e.g., "sha256:"
 	if len(sha) > 12 {
 		return rawSha[0:12]
 	}
@@ -0,0 +1,113 @@
+package lifecycle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/launch"
+)
+
+// checkWorkingDirs ensures that the working directory of each process (and task) exists in the app directory or in a launch layer,
+// creating it if opts.CreateWorkingDirs is set, so that a missing directory fails the export rather than the start of the container.
+// Relative working directories are relative to the app directory.
+// Working directories elsewhere are expected to be provided by the run image, and are not checked.
+func (e *Exporter) checkWorkingDirs(opts ExportOptions, launchMD launch.Metadata) error {
+	procs := append([]launch.Process{}, launchMD.Processes...)
+	for _, task := range launchMD.Tasks {
+		procs = append(procs, task.Process)
+	}
+	for _, proc := range procs {
+		if proc.WorkingDirectory == "" {
+			continue
+		}
+		dir := proc.WorkingDirectory
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(opts.AppDir, dir)
+		}
+		var (
+			found bool
+			err   error
+		)
+		switch {
+		case isWithin(opts.LayersDir, dir):
+			found, err = e.checkLayerWorkingDir(opts.LayersDir, dir, proc.BuildpackID, opts.CreateWorkingDirs)
+		case isWithin(opts.AppDir, dir):
+			found, err = e.checkWorkingDir(dir, opts.CreateWorkingDirs)
+		default:
+			e.Logger.Debugf("Not checking working directory '%s' of process type '%s', expecting it in the run image", proc.WorkingDirectory, proc.Type)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("checking working directory '%s' of process type '%s' (buildpack '%s'): %w", proc.WorkingDirectory, proc.Type, proc.BuildpackID, err)
+		}
+		if !found {
+			return fmt.Errorf("working directory '%s' of process type '%s' (buildpack '%s') does not exist in the app directory or in a launch layer", proc.WorkingDirectory, proc.Type, proc.BuildpackID)
+		}
+	}
+	return nil
+}
+
+// checkLayerWorkingDir checks the working directory within the layers directory, which must be in a launch layer of the buildpack.
+// The working directories in launch layers that are reused from the previous image cannot be checked.
+func (e *Exporter) checkLayerWorkingDir(layersDir, dir, bpID string, create bool) (bool, error) {
+	rel, err := filepath.Rel(layersDir, dir)
+	if err != nil {
+		return false, err
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	if len(parts) < 2 || parts[0] != launch.EscapeID(bpID) {
+		return false, nil
+	}
+	var bp *buildpack.GroupElement
+	for i := range e.Buildpacks {
+		if e.Buildpacks[i].ID == bpID {
+			bp = &e.Buildpacks[i]
+			break
+		}
+	}
+	if bp == nil {
+		return false, nil
+	}
+	bpDir, err := buildpack.ReadLayersDir(layersDir, *bp, e.Logger)
+	if err != nil {
+		return false, err
+	}
+	for _, layer := range bpDir.FindLayers(buildpack.MadeLaunch) {
+		if layer.Name() != parts[1] {
+			continue
+		}
+		if !layer.HasLocalContents() {
+			return true, nil
+		}
+		return e.checkWorkingDir(dir, create)
+	}
+	return false, nil
+}
+
+func (e *Exporter) checkWorkingDir(dir string, create bool) (bool, error) {
+	fi, err := os.Stat(dir)
+	switch {
+	case err == nil && !fi.IsDir():
+		return false, fmt.Errorf("'%s' is not a directory", dir)
+	case err == nil:
+		return true, nil
+	case !os.IsNotExist(err):
+		return false, err
+	case !create:
+		return false, nil
+	}
+	e.Logger.Infof("Creating working directory '%s'", dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// isWithin returns true if path is parent or is within parent.
+func isWithin(parent, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}